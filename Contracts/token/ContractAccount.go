@@ -0,0 +1,96 @@
+package token
+
+import (
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/contractaccount"
+)
+
+// ContractAccountID returns the account ID owned by chaincodeName. subAccount lets a single
+// chaincode keep several segregated accounts (one per wallet, vault, etc.) and may be empty.
+// Fabric forwards the end user's identity on InvokeChaincode, so a chaincode can never sign for
+// its own funds; instead, a contract account may only be debited when the transaction proposal
+// was addressed to the chaincode that owns it.
+func ContractAccountID(chaincodeName string, subAccount string) string {
+	return contractaccount.ID(chaincodeName, subAccount)
+}
+
+// invokingChaincode returns the name of the chaincode the client addressed the transaction
+// proposal to. When another chaincode calls this one via InvokeChaincode, this is the caller.
+func invokingChaincode(sdk kalpsdk.TransactionContextInterface) (string, error) {
+	stub, err := chaincodeStub(sdk)
+	if err != nil {
+		return "", err
+	}
+	signedProposal, err := stub.GetSignedProposal()
+	if err != nil || signedProposal == nil {
+		return "", fmt.Errorf("failed to get signed proposal: %v", err)
+	}
+	proposal := new(peer.Proposal)
+	err = proto.Unmarshal(signedProposal.ProposalBytes, proposal)
+	if err != nil {
+		return "", fmt.Errorf("failed to unmarshal proposal: %v", err)
+	}
+	payload := new(peer.ChaincodeProposalPayload)
+	err = proto.Unmarshal(proposal.Payload, payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to unmarshal proposal payload: %v", err)
+	}
+	invocationSpec := new(peer.ChaincodeInvocationSpec)
+	err = proto.Unmarshal(payload.Input, invocationSpec)
+	if err != nil {
+		return "", fmt.Errorf("failed to unmarshal chaincode invocation spec: %v", err)
+	}
+	if invocationSpec.ChaincodeSpec == nil || invocationSpec.ChaincodeSpec.ChaincodeId == nil {
+		return "", fmt.Errorf("proposal does not name a chaincode")
+	}
+	return invocationSpec.ChaincodeSpec.ChaincodeId.Name, nil
+}
+
+// chaincodeStub returns the Fabric stub behind the kalpsdk context for the few APIs (proposals,
+// private data, transient data) that kalpsdk does not wrap.
+func chaincodeStub(sdk kalpsdk.TransactionContextInterface) (shim.ChaincodeStubInterface, error) {
+	stubContext, ok := sdk.(interface {
+		GetStub() shim.ChaincodeStubInterface
+	})
+	if !ok || stubContext.GetStub() == nil {
+		return nil, fmt.Errorf("transaction context does not expose the chaincode stub")
+	}
+	return stubContext.GetStub(), nil
+}
+
+// checkContractAccount returns an error unless account is a contract account owned by the
+// chaincode that the current transaction was proposed to.
+func checkContractAccount(sdk kalpsdk.TransactionContextInterface, account string) error {
+	owner, ok := contractaccount.Owner(account)
+	if !ok {
+		return fmt.Errorf("account %s is not a contract account", account)
+	}
+	invoker, err := invokingChaincode(sdk)
+	if err != nil {
+		return err
+	}
+	if invoker != owner {
+		return fmt.Errorf("contract account %s can only be debited through chaincode %s", account, owner)
+	}
+	return nil
+}
+
+// invokeChaincodeHelper calls function on chaincodeName in the current channel with string
+// arguments and returns the response payload, failing unless the callee answered 200 OK.
+func invokeChaincodeHelper(sdk kalpsdk.TransactionContextInterface, chaincodeName string, function string, args ...string) ([]byte, error) {
+	invokeArgs := make([][]byte, 0, len(args)+1)
+	invokeArgs = append(invokeArgs, []byte(function))
+	for _, arg := range args {
+		invokeArgs = append(invokeArgs, []byte(arg))
+	}
+	response := sdk.InvokeChaincode(chaincodeName, invokeArgs, "")
+	if response.Status != shim.OK {
+		return nil, fmt.Errorf("failed to invoke %s on chaincode %s: %s", function, chaincodeName, response.Message)
+	}
+	return response.Payload, nil
+}
@@ -3,70 +3,79 @@ package token
 import (
 	"encoding/json"
 	"fmt"
+	"math/big"
 	"sort"
 	"strconv"
 	"strings"
 
 	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+	"github.com/thekalpstudio/kush-go/audit"
+	"github.com/thekalpstudio/kush-go/canonical"
+	"github.com/thekalpstudio/kush-go/config"
+	"github.com/thekalpstudio/kush-go/denylist"
+	"github.com/thekalpstudio/kush-go/events"
+	"github.com/thekalpstudio/kush-go/internal/readcache"
+	"github.com/thekalpstudio/kush-go/internal/statebatch"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+	"github.com/thekalpstudio/kush-go/logging"
+	"github.com/thekalpstudio/kush-go/metrics"
+	"github.com/thekalpstudio/kush-go/migration"
 )
 
 const uriKey = "uri"
 
+// balancePrefix2 keys the balance of an account in a token type, {account, id}. Balances written
+// before the ERC1155BalancesV2 upgrade are kept as one record per crediting sender under
+// balancePrefix1, {account, id, sender}, until the upgrade merges them.
+const balancePrefix2 = "account~tokenId"
 const balancePrefix1 = "account~tokenId~sender"
 const approvalPrefix1 = "account~operator"
 
-const minterMSPID = "mailabs"
-
 // Define key names for options
 const nameKey2 = "name"
 const symbolKey2 = "symbol"
 
 // SmartContract provides functions for transferring tokens between accounts
 type SmartContract struct {
-	kalpsdk.Contract
+	logging.Contract
 }
 
 // TransferSingle MUST emit when a single token is transferred, including zero
 // value transfers as well as minting or burning.
-type TransferSingle struct {
-	Operator string `json:"operator"`
-	From     string `json:"from"`
-	To       string `json:"to"`
-	ID       uint64 `json:"id"`
-	Value    uint64 `json:"value"`
-}
+type TransferSingle = events.TransferSingle
 
 // TransferBatch MUST emit when tokens are transferred, including zero value
 // transfers as well as minting or burning.
-type TransferBatch struct {
-	Operator string   `json:"operator"`
-	From     string   `json:"from"`
-	To       string   `json:"to"`
-	IDs      []uint64 `json:"ids"`
-	Values   []uint64 `json:"values"`
-}
+type TransferBatch = events.TransferBatch
 
 // ApprovalForAll MUST emit when approval for a second party/operator address
 // to manage all tokens for an owner address is enabled or disabled
-type ApprovalForAll struct {
-	Owner    string `json:"owner"`
-	Operator string `json:"operator"`
-	Approved bool   `json:"approved"`
-}
+type ApprovalForAll = events.ApprovalForAll
 
-// URI MUST emit when the URI is updated for a token ID.
-type URI struct {
-	Value string `json:"value"`
-	ID    uint64 `json:"id"`
+// TokenInfo bundles the collection level options of the contract.
+type TokenInfo struct {
+	Name        string `json:"name"`
+	Symbol      string `json:"symbol"`
+	URI         string `json:"uri"`
+	Initialized bool   `json:"initialized"`
 }
 
+// URI MUST emit when the URI is updated for a token ID. A change of the global URI emits it once,
+// with Global set.
+type URI = events.URI
+
 // Mint creates amount tokens of token type id and assigns them to account.
 func (s *SmartContract) Mint(sdk kalpsdk.TransactionContextInterface, account string, id uint64, amount uint64) error {
 	initialized, err := checkInitialized2(sdk)
 	if err != nil || !initialized {
-		return fmt.Errorf("failed to check if contract is already initialized: %v", err)
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
 	}
-	err = authorizationHelper(sdk)
+	err = checkNotPaused(sdk)
+	if err != nil {
+		return err
+	}
+	err = authorizationHelper(sdk, accesscontrol.MinterRole)
 	if err != nil {
 		return err
 	}
@@ -74,11 +83,15 @@ func (s *SmartContract) Mint(sdk kalpsdk.TransactionContextInterface, account st
 	if err != nil {
 		return fmt.Errorf("failed to get client id: %v", err)
 	}
+	err = accesscontrol.CheckMintThreshold(sdk, new(big.Int).SetUint64(amount))
+	if err != nil {
+		return err
+	}
 	err = mintHelper(sdk, operator, account, id, amount)
 	if err != nil {
 		return err
 	}
-	transferSingleEvent := TransferSingle{operator, "0x0", account, id, amount}
+	transferSingleEvent := TransferSingle{Operator: operator, From: "0x0", To: account, ID: id, Value: amount}
 	return emitTransferSingle(sdk, transferSingleEvent)
 }
 
@@ -86,12 +99,16 @@ func (s *SmartContract) Mint(sdk kalpsdk.TransactionContextInterface, account st
 func (s *SmartContract) MintBatch(sdk kalpsdk.TransactionContextInterface, account string, ids []uint64, amounts []uint64) error {
 	initialized, err := checkInitialized2(sdk)
 	if err != nil || !initialized {
-		return fmt.Errorf("failed to check if contract is already initialized: %v", err)
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	err = checkNotPaused(sdk)
+	if err != nil {
+		return err
 	}
 	if len(ids) != len(amounts) {
 		return fmt.Errorf("ids and amounts must have the same length")
 	}
-	err = authorizationHelper(sdk)
+	err = authorizationHelper(sdk, accesscontrol.MinterRole)
 	if err != nil {
 		return err
 	}
@@ -109,37 +126,46 @@ func (s *SmartContract) MintBatch(sdk kalpsdk.TransactionContextInterface, accou
 	amountToSendKeys := sortedKeys(amountToSend)
 	for _, id := range amountToSendKeys {
 		amount := amountToSend[id]
+		err = accesscontrol.CheckMintThreshold(sdk, new(big.Int).SetUint64(amount))
+		if err != nil {
+			return err
+		}
 		err = mintHelper(sdk, operator, account, id, amount)
 		if err != nil {
 			return err
 		}
 	}
-	transferBatchEvent := TransferBatch{operator, "0x0", account, ids, amounts}
+	transferBatchEvent := TransferBatch{Operator: operator, From: "0x0", To: account, IDs: ids, Values: amounts}
 	return emitTransferBatch(sdk, transferBatchEvent)
 }
 
-// Burn destroys amount tokens of token type id from account.
+// Burn destroys amount tokens of token type id from account. The holder, an approved operator or
+// a BURNER_ROLE holder may burn.
 func (s *SmartContract) Burn(sdk kalpsdk.TransactionContextInterface, account string, id uint64, amount uint64) error {
 	initialized, err := checkInitialized2(sdk)
 	if err != nil || !initialized {
-		return fmt.Errorf("failed to check if contract is already initialized: %v", err)
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
 	}
-	if account == "0x0" {
-		return fmt.Errorf("burn to the zero address")
-	}
-	err = authorizationHelper(sdk)
+	err = checkNotPaused(sdk)
 	if err != nil {
 		return err
 	}
+	if account == "0x0" {
+		return fmt.Errorf("burn to the zero address")
+	}
 	operator, err := sdk.GetClientIdentity().GetID()
 	if err != nil {
 		return fmt.Errorf("failed to get client id: %v", err)
 	}
-	err = removeBalance(sdk, account, []uint64{id}, []uint64{amount})
+	err = burnAuthorizationHelper(sdk, account, operator)
+	if err != nil {
+		return err
+	}
+	err = burnHelper(sdk, account, []uint64{id}, []uint64{amount})
 	if err != nil {
 		return err
 	}
-	transferSingleEvent := TransferSingle{operator, account, "0x0", id, amount}
+	transferSingleEvent := TransferSingle{Operator: operator, From: account, To: "0x0", ID: id, Value: amount}
 	return emitTransferSingle(sdk, transferSingleEvent)
 }
 
@@ -147,11 +173,23 @@ func (s *SmartContract) Burn(sdk kalpsdk.TransactionContextInterface, account st
 func (s *SmartContract) TransferFrom(sdk kalpsdk.TransactionContextInterface, sender string, recipient string, id uint64, amount uint64) error {
 	initialized, err := checkInitialized2(sdk)
 	if err != nil || !initialized {
-		return fmt.Errorf("failed to check if contract is already initialized: %v", err)
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	err = checkNotPaused(sdk)
+	if err != nil {
+		return err
+	}
+	err = checkTokenIDsNotFrozen(sdk, id)
+	if err != nil {
+		return err
 	}
 	if sender == recipient {
 		return fmt.Errorf("transfer to self")
 	}
+	err = denylist.CheckNotDenied(sdk, sender, recipient)
+	if err != nil {
+		return err
+	}
 	operator, err := sdk.GetClientIdentity().GetID()
 	if err != nil {
 		return fmt.Errorf("failed to get client id: %v", err)
@@ -159,7 +197,7 @@ func (s *SmartContract) TransferFrom(sdk kalpsdk.TransactionContextInterface, se
 	if operator != sender {
 		approved, err := _isApprovedForAll(sdk, sender, operator)
 		if err != nil || !approved {
-			return fmt.Errorf("caller is not owner nor is approved")
+			return kusherrors.Errorf(kusherrors.ErrUnauthorized, "caller is not owner nor is approved")
 		}
 	}
 	err = removeBalance(sdk, sender, []uint64{id}, []uint64{amount})
@@ -169,19 +207,24 @@ func (s *SmartContract) TransferFrom(sdk kalpsdk.TransactionContextInterface, se
 	if recipient == "0x0" {
 		return fmt.Errorf("transfer to the zero address")
 	}
-	err = add1Balance(sdk, sender, recipient, id, amount)
+	err = add1Balance(sdk, recipient, id, amount)
 	if err != nil {
 		return err
 	}
-	transferSingleEvent := TransferSingle{operator, sender, recipient, id, amount}
+	transferSingleEvent := TransferSingle{Operator: operator, From: sender, To: recipient, ID: id, Value: amount}
 	return emitTransferSingle(sdk, transferSingleEvent)
 }
 
-// BurnBatch destroys amount tokens of for each token type id from account.
+// BurnBatch destroys amount tokens of for each token type id from account. The holder, an approved
+// operator or a BURNER_ROLE holder may burn.
 func (s *SmartContract) BurnBatch(sdk kalpsdk.TransactionContextInterface, account string, ids []uint64, amounts []uint64) error {
 	initialized, err := checkInitialized2(sdk)
 	if err != nil || !initialized {
-		return fmt.Errorf("failed to check if contract is already initialized: %v", err)
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	err = checkNotPaused(sdk)
+	if err != nil {
+		return err
 	}
 	if account == "0x0" {
 		return fmt.Errorf("burn to the zero address")
@@ -189,19 +232,19 @@ func (s *SmartContract) BurnBatch(sdk kalpsdk.TransactionContextInterface, accou
 	if len(ids) != len(amounts) {
 		return fmt.Errorf("ids and amounts must have the same length")
 	}
-	err = authorizationHelper(sdk)
-	if err != nil {
-		return err
-	}
 	operator, err := sdk.GetClientIdentity().GetID()
 	if err != nil {
 		return fmt.Errorf("failed to get client id: %v", err)
 	}
-	err = removeBalance(sdk, account, ids, amounts)
+	err = burnAuthorizationHelper(sdk, account, operator)
 	if err != nil {
 		return err
 	}
-	transferBatchEvent := TransferBatch{operator, account, "0x0", ids, amounts}
+	err = burnHelper(sdk, account, ids, amounts)
+	if err != nil {
+		return err
+	}
+	transferBatchEvent := TransferBatch{Operator: operator, From: account, To: "0x0", IDs: ids, Values: amounts}
 	return emitTransferBatch(sdk, transferBatchEvent)
 }
 
@@ -209,11 +252,23 @@ func (s *SmartContract) BurnBatch(sdk kalpsdk.TransactionContextInterface, accou
 func (s *SmartContract) BatchTransferFrom(sdk kalpsdk.TransactionContextInterface, sender string, recipient string, ids []uint64, amounts []uint64) error {
 	initialized, err := checkInitialized2(sdk)
 	if err != nil || !initialized {
-		return fmt.Errorf("failed to check if contract is already initialized: %v", err)
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	err = checkNotPaused(sdk)
+	if err != nil {
+		return err
+	}
+	err = checkTokenIDsNotFrozen(sdk, ids...)
+	if err != nil {
+		return err
 	}
 	if sender == recipient {
 		return fmt.Errorf("transfer to self")
 	}
+	err = denylist.CheckNotDenied(sdk, sender, recipient)
+	if err != nil {
+		return err
+	}
 	if len(ids) != len(amounts) {
 		return fmt.Errorf("ids and amounts must have the same length")
 	}
@@ -224,7 +279,7 @@ func (s *SmartContract) BatchTransferFrom(sdk kalpsdk.TransactionContextInterfac
 	if operator != sender {
 		approved, err := _isApprovedForAll(sdk, sender, operator)
 		if err != nil || !approved {
-			return fmt.Errorf("caller is not owner nor is approved")
+			return kusherrors.Errorf(kusherrors.ErrUnauthorized, "caller is not owner nor is approved")
 		}
 	}
 	err = removeBalance(sdk, sender, ids, amounts)
@@ -244,12 +299,12 @@ func (s *SmartContract) BatchTransferFrom(sdk kalpsdk.TransactionContextInterfac
 	amountToSendKeys := sortedKeys(amountToSend)
 	for _, id := range amountToSendKeys {
 		amount := amountToSend[id]
-		err = add1Balance(sdk, sender, recipient, id, amount)
+		err = add1Balance(sdk, recipient, id, amount)
 		if err != nil {
 			return err
 		}
 	}
-	transferBatchEvent := TransferBatch{operator, sender, recipient, ids, amounts}
+	transferBatchEvent := TransferBatch{Operator: operator, From: sender, To: recipient, IDs: ids, Values: amounts}
 	return emitTransferBatch(sdk, transferBatchEvent)
 }
 
@@ -258,11 +313,48 @@ func (s *SmartContract) IsApprovedForAll(sdk kalpsdk.TransactionContextInterface
 	return _isApprovedForAll(sdk, account, operator)
 }
 
+// GetOperators returns the operators account ever approved with SetApprovalForAll, revoked ones
+// included with Approved false, so owners can review and revoke blanket approvals.
+func (s *SmartContract) GetOperators(sdk kalpsdk.TransactionContextInterface, account string) ([]*ApprovalForAll, error) {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	if account == "" {
+		return nil, fmt.Errorf("account must not be empty")
+	}
+
+	approvalIterator, err := sdk.GetStateByPartialCompositeKey(approvalPrefix1, []string{account})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get state for prefix %v: %v", approvalPrefix1, err)
+	}
+	defer approvalIterator.Close()
+
+	operators := []*ApprovalForAll{}
+	for approvalIterator.HasNext() {
+		queryResponse, err := approvalIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the next state for prefix %v: %v", approvalPrefix1, err)
+		}
+		_, compositeKeyParts, err := sdk.SplitCompositeKey(queryResponse.Key)
+		if err != nil || len(compositeKeyParts) != 2 {
+			return nil, fmt.Errorf("failed to split approval key %s: %v", queryResponse.Key, err)
+		}
+		var approved bool
+		err = json.Unmarshal(queryResponse.Value, &approved)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode approval JSON of operator %s for account %s: %v", compositeKeyParts[1], account, err)
+		}
+		operators = append(operators, &ApprovalForAll{Owner: account, Operator: compositeKeyParts[1], Approved: approved})
+	}
+	return operators, nil
+}
+
 // SetApprovalForAll returns true if operator is approved to transfer account's tokens.
 func (s *SmartContract) SetApprovalForAll(sdk kalpsdk.TransactionContextInterface, operator string, approved bool) error {
 	initialized, err := checkInitialized2(sdk)
 	if err != nil || !initialized {
-		return fmt.Errorf("failed to check if contract is already initialized: %v", err)
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
 	}
 	account, err := sdk.GetClientIdentity().GetID()
 	if err != nil {
@@ -271,24 +363,20 @@ func (s *SmartContract) SetApprovalForAll(sdk kalpsdk.TransactionContextInterfac
 	if account == operator {
 		return fmt.Errorf("setting approval status for self")
 	}
-	approvalForAllEvent := ApprovalForAll{account, operator, approved}
-	approvalForAllEventJSON, err := json.Marshal(approvalForAllEvent)
+	approvalForAllEvent := ApprovalForAll{Owner: account, Operator: operator, Approved: approved}
+	err = events.Emit(sdk, "ApprovalForAll", &approvalForAllEvent)
 	if err != nil {
-		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
-	}
-	err = sdk.SetEvent("ApprovalForAll", approvalForAllEventJSON)
-	if err != nil {
-		return fmt.Errorf("failed to set event: %v", err)
+		return err
 	}
 	approvalKey, err := sdk.CreateCompositeKey(approvalPrefix1, []string{account, operator})
 	if err != nil {
 		return fmt.Errorf("failed to create the composite key for prefix %s: %v", approvalPrefix1, err)
 	}
-	approvalJSON, err := json.Marshal(approved)
+	approvalJSON, err := canonical.Marshal(approved)
 	if err != nil {
 		return fmt.Errorf("failed to encode approval JSON of operator %s for account %s: %v", operator, account, err)
 	}
-	err = sdk.PutStateWithoutKYC(approvalKey, approvalJSON)
+	err = accesscontrol.PutState(sdk, approvalKey, approvalJSON)
 	if err != nil {
 		return err
 	}
@@ -299,7 +387,7 @@ func (s *SmartContract) SetApprovalForAll(sdk kalpsdk.TransactionContextInterfac
 func (s *SmartContract) BalanceOf(sdk kalpsdk.TransactionContextInterface, account string, id uint64) (uint64, error) {
 	initialized, err := checkInitialized2(sdk)
 	if err != nil || !initialized {
-		return 0, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+		return 0, kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
 	}
 	return balanceOfHelper(sdk, account, id)
 }
@@ -308,7 +396,7 @@ func (s *SmartContract) BalanceOf(sdk kalpsdk.TransactionContextInterface, accou
 func (s *SmartContract) BalanceOfBatch(sdk kalpsdk.TransactionContextInterface, accounts []string, ids []uint64) ([]uint64, error) {
 	initialized, err := checkInitialized2(sdk)
 	if err != nil || !initialized {
-		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
 	}
 	if len(accounts) != len(ids) {
 		return nil, fmt.Errorf("accounts and ids must have the same length")
@@ -327,7 +415,7 @@ func (s *SmartContract) BalanceOfBatch(sdk kalpsdk.TransactionContextInterface,
 func (s *SmartContract) ClientAccountBalance(sdk kalpsdk.TransactionContextInterface, id uint64) (uint64, error) {
 	initialized, err := checkInitialized2(sdk)
 	if err != nil || !initialized {
-		return 0, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+		return 0, kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
 	}
 	clientID, err := sdk.GetClientIdentity().GetID()
 	if err != nil {
@@ -340,7 +428,7 @@ func (s *SmartContract) ClientAccountBalance(sdk kalpsdk.TransactionContextInter
 func (s *SmartContract) ClientAccountID(sdk kalpsdk.TransactionContextInterface) (string, error) {
 	initialized, err := checkInitialized2(sdk)
 	if err != nil || !initialized {
-		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
+		return "", kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
 	}
 	clientAccountID, err := sdk.GetClientIdentity().GetID()
 	if err != nil {
@@ -349,44 +437,71 @@ func (s *SmartContract) ClientAccountID(sdk kalpsdk.TransactionContextInterface)
 	return clientAccountID, nil
 }
 
-// URI returns the URI
+// URI returns the URI of token type id set by SetTokenURI, or the global URI set by SetURI
 func (s *SmartContract) URI(sdk kalpsdk.TransactionContextInterface, id uint64) (string, error) {
 	initialized, err := checkInitialized2(sdk)
 	if err != nil || !initialized {
-		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
-	}
-	uriBytes, err := sdk.GetState(uriKey)
-	if err != nil || uriBytes == nil {
-		return "", fmt.Errorf("failed to get uri: %v", err)
+		return "", kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
 	}
-	return string(uriBytes), nil
+	return uriHelper(sdk, id)
 }
 
 // SetURI set the URI value
 func (s *SmartContract) SetURI(sdk kalpsdk.TransactionContextInterface, uri string) error {
 	initialized, err := checkInitialized2(sdk)
 	if err != nil || !initialized {
-		return fmt.Errorf("failed to check if contract is already initialized: %v", err)
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
 	}
-	err = authorizationHelper(sdk)
+	err = authorizationHelper(sdk, accesscontrol.AdminRole)
 	if err != nil {
 		return err
 	}
+	err = accesscontrol.CheckNotMultisig(sdk, accesscontrol.ActionSetURI)
+	if err != nil {
+		return err
+	}
+	err = accesscontrol.CheckNotTimelocked(sdk, accesscontrol.ActionSetURI)
+	if err != nil {
+		return err
+	}
+	err = checkStrictURI(sdk, uri)
+	if err != nil {
+		return err
+	}
+	return writeURI(sdk, uri)
+}
+
+// writeURI validates and stores the global URI, leaving authorization and strict mode to the
+// caller.
+func writeURI(sdk kalpsdk.TransactionContextInterface, uri string) error {
+	kycRequired, err := accesscontrol.KYCRequired(sdk)
+	if err != nil {
+		return err
+	}
+	return storeURI(sdk, uri, kycRequired)
+}
+
+// storeURI is writeURI with the KYC mode given, for Initialize, which cannot read it back yet.
+func storeURI(sdk kalpsdk.TransactionContextInterface, uri string, kycRequired bool) error {
 	if !strings.Contains(uri, "{id}") {
 		return fmt.Errorf("failed to set uri, uri should contain '{id}'")
 	}
-	err = sdk.PutStateWithoutKYC(uriKey, []byte(uri))
+	err := accesscontrol.WriteState(sdk, kycRequired, uriKey, []byte(uri))
 	if err != nil {
 		return fmt.Errorf("failed to set uri: %v", err)
 	}
-	return nil
+	err = audit.Record(sdk, "SetURI", uri)
+	if err != nil {
+		return err
+	}
+	return events.Emit(sdk, "URI", &URI{Value: uri, Global: true})
 }
 
 // Symbol returns an abbreviated name for fungible tokens in this contract.
 func (s *SmartContract) Symbol(sdk kalpsdk.TransactionContextInterface) (string, error) {
 	initialized, err := checkInitialized2(sdk)
 	if err != nil || !initialized {
-		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
+		return "", kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
 	}
 	bytes, err := sdk.GetState(symbolKey2)
 	if err != nil {
@@ -395,24 +510,73 @@ func (s *SmartContract) Symbol(sdk kalpsdk.TransactionContextInterface) (string,
 	return string(bytes), nil
 }
 
-// Set information for a token and initialize contract.
-func (s *SmartContract) Initialize(sdk kalpsdk.TransactionContextInterface, name string, symbol string) (bool, error) {
-	clientMSPID, err := sdk.GetClientIdentity().GetMSPID()
+// Name returns the name of the token collection.
+func (s *SmartContract) Name(sdk kalpsdk.TransactionContextInterface) (string, error) {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return "", kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	bytes, err := sdk.GetState(nameKey2)
+	if err != nil {
+		return "", fmt.Errorf("failed to get Name: %v", err)
+	}
+	return string(bytes), nil
+}
+
+// TokenInfo returns the collection's name, symbol and global URI in one query. It does not fail
+// on an uninitialized contract, so wallets can probe it.
+func (s *SmartContract) TokenInfo(sdk kalpsdk.TransactionContextInterface) (*TokenInfo, error) {
+	name, err := sdk.GetState(nameKey2)
 	if err != nil {
-		return false, fmt.Errorf("failed to get MSPID: %v", err)
+		return nil, fmt.Errorf("failed to get Name: %v", err)
 	}
-	if clientMSPID != minterMSPID {
-		return false, fmt.Errorf("client is not authorized to initialize contract")
+	symbol, err := sdk.GetState(symbolKey2)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Symbol: %v", err)
 	}
+	uri, err := sdk.GetState(uriKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get uri: %v", err)
+	}
+	return &TokenInfo{string(name), string(symbol), string(uri), name != nil}, nil
+}
+
+// Initialize sets up the collection from cfg, see config.InitConfig. BaseURI becomes the global URI
+// and must contain '{id}'; MaxSupply caps the supply of each token type. With Features.StrictURI
+// every URI set on the collection must also pass config.ValidateURI.
+func (s *SmartContract) Initialize(sdk kalpsdk.TransactionContextInterface, cfg config.InitConfig) (bool, error) {
 	bytes, err := sdk.GetState(nameKey2)
 	if err != nil || bytes != nil {
 		return false, fmt.Errorf("contract options are already set, client is not authorized to change them")
 	}
-	err = sdk.PutStateWithoutKYC(nameKey2, []byte(name))
+	// The global URI is kept under uriKey, where SetURI also writes it.
+	uri := cfg.BaseURI
+	cfg.BaseURI = ""
+	if cfg.Features.StrictURI && uri != "" {
+		err = config.ValidateURI(uri)
+		if err != nil {
+			return false, err
+		}
+	}
+	err = config.Initialize(sdk, cfg, 0)
+	if err != nil {
+		return false, err
+	}
+	if uri != "" {
+		err = storeURI(sdk, uri, cfg.Features.RequireKYC)
+		if err != nil {
+			return false, err
+		}
+	}
+	err = migration.SetVersion(sdk, migration.CurrentVersion(erc1155Upgrades))
+	if err != nil {
+		return false, err
+	}
+	err = accesscontrol.WriteState(sdk, cfg.Features.RequireKYC, nameKey2, []byte(cfg.Name))
 	if err != nil {
 		return false, fmt.Errorf("failed to set token name: %v", err)
 	}
-	err = sdk.PutStateWithoutKYC(symbolKey2, []byte(symbol))
+	err = accesscontrol.WriteState(sdk, cfg.Features.RequireKYC, symbolKey2, []byte(cfg.Symbol))
 	if err != nil {
 		return false, fmt.Errorf("failed to set symbol: %v", err)
 	}
@@ -421,13 +585,35 @@ func (s *SmartContract) Initialize(sdk kalpsdk.TransactionContextInterface, name
 
 // Helper Functions
 
-func authorizationHelper(sdk kalpsdk.TransactionContextInterface) error {
-	clientMSPID, err := sdk.GetClientIdentity().GetMSPID()
-	if err != nil || clientMSPID != minterMSPID {
-		return fmt.Errorf("client is not authorized to mint new tokens")
+// authorizationHelper fails unless the calling user holds role as a member of an authorized MSP.
+func authorizationHelper(sdk kalpsdk.TransactionContextInterface, role string) error {
+	err := accesscontrol.CheckOrgRole(sdk, role)
+	if err != nil {
+		return fmt.Errorf("client is not authorized: %v", err)
+	}
+	return nil
+}
+
+// burnAuthorizationHelper allows holders and their approved operators to burn, and falls back to
+// the BURNER_ROLE for administrative burns.
+func burnAuthorizationHelper(sdk kalpsdk.TransactionContextInterface, account string, operator string) error {
+	if operator == account {
+		return nil
+	}
+	approved, err := _isApprovedForAll(sdk, account, operator)
+	if err != nil {
+		return err
+	}
+	if approved {
+		return nil
+	}
+	err = authorizationHelper(sdk, accesscontrol.BurnerRole)
+	if err != nil {
+		return kusherrors.Errorf(kusherrors.ErrUnauthorized, "caller is not owner nor is approved")
 	}
 	return nil
 }
+
 func mintHelper(sdk kalpsdk.TransactionContextInterface, operator string, account string, id uint64, amount uint64) error {
 	if account == "0x0" {
 		return fmt.Errorf("mint to the zero address")
@@ -435,56 +621,42 @@ func mintHelper(sdk kalpsdk.TransactionContextInterface, operator string, accoun
 	if amount <= 0 {
 		return fmt.Errorf("mint amount must be a positive integer")
 	}
-	return add1Balance(sdk, operator, account, id, amount)
-}
-
-// add1Balance is a function that adds the specified amount of tokens to the balance of a recipient.
-// It takes a transaction context interface, sender address, recipient address, token ID, and amount as parameters.
-// The function creates a composite key using the recipient, token ID, and sender address.
-// It then retrieves the current balance from the world state using the composite key.
-// If the balance exists, it is parsed into a uint64 value.
-// The function adds the specified amount to the balance using the add1 function.
-// Finally, it updates the balance in the world state and returns any error that occurred during the process.
-func add1Balance(sdk kalpsdk.TransactionContextInterface, sender string, recipient string, id uint64, amount uint64) error {
-	idString := strconv.FormatUint(uint64(id), 10)
-	balanceKey, err := sdk.CreateCompositeKey(balancePrefix1, []string{recipient, idString, sender})
+	err := denylist.CheckNotDenied(sdk, account)
 	if err != nil {
-		return fmt.Errorf("failed to create the composite key for prefix %s: %v", balancePrefix1, err)
+		return err
 	}
-	balanceBytes, err := sdk.GetState(balanceKey)
+	err = add1Balance(sdk, account, id, amount)
 	if err != nil {
-		return fmt.Errorf("failed to read account %s from world state: %v", recipient, err)
-	}
-	balance := uint64(0)
-	if balanceBytes != nil {
-		balance, _ = strconv.ParseUint(string(balanceBytes), 10, 64)
+		return err
 	}
-	balance, err = add1(balance, amount)
+	err = increaseSupply(sdk, id, amount)
 	if err != nil {
 		return err
 	}
-	return sdk.PutStateWithoutKYC(balanceKey, []byte(strconv.FormatUint(uint64(balance), 10)))
+	return audit.Record(sdk, "Mint", account, strconv.FormatUint(id, 10), strconv.FormatUint(amount, 10))
 }
 
-// setBalance sets the balance of a specific token for a given sender and recipient.
-// It creates a composite key using the recipient, token ID, and sender, and stores the balance amount in the state.
-// Parameters:
-// - sdk: The transaction context interface for interacting with the blockchain.
-// - sender: The address of the sender.
-// - recipient: The address of the recipient.
-// - id: The ID of the token.
-// - amount: The amount to set as the balance.
-// Returns:
-// - error: An error if the composite key creation or state update fails.
-func setBalance(sdk kalpsdk.TransactionContextInterface, sender string, recipient string, id uint64, amount uint64) error {
-	idString := strconv.FormatUint(uint64(id), 10)
-	balanceKey, err := sdk.CreateCompositeKey(balancePrefix1, []string{recipient, idString, sender})
+// add1Balance adds amount to the balance of recipient in token type id, folding in the legacy
+// records of recipient.
+func add1Balance(sdk kalpsdk.TransactionContextInterface, recipient string, id uint64, amount uint64) error {
+	batch := statebatch.New(sdk)
+	balance, legacy, err := readBalance1(sdk, batch, recipient, id)
 	if err != nil {
-		return fmt.Errorf("failed to create the composite key for prefix %s: %v", balancePrefix1, err)
+		return err
 	}
-	return sdk.PutStateWithoutKYC(balanceKey, []byte(strconv.FormatUint(uint64(amount), 10)))
+	balance, err = add1(balance, amount)
+	if err != nil {
+		return err
+	}
+	err = writeBalance1(sdk, batch, recipient, id, balance, legacy)
+	if err != nil {
+		return err
+	}
+	return batch.Flush()
 }
 
+// removeBalance debits the balances of sender in token types ids by amounts, adding up the amounts
+// of repeated ids. Its writes go through one batch, flushed once every token type is debited.
 func removeBalance(sdk kalpsdk.TransactionContextInterface, sender string, ids []uint64, amounts []uint64) error {
 	// Create a map to store the necessary funds for each token ID
 	necessaryFunds := make(map[uint64]uint64)
@@ -499,149 +671,50 @@ func removeBalance(sdk kalpsdk.TransactionContextInterface, sender string, ids [
 		}
 	}
 
-	// Get the sorted keys of the necessary funds map
-	necessaryFundsKeys := sortedKeys(necessaryFunds)
-
-	// Iterate over the necessary funds keys
-	for _, tokenId := range necessaryFundsKeys {
-		// Get the needed amount for the current token ID
+	batch := statebatch.New(sdk)
+	for _, tokenId := range sortedKeys(necessaryFunds) {
 		neededAmount := necessaryFunds[tokenId]
-
-		// Convert the token ID to a string
-		idString := strconv.FormatUint(uint64(tokenId), 10)
-
-		// Initialize the partial balance and self recipient key variables
-		partialBalance := uint64(0)
-		selfRecipientKeyNeedsToBeRemoved := false
-		selfRecipientKey := ""
-
-		// Get the balance iterator for the sender and token ID
-		balanceIterator, err := sdk.GetStateByPartialCompositeKey(balancePrefix1, []string{sender, idString})
+		balance, legacy, err := readBalance1(sdk, batch, sender, tokenId)
 		if err != nil {
-			return fmt.Errorf("failed to get state for prefix %v: %v", balancePrefix1, err)
+			return err
 		}
-		defer balanceIterator.Close()
-
-		// Iterate over the balance iterator
-		for balanceIterator.HasNext() && partialBalance < neededAmount {
-			// Get the next query response
-			queryResponse, err := balanceIterator.Next()
-			if err != nil {
-				return fmt.Errorf("failed to get the next state for prefix %v: %v", balancePrefix1, err)
-			}
-
-			// Parse the part balance amount from the query response value
-			partBalAmount, _ := strconv.ParseUint(string(queryResponse.Value), 10, 64)
-
-			// add1 the part balance amount to the partial balance
-			partialBalance, err = add1(partialBalance, partBalAmount)
-			if err != nil {
-				return err
-			}
-
-			// Split the composite key into parts
-			_, compositeKeyParts, err := sdk.SplitCompositeKey(queryResponse.Key)
-			if err != nil {
-				return err
-			}
-
-			// Check if the sender is the recipient
-			if compositeKeyParts[2] == sender {
-				// Set the self recipient key needs to be removed flag and store the self recipient key
-				selfRecipientKeyNeedsToBeRemoved = true
-				selfRecipientKey = queryResponse.Key
-			} else {
-				// Delete the state for the query response key
-				err = sdk.DelStateWithoutKYC(queryResponse.Key)
-				if err != nil {
-					return fmt.Errorf("failed to delete the state of %v: %v", queryResponse.Key, err)
-				}
-			}
+		if balance < neededAmount {
+			return kusherrors.Errorf(kusherrors.ErrInsufficientBalance, "sender has insufficient funds for token %v, needed funds: %v, available fund: %v", tokenId, neededAmount, balance)
 		}
-
-		// Check if the partial balance is less than the needed amount
-		if partialBalance < neededAmount {
-			return fmt.Errorf("sender has insufficient funds for token %v, needed funds: %v, available fund: %v", tokenId, neededAmount, partialBalance)
-		} else if partialBalance > neededAmount {
-			// Calculate the remainder
-			remainder, err := sub1(partialBalance, neededAmount)
-			if err != nil {
-				return err
-			}
-
-			// Check if the self recipient key needs to be removed
-			if selfRecipientKeyNeedsToBeRemoved {
-				// Set the balance for the sender and token ID
-				err = setBalance(sdk, sender, sender, tokenId, remainder)
-				if err != nil {
-					return err
-				}
-			} else {
-				// add1 the balance for the sender and token ID
-				err = add1Balance(sdk, sender, sender, tokenId, remainder)
-				if err != nil {
-					return err
-				}
-			}
-		} else {
-			// Delete the self recipient key
-			err = sdk.DelStateWithoutKYC(selfRecipientKey)
-			if err != nil {
-				return fmt.Errorf("failed to delete the state of %v: %v", selfRecipientKey, err)
-			}
+		err = writeBalance1(sdk, batch, sender, tokenId, balance-neededAmount, legacy)
+		if err != nil {
+			return err
 		}
 	}
-
-	return nil
+	return batch.Flush()
 }
 
+// emitTransferSingle emits the event of a mint, burn or transfer and counts it in the metrics of
+// the contract.
 func emitTransferSingle(sdk kalpsdk.TransactionContextInterface, transferSingleEvent TransferSingle) error {
-	transferSingleEventJSON, err := json.Marshal(transferSingleEvent)
+	err := metrics.Count(sdk, transferSingleEvent.From, transferSingleEvent.To)
 	if err != nil {
-		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
-	}
-	err = sdk.SetEvent("TransferSingle", transferSingleEventJSON)
-	if err != nil {
-		return fmt.Errorf("failed to set event: %v", err)
+		return err
 	}
-	return nil
+	return events.Emit(sdk, "TransferSingle", &transferSingleEvent)
 }
 
+// emitTransferBatch emits the event of a batch mint, burn or transfer and counts it in the metrics
+// of the contract as one operation.
 func emitTransferBatch(sdk kalpsdk.TransactionContextInterface, transferBatchEvent TransferBatch) error {
-	transferBatchEventJSON, err := json.Marshal(transferBatchEvent)
-	if err != nil {
-		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
-	}
-	err = sdk.SetEvent("TransferBatch", transferBatchEventJSON)
+	err := metrics.Count(sdk, transferBatchEvent.From, transferBatchEvent.To)
 	if err != nil {
-		return fmt.Errorf("failed to set event: %v", err)
+		return err
 	}
-	return nil
+	return events.Emit(sdk, "TransferBatch", &transferBatchEvent)
 }
 
 func balanceOfHelper(sdk kalpsdk.TransactionContextInterface, account string, id uint64) (uint64, error) {
 	if account == "0x0" {
 		return 0, fmt.Errorf("balance query for the zero address")
 	}
-	idString := strconv.FormatUint(uint64(id), 10)
-	var balance uint64
-	balanceIterator, err := sdk.GetStateByPartialCompositeKey(balancePrefix1, []string{account, idString})
-	if err != nil {
-		return 0, fmt.Errorf("failed to get state for prefix %v: %v", balancePrefix1, err)
-	}
-	defer balanceIterator.Close()
-	for balanceIterator.HasNext() {
-		queryResponse, err := balanceIterator.Next()
-		if err != nil {
-			return 0, fmt.Errorf("failed to get the next state for prefix %v: %v", balancePrefix1, err)
-		}
-		balAmount, _ := strconv.ParseUint(string(queryResponse.Value), 10, 64)
-		balance, err = add1(balance, balAmount)
-		if err != nil {
-			return 0, err
-		}
-	}
-	return balance, nil
+	balance, _, err := readBalance1(sdk, nil, account, id)
+	return balance, err
 }
 
 func sortedKeys(m map[uint64]uint64) []uint64 {
@@ -656,8 +729,8 @@ func sortedKeys(m map[uint64]uint64) []uint64 {
 }
 
 func checkInitialized2(sdk kalpsdk.TransactionContextInterface) (bool, error) {
-	tokenName, err := sdk.GetState(nameKey2)
-	if err != nil || tokenName == nil {
+	initialized, err := config.IsInitialized(sdk, nameKey2)
+	if err != nil || !initialized {
 		return false, fmt.Errorf("failed to get token name: %v", err)
 	}
 	return true, nil
@@ -666,7 +739,7 @@ func checkInitialized2(sdk kalpsdk.TransactionContextInterface) (bool, error) {
 func add1(b uint64, q uint64) (uint64, error) {
 	sum := q + b
 	if sum < q {
-		return 0, fmt.Errorf("Math: addition overflow occurred %d + %d", b, q)
+		return 0, kusherrors.Errorf(kusherrors.ErrOverflow, "Math: addition overflow occurred %d + %d", b, q)
 	}
 	return sum, nil
 }
@@ -674,7 +747,7 @@ func add1(b uint64, q uint64) (uint64, error) {
 func sub1(b uint64, q uint64) (uint64, error) {
 	diff := b - q
 	if diff > b {
-		return 0, fmt.Errorf("Math: subtraction overflow occurred  %d - %d", b, q)
+		return 0, kusherrors.Errorf(kusherrors.ErrOverflow, "Math: subtraction overflow occurred  %d - %d", b, q)
 	}
 	return diff, nil
 }
@@ -685,7 +758,7 @@ func _isApprovedForAll(sdk kalpsdk.TransactionContextInterface, account string,
 	if err != nil {
 		return false, fmt.Errorf("failed to create the composite key for prefix %s: %v", approvalPrefix1, err)
 	}
-	approvalBytes, err := sdk.GetState(approvalKey)
+	approvalBytes, err := readcache.GetState(sdk, approvalKey)
 	if err != nil {
 		return false, fmt.Errorf("failed to get approval state for key %s: %v", approvalKey, err)
 	}
@@ -0,0 +1,97 @@
+package token
+
+import (
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+// BootstrapRoles grants every built-in role to the caller, who must belong to an authorized MSP.
+// Initialize does this for new contracts; contracts initialized before roles existed call it once,
+// passing their authorized MSPs if they never stored any, see accesscontrol.Bootstrap.
+func (s *SmartContract) BootstrapRoles(sdk kalpsdk.TransactionContextInterface, authorizedMSPs []string) error {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	return accesscontrol.Bootstrap(sdk, authorizedMSPs)
+}
+
+// GrantRole gives account role. The caller must hold the admin role of role.
+func (s *SmartContract) GrantRole(sdk kalpsdk.TransactionContextInterface, role string, account string) error {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	return accesscontrol.GrantRole(sdk, role, account)
+}
+
+// RevokeRole removes role from account. The caller must hold the admin role of role.
+func (s *SmartContract) RevokeRole(sdk kalpsdk.TransactionContextInterface, role string, account string) error {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	return accesscontrol.RevokeRole(sdk, role, account)
+}
+
+// RenounceRole removes role from the caller, whose ID must be passed as account.
+func (s *SmartContract) RenounceRole(sdk kalpsdk.TransactionContextInterface, role string, account string) error {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	return accesscontrol.RenounceRole(sdk, role, account)
+}
+
+// SetRoleAdmin makes adminRole the admin of role. The caller must hold the current admin role.
+func (s *SmartContract) SetRoleAdmin(sdk kalpsdk.TransactionContextInterface, role string, adminRole string) error {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	return accesscontrol.SetRoleAdmin(sdk, role, adminRole)
+}
+
+func (s *SmartContract) GetRoleAdmin(sdk kalpsdk.TransactionContextInterface, role string) (string, error) {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return "", kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	return accesscontrol.GetRoleAdmin(sdk, role)
+}
+
+func (s *SmartContract) HasRole(sdk kalpsdk.TransactionContextInterface, role string, account string) (bool, error) {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return false, kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	return accesscontrol.HasRole(sdk, role, account)
+}
+
+// AddAuthorizedOrg lets members of mspID exercise minting and burning roles. Only admins may call it.
+func (s *SmartContract) AddAuthorizedOrg(sdk kalpsdk.TransactionContextInterface, mspID string) error {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	return accesscontrol.AddAuthorizedOrg(sdk, mspID)
+}
+
+// RemoveAuthorizedOrg suspends the roles held by members of mspID. Only admins may call it.
+func (s *SmartContract) RemoveAuthorizedOrg(sdk kalpsdk.TransactionContextInterface, mspID string) error {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	return accesscontrol.RemoveAuthorizedOrg(sdk, mspID)
+}
+
+// ListAuthorizedOrgs returns the MSPs whose members may exercise minting and burning roles.
+func (s *SmartContract) ListAuthorizedOrgs(sdk kalpsdk.TransactionContextInterface) ([]string, error) {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	return accesscontrol.GetAuthorizedMSPs(sdk)
+}
@@ -0,0 +1,17 @@
+package token
+
+import (
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/audit"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+// GetAuditLog returns up to pageSize privileged actions taken on the collection starting at
+// bookmark, oldest first. Pass the returned bookmark to read the next page.
+func (s *SmartContract) GetAuditLog(sdk kalpsdk.TransactionContextInterface, bookmark string, pageSize int) (*audit.Page, error) {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	return audit.GetAuditLog(sdk, bookmark, pageSize)
+}
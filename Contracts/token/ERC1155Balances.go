@@ -0,0 +1,278 @@
+package token
+
+import (
+	"encoding/binary"
+	"fmt"
+	"strconv"
+
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/config"
+	"github.com/thekalpstudio/kush-go/internal/readcache"
+	"github.com/thekalpstudio/kush-go/internal/statebatch"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+	"github.com/thekalpstudio/kush-go/metrics"
+	"github.com/thekalpstudio/kush-go/migration"
+)
+
+// balanceEncodingV1 tags balance records holding the amount as 8 big-endian bytes. Records written
+// before it hold the amount as a decimal string, whose first byte is a digit, and are still read.
+const balanceEncodingV1 = 0x01
+
+const erc1155BalancesV2 = "ERC1155BalancesV2"
+
+// Compact merges up to the configured max balance keys of the legacy balance records account holds
+// in token type id, one per sender, into its balance key, which leaves the balance unchanged. It
+// returns true once no legacy records are left, so call it until it does on a balance transfers
+// reject as too fragmented. The ERC1155BalancesV2 upgrade does the same for every account.
+func (s *SmartContract) Compact(sdk kalpsdk.TransactionContextInterface, account string, id uint64) (bool, error) {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return false, kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	if account == "0x0" {
+		return false, fmt.Errorf("balance query for the zero address")
+	}
+	limit, err := config.GetMaxBalanceKeys(sdk)
+	if err != nil {
+		return false, err
+	}
+
+	batch := statebatch.New(sdk)
+	balance, err := _readBalanceKey(sdk, batch, account, id)
+	if err != nil {
+		return false, err
+	}
+	legacyBalance, legacy, more, err := _readLegacyBalances(sdk, batch, account, id, limit)
+	if err != nil {
+		return false, err
+	}
+	if len(legacy) == 0 {
+		return true, nil
+	}
+	balance, err = add1(balance, legacyBalance)
+	if err != nil {
+		return false, err
+	}
+	err = writeBalance1(sdk, batch, account, id, balance, legacy)
+	if err != nil {
+		return false, err
+	}
+	err = batch.Flush()
+	if err != nil {
+		return false, err
+	}
+	return !more, nil
+}
+
+// readBalance1 returns the balance of account in token type id and the keys of the legacy records
+// it includes. Reads go through batch, or through the read cache of the transaction if batch is nil.
+// Transactions writing through batch read at most the configured max balance keys of legacy
+// records and fail with ErrBalanceFragmented beyond.
+func readBalance1(sdk kalpsdk.TransactionContextInterface, batch *statebatch.Batch, account string, id uint64) (uint64, []string, error) {
+	balance, err := _readBalanceKey(sdk, batch, account, id)
+	if err != nil {
+		return 0, nil, err
+	}
+	limit := 0
+	if batch != nil {
+		limit, err = config.GetMaxBalanceKeys(sdk)
+		if err != nil {
+			return 0, nil, err
+		}
+	}
+	legacyBalance, legacy, more, err := _readLegacyBalances(sdk, batch, account, id, limit)
+	if err != nil {
+		return 0, nil, err
+	}
+	if more {
+		return 0, nil, kusherrors.Errorf(kusherrors.ErrBalanceFragmented, "balance of %s in token %d spans more than %d records, run Compact", account, id, limit)
+	}
+	balance, err = add1(balance, legacyBalance)
+	if err != nil {
+		return 0, nil, err
+	}
+	return balance, legacy, nil
+}
+
+// _readBalanceKey returns the balance stored under the balance key of account in token type id.
+func _readBalanceKey(sdk kalpsdk.TransactionContextInterface, batch *statebatch.Batch, account string, id uint64) (uint64, error) {
+	balanceKey, err := sdk.CreateCompositeKey(balancePrefix2, []string{account, strconv.FormatUint(id, 10)})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create the composite key for prefix %s: %v", balancePrefix2, err)
+	}
+	var balanceBytes []byte
+	if batch != nil {
+		balanceBytes, err = batch.Get(balanceKey)
+	} else {
+		balanceBytes, err = readcache.GetState(sdk, balanceKey)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read account %s from world state: %v", account, err)
+	}
+	if balanceBytes == nil {
+		return 0, nil
+	}
+	balance, err := decodeBalance1(balanceBytes)
+	if err != nil {
+		return 0, fmt.Errorf("failed to decode balance %s: %v", balanceKey, err)
+	}
+	return balance, nil
+}
+
+// _readLegacyBalances sums up to limit legacy balance records of account in token type id, all of
+// them if limit is 0, and returns their keys and whether more are left. Records already deleted in
+// batch are skipped. There are none once the ERC1155BalancesV2 upgrade has merged them.
+func _readLegacyBalances(sdk kalpsdk.TransactionContextInterface, batch *statebatch.Batch, account string, id uint64, limit int) (uint64, []string, bool, error) {
+	merged, err := _balancesMerged(sdk)
+	if err != nil || merged {
+		return 0, nil, false, err
+	}
+	attributes := []string{account, strconv.FormatUint(id, 10)}
+	var states []*queryresult.KV
+	more := false
+	if limit == 0 {
+		states, err = readcache.GetStateByPartialCompositeKey(sdk, balancePrefix1, attributes)
+		if err != nil {
+			return 0, nil, false, fmt.Errorf("failed to get state for prefix %v: %v", balancePrefix1, err)
+		}
+	} else {
+		balanceIterator, err := sdk.GetStateByPartialCompositeKey(balancePrefix1, attributes)
+		if err != nil {
+			return 0, nil, false, fmt.Errorf("failed to get state for prefix %v: %v", balancePrefix1, err)
+		}
+		defer balanceIterator.Close()
+		for balanceIterator.HasNext() {
+			if len(states) == limit {
+				more = true
+				break
+			}
+			queryResponse, err := balanceIterator.Next()
+			if err != nil {
+				return 0, nil, false, fmt.Errorf("failed to get the next state for prefix %v: %v", balancePrefix1, err)
+			}
+			states = append(states, queryResponse)
+		}
+	}
+
+	balance := uint64(0)
+	legacy := []string{}
+	for _, state := range states {
+		if batch != nil && batch.Deleted(state.Key) {
+			continue
+		}
+		amount, _ := decodeBalance1(state.Value)
+		balance, err = add1(balance, amount)
+		if err != nil {
+			return 0, nil, false, err
+		}
+		legacy = append(legacy, state.Key)
+	}
+	return balance, legacy, more, nil
+}
+
+// writeBalance1 stores balance as the balance of account in token type id through batch, deleting
+// the legacy records it includes, and records whether account still holds the token type.
+func writeBalance1(sdk kalpsdk.TransactionContextInterface, batch *statebatch.Batch, account string, id uint64, balance uint64, legacy []string) error {
+	idString := strconv.FormatUint(id, 10)
+	balanceKey, err := sdk.CreateCompositeKey(balancePrefix2, []string{account, idString})
+	if err != nil {
+		return fmt.Errorf("failed to create the composite key for prefix %s: %v", balancePrefix2, err)
+	}
+	for _, key := range legacy {
+		batch.Del(key)
+	}
+	if balance == 0 {
+		batch.Del(balanceKey)
+	} else {
+		batch.Put(balanceKey, encodeBalance1(balance))
+	}
+	return metrics.HoldToken(sdk, idString, account, balance > 0)
+}
+
+// _balancesMerged reports whether no legacy balance records are left: the contract was initialized
+// with single balance keys or upgraded past ERC1155BalancesV2.
+func _balancesMerged(sdk kalpsdk.TransactionContextInterface) (bool, error) {
+	version, err := migration.GetVersion(sdk)
+	if err != nil {
+		return false, err
+	}
+	return version >= migration.CurrentVersion(erc1155Upgrades), nil
+}
+
+// newMergeBalancesStep folds a legacy balance record into the balance key of its account and token
+// type and deletes it, recording the account as a holder of the token type. Reads do not see
+// writes of the same transaction, so the balances written are also tracked in memory across the
+// keys of one batch.
+func newMergeBalancesStep() migration.Step {
+	merged := make(map[string]uint64)
+
+	return func(sdk kalpsdk.TransactionContextInterface, key string, value []byte, dryRun bool) ([]string, error) {
+		_, parts, err := sdk.SplitCompositeKey(key)
+		if err != nil || len(parts) != 3 {
+			return nil, fmt.Errorf("failed to split balance key %s: %v", key, err)
+		}
+		amount, err := decodeBalance1(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode balance %s: %v", key, err)
+		}
+		balanceKey, err := sdk.CreateCompositeKey(balancePrefix2, parts[:2])
+		if err != nil {
+			return nil, fmt.Errorf("failed to create the composite key for prefix %s: %v", balancePrefix2, err)
+		}
+
+		balance, ok := merged[balanceKey]
+		if !ok {
+			balanceBytes, err := sdk.GetState(balanceKey)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read balance %s: %v", balanceKey, err)
+			}
+			if balanceBytes != nil {
+				balance, err = decodeBalance1(balanceBytes)
+				if err != nil {
+					return nil, fmt.Errorf("failed to decode balance %s: %v", balanceKey, err)
+				}
+			}
+		}
+		balance, err = add1(balance, amount)
+		if err != nil {
+			return nil, err
+		}
+		merged[balanceKey] = balance
+
+		if !dryRun {
+			err = sdk.PutStateWithoutKYC(balanceKey, encodeBalance1(balance))
+			if err != nil {
+				return nil, fmt.Errorf("failed to write balance %s: %v", balanceKey, err)
+			}
+			err = sdk.DelStateWithoutKYC(key)
+			if err != nil {
+				return nil, fmt.Errorf("failed to delete balance record %s: %v", key, err)
+			}
+			err = metrics.HoldToken(sdk, parts[1], parts[0], balance > 0)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return []string{balanceKey, key}, nil
+	}
+}
+
+// encodeBalance1 encodes a balance record in the balanceEncodingV1 format.
+func encodeBalance1(amount uint64) []byte {
+	value := make([]byte, 9)
+	value[0] = balanceEncodingV1
+	binary.BigEndian.PutUint64(value[1:], amount)
+	return value
+}
+
+// decodeBalance1 decodes a balance record in the balanceEncodingV1 format or as a decimal string.
+func decodeBalance1(value []byte) (uint64, error) {
+	if len(value) > 0 && value[0] == balanceEncodingV1 {
+		if len(value) != 9 {
+			return 0, fmt.Errorf("balance record of %d bytes is malformed", len(value))
+		}
+		return binary.BigEndian.Uint64(value[1:]), nil
+	}
+	return strconv.ParseUint(string(value), 10, 64)
+}
@@ -0,0 +1,109 @@
+package token
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/config"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+// GetConfig returns the configuration the collection was initialized with, as changed since.
+// BaseURI is the global URI.
+func (s *SmartContract) GetConfig(sdk kalpsdk.TransactionContextInterface) (*config.InitConfig, error) {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	return readERC1155Config(sdk)
+}
+
+// UpdateConfig changes the max supply, base URI or features, see config.Update. The max supply
+// cannot drop below the supply of any token type.
+func (s *SmartContract) UpdateConfig(sdk kalpsdk.TransactionContextInterface, cfg config.InitConfig) error {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	current, err := readERC1155Config(sdk)
+	if err != nil {
+		return err
+	}
+	return config.Update(sdk, current, cfg, erc1155ConfigHooks(sdk))
+}
+
+// applyERC1155Config applies the JSON config.InitConfig of an approved
+// accesscontrol.ActionUpdateConfig.
+func applyERC1155Config(sdk kalpsdk.TransactionContextInterface, updateJSON string) error {
+	var update config.InitConfig
+	err := json.Unmarshal([]byte(updateJSON), &update)
+	if err != nil {
+		return fmt.Errorf("failed to decode config: %v", err)
+	}
+	current, err := readERC1155Config(sdk)
+	if err != nil {
+		return err
+	}
+	return config.Apply(sdk, current, update, erc1155ConfigHooks(sdk))
+}
+
+// erc1155ConfigHooks keeps the max supply above the supply of every token type and the base URI
+// in the global URI.
+func erc1155ConfigHooks(sdk kalpsdk.TransactionContextInterface) config.Hooks {
+	return config.Hooks{
+		CheckMaxSupply: func(maxSupply *big.Int) error {
+			return checkSuppliesBelow(sdk, maxSupply)
+		},
+		SetBaseURI: func(uri string) error {
+			return writeURI(sdk, uri)
+		},
+	}
+}
+
+func readERC1155Config(sdk kalpsdk.TransactionContextInterface) (*config.InitConfig, error) {
+	nameBytes, err := sdk.GetState(nameKey2)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Name: %v", err)
+	}
+	symbolBytes, err := sdk.GetState(symbolKey2)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Symbol: %v", err)
+	}
+	uriBytes, err := sdk.GetState(uriKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get uri: %v", err)
+	}
+	cfg, err := config.Read(sdk, string(nameBytes), string(symbolBytes), 0)
+	if err != nil {
+		return nil, err
+	}
+	cfg.BaseURI = string(uriBytes)
+	return cfg, nil
+}
+
+// checkSuppliesBelow fails if the supply of any token type exceeds maxSupply.
+func checkSuppliesBelow(sdk kalpsdk.TransactionContextInterface, maxSupply *big.Int) error {
+	iterator, err := sdk.GetStateByPartialCompositeKey(supplyPrefix, []string{})
+	if err != nil {
+		return fmt.Errorf("failed to get state for prefix %v: %v", supplyPrefix, err)
+	}
+	defer iterator.Close()
+
+	for iterator.HasNext() {
+		queryResponse, err := iterator.Next()
+		if err != nil {
+			return fmt.Errorf("failed to get the next state for prefix %v: %v", supplyPrefix, err)
+		}
+		supply, err := strconv.ParseUint(string(queryResponse.Value), 10, 64)
+		if err != nil {
+			return fmt.Errorf("failed to decode supply %s: %v", queryResponse.Key, err)
+		}
+		if new(big.Int).SetUint64(supply).Cmp(maxSupply) > 0 {
+			return fmt.Errorf("max supply %s is below the supply of %d of a token type", maxSupply, supply)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package token
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+	"github.com/thekalpstudio/kush-go/denylist"
+	"github.com/thekalpstudio/kush-go/events"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+const frozenTokenPrefix = "frozen~tokenId"
+
+// TokenFreezeEvent is emitted as TokenIDFrozen or TokenIDUnfrozen.
+type TokenFreezeEvent = events.TokenFreezeEvent
+
+// FreezeTokenID halts transfers of token type id, e.g. while a duplicated item is investigated.
+// Other token types keep trading.
+func (s *SmartContract) FreezeTokenID(sdk kalpsdk.TransactionContextInterface, id uint64) error {
+	return setTokenIDFrozen(sdk, id, true)
+}
+
+// UnfreezeTokenID lifts a freeze placed by FreezeTokenID.
+func (s *SmartContract) UnfreezeTokenID(sdk kalpsdk.TransactionContextInterface, id uint64) error {
+	return setTokenIDFrozen(sdk, id, false)
+}
+
+func (s *SmartContract) IsTokenIDFrozen(sdk kalpsdk.TransactionContextInterface, id uint64) (bool, error) {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return false, kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	return isTokenIDFrozen(sdk, id)
+}
+
+func setTokenIDFrozen(sdk kalpsdk.TransactionContextInterface, id uint64, frozen bool) error {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	err = checkAdmin(sdk)
+	if err != nil {
+		return err
+	}
+
+	current, err := isTokenIDFrozen(sdk, id)
+	if err != nil {
+		return err
+	}
+	if current == frozen {
+		if frozen {
+			return fmt.Errorf("token %d is already frozen", id)
+		}
+		return fmt.Errorf("token %d is not frozen", id)
+	}
+
+	frozenKey, err := sdk.CreateCompositeKey(frozenTokenPrefix, []string{strconv.FormatUint(id, 10)})
+	if err != nil {
+		return fmt.Errorf("failed to create the composite key for prefix %s: %v", frozenTokenPrefix, err)
+	}
+	if frozen {
+		err = accesscontrol.PutState(sdk, frozenKey, []byte("true"))
+	} else {
+		err = accesscontrol.DelState(sdk, frozenKey)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update frozen state of token %d: %v", id, err)
+	}
+
+	sender, err := sdk.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+	eventName := "TokenIDUnfrozen"
+	if frozen {
+		eventName = "TokenIDFrozen"
+	}
+	return events.Emit(sdk, eventName, &TokenFreezeEvent{ID: id, Sender: sender})
+}
+
+func isTokenIDFrozen(sdk kalpsdk.TransactionContextInterface, id uint64) (bool, error) {
+	frozenKey, err := sdk.CreateCompositeKey(frozenTokenPrefix, []string{strconv.FormatUint(id, 10)})
+	if err != nil {
+		return false, fmt.Errorf("failed to create the composite key for prefix %s: %v", frozenTokenPrefix, err)
+	}
+	frozenBytes, err := sdk.GetState(frozenKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to read frozen state of token %d: %v", id, err)
+	}
+	return frozenBytes != nil, nil
+}
+
+// checkTokenIDsNotFrozen is called on every path that transfers tokens between accounts.
+func checkTokenIDsNotFrozen(sdk kalpsdk.TransactionContextInterface, ids ...uint64) error {
+	for _, id := range ids {
+		frozen, err := isTokenIDFrozen(sdk, id)
+		if err != nil {
+			return err
+		}
+		if frozen {
+			return fmt.Errorf("transfers of token %d are frozen", id)
+		}
+	}
+	return nil
+}
+
+// SetDenyList makes transfers from or to, and mints to, accounts on the deny list chaincode
+// chaincodeName fail. An empty name detaches the deny list.
+func (s *SmartContract) SetDenyList(sdk kalpsdk.TransactionContextInterface, chaincodeName string) error {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	return denylist.SetDenyList(sdk, chaincodeName)
+}
+
+func (s *SmartContract) GetDenyList(sdk kalpsdk.TransactionContextInterface) (string, error) {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return "", kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	return denylist.GetDenyList(sdk)
+}
@@ -0,0 +1,37 @@
+package token
+
+import (
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/journal"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+// SetJournalConfig switches the journal of rejected operations on or off, see journal.Config. The
+// caller must hold AdminRole.
+func (s *SmartContract) SetJournalConfig(sdk kalpsdk.TransactionContextInterface, cfg journal.Config) error {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	return journal.SetConfig(sdk, cfg)
+}
+
+func (s *SmartContract) GetJournalConfig(sdk kalpsdk.TransactionContextInterface) (*journal.Config, error) {
+	return journal.GetConfig(sdk)
+}
+
+// ReportRejection records that the transaction txID of account calling function with amount was
+// rejected with reason, see journal.Report. The caller must hold journal.ReporterRole.
+func (s *SmartContract) ReportRejection(sdk kalpsdk.TransactionContextInterface, txID string, account string, function string, amount string, reason string) (bool, error) {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return false, kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	return journal.Report(sdk, txID, account, function, amount, reason)
+}
+
+// GetRejections returns up to pageSize rejected operations of account starting at bookmark,
+// oldest first. An empty account reads those of every account.
+func (s *SmartContract) GetRejections(sdk kalpsdk.TransactionContextInterface, account string, bookmark string, pageSize int) (*journal.Page, error) {
+	return journal.GetRejections(sdk, account, bookmark, pageSize)
+}
@@ -0,0 +1,43 @@
+package token
+
+import (
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+// SetKYCRequired makes state writes of every function without an override require a KYC'd
+// caller, or stop requiring one.
+func (s *SmartContract) SetKYCRequired(sdk kalpsdk.TransactionContextInterface, required bool) error {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	return accesscontrol.SetKYCRequired(sdk, required)
+}
+
+// SetFunctionKYCRequired overrides the KYC mode of function, e.g. "Transfer".
+func (s *SmartContract) SetFunctionKYCRequired(sdk kalpsdk.TransactionContextInterface, function string, required bool) error {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	return accesscontrol.SetFunctionKYCRequired(sdk, function, required)
+}
+
+// RemoveFunctionKYCOverride makes function follow the contract-wide KYC mode again.
+func (s *SmartContract) RemoveFunctionKYCOverride(sdk kalpsdk.TransactionContextInterface, function string) error {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	return accesscontrol.RemoveFunctionKYCOverride(sdk, function)
+}
+
+func (s *SmartContract) GetKYCConfig(sdk kalpsdk.TransactionContextInterface) (*accesscontrol.KYCConfig, error) {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	return accesscontrol.GetKYCConfig(sdk)
+}
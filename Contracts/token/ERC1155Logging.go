@@ -0,0 +1,21 @@
+package token
+
+import (
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+	"github.com/thekalpstudio/kush-go/logging"
+)
+
+// SetLogLevel changes the level at which the peer evaluating it logs the transactions of the
+// collection, one of logging.Levels, until the chaincode restarts.
+func (s *SmartContract) SetLogLevel(sdk kalpsdk.TransactionContextInterface, level string) error {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	return logging.SetLogLevel(sdk, level)
+}
+
+func (s *SmartContract) GetLogLevel(sdk kalpsdk.TransactionContextInterface) (string, error) {
+	return logging.GetLogLevel(sdk)
+}
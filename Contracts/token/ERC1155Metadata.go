@@ -0,0 +1,111 @@
+package token
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+	"github.com/thekalpstudio/kush-go/canonical"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+const tokenMetadataPrefix = "metadata~tokenId"
+
+// tokenMetadataSchema follows the ERC-1155 metadata JSON schema. Free-form item data such as
+// rarity or stats goes into properties.
+const tokenMetadataSchema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["name"],
+  "properties": {
+    "name": {"type": "string", "minLength": 1, "maxLength": 256},
+    "description": {"type": "string", "maxLength": 4096},
+    "image": {"type": "string", "maxLength": 2048},
+    "decimals": {"type": "integer", "minimum": 0, "maximum": 18},
+    "properties": {"type": "object", "maxProperties": 100}
+  }
+}`
+
+var tokenMetadataSchemaLoader = gojsonschema.NewStringLoader(tokenMetadataSchema)
+
+// TokenMetadata is the structured metadata of a token type.
+type TokenMetadata struct {
+	Name        string                 `json:"name"`
+	Description string                 `json:"description,omitempty" metadata:",optional"`
+	Image       string                 `json:"image,omitempty" metadata:",optional"`
+	Decimals    int                    `json:"decimals,omitempty" metadata:",optional"`
+	Properties  map[string]interface{} `json:"properties,omitempty" metadata:",optional"`
+}
+
+// SetTokenMetadata validates metadataJSON against the ERC-1155 metadata schema and stores it for
+// token type id.
+func (s *SmartContract) SetTokenMetadata(sdk kalpsdk.TransactionContextInterface, id uint64, metadataJSON string) error {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	err = accesscontrol.CheckRole(sdk, accesscontrol.AdminRole)
+	if err != nil {
+		return fmt.Errorf("client is not authorized to set token metadata: %v", err)
+	}
+
+	result, err := gojsonschema.Validate(tokenMetadataSchemaLoader, gojsonschema.NewStringLoader(metadataJSON))
+	if err != nil {
+		return fmt.Errorf("failed to parse token metadata: %v", err)
+	}
+	if !result.Valid() {
+		problems := []string{}
+		for _, desc := range result.Errors() {
+			problems = append(problems, desc.String())
+		}
+		return fmt.Errorf("invalid token metadata: %s", strings.Join(problems, "; "))
+	}
+	metadata := new(TokenMetadata)
+	err = json.Unmarshal([]byte(metadataJSON), metadata)
+	if err != nil {
+		return fmt.Errorf("failed to decode token metadata: %v", err)
+	}
+	metadataBytes, err := canonical.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+
+	metadataKey, err := sdk.CreateCompositeKey(tokenMetadataPrefix, []string{strconv.FormatUint(id, 10)})
+	if err != nil {
+		return fmt.Errorf("failed to create the composite key for prefix %s: %v", tokenMetadataPrefix, err)
+	}
+	err = accesscontrol.PutState(sdk, metadataKey, metadataBytes)
+	if err != nil {
+		return fmt.Errorf("failed to set metadata of token %d: %v", id, err)
+	}
+	return nil
+}
+
+// GetTokenMetadata returns the structured metadata of token type id, or nil if none was set.
+func (s *SmartContract) GetTokenMetadata(sdk kalpsdk.TransactionContextInterface, id uint64) (*TokenMetadata, error) {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	metadataKey, err := sdk.CreateCompositeKey(tokenMetadataPrefix, []string{strconv.FormatUint(id, 10)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the composite key for prefix %s: %v", tokenMetadataPrefix, err)
+	}
+	metadataBytes, err := sdk.GetState(metadataKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get metadata of token %d: %v", id, err)
+	}
+	if metadataBytes == nil {
+		return nil, nil
+	}
+	metadata := new(TokenMetadata)
+	err = json.Unmarshal(metadataBytes, metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode metadata of token %d: %v", id, err)
+	}
+	return metadata, nil
+}
@@ -0,0 +1,27 @@
+package token
+
+import (
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+	"github.com/thekalpstudio/kush-go/metrics"
+)
+
+// GetMetrics returns the number of transfers, mints and burns of the collection and of the
+// accounts that ever held a token of it. Batch operations count once.
+func (s *SmartContract) GetMetrics(sdk kalpsdk.TransactionContextInterface) (*metrics.Metrics, error) {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	return metrics.GetMetrics(sdk)
+}
+
+// CompactMetrics folds the per-transaction metrics deltas into the totals, keeping GetMetrics
+// cheap. The caller must hold AdminRole.
+func (s *SmartContract) CompactMetrics(sdk kalpsdk.TransactionContextInterface) (int, error) {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return 0, kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	return metrics.CompactMetrics(sdk)
+}
@@ -0,0 +1,305 @@
+package token
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+	"github.com/thekalpstudio/kush-go/metrics"
+	"github.com/thekalpstudio/kush-go/migration"
+)
+
+const erc1155SupplyV1 = "ERC1155SupplyV1"
+
+// rebuiltSupplyPrefix marks token types whose supply the ERC1155SupplyV1 upgrade already reset.
+const rebuiltSupplyPrefix = "supply~rebuilt"
+
+// erc1155Upgrades moves state written by earlier versions of the contract to the current schema.
+var erc1155Upgrades = []migration.Upgrade{
+	{From: 0, Migration: func() migration.Migration {
+		rebuildSupply := newRebuildSupplyStep()
+		return migration.Migration{
+			Name: erc1155SupplyV1,
+			Phases: []migration.Phase{
+				{Prefix: balancePrefix1, Step: rebuildSupply},
+				{Prefix: balancePrefix2, Step: rebuildSupply},
+			},
+		}
+	}},
+	{From: 1, Migration: func() migration.Migration {
+		return migration.Migration{
+			Name: erc1155BalancesV2,
+			Phases: []migration.Phase{
+				{Prefix: balancePrefix1, Step: newMergeBalancesStep()},
+			},
+		}
+	}},
+}
+
+// GetVersion returns the schema version of the contract state.
+func (s *SmartContract) GetVersion(sdk kalpsdk.TransactionContextInterface) (int, error) {
+	return migration.GetVersion(sdk)
+}
+
+// Migrate runs the next batch of the upgrade from fromVersion, moving the state to fromVersion+1
+// once it is Done. Invoke it repeatedly until GetVersion reports the current version. Pause the
+// contract first: tokens minted or burned while an upgrade runs may be counted twice.
+func (s *SmartContract) Migrate(sdk kalpsdk.TransactionContextInterface, fromVersion int, batchSize int, dryRun bool) (*migration.Report, error) {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	err = accesscontrol.CheckRole(sdk, accesscontrol.AdminRole)
+	if err != nil {
+		return nil, fmt.Errorf("client is not authorized to run migrations: %v", err)
+	}
+	return migration.Migrate(sdk, erc1155Upgrades, fromVersion, batchSize, dryRun)
+}
+
+// PreviewMigration dry-runs up to batchSize keys of the upgrade from fromVersion after phase and
+// bookmark, those of the last Migrate report or of the previous preview. Evaluate it, do not
+// submit it.
+func (s *SmartContract) PreviewMigration(sdk kalpsdk.TransactionContextInterface, fromVersion int, phase int, bookmark string, batchSize int) (*migration.Report, error) {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	err = accesscontrol.CheckRole(sdk, accesscontrol.AdminRole)
+	if err != nil {
+		return nil, fmt.Errorf("client is not authorized to run migrations: %v", err)
+	}
+	return migration.PreviewUpgrade(sdk, erc1155Upgrades, fromVersion, phase, bookmark, batchSize)
+}
+
+// newRebuildSupplyStep recomputes the supply of every token type from its balances, so types minted
+// before supply tracking report a TotalSupply. It reads both the legacy balance records and the
+// balance keys, which every balance write since ERC1155BalancesV2 moves the legacy records into,
+// so both phases of the upgrade share one step. The first balance of a type resets its supply and
+// marks it rebuilt; reads do not see writes of the same transaction, so supplies are also tracked
+// in memory across the keys of one batch.
+func newRebuildSupplyStep() migration.Step {
+	supplies := make(map[uint64]uint64)
+
+	return func(sdk kalpsdk.TransactionContextInterface, key string, value []byte, dryRun bool) ([]string, error) {
+		_, parts, err := sdk.SplitCompositeKey(key)
+		if err != nil || (len(parts) != 2 && len(parts) != 3) {
+			return nil, fmt.Errorf("failed to split balance key %s: %v", key, err)
+		}
+		id, err := strconv.ParseUint(parts[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode token id of balance key %s: %v", key, err)
+		}
+		amount, err := decodeBalance1(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode balance %s: %v", key, err)
+		}
+
+		supply, ok := supplies[id]
+		if !ok {
+			rebuiltKey, err := sdk.CreateCompositeKey(rebuiltSupplyPrefix, []string{parts[1]})
+			if err != nil {
+				return nil, fmt.Errorf("failed to create the composite key for prefix %s: %v", rebuiltSupplyPrefix, err)
+			}
+			rebuiltBytes, err := sdk.GetState(rebuiltKey)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read rebuilt marker of token %d: %v", id, err)
+			}
+			if rebuiltBytes != nil {
+				supply, err = supplyHelper(sdk, id)
+				if err != nil {
+					return nil, err
+				}
+			} else if !dryRun {
+				err = sdk.PutStateWithoutKYC(rebuiltKey, []byte{'\u0000'})
+				if err != nil {
+					return nil, fmt.Errorf("failed to mark supply of token %d as rebuilt: %v", id, err)
+				}
+			}
+		}
+		supply, err = add1(supply, amount)
+		if err != nil {
+			return nil, err
+		}
+		supplies[id] = supply
+
+		supplyKey, err := sdk.CreateCompositeKey(supplyPrefix, []string{parts[1]})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create the composite key for prefix %s: %v", supplyPrefix, err)
+		}
+		if !dryRun {
+			err = setSupply(sdk, id, supply)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return []string{supplyKey}, nil
+	}
+}
+
+// erc1155ExportPrefixes are the keys ExportState reads: contract options, balances and legacy
+// balance records, operator approvals, supplies, token URIs and metadata.
+var erc1155ExportPrefixes = []string{
+	migration.SimpleKeys, balancePrefix2, balancePrefix1, approvalPrefix1, supplyPrefix, tokenURIPrefix, tokenMetadataPrefix,
+}
+
+// ExportState returns the next chunk of the token state, for backups, audits or moving the
+// collection to a new channel. Start with phase 0 and an empty bookmark and continue with those of
+// the returned chunk until it is Done. The caller must hold AdminRole.
+func (s *SmartContract) ExportState(sdk kalpsdk.TransactionContextInterface, phase int, bookmark string, pageSize int) (*migration.Chunk, error) {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	err = accesscontrol.CheckRole(sdk, accesscontrol.AdminRole)
+	if err != nil {
+		return nil, fmt.Errorf("client is not authorized to export state: %v", err)
+	}
+	return migration.Export(sdk, erc1155ExportPrefixes, phase, bookmark, pageSize)
+}
+
+// ImportState writes a chunk exported by ExportState of a previous deployment, so a collection
+// moves to a new channel without replaying its transactions. Initialize the collection with
+// ImportState set, import every chunk before the collection is opened to users, then call
+// SealImport. The supplies and holders of the token types are counted from the imported balances
+// rather than copied, and the activity metrics start over. Legacy balance records of a deployment
+// older than ERC1155BalancesV2 move the state back to the version of that upgrade, so Migrate
+// merges them and records their holders. The caller must hold AdminRole.
+func (s *SmartContract) ImportState(sdk kalpsdk.TransactionContextInterface, chunk migration.Chunk) (int, error) {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return 0, kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	err = accesscontrol.CheckRole(sdk, accesscontrol.AdminRole)
+	if err != nil {
+		return 0, fmt.Errorf("client is not authorized to import state: %v", err)
+	}
+	entries := make([]*migration.Entry, 0, len(chunk.Entries))
+	for _, entry := range chunk.Entries {
+		if entry.Prefix == supplyPrefix || (entry.Prefix == migration.SimpleKeys && metrics.IsDerivedKey(entry.Key)) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	chunk.Entries = entries
+	imported, err := migration.Import(sdk, erc1155ExportPrefixes, &chunk)
+	if err != nil {
+		return 0, err
+	}
+	err = countImportedBalances1(sdk, chunk.Entries)
+	if err != nil {
+		return 0, err
+	}
+	for _, entry := range chunk.Entries {
+		if entry.Prefix == balancePrefix1 {
+			err = migration.SetVersion(sdk, 1)
+			if err != nil {
+				return 0, err
+			}
+			break
+		}
+	}
+	return imported, nil
+}
+
+// countImportedBalances1 adds the imported balances to the supplies of their token types and
+// records the accounts holding a balance key as holders.
+func countImportedBalances1(sdk kalpsdk.TransactionContextInterface, entries []*migration.Entry) error {
+	imported := make(map[uint64]uint64)
+	ids := []uint64{}
+	for _, entry := range entries {
+		if entry.Prefix != balancePrefix2 && entry.Prefix != balancePrefix1 {
+			continue
+		}
+		_, parts, err := sdk.SplitCompositeKey(entry.Key)
+		if err != nil || len(parts) < 2 {
+			return fmt.Errorf("failed to split balance key %s: %v", entry.Key, err)
+		}
+		id, err := strconv.ParseUint(parts[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("failed to decode token id of balance key %s: %v", entry.Key, err)
+		}
+		amount, err := decodeBalance1(entry.Value)
+		if err != nil {
+			return fmt.Errorf("failed to decode balance %s: %v", entry.Key, err)
+		}
+		if entry.Prefix == balancePrefix2 && amount > 0 {
+			err = metrics.HoldToken(sdk, parts[1], parts[0], true)
+			if err != nil {
+				return err
+			}
+		}
+		if _, ok := imported[id]; !ok {
+			ids = append(ids, id)
+		}
+		imported[id], err = add1(imported[id], amount)
+		if err != nil {
+			return err
+		}
+	}
+	for _, id := range ids {
+		supply, err := supplyHelper(sdk, id)
+		if err != nil {
+			return err
+		}
+		supply, err = add1(supply, imported[id])
+		if err != nil {
+			return err
+		}
+		err = setSupply(sdk, id, supply)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SealImport refuses every later ImportState call. The caller must hold AdminRole.
+func (s *SmartContract) SealImport(sdk kalpsdk.TransactionContextInterface) error {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	err = accesscontrol.CheckRole(sdk, accesscontrol.AdminRole)
+	if err != nil {
+		return fmt.Errorf("client is not authorized to seal the state import: %v", err)
+	}
+	return migration.SealImport(sdk)
+}
+
+// erc1155Decoders decode the values GetRawStateRange reads.
+var erc1155Decoders = map[string]migration.Decoder{
+	migration.SimpleKeys: migration.DecodeText,
+	balancePrefix2: func(value []byte) (interface{}, error) {
+		return decodeBalance1(value)
+	},
+	balancePrefix1: func(value []byte) (interface{}, error) {
+		return decodeBalance1(value)
+	},
+	approvalPrefix1: migration.DecodeJSON,
+	supplyPrefix:    migration.DecodeText,
+	tokenURIPrefix:  migration.DecodeText,
+	tokenMetadataPrefix: func(value []byte) (interface{}, error) {
+		metadata := new(TokenMetadata)
+		err := json.Unmarshal(value, metadata)
+		return metadata, err
+	},
+}
+
+// GetRawStateRange returns up to pageSize raw keys under prefix, a composite key object type or ""
+// for the simple keys, starting at bookmark, decoding the values of the known prefixes. It is
+// meant for debugging orphaned keys without access to the state database. The caller must hold
+// AdminRole.
+func (s *SmartContract) GetRawStateRange(sdk kalpsdk.TransactionContextInterface, prefix string, bookmark string, pageSize int) (*migration.RawPage, error) {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	err = accesscontrol.CheckRole(sdk, accesscontrol.AdminRole)
+	if err != nil {
+		return nil, fmt.Errorf("client is not authorized to inspect state: %v", err)
+	}
+	return migration.RawRange(sdk, prefix, erc1155Decoders, bookmark, pageSize)
+}
@@ -0,0 +1,175 @@
+package token
+
+import (
+	"testing"
+
+	"github.com/thekalpstudio/kush-go/migration"
+	"github.com/thekalpstudio/kush-go/testutil"
+)
+
+// seedLegacyBalances rolls the collection of l back to schema version 0, with legacy balance
+// records for bob and dave and no supplies.
+func seedLegacyBalances(t *testing.T, l *testutil.Ledger) {
+	t.Helper()
+	testutil.MustRun(t, l, admin, "Legacy", func(ctx *testutil.Context) error {
+		err := migration.SetVersion(ctx, 0)
+		if err != nil {
+			return err
+		}
+		for _, holder := range []struct {
+			user   string
+			amount uint64
+		}{{"bob", 30}, {"dave", 5}} {
+			legacyKey, err := ctx.CreateCompositeKey(balancePrefix1, []string{testutil.ClientID(holder.user), "1", testutil.ClientID(admin)})
+			if err != nil {
+				return err
+			}
+			err = ctx.PutStateWithoutKYC(legacyKey, encodeBalance1(holder.amount))
+			if err != nil {
+				return err
+			}
+		}
+		// supplies were not tracked before ERC1155SupplyV1
+		err = setSupply(ctx, 1, 0)
+		if err != nil {
+			return err
+		}
+		return setSupply(ctx, 2, 0)
+	})
+}
+
+// TestERC1155UpgradeLegacyBalances upgrades a collection holding legacy balance records, some of
+// which a transfer moved into balance keys before the upgrade ran.
+func TestERC1155UpgradeLegacyBalances(t *testing.T) {
+	l, s := newERC1155(t)
+	seedLegacyBalances(t, l)
+	testutil.MustRun(t, l, "bob", "TransferFrom", func(ctx *testutil.Context) error {
+		return s.TransferFrom(ctx, testutil.ClientID("bob"), testutil.ClientID("carol"), 1, 10)
+	})
+
+	for fromVersion := 0; fromVersion < migration.CurrentVersion(erc1155Upgrades); fromVersion++ {
+		for done := false; !done; {
+			testutil.MustRun(t, l, admin, "Migrate", func(ctx *testutil.Context) error {
+				report, err := s.Migrate(ctx, fromVersion, 1, false)
+				if err == nil {
+					done = report.Done
+				}
+				return err
+			})
+		}
+	}
+
+	ctx := l.Tx(admin, "TotalSupply")
+	for id, want := range map[uint64]uint64{1: 135, 2: 50} {
+		supply, err := s.TotalSupply(ctx, id)
+		if err != nil || supply != want {
+			t.Fatalf("supply of token %d is %d, want %d: %v", id, supply, want, err)
+		}
+	}
+	for user, want := range map[string]uint64{admin: 100, "bob": 20, "carol": 10, "dave": 5} {
+		if balance := erc1155Balance(t, l, s, user, 1); balance != want {
+			t.Fatalf("balance of %s is %d, want %d", user, balance, want)
+		}
+	}
+	holders, err := s.HolderCount(ctx, 1)
+	if err != nil || holders != 4 {
+		t.Fatalf("token 1 has %d holders, want 4: %v", holders, err)
+	}
+	for _, key := range l.Keys() {
+		objectType, _, err := ctx.SplitCompositeKey(key)
+		if err == nil && objectType == balancePrefix1 {
+			t.Fatalf("legacy balance record %s is left", key)
+		}
+	}
+}
+
+// TestERC1155PreviewMigration pages a dry run through every key of the upgrade without writing.
+func TestERC1155PreviewMigration(t *testing.T) {
+	l, s := newERC1155(t)
+	seedLegacyBalances(t, l)
+	keys := 0
+	ctx := l.Tx(admin, "Keys")
+	for _, key := range l.Keys() {
+		objectType, _, err := ctx.SplitCompositeKey(key)
+		if err == nil && (objectType == balancePrefix1 || objectType == balancePrefix2) {
+			keys++
+		}
+	}
+	state := len(l.Keys())
+
+	previewed := 0
+	for phase, bookmark, done := 0, "", false; !done; {
+		report, err := s.PreviewMigration(l.Tx(admin, "PreviewMigration"), 0, phase, bookmark, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !report.Done && report.Processed != 1 {
+			t.Fatalf("preview processed %d keys, want 1", report.Processed)
+		}
+		previewed += report.Processed
+		phase, bookmark, done = report.Phase, report.Bookmark, report.Done
+	}
+	if previewed != keys {
+		t.Fatalf("preview processed %d keys, want %d", previewed, keys)
+	}
+	if len(l.Keys()) != state {
+		t.Fatalf("preview wrote %d keys", len(l.Keys())-state)
+	}
+	if version, err := s.GetVersion(l.Tx(admin, "GetVersion")); err != nil || version != 0 {
+		t.Fatalf("state is at version %d, want 0: %v", version, err)
+	}
+}
+
+func TestERC1155ImportState(t *testing.T) {
+	source, s := newERC1155(t)
+	testutil.MustRun(t, source, admin, "TransferFrom", func(ctx *testutil.Context) error {
+		return s.TransferFrom(ctx, testutil.ClientID(admin), testutil.ClientID("bob"), 1, 40)
+	})
+	chunks := []*migration.Chunk{}
+	for phase, bookmark, done := 0, "", false; !done; {
+		chunk, err := s.ExportState(source.Tx(admin, "ExportState"), phase, bookmark, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		chunks = append(chunks, chunk)
+		phase, bookmark, done = chunk.Phase, chunk.Bookmark, chunk.Done
+	}
+
+	l := testutil.NewLedger()
+	testutil.MustRun(t, l, admin, "Initialize", func(ctx *testutil.Context) error {
+		cfg := testConfig()
+		cfg.ImportState = true
+		_, err := s.Initialize(ctx, cfg)
+		return err
+	})
+	for _, chunk := range chunks {
+		chunk := chunk
+		testutil.MustRun(t, l, admin, "ImportState", func(ctx *testutil.Context) error {
+			_, err := s.ImportState(ctx, *chunk)
+			return err
+		})
+	}
+	testutil.MustRun(t, l, admin, "SealImport", func(ctx *testutil.Context) error {
+		return s.SealImport(ctx)
+	})
+
+	for user, want := range map[string]uint64{admin: 60, "bob": 40} {
+		if balance := erc1155Balance(t, l, s, user, 1); balance != want {
+			t.Fatalf("balance of %s is %d, want %d", user, balance, want)
+		}
+	}
+	ctx := l.Tx(admin, "TotalSupply")
+	for id, want := range map[uint64]uint64{1: 100, 2: 50} {
+		supply, err := s.TotalSupply(ctx, id)
+		if err != nil || supply != want {
+			t.Fatalf("supply of token %d is %d, want %d: %v", id, supply, want, err)
+		}
+	}
+	if holders, err := s.HolderCount(ctx, 1); err != nil || holders != 2 {
+		t.Fatalf("token 1 has %d holders, want 2: %v", holders, err)
+	}
+	testutil.CheckErr(t, testutil.Run(l, admin, "ImportState", func(ctx *testutil.Context) error {
+		_, err := s.ImportState(ctx, *chunks[0])
+		return err
+	}), true, "")
+}
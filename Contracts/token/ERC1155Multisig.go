@@ -0,0 +1,157 @@
+package token
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+	"github.com/thekalpstudio/kush-go/denylist"
+	"github.com/thekalpstudio/kush-go/guardian"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+// SetMultisig makes accesscontrol.MultisigActions, and mints beyond mintThreshold tokens within
+// mintWindow seconds, require threshold approvals of signers. An empty mintThreshold leaves mints
+// to the MINTER_ROLE, and a mintWindow of 0 stands for accesscontrol.DefaultMintWindow. Only the
+// owner may enable it; afterwards the configuration changes through an
+// accesscontrol.ActionSetMultisig proposal.
+func (s *SmartContract) SetMultisig(sdk kalpsdk.TransactionContextInterface, signers []string, threshold int, mintThreshold string, mintWindow int64) error {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	return accesscontrol.SetMultisig(sdk, &accesscontrol.MultisigConfig{Signers: signers, Threshold: threshold, MintThreshold: mintThreshold, MintWindow: mintWindow})
+}
+
+// GetMultisig returns the multisig configuration, or nil if multisig approval is disabled.
+func (s *SmartContract) GetMultisig(sdk kalpsdk.TransactionContextInterface) (*accesscontrol.MultisigConfig, error) {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	return accesscontrol.GetMultisig(sdk)
+}
+
+// Propose records a proposal for action with args, valid for ttl seconds. See
+// accesscontrol.Proposal for the arguments of each action.
+func (s *SmartContract) Propose(sdk kalpsdk.TransactionContextInterface, action string, args []string, ttl int64) (*accesscontrol.Proposal, error) {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	return accesscontrol.Propose(sdk, action, args, ttl)
+}
+
+// ApproveProposal adds the caller's approval to proposal id.
+func (s *SmartContract) ApproveProposal(sdk kalpsdk.TransactionContextInterface, id string) (*accesscontrol.Proposal, error) {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	return accesscontrol.ApproveProposal(sdk, id)
+}
+
+func (s *SmartContract) GetProposal(sdk kalpsdk.TransactionContextInterface, id string) (*accesscontrol.Proposal, error) {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	return accesscontrol.GetProposal(sdk, id)
+}
+
+// GetPendingProposals returns the proposals that are neither executed nor expired.
+func (s *SmartContract) GetPendingProposals(sdk kalpsdk.TransactionContextInterface) ([]*accesscontrol.Proposal, error) {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	return accesscontrol.GetPendingProposals(sdk)
+}
+
+// ExecuteProposal executes proposal id once enough signers approved it, or schedules it while
+// the timelock covers its action.
+func (s *SmartContract) ExecuteProposal(sdk kalpsdk.TransactionContextInterface, id string) (*accesscontrol.Proposal, error) {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	return accesscontrol.ExecuteProposal(sdk, id, erc1155Executor(sdk))
+}
+
+// erc1155Executor executes the ERC1155 actions of approved proposals and timelock operations.
+func erc1155Executor(sdk kalpsdk.TransactionContextInterface) accesscontrol.Executor {
+	return func(action string, args []string) error {
+		switch action {
+		case accesscontrol.ActionMint:
+			if len(args) != 3 {
+				return fmt.Errorf("%s takes a token id, an account and an amount", action)
+			}
+			err := checkNotPaused(sdk)
+			if err != nil {
+				return err
+			}
+			id, err := strconv.ParseUint(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid token id %q: %v", args[0], err)
+			}
+			account := args[1]
+			amount, err := strconv.ParseUint(args[2], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid amount %q: %v", args[2], err)
+			}
+			operator, err := sdk.GetClientIdentity().GetID()
+			if err != nil {
+				return fmt.Errorf("failed to get client id: %v", err)
+			}
+			err = mintHelper(sdk, operator, account, id, amount)
+			if err != nil {
+				return err
+			}
+			return emitTransferSingle(sdk, TransferSingle{Operator: operator, From: "0x0", To: account, ID: id, Value: amount})
+		case accesscontrol.ActionPause, accesscontrol.ActionUnpause:
+			return writePaused(sdk, action == accesscontrol.ActionPause)
+		case accesscontrol.ActionSetURI:
+			if len(args) != 1 {
+				return fmt.Errorf("%s takes a uri", action)
+			}
+			err := checkStrictURI(sdk, args[0])
+			if err != nil {
+				return err
+			}
+			return writeURI(sdk, args[0])
+		case accesscontrol.ActionSetTokenURI:
+			if len(args) != 2 {
+				return fmt.Errorf("%s takes a token id and a uri", action)
+			}
+			id, err := strconv.ParseUint(args[0], 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid token id %q: %v", args[0], err)
+			}
+			if args[1] != "" {
+				err = checkStrictURI(sdk, args[1])
+				if err != nil {
+					return err
+				}
+			}
+			return writeTokenURI(sdk, id, args[1])
+		case accesscontrol.ActionUpdateConfig:
+			if len(args) != 1 {
+				return fmt.Errorf("%s takes a JSON config", action)
+			}
+			return applyERC1155Config(sdk, args[0])
+		case accesscontrol.ActionSetGuardian:
+			if len(args) != 1 {
+				return fmt.Errorf("%s takes a chaincode name", action)
+			}
+			return guardian.WriteGuardian(sdk, args[0])
+		case accesscontrol.ActionSetDenyList:
+			if len(args) != 1 {
+				return fmt.Errorf("%s takes a chaincode name", action)
+			}
+			return denylist.WriteDenyList(sdk, args[0])
+		default:
+			return fmt.Errorf("action %s is not supported by this contract", action)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package token
+
+import (
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+// Owner returns the account that owns the contract and holds its ADMIN_ROLE.
+func (s *SmartContract) Owner(sdk kalpsdk.TransactionContextInterface) (string, error) {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return "", kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	return accesscontrol.Owner(sdk)
+}
+
+// PendingOwner returns the account nominated by TransferOwnership, if any.
+func (s *SmartContract) PendingOwner(sdk kalpsdk.TransactionContextInterface) (string, error) {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return "", kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	return accesscontrol.PendingOwner(sdk)
+}
+
+// TransferOwnership nominates newOwner as the next owner. Ownership only moves once newOwner calls
+// AcceptOwnership, so a mistyped account cannot take over the contract.
+func (s *SmartContract) TransferOwnership(sdk kalpsdk.TransactionContextInterface, newOwner string) error {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	return accesscontrol.TransferOwnership(sdk, newOwner)
+}
+
+// AcceptOwnership completes a transfer started by TransferOwnership. Only the pending owner may
+// call it.
+func (s *SmartContract) AcceptOwnership(sdk kalpsdk.TransactionContextInterface) error {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	return accesscontrol.AcceptOwnership(sdk)
+}
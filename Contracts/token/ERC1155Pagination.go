@@ -0,0 +1,63 @@
+package token
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+	"github.com/thekalpstudio/kush-go/pagination"
+)
+
+// BalancePage is one page of a paginated balance. Until the ERC1155BalancesV2 upgrade has run a
+// balance is partly kept as one legacy entry per sender that credited the account, so callers add
+// up the balances of the pages; Bookmark is empty on the last page.
+type BalancePage struct {
+	Balance  uint64 `json:"balance"`
+	Bookmark string `json:"bookmark"`
+}
+
+// BalanceOfWithPagination sums up to pageSize legacy balance entries of account in token type id
+// starting at bookmark, like BalanceOf, for accounts credited by many senders. The first page also
+// holds the balance stored under the balance key.
+func (s *SmartContract) BalanceOfWithPagination(sdk kalpsdk.TransactionContextInterface, account string, id uint64, bookmark string, pageSize int) (*BalancePage, error) {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	if account == "0x0" {
+		return nil, fmt.Errorf("balance query for the zero address")
+	}
+	states, next, err := pagination.ByPartialCompositeKey(sdk, balancePrefix1, []string{account, strconv.FormatUint(id, 10)}, bookmark, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	page := &BalancePage{Bookmark: next}
+	if bookmark == "" {
+		balanceKey, err := sdk.CreateCompositeKey(balancePrefix2, []string{account, strconv.FormatUint(id, 10)})
+		if err != nil {
+			return nil, fmt.Errorf("failed to create the composite key for prefix %s: %v", balancePrefix2, err)
+		}
+		balanceBytes, err := sdk.GetState(balanceKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read account %s from world state: %v", account, err)
+		}
+		if balanceBytes != nil {
+			page.Balance, err = decodeBalance1(balanceBytes)
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode balance %s: %v", balanceKey, err)
+			}
+		}
+	}
+	for _, state := range states {
+		amount, err := decodeBalance1(state.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode balance %s: %v", state.Key, err)
+		}
+		page.Balance, err = add1(page.Balance, amount)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
@@ -0,0 +1,44 @@
+package token
+
+import (
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/guardian"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+// Pause halts minting, burning and transfers until Unpause is called. It shares its state and
+// Paused/Unpaused events with the ERC20 contract's pause.
+func (s *SmartContract) Pause(sdk kalpsdk.TransactionContextInterface) error {
+	return setPaused(sdk, true)
+}
+
+// Unpause resumes token movement after Pause.
+func (s *SmartContract) Unpause(sdk kalpsdk.TransactionContextInterface) error {
+	return setPaused(sdk, false)
+}
+
+func (s *SmartContract) IsPaused(sdk kalpsdk.TransactionContextInterface) (bool, error) {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return false, kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	return isPaused(sdk)
+}
+
+// SetGuardian makes the contract also stop while the guardian chaincode chaincodeName is paused.
+// An empty name detaches the guardian.
+func (s *SmartContract) SetGuardian(sdk kalpsdk.TransactionContextInterface, chaincodeName string) error {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	return guardian.SetGuardian(sdk, chaincodeName)
+}
+
+func (s *SmartContract) GetGuardian(sdk kalpsdk.TransactionContextInterface) (string, error) {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return "", kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	return guardian.GetGuardian(sdk)
+}
@@ -0,0 +1,209 @@
+package token
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+	"github.com/thekalpstudio/kush-go/canonical"
+	"github.com/thekalpstudio/kush-go/events"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+// promotionPrefix keys the promotions waiting to be claimed, by ERC721 token id.
+const promotionPrefix = "promotion~tokenId"
+
+// Promoted is emitted when a unique token leaves this contract for an ERC721 chaincode. Events of
+// the invoked ERC721 chaincode are not kept by Fabric, so this is the only record of the mint.
+type Promoted = events.Promoted
+
+// Promotion is a promoted token waiting to be minted on its ERC721 chaincode. Metadata is the
+// ERC721 metadata JSON, "" when the token had none.
+type Promotion struct {
+	Account      string `json:"account"`
+	ID           uint64 `json:"id"`
+	NftChaincode string `json:"nftChaincode"`
+	TokenId      string `json:"tokenId"`
+	URI          string `json:"uri"`
+	Metadata     string `json:"metadata"`
+}
+
+// promotedMetadata is the ERC721 metadata a promoted token carries over.
+type promotedMetadata struct {
+	Name        string              `json:"name"`
+	Description string              `json:"description,omitempty"`
+	Image       string              `json:"image,omitempty"`
+	Attributes  []promotedAttribute `json:"attributes,omitempty"`
+}
+
+type promotedAttribute struct {
+	TraitType string      `json:"trait_type"`
+	Value     interface{} `json:"value"`
+}
+
+// Promote burns the caller's token of type id, which must have a supply of exactly 1, and records
+// its promotion to the ERC721 chaincode nftChaincode under token id "<id>", with the token's URI
+// and its metadata. The caller then mints it in a transaction of its own through MintPromoted of
+// nftChaincode, which must have named this chaincode with SetPromoter and claims the promotion
+// back through ClaimPromotion. Fabric rejects a chaincode calling back into its caller, and a
+// transaction does not read its own writes, so the mint cannot happen here.
+func (s *SmartContract) Promote(sdk kalpsdk.TransactionContextInterface, nftChaincode string, id uint64) (string, error) {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return "", kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	err = checkNotPaused(sdk)
+	if err != nil {
+		return "", err
+	}
+	err = checkTokenIDsNotFrozen(sdk, id)
+	if err != nil {
+		return "", err
+	}
+	if nftChaincode == "" {
+		return "", fmt.Errorf("nft chaincode must be set")
+	}
+
+	account, err := sdk.GetClientIdentity().GetID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get client id: %v", err)
+	}
+	supply, err := supplyHelper(sdk, id)
+	if err != nil {
+		return "", err
+	}
+	if supply != 1 {
+		return "", fmt.Errorf("only tokens with a supply of 1 can be promoted, token %d has %d", id, supply)
+	}
+	balance, err := balanceOfHelper(sdk, account, id)
+	if err != nil {
+		return "", err
+	}
+	if balance != 1 {
+		return "", fmt.Errorf("client does not hold token %d", id)
+	}
+
+	uri, err := uriHelper(sdk, id)
+	if err != nil {
+		return "", err
+	}
+	// clients substitute {id} with the zero padded hex id, which the ERC721 URI cannot defer
+	uri = strings.ReplaceAll(uri, "{id}", fmt.Sprintf("%064x", id))
+	metadataJSON, err := promotedMetadataJSON(sdk, id)
+	if err != nil {
+		return "", err
+	}
+
+	err = burnHelper(sdk, account, []uint64{id}, []uint64{1})
+	if err != nil {
+		return "", err
+	}
+	tokenId := strconv.FormatUint(id, 10)
+	promotionKey, err := sdk.CreateCompositeKey(promotionPrefix, []string{tokenId})
+	if err != nil {
+		return "", fmt.Errorf("failed to create the composite key for prefix %s: %v", promotionPrefix, err)
+	}
+	promotionJSON, err := canonical.Marshal(Promotion{account, id, nftChaincode, tokenId, uri, metadataJSON})
+	if err != nil {
+		return "", fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	err = accesscontrol.PutState(sdk, promotionKey, promotionJSON)
+	if err != nil {
+		return "", fmt.Errorf("failed to record the promotion of token %d: %v", id, err)
+	}
+
+	err = events.Emit(sdk, "Promoted", &Promoted{Account: account, ID: id, NftChaincode: nftChaincode, TokenId: tokenId})
+	if err != nil {
+		return "", err
+	}
+	return tokenId, nil
+}
+
+// ClaimPromotion consumes the promotion of tokenId for the caller, who must be the account that
+// promoted it, and returns it. MintPromoted of the ERC721 chaincode calls it before minting; a
+// promotion is claimed once, by the first chaincode the holder claims it through.
+func (s *SmartContract) ClaimPromotion(sdk kalpsdk.TransactionContextInterface, tokenId string) (*Promotion, error) {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	promotionKey, err := sdk.CreateCompositeKey(promotionPrefix, []string{tokenId})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the composite key for prefix %s: %v", promotionPrefix, err)
+	}
+	promotionBytes, err := sdk.GetState(promotionKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the promotion of token %s: %v", tokenId, err)
+	}
+	if promotionBytes == nil {
+		return nil, fmt.Errorf("token %s has no promotion to claim", tokenId)
+	}
+	promotion := new(Promotion)
+	err = json.Unmarshal(promotionBytes, promotion)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the promotion of token %s: %v", tokenId, err)
+	}
+	account, err := sdk.GetClientIdentity().GetID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client id: %v", err)
+	}
+	if account != promotion.Account {
+		return nil, kusherrors.Errorf(kusherrors.ErrUnauthorized, "token %s was not promoted by the caller", tokenId)
+	}
+	err = accesscontrol.DelState(sdk, promotionKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to consume the promotion of token %s: %v", tokenId, err)
+	}
+	return promotion, nil
+}
+
+// promotedMetadataJSON converts the metadata of id to the ERC721 format, turning properties into
+// attributes. It returns an empty string if id has no metadata.
+func promotedMetadataJSON(sdk kalpsdk.TransactionContextInterface, id uint64) (string, error) {
+	metadataKey, err := sdk.CreateCompositeKey(tokenMetadataPrefix, []string{strconv.FormatUint(id, 10)})
+	if err != nil {
+		return "", fmt.Errorf("failed to create the composite key for prefix %s: %v", tokenMetadataPrefix, err)
+	}
+	metadataBytes, err := sdk.GetState(metadataKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to get metadata of token %d: %v", id, err)
+	}
+	if metadataBytes == nil {
+		return "", nil
+	}
+	metadata := new(TokenMetadata)
+	err = json.Unmarshal(metadataBytes, metadata)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode metadata of token %d: %v", id, err)
+	}
+
+	promoted := promotedMetadata{Name: metadata.Name, Description: metadata.Description, Image: metadata.Image}
+	names := make([]string, 0, len(metadata.Properties))
+	for name := range metadata.Properties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := metadata.Properties[name]
+		switch value.(type) {
+		case string, float64:
+		default:
+			// ERC721 attributes only hold strings and numbers
+			valueJSON, err := canonical.Marshal(value)
+			if err != nil {
+				return "", fmt.Errorf("failed to obtain JSON encoding: %v", err)
+			}
+			value = string(valueJSON)
+		}
+		promoted.Attributes = append(promoted.Attributes, promotedAttribute{name, value})
+	}
+	promotedJSON, err := canonical.Marshal(promoted)
+	if err != nil {
+		return "", fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	return string(promotedJSON), nil
+}
@@ -0,0 +1,36 @@
+package token
+
+import (
+	"testing"
+
+	"github.com/thekalpstudio/kush-go/kusherrors"
+	"github.com/thekalpstudio/kush-go/testutil"
+)
+
+func TestERC1155Promote(t *testing.T) {
+	l, s := newERC1155(t)
+	testutil.MustRun(t, l, admin, "Mint", func(ctx *testutil.Context) error {
+		return s.Mint(ctx, testutil.ClientID(admin), 3, 1)
+	})
+	testutil.MustRun(t, l, admin, "SetURI", func(ctx *testutil.Context) error {
+		return s.SetURI(ctx, "https://example.com/{id}.json")
+	})
+	testutil.MustRun(t, l, admin, "Promote", func(ctx *testutil.Context) error {
+		_, err := s.Promote(ctx, "erc721", 3)
+		return err
+	})
+	if balance := erc1155Balance(t, l, s, admin, 3); balance != 0 {
+		t.Fatalf("balance of the promoted token is %d, want 0", balance)
+	}
+
+	claim := func(ctx *testutil.Context) error {
+		promotion, err := s.ClaimPromotion(ctx, "3")
+		if err == nil && (promotion.Account != testutil.ClientID(admin) || promotion.NftChaincode != "erc721") {
+			t.Fatalf("promotion is %+v", promotion)
+		}
+		return err
+	}
+	testutil.CheckErr(t, testutil.Run(l, "bob", "ClaimPromotion", claim), true, kusherrors.ErrUnauthorized)
+	testutil.MustRun(t, l, admin, "ClaimPromotion", claim)
+	testutil.CheckErr(t, testutil.Run(l, admin, "ClaimPromotion", claim), true, "")
+}
@@ -0,0 +1,62 @@
+package token
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+	"github.com/thekalpstudio/kush-go/pagination"
+)
+
+// TokenTypeEntry is a token type found by QueryNFTs.
+type TokenTypeEntry struct {
+	ID       uint64         `json:"id"`
+	Metadata *TokenMetadata `json:"metadata"`
+}
+
+// TokenTypePage is one page of QueryNFTs. Bookmark is empty on the last page.
+type TokenTypePage struct {
+	Tokens   []*TokenTypeEntry `json:"tokens"`
+	Bookmark string            `json:"bookmark"`
+}
+
+// QueryNFTs returns up to pageSize token types whose metadata matches the CouchDB selector, a JSON
+// object such as {"properties.rarity":"legendary"}, starting at bookmark. It needs a CouchDB state
+// database; the indexes in integration/chaincode/erc1155/META-INF must be packaged with the
+// chaincode for such queries to stay fast. Other documents matching the selector are skipped, so a page may
+// hold fewer than pageSize token types before the last one.
+func (s *SmartContract) QueryNFTs(sdk kalpsdk.TransactionContextInterface, selector string, bookmark string, pageSize int) (*TokenTypePage, error) {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+
+	query, err := pagination.SelectorQuery(selector)
+	if err != nil {
+		return nil, err
+	}
+	states, next, err := pagination.ByQuery(sdk, query, bookmark, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	page := &TokenTypePage{Tokens: []*TokenTypeEntry{}, Bookmark: next}
+	for _, state := range states {
+		objectType, parts, err := sdk.SplitCompositeKey(state.Key)
+		if err != nil || objectType != tokenMetadataPrefix || len(parts) != 1 {
+			continue
+		}
+		id, err := strconv.ParseUint(parts[0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid token id in key %s: %v", state.Key, err)
+		}
+		metadata := new(TokenMetadata)
+		err = json.Unmarshal(state.Value, metadata)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode metadata of token %d: %v", id, err)
+		}
+		page.Tokens = append(page.Tokens, &TokenTypeEntry{id, metadata})
+	}
+	return page, nil
+}
@@ -0,0 +1,75 @@
+package token
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/canonical"
+	"github.com/thekalpstudio/kush-go/contractaccount"
+)
+
+// Chaincodes that hold ERC1155 tokens in a contract account implement these functions and return
+// their own name to accept a transfer. Anything else, including an error, reverts the transfer.
+const (
+	onERC1155Received      = "OnERC1155Received"
+	onERC1155BatchReceived = "OnERC1155BatchReceived"
+)
+
+// SafeTransferFrom transfers like TransferFrom. If recipient is a contract account, the owning
+// chaincode's OnERC1155Received(operator, from, id, amount, data, to) must accept the tokens.
+func (s *SmartContract) SafeTransferFrom(sdk kalpsdk.TransactionContextInterface, sender string, recipient string, id uint64, amount uint64, data string) error {
+	err := s.TransferFrom(sdk, sender, recipient, id, amount)
+	if err != nil {
+		return err
+	}
+	receiver, ok := contractaccount.Owner(recipient)
+	if !ok {
+		return nil
+	}
+	operator, err := sdk.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+	return checkERC1155Acceptance(sdk, receiver, onERC1155Received,
+		operator, sender, strconv.FormatUint(id, 10), strconv.FormatUint(amount, 10), data, recipient)
+}
+
+// SafeBatchTransferFrom transfers like BatchTransferFrom. If recipient is a contract account, the
+// owning chaincode's OnERC1155BatchReceived(operator, from, ids, amounts, data, to) must accept
+// the tokens; ids and amounts are passed as JSON arrays.
+func (s *SmartContract) SafeBatchTransferFrom(sdk kalpsdk.TransactionContextInterface, sender string, recipient string, ids []uint64, amounts []uint64, data string) error {
+	err := s.BatchTransferFrom(sdk, sender, recipient, ids, amounts)
+	if err != nil {
+		return err
+	}
+	receiver, ok := contractaccount.Owner(recipient)
+	if !ok {
+		return nil
+	}
+	operator, err := sdk.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+	idsJSON, err := canonical.Marshal(ids)
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	amountsJSON, err := canonical.Marshal(amounts)
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	return checkERC1155Acceptance(sdk, receiver, onERC1155BatchReceived,
+		operator, sender, string(idsJSON), string(amountsJSON), data, recipient)
+}
+
+func checkERC1155Acceptance(sdk kalpsdk.TransactionContextInterface, receiver string, function string, args ...string) error {
+	payload, err := invokeChaincodeHelper(sdk, receiver, function, args...)
+	if err != nil {
+		return err
+	}
+	if string(payload) != function {
+		return fmt.Errorf("receiver chaincode %s rejected the transfer", receiver)
+	}
+	return nil
+}
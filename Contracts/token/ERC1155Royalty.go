@@ -0,0 +1,97 @@
+package token
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+	"github.com/thekalpstudio/kush-go/canonical"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+const royaltyPrefix = "royalty~tokenId"
+
+// Royalty is the ERC-2981 receiver and rate, in basis points of the sale price, of a token type.
+type Royalty struct {
+	Receiver string `json:"receiver"`
+	FeeBps   int    `json:"feeBps"`
+}
+
+// RoyaltyInfo is the royalty owed on a sale. RoyaltyAmount is a decimal string in the sale
+// currency's base units.
+type RoyaltyInfo struct {
+	Receiver      string `json:"receiver"`
+	RoyaltyAmount string `json:"royaltyAmount"`
+}
+
+// SetTokenRoyalty sets the royalty of token type id. A feeBps of 0 removes it.
+func (s *SmartContract) SetTokenRoyalty(sdk kalpsdk.TransactionContextInterface, id uint64, receiver string, feeBps int) error {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	err = accesscontrol.CheckRole(sdk, accesscontrol.AdminRole)
+	if err != nil {
+		return fmt.Errorf("client is not authorized to set royalties: %v", err)
+	}
+	if feeBps < 0 || feeBps > maxBasisPoints {
+		return fmt.Errorf("royalty must be between 0 and %d basis points", maxBasisPoints)
+	}
+
+	royaltyKey, err := sdk.CreateCompositeKey(royaltyPrefix, []string{strconv.FormatUint(id, 10)})
+	if err != nil {
+		return fmt.Errorf("failed to create the composite key for prefix %s: %v", royaltyPrefix, err)
+	}
+	if feeBps == 0 {
+		return accesscontrol.DelState(sdk, royaltyKey)
+	}
+	if receiver == "" || receiver == "0x0" {
+		return fmt.Errorf("royalty receiver must be set")
+	}
+	royaltyJSON, err := canonical.Marshal(Royalty{receiver, feeBps})
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	err = accesscontrol.PutState(sdk, royaltyKey, royaltyJSON)
+	if err != nil {
+		return fmt.Errorf("failed to set royalty of token %d: %v", id, err)
+	}
+	return nil
+}
+
+// RoyaltyInfo returns the receiver and amount of the royalty owed when a token of type id is sold
+// for salePrice, a decimal string in base units. The receiver is empty if no royalty is set.
+func (s *SmartContract) RoyaltyInfo(sdk kalpsdk.TransactionContextInterface, id uint64, salePrice string) (*RoyaltyInfo, error) {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	price, ok := new(big.Int).SetString(salePrice, 10)
+	if !ok || price.Sign() < 0 {
+		return nil, fmt.Errorf("sale price must be a non-negative integer")
+	}
+
+	royaltyKey, err := sdk.CreateCompositeKey(royaltyPrefix, []string{strconv.FormatUint(id, 10)})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the composite key for prefix %s: %v", royaltyPrefix, err)
+	}
+	royaltyBytes, err := sdk.GetState(royaltyKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get royalty of token %d: %v", id, err)
+	}
+	if royaltyBytes == nil {
+		return &RoyaltyInfo{Receiver: "", RoyaltyAmount: "0"}, nil
+	}
+	royalty := new(Royalty)
+	err = json.Unmarshal(royaltyBytes, royalty)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode royalty of token %d: %v", id, err)
+	}
+
+	amount := new(big.Int).Mul(price, big.NewInt(int64(royalty.FeeBps)))
+	amount.Quo(amount, big.NewInt(maxBasisPoints))
+	return &RoyaltyInfo{Receiver: royalty.Receiver, RoyaltyAmount: amount.String()}, nil
+}
@@ -0,0 +1,127 @@
+package token
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+	"github.com/thekalpstudio/kush-go/audit"
+	"github.com/thekalpstudio/kush-go/config"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+	"github.com/thekalpstudio/kush-go/metrics"
+)
+
+// supplyPrefix keys the circulating supply of each token type. Counters start with the first mint
+// after they were introduced; burning more than a counter holds stops it at zero instead of
+// blocking burns of older tokens.
+const supplyPrefix = "supply~tokenId"
+
+// TotalSupply returns the circulating supply of token type id.
+func (s *SmartContract) TotalSupply(sdk kalpsdk.TransactionContextInterface, id uint64) (uint64, error) {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return 0, kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	return supplyHelper(sdk, id)
+}
+
+// Exists reports whether any tokens of type id are in circulation.
+func (s *SmartContract) Exists(sdk kalpsdk.TransactionContextInterface, id uint64) (bool, error) {
+	supply, err := s.TotalSupply(sdk, id)
+	if err != nil {
+		return false, err
+	}
+	return supply > 0, nil
+}
+
+// HolderCount returns the number of accounts holding tokens of type id. Accounts whose balance
+// has not changed since holders were counted are only counted once it does.
+func (s *SmartContract) HolderCount(sdk kalpsdk.TransactionContextInterface, id uint64) (int64, error) {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return 0, kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	return metrics.TokenHolderCount(sdk, strconv.FormatUint(id, 10))
+}
+
+// burnHelper removes amounts of ids from account and lowers their supply.
+func burnHelper(sdk kalpsdk.TransactionContextInterface, account string, ids []uint64, amounts []uint64) error {
+	err := removeBalance(sdk, account, ids, amounts)
+	if err != nil {
+		return err
+	}
+	burned := make(map[uint64]uint64)
+	for i := 0; i < len(amounts); i++ {
+		burned[ids[i]], err = add1(burned[ids[i]], amounts[i])
+		if err != nil {
+			return err
+		}
+	}
+	for _, id := range sortedKeys(burned) {
+		supply, err := supplyHelper(sdk, id)
+		if err != nil {
+			return err
+		}
+		if supply > burned[id] {
+			supply -= burned[id]
+		} else {
+			supply = 0
+		}
+		err = setSupply(sdk, id, supply)
+		if err != nil {
+			return err
+		}
+		err = audit.Record(sdk, "Burn", account, strconv.FormatUint(id, 10), strconv.FormatUint(burned[id], 10))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// increaseSupply adds amount to the supply of id. It must be called at most once per id and
+// transaction, because reads do not see earlier writes of the same transaction.
+func increaseSupply(sdk kalpsdk.TransactionContextInterface, id uint64, amount uint64) error {
+	supply, err := supplyHelper(sdk, id)
+	if err != nil {
+		return err
+	}
+	supply, err = add1(supply, amount)
+	if err != nil {
+		return err
+	}
+	err = config.CheckMaxSupply(sdk, new(big.Int).SetUint64(supply))
+	if err != nil {
+		return fmt.Errorf("failed to mint token %d: %v", id, err)
+	}
+	return setSupply(sdk, id, supply)
+}
+
+func supplyHelper(sdk kalpsdk.TransactionContextInterface, id uint64) (uint64, error) {
+	supplyKey, err := sdk.CreateCompositeKey(supplyPrefix, []string{strconv.FormatUint(id, 10)})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create the composite key for prefix %s: %v", supplyPrefix, err)
+	}
+	supplyBytes, err := sdk.GetState(supplyKey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read supply of token %d: %v", id, err)
+	}
+	if supplyBytes == nil {
+		return 0, nil
+	}
+	supply, err := strconv.ParseUint(string(supplyBytes), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to decode supply of token %d: %v", id, err)
+	}
+	return supply, nil
+}
+
+func setSupply(sdk kalpsdk.TransactionContextInterface, id uint64, supply uint64) error {
+	supplyKey, err := sdk.CreateCompositeKey(supplyPrefix, []string{strconv.FormatUint(id, 10)})
+	if err != nil {
+		return fmt.Errorf("failed to create the composite key for prefix %s: %v", supplyPrefix, err)
+	}
+	return accesscontrol.PutState(sdk, supplyKey, []byte(strconv.FormatUint(supply, 10)))
+}
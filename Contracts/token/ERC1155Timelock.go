@@ -0,0 +1,62 @@
+package token
+
+import (
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+// SetTimelockDelay makes SetURI, SetTokenURI, role and ownership changes and config updates wait
+// delay seconds between ScheduleOperation and ExecuteOperation. Once enabled, the delay itself changes through a scheduled
+// accesscontrol.ActionSetTimelockDelay.
+func (s *SmartContract) SetTimelockDelay(sdk kalpsdk.TransactionContextInterface, delay int64) error {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	return accesscontrol.SetTimelockDelay(sdk, delay)
+}
+
+func (s *SmartContract) GetTimelockDelay(sdk kalpsdk.TransactionContextInterface) (int64, error) {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return 0, kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	return accesscontrol.GetTimelockDelay(sdk)
+}
+
+// ScheduleOperation schedules action with args. See accesscontrol.Proposal for the arguments of
+// each action.
+func (s *SmartContract) ScheduleOperation(sdk kalpsdk.TransactionContextInterface, action string, args []string) (*accesscontrol.TimelockOperation, error) {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	return accesscontrol.ScheduleOperation(sdk, action, args)
+}
+
+// ExecuteOperation executes timelock operation id once its delay passed.
+func (s *SmartContract) ExecuteOperation(sdk kalpsdk.TransactionContextInterface, id string) (*accesscontrol.TimelockOperation, error) {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	return accesscontrol.ExecuteOperation(sdk, id, erc1155Executor(sdk))
+}
+
+// CancelOperation cancels timelock operation id.
+func (s *SmartContract) CancelOperation(sdk kalpsdk.TransactionContextInterface, id string) (*accesscontrol.TimelockOperation, error) {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	return accesscontrol.CancelOperation(sdk, id)
+}
+
+func (s *SmartContract) GetOperation(sdk kalpsdk.TransactionContextInterface, id string) (*accesscontrol.TimelockOperation, error) {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	return accesscontrol.GetOperation(sdk, id)
+}
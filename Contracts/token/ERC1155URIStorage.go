@@ -0,0 +1,105 @@
+package token
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+	"github.com/thekalpstudio/kush-go/audit"
+	"github.com/thekalpstudio/kush-go/config"
+	"github.com/thekalpstudio/kush-go/events"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+const tokenURIPrefix = "uri~tokenId"
+
+// SetTokenURI sets the URI of token type id, overriding the global {id} template URI. An empty
+// uri removes the override.
+func (s *SmartContract) SetTokenURI(sdk kalpsdk.TransactionContextInterface, id uint64, uri string) error {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	err = authorizationHelper(sdk, accesscontrol.AdminRole)
+	if err != nil {
+		return err
+	}
+	err = accesscontrol.CheckNotTimelocked(sdk, accesscontrol.ActionSetTokenURI)
+	if err != nil {
+		return err
+	}
+	if uri != "" {
+		err = checkStrictURI(sdk, uri)
+		if err != nil {
+			return err
+		}
+	}
+	return writeTokenURI(sdk, id, uri)
+}
+
+// writeTokenURI stores the URI of token type id, leaving authorization to the caller.
+func writeTokenURI(sdk kalpsdk.TransactionContextInterface, id uint64, uri string) error {
+	tokenURIKey, err := sdk.CreateCompositeKey(tokenURIPrefix, []string{strconv.FormatUint(id, 10)})
+	if err != nil {
+		return fmt.Errorf("failed to create the composite key for prefix %s: %v", tokenURIPrefix, err)
+	}
+	if uri == "" {
+		err = accesscontrol.DelState(sdk, tokenURIKey)
+	} else {
+		err = accesscontrol.PutState(sdk, tokenURIKey, []byte(uri))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set uri of token %d: %v", id, err)
+	}
+	err = audit.Record(sdk, "SetTokenURI", strconv.FormatUint(id, 10), uri)
+	if err != nil {
+		return err
+	}
+
+	// after a removal the token type reverts to the global URI, which may not be set yet
+	if uri == "" {
+		uriBytes, err := sdk.GetState(uriKey)
+		if err != nil {
+			return fmt.Errorf("failed to get uri: %v", err)
+		}
+		uri = string(uriBytes)
+	}
+	return events.Emit(sdk, "URI", &URI{Value: uri, ID: id})
+}
+
+// checkStrictURI validates uri with config.ValidateURI if the collection runs in strict URI mode.
+func checkStrictURI(sdk kalpsdk.TransactionContextInterface, uri string) error {
+	strict, err := config.GetStrictURI(sdk)
+	if err != nil {
+		return err
+	}
+	if !strict {
+		return nil
+	}
+	return config.ValidateURI(uri)
+}
+
+// uriHelper returns the URI of token type id, falling back to the global template URI.
+func uriHelper(sdk kalpsdk.TransactionContextInterface, id uint64) (string, error) {
+	tokenURIKey, err := sdk.CreateCompositeKey(tokenURIPrefix, []string{strconv.FormatUint(id, 10)})
+	if err != nil {
+		return "", fmt.Errorf("failed to create the composite key for prefix %s: %v", tokenURIPrefix, err)
+	}
+	uriBytes, err := sdk.GetState(tokenURIKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to get uri of token %d: %v", id, err)
+	}
+	if uriBytes != nil {
+		return string(uriBytes), nil
+	}
+	return globalURIHelper(sdk)
+}
+
+func globalURIHelper(sdk kalpsdk.TransactionContextInterface) (string, error) {
+	uriBytes, err := sdk.GetState(uriKey)
+	if err != nil || uriBytes == nil {
+		return "", fmt.Errorf("failed to get uri: %v", err)
+	}
+	return string(uriBytes), nil
+}
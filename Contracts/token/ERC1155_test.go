@@ -0,0 +1,391 @@
+package token
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/thekalpstudio/kush-go/config"
+	"github.com/thekalpstudio/kush-go/events"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+	"github.com/thekalpstudio/kush-go/testutil"
+)
+
+// newERC1155 returns an initialized collection whose admin holds 100 tokens of type 1 and 50 of
+// type 2.
+func newERC1155(t *testing.T) (*testutil.Ledger, *SmartContract) {
+	t.Helper()
+	l := testutil.NewLedger()
+	s := new(SmartContract)
+	testutil.MustRun(t, l, admin, "Initialize", func(ctx *testutil.Context) error {
+		_, err := s.Initialize(ctx, testConfig())
+		return err
+	})
+	testutil.MustRun(t, l, admin, "MintBatch", func(ctx *testutil.Context) error {
+		return s.MintBatch(ctx, testutil.ClientID(admin), []uint64{1, 2}, []uint64{100, 50})
+	})
+	return l, s
+}
+
+func erc1155Balance(t *testing.T, l *testutil.Ledger, s *SmartContract, user string, id uint64) uint64 {
+	t.Helper()
+	balance, err := s.BalanceOf(l.Tx(admin, "BalanceOf"), testutil.ClientID(user), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return balance
+}
+
+func TestERC1155Initialize(t *testing.T) {
+	tests := []struct {
+		name    string
+		init    func(cfg *config.InitConfig)
+		twice   bool
+		wantErr bool
+	}{
+		{name: "valid", init: func(cfg *config.InitConfig) {}},
+		{name: "already initialized", init: func(cfg *config.InitConfig) {}, twice: true, wantErr: true},
+		{name: "decimals", init: func(cfg *config.InitConfig) { cfg.Decimals = 2 }, wantErr: true},
+		{name: "no symbol", init: func(cfg *config.InitConfig) { cfg.Symbol = "" }, wantErr: true},
+		{name: "negative max balance keys", init: func(cfg *config.InitConfig) { cfg.MaxBalanceKeys = -1 }, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := testutil.NewLedger()
+			s := new(SmartContract)
+			cfg := testConfig()
+			tt.init(&cfg)
+			initialize := func(ctx *testutil.Context) error {
+				_, err := s.Initialize(ctx, cfg)
+				return err
+			}
+			if tt.twice {
+				testutil.MustRun(t, l, admin, "Initialize", initialize)
+			}
+			err := testutil.Run(l, admin, "Initialize", initialize)
+			testutil.CheckErr(t, err, tt.wantErr, "")
+			if tt.wantErr {
+				return
+			}
+
+			ctx := l.Tx(admin, "Name")
+			name, err := s.Name(ctx)
+			if err != nil || name != cfg.Name {
+				t.Fatalf("name is %q: %v", name, err)
+			}
+			symbol, err := s.Symbol(ctx)
+			if err != nil || symbol != cfg.Symbol {
+				t.Fatalf("symbol is %q: %v", symbol, err)
+			}
+		})
+	}
+}
+
+func TestERC1155InitializeOutsideAuthorizedOrgs(t *testing.T) {
+	l := testutil.NewLedger()
+	s := new(SmartContract)
+	err := testutil.Run(l, "mallory", "Initialize", func(ctx *testutil.Context) error {
+		cfg := testConfig()
+		cfg.AuthorizedOrgs = []string{"Org2MSP"}
+		_, err := s.Initialize(ctx, cfg)
+		return err
+	})
+	testutil.CheckErr(t, err, true, kusherrors.ErrUnauthorized)
+}
+
+func TestERC1155NotInitialized(t *testing.T) {
+	l := testutil.NewLedger()
+	s := new(SmartContract)
+	err := testutil.Run(l, admin, "Mint", func(ctx *testutil.Context) error {
+		return s.Mint(ctx, testutil.ClientID(admin), 1, 1)
+	})
+	testutil.CheckErr(t, err, true, kusherrors.ErrNotInitialized)
+}
+
+func TestERC1155Mint(t *testing.T) {
+	tests := []struct {
+		name       string
+		user       string
+		account    string
+		amount     uint64
+		wantErr    bool
+		wantCode   kusherrors.Code
+		wantSupply uint64
+	}{
+		{name: "to self", user: admin, account: testutil.ClientID(admin), amount: 10, wantSupply: 110},
+		{name: "to another account", user: admin, account: testutil.ClientID("bob"), amount: 10, wantSupply: 110},
+		{name: "without minter role", user: "bob", account: testutil.ClientID("bob"), amount: 10, wantErr: true, wantCode: kusherrors.ErrUnauthorized},
+		{name: "zero", user: admin, account: testutil.ClientID("bob"), amount: 0, wantErr: true},
+		{name: "to the zero address", user: admin, account: "0x0", amount: 10, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, s := newERC1155(t)
+			err := testutil.Run(l, tt.user, "Mint", func(ctx *testutil.Context) error {
+				return s.Mint(ctx, tt.account, 1, tt.amount)
+			})
+			testutil.CheckErr(t, err, tt.wantErr, tt.wantCode)
+			if tt.wantErr {
+				return
+			}
+
+			supply, err := s.TotalSupply(l.Tx(admin, "TotalSupply"), 1)
+			if err != nil || supply != tt.wantSupply {
+				t.Fatalf("supply is %d: %v, want %d", supply, err, tt.wantSupply)
+			}
+			transfer := testutil.LastEvent(t, l, "TransferSingle").(*events.TransferSingle)
+			want := events.TransferSingle{Operator: testutil.ClientID(admin), From: "0x0", To: tt.account, ID: 1, Value: tt.amount}
+			want.TxContext = transfer.TxContext
+			if *transfer != want {
+				t.Fatalf("event is %+v, want %+v", transfer, want)
+			}
+		})
+	}
+}
+
+func TestERC1155MintBatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		ids      []uint64
+		amounts  []uint64
+		wantErr  bool
+		wantBobs map[uint64]uint64
+	}{
+		{name: "distinct ids", ids: []uint64{1, 3}, amounts: []uint64{5, 7}, wantBobs: map[uint64]uint64{1: 5, 3: 7}},
+		{name: "repeated id", ids: []uint64{3, 3}, amounts: []uint64{5, 7}, wantBobs: map[uint64]uint64{3: 12}},
+		{name: "length mismatch", ids: []uint64{1, 3}, amounts: []uint64{5}, wantErr: true},
+		{name: "zero amount", ids: []uint64{1, 3}, amounts: []uint64{5, 0}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, s := newERC1155(t)
+			err := testutil.Run(l, admin, "MintBatch", func(ctx *testutil.Context) error {
+				return s.MintBatch(ctx, testutil.ClientID("bob"), tt.ids, tt.amounts)
+			})
+			testutil.CheckErr(t, err, tt.wantErr, "")
+			if tt.wantErr {
+				return
+			}
+
+			for id, want := range tt.wantBobs {
+				if balance := erc1155Balance(t, l, s, "bob", id); balance != want {
+					t.Fatalf("balance of token %d is %d, want %d", id, balance, want)
+				}
+			}
+			transfer := testutil.LastEvent(t, l, "TransferBatch").(*events.TransferBatch)
+			if !reflect.DeepEqual(transfer.IDs, tt.ids) || !reflect.DeepEqual(transfer.Values, tt.amounts) {
+				t.Fatalf("event is %+v", transfer)
+			}
+		})
+	}
+}
+
+func TestERC1155TransferFrom(t *testing.T) {
+	tests := []struct {
+		name       string
+		user       string
+		approve    bool
+		sender     string
+		recipient  string
+		amount     uint64
+		wantErr    bool
+		wantCode   kusherrors.Code
+		wantSender uint64
+	}{
+		{name: "by the owner", user: admin, sender: admin, recipient: "bob", amount: 40, wantSender: 60},
+		{name: "whole balance", user: admin, sender: admin, recipient: "bob", amount: 100, wantSender: 0},
+		{name: "by an approved operator", user: "carol", approve: true, sender: admin, recipient: "bob", amount: 40, wantSender: 60},
+		{name: "by an operator without approval", user: "carol", sender: admin, recipient: "bob", amount: 40, wantErr: true, wantCode: kusherrors.ErrUnauthorized},
+		{name: "insufficient balance", user: admin, sender: admin, recipient: "bob", amount: 101, wantErr: true, wantCode: kusherrors.ErrInsufficientBalance},
+		{name: "from an empty account", user: "bob", sender: "bob", recipient: admin, amount: 1, wantErr: true, wantCode: kusherrors.ErrInsufficientBalance},
+		{name: "to self", user: admin, sender: admin, recipient: admin, amount: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, s := newERC1155(t)
+			if tt.approve {
+				testutil.MustRun(t, l, admin, "SetApprovalForAll", func(ctx *testutil.Context) error {
+					return s.SetApprovalForAll(ctx, testutil.ClientID(tt.user), true)
+				})
+			}
+			sender, recipient := testutil.ClientID(tt.sender), testutil.ClientID(tt.recipient)
+			err := testutil.Run(l, tt.user, "TransferFrom", func(ctx *testutil.Context) error {
+				return s.TransferFrom(ctx, sender, recipient, 1, tt.amount)
+			})
+			testutil.CheckErr(t, err, tt.wantErr, tt.wantCode)
+			if tt.wantErr {
+				if balance := erc1155Balance(t, l, s, admin, 1); balance != 100 {
+					t.Fatalf("failed transfer changed the balance to %d", balance)
+				}
+				return
+			}
+
+			if balance := erc1155Balance(t, l, s, tt.sender, 1); balance != tt.wantSender {
+				t.Fatalf("sender balance is %d, want %d", balance, tt.wantSender)
+			}
+			if balance := erc1155Balance(t, l, s, tt.recipient, 1); balance != tt.amount {
+				t.Fatalf("recipient balance is %d, want %d", balance, tt.amount)
+			}
+			transfer := testutil.LastEvent(t, l, "TransferSingle").(*events.TransferSingle)
+			if transfer.Operator != testutil.ClientID(tt.user) || transfer.From != sender || transfer.To != recipient || transfer.Value != tt.amount {
+				t.Fatalf("event is %+v", transfer)
+			}
+		})
+	}
+}
+
+func TestERC1155BatchTransferFrom(t *testing.T) {
+	tests := []struct {
+		name      string
+		ids       []uint64
+		amounts   []uint64
+		wantErr   bool
+		wantCode  kusherrors.Code
+		wantAdmin map[uint64]uint64
+	}{
+		{name: "two types", ids: []uint64{1, 2}, amounts: []uint64{10, 20}, wantAdmin: map[uint64]uint64{1: 90, 2: 30}},
+		{name: "repeated type", ids: []uint64{1, 1}, amounts: []uint64{10, 20}, wantAdmin: map[uint64]uint64{1: 70, 2: 50}},
+		{name: "repeated type above the balance", ids: []uint64{2, 2}, amounts: []uint64{30, 30}, wantErr: true, wantCode: kusherrors.ErrInsufficientBalance},
+		{name: "one leg above the balance", ids: []uint64{1, 2}, amounts: []uint64{10, 51}, wantErr: true, wantCode: kusherrors.ErrInsufficientBalance},
+		{name: "length mismatch", ids: []uint64{1, 2}, amounts: []uint64{10}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, s := newERC1155(t)
+			err := testutil.Run(l, admin, "BatchTransferFrom", func(ctx *testutil.Context) error {
+				return s.BatchTransferFrom(ctx, testutil.ClientID(admin), testutil.ClientID("bob"), tt.ids, tt.amounts)
+			})
+			testutil.CheckErr(t, err, tt.wantErr, tt.wantCode)
+			if tt.wantErr {
+				return
+			}
+
+			for id, want := range tt.wantAdmin {
+				if balance := erc1155Balance(t, l, s, admin, id); balance != want {
+					t.Fatalf("balance of token %d is %d, want %d", id, balance, want)
+				}
+				if balance := erc1155Balance(t, l, s, "bob", id); balance != map[uint64]uint64{1: 100, 2: 50}[id]-want {
+					t.Fatalf("recipient balance of token %d is %d", id, balance)
+				}
+			}
+			transfer := testutil.LastEvent(t, l, "TransferBatch").(*events.TransferBatch)
+			if !reflect.DeepEqual(transfer.IDs, tt.ids) || !reflect.DeepEqual(transfer.Values, tt.amounts) {
+				t.Fatalf("event is %+v", transfer)
+			}
+		})
+	}
+}
+
+func TestERC1155BalanceOfBatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		users   []string
+		ids     []uint64
+		wantErr bool
+		want    []uint64
+	}{
+		{name: "one account", users: []string{admin, admin}, ids: []uint64{1, 2}, want: []uint64{70, 50}},
+		{name: "several accounts", users: []string{admin, "bob", "bob"}, ids: []uint64{1, 1, 2}, want: []uint64{70, 30, 0}},
+		{name: "repeated pair", users: []string{"bob", "bob"}, ids: []uint64{1, 1}, want: []uint64{30, 30}},
+		{name: "without holders", users: []string{"carol"}, ids: []uint64{3}, want: []uint64{0}},
+		{name: "length mismatch", users: []string{admin, "bob"}, ids: []uint64{1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, s := newERC1155(t)
+			testutil.MustRun(t, l, admin, "TransferFrom", func(ctx *testutil.Context) error {
+				return s.TransferFrom(ctx, testutil.ClientID(admin), testutil.ClientID("bob"), 1, 30)
+			})
+			transfer := testutil.LastEvent(t, l, "TransferSingle").(*events.TransferSingle)
+			if transfer.To != testutil.ClientID("bob") || transfer.ID != 1 || transfer.Value != 30 {
+				t.Fatalf("event is %+v", transfer)
+			}
+
+			accounts := make([]string, len(tt.users))
+			for i, user := range tt.users {
+				accounts[i] = testutil.ClientID(user)
+			}
+			balances, err := s.BalanceOfBatch(l.Tx(admin, "BalanceOfBatch"), accounts, tt.ids)
+			testutil.CheckErr(t, err, tt.wantErr, "")
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(balances, tt.want) {
+				t.Fatalf("balances are %v, want %v", balances, tt.want)
+			}
+		})
+	}
+}
+
+func TestERC1155Burn(t *testing.T) {
+	tests := []struct {
+		name       string
+		user       string
+		amount     uint64
+		wantErr    bool
+		wantCode   kusherrors.Code
+		wantSupply uint64
+	}{
+		{name: "by the holder", user: admin, amount: 30, wantSupply: 70},
+		{name: "whole balance", user: admin, amount: 100, wantSupply: 0},
+		{name: "above the balance", user: admin, amount: 101, wantErr: true, wantCode: kusherrors.ErrInsufficientBalance},
+		{name: "by a stranger", user: "bob", amount: 1, wantErr: true, wantCode: kusherrors.ErrUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, s := newERC1155(t)
+			err := testutil.Run(l, tt.user, "Burn", func(ctx *testutil.Context) error {
+				return s.Burn(ctx, testutil.ClientID(admin), 1, tt.amount)
+			})
+			testutil.CheckErr(t, err, tt.wantErr, tt.wantCode)
+			if tt.wantErr {
+				return
+			}
+
+			supply, err := s.TotalSupply(l.Tx(admin, "TotalSupply"), 1)
+			if err != nil || supply != tt.wantSupply {
+				t.Fatalf("supply is %d: %v, want %d", supply, err, tt.wantSupply)
+			}
+			if balance := erc1155Balance(t, l, s, admin, 1); balance != tt.wantSupply {
+				t.Fatalf("balance is %d, want %d", balance, tt.wantSupply)
+			}
+			transfer := testutil.LastEvent(t, l, "TransferSingle").(*events.TransferSingle)
+			if transfer.From != testutil.ClientID(admin) || transfer.To != "0x0" || transfer.Value != tt.amount {
+				t.Fatalf("event is %+v", transfer)
+			}
+		})
+	}
+}
+
+func TestERC1155SetApprovalForAll(t *testing.T) {
+	tests := []struct {
+		name     string
+		operator string
+		approved bool
+		wantErr  bool
+	}{
+		{name: "approve", operator: testutil.ClientID("bob"), approved: true},
+		{name: "revoke", operator: testutil.ClientID("bob"), approved: false},
+		{name: "self", operator: testutil.ClientID(admin), approved: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, s := newERC1155(t)
+			err := testutil.Run(l, admin, "SetApprovalForAll", func(ctx *testutil.Context) error {
+				return s.SetApprovalForAll(ctx, tt.operator, tt.approved)
+			})
+			testutil.CheckErr(t, err, tt.wantErr, "")
+			if tt.wantErr {
+				return
+			}
+
+			approved, err := s.IsApprovedForAll(l.Tx(admin, "IsApprovedForAll"), testutil.ClientID(admin), tt.operator)
+			if err != nil || approved != tt.approved {
+				t.Fatalf("approval is %v: %v, want %v", approved, err, tt.approved)
+			}
+			approval := testutil.LastEvent(t, l, "ApprovalForAll").(*events.ApprovalForAll)
+			if approval.Owner != testutil.ClientID(admin) || approval.Operator != tt.operator || approval.Approved != tt.approved {
+				t.Fatalf("event is %+v", approval)
+			}
+		})
+	}
+}
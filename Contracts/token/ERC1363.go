@@ -0,0 +1,102 @@
+package token
+
+import (
+	"fmt"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/events"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+// Receiver chaincodes implement these functions to react to TransferAndCall and ApproveAndCall.
+// Returning an error from them reverts the whole transaction, including the token movement.
+const (
+	onTransferReceived = "OnTransferReceived"
+	onApprovalReceived = "OnApprovalReceived"
+)
+
+// TransferAndCall transfers amount to to and then invokes OnTransferReceived(operator, from, to,
+// amount, data) on the receiver chaincode in the same transaction. Depositing into a chaincode's
+// own custody is done by passing ContractAccountID(receiver, "") as to.
+func (c *TokenERC20Contract) TransferAndCall(ctx kalpsdk.TransactionContextInterface, receiver string, to string, amount string, data string) error {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	err = checkNotPaused(ctx)
+	if err != nil {
+		return err
+	}
+
+	clientID, err := ctx.GetUserID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	transferAmount, err := parseAmount(amount)
+	if err != nil {
+		return err
+	}
+
+	err = checkTravelRule(ctx, transferAmount)
+	if err != nil {
+		return err
+	}
+
+	fee, err := transferHelper(ctx, clientID, to, transferAmount)
+	if err != nil {
+		return fmt.Errorf("failed to transfer: %v", err)
+	}
+
+	_, err = invokeChaincodeHelper(ctx, receiver, onTransferReceived, clientID, clientID, to, transferAmount.String(), data)
+	if err != nil {
+		return err
+	}
+
+	transferEvent := events.Transfer{From: clientID, To: to, Value: transferAmount, FeeCharged: fee}
+	err = events.Emit(ctx, "Transfer", &transferEvent)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ApproveAndCall sets the allowance of spender and then invokes OnApprovalReceived(owner, spender,
+// amount, data) on the receiver chaincode in the same transaction.
+func (c *TokenERC20Contract) ApproveAndCall(ctx kalpsdk.TransactionContextInterface, receiver string, spender string, amount string, data string) error {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	err = checkNotPaused(ctx)
+	if err != nil {
+		return err
+	}
+
+	owner, err := ctx.GetUserID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	allowance, err := parseAmount(amount)
+	if err != nil {
+		return err
+	}
+
+	err = approveHelper(ctx, owner, spender, allowance)
+	if err != nil {
+		return err
+	}
+
+	_, err = invokeChaincodeHelper(ctx, receiver, onApprovalReceived, owner, spender, allowance.String(), data)
+	return err
+}
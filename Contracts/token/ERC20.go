@@ -1,11 +1,20 @@
 package token
 
 import (
-	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+	"github.com/thekalpstudio/kush-go/audit"
+	"github.com/thekalpstudio/kush-go/config"
+	"github.com/thekalpstudio/kush-go/events"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+	"github.com/thekalpstudio/kush-go/logging"
+	"github.com/thekalpstudio/kush-go/metrics"
+	"github.com/thekalpstudio/kush-go/migration"
+	"math/big"
 	"strconv"
+	"strings"
 )
 const (
 	nameKey         = "name"
@@ -16,24 +25,11 @@ const (
 )
 
 type TokenERC20Contract struct {
-	kalpsdk.Contract
+	logging.Contract
 }
 
-type event struct {
-	From  string `json:"from"`
-	To    string `json:"to"`
-	Value int    `json:"value"`
-}
-
-func (c *TokenERC20Contract) Initialize(ctx kalpsdk.TransactionContextInterface, name, symbol string, decimals int) (bool, error) {
-	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
-	if err != nil {
-		return false, fmt.Errorf("failed to get MSPID: %v", err)
-	}
-	if clientMSPID != "mailabs" {
-		return false, fmt.Errorf("client is not authorized to initialize contract")
-	}
-
+// Initialize sets up the token from cfg, see config.InitConfig. MaxSupply caps the total supply.
+func (c *TokenERC20Contract) Initialize(ctx kalpsdk.TransactionContextInterface, cfg config.InitConfig) (bool, error) {
 	bytes, err := ctx.GetState(nameKey)
 	if err != nil {
 		return false, fmt.Errorf("failed to get Name: %v", err)
@@ -42,17 +38,26 @@ func (c *TokenERC20Contract) Initialize(ctx kalpsdk.TransactionContextInterface,
 		return false, fmt.Errorf("contract options are already set, client is not authorized to change them")
 	}
 
-	err = ctx.PutStateWithoutKYC(nameKey, []byte(name))
+	err = config.Initialize(ctx, cfg, maxDecimals)
+	if err != nil {
+		return false, err
+	}
+
+	err = migration.SetVersion(ctx, migration.CurrentVersion(erc20Upgrades))
+	if err != nil {
+		return false, err
+	}
+	err = accesscontrol.WriteState(ctx, cfg.Features.RequireKYC, nameKey, []byte(cfg.Name))
 	if err != nil {
 		return false, fmt.Errorf("failed to set token name: %v", err)
 	}
 
-	err = ctx.PutStateWithoutKYC(symbolKey, []byte(symbol))
+	err = accesscontrol.WriteState(ctx, cfg.Features.RequireKYC, symbolKey, []byte(cfg.Symbol))
 	if err != nil {
 		return false, fmt.Errorf("failed to set symbol: %v", err)
 	}
 
-	err = ctx.PutStateWithoutKYC(decimalsKey, []byte(strconv.Itoa(decimals)))
+	err = accesscontrol.WriteState(ctx, cfg.Features.RequireKYC, decimalsKey, []byte(strconv.Itoa(cfg.Decimals)))
 	if err != nil {
 		return false, fmt.Errorf("failed to set decimals: %v", err)
 	}
@@ -60,21 +65,23 @@ func (c *TokenERC20Contract) Initialize(ctx kalpsdk.TransactionContextInterface,
 	return true, nil
 }
 
-func (c *TokenERC20Contract) Mint(ctx kalpsdk.TransactionContextInterface, amount int) error {
+func (c *TokenERC20Contract) Mint(ctx kalpsdk.TransactionContextInterface, amount string) error {
 	initialized, err := checkInitialized(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to check if contract is already initialized: %v", err)
 	}
 	if !initialized {
-		return fmt.Errorf("contract options need to be set before calling any function, call Initialize() to initialize contract")
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
 	}
 
-	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	err = checkNotPaused(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get MSPID: %v", err)
+		return err
 	}
-	if clientMSPID != "mailabs" {
-		return fmt.Errorf("client is not authorized to mint new tokens")
+
+	err = accesscontrol.CheckOrgRole(ctx, accesscontrol.MinterRole)
+	if err != nil {
+		return fmt.Errorf("client is not authorized to mint new tokens: %v", err)
 	}
 
 	minter, err := ctx.GetUserID()
@@ -82,82 +89,77 @@ func (c *TokenERC20Contract) Mint(ctx kalpsdk.TransactionContextInterface, amoun
 		return fmt.Errorf("failed to get client id: %v", err)
 	}
 
-	if amount <= 0 {
+	mintAmount, err := parseAmount(amount)
+	if err != nil {
+		return err
+	}
+	if mintAmount.Sign() <= 0 {
 		return fmt.Errorf("mint amount must be a positive integer")
 	}
-
-	currentBalanceBytes, err := ctx.GetState(minter)
+	err = accesscontrol.CheckMintThreshold(ctx, mintAmount)
 	if err != nil {
-		return fmt.Errorf("failed to read minter account %s from world state: %v", minter, err)
+		return err
 	}
 
-	var currentBalance int
-	if currentBalanceBytes == nil {
-		currentBalance = 0
-	} else {
-		currentBalance, _ = strconv.Atoi(string(currentBalanceBytes))
-	}
+	return _mint(ctx, minter, mintAmount)
+}
 
-	updatedBalance, err := add(currentBalance, amount)
+// MintTo mints amount directly to account, with the same authorization as Mint.
+func (c *TokenERC20Contract) MintTo(ctx kalpsdk.TransactionContextInterface, account string, amount string) error {
+	initialized, err := checkInitialized(ctx)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
 	}
 
-	err = ctx.PutStateWithoutKYC(minter, []byte(strconv.Itoa(updatedBalance)))
+	err = checkNotPaused(ctx)
 	if err != nil {
 		return err
 	}
 
-	totalSupplyBytes, err := ctx.GetState(totalSupplyKey)
+	err = accesscontrol.CheckOrgRole(ctx, accesscontrol.MinterRole)
 	if err != nil {
-		return fmt.Errorf("failed to retrieve total token supply: %v", err)
-	}
-
-	var totalSupply int
-	if totalSupplyBytes == nil {
-		totalSupply = 0
-	} else {
-		totalSupply, _ = strconv.Atoi(string(totalSupplyBytes))
+		return fmt.Errorf("client is not authorized to mint new tokens: %v", err)
 	}
 
-	totalSupply, err = add(totalSupply, amount)
-	if err != nil {
-		return err
+	if account == "" || account == "0x0" {
+		return fmt.Errorf("mint to the zero address")
 	}
 
-	err = ctx.PutStateWithoutKYC(totalSupplyKey, []byte(strconv.Itoa(totalSupply)))
+	mintAmount, err := parseAmount(amount)
 	if err != nil {
 		return err
 	}
-
-	transferEvent := event{"0x0", minter, amount}
-	transferEventJSON, err := json.Marshal(transferEvent)
-	if err != nil {
-		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	if mintAmount.Sign() <= 0 {
+		return fmt.Errorf("mint amount must be a positive integer")
 	}
-	err = ctx.SetEvent("Transfer", transferEventJSON)
+	err = accesscontrol.CheckMintThreshold(ctx, mintAmount)
 	if err != nil {
-		return fmt.Errorf("failed to set event: %v", err)
+		return err
 	}
 
-	return nil
+	return _mint(ctx, account, mintAmount)
 }
 
-func (c *TokenERC20Contract) Burn(ctx kalpsdk.TransactionContextInterface, amount int) error {
+func (c *TokenERC20Contract) Burn(ctx kalpsdk.TransactionContextInterface, amount string) error {
 	initialized, err := checkInitialized(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to check if contract is already initialized: %v", err)
 	}
 	if !initialized {
-		return fmt.Errorf("contract options need to be set before calling any function, call Initialize() to initialize contract")
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
 	}
 
-	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	err = checkNotPaused(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get MSPID: %v", err)
+		return err
 	}
-	if clientMSPID != "mailabs" {
-		return fmt.Errorf("client is not authorized to burn tokens")
+
+	err = accesscontrol.CheckOrgRole(ctx, accesscontrol.BurnerRole)
+	if err != nil {
+		return fmt.Errorf("client is not authorized to burn tokens: %v", err)
 	}
 
 	minter, err := ctx.GetUserID()
@@ -165,72 +167,94 @@ func (c *TokenERC20Contract) Burn(ctx kalpsdk.TransactionContextInterface, amoun
 		return fmt.Errorf("failed to get client id: %v", err)
 	}
 
-	if amount <= 0 {
-		return errors.New("burn amount must be a positive integer")
+	err = checkNotFrozen(ctx, minter)
+	if err != nil {
+		return err
 	}
 
-	currentBalanceBytes, err := ctx.GetState(minter)
+	burnAmount, err := parseAmount(amount)
 	if err != nil {
-		return fmt.Errorf("failed to read minter account %s from world state: %v", minter, err)
+		return err
 	}
-
-	if currentBalanceBytes == nil {
-		return errors.New("the balance does not exist")
+	if burnAmount.Sign() <= 0 {
+		return errors.New("burn amount must be a positive integer")
 	}
 
-	currentBalance, _ := strconv.Atoi(string(currentBalanceBytes))
+	return _burn(ctx, minter, burnAmount)
+}
 
-	updatedBalance, err := sub(currentBalance, amount)
+// BurnFrom destroys amount tokens of account using the caller's allowance over it.
+func (c *TokenERC20Contract) BurnFrom(ctx kalpsdk.TransactionContextInterface, account string, amount string) error {
+	initialized, err := checkInitialized(ctx)
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
 	}
 
-	err = ctx.PutStateWithoutKYC(minter, []byte(strconv.Itoa(updatedBalance)))
+	err = checkNotPaused(ctx)
 	if err != nil {
 		return err
 	}
 
-	totalSupplyBytes, err := ctx.GetState(totalSupplyKey)
+	spender, err := ctx.GetUserID()
 	if err != nil {
-		return fmt.Errorf("failed to retrieve total token supply: %v", err)
+		return fmt.Errorf("failed to get client id: %v", err)
 	}
 
-	if totalSupplyBytes == nil {
-		return errors.New("totalSupply does not exist")
+	err = checkNotFrozen(ctx, account)
+	if err != nil {
+		return err
 	}
 
-	totalSupply, _ := strconv.Atoi(string(totalSupplyBytes))
-
-	totalSupply, err = sub(totalSupply, amount)
+	burnAmount, err := parseAmount(amount)
 	if err != nil {
 		return err
 	}
+	if burnAmount.Sign() <= 0 {
+		return errors.New("burn amount must be a positive integer")
+	}
 
-	err = ctx.PutStateWithoutKYC(totalSupplyKey, []byte(strconv.Itoa(totalSupply)))
+	allowanceKey, err := ctx.CreateCompositeKey(allowancePrefix, []string{account, spender})
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to create the composite key for prefix %s: %v", allowancePrefix, err)
+	}
+
+	currentAllowance, err := readAmount(ctx, allowanceKey)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve the allowance for %s from world state: %v", allowanceKey, err)
 	}
 
-	transferEvent := event{minter, "0x0", amount}
-	transferEventJSON, err := json.Marshal(transferEvent)
+	if currentAllowance.Cmp(burnAmount) < 0 {
+		return fmt.Errorf("spender does not have enough allowance for burn")
+	}
+
+	updatedAllowance, err := sub(currentAllowance, burnAmount)
 	if err != nil {
-		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+		return err
 	}
-	err = ctx.SetEvent("Transfer", transferEventJSON)
+
+	err = writeAmount(ctx, allowanceKey, updatedAllowance)
 	if err != nil {
-		return fmt.Errorf("failed to set event: %v", err)
+		return err
 	}
 
-	return nil
+	return _burn(ctx, account, burnAmount)
 }
 
-func (c *TokenERC20Contract) Transfer(ctx kalpsdk.TransactionContextInterface, recipient string, amount int) error {
+func (c *TokenERC20Contract) Transfer(ctx kalpsdk.TransactionContextInterface, recipient string, amount string) error {
 	initialized, err := checkInitialized(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to check if contract is already initialized: %v", err)
 	}
 	if !initialized {
-		return fmt.Errorf("contract options need to be set before calling any function, call Initialize() to initialize contract")
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	err = checkNotPaused(ctx)
+	if err != nil {
+		return err
 	}
 
 	clientID, err := ctx.GetUserID()
@@ -238,69 +262,71 @@ func (c *TokenERC20Contract) Transfer(ctx kalpsdk.TransactionContextInterface, r
 		return fmt.Errorf("failed to get client id: %v", err)
 	}
 
-	err = transferHelper(ctx, clientID, recipient, amount)
+	transferAmount, err := parseAmount(amount)
 	if err != nil {
-		return fmt.Errorf("failed to transfer: %v", err)
+		return err
 	}
 
-	transferEvent := event{clientID, recipient, amount}
-	transferEventJSON, err := json.Marshal(transferEvent)
+	err = checkTravelRule(ctx, transferAmount)
 	if err != nil {
-		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+		return err
 	}
-	err = ctx.SetEvent("Transfer", transferEventJSON)
+
+	fee, err := transferHelper(ctx, clientID, recipient, transferAmount)
 	if err != nil {
-		return fmt.Errorf("failed to set event: %v", err)
+		return fmt.Errorf("failed to transfer: %v", err)
+	}
+
+	transferEvent := events.Transfer{From: clientID, To: recipient, Value: transferAmount, FeeCharged: fee}
+	err = events.Emit(ctx, "Transfer", &transferEvent)
+	if err != nil {
+		return err
 	}
 
 	return nil
 }
 
-func (c *TokenERC20Contract) BalanceOf(ctx kalpsdk.TransactionContextInterface, account string) (int, error) {
+func (c *TokenERC20Contract) BalanceOf(ctx kalpsdk.TransactionContextInterface, account string) (string, error) {
 	initialized, err := checkInitialized(ctx)
 	if err != nil {
-		return 0, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
 	}
 	if !initialized {
-		return 0, fmt.Errorf("contract options need to be set before calling any function, call Initialize() to initialize contract")
+		return "", kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
 	}
 
-	balanceBytes, err := ctx.GetState(account)
+	balance, exists, err := lookupBalance(ctx, account)
 	if err != nil {
-		return 0, fmt.Errorf("failed to read from world state: %v", err)
+		return "", err
 	}
-	if balanceBytes == nil {
-		return 0, fmt.Errorf("the account %s does not exist", account)
+	if !exists {
+		return "", fmt.Errorf("the account %s does not exist", account)
 	}
-
-	balance, _ := strconv.Atoi(string(balanceBytes))
-	return balance, nil
+	return balance.String(), nil
 }
 
-func (c *TokenERC20Contract) ClientAccountBalance(ctx kalpsdk.TransactionContextInterface) (int, error) {
+func (c *TokenERC20Contract) ClientAccountBalance(ctx kalpsdk.TransactionContextInterface) (string, error) {
 	initialized, err := checkInitialized(ctx)
 	if err != nil {
-		return 0, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
 	}
 	if !initialized {
-		return 0, fmt.Errorf("contract options need to be set before calling any function, call Initialize() to initialize contract")
+		return "", kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
 	}
 
 	clientID, err := ctx.GetUserID()
 	if err != nil {
-		return 0, fmt.Errorf("failed to get client id: %v", err)
+		return "", fmt.Errorf("failed to get client id: %v", err)
 	}
 
-	balanceBytes, err := ctx.GetState(clientID)
+	balance, exists, err := lookupBalance(ctx, clientID)
 	if err != nil {
-		return 0, fmt.Errorf("failed to read from world state: %v", err)
+		return "", err
 	}
-	if balanceBytes == nil {
-		return 0, fmt.Errorf("the account %s does not exist", clientID)
+	if !exists {
+		return "", fmt.Errorf("the account %s does not exist", clientID)
 	}
-
-	balance, _ := strconv.Atoi(string(balanceBytes))
-	return balance, nil
+	return balance.String(), nil
 }
 
 func (c *TokenERC20Contract) ClientAccountID(ctx kalpsdk.TransactionContextInterface) (string, error) {
@@ -309,7 +335,7 @@ func (c *TokenERC20Contract) ClientAccountID(ctx kalpsdk.TransactionContextInter
 		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
 	}
 	if !initialized {
-		return "", fmt.Errorf("contract options need to be set before calling any function, call Initialize() to initialize contract")
+		return "", kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
 	}
 
 	clientAccountID, err := ctx.GetUserID()
@@ -320,37 +346,35 @@ func (c *TokenERC20Contract) ClientAccountID(ctx kalpsdk.TransactionContextInter
 	return clientAccountID, nil
 }
 
-func (c *TokenERC20Contract) TotalSupply(ctx kalpsdk.TransactionContextInterface) (int, error) {
+func (c *TokenERC20Contract) TotalSupply(ctx kalpsdk.TransactionContextInterface) (string, error) {
 	initialized, err := checkInitialized(ctx)
 	if err != nil {
-		return 0, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
 	}
 	if !initialized {
-		return 0, fmt.Errorf("contract options need to be set before calling any function, call Initialize() to initialize contract")
+		return "", kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
 	}
 
-	totalSupplyBytes, err := ctx.GetState(totalSupplyKey)
+	totalSupply, err := readAmount(ctx, totalSupplyKey)
 	if err != nil {
-		return 0, fmt.Errorf("failed to retrieve total token supply: %v", err)
-	}
-
-	var totalSupply int
-	if totalSupplyBytes == nil {
-		totalSupply = 0
-	} else {
-		totalSupply, _ = strconv.Atoi(string(totalSupplyBytes))
+		return "", fmt.Errorf("failed to retrieve total token supply: %v", err)
 	}
 
-	return totalSupply, nil
+	return totalSupply.String(), nil
 }
 
-func (c *TokenERC20Contract) Approve(ctx kalpsdk.TransactionContextInterface, spender string, value int) error {
+func (c *TokenERC20Contract) Approve(ctx kalpsdk.TransactionContextInterface, spender string, value string) error {
 	initialized, err := checkInitialized(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to check if contract is already initialized: %v", err)
 	}
 	if !initialized {
-		return fmt.Errorf("contract options need to be set before calling any function, call Initialize() to initialize contract")
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	err = checkNotPaused(ctx)
+	if err != nil {
+		return err
 	}
 
 	owner, err := ctx.GetUserID()
@@ -358,202 +382,518 @@ func (c *TokenERC20Contract) Approve(ctx kalpsdk.TransactionContextInterface, sp
 		return fmt.Errorf("failed to get client id: %v", err)
 	}
 
-	allowanceKey, err := ctx.CreateCompositeKey(allowancePrefix, []string{owner, spender})
+	allowance, err := parseAmount(value)
 	if err != nil {
-		return fmt.Errorf("failed to create the composite key for prefix %s: %v", allowancePrefix, err)
+		return err
 	}
 
-	err = ctx.PutStateWithoutKYC(allowanceKey, []byte(strconv.Itoa(value)))
+	return approveHelper(ctx, owner, spender, allowance)
+}
+
+func (c *TokenERC20Contract) Allowance(ctx kalpsdk.TransactionContextInterface, owner string, spender string) (string, error) {
+	initialized, err := checkInitialized(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to update state of smart contract for key %s: %v", allowanceKey, err)
+		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return "", kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
 	}
 
-	approvalEvent := event{owner, spender, value}
-	approvalEventJSON, err := json.Marshal(approvalEvent)
+	allowanceKey, err := ctx.CreateCompositeKey(allowancePrefix, []string{owner, spender})
 	if err != nil {
-		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+		return "", fmt.Errorf("failed to create the composite key for prefix %s: %v", allowancePrefix, err)
 	}
-	err = ctx.SetEvent("Approval", approvalEventJSON)
+
+	allowance, err := readAmount(ctx, allowanceKey)
 	if err != nil {
-		return fmt.Errorf("failed to set event: %v", err)
+		return "", fmt.Errorf("failed to read allowance for %s from world state: %v", allowanceKey, err)
 	}
 
-	return nil
+	return allowance.String(), nil
 }
 
-func (c *TokenERC20Contract) Allowance(ctx kalpsdk.TransactionContextInterface, owner string, spender string) (int, error) {
+func (c *TokenERC20Contract) TransferFrom(ctx kalpsdk.TransactionContextInterface, from string, to string, value string) error {
 	initialized, err := checkInitialized(ctx)
 	if err != nil {
-		return 0, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+		return fmt.Errorf("failed to check if contract is already initialized: %v", err)
 	}
 	if !initialized {
-		return 0, fmt.Errorf("contract options need to be set before calling any function, call Initialize() to initialize contract")
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
 	}
 
-	allowanceKey, err := ctx.CreateCompositeKey(allowancePrefix, []string{owner, spender})
+	err = checkNotPaused(ctx)
 	if err != nil {
-		return 0, fmt.Errorf("failed to create the composite key for prefix %s: %v", allowancePrefix, err)
+		return err
 	}
 
-	allowanceBytes, err := ctx.GetState(allowanceKey)
+	spender, err := ctx.GetUserID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	_, err = transferFromHelper(ctx, spender, from, to, value)
+	return err
+}
+
+// transferFromHelper moves value from from to to, spending the allowance from granted to spender,
+// and returns the amount to received after the transfer fee. Above the travel rule threshold the
+// transfer stores the travel rule information passed in the transient map, as
+// TransferWithTravelRule does.
+func transferFromHelper(ctx kalpsdk.TransactionContextInterface, spender string, from string, to string, value string) (*big.Int, error) {
+	allowanceKey, err := ctx.CreateCompositeKey(allowancePrefix, []string{from, spender})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the composite key for prefix %s: %v", allowancePrefix, err)
+	}
+
+	transferAmount, err := parseAmount(value)
+	if err != nil {
+		return nil, err
+	}
+
+	currentAllowance, err := readAmount(ctx, allowanceKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve the allowance for %s from world state: %v", allowanceKey, err)
+	}
+
+	if currentAllowance.Cmp(transferAmount) < 0 {
+		return nil, fmt.Errorf("spender does not have enough allowance for transfer")
+	}
+
+	travelRule, err := travelRuleFor(ctx, transferAmount)
 	if err != nil {
-		return 0, fmt.Errorf("failed to read allowance for %s from world state: %v", allowanceKey, err)
+		return nil, err
 	}
 
-	var allowance int
-	if allowanceBytes == nil {
-		allowance = 0
-	} else {
-		allowance, err = strconv.Atoi(string(allowanceBytes))
+	fee, err := transferHelper(ctx, from, to, transferAmount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to transfer: %v", err)
+	}
+	if travelRule != nil {
+		_, err = travelRule.store(ctx, from, to, transferAmount)
 		if err != nil {
-			return 0, fmt.Errorf("failed to convert allowance: %v", err)
+			return nil, err
 		}
 	}
 
-	return allowance, nil
+	updatedAllowance, err := sub(currentAllowance, transferAmount)
+	if err != nil {
+		return nil, err
+	}
+
+	err = writeAmount(ctx, allowanceKey, updatedAllowance)
+	if err != nil {
+		return nil, err
+	}
+
+	transferEvent := events.Transfer{From: from, To: to, Value: transferAmount, FeeCharged: fee}
+	err = events.Emit(ctx, "Transfer", &transferEvent)
+	if err != nil {
+		return nil, err
+	}
+
+	received := transferAmount
+	if fee != nil {
+		received = new(big.Int).Sub(transferAmount, fee.Amount)
+	}
+	return received, nil
 }
 
-func (c *TokenERC20Contract) TransferFrom(ctx kalpsdk.TransactionContextInterface, from string, to string, value int) error {
+// ContractTransfer moves funds out of a contract account (see ContractAccountID). It only
+// succeeds when called through the chaincode owning the from account.
+func (c *TokenERC20Contract) ContractTransfer(ctx kalpsdk.TransactionContextInterface, from string, to string, value string) error {
 	initialized, err := checkInitialized(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to check if contract is already initialized: %v", err)
 	}
 	if !initialized {
-		return fmt.Errorf("contract options need to be set before calling any function, call Initialize() to initialize contract")
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
 	}
 
-	spender, err := ctx.GetUserID()
+	err = checkNotPaused(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get client id: %v", err)
+		return err
 	}
 
-	allowanceKey, err := ctx.CreateCompositeKey(allowancePrefix, []string{from, spender})
+	err = checkContractAccount(ctx, from)
 	if err != nil {
-		return fmt.Errorf("failed to create the composite key for prefix %s: %v", allowancePrefix, err)
+		return err
 	}
 
-	currentAllowanceBytes, err := ctx.GetState(allowanceKey)
+	transferAmount, err := parseAmount(value)
 	if err != nil {
-		return fmt.Errorf("failed to retrieve the allowance for %s from world state: %v", allowanceKey, err)
+		return err
 	}
 
-	var currentAllowance int
-	currentAllowance, _ = strconv.Atoi(string(currentAllowanceBytes))
-
-	if currentAllowance < value {
-		return fmt.Errorf("spender does not have enough allowance for transfer")
+	err = checkTravelRule(ctx, transferAmount)
+	if err != nil {
+		return err
 	}
 
-	err = transferHelper(ctx, from, to, value)
+	fee, err := transferHelper(ctx, from, to, transferAmount)
 	if err != nil {
 		return fmt.Errorf("failed to transfer: %v", err)
 	}
 
-	updatedAllowance, err := sub(currentAllowance, value)
+	transferEvent := events.Transfer{From: from, To: to, Value: transferAmount, FeeCharged: fee}
+	err = events.Emit(ctx, "Transfer", &transferEvent)
 	if err != nil {
 		return err
 	}
 
-	err = ctx.PutStateWithoutKYC(allowanceKey, []byte(strconv.Itoa(updatedAllowance)))
+	return nil
+}
+
+// ContractTransferFrom moves value from from to to under the allowance from granted to spender, a
+// contract account (see ContractAccountID), and returns the amount to received after the transfer
+// fee. It only succeeds when called through the chaincode owning spender, so contracts pull the
+// tokens approved to them the way accounts do with TransferFrom.
+func (c *TokenERC20Contract) ContractTransferFrom(ctx kalpsdk.TransactionContextInterface, spender string, from string, to string, value string) (string, error) {
+	initialized, err := checkInitialized(ctx)
 	if err != nil {
-		return err
+		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return "", kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
 	}
 
-	transferEvent := event{from, to, value}
-	transferEventJSON, err := json.Marshal(transferEvent)
+	err = checkNotPaused(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+		return "", err
 	}
-	err = ctx.SetEvent("Transfer", transferEventJSON)
+
+	err = checkContractAccount(ctx, spender)
 	if err != nil {
-		return fmt.Errorf("failed to set event: %v", err)
+		return "", err
 	}
 
-	return nil
+	received, err := transferFromHelper(ctx, spender, from, to, value)
+	if err != nil {
+		return "", err
+	}
+	return received.String(), nil
 }
 
 func checkInitialized(ctx kalpsdk.TransactionContextInterface) (bool, error) {
-	tokenName, err := ctx.GetState(nameKey)
+	return config.IsInitialized(ctx, nameKey)
+}
+
+func _burn(ctx kalpsdk.TransactionContextInterface, account string, amount *big.Int) error {
+	currentBalance, exists, err := lookupBalance(ctx, account)
 	if err != nil {
-		return false, fmt.Errorf("failed to get token name: %v", err)
+		return err
 	}
-	if tokenName == nil {
-		return false, nil
+
+	if !exists {
+		return errors.New("the balance does not exist")
 	}
-	return true, nil
+
+	updatedBalance, err := sub(currentBalance, amount)
+	if err != nil {
+		return err
+	}
+
+	err = writeBalance(ctx, account, updatedBalance)
+	if err != nil {
+		return err
+	}
+
+	totalSupplyBytes, err := ctx.GetState(totalSupplyKey)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve total token supply: %v", err)
+	}
+
+	if totalSupplyBytes == nil {
+		return errors.New("totalSupply does not exist")
+	}
+
+	totalSupply, err := decodeAmount(totalSupplyBytes)
+	if err != nil {
+		return err
+	}
+
+	totalSupply, err = sub(totalSupply, amount)
+	if err != nil {
+		return err
+	}
+
+	err = writeBalance(ctx, totalSupplyKey, totalSupply)
+	if err != nil {
+		return err
+	}
+
+	err = metrics.Count(ctx, account, "0x0")
+	if err != nil {
+		return err
+	}
+
+	err = audit.Record(ctx, "Burn", account, amount.String())
+	if err != nil {
+		return err
+	}
+
+	transferEvent := events.Transfer{From: account, To: "0x0", Value: amount}
+	err = events.Emit(ctx, "Transfer", &transferEvent)
+	if err != nil {
+		return err
+	}
+
+	return nil
 }
 
-func transferHelper(ctx kalpsdk.TransactionContextInterface, from string, to string, value int) error {
-	if from == to {
-		return fmt.Errorf("cannot transfer to and from same client account")
+func _mint(ctx kalpsdk.TransactionContextInterface, account string, amount *big.Int) error {
+	err := checkNotFrozen(ctx, account)
+	if err != nil {
+		return err
+	}
+
+	err = creditBalance(ctx, account, amount)
+	if err != nil {
+		return err
 	}
-	if value < 0 {
-		return fmt.Errorf("transfer amount cannot be negative")
+
+	totalSupply, err := readAmount(ctx, totalSupplyKey)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve total token supply: %v", err)
 	}
 
-	fromCurrentBalanceBytes, err := ctx.GetState(from)
+	totalSupply, err = add(totalSupply, amount)
 	if err != nil {
-		return fmt.Errorf("failed to read client account %s from world state: %v", from, err)
+		return err
 	}
-	if fromCurrentBalanceBytes == nil {
-		return fmt.Errorf("client account %s has no balance", from)
+	err = config.CheckMaxSupply(ctx, totalSupply)
+	if err != nil {
+		return err
 	}
 
-	fromCurrentBalance, _ := strconv.Atoi(string(fromCurrentBalanceBytes))
-	if fromCurrentBalance < value {
-		return fmt.Errorf("client account %s has insufficient funds", from)
+	err = writeBalance(ctx, totalSupplyKey, totalSupply)
+	if err != nil {
+		return err
 	}
 
-	toCurrentBalanceBytes, err := ctx.GetState(to)
+	err = metrics.Count(ctx, "0x0", account)
 	if err != nil {
-		return fmt.Errorf("failed to read recipient account %s from world state: %v", to, err)
+		return err
 	}
 
-	var toCurrentBalance int
-	if toCurrentBalanceBytes == nil {
-		toCurrentBalance = 0
-	} else {
-		toCurrentBalance, _ = strconv.Atoi(string(toCurrentBalanceBytes))
+	err = audit.Record(ctx, "Mint", account, amount.String())
+	if err != nil {
+		return err
 	}
 
-	fromUpdatedBalance, err := sub(fromCurrentBalance, value)
+	transferEvent := events.Transfer{From: "0x0", To: account, Value: amount}
+	err = events.Emit(ctx, "Transfer", &transferEvent)
 	if err != nil {
 		return err
 	}
 
-	toUpdatedBalance, err := add(toCurrentBalance, value)
+	return nil
+}
+
+func approveHelper(ctx kalpsdk.TransactionContextInterface, owner string, spender string, allowance *big.Int) error {
+	allowanceKey, err := ctx.CreateCompositeKey(allowancePrefix, []string{owner, spender})
+	if err != nil {
+		return fmt.Errorf("failed to create the composite key for prefix %s: %v", allowancePrefix, err)
+	}
+
+	err = writeAmount(ctx, allowanceKey, allowance)
+	if err != nil {
+		return fmt.Errorf("failed to update state of smart contract for key %s: %v", allowanceKey, err)
+	}
+
+	approvalEvent := events.Approval{From: owner, To: spender, Value: allowance}
+	err = events.Emit(ctx, "Approval", &approvalEvent)
 	if err != nil {
 		return err
 	}
 
-	err = ctx.PutStateWithoutKYC(from, []byte(strconv.Itoa(fromUpdatedBalance)))
+	return nil
+}
+
+// transferHelper moves value from from to to, withholding the configured transfer fee from the
+// amount credited to to. It returns the fee charged, if any.
+func transferHelper(ctx kalpsdk.TransactionContextInterface, from string, to string, value *big.Int) (*FeeCharged, error) {
+	fees, err := transferManyHelper(ctx, from, []string{to}, []*big.Int{value})
+	if err != nil {
+		return nil, err
+	}
+	return fees[0], nil
+}
+
+// transferManyHelper moves values[i] from from to tos[i] like transferHelper and returns the fee
+// charged on every transfer. World state reads do not see earlier writes of the same transaction,
+// so from is debited once and the credits of each account, fees included, are summed before any
+// balance is updated.
+func transferManyHelper(ctx kalpsdk.TransactionContextInterface, from string, tos []string, values []*big.Int) ([]*FeeCharged, error) {
+	total := new(big.Int)
+	for i, to := range tos {
+		if from == to {
+			return nil, fmt.Errorf("cannot transfer to and from same client account")
+		}
+		if values[i].Sign() < 0 {
+			return nil, fmt.Errorf("transfer amount cannot be negative")
+		}
+		total.Add(total, values[i])
+	}
+
+	err := checkNotFrozen(ctx, append([]string{from}, tos...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	fromCurrentBalance, exists, err := lookupBalance(ctx, from)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("client account %s has no balance", from)
+	}
+	if fromCurrentBalance.Cmp(total) < 0 {
+		return nil, kusherrors.Errorf(kusherrors.ErrInsufficientBalance, "client account %s has insufficient funds", from)
+	}
+
+	fees := make([]*FeeCharged, len(tos))
+	credits := make(map[string]*big.Int)
+	order := []string{}
+	for i, to := range tos {
+		fees[i], err = transferFeeFor(ctx, from, to, values[i])
+		if err != nil {
+			return nil, err
+		}
+		received := values[i]
+		if fees[i] != nil {
+			received = new(big.Int).Sub(values[i], fees[i].Amount)
+			order = addCredit(credits, order, fees[i].Collector, fees[i].Amount)
+		}
+		order = addCredit(credits, order, to, received)
+	}
+
+	fromUpdatedBalance, err := sub(fromCurrentBalance, total)
+	if err != nil {
+		return nil, err
+	}
+
+	err = writeBalance(ctx, from, fromUpdatedBalance)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, account := range order {
+		err = creditBalance(ctx, account, credits[account])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = metrics.Count(ctx, from, tos...)
+	if err != nil {
+		return nil, err
+	}
+
+	return fees, nil
+}
+
+// moveBalance moves amount between two accounts without charging the transfer fee. It is used to
+// move tokens in and out of the contract's internal escrow accounts.
+func moveBalance(ctx kalpsdk.TransactionContextInterface, from string, to string, amount *big.Int) error {
+	return moveBalances(ctx, from, []string{to}, []*big.Int{amount})
+}
+
+// moveBalances debits from once and credits tos[i] with amounts[i]. World state reads do not see
+// earlier writes of the same transaction, so paying several accounts out of one account has to go
+// through a single debit; the recipients must be distinct.
+func moveBalances(ctx kalpsdk.TransactionContextInterface, from string, tos []string, amounts []*big.Int) error {
+	err := checkNotFrozen(ctx, append([]string{from}, tos...)...)
 	if err != nil {
 		return err
 	}
 
-	err = ctx.PutStateWithoutKYC(to, []byte(strconv.Itoa(toUpdatedBalance)))
+	total := new(big.Int)
+	seen := make(map[string]bool)
+	for i, to := range tos {
+		if to == from || seen[to] {
+			return fmt.Errorf("account %s is credited more than once", to)
+		}
+		seen[to] = true
+		total.Add(total, amounts[i])
+	}
+
+	fromBalance, err := readBalance(ctx, from)
+	if err != nil {
+		return err
+	}
+	if fromBalance.Cmp(total) < 0 {
+		return kusherrors.Errorf(kusherrors.ErrInsufficientBalance, "account %s has insufficient funds", from)
+	}
+	fromBalance, err = sub(fromBalance, total)
+	if err != nil {
+		return err
+	}
+	err = writeBalance(ctx, from, fromBalance)
 	if err != nil {
 		return err
 	}
 
+	for i, to := range tos {
+		err = creditBalance(ctx, to, amounts[i])
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
-func add(b int, q int) (int, error) {
-	sum := q + b
-	if (sum < q || sum < b) == (b >= 0 && q >= 0) {
-		return 0, fmt.Errorf("Math: addition overflow occurred %d + %d", b, q)
+// parseAmount converts a decimal amount argument into a big.Int. Only non-negative base-10
+// integers are accepted, so amounts can never be finer than the configured decimals.
+func parseAmount(amount string) (*big.Int, error) {
+	if strings.Contains(amount, ".") {
+		return nil, fmt.Errorf("amount %q must be an integer number of base units, use ToBaseUnits to convert it", amount)
+	}
+	value, ok := new(big.Int).SetString(amount, 10)
+	if !ok || value.Sign() < 0 {
+		return nil, fmt.Errorf("amount %q must be a non-negative integer", amount)
+	}
+	return value, nil
+}
+
+// decodeAmount parses an amount stored in world state. Values are kept as decimal strings,
+// which is also what earlier versions wrote with strconv.Itoa, so legacy state reads as-is.
+func decodeAmount(amountBytes []byte) (*big.Int, error) {
+	value, ok := new(big.Int).SetString(string(amountBytes), 10)
+	if !ok {
+		return nil, fmt.Errorf("failed to decode stored amount %q", string(amountBytes))
+	}
+	return value, nil
+}
+
+// readAmount returns the amount stored under key, or zero if the key does not exist.
+func readAmount(ctx kalpsdk.TransactionContextInterface, key string) (*big.Int, error) {
+	amountBytes, err := ctx.GetState(key)
+	if err != nil {
+		return nil, err
+	}
+	if amountBytes == nil {
+		return new(big.Int), nil
+	}
+	return decodeAmount(amountBytes)
+}
+
+func writeAmount(ctx kalpsdk.TransactionContextInterface, key string, amount *big.Int) error {
+	return accesscontrol.PutState(ctx, key, []byte(amount.String()))
+}
+
+func add(b *big.Int, q *big.Int) (*big.Int, error) {
+	if b.Sign() < 0 || q.Sign() < 0 {
+		return nil, fmt.Errorf("Math: addition of negative amounts %s + %s", b, q)
 	}
-	return sum, nil
+	return new(big.Int).Add(b, q), nil
 }
 
-func sub(b int, q int) (int, error) {
-	if q <= 0 {
-		return 0, fmt.Errorf("Error: the subtraction number is %d, it should be greater than 0", q)
+func sub(b *big.Int, q *big.Int) (*big.Int, error) {
+	if q.Sign() <= 0 {
+		return nil, fmt.Errorf("Error: the subtraction number is %s, it should be greater than 0", q)
 	}
-	if b < q {
-		return 0, fmt.Errorf("Error: the number %d is not enough to be subtracted by %d", b, q)
+	if b.Cmp(q) < 0 {
+		return nil, kusherrors.Errorf(kusherrors.ErrInsufficientBalance, "Error: the number %s is not enough to be subtracted by %s", b, q)
 	}
-	diff := b - q
-	return diff, nil
+	return new(big.Int).Sub(b, q), nil
 }
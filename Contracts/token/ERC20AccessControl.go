@@ -0,0 +1,149 @@
+package token
+
+import (
+	"fmt"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+// BootstrapRoles grants every built-in role to the caller, who must belong to an authorized MSP.
+// Initialize does this for new contracts; contracts initialized before roles existed call it once,
+// passing their authorized MSPs if they never stored any, see accesscontrol.Bootstrap.
+func (c *TokenERC20Contract) BootstrapRoles(ctx kalpsdk.TransactionContextInterface, authorizedMSPs []string) error {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return accesscontrol.Bootstrap(ctx, authorizedMSPs)
+}
+
+// GrantMinter gives account the MINTER_ROLE. Only admins of that role may call it.
+func (c *TokenERC20Contract) GrantMinter(ctx kalpsdk.TransactionContextInterface, account string) error {
+	return c.GrantRole(ctx, accesscontrol.MinterRole, account)
+}
+
+// RevokeMinter removes the MINTER_ROLE from account. Only admins of that role may call it.
+func (c *TokenERC20Contract) RevokeMinter(ctx kalpsdk.TransactionContextInterface, account string) error {
+	return c.RevokeRole(ctx, accesscontrol.MinterRole, account)
+}
+
+// GrantRole gives account role. The caller must hold the admin role of role.
+func (c *TokenERC20Contract) GrantRole(ctx kalpsdk.TransactionContextInterface, role string, account string) error {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return accesscontrol.GrantRole(ctx, role, account)
+}
+
+// RevokeRole removes role from account. The caller must hold the admin role of role.
+func (c *TokenERC20Contract) RevokeRole(ctx kalpsdk.TransactionContextInterface, role string, account string) error {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return accesscontrol.RevokeRole(ctx, role, account)
+}
+
+// RenounceRole removes role from the caller, whose ID must be passed as account.
+func (c *TokenERC20Contract) RenounceRole(ctx kalpsdk.TransactionContextInterface, role string, account string) error {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return accesscontrol.RenounceRole(ctx, role, account)
+}
+
+// SetRoleAdmin makes adminRole the admin of role. The caller must hold the current admin role.
+func (c *TokenERC20Contract) SetRoleAdmin(ctx kalpsdk.TransactionContextInterface, role string, adminRole string) error {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return accesscontrol.SetRoleAdmin(ctx, role, adminRole)
+}
+
+func (c *TokenERC20Contract) GetRoleAdmin(ctx kalpsdk.TransactionContextInterface, role string) (string, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return "", kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return accesscontrol.GetRoleAdmin(ctx, role)
+}
+
+func (c *TokenERC20Contract) HasRole(ctx kalpsdk.TransactionContextInterface, role string, account string) (bool, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return false, kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	return accesscontrol.HasRole(ctx, role, account)
+}
+
+// checkAdmin fails unless the calling user holds the ADMIN_ROLE.
+func checkAdmin(ctx kalpsdk.TransactionContextInterface) error {
+	err := accesscontrol.CheckRole(ctx, accesscontrol.AdminRole)
+	if err != nil {
+		return fmt.Errorf("client is not authorized to perform admin operations: %v", err)
+	}
+	return nil
+}
+
+// AddAuthorizedOrg lets members of mspID exercise minting and burning roles. Only admins may call it.
+func (c *TokenERC20Contract) AddAuthorizedOrg(ctx kalpsdk.TransactionContextInterface, mspID string) error {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return accesscontrol.AddAuthorizedOrg(ctx, mspID)
+}
+
+// RemoveAuthorizedOrg suspends the roles held by members of mspID. Only admins may call it.
+func (c *TokenERC20Contract) RemoveAuthorizedOrg(ctx kalpsdk.TransactionContextInterface, mspID string) error {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return accesscontrol.RemoveAuthorizedOrg(ctx, mspID)
+}
+
+// ListAuthorizedOrgs returns the MSPs whose members may exercise minting and burning roles.
+func (c *TokenERC20Contract) ListAuthorizedOrgs(ctx kalpsdk.TransactionContextInterface) ([]string, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return accesscontrol.GetAuthorizedMSPs(ctx)
+}
@@ -0,0 +1,198 @@
+package token
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+	"github.com/thekalpstudio/kush-go/events"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+	"github.com/thekalpstudio/kush-go/metrics"
+)
+
+// maxAirdropRecipients bounds the write set of a single Airdrop call. Longer lists are processed
+// up to this many entries and the report's NextIndex tells the caller where to resume.
+const maxAirdropRecipients = 500
+
+// AirdropFailure records an entry that was skipped.
+type AirdropFailure struct {
+	Index   int    `json:"index"`
+	Account string `json:"account"`
+	Reason  string `json:"reason"`
+}
+
+// AirdropReport summarizes one Airdrop call.
+type AirdropReport struct {
+	Processed int              `json:"processed"`
+	Succeeded int              `json:"succeeded"`
+	Total     *big.Int         `json:"total"`
+	Failures  []AirdropFailure `json:"failures"`
+	NextIndex int              `json:"nextIndex"`
+	Done      bool             `json:"done"`
+}
+
+// AirdropEvent lists every credited recipient, the per-recipient transfers emitted together.
+type AirdropEvent = events.Airdrop
+
+// Airdrop credits accounts[i] with amounts[i], either minting the tokens (mint) or transferring
+// them out of the caller's balance, which charges the transfer fee like Transfer. Invalid or
+// frozen recipients, and transfers that would need travel rule information, are skipped and
+// reported instead of failing the whole batch.
+func (c *TokenERC20Contract) Airdrop(ctx kalpsdk.TransactionContextInterface, accounts []string, amounts []string, mint bool) (*AirdropReport, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	err = checkNotPaused(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if mint {
+		err = accesscontrol.CheckOrgRole(ctx, accesscontrol.MinterRole)
+		if err != nil {
+			return nil, fmt.Errorf("client is not authorized to mint new tokens: %v", err)
+		}
+	}
+
+	if len(accounts) != len(amounts) {
+		return nil, fmt.Errorf("accounts and amounts must have the same length")
+	}
+
+	clientID, err := ctx.GetUserID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client id: %v", err)
+	}
+	from := clientID
+	if mint {
+		from = "0x0"
+	}
+
+	report := &AirdropReport{Total: new(big.Int), Failures: []AirdropFailure{}}
+	recipients := []string{}
+	values := []*big.Int{}
+	legs := []events.Transfer{}
+	end := len(accounts)
+	if end > maxAirdropRecipients {
+		end = maxAirdropRecipients
+	}
+	for i := 0; i < end; i++ {
+		account := accounts[i]
+		report.Processed++
+		amount, reason := validateAirdropEntry(ctx, clientID, account, amounts[i], mint)
+		if reason != "" {
+			report.Failures = append(report.Failures, AirdropFailure{i, account, reason})
+			continue
+		}
+		recipients = append(recipients, account)
+		values = append(values, amount)
+		report.Total.Add(report.Total, amount)
+		report.Succeeded++
+		legs = append(legs, events.Transfer{From: from, To: account, Value: amount})
+	}
+	report.NextIndex = end
+	report.Done = end == len(accounts)
+
+	if len(recipients) == 0 {
+		return report, nil
+	}
+
+	if mint {
+		err = accesscontrol.CheckMintThreshold(ctx, report.Total)
+		if err != nil {
+			return nil, err
+		}
+		// World state reads do not see earlier writes of the same transaction, so the credits of
+		// each recipient are summed before any balance is updated.
+		credits := make(map[string]*big.Int)
+		order := []string{}
+		for i, account := range recipients {
+			order = addCredit(credits, order, account, values[i])
+		}
+		creditAmounts := make([]*big.Int, len(order))
+		for i, account := range order {
+			creditAmounts[i] = credits[account]
+		}
+		err = mintBatch(ctx, order, creditAmounts, report.Total)
+		if err != nil {
+			return nil, err
+		}
+		err = metrics.Count(ctx, from, order...)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		fees, err := transferManyHelper(ctx, clientID, recipients, values)
+		if err != nil {
+			return nil, err
+		}
+		for i := range legs {
+			legs[i].FeeCharged = fees[i]
+		}
+	}
+
+	airdropEvent := AirdropEvent{From: from, Transfers: legs}
+	err = events.Emit(ctx, "Airdrop", &airdropEvent)
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// validateAirdropEntry returns the parsed amount of an entry, or the reason it has to be skipped.
+// Entries that are not minted are transfers, which may need travel rule information.
+func validateAirdropEntry(ctx kalpsdk.TransactionContextInterface, clientID string, account string, amount string, mint bool) (*big.Int, string) {
+	if account == "" || account == "0x0" || account == clientID {
+		return nil, "invalid recipient"
+	}
+	value, err := parseAmount(amount)
+	if err != nil {
+		return nil, err.Error()
+	}
+	if value.Sign() <= 0 {
+		return nil, "amount must be a positive integer"
+	}
+	frozen, err := isFrozen(ctx, account)
+	if err != nil {
+		return nil, err.Error()
+	}
+	if frozen {
+		return nil, "account is frozen"
+	}
+	if !mint {
+		err = checkTravelRule(ctx, value)
+		if err != nil {
+			return nil, err.Error()
+		}
+	}
+	return value, ""
+}
+
+// mintBatch credits distinct accounts and raises the total supply once by total.
+func mintBatch(ctx kalpsdk.TransactionContextInterface, accounts []string, amounts []*big.Int, total *big.Int) error {
+	for i, account := range accounts {
+		err := creditBalance(ctx, account, amounts[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	totalSupply, err := readAmount(ctx, totalSupplyKey)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve total token supply: %v", err)
+	}
+	totalSupply, err = add(totalSupply, total)
+	if err != nil {
+		return err
+	}
+	return writeBalance(ctx, totalSupplyKey, totalSupply)
+}
@@ -0,0 +1,59 @@
+package token
+
+import (
+	"fmt"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+	"github.com/thekalpstudio/kush-go/pagination"
+)
+
+// AllowanceEntry is an allowance granted by an owner. Allowances do not expire; they last until
+// they are spent or approved away.
+type AllowanceEntry struct {
+	Spender string `json:"spender"`
+	Value   string `json:"value"`
+}
+
+// AllowancePage is one page of ListAllowances. Bookmark is empty on the last page.
+type AllowancePage struct {
+	Allowances []*AllowanceEntry `json:"allowances"`
+	Bookmark   string            `json:"bookmark"`
+}
+
+// ListAllowances returns the allowances owner granted, reading up to pageSize allowance keys
+// starting at bookmark, so owners can find and revoke approvals they forgot. Spent or revoked
+// allowances are left out, so a page may hold fewer than pageSize entries before the last one.
+func (c *TokenERC20Contract) ListAllowances(ctx kalpsdk.TransactionContextInterface, owner string, bookmark string, pageSize int) (*AllowancePage, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	if owner == "" {
+		return nil, fmt.Errorf("owner must be set")
+	}
+
+	states, next, err := pagination.ByPartialCompositeKey(ctx, allowancePrefix, []string{owner}, bookmark, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	page := &AllowancePage{Allowances: []*AllowanceEntry{}, Bookmark: next}
+	for _, state := range states {
+		_, parts, err := ctx.SplitCompositeKey(state.Key)
+		if err != nil || len(parts) != 2 {
+			return nil, fmt.Errorf("invalid allowance key %s", state.Key)
+		}
+		value, err := decodeAmount(state.Value)
+		if err != nil {
+			return nil, err
+		}
+		if value.Sign() == 0 {
+			continue
+		}
+		page.Allowances = append(page.Allowances, &AllowanceEntry{parts[1], value.String()})
+	}
+	return page, nil
+}
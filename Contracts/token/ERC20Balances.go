@@ -0,0 +1,232 @@
+package token
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/config"
+	"github.com/thekalpstudio/kush-go/internal/txstate"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+	"github.com/thekalpstudio/kush-go/metrics"
+)
+
+// balanceDeltaPrefix keys the credits stored as delta records when config.Features.DeltaBalances
+// is set: the amount credited to an account by a transaction, numbered within the transaction.
+// Credits then write new keys only, so transfers to a busy account, such as a merchant or the fee
+// collector, no longer conflict with each other. The balance of an account is its stored balance
+// plus its delta records; every write of the stored balance, as by a debit, folds the records
+// into it, and CompactBalances folds those of accounts that only receive.
+const balanceDeltaPrefix = "balance~delta"
+
+// maxBalanceDeltas bounds the delta records read with a stored balance. An account with more
+// reports the balance including its first maxBalanceDeltas records, which is never more than it
+// holds, until writes of its balance or CompactBalances have folded the rest.
+const maxBalanceDeltas = 100
+
+// creditSeqKey keys the number of delta records a transaction wrote in its transaction state.
+type creditSeqKey struct{}
+
+// nextCreditSeq numbers the delta records of the transaction of ctx.
+func nextCreditSeq(ctx kalpsdk.TransactionContextInterface) (int, error) {
+	seq, err := txstate.Get(ctx, creditSeqKey{}, func() interface{} { return new(int) })
+	if err != nil {
+		return 0, fmt.Errorf("failed to number the credit: %v", err)
+	}
+	next := seq.(*int)
+	*next++
+	return *next - 1, nil
+}
+
+// CompactBalances folds the delta records of accounts into their stored balances and returns how
+// many accounts had records. Every call folds up to maxBalanceDeltas records per account, so
+// accounts receiving many transfers should be compacted from time to time. The caller must hold
+// AdminRole.
+func (c *TokenERC20Contract) CompactBalances(ctx kalpsdk.TransactionContextInterface, accounts []string) (int, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return 0, kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	err = checkAdmin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if len(accounts) > maxBatchTransferLegs {
+		return 0, fmt.Errorf("at most %d accounts can be compacted at once", maxBatchTransferLegs)
+	}
+
+	compacted := 0
+	for _, account := range accounts {
+		deltas, err := balanceDeltas(ctx, account)
+		if err != nil {
+			return 0, err
+		}
+		if len(deltas) == 0 {
+			continue
+		}
+		balance, err := readBalance(ctx, account)
+		if err != nil {
+			return 0, err
+		}
+		err = writeBalance(ctx, account, balance)
+		if err != nil {
+			return 0, err
+		}
+		compacted++
+	}
+	return compacted, nil
+}
+
+// reservedAccounts are the keys the contract stores next to the balances, which are kept under
+// the account ID itself.
+var reservedAccounts = map[string]bool{
+	nameKey:                true,
+	symbolKey:              true,
+	decimalsKey:            true,
+	totalSupplyKey:         true,
+	emissionScheduleKey:    true,
+	transferFeeKey:         true,
+	pausedKey:              true,
+	snapshotIdKey:          true,
+	travelRuleConfigKey:    true,
+	travelRuleTransientKey: true,
+}
+
+// checkAccount fails unless account can be used as a balance key: it must not be one of the
+// reservedAccounts, nor contain "~", which the keys of the shared packages are namespaced with, or
+// the null byte composite keys are built with.
+func checkAccount(account string) error {
+	if account == "" || reservedAccounts[account] || strings.ContainsAny(account, "~\x00") {
+		return kusherrors.Errorf(kusherrors.ErrInvalidAccount, "%q is not a valid account", account)
+	}
+	return nil
+}
+
+// lookupBalance returns the balance of account and whether the account has one at all.
+func lookupBalance(ctx kalpsdk.TransactionContextInterface, account string) (*big.Int, bool, error) {
+	err := checkAccount(account)
+	if err != nil {
+		return nil, false, err
+	}
+	balanceBytes, err := ctx.GetState(account)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to read account %s from world state: %v", account, err)
+	}
+	balance := new(big.Int)
+	exists := balanceBytes != nil
+	if exists {
+		balance, err = decodeAmount(balanceBytes)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	deltas, err := balanceDeltas(ctx, account)
+	if err != nil {
+		return nil, false, err
+	}
+	for _, state := range deltas {
+		delta, err := decodeAmount(state.Value)
+		if err != nil {
+			return nil, false, err
+		}
+		balance.Add(balance, delta)
+		exists = true
+	}
+	return balance, exists, nil
+}
+
+// readBalance returns the balance of account, or zero if it has none.
+func readBalance(ctx kalpsdk.TransactionContextInterface, account string) (*big.Int, error) {
+	balance, _, err := lookupBalance(ctx, account)
+	return balance, err
+}
+
+// creditBalance adds amount to the balance of account. With delta balances it appends a delta
+// record instead of reading and rewriting the balance.
+func creditBalance(ctx kalpsdk.TransactionContextInterface, account string, amount *big.Int) error {
+	err := checkAccount(account)
+	if err != nil {
+		return err
+	}
+	deltaBalances, err := config.GetDeltaBalances(ctx)
+	if err != nil {
+		return err
+	}
+	if !deltaBalances {
+		balance, err := readBalance(ctx, account)
+		if err != nil {
+			return err
+		}
+		balance, err = add(balance, amount)
+		if err != nil {
+			return err
+		}
+		return writeBalance(ctx, account, balance)
+	}
+
+	err = checkpointBalance(ctx, account)
+	if err != nil {
+		return err
+	}
+	if amount.Sign() > 0 {
+		err = metrics.Hold(ctx, account, true)
+		if err != nil {
+			return err
+		}
+	}
+	seq, err := nextCreditSeq(ctx)
+	if err != nil {
+		return err
+	}
+	deltaKey, err := ctx.CreateCompositeKey(balanceDeltaPrefix, []string{account, ctx.GetTxID(), fmt.Sprintf("%06d", seq)})
+	if err != nil {
+		return fmt.Errorf("failed to create the composite key for prefix %s: %v", balanceDeltaPrefix, err)
+	}
+	return writeAmount(ctx, deltaKey, amount)
+}
+
+// foldBalanceDeltas deletes the delta records of account once a balance including them is stored:
+// the first maxBalanceDeltas, those lookupBalance read. Records written earlier in the same
+// transaction are not visible yet, so they are kept, as the stored balance does not include them
+// either.
+//
+// Reading the records is a range read, so a transaction writing the stored balance, such as a
+// debit, fails validation when a concurrent transaction credits the account. Delta balances only
+// spare accounts that mostly receive.
+func foldBalanceDeltas(ctx kalpsdk.TransactionContextInterface, account string) error {
+	deltas, err := balanceDeltas(ctx, account)
+	if err != nil {
+		return err
+	}
+	for _, state := range deltas {
+		err = ctx.DelStateWithoutKYC(state.Key)
+		if err != nil {
+			return fmt.Errorf("failed to delete balance delta %s: %v", state.Key, err)
+		}
+	}
+	return nil
+}
+
+// balanceDeltas returns the first maxBalanceDeltas delta records of account.
+func balanceDeltas(ctx kalpsdk.TransactionContextInterface, account string) ([]*queryresult.KV, error) {
+	iterator, err := ctx.GetStateByPartialCompositeKey(balanceDeltaPrefix, []string{account})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get state for prefix %v: %v", balanceDeltaPrefix, err)
+	}
+	defer iterator.Close()
+	deltas := []*queryresult.KV{}
+	for len(deltas) < maxBalanceDeltas && iterator.HasNext() {
+		queryResponse, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the next state for prefix %v: %v", balanceDeltaPrefix, err)
+		}
+		deltas = append(deltas, queryResponse)
+	}
+	return deltas, nil
+}
@@ -0,0 +1,116 @@
+package token
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/thekalpstudio/kush-go/testutil"
+)
+
+// newDeltaERC20 returns a token storing credits as delta records, of which admin holds 1000.
+func newDeltaERC20(t *testing.T) (*testutil.Ledger, *TokenERC20Contract) {
+	t.Helper()
+	l := testutil.NewLedger()
+	c := new(TokenERC20Contract)
+	testutil.MustRun(t, l, admin, "Initialize", func(ctx *testutil.Context) error {
+		cfg := testConfig()
+		cfg.Features.DeltaBalances = true
+		_, err := c.Initialize(ctx, cfg)
+		return err
+	})
+	testutil.MustRun(t, l, admin, "Mint", func(ctx *testutil.Context) error {
+		return c.Mint(ctx, "1000")
+	})
+	return l, c
+}
+
+// deltaRecords counts the delta records of account on l.
+func deltaRecords(t *testing.T, l *testutil.Ledger, account string) int {
+	t.Helper()
+	ctx := l.Tx(admin, "Keys")
+	records := 0
+	for _, key := range l.Keys() {
+		objectType, attributes, err := ctx.SplitCompositeKey(key)
+		if err == nil && objectType == balanceDeltaPrefix && attributes[0] == account {
+			records++
+		}
+	}
+	return records
+}
+
+func TestERC20DeltaBalances(t *testing.T) {
+	l, c := newDeltaERC20(t)
+	for i := 0; i < 3; i++ {
+		testutil.MustRun(t, l, admin, "Transfer", func(ctx *testutil.Context) error {
+			return c.Transfer(ctx, "bob", "100")
+		})
+	}
+	if records := deltaRecords(t, l, "bob"); records != 3 {
+		t.Fatalf("bob has %d delta records, want 3", records)
+	}
+	if balance := erc20Balance(t, l, c, "bob"); balance != "300" {
+		t.Fatalf("balance of bob is %s, want 300", balance)
+	}
+
+	// a debit stores the balance and folds the records into it
+	testutil.MustRun(t, l, "bob", "Transfer", func(ctx *testutil.Context) error {
+		return c.Transfer(ctx, "carol", "50")
+	})
+	if records := deltaRecords(t, l, "bob"); records != 0 {
+		t.Fatalf("bob has %d delta records after a debit, want 0", records)
+	}
+	for account, want := range map[string]string{admin: "700", "bob": "250", "carol": "50"} {
+		if balance := erc20Balance(t, l, c, account); balance != want {
+			t.Fatalf("balance of %s is %s, want %s", account, balance, want)
+		}
+	}
+
+	var compacted int
+	testutil.MustRun(t, l, admin, "CompactBalances", func(ctx *testutil.Context) error {
+		var err error
+		compacted, err = c.CompactBalances(ctx, []string{"bob", "carol"})
+		return err
+	})
+	if compacted != 1 || deltaRecords(t, l, "carol") != 0 {
+		t.Fatalf("compacted %d accounts, want carol only", compacted)
+	}
+	if balance := erc20Balance(t, l, c, "carol"); balance != "50" {
+		t.Fatalf("balance of carol is %s after compaction, want 50", balance)
+	}
+	ctx := l.Tx(admin, "TotalSupply")
+	if supply, err := c.TotalSupply(ctx); err != nil || supply != "1000" {
+		t.Fatalf("total supply is %s, want 1000: %v", supply, err)
+	}
+	if holders, err := c.HolderCount(ctx); err != nil || holders != 3 {
+		t.Fatalf("token has %d holders, want 3: %v", holders, err)
+	}
+}
+
+// TestERC20DeltaBalancesBounded reads and folds at most maxBalanceDeltas records of an account per
+// transaction.
+func TestERC20DeltaBalancesBounded(t *testing.T) {
+	l, c := newDeltaERC20(t)
+	testutil.MustRun(t, l, admin, "Credit", func(ctx *testutil.Context) error {
+		for i := 0; i < maxBalanceDeltas+5; i++ {
+			err := creditBalance(ctx, "dave", big.NewInt(1))
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if balance := erc20Balance(t, l, c, "dave"); balance != "100" {
+		t.Fatalf("balance of dave is %s, want the first %d records", balance, maxBalanceDeltas)
+	}
+
+	testutil.MustRun(t, l, admin, "CompactBalances", func(ctx *testutil.Context) error {
+		_, err := c.CompactBalances(ctx, []string{"dave"})
+		return err
+	})
+	if records := deltaRecords(t, l, "dave"); records != 5 {
+		t.Fatalf("dave has %d delta records after compaction, want 5", records)
+	}
+	if balance := erc20Balance(t, l, c, "dave"); balance != "105" {
+		t.Fatalf("balance of dave is %s after compaction, want 105", balance)
+	}
+}
@@ -0,0 +1,178 @@
+package token
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/events"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+	"github.com/thekalpstudio/kush-go/metrics"
+)
+
+// maxBatchTransferLegs bounds the size of a BatchTransfer so the write set stays reasonable.
+const maxBatchTransferLegs = 500
+
+// TransferBatchEvent lists every leg of a BatchTransfer, emitted together instead of as separate
+// Transfer events.
+type TransferBatchEvent = events.BatchTransfer
+
+// BatchTransfer debits the caller once for the sum of amounts and credits recipients[i] with
+// amounts[i], less the transfer fee. Either every leg succeeds or none does.
+func (c *TokenERC20Contract) BatchTransfer(ctx kalpsdk.TransactionContextInterface, recipients []string, amounts []string) error {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	err = checkNotPaused(ctx)
+	if err != nil {
+		return err
+	}
+
+	if len(recipients) == 0 {
+		return fmt.Errorf("at least one recipient is required")
+	}
+	if len(recipients) != len(amounts) {
+		return fmt.Errorf("recipients and amounts must have the same length")
+	}
+	if len(recipients) > maxBatchTransferLegs {
+		return fmt.Errorf("a batch transfer may have at most %d recipients", maxBatchTransferLegs)
+	}
+
+	clientID, err := ctx.GetUserID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	// World state reads do not observe writes made earlier in the same transaction, so credits
+	// to the same recipient, fees included, are summed before any balance is updated.
+	total := new(big.Int)
+	var totalFee *FeeCharged
+	credits := make(map[string]*big.Int)
+	order := []string{}
+	legs := make([]events.Transfer, 0, len(recipients))
+	for i, recipient := range recipients {
+		if recipient == "" || recipient == "0x0" {
+			return kusherrors.Errorf(kusherrors.ErrInvalidAccount, "%q is not a valid recipient", recipient)
+		}
+		if recipient == clientID {
+			return fmt.Errorf("cannot transfer to and from same client account")
+		}
+		amount, err := parseAmount(amounts[i])
+		if err != nil {
+			return err
+		}
+		if amount.Sign() <= 0 {
+			return fmt.Errorf("transfer amount to %s must be a positive integer", recipient)
+		}
+		err = checkTravelRule(ctx, amount)
+		if err != nil {
+			return err
+		}
+		fee, err := transferFeeFor(ctx, clientID, recipient, amount)
+		if err != nil {
+			return err
+		}
+		received := amount
+		if fee != nil {
+			received = new(big.Int).Sub(amount, fee.Amount)
+			if totalFee == nil {
+				totalFee = &FeeCharged{From: clientID, Collector: fee.Collector, Amount: new(big.Int)}
+			}
+			totalFee.Amount.Add(totalFee.Amount, fee.Amount)
+		}
+		order = addCredit(credits, order, recipient, received)
+		total.Add(total, amount)
+		legs = append(legs, events.Transfer{From: clientID, To: recipient, Value: amount, FeeCharged: fee})
+	}
+	if totalFee != nil {
+		order = addCredit(credits, order, totalFee.Collector, totalFee.Amount)
+	}
+
+	err = checkNotFrozen(ctx, append([]string{clientID}, order...)...)
+	if err != nil {
+		return err
+	}
+
+	balance, exists, err := lookupBalance(ctx, clientID)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("client account %s has no balance", clientID)
+	}
+	if balance.Cmp(total) < 0 {
+		return kusherrors.Errorf(kusherrors.ErrInsufficientBalance, "client account %s has insufficient funds", clientID)
+	}
+	balance, err = sub(balance, total)
+	if err != nil {
+		return err
+	}
+	err = writeBalance(ctx, clientID, balance)
+	if err != nil {
+		return err
+	}
+
+	for _, recipient := range order {
+		err = creditBalance(ctx, recipient, credits[recipient])
+		if err != nil {
+			return err
+		}
+	}
+
+	err = metrics.Count(ctx, clientID, recipients...)
+	if err != nil {
+		return err
+	}
+
+	transferBatchEvent := TransferBatchEvent{From: clientID, Transfers: legs, FeeCharged: totalFee}
+	err = events.Emit(ctx, "TransferBatch", &transferBatchEvent)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// addCredit adds amount to the pending credit of account, recording first-seen order.
+func addCredit(credits map[string]*big.Int, order []string, account string, amount *big.Int) []string {
+	if _, ok := credits[account]; !ok {
+		credits[account] = new(big.Int)
+		order = append(order, account)
+	}
+	credits[account].Add(credits[account], amount)
+	return order
+}
+
+// BalanceOfBatch returns the balances of up to 500 accounts in the order given. Unlike BalanceOf
+// it returns "0" for accounts that never held the token, so one unknown account does not fail the
+// whole batch.
+func (c *TokenERC20Contract) BalanceOfBatch(ctx kalpsdk.TransactionContextInterface, accounts []string) ([]string, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	if len(accounts) > maxBatchTransferLegs {
+		return nil, fmt.Errorf("at most %d accounts can be queried at once", maxBatchTransferLegs)
+	}
+
+	balances := make([]string, 0, len(accounts))
+	for _, account := range accounts {
+		if account == "" {
+			return nil, fmt.Errorf("account must not be empty")
+		}
+		balance, err := readBalance(ctx, account)
+		if err != nil {
+			return nil, err
+		}
+		balances = append(balances, balance.String())
+	}
+	return balances, nil
+}
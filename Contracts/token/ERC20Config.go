@@ -0,0 +1,87 @@
+package token
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/config"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+// GetConfig returns the configuration the token was initialized with, as changed since.
+func (c *TokenERC20Contract) GetConfig(ctx kalpsdk.TransactionContextInterface) (*config.InitConfig, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return readERC20Config(ctx)
+}
+
+// UpdateConfig changes the max supply, base URI or features, see config.Update. The max supply
+// cannot drop below the current total supply.
+func (c *TokenERC20Contract) UpdateConfig(ctx kalpsdk.TransactionContextInterface, cfg config.InitConfig) error {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	current, err := readERC20Config(ctx)
+	if err != nil {
+		return err
+	}
+	return config.Update(ctx, current, cfg, erc20ConfigHooks(ctx))
+}
+
+// applyERC20Config applies the JSON config.InitConfig of an approved
+// accesscontrol.ActionUpdateConfig.
+func applyERC20Config(ctx kalpsdk.TransactionContextInterface, updateJSON string) error {
+	var update config.InitConfig
+	err := json.Unmarshal([]byte(updateJSON), &update)
+	if err != nil {
+		return fmt.Errorf("failed to decode config: %v", err)
+	}
+	current, err := readERC20Config(ctx)
+	if err != nil {
+		return err
+	}
+	return config.Apply(ctx, current, update, erc20ConfigHooks(ctx))
+}
+
+// erc20ConfigHooks keeps the max supply above the total supply.
+func erc20ConfigHooks(ctx kalpsdk.TransactionContextInterface) config.Hooks {
+	return config.Hooks{
+		CheckMaxSupply: func(maxSupply *big.Int) error {
+			totalSupply, err := readAmount(ctx, totalSupplyKey)
+			if err != nil {
+				return fmt.Errorf("failed to retrieve total token supply: %v", err)
+			}
+			if maxSupply.Cmp(totalSupply) < 0 {
+				return fmt.Errorf("max supply %s is below the total supply of %s", maxSupply, totalSupply)
+			}
+			return nil
+		},
+	}
+}
+
+func readERC20Config(ctx kalpsdk.TransactionContextInterface) (*config.InitConfig, error) {
+	nameBytes, err := ctx.GetState(nameKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Name: %v", err)
+	}
+	symbolBytes, err := ctx.GetState(symbolKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Symbol: %v", err)
+	}
+	decimals, err := readDecimals(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return config.Read(ctx, string(nameBytes), string(symbolBytes), decimals)
+}
@@ -0,0 +1,110 @@
+package token
+
+import (
+	"fmt"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+// maxDecimals mirrors the uint8 decimals of the ERC20 standard.
+const maxDecimals = 255
+
+// unitsPattern matches the human-readable amounts ToBaseUnits accepts: digits with an optional
+// fraction, such as "1", "1.5" or ".5".
+var unitsPattern = regexp.MustCompile(`^(\d+|\d*\.\d+)$`)
+
+// Decimals returns the number of decimals used to display token amounts. All amounts taken and
+// returned by the contract are in base units, i.e. multiples of 10^-decimals tokens.
+func (c *TokenERC20Contract) Decimals(ctx kalpsdk.TransactionContextInterface) (int, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return 0, kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	return readDecimals(ctx)
+}
+
+// ToBaseUnits converts a human-readable amount such as "1.5" into base units.
+func (c *TokenERC20Contract) ToBaseUnits(ctx kalpsdk.TransactionContextInterface, amount string) (string, error) {
+	decimals, err := c.Decimals(ctx)
+	if err != nil {
+		return "", err
+	}
+	value, err := parseUnits(amount, decimals)
+	if err != nil {
+		return "", err
+	}
+	return value.String(), nil
+}
+
+// FromBaseUnits converts an amount in base units into its human-readable form.
+func (c *TokenERC20Contract) FromBaseUnits(ctx kalpsdk.TransactionContextInterface, amount string) (string, error) {
+	decimals, err := c.Decimals(ctx)
+	if err != nil {
+		return "", err
+	}
+	value, err := parseAmount(amount)
+	if err != nil {
+		return "", err
+	}
+	return formatUnits(value, decimals), nil
+}
+
+func readDecimals(ctx kalpsdk.TransactionContextInterface) (int, error) {
+	decimalsBytes, err := ctx.GetState(decimalsKey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get decimals: %v", err)
+	}
+	if decimalsBytes == nil {
+		return 0, nil
+	}
+	decimals, err := strconv.Atoi(string(decimalsBytes))
+	if err != nil {
+		return 0, fmt.Errorf("failed to decode decimals: %v", err)
+	}
+	return decimals, nil
+}
+
+// parseUnits converts a decimal string with at most decimals fractional digits into base units.
+func parseUnits(amount string, decimals int) (*big.Int, error) {
+	if !unitsPattern.MatchString(amount) {
+		return nil, fmt.Errorf("amount %q must be a non-negative decimal number", amount)
+	}
+	whole, fraction, _ := strings.Cut(amount, ".")
+	if len(fraction) > decimals {
+		return nil, fmt.Errorf("amount %q has more than %d decimals", amount, decimals)
+	}
+	if whole == "" {
+		whole = "0"
+	}
+	digits := whole + fraction + strings.Repeat("0", decimals-len(fraction))
+	value, err := parseAmount(digits)
+	if err != nil {
+		return nil, fmt.Errorf("amount %q must be a non-negative decimal number", amount)
+	}
+	return value, nil
+}
+
+// formatUnits renders base units as a decimal string without trailing fractional zeros.
+func formatUnits(value *big.Int, decimals int) string {
+	digits := value.String()
+	if decimals == 0 {
+		return digits
+	}
+	if len(digits) <= decimals {
+		digits = strings.Repeat("0", decimals-len(digits)+1) + digits
+	}
+	whole, fraction := digits[:len(digits)-decimals], strings.TrimRight(digits[len(digits)-decimals:], "0")
+	if fraction == "" {
+		return whole
+	}
+	return whole + "." + fraction
+}
@@ -0,0 +1,244 @@
+package token
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+	"github.com/thekalpstudio/kush-go/canonical"
+	"github.com/thekalpstudio/kush-go/events"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+const emissionScheduleKey = "emissionSchedule"
+
+// maxEmissionPeriods bounds the number of periods settled by one ExecuteEmission call so that a
+// long-neglected schedule cannot exceed the chaincode execution timeout; callers simply repeat.
+const maxEmissionPeriods = 1000
+
+// EmissionSchedule mints AmountPerPeriod to Treasury once per PeriodSeconds between StartTime and
+// EndTime (unix seconds). After every period the amount shrinks by DecayBps basis points.
+type EmissionSchedule struct {
+	AmountPerPeriod *big.Int `json:"amountPerPeriod"`
+	PeriodSeconds   int64    `json:"periodSeconds"`
+	DecayBps        int      `json:"decayBps"`
+	StartTime       int64    `json:"startTime"`
+	EndTime         int64    `json:"endTime"`
+	Treasury        string   `json:"treasury"`
+	PeriodsEmitted  int64    `json:"periodsEmitted"`
+	NextAmount      *big.Int `json:"nextAmount"`
+	TotalEmitted    *big.Int `json:"totalEmitted"`
+}
+
+// EmissionExecuted MUST emit when matured emission periods are minted, in place of the Transfer
+// event of the mint.
+type EmissionExecuted = events.EmissionExecuted
+
+func (c *TokenERC20Contract) SetEmissionSchedule(ctx kalpsdk.TransactionContextInterface, amountPerPeriod string, periodSeconds int64, decayBps int, endTime int64, treasury string) error {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	err = checkAdmin(ctx)
+	if err != nil {
+		return err
+	}
+
+	amount, err := parseAmount(amountPerPeriod)
+	if err != nil {
+		return err
+	}
+	if amount.Sign() <= 0 {
+		return fmt.Errorf("emission amount must be a positive integer")
+	}
+	if periodSeconds <= 0 {
+		return fmt.Errorf("emission period must be a positive number of seconds")
+	}
+	if decayBps < 0 || decayBps > maxBasisPoints {
+		return fmt.Errorf("emission decay must be between 0 and %d basis points", maxBasisPoints)
+	}
+	if treasury == "" || treasury == "0x0" {
+		return fmt.Errorf("emission treasury must be a valid account")
+	}
+
+	timestamp, err := ctx.GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	if endTime <= timestamp.GetSeconds() {
+		return fmt.Errorf("emission end time must be in the future")
+	}
+
+	// Settle whatever the previous schedule already owes before it is replaced.
+	previous, err := readEmissionSchedule(ctx)
+	if err != nil {
+		return err
+	}
+	if previous != nil {
+		pending, err := pendingEmissionPeriods(ctx, previous)
+		if err != nil {
+			return err
+		}
+		if pending > maxEmissionPeriods {
+			return fmt.Errorf("the previous emission schedule owes %d periods, call ExecuteEmission until it owes at most %d before replacing it", pending, maxEmissionPeriods)
+		}
+		_, err = executeEmissionHelper(ctx, previous)
+		if err != nil {
+			return err
+		}
+	}
+
+	schedule := &EmissionSchedule{
+		AmountPerPeriod: amount,
+		PeriodSeconds:   periodSeconds,
+		DecayBps:        decayBps,
+		StartTime:       timestamp.GetSeconds(),
+		EndTime:         endTime,
+		Treasury:        treasury,
+		NextAmount:      new(big.Int).Set(amount),
+		TotalEmitted:    new(big.Int),
+	}
+	return writeEmissionSchedule(ctx, schedule)
+}
+
+// ExecuteEmission mints every period that has matured since the last execution. Anyone may call it.
+func (c *TokenERC20Contract) ExecuteEmission(ctx kalpsdk.TransactionContextInterface) (string, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return "", kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	err = checkNotPaused(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	schedule, err := readEmissionSchedule(ctx)
+	if err != nil {
+		return "", err
+	}
+	if schedule == nil {
+		return "", fmt.Errorf("no emission schedule is configured")
+	}
+
+	amount, err := executeEmissionHelper(ctx, schedule)
+	if err != nil {
+		return "", err
+	}
+	return amount.String(), nil
+}
+
+func (c *TokenERC20Contract) GetEmissionSchedule(ctx kalpsdk.TransactionContextInterface) (*EmissionSchedule, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	schedule, err := readEmissionSchedule(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if schedule == nil {
+		return nil, fmt.Errorf("no emission schedule is configured")
+	}
+	return schedule, nil
+}
+
+// pendingEmissionPeriods returns the number of matured periods of schedule that are not minted
+// yet.
+func pendingEmissionPeriods(ctx kalpsdk.TransactionContextInterface, schedule *EmissionSchedule) (int64, error) {
+	timestamp, err := ctx.GetTxTimestamp()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	now := timestamp.GetSeconds()
+	if now > schedule.EndTime {
+		now = schedule.EndTime
+	}
+	matured := (now - schedule.StartTime) / schedule.PeriodSeconds
+	return matured - schedule.PeriodsEmitted, nil
+}
+
+// executeEmissionHelper mints up to maxEmissionPeriods matured periods of schedule. The periods
+// left stay pending for the next call.
+func executeEmissionHelper(ctx kalpsdk.TransactionContextInterface, schedule *EmissionSchedule) (*big.Int, error) {
+	periods, err := pendingEmissionPeriods(ctx, schedule)
+	if err != nil {
+		return nil, err
+	}
+	if periods <= 0 {
+		return new(big.Int), nil
+	}
+	if periods > maxEmissionPeriods {
+		periods = maxEmissionPeriods
+	}
+
+	amount := new(big.Int)
+	for i := int64(0); i < periods && schedule.NextAmount.Sign() > 0; i++ {
+		amount.Add(amount, schedule.NextAmount)
+		schedule.NextAmount = applyDecay(schedule.NextAmount, schedule.DecayBps)
+	}
+	schedule.PeriodsEmitted += periods
+	schedule.TotalEmitted = new(big.Int).Add(schedule.TotalEmitted, amount)
+
+	err = writeEmissionSchedule(ctx, schedule)
+	if err != nil {
+		return nil, err
+	}
+	if amount.Sign() == 0 {
+		return amount, nil
+	}
+	err = _mint(ctx, schedule.Treasury, amount)
+	if err != nil {
+		return nil, err
+	}
+
+	emissionExecutedEvent := EmissionExecuted{Treasury: schedule.Treasury, Periods: periods, Amount: amount}
+	err = events.Emit(ctx, "EmissionExecuted", &emissionExecutedEvent)
+	if err != nil {
+		return nil, err
+	}
+	return amount, nil
+}
+
+// applyDecay returns amount reduced by decayBps basis points, rounded down.
+func applyDecay(amount *big.Int, decayBps int) *big.Int {
+	decayed := new(big.Int).Mul(amount, big.NewInt(int64(maxBasisPoints-decayBps)))
+	return decayed.Quo(decayed, big.NewInt(maxBasisPoints))
+}
+
+func readEmissionSchedule(ctx kalpsdk.TransactionContextInterface) (*EmissionSchedule, error) {
+	scheduleBytes, err := ctx.GetState(emissionScheduleKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read emission schedule: %v", err)
+	}
+	if scheduleBytes == nil {
+		return nil, nil
+	}
+	schedule := new(EmissionSchedule)
+	err = json.Unmarshal(scheduleBytes, schedule)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode emission schedule: %v", err)
+	}
+	return schedule, nil
+}
+
+func writeEmissionSchedule(ctx kalpsdk.TransactionContextInterface, schedule *EmissionSchedule) error {
+	scheduleJSON, err := canonical.Marshal(schedule)
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	return accesscontrol.PutState(ctx, emissionScheduleKey, scheduleJSON)
+}
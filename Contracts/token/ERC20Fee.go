@@ -0,0 +1,124 @@
+package token
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+	"github.com/thekalpstudio/kush-go/audit"
+	"github.com/thekalpstudio/kush-go/canonical"
+	"github.com/thekalpstudio/kush-go/events"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+const transferFeeKey = "transferFee"
+
+// TransferFee takes FeeBps basis points of every transfer and credits it to Collector. Transfers
+// from or to the collector are exempt.
+type TransferFee struct {
+	FeeBps    int    `json:"feeBps"`
+	Collector string `json:"collector"`
+}
+
+// FeeCharged describes the fee deducted from a transfer. It travels inside the Transfer event
+// rather than as a separate FeeCharged event, see package events.
+type FeeCharged = events.FeeCharged
+
+// SetTransferFee configures the fee on transfer. A feeBps of zero disables it. While multisig
+// approval is enabled the fee has to be proposed instead.
+func (c *TokenERC20Contract) SetTransferFee(ctx kalpsdk.TransactionContextInterface, feeBps int, collector string) error {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	err = checkAdmin(ctx)
+	if err != nil {
+		return err
+	}
+	err = accesscontrol.CheckNotMultisig(ctx, accesscontrol.ActionSetTransferFee)
+	if err != nil {
+		return err
+	}
+	err = accesscontrol.CheckNotTimelocked(ctx, accesscontrol.ActionSetTransferFee)
+	if err != nil {
+		return err
+	}
+	return writeTransferFee(ctx, feeBps, collector)
+}
+
+// writeTransferFee validates and stores the fee on transfer, leaving authorization to the caller.
+func writeTransferFee(ctx kalpsdk.TransactionContextInterface, feeBps int, collector string) error {
+	if feeBps < 0 || feeBps > maxBasisPoints {
+		return fmt.Errorf("transfer fee must be between 0 and %d basis points", maxBasisPoints)
+	}
+	if feeBps > 0 && (collector == "" || collector == "0x0") {
+		return fmt.Errorf("fee collector must be a valid account")
+	}
+
+	feeJSON, err := canonical.Marshal(TransferFee{feeBps, collector})
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	err = accesscontrol.PutState(ctx, transferFeeKey, feeJSON)
+	if err != nil {
+		return err
+	}
+	return audit.Record(ctx, "SetTransferFee", strconv.Itoa(feeBps), collector)
+}
+
+func (c *TokenERC20Contract) GetTransferFee(ctx kalpsdk.TransactionContextInterface) (*TransferFee, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	return readTransferFee(ctx)
+}
+
+// transferFeeFor returns the fee due on a transfer of value from from to to, or nil if none is.
+func transferFeeFor(ctx kalpsdk.TransactionContextInterface, from string, to string, value *big.Int) (*FeeCharged, error) {
+	config, err := readTransferFee(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if config.FeeBps == 0 || from == config.Collector || to == config.Collector {
+		return nil, nil
+	}
+	fee := new(big.Int).Mul(value, big.NewInt(int64(config.FeeBps)))
+	fee.Quo(fee, big.NewInt(maxBasisPoints))
+	if fee.Sign() == 0 {
+		return nil, nil
+	}
+	return &FeeCharged{From: from, Collector: config.Collector, Amount: fee}, nil
+}
+
+// creditFee adds a charged fee to the collector's balance.
+func creditFee(ctx kalpsdk.TransactionContextInterface, fee *FeeCharged) error {
+	return creditBalance(ctx, fee.Collector, fee.Amount)
+}
+
+func readTransferFee(ctx kalpsdk.TransactionContextInterface) (*TransferFee, error) {
+	feeBytes, err := ctx.GetState(transferFeeKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read transfer fee: %v", err)
+	}
+	config := new(TransferFee)
+	if feeBytes == nil {
+		return config, nil
+	}
+	err = json.Unmarshal(feeBytes, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode transfer fee: %v", err)
+	}
+	return config, nil
+}
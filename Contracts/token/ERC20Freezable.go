@@ -0,0 +1,140 @@
+package token
+
+import (
+	"fmt"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+	"github.com/thekalpstudio/kush-go/denylist"
+	"github.com/thekalpstudio/kush-go/events"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+const frozenPrefix = "frozen"
+
+// FreezeEvent is emitted as Frozen or Unfrozen.
+type FreezeEvent = events.FreezeEvent
+
+// FreezeAccount blocks account from sending, receiving, minting or burning tokens.
+func (c *TokenERC20Contract) FreezeAccount(ctx kalpsdk.TransactionContextInterface, account string) error {
+	return setFrozen(ctx, account, true)
+}
+
+// UnfreezeAccount lifts a freeze placed by FreezeAccount.
+func (c *TokenERC20Contract) UnfreezeAccount(ctx kalpsdk.TransactionContextInterface, account string) error {
+	return setFrozen(ctx, account, false)
+}
+
+func (c *TokenERC20Contract) IsFrozen(ctx kalpsdk.TransactionContextInterface, account string) (bool, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return false, kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	return isFrozen(ctx, account)
+}
+
+func setFrozen(ctx kalpsdk.TransactionContextInterface, account string, frozen bool) error {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	err = checkAdmin(ctx)
+	if err != nil {
+		return err
+	}
+	if account == "" {
+		return fmt.Errorf("account must not be empty")
+	}
+
+	current, err := isFrozen(ctx, account)
+	if err != nil {
+		return err
+	}
+	if current == frozen {
+		if frozen {
+			return fmt.Errorf("account %s is already frozen", account)
+		}
+		return fmt.Errorf("account %s is not frozen", account)
+	}
+
+	frozenKey, err := ctx.CreateCompositeKey(frozenPrefix, []string{account})
+	if err != nil {
+		return fmt.Errorf("failed to create the composite key for prefix %s: %v", frozenPrefix, err)
+	}
+	if frozen {
+		err = accesscontrol.PutState(ctx, frozenKey, []byte{'\u0000'})
+	} else {
+		err = accesscontrol.DelState(ctx, frozenKey)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update frozen state of %s: %v", account, err)
+	}
+
+	sender, err := ctx.GetUserID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+	eventName := "Unfrozen"
+	if frozen {
+		eventName = "Frozen"
+	}
+	return events.Emit(ctx, eventName, &FreezeEvent{Account: account, Sender: sender})
+}
+
+func isFrozen(ctx kalpsdk.TransactionContextInterface, account string) (bool, error) {
+	frozenKey, err := ctx.CreateCompositeKey(frozenPrefix, []string{account})
+	if err != nil {
+		return false, fmt.Errorf("failed to create the composite key for prefix %s: %v", frozenPrefix, err)
+	}
+	frozenBytes, err := ctx.GetState(frozenKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to read frozen state of %s: %v", account, err)
+	}
+	return frozenBytes != nil, nil
+}
+
+// checkNotFrozen fails if any of accounts is frozen or on the deny list of the contract.
+func checkNotFrozen(ctx kalpsdk.TransactionContextInterface, accounts ...string) error {
+	for _, account := range accounts {
+		frozen, err := isFrozen(ctx, account)
+		if err != nil {
+			return err
+		}
+		if frozen {
+			return fmt.Errorf("account %s is frozen", account)
+		}
+	}
+	return denylist.CheckNotDenied(ctx, accounts...)
+}
+
+// SetDenyList makes transfers, mints and burns of accounts on the deny list chaincode
+// chaincodeName fail. An empty name detaches the deny list.
+func (c *TokenERC20Contract) SetDenyList(ctx kalpsdk.TransactionContextInterface, chaincodeName string) error {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return denylist.SetDenyList(ctx, chaincodeName)
+}
+
+func (c *TokenERC20Contract) GetDenyList(ctx kalpsdk.TransactionContextInterface) (string, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return "", kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return denylist.GetDenyList(ctx)
+}
@@ -0,0 +1,312 @@
+package token
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+	"github.com/thekalpstudio/kush-go/guardian"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+	"github.com/thekalpstudio/kush-go/testutil"
+)
+
+func TestERC20Multisig(t *testing.T) {
+	l, c := newERC20(t)
+	testutil.MustRun(t, l, admin, "SetMultisig", func(ctx *testutil.Context) error {
+		return c.SetMultisig(ctx, []string{admin, "bob"}, 2, "100", 0)
+	})
+	pause := func(ctx *testutil.Context) error {
+		return c.Pause(ctx)
+	}
+	testutil.CheckErr(t, testutil.Run(l, admin, "Pause", pause), true, "")
+
+	var proposal *accesscontrol.Proposal
+	testutil.MustRun(t, l, "bob", "Propose", func(ctx *testutil.Context) error {
+		var err error
+		proposal, err = c.Propose(ctx, accesscontrol.ActionPause, []string{}, 3600)
+		return err
+	})
+	testutil.CheckErr(t, testutil.Run(l, "carol", "ApproveProposal", func(ctx *testutil.Context) error {
+		_, err := c.ApproveProposal(ctx, proposal.ID)
+		return err
+	}), true, kusherrors.ErrUnauthorized)
+	execute := func(ctx *testutil.Context) error {
+		_, err := c.ExecuteProposal(ctx, proposal.ID)
+		return err
+	}
+	// one of two approvals
+	testutil.CheckErr(t, testutil.Run(l, "bob", "ExecuteProposal", execute), true, "")
+	testutil.MustRun(t, l, admin, "ApproveProposal", func(ctx *testutil.Context) error {
+		_, err := c.ApproveProposal(ctx, proposal.ID)
+		return err
+	})
+	testutil.MustRun(t, l, "bob", "ExecuteProposal", execute)
+	if paused, err := c.IsPaused(l.Tx(admin, "IsPaused")); err != nil || !paused {
+		t.Fatalf("token is not paused after the proposal executed: %v", err)
+	}
+	testutil.CheckErr(t, testutil.Run(l, "bob", "ExecuteProposal", execute), true, "")
+}
+
+func TestERC20MultisigMintThreshold(t *testing.T) {
+	l, c := newERC20(t)
+	testutil.MustRun(t, l, admin, "SetMultisig", func(ctx *testutil.Context) error {
+		return c.SetMultisig(ctx, []string{admin, "bob"}, 2, "100", 0)
+	})
+	mint := func(amount string) error {
+		return testutil.Run(l, admin, "Mint", func(ctx *testutil.Context) error {
+			return c.Mint(ctx, amount)
+		})
+	}
+	testutil.CheckErr(t, mint("60"), false, "")
+	// the mint window counts the earlier mint, so the threshold cannot be split
+	testutil.CheckErr(t, mint("60"), true, "")
+	testutil.CheckErr(t, mint("40"), false, "")
+	if balance := erc20Balance(t, l, c, admin); balance != "1100" {
+		t.Fatalf("balance of admin is %s, want 1100", balance)
+	}
+}
+
+func TestERC20Timelock(t *testing.T) {
+	l, c := newERC20(t)
+	testutil.MustRun(t, l, admin, "SetTimelockDelay", func(ctx *testutil.Context) error {
+		return c.SetTimelockDelay(ctx, 3600)
+	})
+	testutil.CheckErr(t, testutil.Run(l, admin, "SetTransferFee", func(ctx *testutil.Context) error {
+		return c.SetTransferFee(ctx, 100, "fees")
+	}), true, "")
+
+	schedule := func(feeBps string) *accesscontrol.TimelockOperation {
+		var operation *accesscontrol.TimelockOperation
+		testutil.MustRun(t, l, admin, "ScheduleOperation", func(ctx *testutil.Context) error {
+			var err error
+			operation, err = c.ScheduleOperation(ctx, accesscontrol.ActionSetTransferFee, []string{feeBps, "fees"})
+			return err
+		})
+		return operation
+	}
+	execute := func(user string, operation *accesscontrol.TimelockOperation) error {
+		return testutil.Run(l, user, "ExecuteOperation", func(ctx *testutil.Context) error {
+			_, err := c.ExecuteOperation(ctx, operation.ID)
+			return err
+		})
+	}
+	cancelled := schedule("200")
+	testutil.MustRun(t, l, admin, "CancelOperation", func(ctx *testutil.Context) error {
+		_, err := c.CancelOperation(ctx, cancelled.ID)
+		return err
+	})
+	operation := schedule("100")
+	testutil.CheckErr(t, execute(admin, operation), true, "")
+
+	l.Advance(time.Hour)
+	testutil.CheckErr(t, execute("bob", operation), true, kusherrors.ErrUnauthorized)
+	testutil.CheckErr(t, execute(admin, cancelled), true, "")
+	testutil.CheckErr(t, execute(admin, operation), false, "")
+	testutil.CheckErr(t, execute(admin, operation), true, "")
+	fee, err := c.GetTransferFee(l.Tx(admin, "GetTransferFee"))
+	if err != nil || fee.FeeBps != 100 || fee.Collector != "fees" {
+		t.Fatalf("transfer fee is %+v: %v", fee, err)
+	}
+}
+
+func TestERC20Ownership(t *testing.T) {
+	l, c := newERC20(t)
+	transfer := func(user string, newOwner string) error {
+		return testutil.Run(l, user, "TransferOwnership", func(ctx *testutil.Context) error {
+			return c.TransferOwnership(ctx, newOwner)
+		})
+	}
+	accept := func(user string) error {
+		return testutil.Run(l, user, "AcceptOwnership", func(ctx *testutil.Context) error {
+			return c.AcceptOwnership(ctx)
+		})
+	}
+	testutil.CheckErr(t, transfer("bob", "bob"), true, kusherrors.ErrUnauthorized)
+	testutil.CheckErr(t, transfer(admin, "bob"), false, "")
+	testutil.CheckErr(t, accept("carol"), true, kusherrors.ErrUnauthorized)
+	testutil.CheckErr(t, accept("bob"), false, "")
+
+	ctx := l.Tx(admin, "Owner")
+	if owner, err := c.Owner(ctx); err != nil || owner != "bob" {
+		t.Fatalf("owner is %s, want bob: %v", owner, err)
+	}
+	if pending, err := c.PendingOwner(ctx); err != nil || pending != "" {
+		t.Fatalf("pending owner is %s after acceptance: %v", pending, err)
+	}
+	for account, want := range map[string]bool{admin: false, "bob": true} {
+		if isAdmin, err := c.HasRole(ctx, accesscontrol.AdminRole, account); err != nil || isAdmin != want {
+			t.Fatalf("%s holds ADMIN_ROLE: %t, want %t: %v", account, isAdmin, want, err)
+		}
+	}
+	testutil.CheckErr(t, transfer(admin, "carol"), true, kusherrors.ErrUnauthorized)
+}
+
+func TestERC20KYC(t *testing.T) {
+	l, c := newERC20(t)
+	testutil.MustRun(t, l, admin, "Transfer", func(ctx *testutil.Context) error {
+		return c.Transfer(ctx, "bob", "100")
+	})
+	testutil.MustRun(t, l, admin, "SetKYCRequired", func(ctx *testutil.Context) error {
+		return c.SetKYCRequired(ctx, true)
+	})
+	transfer := func() error {
+		return testutil.Run(l, "bob", "Transfer", func(ctx *testutil.Context) error {
+			return c.Transfer(ctx, "carol", "10")
+		})
+	}
+	testutil.CheckErr(t, transfer(), true, "")
+
+	testutil.MustRun(t, l, admin, "SetFunctionKYCRequired", func(ctx *testutil.Context) error {
+		return c.SetFunctionKYCRequired(ctx, "Transfer", false)
+	})
+	testutil.CheckErr(t, transfer(), false, "")
+
+	testutil.MustRun(t, l, admin, "RemoveFunctionKYCOverride", func(ctx *testutil.Context) error {
+		return c.RemoveFunctionKYCOverride(ctx, "Transfer")
+	})
+	testutil.CheckErr(t, transfer(), true, "")
+	l.SetKYC("bob", true)
+	testutil.CheckErr(t, transfer(), false, "")
+	if balance := erc20Balance(t, l, c, "carol"); balance != "20" {
+		t.Fatalf("balance of carol is %s, want 20", balance)
+	}
+}
+
+func TestERC20Guardian(t *testing.T) {
+	l, c := newERC20(t)
+	guardianLedger := testutil.NewLedger()
+	g := new(guardian.GuardianContract)
+	testutil.MustRun(t, guardianLedger, admin, "Initialize", func(ctx *testutil.Context) error {
+		return g.Initialize(ctx, []string{testutil.MSPID})
+	})
+	l.RegisterChaincode("guardian", func(args [][]byte) peer.Response {
+		paused, err := g.IsPaused(guardianLedger.Tx(admin, string(args[0])))
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		if paused {
+			return shim.Success([]byte("true"))
+		}
+		return shim.Success([]byte("false"))
+	})
+	testutil.CheckErr(t, testutil.Run(l, "bob", "SetGuardian", func(ctx *testutil.Context) error {
+		return c.SetGuardian(ctx, "guardian")
+	}), true, "")
+	testutil.MustRun(t, l, admin, "SetGuardian", func(ctx *testutil.Context) error {
+		return c.SetGuardian(ctx, "guardian")
+	})
+
+	transfer := func() error {
+		return testutil.Run(l, admin, "Transfer", func(ctx *testutil.Context) error {
+			return c.Transfer(ctx, "bob", "10")
+		})
+	}
+	testutil.CheckErr(t, transfer(), false, "")
+	testutil.CheckErr(t, testutil.Run(guardianLedger, "bob", "Pause", func(ctx *testutil.Context) error {
+		return g.Pause(ctx, "incident")
+	}), true, "")
+	testutil.MustRun(t, guardianLedger, admin, "Pause", func(ctx *testutil.Context) error {
+		return g.Pause(ctx, "incident")
+	})
+	testutil.CheckErr(t, transfer(), true, "")
+	testutil.MustRun(t, guardianLedger, admin, "Unpause", func(ctx *testutil.Context) error {
+		return g.Unpause(ctx)
+	})
+	testutil.CheckErr(t, transfer(), false, "")
+}
+
+// TestERC20GuardianGates makes a single admin schedule the guardian through the timelock, and
+// propose it once multisig approval is enabled.
+func TestERC20GuardianGates(t *testing.T) {
+	l, c := newERC20(t)
+	setGuardian := func(ctx *testutil.Context) error {
+		return c.SetGuardian(ctx, "guardian")
+	}
+	testutil.MustRun(t, l, admin, "SetTimelockDelay", func(ctx *testutil.Context) error {
+		return c.SetTimelockDelay(ctx, 3600)
+	})
+	testutil.CheckErr(t, testutil.Run(l, admin, "SetGuardian", setGuardian), true, "")
+	var operation *accesscontrol.TimelockOperation
+	testutil.MustRun(t, l, admin, "ScheduleOperation", func(ctx *testutil.Context) error {
+		var err error
+		operation, err = c.ScheduleOperation(ctx, accesscontrol.ActionSetGuardian, []string{"guardian"})
+		return err
+	})
+	l.Advance(time.Hour)
+	testutil.MustRun(t, l, admin, "ExecuteOperation", func(ctx *testutil.Context) error {
+		_, err := c.ExecuteOperation(ctx, operation.ID)
+		return err
+	})
+	if name, err := c.GetGuardian(l.Tx(admin, "GetGuardian")); err != nil || name != "guardian" {
+		t.Fatalf("guardian is %q: %v", name, err)
+	}
+
+	testutil.MustRun(t, l, admin, "SetMultisig", func(ctx *testutil.Context) error {
+		return c.SetMultisig(ctx, []string{admin, "bob"}, 2, "", 0)
+	})
+	testutil.CheckErr(t, testutil.Run(l, admin, "ScheduleOperation", func(ctx *testutil.Context) error {
+		_, err := c.ScheduleOperation(ctx, accesscontrol.ActionSetGuardian, []string{""})
+		return err
+	}), true, "")
+}
+
+func TestERC20DenyListGates(t *testing.T) {
+	l, c := newERC20(t)
+	testutil.MustRun(t, l, admin, "SetMultisig", func(ctx *testutil.Context) error {
+		return c.SetMultisig(ctx, []string{admin, "bob"}, 2, "", 0)
+	})
+	testutil.CheckErr(t, testutil.Run(l, admin, "SetDenyList", func(ctx *testutil.Context) error {
+		return c.SetDenyList(ctx, "denylist")
+	}), true, "")
+
+	var proposal *accesscontrol.Proposal
+	testutil.MustRun(t, l, admin, "Propose", func(ctx *testutil.Context) error {
+		var err error
+		proposal, err = c.Propose(ctx, accesscontrol.ActionSetDenyList, []string{"denylist"}, 3600)
+		return err
+	})
+	testutil.MustRun(t, l, "bob", "ApproveProposal", func(ctx *testutil.Context) error {
+		_, err := c.ApproveProposal(ctx, proposal.ID)
+		return err
+	})
+	testutil.MustRun(t, l, "bob", "ExecuteProposal", func(ctx *testutil.Context) error {
+		_, err := c.ExecuteProposal(ctx, proposal.ID)
+		return err
+	})
+	if name, err := c.GetDenyList(l.Tx(admin, "GetDenyList")); err != nil || name != "denylist" {
+		t.Fatalf("deny list is %q: %v", name, err)
+	}
+}
+
+// TestERC20BootstrapRoles bootstraps a token initialized before roles and authorized MSPs were
+// stored, which takes its authorized MSPs from the arguments.
+func TestERC20BootstrapRoles(t *testing.T) {
+	l, c := newERC20(t)
+	testutil.MustRun(t, l, admin, "Legacy", func(ctx *testutil.Context) error {
+		for _, key := range l.Keys() {
+			objectType, _, err := ctx.SplitCompositeKey(key)
+			if (err == nil && objectType == accesscontrol.RolePrefix) || key == "role~authorizedMSPs" || key == "role~bootstrapped" || key == "role~owner" {
+				err = ctx.DelStateWithoutKYC(key)
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	bootstrap := func(user string, authorizedMSPs []string) error {
+		return testutil.Run(l, user, "BootstrapRoles", func(ctx *testutil.Context) error {
+			return c.BootstrapRoles(ctx, authorizedMSPs)
+		})
+	}
+	testutil.CheckErr(t, bootstrap("mallory", nil), true, "")
+	testutil.CheckErr(t, bootstrap("mallory", []string{"Org2MSP"}), true, kusherrors.ErrUnauthorized)
+	testutil.CheckErr(t, bootstrap(admin, []string{testutil.MSPID}), false, "")
+	testutil.CheckErr(t, bootstrap(admin, nil), true, "")
+
+	if isAdmin, err := c.HasRole(l.Tx(admin, "HasRole"), accesscontrol.AdminRole, admin); err != nil || !isAdmin {
+		t.Fatalf("admin holds ADMIN_ROLE: %t: %v", isAdmin, err)
+	}
+}
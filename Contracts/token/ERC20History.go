@@ -0,0 +1,89 @@
+package token
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+	"github.com/thekalpstudio/kush-go/pagination"
+)
+
+// BalanceChange is one committed write of a balance: the balance after transaction TxID and the
+// change from the balance before it.
+type BalanceChange struct {
+	TxID      string `json:"txId"`
+	Timestamp int64  `json:"timestamp"`
+	Balance   string `json:"balance"`
+	Change    string `json:"change"`
+}
+
+// AccountHistory is one page of the statement of an account. Bookmark is passed to
+// GetAccountHistory to read the next, older page and is empty on the last one.
+type AccountHistory struct {
+	Changes  []*BalanceChange `json:"changes"`
+	Bookmark string           `json:"bookmark"`
+}
+
+// GetAccountHistory returns up to pageSize balance changes of account following bookmark, newest
+// first. It needs the peer's history database (core.ledger.history.enableHistoryDatabase) and is
+// meant for queries only: Fabric does not re-check history reads at commit time. Credits stored as
+// delta records show up once they are folded into the balance.
+func (c *TokenERC20Contract) GetAccountHistory(ctx kalpsdk.TransactionContextInterface, account string, bookmark string, pageSize int) (*AccountHistory, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	pageSize = pagination.PageSize(pageSize)
+
+	iterator, err := ctx.GetHistoryForKey(account)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get history of %s: %v", account, err)
+	}
+	defer iterator.Close()
+
+	// Fabric returns the newest write first, so the change of an entry is only known once the
+	// entry before it is read.
+	page := &AccountHistory{Changes: []*BalanceChange{}}
+	var previous *big.Int
+	skipping := bookmark != ""
+	for iterator.HasNext() {
+		modification, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read history of %s: %v", account, err)
+		}
+		balance := new(big.Int)
+		if !modification.IsDelete {
+			balance, err = decodeAmount(modification.Value)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if skipping {
+			skipping = modification.TxId != bookmark
+			continue
+		}
+		if previous != nil {
+			last := page.Changes[len(page.Changes)-1]
+			last.Change = new(big.Int).Sub(previous, balance).String()
+			if len(page.Changes) == pageSize {
+				page.Bookmark = last.TxID
+				return page, nil
+			}
+		}
+		page.Changes = append(page.Changes, &BalanceChange{
+			TxID:      modification.TxId,
+			Timestamp: modification.Timestamp.GetSeconds(),
+			Balance:   balance.String(),
+		})
+		previous = balance
+	}
+	if previous != nil {
+		// the oldest write created the balance
+		page.Changes[len(page.Changes)-1].Change = previous.String()
+	}
+	return page, nil
+}
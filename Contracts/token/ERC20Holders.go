@@ -0,0 +1,242 @@
+package token
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/canonical"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+	"github.com/thekalpstudio/kush-go/metrics"
+	"github.com/thekalpstudio/kush-go/pagination"
+)
+
+// HolderPage is one page of ListHolders. Bookmark is empty on the last page.
+type HolderPage struct {
+	Holders  []string `json:"holders"`
+	Bookmark string   `json:"bookmark"`
+}
+
+// ListHolders returns up to pageSize accounts with a positive balance starting at bookmark, in
+// lexical order. Accounts whose balance has not changed since the holder index was introduced are
+// only listed once it does.
+func (c *TokenERC20Contract) ListHolders(ctx kalpsdk.TransactionContextInterface, bookmark string, pageSize int) (*HolderPage, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	holders, next, err := metrics.ListHolders(ctx, bookmark, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	return &HolderPage{holders, next}, nil
+}
+
+// HolderCount returns the number of accounts with a positive balance.
+func (c *TokenERC20Contract) HolderCount(ctx kalpsdk.TransactionContextInterface) (int64, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return 0, kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	totals, err := metrics.GetMetrics(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return totals.Holders, nil
+}
+
+const topHoldersKey = "holder~top"
+const topHoldersRefreshKey = "holder~top~refresh"
+
+// MaxTopHolders bounds the leaderboards of GetTopHolders and RefreshTopHolders.
+const MaxTopHolders = 100
+
+// HolderBalance is an entry of a leaderboard.
+type HolderBalance struct {
+	Account string `json:"account"`
+	Balance string `json:"balance"`
+}
+
+// TopHolders is a leaderboard, largest balance first. Timestamp is the time of the transaction
+// that completed it. Bookmark is set while RefreshTopHolders is still ranking holders: the last
+// one it ranked.
+type TopHolders struct {
+	Holders   []*HolderBalance `json:"holders"`
+	Timestamp int64            `json:"timestamp"`
+	Bookmark  string           `json:"bookmark,omitempty" metadata:",optional"`
+}
+
+// GetTopHolders returns the n largest holders, at most MaxTopHolders, from the current balances.
+// It reads the balance of every holder; tokens with many holders should publish a leaderboard with
+// RefreshTopHolders and read it with GetTopHoldersSnapshot.
+func (c *TokenERC20Contract) GetTopHolders(ctx kalpsdk.TransactionContextInterface, n int) (*TopHolders, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return topHolders(ctx, n)
+}
+
+// RefreshTopHolders ranks the next pageSize holders into the leaderboard being refreshed, a
+// DefaultPageSize if pageSize is not positive, and returns it. Once every holder is ranked the
+// leaderboard has no Bookmark and replaces the one GetTopHoldersSnapshot returns; invoke it until
+// then. Balances are read page by page, so the leaderboard reflects them as of their page. The
+// caller must hold AdminRole.
+func (c *TokenERC20Contract) RefreshTopHolders(ctx kalpsdk.TransactionContextInterface, pageSize int) (*TopHolders, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	err = checkAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	top, err := readTopHolders(ctx, topHoldersRefreshKey)
+	if err != nil {
+		return nil, err
+	}
+	if top == nil {
+		top = &TopHolders{Holders: []*HolderBalance{}}
+	}
+	holders, exhausted, err := metrics.HoldersAfter(ctx, top.Bookmark, pagination.PageSize(pageSize))
+	if err != nil {
+		return nil, err
+	}
+	err = rankHolders(ctx, top, holders, MaxTopHolders)
+	if err != nil {
+		return nil, err
+	}
+	if len(holders) > 0 {
+		top.Bookmark = holders[len(holders)-1]
+	}
+	if !exhausted {
+		return top, writeTopHolders(ctx, topHoldersRefreshKey, top)
+	}
+
+	timestamp, err := ctx.GetTxTimestamp()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	top.Timestamp = timestamp.GetSeconds()
+	top.Bookmark = ""
+	err = ctx.DelStateWithoutKYC(topHoldersRefreshKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store top holders: %v", err)
+	}
+	return top, writeTopHolders(ctx, topHoldersKey, top)
+}
+
+// GetTopHoldersSnapshot returns the leaderboard of the last completed RefreshTopHolders, or nil if
+// none was stored.
+func (c *TokenERC20Contract) GetTopHoldersSnapshot(ctx kalpsdk.TransactionContextInterface) (*TopHolders, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return readTopHolders(ctx, topHoldersKey)
+}
+
+func readTopHolders(ctx kalpsdk.TransactionContextInterface, key string) (*TopHolders, error) {
+	topBytes, err := ctx.GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read top holders: %v", err)
+	}
+	if topBytes == nil {
+		return nil, nil
+	}
+	top := new(TopHolders)
+	err = json.Unmarshal(topBytes, top)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode top holders: %v", err)
+	}
+	return top, nil
+}
+
+func writeTopHolders(ctx kalpsdk.TransactionContextInterface, key string, top *TopHolders) error {
+	topJSON, err := canonical.Marshal(top)
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	err = ctx.PutStateWithoutKYC(key, topJSON)
+	if err != nil {
+		return fmt.Errorf("failed to store top holders: %v", err)
+	}
+	return nil
+}
+
+// topHolders ranks every holder by balance, ties broken by account, and returns the first n.
+func topHolders(ctx kalpsdk.TransactionContextInterface, n int) (*TopHolders, error) {
+	if n <= 0 || n > MaxTopHolders {
+		return nil, fmt.Errorf("n must be between 1 and %d", MaxTopHolders)
+	}
+	holders, err := metrics.AllHolders(ctx)
+	if err != nil {
+		return nil, err
+	}
+	timestamp, err := ctx.GetTxTimestamp()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	top := &TopHolders{Holders: []*HolderBalance{}, Timestamp: timestamp.GetSeconds()}
+	err = rankHolders(ctx, top, holders, n)
+	if err != nil {
+		return nil, err
+	}
+	return top, nil
+}
+
+// rankHolders merges holders, with their current balances, into the leaderboard top and keeps its
+// first n entries, ranked by balance with ties broken by account.
+func rankHolders(ctx kalpsdk.TransactionContextInterface, top *TopHolders, holders []string, n int) error {
+	balances := make(map[string]*big.Int, len(top.Holders)+len(holders))
+	for _, entry := range top.Holders {
+		balance, ok := new(big.Int).SetString(entry.Balance, 10)
+		if !ok {
+			return fmt.Errorf("invalid balance %q of top holder %s", entry.Balance, entry.Account)
+		}
+		balances[entry.Account] = balance
+	}
+	for _, holder := range holders {
+		balance, err := readBalance(ctx, holder)
+		if err != nil {
+			return err
+		}
+		balances[holder] = balance
+	}
+	accounts := make([]string, 0, len(balances))
+	for account := range balances {
+		accounts = append(accounts, account)
+	}
+	sort.Slice(accounts, func(i, j int) bool {
+		if cmp := balances[accounts[i]].Cmp(balances[accounts[j]]); cmp != 0 {
+			return cmp > 0
+		}
+		return accounts[i] < accounts[j]
+	})
+	if len(accounts) > n {
+		accounts = accounts[:n]
+	}
+	top.Holders = make([]*HolderBalance, 0, len(accounts))
+	for _, account := range accounts {
+		top.Holders = append(top.Holders, &HolderBalance{account, balances[account].String()})
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package token
+
+import (
+	"fmt"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/journal"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+// SetJournalConfig switches the journal of rejected operations on or off, see journal.Config. The
+// caller must hold AdminRole.
+func (c *TokenERC20Contract) SetJournalConfig(ctx kalpsdk.TransactionContextInterface, cfg journal.Config) error {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return journal.SetConfig(ctx, cfg)
+}
+
+func (c *TokenERC20Contract) GetJournalConfig(ctx kalpsdk.TransactionContextInterface) (*journal.Config, error) {
+	return journal.GetConfig(ctx)
+}
+
+// ReportRejection records that the transaction txID of account calling function with amount was
+// rejected with reason, see journal.Report. The caller must hold journal.ReporterRole.
+func (c *TokenERC20Contract) ReportRejection(ctx kalpsdk.TransactionContextInterface, txID string, account string, function string, amount string, reason string) (bool, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return false, kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return journal.Report(ctx, txID, account, function, amount, reason)
+}
+
+// GetRejections returns up to pageSize rejected operations of account starting at bookmark,
+// oldest first. An empty account reads those of every account.
+func (c *TokenERC20Contract) GetRejections(ctx kalpsdk.TransactionContextInterface, account string, bookmark string, pageSize int) (*journal.Page, error) {
+	return journal.GetRejections(ctx, account, bookmark, pageSize)
+}
@@ -0,0 +1,57 @@
+package token
+
+import (
+	"fmt"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+// SetKYCRequired makes state writes of every function without an override require a KYC'd
+// caller, or stop requiring one.
+func (c *TokenERC20Contract) SetKYCRequired(ctx kalpsdk.TransactionContextInterface, required bool) error {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return accesscontrol.SetKYCRequired(ctx, required)
+}
+
+// SetFunctionKYCRequired overrides the KYC mode of function, e.g. "Transfer".
+func (c *TokenERC20Contract) SetFunctionKYCRequired(ctx kalpsdk.TransactionContextInterface, function string, required bool) error {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return accesscontrol.SetFunctionKYCRequired(ctx, function, required)
+}
+
+// RemoveFunctionKYCOverride makes function follow the contract-wide KYC mode again.
+func (c *TokenERC20Contract) RemoveFunctionKYCOverride(ctx kalpsdk.TransactionContextInterface, function string) error {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return accesscontrol.RemoveFunctionKYCOverride(ctx, function)
+}
+
+func (c *TokenERC20Contract) GetKYCConfig(ctx kalpsdk.TransactionContextInterface) (*accesscontrol.KYCConfig, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return accesscontrol.GetKYCConfig(ctx)
+}
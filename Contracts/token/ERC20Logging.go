@@ -0,0 +1,26 @@
+package token
+
+import (
+	"fmt"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+	"github.com/thekalpstudio/kush-go/logging"
+)
+
+// SetLogLevel changes the level at which the peer evaluating it logs the transactions of the
+// token, one of logging.Levels, until the chaincode restarts.
+func (c *TokenERC20Contract) SetLogLevel(ctx kalpsdk.TransactionContextInterface, level string) error {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return logging.SetLogLevel(ctx, level)
+}
+
+func (c *TokenERC20Contract) GetLogLevel(ctx kalpsdk.TransactionContextInterface) (string, error) {
+	return logging.GetLogLevel(ctx)
+}
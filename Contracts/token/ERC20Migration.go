@@ -0,0 +1,272 @@
+package token
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+	"github.com/thekalpstudio/kush-go/metrics"
+	"github.com/thekalpstudio/kush-go/migration"
+)
+
+const erc20AllowancesV1 = "ERC20AllowancesV1"
+const erc20BurnerRoleV1 = "ERC20BurnerRoleV1"
+
+// erc20Upgrades moves state written by earlier versions of the contract to the current schema.
+var erc20Upgrades = []migration.Upgrade{
+	{From: 0, Migration: func() migration.Migration {
+		return migration.Migration{
+			Name: erc20AllowancesV1,
+			Phases: []migration.Phase{
+				{Prefix: allowancePrefix, Step: normalizeAllowanceStep},
+			},
+		}
+	}},
+	{From: 1, Migration: func() migration.Migration {
+		return migration.Migration{
+			Name: erc20BurnerRoleV1,
+			Phases: []migration.Phase{
+				{Prefix: accesscontrol.RolePrefix, Step: grantBurnerRoleStep},
+			},
+		}
+	}},
+}
+
+// GetVersion returns the schema version of the contract state.
+func (c *TokenERC20Contract) GetVersion(ctx kalpsdk.TransactionContextInterface) (int, error) {
+	return migration.GetVersion(ctx)
+}
+
+// Migrate runs the next batch of the upgrade from fromVersion, moving the state to fromVersion+1
+// once it is Done. Invoke it repeatedly until GetVersion reports the current version.
+func (c *TokenERC20Contract) Migrate(ctx kalpsdk.TransactionContextInterface, fromVersion int, batchSize int, dryRun bool) (*migration.Report, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	err = accesscontrol.CheckRole(ctx, accesscontrol.AdminRole)
+	if err != nil {
+		return nil, fmt.Errorf("client is not authorized to run migrations: %v", err)
+	}
+	return migration.Migrate(ctx, erc20Upgrades, fromVersion, batchSize, dryRun)
+}
+
+// PreviewMigration dry-runs up to batchSize keys of the upgrade from fromVersion after phase and
+// bookmark, those of the last Migrate report or of the previous preview. Evaluate it, do not
+// submit it.
+func (c *TokenERC20Contract) PreviewMigration(ctx kalpsdk.TransactionContextInterface, fromVersion int, phase int, bookmark string, batchSize int) (*migration.Report, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	err = accesscontrol.CheckRole(ctx, accesscontrol.AdminRole)
+	if err != nil {
+		return nil, fmt.Errorf("client is not authorized to run migrations: %v", err)
+	}
+	return migration.PreviewUpgrade(ctx, erc20Upgrades, fromVersion, phase, bookmark, batchSize)
+}
+
+// normalizeAllowanceStep rewrites allowances stored by the int-based versions of the contract in
+// canonical big.Int form, and deletes the zero allowances they left behind.
+func normalizeAllowanceStep(ctx kalpsdk.TransactionContextInterface, key string, value []byte, dryRun bool) ([]string, error) {
+	allowance, err := decodeAmount(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode allowance %s: %v", key, err)
+	}
+	if allowance.Sign() > 0 && allowance.String() == string(value) {
+		return nil, nil
+	}
+	if !dryRun {
+		if allowance.Sign() > 0 {
+			err = writeAmount(ctx, key, allowance)
+		} else {
+			err = ctx.DelStateWithoutKYC(key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to update allowance %s: %v", key, err)
+		}
+	}
+	return []string{key}, nil
+}
+
+// grantBurnerRoleStep grants BURNER_ROLE to every holder of MINTER_ROLE. Burn required
+// MINTER_ROLE before BURNER_ROLE existed, so the upgrade keeps the minters of older tokens able to
+// burn. Revoke BURNER_ROLE afterwards from minters that should not burn.
+func grantBurnerRoleStep(ctx kalpsdk.TransactionContextInterface, key string, value []byte, dryRun bool) ([]string, error) {
+	_, attributes, err := ctx.SplitCompositeKey(key)
+	if err != nil || len(attributes) != 2 {
+		return nil, fmt.Errorf("failed to split role key %s: %v", key, err)
+	}
+	if attributes[0] != accesscontrol.MinterRole {
+		return nil, nil
+	}
+	account := attributes[1]
+	burner, err := accesscontrol.HasRole(ctx, accesscontrol.BurnerRole, account)
+	if err != nil || burner {
+		return nil, err
+	}
+	burnerKey, err := ctx.CreateCompositeKey(accesscontrol.RolePrefix, []string{accesscontrol.BurnerRole, account})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the composite key for prefix %s: %v", accesscontrol.RolePrefix, err)
+	}
+	if !dryRun {
+		err = accesscontrol.MigrateRole(ctx, accesscontrol.BurnerRole, account)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return []string{burnerKey}, nil
+}
+
+// erc20ExportPrefixes are the keys ExportState reads: balances and contract options, which are
+// simple keys, balance delta records, allowances and snapshot checkpoints.
+var erc20ExportPrefixes = []string{migration.SimpleKeys, balanceDeltaPrefix, allowancePrefix, snapshotPrefix}
+
+// ExportState returns the next chunk of the token state, for backups, audits or moving the token
+// to a new channel. Start with phase 0 and an empty bookmark and continue with those of the
+// returned chunk until it is Done. The caller must hold AdminRole.
+func (c *TokenERC20Contract) ExportState(ctx kalpsdk.TransactionContextInterface, phase int, bookmark string, pageSize int) (*migration.Chunk, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	err = checkAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return migration.Export(ctx, erc20ExportPrefixes, phase, bookmark, pageSize)
+}
+
+// ImportState writes a chunk exported by ExportState of a previous deployment, so a token moves to
+// a new channel without replaying its transactions. Initialize the token with ImportState set,
+// import every chunk before the token is opened to users, then call SealImport. The total supply
+// and the holder index are counted from the imported balances rather than copied, and the activity
+// metrics start over. The caller must hold AdminRole.
+func (c *TokenERC20Contract) ImportState(ctx kalpsdk.TransactionContextInterface, chunk migration.Chunk) (int, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return 0, kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	err = checkAdmin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	entries := make([]*migration.Entry, 0, len(chunk.Entries))
+	for _, entry := range chunk.Entries {
+		if entry.Prefix == migration.SimpleKeys && (entry.Key == totalSupplyKey || metrics.IsDerivedKey(entry.Key)) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	chunk.Entries = entries
+	imported, err := migration.Import(ctx, erc20ExportPrefixes, &chunk)
+	if err != nil {
+		return 0, err
+	}
+	err = countImportedBalances(ctx, chunk.Entries)
+	if err != nil {
+		return 0, err
+	}
+	return imported, nil
+}
+
+// countImportedBalances adds the imported balances and delta records to the total supply and
+// records their accounts as holders.
+func countImportedBalances(ctx kalpsdk.TransactionContextInterface, entries []*migration.Entry) error {
+	imported := new(big.Int)
+	for _, entry := range entries {
+		account := entry.Key
+		switch entry.Prefix {
+		case migration.SimpleKeys:
+			if checkAccount(account) != nil {
+				continue
+			}
+		case balanceDeltaPrefix:
+			_, parts, err := ctx.SplitCompositeKey(entry.Key)
+			if err != nil || len(parts) == 0 {
+				return fmt.Errorf("failed to split balance delta key %s: %v", entry.Key, err)
+			}
+			account = parts[0]
+		default:
+			continue
+		}
+		amount, err := decodeAmount(entry.Value)
+		if err != nil {
+			return fmt.Errorf("failed to decode balance %s: %v", entry.Key, err)
+		}
+		if amount.Sign() > 0 {
+			err = metrics.Hold(ctx, account, true)
+			if err != nil {
+				return err
+			}
+		}
+		imported.Add(imported, amount)
+	}
+	if imported.Sign() == 0 {
+		return nil
+	}
+	totalSupply, err := readAmount(ctx, totalSupplyKey)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve total token supply: %v", err)
+	}
+	return writeAmount(ctx, totalSupplyKey, totalSupply.Add(totalSupply, imported))
+}
+
+// SealImport refuses every later ImportState call. The caller must hold AdminRole.
+func (c *TokenERC20Contract) SealImport(ctx kalpsdk.TransactionContextInterface) error {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	err = checkAdmin(ctx)
+	if err != nil {
+		return err
+	}
+	return migration.SealImport(ctx)
+}
+
+// erc20Decoders decode the values GetRawStateRange reads.
+var erc20Decoders = map[string]migration.Decoder{
+	migration.SimpleKeys: migration.DecodeText,
+	balanceDeltaPrefix:   migration.DecodeText,
+	allowancePrefix:      migration.DecodeText,
+	snapshotPrefix:       migration.DecodeText,
+}
+
+// GetRawStateRange returns up to pageSize raw keys under prefix, a composite key object type or ""
+// for the simple keys, starting at bookmark, decoding the values of the known prefixes. It is
+// meant for debugging orphaned keys without access to the state database. The caller must hold
+// AdminRole.
+func (c *TokenERC20Contract) GetRawStateRange(ctx kalpsdk.TransactionContextInterface, prefix string, bookmark string, pageSize int) (*migration.RawPage, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	err = checkAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return migration.RawRange(ctx, prefix, erc20Decoders, bookmark, pageSize)
+}
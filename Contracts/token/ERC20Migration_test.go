@@ -0,0 +1,124 @@
+package token
+
+import (
+	"testing"
+
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+	"github.com/thekalpstudio/kush-go/migration"
+	"github.com/thekalpstudio/kush-go/testutil"
+)
+
+// exportERC20 returns every chunk ExportState reads from the token of l.
+func exportERC20(t *testing.T, l *testutil.Ledger, c *TokenERC20Contract) []*migration.Chunk {
+	t.Helper()
+	chunks := []*migration.Chunk{}
+	for phase, bookmark := 0, ""; ; {
+		chunk, err := c.ExportState(l.Tx(admin, "ExportState"), phase, bookmark, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		chunks = append(chunks, chunk)
+		if chunk.Done {
+			return chunks
+		}
+		phase, bookmark = chunk.Phase, chunk.Bookmark
+	}
+}
+
+func TestERC20ImportState(t *testing.T) {
+	source, c := newERC20(t)
+	testutil.MustRun(t, source, admin, "Transfer", func(ctx *testutil.Context) error {
+		return c.Transfer(ctx, testutil.ClientID("bob"), "300")
+	})
+	chunks := exportERC20(t, source, c)
+
+	// a token initialized without ImportState refuses imports
+	sealed, _ := newERC20(t)
+	testutil.CheckErr(t, testutil.Run(sealed, admin, "ImportState", func(ctx *testutil.Context) error {
+		_, err := c.ImportState(ctx, *chunks[0])
+		return err
+	}), true, "")
+
+	l := testutil.NewLedger()
+	testutil.MustRun(t, l, admin, "Initialize", func(ctx *testutil.Context) error {
+		cfg := testConfig()
+		cfg.ImportState = true
+		_, err := c.Initialize(ctx, cfg)
+		return err
+	})
+	for _, chunk := range chunks {
+		chunk := chunk
+		testutil.MustRun(t, l, admin, "ImportState", func(ctx *testutil.Context) error {
+			_, err := c.ImportState(ctx, *chunk)
+			return err
+		})
+	}
+	testutil.MustRun(t, l, admin, "SealImport", func(ctx *testutil.Context) error {
+		return c.SealImport(ctx)
+	})
+
+	for account, want := range map[string]string{admin: "700", testutil.ClientID("bob"): "300"} {
+		if balance := erc20Balance(t, l, c, account); balance != want {
+			t.Fatalf("balance of %s is %s, want %s", account, balance, want)
+		}
+	}
+	ctx := l.Tx(admin, "TotalSupply")
+	if supply, err := c.TotalSupply(ctx); err != nil || supply != "1000" {
+		t.Fatalf("total supply is %s, want 1000: %v", supply, err)
+	}
+	if holders, err := c.HolderCount(ctx); err != nil || holders != 2 {
+		t.Fatalf("token has %d holders, want 2: %v", holders, err)
+	}
+	testutil.CheckErr(t, testutil.Run(l, admin, "ImportState", func(ctx *testutil.Context) error {
+		_, err := c.ImportState(ctx, *chunks[0])
+		return err
+	}), true, "")
+}
+
+// TestERC20UpgradeBurnerRole upgrades a token from before BURNER_ROLE, whose minters burned with
+// MINTER_ROLE.
+func TestERC20UpgradeBurnerRole(t *testing.T) {
+	l, c := newERC20(t)
+	testutil.MustRun(t, l, admin, "Legacy", func(ctx *testutil.Context) error {
+		err := migration.SetVersion(ctx, 1)
+		if err != nil {
+			return err
+		}
+		err = accesscontrol.GrantRole(ctx, accesscontrol.MinterRole, "bob")
+		if err != nil {
+			return err
+		}
+		return accesscontrol.RevokeRole(ctx, accesscontrol.BurnerRole, admin)
+	})
+	testutil.MustRun(t, l, admin, "Transfer", func(ctx *testutil.Context) error {
+		return c.Transfer(ctx, "bob", "100")
+	})
+	testutil.CheckErr(t, testutil.Run(l, "bob", "Burn", func(ctx *testutil.Context) error {
+		return c.Burn(ctx, "10")
+	}), true, kusherrors.ErrUnauthorized)
+
+	for done := false; !done; {
+		testutil.MustRun(t, l, admin, "Migrate", func(ctx *testutil.Context) error {
+			report, err := c.Migrate(ctx, 1, 1, false)
+			if err == nil {
+				done = report.Done
+			}
+			return err
+		})
+	}
+	ctx := l.Tx(admin, "GetVersion")
+	if version, err := c.GetVersion(ctx); err != nil || version != 2 {
+		t.Fatalf("state is at version %d, want 2: %v", version, err)
+	}
+	for _, account := range []string{admin, "bob"} {
+		if burner, err := accesscontrol.HasRole(ctx, accesscontrol.BurnerRole, account); err != nil || !burner {
+			t.Fatalf("%s has no BURNER_ROLE after the upgrade: %v", account, err)
+		}
+	}
+	for _, user := range []string{admin, "bob"} {
+		testutil.MustRun(t, l, user, "Burn", func(ctx *testutil.Context) error {
+			return c.Burn(ctx, "10")
+		})
+	}
+}
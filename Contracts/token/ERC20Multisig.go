@@ -0,0 +1,157 @@
+package token
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+	"github.com/thekalpstudio/kush-go/denylist"
+	"github.com/thekalpstudio/kush-go/guardian"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+// SetMultisig makes accesscontrol.MultisigActions, and mints beyond mintThreshold tokens within
+// mintWindow seconds, require threshold approvals of signers. An empty mintThreshold leaves mints
+// to the MINTER_ROLE, and a mintWindow of 0 stands for accesscontrol.DefaultMintWindow. Only the
+// owner may enable it; afterwards the configuration changes through an
+// accesscontrol.ActionSetMultisig proposal.
+func (c *TokenERC20Contract) SetMultisig(ctx kalpsdk.TransactionContextInterface, signers []string, threshold int, mintThreshold string, mintWindow int64) error {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return accesscontrol.SetMultisig(ctx, &accesscontrol.MultisigConfig{Signers: signers, Threshold: threshold, MintThreshold: mintThreshold, MintWindow: mintWindow})
+}
+
+// GetMultisig returns the multisig configuration, or nil if multisig approval is disabled.
+func (c *TokenERC20Contract) GetMultisig(ctx kalpsdk.TransactionContextInterface) (*accesscontrol.MultisigConfig, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return accesscontrol.GetMultisig(ctx)
+}
+
+// Propose records a proposal for action with args, valid for ttl seconds. See
+// accesscontrol.Proposal for the arguments of each action.
+func (c *TokenERC20Contract) Propose(ctx kalpsdk.TransactionContextInterface, action string, args []string, ttl int64) (*accesscontrol.Proposal, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return accesscontrol.Propose(ctx, action, args, ttl)
+}
+
+// ApproveProposal adds the caller's approval to proposal id.
+func (c *TokenERC20Contract) ApproveProposal(ctx kalpsdk.TransactionContextInterface, id string) (*accesscontrol.Proposal, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return accesscontrol.ApproveProposal(ctx, id)
+}
+
+func (c *TokenERC20Contract) GetProposal(ctx kalpsdk.TransactionContextInterface, id string) (*accesscontrol.Proposal, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return accesscontrol.GetProposal(ctx, id)
+}
+
+// GetPendingProposals returns the proposals that are neither executed nor expired.
+func (c *TokenERC20Contract) GetPendingProposals(ctx kalpsdk.TransactionContextInterface) ([]*accesscontrol.Proposal, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return accesscontrol.GetPendingProposals(ctx)
+}
+
+// ExecuteProposal executes proposal id once enough signers approved it, or schedules it while
+// the timelock covers its action.
+func (c *TokenERC20Contract) ExecuteProposal(ctx kalpsdk.TransactionContextInterface, id string) (*accesscontrol.Proposal, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return accesscontrol.ExecuteProposal(ctx, id, erc20Executor(ctx))
+}
+
+// erc20Executor executes the ERC20 actions of approved proposals and timelock operations.
+func erc20Executor(ctx kalpsdk.TransactionContextInterface) accesscontrol.Executor {
+	return func(action string, args []string) error {
+		switch action {
+		case accesscontrol.ActionMint:
+			if len(args) != 2 {
+				return fmt.Errorf("%s takes an account and an amount", action)
+			}
+			err := checkNotPaused(ctx)
+			if err != nil {
+				return err
+			}
+			account := args[0]
+			if account == "" || account == "0x0" {
+				return fmt.Errorf("mint to the zero address")
+			}
+			mintAmount, err := parseAmount(args[1])
+			if err != nil {
+				return err
+			}
+			if mintAmount.Sign() <= 0 {
+				return fmt.Errorf("mint amount must be a positive integer")
+			}
+			return _mint(ctx, account, mintAmount)
+		case accesscontrol.ActionPause, accesscontrol.ActionUnpause:
+			return writePaused(ctx, action == accesscontrol.ActionPause)
+		case accesscontrol.ActionSetTransferFee:
+			if len(args) != 2 {
+				return fmt.Errorf("%s takes a fee in basis points and a collector", action)
+			}
+			feeBps, err := strconv.Atoi(args[0])
+			if err != nil {
+				return fmt.Errorf("invalid fee %q: %v", args[0], err)
+			}
+			return writeTransferFee(ctx, feeBps, args[1])
+		case accesscontrol.ActionUpdateConfig:
+			if len(args) != 1 {
+				return fmt.Errorf("%s takes a JSON config", action)
+			}
+			return applyERC20Config(ctx, args[0])
+		case accesscontrol.ActionSetGuardian:
+			if len(args) != 1 {
+				return fmt.Errorf("%s takes a chaincode name", action)
+			}
+			return guardian.WriteGuardian(ctx, args[0])
+		case accesscontrol.ActionSetDenyList:
+			if len(args) != 1 {
+				return fmt.Errorf("%s takes a chaincode name", action)
+			}
+			return denylist.WriteDenyList(ctx, args[0])
+		default:
+			return fmt.Errorf("action %s is not supported by this contract", action)
+		}
+	}
+}
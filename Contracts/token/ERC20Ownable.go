@@ -0,0 +1,59 @@
+package token
+
+import (
+	"fmt"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+// Owner returns the account that owns the contract and holds its ADMIN_ROLE.
+func (c *TokenERC20Contract) Owner(ctx kalpsdk.TransactionContextInterface) (string, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return "", kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return accesscontrol.Owner(ctx)
+}
+
+// PendingOwner returns the account nominated by TransferOwnership, if any.
+func (c *TokenERC20Contract) PendingOwner(ctx kalpsdk.TransactionContextInterface) (string, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return "", kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return accesscontrol.PendingOwner(ctx)
+}
+
+// TransferOwnership nominates newOwner as the next owner. Ownership only moves once newOwner calls
+// AcceptOwnership, so a mistyped account cannot take over the contract.
+func (c *TokenERC20Contract) TransferOwnership(ctx kalpsdk.TransactionContextInterface, newOwner string) error {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return accesscontrol.TransferOwnership(ctx, newOwner)
+}
+
+// AcceptOwnership completes a transfer started by TransferOwnership. Only the pending owner may
+// call it.
+func (c *TokenERC20Contract) AcceptOwnership(ctx kalpsdk.TransactionContextInterface) error {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return accesscontrol.AcceptOwnership(ctx)
+}
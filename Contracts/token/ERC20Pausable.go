@@ -0,0 +1,146 @@
+package token
+
+import (
+	"fmt"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+	"github.com/thekalpstudio/kush-go/audit"
+	"github.com/thekalpstudio/kush-go/events"
+	"github.com/thekalpstudio/kush-go/guardian"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+const pausedKey = "paused"
+
+// PauseEvent is emitted as Paused or Unpaused by the account that changed the state.
+type PauseEvent = events.PauseEvent
+
+// Pause halts minting, burning, transfers and approvals until Unpause is called.
+func (c *TokenERC20Contract) Pause(ctx kalpsdk.TransactionContextInterface) error {
+	return setPaused(ctx, true)
+}
+
+// Unpause resumes token movement after Pause.
+func (c *TokenERC20Contract) Unpause(ctx kalpsdk.TransactionContextInterface) error {
+	return setPaused(ctx, false)
+}
+
+// SetGuardian makes the contract also stop while the guardian chaincode chaincodeName is paused.
+// An empty name detaches the guardian.
+func (c *TokenERC20Contract) SetGuardian(ctx kalpsdk.TransactionContextInterface, chaincodeName string) error {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return guardian.SetGuardian(ctx, chaincodeName)
+}
+
+func (c *TokenERC20Contract) GetGuardian(ctx kalpsdk.TransactionContextInterface) (string, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return "", kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return guardian.GetGuardian(ctx)
+}
+
+func (c *TokenERC20Contract) IsPaused(ctx kalpsdk.TransactionContextInterface) (bool, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return false, kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	return isPaused(ctx)
+}
+
+func setPaused(ctx kalpsdk.TransactionContextInterface, paused bool) error {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	err = accesscontrol.CheckRole(ctx, accesscontrol.PauserRole)
+	if err != nil {
+		return fmt.Errorf("client is not authorized to pause: %v", err)
+	}
+
+	action := accesscontrol.ActionUnpause
+	if paused {
+		action = accesscontrol.ActionPause
+	}
+	err = accesscontrol.CheckNotMultisig(ctx, action)
+	if err != nil {
+		return err
+	}
+	return writePaused(ctx, paused)
+}
+
+// writePaused changes the paused state, leaving authorization to the caller.
+func writePaused(ctx kalpsdk.TransactionContextInterface, paused bool) error {
+	current, err := isPaused(ctx)
+	if err != nil {
+		return err
+	}
+	if current == paused {
+		if paused {
+			return fmt.Errorf("contract is already paused")
+		}
+		return fmt.Errorf("contract is not paused")
+	}
+
+	if paused {
+		err = accesscontrol.PutState(ctx, pausedKey, []byte("true"))
+	} else {
+		err = accesscontrol.DelState(ctx, pausedKey)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update paused state: %v", err)
+	}
+
+	account, err := ctx.GetUserID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+	eventName := "Unpaused"
+	if paused {
+		eventName = "Paused"
+	}
+	err = audit.Record(ctx, eventName)
+	if err != nil {
+		return err
+	}
+	return events.Emit(ctx, eventName, &PauseEvent{Account: account})
+}
+
+func isPaused(ctx kalpsdk.TransactionContextInterface) (bool, error) {
+	pausedBytes, err := ctx.GetState(pausedKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to read paused state: %v", err)
+	}
+	return pausedBytes != nil, nil
+}
+
+// checkNotPaused is called on every path that moves, creates, destroys or approves tokens.
+// checkNotPaused fails if this contract or its guardian is paused.
+func checkNotPaused(ctx kalpsdk.TransactionContextInterface) error {
+	paused, err := isPaused(ctx)
+	if err != nil {
+		return err
+	}
+	if paused {
+		return fmt.Errorf("token operations are paused")
+	}
+	return guardian.CheckNotGloballyPaused(ctx)
+}
@@ -0,0 +1,221 @@
+package token
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+	"github.com/thekalpstudio/kush-go/canonical"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+const permitKeyPrefix = "permit~key"
+const permitNoncePrefix = "permit~nonce"
+
+// permitMessage is the payload an owner signs to authorize Permit. The channel and token name act
+// as the signing domain so a signature cannot be replayed against another deployment.
+type permitMessage struct {
+	Channel  string `json:"channel"`
+	Name     string `json:"name"`
+	Owner    string `json:"owner"`
+	Spender  string `json:"spender"`
+	Value    string `json:"value"`
+	Nonce    uint64 `json:"nonce"`
+	Deadline int64  `json:"deadline"`
+}
+
+// RegisterPermitKey records the public key of the caller's enrollment certificate as the key that
+// signs the caller's permits.
+func (c *TokenERC20Contract) RegisterPermitKey(ctx kalpsdk.TransactionContextInterface) error {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	owner, err := ctx.GetUserID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+	cert, err := ctx.GetClientIdentity().GetX509Certificate()
+	if err != nil || cert == nil {
+		return fmt.Errorf("failed to get client certificate: %v", err)
+	}
+	publicKey, err := x509.MarshalPKIXPublicKey(cert.PublicKey)
+	if err != nil {
+		return fmt.Errorf("failed to encode client public key: %v", err)
+	}
+
+	permitKey, err := ctx.CreateCompositeKey(permitKeyPrefix, []string{owner})
+	if err != nil {
+		return fmt.Errorf("failed to create the composite key for prefix %s: %v", permitKeyPrefix, err)
+	}
+	return accesscontrol.PutState(ctx, permitKey, publicKey)
+}
+
+// Nonces returns the nonce the next permit of owner must be signed with.
+func (c *TokenERC20Contract) Nonces(ctx kalpsdk.TransactionContextInterface, owner string) (uint64, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return 0, kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	return readPermitNonce(ctx, owner)
+}
+
+// PermitDigest returns the hex encoded sha256 digest owner has to sign for a permit.
+func (c *TokenERC20Contract) PermitDigest(ctx kalpsdk.TransactionContextInterface, owner string, spender string, value string, deadline int64) (string, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return "", kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	nonce, err := readPermitNonce(ctx, owner)
+	if err != nil {
+		return "", err
+	}
+	digest, err := permitDigest(ctx, owner, spender, value, nonce, deadline)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(digest), nil
+}
+
+// Permit sets the allowance of spender over owner's tokens from a signature made by owner, so a
+// relayer can submit the approval on the owner's behalf. The signature is the base64 encoded
+// ECDSA (ASN.1) or Ed25519 signature over PermitDigest.
+func (c *TokenERC20Contract) Permit(ctx kalpsdk.TransactionContextInterface, owner string, spender string, value string, deadline int64, signature string) error {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	err = checkNotPaused(ctx)
+	if err != nil {
+		return err
+	}
+
+	timestamp, err := ctx.GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	if timestamp.GetSeconds() > deadline {
+		return fmt.Errorf("permit deadline has expired")
+	}
+
+	allowance, err := parseAmount(value)
+	if err != nil {
+		return err
+	}
+
+	nonce, err := readPermitNonce(ctx, owner)
+	if err != nil {
+		return err
+	}
+	digest, err := permitDigest(ctx, owner, spender, value, nonce, deadline)
+	if err != nil {
+		return err
+	}
+	err = verifyPermitSignature(ctx, owner, digest, signature)
+	if err != nil {
+		return err
+	}
+
+	nonceKey, err := ctx.CreateCompositeKey(permitNoncePrefix, []string{owner})
+	if err != nil {
+		return fmt.Errorf("failed to create the composite key for prefix %s: %v", permitNoncePrefix, err)
+	}
+	err = accesscontrol.PutState(ctx, nonceKey, []byte(strconv.FormatUint(nonce+1, 10)))
+	if err != nil {
+		return fmt.Errorf("failed to update permit nonce: %v", err)
+	}
+
+	return approveHelper(ctx, owner, spender, allowance)
+}
+
+func permitDigest(ctx kalpsdk.TransactionContextInterface, owner string, spender string, value string, nonce uint64, deadline int64) ([]byte, error) {
+	name, err := ctx.GetState(nameKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get token name: %v", err)
+	}
+	message := permitMessage{ctx.GetChannelID(), string(name), owner, spender, value, nonce, deadline}
+	messageJSON, err := canonical.Marshal(message)
+	if err != nil {
+		return nil, fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	digest := sha256.Sum256(messageJSON)
+	return digest[:], nil
+}
+
+func verifyPermitSignature(ctx kalpsdk.TransactionContextInterface, owner string, digest []byte, signature string) error {
+	permitKey, err := ctx.CreateCompositeKey(permitKeyPrefix, []string{owner})
+	if err != nil {
+		return fmt.Errorf("failed to create the composite key for prefix %s: %v", permitKeyPrefix, err)
+	}
+	publicKeyBytes, err := ctx.GetState(permitKey)
+	if err != nil {
+		return fmt.Errorf("failed to read permit key of %s: %v", owner, err)
+	}
+	if publicKeyBytes == nil {
+		return fmt.Errorf("account %s has not registered a permit key", owner)
+	}
+	publicKey, err := x509.ParsePKIXPublicKey(publicKeyBytes)
+	if err != nil {
+		return fmt.Errorf("failed to decode permit key of %s: %v", owner, err)
+	}
+	signatureBytes, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("failed to decode permit signature: %v", err)
+	}
+
+	valid := false
+	switch key := publicKey.(type) {
+	case *ecdsa.PublicKey:
+		valid = ecdsa.VerifyASN1(key, digest, signatureBytes)
+	case ed25519.PublicKey:
+		valid = ed25519.Verify(key, digest, signatureBytes)
+	default:
+		return fmt.Errorf("unsupported permit key type %T", publicKey)
+	}
+	if !valid {
+		return fmt.Errorf("invalid permit signature")
+	}
+	return nil
+}
+
+func readPermitNonce(ctx kalpsdk.TransactionContextInterface, owner string) (uint64, error) {
+	nonceKey, err := ctx.CreateCompositeKey(permitNoncePrefix, []string{owner})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create the composite key for prefix %s: %v", permitNoncePrefix, err)
+	}
+	nonceBytes, err := ctx.GetState(nonceKey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read permit nonce of %s: %v", owner, err)
+	}
+	if nonceBytes == nil {
+		return 0, nil
+	}
+	nonce, err := strconv.ParseUint(string(nonceBytes), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to decode permit nonce of %s: %v", owner, err)
+	}
+	return nonce, nil
+}
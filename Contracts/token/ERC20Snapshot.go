@@ -0,0 +1,202 @@
+package token
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+	"github.com/thekalpstudio/kush-go/events"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+	"github.com/thekalpstudio/kush-go/metrics"
+)
+
+const snapshotIdKey = "snapshotId"
+const snapshotPrefix = "snapshot"
+
+// SnapshotEvent MUST emit when a snapshot is taken.
+type SnapshotEvent = events.Snapshot
+
+// Snapshot records the current balances and total supply under a new snapshot id and returns it.
+// Balances are checkpointed lazily: an account's value at a snapshot is only written when the
+// balance first changes afterwards.
+func (c *TokenERC20Contract) Snapshot(ctx kalpsdk.TransactionContextInterface) (uint64, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return 0, kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	err = checkAdmin(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	id, err := currentSnapshotId(ctx)
+	if err != nil {
+		return 0, err
+	}
+	id++
+	err = accesscontrol.PutState(ctx, snapshotIdKey, []byte(strconv.FormatUint(id, 10)))
+	if err != nil {
+		return 0, fmt.Errorf("failed to update snapshot id: %v", err)
+	}
+
+	err = events.Emit(ctx, "Snapshot", &SnapshotEvent{Id: id})
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+// BalanceOfAt returns the balance of account at the time snapshotId was taken.
+func (c *TokenERC20Contract) BalanceOfAt(ctx kalpsdk.TransactionContextInterface, account string, snapshotId uint64) (string, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return "", kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	balance, err := valueAt(ctx, account, snapshotId)
+	if err != nil {
+		return "", err
+	}
+	return balance.String(), nil
+}
+
+// TotalSupplyAt returns the total supply at the time snapshotId was taken.
+func (c *TokenERC20Contract) TotalSupplyAt(ctx kalpsdk.TransactionContextInterface, snapshotId uint64) (string, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return "", kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	totalSupply, err := valueAt(ctx, totalSupplyKey, snapshotId)
+	if err != nil {
+		return "", err
+	}
+	return totalSupply.String(), nil
+}
+
+// writeBalance stores a balance or the total supply, checkpointing the previous value first if
+// it has not been recorded for the current snapshot yet. Balances include their delta records,
+// which are folded into the stored balance, and keep the holder index up to date.
+func writeBalance(ctx kalpsdk.TransactionContextInterface, key string, amount *big.Int) error {
+	err := checkpointBalance(ctx, key)
+	if err != nil {
+		return err
+	}
+	if key != totalSupplyKey {
+		err = metrics.Hold(ctx, key, amount.Sign() > 0)
+		if err != nil {
+			return err
+		}
+		err = foldBalanceDeltas(ctx, key)
+		if err != nil {
+			return err
+		}
+	}
+	return writeAmount(ctx, key, amount)
+}
+
+// checkpointBalance records the value of key for the current snapshot, unless it already was.
+func checkpointBalance(ctx kalpsdk.TransactionContextInterface, key string) error {
+	id, err := currentSnapshotId(ctx)
+	if err != nil {
+		return err
+	}
+	if id == 0 {
+		return nil
+	}
+	checkpointKey, err := ctx.CreateCompositeKey(snapshotPrefix, []string{key, snapshotIdString(id)})
+	if err != nil {
+		return fmt.Errorf("failed to create the composite key for prefix %s: %v", snapshotPrefix, err)
+	}
+	checkpointBytes, err := ctx.GetState(checkpointKey)
+	if err != nil {
+		return fmt.Errorf("failed to read snapshot checkpoint of %s: %v", key, err)
+	}
+	if checkpointBytes != nil {
+		return nil
+	}
+	previous, err := readValue(ctx, key)
+	if err != nil {
+		return err
+	}
+	err = writeAmount(ctx, checkpointKey, previous)
+	if err != nil {
+		return fmt.Errorf("failed to write snapshot checkpoint of %s: %v", key, err)
+	}
+	return nil
+}
+
+// valueAt returns the value of key at snapshotId: the first checkpoint taken at or after that
+// snapshot or, if the value has not changed since, the current value.
+func valueAt(ctx kalpsdk.TransactionContextInterface, key string, snapshotId uint64) (*big.Int, error) {
+	current, err := currentSnapshotId(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if snapshotId == 0 || snapshotId > current {
+		return nil, fmt.Errorf("snapshot %d does not exist", snapshotId)
+	}
+
+	iterator, err := ctx.GetStateByPartialCompositeKey(snapshotPrefix, []string{key})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get state for prefix %v: %v", snapshotPrefix, err)
+	}
+	defer iterator.Close()
+
+	wanted := snapshotIdString(snapshotId)
+	for iterator.HasNext() {
+		queryResponse, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the next state for prefix %v: %v", snapshotPrefix, err)
+		}
+		_, parts, err := ctx.SplitCompositeKey(queryResponse.Key)
+		if err != nil || len(parts) != 2 {
+			return nil, fmt.Errorf("failed to split snapshot key %s: %v", queryResponse.Key, err)
+		}
+		// Ids are zero padded, so checkpoints come back in snapshot order.
+		if parts[0] == key && parts[1] >= wanted {
+			return decodeAmount(queryResponse.Value)
+		}
+	}
+	return readValue(ctx, key)
+}
+
+// readValue returns the current value of key, a balance or the total supply, which is not an
+// account and is read as a plain amount.
+func readValue(ctx kalpsdk.TransactionContextInterface, key string) (*big.Int, error) {
+	if key == totalSupplyKey {
+		return readAmount(ctx, key)
+	}
+	return readBalance(ctx, key)
+}
+
+func currentSnapshotId(ctx kalpsdk.TransactionContextInterface) (uint64, error) {
+	idBytes, err := ctx.GetState(snapshotIdKey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read snapshot id: %v", err)
+	}
+	if idBytes == nil {
+		return 0, nil
+	}
+	id, err := strconv.ParseUint(string(idBytes), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to decode snapshot id: %v", err)
+	}
+	return id, nil
+}
+
+func snapshotIdString(id uint64) string {
+	return fmt.Sprintf("%020d", id)
+}
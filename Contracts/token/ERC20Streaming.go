@@ -0,0 +1,317 @@
+package token
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+	"github.com/thekalpstudio/kush-go/canonical"
+	"github.com/thekalpstudio/kush-go/events"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+const streamPrefix = "stream"
+
+// streamEscrowAccount holds the undistributed deposits of all open streams.
+const streamEscrowAccount = "0xstream"
+
+// Stream pays Deposit from Sender to Recipient evenly between StartTime and StopTime. The deposit
+// is escrowed when the stream is created and is RatePerSecond over the whole stream, less the
+// transfer fee.
+type Stream struct {
+	StreamId      string   `json:"streamId"`
+	Sender        string   `json:"sender"`
+	Recipient     string   `json:"recipient"`
+	RatePerSecond *big.Int `json:"ratePerSecond"`
+	StartTime     int64    `json:"startTime"`
+	StopTime      int64    `json:"stopTime"`
+	Deposit       *big.Int `json:"deposit"`
+	Withdrawn     *big.Int `json:"withdrawn"`
+	Cancelled     bool     `json:"cancelled"`
+}
+
+// StreamBalance splits the remaining deposit of a stream between its two parties.
+type StreamBalance struct {
+	StreamId  string   `json:"streamId"`
+	Recipient *big.Int `json:"recipient"`
+	Sender    *big.Int `json:"sender"`
+}
+
+// StreamEvent is emitted as StreamCreated, StreamWithdrawn and StreamCancelled, listing the
+// transfers in and out of escrow.
+type StreamEvent = events.StreamEvent
+
+// CreateStream transfers ratePerSecond*(stopTime-now) from the caller into escrow, subject to the
+// transfer fee and the travel rule like Transfer, and streams what arrives to recipient until
+// stopTime. The stream id is the transaction id.
+func (c *TokenERC20Contract) CreateStream(ctx kalpsdk.TransactionContextInterface, recipient string, ratePerSecond string, stopTime int64) (*Stream, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	err = checkNotPaused(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sender, err := ctx.GetUserID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client id: %v", err)
+	}
+	if recipient == "" || recipient == "0x0" || recipient == sender {
+		return nil, fmt.Errorf("stream recipient must be a valid account other than the sender")
+	}
+	rate, err := parseAmount(ratePerSecond)
+	if err != nil {
+		return nil, err
+	}
+	if rate.Sign() <= 0 {
+		return nil, fmt.Errorf("stream rate must be a positive integer")
+	}
+	now, err := txTimestampSeconds(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if stopTime <= now {
+		return nil, fmt.Errorf("stream stop time must be in the future")
+	}
+
+	deposit := new(big.Int).Mul(rate, big.NewInt(stopTime-now))
+	err = checkTravelRule(ctx, deposit)
+	if err != nil {
+		return nil, err
+	}
+	fee, err := transferHelper(ctx, sender, streamEscrowAccount, deposit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to transfer the stream deposit: %v", err)
+	}
+	leg := events.Transfer{From: sender, To: streamEscrowAccount, Value: deposit, FeeCharged: fee}
+	if fee != nil {
+		deposit = new(big.Int).Sub(deposit, fee.Amount)
+	}
+
+	stream := &Stream{
+		StreamId:      ctx.GetTxID(),
+		Sender:        sender,
+		Recipient:     recipient,
+		RatePerSecond: rate,
+		StartTime:     now,
+		StopTime:      stopTime,
+		Deposit:       deposit,
+		Withdrawn:     new(big.Int),
+	}
+	err = writeStream(ctx, stream)
+	if err != nil {
+		return nil, err
+	}
+
+	streamEvent := StreamEvent{StreamId: stream.StreamId, Sender: sender, Recipient: recipient, Amount: deposit, Transfers: []events.Transfer{leg}}
+	err = events.Emit(ctx, "StreamCreated", &streamEvent)
+	if err != nil {
+		return nil, err
+	}
+	return stream, nil
+}
+
+// WithdrawFromStream pays amount of the accrued, not yet withdrawn balance to the recipient.
+func (c *TokenERC20Contract) WithdrawFromStream(ctx kalpsdk.TransactionContextInterface, streamId string, amount string) error {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	err = checkNotPaused(ctx)
+	if err != nil {
+		return err
+	}
+
+	stream, err := readStream(ctx, streamId)
+	if err != nil {
+		return err
+	}
+	clientID, err := ctx.GetUserID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+	if clientID != stream.Recipient {
+		return fmt.Errorf("only the recipient of stream %s can withdraw from it", streamId)
+	}
+	withdrawal, err := parseAmount(amount)
+	if err != nil {
+		return err
+	}
+	if withdrawal.Sign() <= 0 {
+		return fmt.Errorf("withdrawal amount must be a positive integer")
+	}
+
+	balance, err := streamBalance(ctx, stream)
+	if err != nil {
+		return err
+	}
+	if balance.Recipient.Cmp(withdrawal) < 0 {
+		return fmt.Errorf("withdrawal exceeds the available stream balance of %s", balance.Recipient)
+	}
+
+	stream.Withdrawn.Add(stream.Withdrawn, withdrawal)
+	err = writeStream(ctx, stream)
+	if err != nil {
+		return err
+	}
+	err = moveBalance(ctx, streamEscrowAccount, stream.Recipient, withdrawal)
+	if err != nil {
+		return err
+	}
+
+	legs := []events.Transfer{{From: streamEscrowAccount, To: stream.Recipient, Value: withdrawal}}
+	streamEvent := StreamEvent{StreamId: streamId, Sender: stream.Sender, Recipient: stream.Recipient, Amount: withdrawal, Transfers: legs}
+	return events.Emit(ctx, "StreamWithdrawn", &streamEvent)
+}
+
+// CancelStream settles the stream pro rata: the recipient receives what has accrued and the
+// sender is refunded the rest. Either party can cancel.
+func (c *TokenERC20Contract) CancelStream(ctx kalpsdk.TransactionContextInterface, streamId string) error {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	err = checkNotPaused(ctx)
+	if err != nil {
+		return err
+	}
+
+	stream, err := readStream(ctx, streamId)
+	if err != nil {
+		return err
+	}
+	clientID, err := ctx.GetUserID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+	if clientID != stream.Sender && clientID != stream.Recipient {
+		return fmt.Errorf("only the sender or recipient of stream %s can cancel it", streamId)
+	}
+
+	balance, err := streamBalance(ctx, stream)
+	if err != nil {
+		return err
+	}
+
+	stream.Cancelled = true
+	stream.Withdrawn.Add(stream.Withdrawn, balance.Recipient)
+	err = writeStream(ctx, stream)
+	if err != nil {
+		return err
+	}
+	tos, amounts, legs := []string{}, []*big.Int{}, []events.Transfer{}
+	if balance.Recipient.Sign() > 0 {
+		tos, amounts = append(tos, stream.Recipient), append(amounts, balance.Recipient)
+	}
+	if balance.Sender.Sign() > 0 {
+		tos, amounts = append(tos, stream.Sender), append(amounts, balance.Sender)
+	}
+	if len(tos) > 0 {
+		err = moveBalances(ctx, streamEscrowAccount, tos, amounts)
+		if err != nil {
+			return err
+		}
+	}
+	for i, to := range tos {
+		legs = append(legs, events.Transfer{From: streamEscrowAccount, To: to, Value: amounts[i]})
+	}
+
+	streamEvent := StreamEvent{StreamId: streamId, Sender: stream.Sender, Recipient: stream.Recipient, Amount: balance.Recipient, Refund: balance.Sender, Transfers: legs}
+	return events.Emit(ctx, "StreamCancelled", &streamEvent)
+}
+
+func (c *TokenERC20Contract) GetStream(ctx kalpsdk.TransactionContextInterface, streamId string) (*Stream, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	return readStream(ctx, streamId)
+}
+
+// BalanceOfStream returns what the recipient can withdraw now and what would be refunded to the
+// sender if the stream were cancelled.
+func (c *TokenERC20Contract) BalanceOfStream(ctx kalpsdk.TransactionContextInterface, streamId string) (*StreamBalance, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	stream, err := readStream(ctx, streamId)
+	if err != nil {
+		return nil, err
+	}
+	return streamBalance(ctx, stream)
+}
+
+func streamBalance(ctx kalpsdk.TransactionContextInterface, stream *Stream) (*StreamBalance, error) {
+	if stream.Cancelled {
+		return nil, fmt.Errorf("stream %s has been cancelled", stream.StreamId)
+	}
+	now, err := txTimestampSeconds(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if now > stream.StopTime {
+		now = stream.StopTime
+	}
+	accrued := new(big.Int).Mul(stream.Deposit, big.NewInt(now-stream.StartTime))
+	accrued.Quo(accrued, big.NewInt(stream.StopTime-stream.StartTime))
+	recipient := new(big.Int).Sub(accrued, stream.Withdrawn)
+	sender := new(big.Int).Sub(stream.Deposit, accrued)
+	return &StreamBalance{stream.StreamId, recipient, sender}, nil
+}
+
+func readStream(ctx kalpsdk.TransactionContextInterface, streamId string) (*Stream, error) {
+	streamKey, err := ctx.CreateCompositeKey(streamPrefix, []string{streamId})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the composite key for prefix %s: %v", streamPrefix, err)
+	}
+	streamBytes, err := ctx.GetState(streamKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stream %s: %v", streamId, err)
+	}
+	if streamBytes == nil {
+		return nil, fmt.Errorf("stream %s does not exist", streamId)
+	}
+	stream := new(Stream)
+	err = json.Unmarshal(streamBytes, stream)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode stream %s: %v", streamId, err)
+	}
+	return stream, nil
+}
+
+func writeStream(ctx kalpsdk.TransactionContextInterface, stream *Stream) error {
+	streamKey, err := ctx.CreateCompositeKey(streamPrefix, []string{stream.StreamId})
+	if err != nil {
+		return fmt.Errorf("failed to create the composite key for prefix %s: %v", streamPrefix, err)
+	}
+	streamJSON, err := canonical.Marshal(stream)
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	return accesscontrol.PutState(ctx, streamKey, streamJSON)
+}
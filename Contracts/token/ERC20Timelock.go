@@ -0,0 +1,82 @@
+package token
+
+import (
+	"fmt"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+// SetTimelockDelay makes fee changes, role and ownership changes and config updates wait delay
+// seconds between ScheduleOperation and ExecuteOperation. Once enabled, the delay itself changes through a scheduled
+// accesscontrol.ActionSetTimelockDelay.
+func (c *TokenERC20Contract) SetTimelockDelay(ctx kalpsdk.TransactionContextInterface, delay int64) error {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return accesscontrol.SetTimelockDelay(ctx, delay)
+}
+
+func (c *TokenERC20Contract) GetTimelockDelay(ctx kalpsdk.TransactionContextInterface) (int64, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return 0, kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return accesscontrol.GetTimelockDelay(ctx)
+}
+
+// ScheduleOperation schedules action with args. See accesscontrol.Proposal for the arguments of
+// each action.
+func (c *TokenERC20Contract) ScheduleOperation(ctx kalpsdk.TransactionContextInterface, action string, args []string) (*accesscontrol.TimelockOperation, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return accesscontrol.ScheduleOperation(ctx, action, args)
+}
+
+// ExecuteOperation executes timelock operation id once its delay passed.
+func (c *TokenERC20Contract) ExecuteOperation(ctx kalpsdk.TransactionContextInterface, id string) (*accesscontrol.TimelockOperation, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return accesscontrol.ExecuteOperation(ctx, id, erc20Executor(ctx))
+}
+
+// CancelOperation cancels timelock operation id.
+func (c *TokenERC20Contract) CancelOperation(ctx kalpsdk.TransactionContextInterface, id string) (*accesscontrol.TimelockOperation, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return accesscontrol.CancelOperation(ctx, id)
+}
+
+func (c *TokenERC20Contract) GetOperation(ctx kalpsdk.TransactionContextInterface, id string) (*accesscontrol.TimelockOperation, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return accesscontrol.GetOperation(ctx, id)
+}
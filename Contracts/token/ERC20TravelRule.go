@@ -0,0 +1,379 @@
+package token
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+	"github.com/thekalpstudio/kush-go/canonical"
+	"github.com/thekalpstudio/kush-go/events"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+const travelRuleConfigKey = "travelRuleConfig"
+const travelRulePrefix = "travelRule"
+const travelRuleSaltPrefix = "travelRule~salt"
+
+// travelRuleTransientKey is the transient map entry carrying the originator/beneficiary payload,
+// so the personal data never appears in the transaction proposal stored on the ledger.
+const travelRuleTransientKey = "travelRule"
+
+// travelRuleSaltTransientKey is the transient map entry carrying the random salt keying the
+// public hash of the payload. Without it names and account numbers could be recovered from the
+// hash by trying likely values.
+const travelRuleSaltTransientKey = "travelRuleSalt"
+
+// minTravelRuleSalt is the minimum length of the salt, in bytes.
+const minTravelRuleSalt = 16
+
+// TravelRuleConfig enables the compliance mode. Transfers of Threshold or more must go through
+// TransferWithTravelRule or TransferFrom with travel rule information; their payloads are kept in
+// the Collection private data collection and can only be read by ComplianceMSPs.
+type TravelRuleConfig struct {
+	Enabled        bool     `json:"enabled"`
+	Threshold      *big.Int `json:"threshold"`
+	Collection     string   `json:"collection"`
+	ComplianceMSPs []string `json:"complianceMSPs"`
+}
+
+// TravelRuleParty identifies one side of a transfer.
+type TravelRuleParty struct {
+	Name        string `json:"name"`
+	Account     string `json:"account"`
+	Institution string `json:"institution"`
+}
+
+// TravelRuleInfo is the originator/beneficiary payload required above the threshold. Salt is the
+// hex encoded salt of the payload hash, set by GetTravelRuleInfo.
+type TravelRuleInfo struct {
+	Originator  TravelRuleParty `json:"originator"`
+	Beneficiary TravelRuleParty `json:"beneficiary"`
+	Salt        string          `json:"salt,omitempty" metadata:",optional"`
+}
+
+// TravelRuleRecord is the public anchor of a payload stored in the private data collection.
+// PayloadHash is the hex encoded HMAC-SHA256 of the payload keyed with its salt.
+type TravelRuleRecord struct {
+	TxId        string   `json:"txId"`
+	From        string   `json:"from"`
+	To          string   `json:"to"`
+	Value       *big.Int `json:"value"`
+	PayloadHash string   `json:"payloadHash"`
+}
+
+func (c *TokenERC20Contract) SetTravelRuleConfig(ctx kalpsdk.TransactionContextInterface, enabled bool, threshold string, collection string, complianceMSPs []string) error {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	err = checkAdmin(ctx)
+	if err != nil {
+		return err
+	}
+
+	// the threshold, collection and MSPs only matter while the mode is enabled
+	thresholdAmount := new(big.Int)
+	if enabled {
+		thresholdAmount, err = parseAmount(threshold)
+		if err != nil {
+			return err
+		}
+		if thresholdAmount.Sign() <= 0 {
+			return fmt.Errorf("travel rule threshold must be a positive integer")
+		}
+		if collection == "" {
+			return fmt.Errorf("travel rule private data collection must be set")
+		}
+		if len(complianceMSPs) == 0 {
+			return fmt.Errorf("at least one compliance MSP must be set")
+		}
+	}
+
+	config := TravelRuleConfig{enabled, thresholdAmount, collection, complianceMSPs}
+	configJSON, err := canonical.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	return accesscontrol.PutState(ctx, travelRuleConfigKey, configJSON)
+}
+
+func (c *TokenERC20Contract) GetTravelRuleConfig(ctx kalpsdk.TransactionContextInterface) (*TravelRuleConfig, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	return readTravelRuleConfig(ctx)
+}
+
+// TransferWithTravelRule transfers like Transfer and stores the TravelRuleInfo passed in the
+// "travelRule" transient field in the private data collection, anchoring its hash, keyed with the
+// salt passed in the "travelRuleSalt" transient field, publicly.
+func (c *TokenERC20Contract) TransferWithTravelRule(ctx kalpsdk.TransactionContextInterface, recipient string, amount string) (*TravelRuleRecord, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	err = checkNotPaused(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	clientID, err := ctx.GetUserID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	config, err := readTravelRuleConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !config.Enabled {
+		return nil, fmt.Errorf("travel rule compliance mode is not enabled")
+	}
+	travelRule, err := readTravelRuleData(ctx, config)
+	if err != nil {
+		return nil, err
+	}
+
+	transferAmount, err := parseAmount(amount)
+	if err != nil {
+		return nil, err
+	}
+
+	fee, err := transferHelper(ctx, clientID, recipient, transferAmount)
+	if err != nil {
+		return nil, fmt.Errorf("failed to transfer: %v", err)
+	}
+
+	record, err := travelRule.store(ctx, clientID, recipient, transferAmount)
+	if err != nil {
+		return nil, err
+	}
+
+	transferEvent := events.Transfer{From: clientID, To: recipient, Value: transferAmount, FeeCharged: fee}
+	err = events.Emit(ctx, "Transfer", &transferEvent)
+	if err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// travelRuleData is the travel rule information passed to a transfer in the transient map.
+type travelRuleData struct {
+	stub       shim.ChaincodeStubInterface
+	collection string
+	payload    []byte
+	salt       []byte
+}
+
+// readTravelRuleData reads and checks the travel rule information passed in the transient map.
+func readTravelRuleData(ctx kalpsdk.TransactionContextInterface, config *TravelRuleConfig) (*travelRuleData, error) {
+	stub, err := chaincodeStub(ctx)
+	if err != nil {
+		return nil, err
+	}
+	transient, err := stub.GetTransient()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transient data: %v", err)
+	}
+	payload, ok := transient[travelRuleTransientKey]
+	if !ok {
+		return nil, fmt.Errorf("travel rule information must be passed in the %q transient field", travelRuleTransientKey)
+	}
+	info := new(TravelRuleInfo)
+	err = json.Unmarshal(payload, info)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode travel rule information: %v", err)
+	}
+	if info.Originator.Name == "" || info.Beneficiary.Name == "" {
+		return nil, fmt.Errorf("travel rule information must name both originator and beneficiary")
+	}
+	salt := transient[travelRuleSaltTransientKey]
+	if len(salt) < minTravelRuleSalt {
+		return nil, fmt.Errorf("a random salt of at least %d bytes must be passed in the %q transient field", minTravelRuleSalt, travelRuleSaltTransientKey)
+	}
+	return &travelRuleData{stub, config.Collection, payload, salt}, nil
+}
+
+// store keeps the payload and salt of d in the private data collection and its public anchor
+// under the ID of the transaction, which moved value from from to to.
+func (d *travelRuleData) store(ctx kalpsdk.TransactionContextInterface, from string, to string, value *big.Int) (*TravelRuleRecord, error) {
+	recordKey, err := ctx.CreateCompositeKey(travelRulePrefix, []string{ctx.GetTxID()})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the composite key for prefix %s: %v", travelRulePrefix, err)
+	}
+	saltKey, err := ctx.CreateCompositeKey(travelRuleSaltPrefix, []string{ctx.GetTxID()})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the composite key for prefix %s: %v", travelRuleSaltPrefix, err)
+	}
+	err = d.stub.PutPrivateData(d.collection, recordKey, d.payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store travel rule information: %v", err)
+	}
+	err = d.stub.PutPrivateData(d.collection, saltKey, d.salt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to store travel rule salt: %v", err)
+	}
+
+	mac := hmac.New(sha256.New, d.salt)
+	mac.Write(d.payload)
+	record := &TravelRuleRecord{ctx.GetTxID(), from, to, value, hex.EncodeToString(mac.Sum(nil))}
+	recordJSON, err := canonical.Marshal(record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	err = accesscontrol.PutState(ctx, recordKey, recordJSON)
+	if err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// GetTravelRuleRecord returns the public anchor of the travel rule payload sent in txId.
+func (c *TokenERC20Contract) GetTravelRuleRecord(ctx kalpsdk.TransactionContextInterface, txId string) (*TravelRuleRecord, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	recordKey, err := ctx.CreateCompositeKey(travelRulePrefix, []string{txId})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the composite key for prefix %s: %v", travelRulePrefix, err)
+	}
+	recordBytes, err := ctx.GetState(recordKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read travel rule record %s: %v", txId, err)
+	}
+	if recordBytes == nil {
+		return nil, fmt.Errorf("no travel rule record exists for transaction %s", txId)
+	}
+	record := new(TravelRuleRecord)
+	err = json.Unmarshal(recordBytes, record)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode travel rule record: %v", err)
+	}
+	return record, nil
+}
+
+// GetTravelRuleInfo returns the private originator/beneficiary payload of txId. Only members of
+// the configured compliance MSPs may read it.
+func (c *TokenERC20Contract) GetTravelRuleInfo(ctx kalpsdk.TransactionContextInterface, txId string) (*TravelRuleInfo, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	config, err := readTravelRuleConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get MSPID: %v", err)
+	}
+	if !containsString(config.ComplianceMSPs, clientMSPID) {
+		return nil, kusherrors.Errorf(kusherrors.ErrUnauthorized, "client is not authorized to read travel rule information")
+	}
+
+	recordKey, err := ctx.CreateCompositeKey(travelRulePrefix, []string{txId})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the composite key for prefix %s: %v", travelRulePrefix, err)
+	}
+	stub, err := chaincodeStub(ctx)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := stub.GetPrivateData(config.Collection, recordKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read travel rule information: %v", err)
+	}
+	if payload == nil {
+		return nil, fmt.Errorf("no travel rule information exists for transaction %s", txId)
+	}
+	info := new(TravelRuleInfo)
+	err = json.Unmarshal(payload, info)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode travel rule information: %v", err)
+	}
+
+	saltKey, err := ctx.CreateCompositeKey(travelRuleSaltPrefix, []string{txId})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the composite key for prefix %s: %v", travelRuleSaltPrefix, err)
+	}
+	salt, err := stub.GetPrivateData(config.Collection, saltKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read travel rule salt: %v", err)
+	}
+	info.Salt = hex.EncodeToString(salt)
+	return info, nil
+}
+
+// travelRuleFor returns the travel rule information a transfer of value has to store, or nil if
+// the compliance mode does not require any.
+func travelRuleFor(ctx kalpsdk.TransactionContextInterface, value *big.Int) (*travelRuleData, error) {
+	config, err := readTravelRuleConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !config.Enabled || value.Cmp(config.Threshold) < 0 {
+		return nil, nil
+	}
+	travelRule, err := readTravelRuleData(ctx, config)
+	if err != nil {
+		return nil, fmt.Errorf("transfers of %s or more require travel rule information: %v", config.Threshold, err)
+	}
+	return travelRule, nil
+}
+
+// checkTravelRule rejects plain transfers that the compliance mode requires to carry a payload.
+func checkTravelRule(ctx kalpsdk.TransactionContextInterface, value *big.Int) error {
+	config, err := readTravelRuleConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if config.Enabled && value.Cmp(config.Threshold) >= 0 {
+		return fmt.Errorf("transfers of %s or more require travel rule information, use TransferWithTravelRule", config.Threshold)
+	}
+	return nil
+}
+
+func readTravelRuleConfig(ctx kalpsdk.TransactionContextInterface) (*TravelRuleConfig, error) {
+	configBytes, err := ctx.GetState(travelRuleConfigKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read travel rule config: %v", err)
+	}
+	config := new(TravelRuleConfig)
+	if configBytes == nil {
+		return config, nil
+	}
+	err = json.Unmarshal(configBytes, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode travel rule config: %v", err)
+	}
+	return config, nil
+}
@@ -0,0 +1,271 @@
+package token
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+	"github.com/thekalpstudio/kush-go/canonical"
+	"github.com/thekalpstudio/kush-go/events"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+const vestingPrefix = "vesting"
+
+// vestingEscrowAccount holds minted tokens until they are released to their beneficiaries.
+const vestingEscrowAccount = "0xvesting"
+
+// VestingSchedule releases Amount to Beneficiary linearly over Duration seconds from Start, in
+// steps of SlicePeriod seconds, with nothing releasable before Start+Cliff.
+type VestingSchedule struct {
+	VestingId   string   `json:"vestingId"`
+	Beneficiary string   `json:"beneficiary"`
+	Amount      *big.Int `json:"amount"`
+	Start       int64    `json:"start"`
+	Cliff       int64    `json:"cliff"`
+	Duration    int64    `json:"duration"`
+	SlicePeriod int64    `json:"slicePeriod"`
+	Released    *big.Int `json:"released"`
+}
+
+// VestingReleased MUST emit when vested tokens are released. It lists the transfer out of the
+// vesting escrow account.
+type VestingReleased = events.VestingReleased
+
+// CreateVestingSchedule mints amount into escrow and vests it to beneficiary starting now. cliff,
+// duration and slicePeriod are in seconds. The schedule id is the transaction id.
+func (c *TokenERC20Contract) CreateVestingSchedule(ctx kalpsdk.TransactionContextInterface, beneficiary string, amount string, cliff int64, duration int64, slicePeriod int64) (*VestingSchedule, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	err = checkNotPaused(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = accesscontrol.CheckOrgRole(ctx, accesscontrol.MinterRole)
+	if err != nil {
+		return nil, fmt.Errorf("client is not authorized to create vesting schedules: %v", err)
+	}
+
+	if beneficiary == "" || beneficiary == "0x0" {
+		return nil, fmt.Errorf("vesting beneficiary must be a valid account")
+	}
+	vestingAmount, err := parseAmount(amount)
+	if err != nil {
+		return nil, err
+	}
+	if vestingAmount.Sign() <= 0 {
+		return nil, fmt.Errorf("vesting amount must be a positive integer")
+	}
+	err = accesscontrol.CheckMintThreshold(ctx, vestingAmount)
+	if err != nil {
+		return nil, err
+	}
+	if duration <= 0 || slicePeriod <= 0 || slicePeriod > duration {
+		return nil, fmt.Errorf("vesting duration and slice period must be positive, with the slice period at most the duration")
+	}
+	if cliff < 0 || cliff > duration {
+		return nil, fmt.Errorf("vesting cliff must be between 0 and the duration")
+	}
+
+	timestamp, err := ctx.GetTxTimestamp()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+
+	schedule := &VestingSchedule{
+		VestingId:   ctx.GetTxID(),
+		Beneficiary: beneficiary,
+		Amount:      vestingAmount,
+		Start:       timestamp.GetSeconds(),
+		Cliff:       cliff,
+		Duration:    duration,
+		SlicePeriod: slicePeriod,
+		Released:    new(big.Int),
+	}
+	err = writeVestingSchedule(ctx, schedule)
+	if err != nil {
+		return nil, err
+	}
+
+	err = _mint(ctx, vestingEscrowAccount, vestingAmount)
+	if err != nil {
+		return nil, err
+	}
+	return schedule, nil
+}
+
+// Release transfers everything that has vested for the caller across all of their schedules.
+func (c *TokenERC20Contract) Release(ctx kalpsdk.TransactionContextInterface) (string, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return "", kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	err = checkNotPaused(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	beneficiary, err := ctx.GetUserID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get client id: %v", err)
+	}
+	schedules, err := readVestingSchedules(ctx, beneficiary)
+	if err != nil {
+		return "", err
+	}
+	now, err := txTimestampSeconds(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	total := new(big.Int)
+	for _, schedule := range schedules {
+		releasable := releasableAmount(schedule, now)
+		if releasable.Sign() == 0 {
+			continue
+		}
+		schedule.Released.Add(schedule.Released, releasable)
+		err = writeVestingSchedule(ctx, schedule)
+		if err != nil {
+			return "", err
+		}
+		total.Add(total, releasable)
+	}
+	if total.Sign() == 0 {
+		return "", fmt.Errorf("no vested tokens are due for release")
+	}
+
+	err = moveBalance(ctx, vestingEscrowAccount, beneficiary, total)
+	if err != nil {
+		return "", err
+	}
+
+	legs := []events.Transfer{{From: vestingEscrowAccount, To: beneficiary, Value: total}}
+	vestingReleased := VestingReleased{Beneficiary: beneficiary, Amount: total, Transfers: legs}
+	err = events.Emit(ctx, "VestingReleased", &vestingReleased)
+	if err != nil {
+		return "", err
+	}
+	return total.String(), nil
+}
+
+func (c *TokenERC20Contract) GetVestingSchedules(ctx kalpsdk.TransactionContextInterface, beneficiary string) ([]*VestingSchedule, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	return readVestingSchedules(ctx, beneficiary)
+}
+
+// VestedAmount returns how much of beneficiary's schedules has vested so far, released or not.
+func (c *TokenERC20Contract) VestedAmount(ctx kalpsdk.TransactionContextInterface, beneficiary string) (string, error) {
+	return sumVesting(ctx, beneficiary, vestedAmount)
+}
+
+// ReleasableAmount returns how much Release would currently pay out to beneficiary.
+func (c *TokenERC20Contract) ReleasableAmount(ctx kalpsdk.TransactionContextInterface, beneficiary string) (string, error) {
+	return sumVesting(ctx, beneficiary, releasableAmount)
+}
+
+func sumVesting(ctx kalpsdk.TransactionContextInterface, beneficiary string, amountOf func(*VestingSchedule, int64) *big.Int) (string, error) {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return "", kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	schedules, err := readVestingSchedules(ctx, beneficiary)
+	if err != nil {
+		return "", err
+	}
+	now, err := txTimestampSeconds(ctx)
+	if err != nil {
+		return "", err
+	}
+	total := new(big.Int)
+	for _, schedule := range schedules {
+		total.Add(total, amountOf(schedule, now))
+	}
+	return total.String(), nil
+}
+
+// vestedAmount returns the part of schedule vested at now, counting whole slice periods only.
+func vestedAmount(schedule *VestingSchedule, now int64) *big.Int {
+	elapsed := now - schedule.Start
+	if elapsed < schedule.Cliff {
+		return new(big.Int)
+	}
+	if elapsed >= schedule.Duration {
+		return new(big.Int).Set(schedule.Amount)
+	}
+	vestedSeconds := elapsed / schedule.SlicePeriod * schedule.SlicePeriod
+	vested := new(big.Int).Mul(schedule.Amount, big.NewInt(vestedSeconds))
+	return vested.Quo(vested, big.NewInt(schedule.Duration))
+}
+
+func releasableAmount(schedule *VestingSchedule, now int64) *big.Int {
+	vested := vestedAmount(schedule, now)
+	return vested.Sub(vested, schedule.Released)
+}
+
+func txTimestampSeconds(ctx kalpsdk.TransactionContextInterface) (int64, error) {
+	timestamp, err := ctx.GetTxTimestamp()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	return timestamp.GetSeconds(), nil
+}
+
+func readVestingSchedules(ctx kalpsdk.TransactionContextInterface, beneficiary string) ([]*VestingSchedule, error) {
+	iterator, err := ctx.GetStateByPartialCompositeKey(vestingPrefix, []string{beneficiary})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get state for prefix %v: %v", vestingPrefix, err)
+	}
+	defer iterator.Close()
+
+	schedules := []*VestingSchedule{}
+	for iterator.HasNext() {
+		queryResponse, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the next state for prefix %v: %v", vestingPrefix, err)
+		}
+		schedule := new(VestingSchedule)
+		err = json.Unmarshal(queryResponse.Value, schedule)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode vesting schedule: %v", err)
+		}
+		schedules = append(schedules, schedule)
+	}
+	return schedules, nil
+}
+
+func writeVestingSchedule(ctx kalpsdk.TransactionContextInterface, schedule *VestingSchedule) error {
+	vestingKey, err := ctx.CreateCompositeKey(vestingPrefix, []string{schedule.Beneficiary, schedule.VestingId})
+	if err != nil {
+		return fmt.Errorf("failed to create the composite key for prefix %s: %v", vestingPrefix, err)
+	}
+	scheduleJSON, err := canonical.Marshal(schedule)
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	return accesscontrol.PutState(ctx, vestingKey, scheduleJSON)
+}
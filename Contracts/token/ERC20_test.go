@@ -0,0 +1,977 @@
+package token
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/thekalpstudio/kush-go/config"
+	"github.com/thekalpstudio/kush-go/events"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+	"github.com/thekalpstudio/kush-go/testutil"
+)
+
+// newERC20 returns an initialized token whose admin holds 1000 base units.
+func newERC20(t *testing.T) (*testutil.Ledger, *TokenERC20Contract) {
+	t.Helper()
+	l := testutil.NewLedger()
+	c := new(TokenERC20Contract)
+	testutil.MustRun(t, l, admin, "Initialize", func(ctx *testutil.Context) error {
+		_, err := c.Initialize(ctx, testConfig())
+		return err
+	})
+	testutil.MustRun(t, l, admin, "Mint", func(ctx *testutil.Context) error {
+		return c.Mint(ctx, "1000")
+	})
+	return l, c
+}
+
+func erc20Balance(t *testing.T, l *testutil.Ledger, c *TokenERC20Contract, account string) string {
+	t.Helper()
+	balance, err := c.BalanceOf(l.Tx(admin, "BalanceOf"), account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return balance
+}
+
+func TestERC20Initialize(t *testing.T) {
+	tests := []struct {
+		name    string
+		init    func(cfg *config.InitConfig)
+		twice   bool
+		wantErr bool
+	}{
+		{name: "valid", init: func(cfg *config.InitConfig) {}},
+		{name: "with decimals", init: func(cfg *config.InitConfig) { cfg.Decimals = 18 }},
+		{name: "already initialized", init: func(cfg *config.InitConfig) {}, twice: true, wantErr: true},
+		{name: "no name", init: func(cfg *config.InitConfig) { cfg.Name = "" }, wantErr: true},
+		{name: "no authorized org", init: func(cfg *config.InitConfig) { cfg.AuthorizedOrgs = nil }, wantErr: true},
+		{name: "too many decimals", init: func(cfg *config.InitConfig) { cfg.Decimals = maxDecimals + 1 }, wantErr: true},
+		{name: "invalid max supply", init: func(cfg *config.InitConfig) { cfg.MaxSupply = "-1" }, wantErr: true},
+		{name: "caller outside authorized orgs", init: func(cfg *config.InitConfig) { cfg.AuthorizedOrgs = []string{"Org2MSP"} }, wantErr: true},
+		{name: "KYC required of a caller without KYC", init: func(cfg *config.InitConfig) { cfg.Features.RequireKYC = true }, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := testutil.NewLedger()
+			c := new(TokenERC20Contract)
+			cfg := testConfig()
+			tt.init(&cfg)
+			initialize := func(ctx *testutil.Context) error {
+				_, err := c.Initialize(ctx, cfg)
+				return err
+			}
+			if tt.twice {
+				testutil.MustRun(t, l, admin, "Initialize", initialize)
+			}
+			err := testutil.Run(l, admin, "Initialize", initialize)
+			testutil.CheckErr(t, err, tt.wantErr, "")
+			if tt.wantErr {
+				return
+			}
+
+			stored, err := c.GetConfig(l.Tx(admin, "GetConfig"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored.Name != cfg.Name || stored.Symbol != cfg.Symbol || stored.Decimals != cfg.Decimals {
+				t.Fatalf("stored config is %+v, want %+v", stored, cfg)
+			}
+		})
+	}
+}
+
+func TestERC20NotInitialized(t *testing.T) {
+	l := testutil.NewLedger()
+	c := new(TokenERC20Contract)
+	calls := map[string]func(ctx *testutil.Context) error{
+		"Mint":     func(ctx *testutil.Context) error { return c.Mint(ctx, "1") },
+		"Transfer": func(ctx *testutil.Context) error { return c.Transfer(ctx, "bob", "1") },
+		"Burn":     func(ctx *testutil.Context) error { return c.Burn(ctx, "1") },
+		"Approve":  func(ctx *testutil.Context) error { return c.Approve(ctx, "bob", "1") },
+		"BalanceOf": func(ctx *testutil.Context) error {
+			_, err := c.BalanceOf(ctx, "bob")
+			return err
+		},
+	}
+	for function, call := range calls {
+		t.Run(function, func(t *testing.T) {
+			err := testutil.Run(l, admin, function, call)
+			testutil.CheckErr(t, err, true, kusherrors.ErrNotInitialized)
+		})
+	}
+}
+
+func TestERC20Mint(t *testing.T) {
+	tests := []struct {
+		name     string
+		user     string
+		amount   string
+		wantErr  bool
+		wantCode kusherrors.Code
+	}{
+		{name: "minter", user: admin, amount: "500"},
+		{name: "without minter role", user: "bob", amount: "500", wantErr: true, wantCode: kusherrors.ErrUnauthorized},
+		{name: "zero", user: admin, amount: "0", wantErr: true},
+		{name: "negative", user: admin, amount: "-5", wantErr: true},
+		{name: "fractional", user: admin, amount: "1.5", wantErr: true},
+		{name: "not a number", user: admin, amount: "many", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, c := newERC20(t)
+			err := testutil.Run(l, tt.user, "Mint", func(ctx *testutil.Context) error {
+				return c.Mint(ctx, tt.amount)
+			})
+			testutil.CheckErr(t, err, tt.wantErr, tt.wantCode)
+			if tt.wantErr {
+				if balance := erc20Balance(t, l, c, admin); balance != "1000" {
+					t.Fatalf("failed mint changed the balance to %s", balance)
+				}
+				return
+			}
+
+			if balance := erc20Balance(t, l, c, admin); balance != "1500" {
+				t.Fatalf("balance is %s, want 1500", balance)
+			}
+			supply, err := c.TotalSupply(l.Tx(admin, "TotalSupply"))
+			if err != nil || supply != "1500" {
+				t.Fatalf("total supply is %s: %v", supply, err)
+			}
+			transfer := testutil.LastEvent(t, l, "Transfer").(*events.Transfer)
+			if transfer.From != "0x0" || transfer.To != admin || transfer.Value.String() != tt.amount {
+				t.Fatalf("event is %+v", transfer)
+			}
+		})
+	}
+}
+
+func TestERC20MaxSupply(t *testing.T) {
+	l := testutil.NewLedger()
+	c := new(TokenERC20Contract)
+	cfg := testConfig()
+	cfg.MaxSupply = "1000"
+	testutil.MustRun(t, l, admin, "Initialize", func(ctx *testutil.Context) error {
+		_, err := c.Initialize(ctx, cfg)
+		return err
+	})
+	testutil.MustRun(t, l, admin, "Mint", func(ctx *testutil.Context) error {
+		return c.Mint(ctx, "1000")
+	})
+	err := testutil.Run(l, admin, "Mint", func(ctx *testutil.Context) error {
+		return c.Mint(ctx, "1")
+	})
+	testutil.CheckErr(t, err, true, "")
+}
+
+func TestERC20Transfer(t *testing.T) {
+	tests := []struct {
+		name        string
+		recipient   string
+		amount      string
+		wantErr     bool
+		wantCode    kusherrors.Code
+		wantSender  string
+		wantReceive string
+	}{
+		{name: "part of the balance", recipient: "bob", amount: "300", wantSender: "700", wantReceive: "300"},
+		{name: "whole balance", recipient: "bob", amount: "1000", wantSender: "0", wantReceive: "1000"},
+		{name: "zero", recipient: "bob", amount: "0", wantErr: true},
+		{name: "insufficient funds", recipient: "bob", amount: "1001", wantErr: true, wantCode: kusherrors.ErrInsufficientBalance},
+		{name: "to self", recipient: admin, amount: "1", wantErr: true},
+		{name: "negative", recipient: "bob", amount: "-1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, c := newERC20(t)
+			err := testutil.Run(l, admin, "Transfer", func(ctx *testutil.Context) error {
+				return c.Transfer(ctx, tt.recipient, tt.amount)
+			})
+			testutil.CheckErr(t, err, tt.wantErr, tt.wantCode)
+			if tt.wantErr {
+				return
+			}
+
+			if balance := erc20Balance(t, l, c, admin); balance != tt.wantSender {
+				t.Fatalf("sender balance is %s, want %s", balance, tt.wantSender)
+			}
+			if balance := erc20Balance(t, l, c, tt.recipient); balance != tt.wantReceive {
+				t.Fatalf("recipient balance is %s, want %s", balance, tt.wantReceive)
+			}
+			transfer := testutil.LastEvent(t, l, "Transfer").(*events.Transfer)
+			if transfer.From != admin || transfer.To != tt.recipient || transfer.Value.String() != tt.amount {
+				t.Fatalf("event is %+v", transfer)
+			}
+		})
+	}
+}
+
+func TestERC20TransferFromAccountWithoutBalance(t *testing.T) {
+	l, c := newERC20(t)
+	err := testutil.Run(l, "bob", "Transfer", func(ctx *testutil.Context) error {
+		return c.Transfer(ctx, admin, "1")
+	})
+	testutil.CheckErr(t, err, true, "")
+}
+
+func TestERC20Burn(t *testing.T) {
+	tests := []struct {
+		name       string
+		user       string
+		amount     string
+		wantErr    bool
+		wantCode   kusherrors.Code
+		wantSupply string
+	}{
+		{name: "part of the balance", user: admin, amount: "400", wantSupply: "600"},
+		{name: "whole balance", user: admin, amount: "1000", wantSupply: "0"},
+		{name: "more than the balance", user: admin, amount: "1001", wantErr: true},
+		{name: "zero", user: admin, amount: "0", wantErr: true},
+		{name: "without burner role", user: "bob", amount: "1", wantErr: true, wantCode: kusherrors.ErrUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, c := newERC20(t)
+			err := testutil.Run(l, tt.user, "Burn", func(ctx *testutil.Context) error {
+				return c.Burn(ctx, tt.amount)
+			})
+			testutil.CheckErr(t, err, tt.wantErr, tt.wantCode)
+			if tt.wantErr {
+				return
+			}
+
+			supply, err := c.TotalSupply(l.Tx(admin, "TotalSupply"))
+			if err != nil || supply != tt.wantSupply {
+				t.Fatalf("total supply is %s: %v, want %s", supply, err, tt.wantSupply)
+			}
+			if balance := erc20Balance(t, l, c, admin); balance != tt.wantSupply {
+				t.Fatalf("balance is %s, want %s", balance, tt.wantSupply)
+			}
+			transfer := testutil.LastEvent(t, l, "Transfer").(*events.Transfer)
+			if transfer.From != admin || transfer.To != "0x0" || transfer.Value.String() != tt.amount {
+				t.Fatalf("event is %+v", transfer)
+			}
+		})
+	}
+}
+
+func TestERC20Allowance(t *testing.T) {
+	tests := []struct {
+		name          string
+		approve       string
+		spend         string
+		wantErr       bool
+		wantAllowance string
+	}{
+		{name: "within allowance", approve: "500", spend: "200", wantAllowance: "300"},
+		{name: "whole allowance", approve: "500", spend: "500", wantAllowance: "0"},
+		{name: "above allowance", approve: "500", spend: "501", wantErr: true, wantAllowance: "500"},
+		{name: "above balance", approve: "5000", spend: "1001", wantErr: true, wantAllowance: "5000"},
+		{name: "without allowance", approve: "", spend: "1", wantErr: true, wantAllowance: "0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, c := newERC20(t)
+			if tt.approve != "" {
+				testutil.MustRun(t, l, admin, "Approve", func(ctx *testutil.Context) error {
+					return c.Approve(ctx, "bob", tt.approve)
+				})
+				approval := testutil.LastEvent(t, l, "Approval").(*events.Approval)
+				if approval.From != admin || approval.To != "bob" || approval.Value.String() != tt.approve {
+					t.Fatalf("event is %+v", approval)
+				}
+			}
+
+			err := testutil.Run(l, "bob", "TransferFrom", func(ctx *testutil.Context) error {
+				return c.TransferFrom(ctx, admin, "carol", tt.spend)
+			})
+			testutil.CheckErr(t, err, tt.wantErr, "")
+
+			allowance, err := c.Allowance(l.Tx(admin, "Allowance"), admin, "bob")
+			if err != nil || allowance != tt.wantAllowance {
+				t.Fatalf("allowance is %s: %v, want %s", allowance, err, tt.wantAllowance)
+			}
+			if tt.wantErr {
+				return
+			}
+			if balance := erc20Balance(t, l, c, "carol"); balance != tt.spend {
+				t.Fatalf("recipient balance is %s, want %s", balance, tt.spend)
+			}
+			transfer := testutil.LastEvent(t, l, "Transfer").(*events.Transfer)
+			if transfer.From != admin || transfer.To != "carol" || transfer.Value.String() != tt.spend {
+				t.Fatalf("event is %+v", transfer)
+			}
+		})
+	}
+}
+
+func TestERC20MintThresholdWindow(t *testing.T) {
+	l, c := newERC20(t)
+	testutil.MustRun(t, l, admin, "SetMultisig", func(ctx *testutil.Context) error {
+		return c.SetMultisig(ctx, []string{admin}, 1, "100", 10)
+	})
+	mint := func(amount string) func(ctx *testutil.Context) error {
+		return func(ctx *testutil.Context) error {
+			return c.Mint(ctx, amount)
+		}
+	}
+	testutil.MustRun(t, l, admin, "Mint", mint("60"))
+	// the mints of a window add up, so the threshold cannot be split across calls
+	testutil.CheckErr(t, testutil.Run(l, admin, "Mint", mint("50")), true, "")
+	testutil.MustRun(t, l, admin, "Mint", mint("40"))
+	for i := 0; i < 10; i++ {
+		l.Tx(admin, "BalanceOf")
+	}
+	testutil.MustRun(t, l, admin, "Mint", mint("50"))
+	if balance := erc20Balance(t, l, c, admin); balance != "1150" {
+		t.Fatalf("balance is %s, want 1150", balance)
+	}
+}
+
+func TestERC20RefreshTopHolders(t *testing.T) {
+	l, c := newERC20(t)
+	for account, amount := range map[string]string{"bob": "300", "carol": "100", "dave": "300"} {
+		account, amount := account, amount
+		testutil.MustRun(t, l, admin, "Transfer", func(ctx *testutil.Context) error {
+			return c.Transfer(ctx, account, amount)
+		})
+	}
+
+	refreshes := 0
+	for done := false; !done; refreshes++ {
+		testutil.MustRun(t, l, admin, "RefreshTopHolders", func(ctx *testutil.Context) error {
+			top, err := c.RefreshTopHolders(ctx, 1)
+			if err == nil {
+				done = top.Bookmark == ""
+			}
+			return err
+		})
+		if !done {
+			if top, err := c.GetTopHoldersSnapshot(l.Tx(admin, "GetTopHoldersSnapshot")); err != nil || top != nil {
+				t.Fatalf("snapshot %+v is published before the refresh completed: %v", top, err)
+			}
+		}
+	}
+	if refreshes != 4 {
+		t.Fatalf("refreshed in %d pages, want 4", refreshes)
+	}
+
+	top, err := c.GetTopHoldersSnapshot(l.Tx(admin, "GetTopHoldersSnapshot"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []HolderBalance{{"admin", "300"}, {"bob", "300"}, {"dave", "300"}, {"carol", "100"}}
+	if len(top.Holders) != len(want) {
+		t.Fatalf("snapshot has %d holders, want %d", len(top.Holders), len(want))
+	}
+	for i, holder := range top.Holders {
+		if *holder != want[i] {
+			t.Fatalf("holder %d is %+v, want %+v", i, holder, want[i])
+		}
+	}
+}
+
+func TestERC20TravelRuleTransferFrom(t *testing.T) {
+	l, c := newERC20(t)
+	setConfig := func(enabled bool, threshold string) error {
+		return testutil.Run(l, admin, "SetTravelRuleConfig", func(ctx *testutil.Context) error {
+			return c.SetTravelRuleConfig(ctx, enabled, threshold, "travelRule", []string{"org1MSP"})
+		})
+	}
+	testutil.CheckErr(t, setConfig(true, "0"), true, "")
+	testutil.CheckErr(t, setConfig(true, "100"), false, "")
+	testutil.MustRun(t, l, admin, "Approve", func(ctx *testutil.Context) error {
+		return c.Approve(ctx, "bob", "500")
+	})
+	transferFrom := func(value string) error {
+		return testutil.Run(l, "bob", "TransferFrom", func(ctx *testutil.Context) error {
+			return c.TransferFrom(ctx, admin, "carol", value)
+		})
+	}
+	testutil.CheckErr(t, transferFrom("99"), false, "")
+	// the ledger of the tests has no chaincode stub, so it cannot pass travel rule information
+	err := transferFrom("100")
+	if err == nil || !strings.Contains(err.Error(), "require travel rule information") {
+		t.Fatalf("transfer above the threshold failed with %v", err)
+	}
+
+	// disabling ignores the threshold
+	testutil.CheckErr(t, setConfig(false, ""), false, "")
+	testutil.CheckErr(t, transferFrom("100"), false, "")
+}
+
+func TestERC20AirdropTransfer(t *testing.T) {
+	l, c := newERC20(t)
+	testutil.MustRun(t, l, admin, "SetTransferFee", func(ctx *testutil.Context) error {
+		return c.SetTransferFee(ctx, 100, "fees")
+	})
+	testutil.MustRun(t, l, admin, "SetTravelRuleConfig", func(ctx *testutil.Context) error {
+		return c.SetTravelRuleConfig(ctx, true, "300", "travelRule", []string{"org1MSP"})
+	})
+
+	var report *AirdropReport
+	testutil.MustRun(t, l, admin, "Airdrop", func(ctx *testutil.Context) error {
+		var err error
+		report, err = c.Airdrop(ctx, []string{"bob", "carol", "bob", "dave"}, []string{"100", "200", "100", "300"}, false)
+		return err
+	})
+	if report.Succeeded != 3 || len(report.Failures) != 1 || report.Failures[0].Account != "dave" {
+		t.Fatalf("report is %+v", report)
+	}
+	for account, want := range map[string]string{admin: "600", "bob": "198", "carol": "198", "fees": "4"} {
+		if balance := erc20Balance(t, l, c, account); balance != want {
+			t.Fatalf("balance of %s is %s, want %s", account, balance, want)
+		}
+	}
+	airdrop := testutil.LastEvent(t, l, "Airdrop").(*events.Airdrop)
+	if len(airdrop.Transfers) != 3 || airdrop.Transfers[1].FeeCharged == nil || airdrop.Transfers[1].FeeCharged.Amount.String() != "2" {
+		t.Fatalf("event is %+v", airdrop)
+	}
+}
+
+func TestERC20EmissionBacklog(t *testing.T) {
+	l, c := newERC20(t)
+	endTime := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC).Unix()
+	setSchedule := func() error {
+		return testutil.Run(l, admin, "SetEmissionSchedule", func(ctx *testutil.Context) error {
+			return c.SetEmissionSchedule(ctx, "1", 1, 0, endTime, "treasury")
+		})
+	}
+	testutil.CheckErr(t, setSchedule(), false, "")
+	l.Advance(2 * maxEmissionPeriods * time.Second)
+
+	// replacing the schedule would drop the periods a single settlement cannot mint
+	testutil.CheckErr(t, setSchedule(), true, "")
+	for i := 0; i < 2; i++ {
+		testutil.MustRun(t, l, "bob", "ExecuteEmission", func(ctx *testutil.Context) error {
+			_, err := c.ExecuteEmission(ctx)
+			return err
+		})
+	}
+	testutil.CheckErr(t, setSchedule(), false, "")
+	if balance := erc20Balance(t, l, c, "treasury"); balance != "2004" {
+		t.Fatalf("balance of treasury is %s, want every matured period", balance)
+	}
+}
+
+func TestERC20ToBaseUnits(t *testing.T) {
+	l := testutil.NewLedger()
+	c := new(TokenERC20Contract)
+	testutil.MustRun(t, l, admin, "Initialize", func(ctx *testutil.Context) error {
+		cfg := testConfig()
+		cfg.Decimals = 2
+		_, err := c.Initialize(ctx, cfg)
+		return err
+	})
+	ctx := l.Tx(admin, "ToBaseUnits")
+	for amount, want := range map[string]string{
+		"1":     "100",
+		"1.5":   "150",
+		".05":   "5",
+		"0":     "0",
+		"":      "",
+		".":     "",
+		"1.":    "",
+		"-1":    "",
+		"+1":    "",
+		"1e3":   "",
+		"1.2.3": "",
+		"0.001": "",
+	} {
+		value, err := c.ToBaseUnits(ctx, amount)
+		if want == "" && err == nil || want != "" && value != want {
+			t.Fatalf("ToBaseUnits(%q) = %q, %v, want %q", amount, value, err, want)
+		}
+	}
+}
+
+func TestERC20BatchTransferRecipients(t *testing.T) {
+	tests := []struct {
+		name       string
+		recipients []string
+		wantCode   kusherrors.Code
+	}{
+		{name: "empty recipient", recipients: []string{"bob", ""}, wantCode: kusherrors.ErrInvalidAccount},
+		{name: "zero address", recipients: []string{"0x0"}, wantCode: kusherrors.ErrInvalidAccount},
+		{name: "caller", recipients: []string{admin}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, c := newERC20(t)
+			amounts := make([]string, len(tt.recipients))
+			for i := range amounts {
+				amounts[i] = "10"
+			}
+			err := testutil.Run(l, admin, "BatchTransfer", func(ctx *testutil.Context) error {
+				return c.BatchTransfer(ctx, tt.recipients, amounts)
+			})
+			testutil.CheckErr(t, err, true, tt.wantCode)
+		})
+	}
+}
+
+func TestERC20VestingRelease(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   string
+		cliff    int64
+		duration int64
+		slice    int64
+		// Release is called after each wait; want is what it releases, "" when nothing is due.
+		waits []time.Duration
+		want  []string
+	}{
+		{name: "before the cliff", amount: "1000", cliff: 10, duration: 100, slice: 30, waits: []time.Duration{5 * time.Second}, want: []string{""}},
+		{name: "before the first slice", amount: "1000", cliff: 10, duration: 100, slice: 30, waits: []time.Duration{20 * time.Second}, want: []string{""}},
+		{name: "whole slices only", amount: "1000", cliff: 10, duration: 100, slice: 30, waits: []time.Duration{58 * time.Second}, want: []string{"300"}},
+		{name: "slice rounding", amount: "100", duration: 90, slice: 30, waits: []time.Duration{30 * time.Second, 29 * time.Second, 30 * time.Second}, want: []string{"33", "33", "34"}},
+		{name: "double release", amount: "1000", cliff: 10, duration: 100, slice: 30, waits: []time.Duration{40 * time.Second, 0}, want: []string{"300", ""}},
+		{name: "after the duration", amount: "1000", cliff: 10, duration: 100, slice: 30, waits: []time.Duration{99 * time.Second}, want: []string{"1000"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, c := newERC20(t)
+			testutil.MustRun(t, l, admin, "CreateVestingSchedule", func(ctx *testutil.Context) error {
+				_, err := c.CreateVestingSchedule(ctx, "bob", tt.amount, tt.cliff, tt.duration, tt.slice)
+				return err
+			})
+			mint := testutil.LastEvent(t, l, "Transfer").(*events.Transfer)
+			if mint.From != "0x0" || mint.To != vestingEscrowAccount || mint.Value.String() != tt.amount {
+				t.Fatalf("event is %+v", mint)
+			}
+
+			released := new(big.Int)
+			for i, wait := range tt.waits {
+				l.Advance(wait)
+				var amount string
+				err := testutil.Run(l, "bob", "Release", func(ctx *testutil.Context) error {
+					var err error
+					amount, err = c.Release(ctx)
+					return err
+				})
+				testutil.CheckErr(t, err, tt.want[i] == "", "")
+				if tt.want[i] == "" {
+					continue
+				}
+				if amount != tt.want[i] {
+					t.Fatalf("release %d is %s, want %s", i, amount, tt.want[i])
+				}
+				event := testutil.LastEvent(t, l, "VestingReleased").(*events.VestingReleased)
+				if event.Beneficiary != "bob" || event.Amount.String() != amount || len(event.Transfers) != 1 {
+					t.Fatalf("event is %+v", event)
+				}
+				if leg := event.Transfers[0]; leg.From != vestingEscrowAccount || leg.To != "bob" || leg.Value.String() != amount {
+					t.Fatalf("transfer is %+v", leg)
+				}
+				value, _ := new(big.Int).SetString(amount, 10)
+				released.Add(released, value)
+			}
+
+			if released.Sign() > 0 {
+				if balance := erc20Balance(t, l, c, "bob"); balance != released.String() {
+					t.Fatalf("balance of bob is %s, want %s", balance, released)
+				}
+			}
+			total, _ := new(big.Int).SetString(tt.amount, 10)
+			if balance := erc20Balance(t, l, c, vestingEscrowAccount); balance != total.Sub(total, released).String() {
+				t.Fatalf("escrow balance is %s, want %s", balance, total)
+			}
+		})
+	}
+}
+
+func TestERC20Stream(t *testing.T) {
+	tests := []struct {
+		name        string
+		fee         bool
+		travelRule  bool
+		wantDeposit string
+		// wait is the time between creating the stream and withdrawing from or cancelling it.
+		wait         time.Duration
+		withdraw     string
+		cancel       bool
+		wantErr      bool
+		wantBalances map[string]string
+		wantLegs     map[string]string
+	}{
+		{name: "withdraw accrued", wantDeposit: "500", wait: 19 * time.Second, withdraw: "100", wantBalances: map[string]string{admin: "500", "bob": "100", streamEscrowAccount: "400"}, wantLegs: map[string]string{"bob": "100"}},
+		{name: "withdraw more than accrued", wantDeposit: "500", wait: 19 * time.Second, withdraw: "105", wantErr: true},
+		{name: "cancel pro rata", wantDeposit: "500", wait: 29 * time.Second, cancel: true, wantBalances: map[string]string{admin: "850", "bob": "150", streamEscrowAccount: "0"}, wantLegs: map[string]string{"bob": "150", admin: "350"}},
+		{name: "cancel after the stop time", wantDeposit: "500", wait: 200 * time.Second, cancel: true, wantBalances: map[string]string{admin: "500", "bob": "500", streamEscrowAccount: "0"}, wantLegs: map[string]string{"bob": "500"}},
+		{name: "deposit pays the transfer fee", fee: true, wantDeposit: "495", wait: 19 * time.Second, withdraw: "99", wantBalances: map[string]string{admin: "500", "bob": "99", "fees": "5", streamEscrowAccount: "396"}, wantLegs: map[string]string{"bob": "99"}},
+		{name: "deposit over the travel rule threshold", travelRule: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, c := newERC20(t)
+			if tt.fee {
+				testutil.MustRun(t, l, admin, "SetTransferFee", func(ctx *testutil.Context) error {
+					return c.SetTransferFee(ctx, 100, "fees")
+				})
+			}
+			if tt.travelRule {
+				testutil.MustRun(t, l, admin, "SetTravelRuleConfig", func(ctx *testutil.Context) error {
+					return c.SetTravelRuleConfig(ctx, true, "300", "travelRule", []string{"org1MSP"})
+				})
+			}
+
+			var stream *Stream
+			err := testutil.Run(l, admin, "CreateStream", func(ctx *testutil.Context) error {
+				now, err := txTimestampSeconds(ctx)
+				if err != nil {
+					return err
+				}
+				stream, err = c.CreateStream(ctx, "bob", "5", now+100)
+				return err
+			})
+			testutil.CheckErr(t, err, tt.wantDeposit == "", "")
+			if tt.wantDeposit == "" {
+				return
+			}
+			created := testutil.LastEvent(t, l, "StreamCreated").(*events.StreamEvent)
+			if created.Amount.String() != tt.wantDeposit || len(created.Transfers) != 1 {
+				t.Fatalf("event is %+v", created)
+			}
+			if leg := created.Transfers[0]; leg.From != admin || leg.To != streamEscrowAccount || leg.Value.String() != "500" || tt.fee != (leg.FeeCharged != nil) {
+				t.Fatalf("transfer is %+v", leg)
+			}
+
+			l.Advance(tt.wait)
+			function, name := "WithdrawFromStream", "StreamWithdrawn"
+			if tt.cancel {
+				function, name = "CancelStream", "StreamCancelled"
+			}
+			err = testutil.Run(l, "bob", function, func(ctx *testutil.Context) error {
+				if tt.cancel {
+					return c.CancelStream(ctx, stream.StreamId)
+				}
+				return c.WithdrawFromStream(ctx, stream.StreamId, tt.withdraw)
+			})
+			testutil.CheckErr(t, err, tt.wantErr, "")
+			if tt.wantErr {
+				return
+			}
+
+			event := testutil.LastEvent(t, l, name).(*events.StreamEvent)
+			if len(event.Transfers) != len(tt.wantLegs) {
+				t.Fatalf("event is %+v", event)
+			}
+			for _, leg := range event.Transfers {
+				if leg.From != streamEscrowAccount || leg.Value.String() != tt.wantLegs[leg.To] {
+					t.Fatalf("transfer is %+v", leg)
+				}
+			}
+			for account, want := range tt.wantBalances {
+				if balance := erc20Balance(t, l, c, account); balance != want {
+					t.Fatalf("balance of %s is %s, want %s", account, balance, want)
+				}
+			}
+		})
+	}
+}
+
+func TestERC20Permit(t *testing.T) {
+	tests := []struct {
+		name      string
+		register  bool
+		signer    string
+		validFor  int64
+		replay    bool
+		wantErr   bool
+		wantNonce uint64
+	}{
+		{name: "signed by the owner", register: true, signer: admin, validFor: 3600, wantNonce: 1},
+		{name: "signed by another user", register: true, signer: "bob", validFor: 3600, wantErr: true},
+		{name: "expired", register: true, signer: admin, validFor: 0, wantErr: true},
+		{name: "replayed", register: true, signer: admin, validFor: 3600, replay: true, wantErr: true, wantNonce: 1},
+		{name: "without a permit key", signer: admin, validFor: 3600, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, c := newERC20(t)
+			if tt.register {
+				testutil.MustRun(t, l, admin, "RegisterPermitKey", func(ctx *testutil.Context) error {
+					return c.RegisterPermitKey(ctx)
+				})
+			}
+
+			ctx := l.Tx("relayer", "PermitDigest")
+			timestamp, err := ctx.GetTxTimestamp()
+			if err != nil {
+				t.Fatal(err)
+			}
+			deadline := timestamp.GetSeconds() + tt.validFor
+			digest, err := c.PermitDigest(ctx, admin, "bob", "300", deadline)
+			if err != nil {
+				t.Fatal(err)
+			}
+			digestBytes, err := hex.DecodeString(digest)
+			if err != nil {
+				t.Fatal(err)
+			}
+			signatureBytes, err := ecdsa.SignASN1(rand.Reader, l.PrivateKey(tt.signer), digestBytes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			signature := base64.StdEncoding.EncodeToString(signatureBytes)
+
+			permit := func(ctx *testutil.Context) error {
+				return c.Permit(ctx, admin, "bob", "300", deadline, signature)
+			}
+			if tt.replay {
+				testutil.MustRun(t, l, "relayer", "Permit", permit)
+			}
+			err = testutil.Run(l, "relayer", "Permit", permit)
+			testutil.CheckErr(t, err, tt.wantErr, "")
+
+			nonce, err := c.Nonces(l.Tx(admin, "Nonces"), admin)
+			if err != nil || nonce != tt.wantNonce {
+				t.Fatalf("nonce is %d: %v, want %d", nonce, err, tt.wantNonce)
+			}
+			if tt.wantErr && !tt.replay {
+				return
+			}
+			allowance, err := c.Allowance(l.Tx(admin, "Allowance"), admin, "bob")
+			if err != nil || allowance != "300" {
+				t.Fatalf("allowance is %s: %v, want 300", allowance, err)
+			}
+			approval := testutil.LastEvent(t, l, "Approval").(*events.Approval)
+			if approval.From != admin || approval.To != "bob" || approval.Value.String() != "300" {
+				t.Fatalf("event is %+v", approval)
+			}
+		})
+	}
+}
+
+func TestERC20TransferAndCall(t *testing.T) {
+	tests := []struct {
+		name        string
+		to          string
+		amount      string
+		reject      bool
+		wantErr     bool
+		wantCode    kusherrors.Code
+		wantSender  string
+		wantReceive string
+	}{
+		{name: "to an account", to: "bob", amount: "300", wantSender: "700", wantReceive: "300"},
+		{name: "to the receiver's custody", to: ContractAccountID("receiver", ""), amount: "300", wantSender: "700", wantReceive: "300"},
+		{name: "rejected by the receiver", to: "bob", amount: "300", reject: true, wantErr: true},
+		{name: "insufficient funds", to: "bob", amount: "1001", wantErr: true, wantCode: kusherrors.ErrInsufficientBalance},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, c := newERC20(t)
+			var received []string
+			l.RegisterChaincode("receiver", func(args [][]byte) peer.Response {
+				for _, arg := range args {
+					received = append(received, string(arg))
+				}
+				if tt.reject {
+					return shim.Error("rejected")
+				}
+				return shim.Success(nil)
+			})
+
+			err := testutil.Run(l, admin, "TransferAndCall", func(ctx *testutil.Context) error {
+				return c.TransferAndCall(ctx, "receiver", tt.to, tt.amount, "order-1")
+			})
+			testutil.CheckErr(t, err, tt.wantErr, tt.wantCode)
+			if tt.wantErr {
+				if balance := erc20Balance(t, l, c, admin); balance != "1000" {
+					t.Fatalf("sender balance is %s, want 1000", balance)
+				}
+				return
+			}
+
+			want := []string{"OnTransferReceived", admin, admin, tt.to, tt.amount, "order-1"}
+			if strings.Join(received, ",") != strings.Join(want, ",") {
+				t.Fatalf("receiver was called with %v, want %v", received, want)
+			}
+			if balance := erc20Balance(t, l, c, admin); balance != tt.wantSender {
+				t.Fatalf("sender balance is %s, want %s", balance, tt.wantSender)
+			}
+			if balance := erc20Balance(t, l, c, tt.to); balance != tt.wantReceive {
+				t.Fatalf("recipient balance is %s, want %s", balance, tt.wantReceive)
+			}
+			transfer := testutil.LastEvent(t, l, "Transfer").(*events.Transfer)
+			if transfer.From != admin || transfer.To != tt.to || transfer.Value.String() != tt.amount {
+				t.Fatalf("event is %+v", transfer)
+			}
+		})
+	}
+}
+
+func TestERC20Snapshot(t *testing.T) {
+	tests := []struct {
+		name         string
+		user         string
+		after        string
+		mint         string
+		wantErr      bool
+		wantAdminAt  string
+		wantBobAt    string
+		wantSupplyAt string
+		wantAdmin    string
+	}{
+		{name: "transfer after the snapshot", user: admin, after: "300", wantAdminAt: "1000", wantBobAt: "0", wantSupplyAt: "1000", wantAdmin: "700"},
+		{name: "mint after the snapshot", user: admin, mint: "500", wantAdminAt: "1000", wantBobAt: "0", wantSupplyAt: "1000", wantAdmin: "1500"},
+		{name: "no change after the snapshot", user: admin, wantAdminAt: "1000", wantBobAt: "0", wantSupplyAt: "1000", wantAdmin: "1000"},
+		{name: "without admin role", user: "bob", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, c := newERC20(t)
+			var id uint64
+			err := testutil.Run(l, tt.user, "Snapshot", func(ctx *testutil.Context) error {
+				var err error
+				id, err = c.Snapshot(ctx)
+				return err
+			})
+			testutil.CheckErr(t, err, tt.wantErr, "")
+			if tt.wantErr {
+				return
+			}
+			snapshot := testutil.LastEvent(t, l, "Snapshot").(*events.Snapshot)
+			if snapshot.Id != id || id != 1 {
+				t.Fatalf("snapshot %d, event is %+v", id, snapshot)
+			}
+
+			if tt.after != "" {
+				testutil.MustRun(t, l, admin, "Transfer", func(ctx *testutil.Context) error {
+					return c.Transfer(ctx, "bob", tt.after)
+				})
+			}
+			if tt.mint != "" {
+				testutil.MustRun(t, l, admin, "Mint", func(ctx *testutil.Context) error {
+					return c.Mint(ctx, tt.mint)
+				})
+			}
+
+			if balance, err := c.BalanceOfAt(l.Tx(admin, "BalanceOfAt"), admin, id); err != nil || balance != tt.wantAdminAt {
+				t.Fatalf("admin balance at the snapshot is %s: %v, want %s", balance, err, tt.wantAdminAt)
+			}
+			if balance, err := c.BalanceOfAt(l.Tx(admin, "BalanceOfAt"), "bob", id); err != nil || balance != tt.wantBobAt {
+				t.Fatalf("bob balance at the snapshot is %s: %v, want %s", balance, err, tt.wantBobAt)
+			}
+			if supply, err := c.TotalSupplyAt(l.Tx(admin, "TotalSupplyAt"), id); err != nil || supply != tt.wantSupplyAt {
+				t.Fatalf("total supply at the snapshot is %s: %v, want %s", supply, err, tt.wantSupplyAt)
+			}
+			if balance := erc20Balance(t, l, c, admin); balance != tt.wantAdmin {
+				t.Fatalf("admin balance is %s, want %s", balance, tt.wantAdmin)
+			}
+		})
+	}
+}
+
+func TestERC20FreezeAccount(t *testing.T) {
+	tests := []struct {
+		name      string
+		user      string
+		account   string
+		unfreeze  bool
+		wantErr   bool
+		wantEvent string
+		wantSent  bool
+	}{
+		{name: "recipient", user: admin, account: "bob", wantEvent: "Frozen"},
+		{name: "sender", user: admin, account: admin, wantEvent: "Frozen"},
+		{name: "unfrozen", user: admin, account: "bob", unfreeze: true, wantEvent: "Unfrozen", wantSent: true},
+		{name: "without admin role", user: "bob", account: "bob", wantErr: true, wantSent: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, c := newERC20(t)
+			err := testutil.Run(l, tt.user, "FreezeAccount", func(ctx *testutil.Context) error {
+				return c.FreezeAccount(ctx, tt.account)
+			})
+			testutil.CheckErr(t, err, tt.wantErr, "")
+			if tt.unfreeze {
+				testutil.MustRun(t, l, admin, "UnfreezeAccount", func(ctx *testutil.Context) error {
+					return c.UnfreezeAccount(ctx, tt.account)
+				})
+			}
+			if !tt.wantErr {
+				freeze := testutil.LastEvent(t, l, tt.wantEvent).(*events.FreezeEvent)
+				if freeze.Account != tt.account || freeze.Sender != admin {
+					t.Fatalf("event is %+v", freeze)
+				}
+			}
+			frozen, err := c.IsFrozen(l.Tx(admin, "IsFrozen"), tt.account)
+			if err != nil || frozen != !tt.wantSent {
+				t.Fatalf("frozen is %t: %v, want %t", frozen, err, !tt.wantSent)
+			}
+
+			err = testutil.Run(l, admin, "Transfer", func(ctx *testutil.Context) error {
+				return c.Transfer(ctx, "bob", "300")
+			})
+			testutil.CheckErr(t, err, !tt.wantSent, "")
+			wantSender := "1000"
+			if tt.wantSent {
+				wantSender = "700"
+			}
+			if balance := erc20Balance(t, l, c, admin); balance != wantSender {
+				t.Fatalf("sender balance is %s, want %s", balance, wantSender)
+			}
+		})
+	}
+}
+
+func TestERC20BurnFrom(t *testing.T) {
+	tests := []struct {
+		name          string
+		approve       string
+		burn          string
+		wantErr       bool
+		wantAllowance string
+		wantSupply    string
+	}{
+		{name: "within allowance", approve: "500", burn: "200", wantAllowance: "300", wantSupply: "800"},
+		{name: "whole allowance", approve: "500", burn: "500", wantAllowance: "0", wantSupply: "500"},
+		{name: "above allowance", approve: "500", burn: "501", wantErr: true, wantAllowance: "500", wantSupply: "1000"},
+		{name: "above balance", approve: "5000", burn: "1001", wantErr: true, wantAllowance: "5000", wantSupply: "1000"},
+		{name: "without allowance", burn: "1", wantErr: true, wantAllowance: "0", wantSupply: "1000"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, c := newERC20(t)
+			if tt.approve != "" {
+				testutil.MustRun(t, l, admin, "Approve", func(ctx *testutil.Context) error {
+					return c.Approve(ctx, "bob", tt.approve)
+				})
+			}
+
+			err := testutil.Run(l, "bob", "BurnFrom", func(ctx *testutil.Context) error {
+				return c.BurnFrom(ctx, admin, tt.burn)
+			})
+			testutil.CheckErr(t, err, tt.wantErr, "")
+
+			allowance, err := c.Allowance(l.Tx(admin, "Allowance"), admin, "bob")
+			if err != nil || allowance != tt.wantAllowance {
+				t.Fatalf("allowance is %s: %v, want %s", allowance, err, tt.wantAllowance)
+			}
+			supply, err := c.TotalSupply(l.Tx(admin, "TotalSupply"))
+			if err != nil || supply != tt.wantSupply {
+				t.Fatalf("total supply is %s: %v, want %s", supply, err, tt.wantSupply)
+			}
+			if balance := erc20Balance(t, l, c, admin); balance != tt.wantSupply {
+				t.Fatalf("balance is %s, want %s", balance, tt.wantSupply)
+			}
+			if tt.wantErr {
+				return
+			}
+			transfer := testutil.LastEvent(t, l, "Transfer").(*events.Transfer)
+			if transfer.From != admin || transfer.To != "0x0" || transfer.Value.String() != tt.burn {
+				t.Fatalf("event is %+v", transfer)
+			}
+		})
+	}
+}
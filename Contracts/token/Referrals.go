@@ -0,0 +1,395 @@
+package token
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+	"github.com/thekalpstudio/kush-go/canonical"
+	"github.com/thekalpstudio/kush-go/events"
+	"github.com/thekalpstudio/kush-go/logging"
+)
+
+const referrerCodePrefix = "referrer"
+const referralCampaignPrefix = "campaign"
+const referralStatsPrefix = "campaign~code"
+
+const maxBasisPoints = 10000
+
+// ReferralsContract accrues ERC20 commissions for referrers on purchases and mints recorded by
+// sale and marketplace chaincodes.
+type ReferralsContract struct {
+	logging.Contract
+}
+
+// Campaign defines the commission rate and the chaincodes allowed to record referrals.
+// Commissions are paid in Token out of the campaign's contract account (see CampaignAccount),
+// which the sponsor funds with a regular Transfer.
+type Campaign struct {
+	CampaignId string   `json:"campaignId"`
+	Token      string   `json:"token"`
+	RateBps    uint64   `json:"rateBps"`
+	Sources    []string `json:"sources"`
+	Account    string   `json:"account"`
+}
+
+// ReferrerStats is the per campaign report entry of a referrer code.
+type ReferrerStats struct {
+	Code      string `json:"code"`
+	Referrer  string `json:"referrer"`
+	Referrals uint64 `json:"referrals"`
+	Volume    string `json:"volume"`
+	Accrued   string `json:"accrued"`
+	Withdrawn string `json:"withdrawn"`
+}
+
+// CampaignReport aggregates all referrers of a campaign.
+type CampaignReport struct {
+	Campaign  *Campaign        `json:"campaign"`
+	Referrals uint64           `json:"referrals"`
+	Volume    string           `json:"volume"`
+	Accrued   string           `json:"accrued"`
+	Withdrawn string           `json:"withdrawn"`
+	Referrers []*ReferrerStats `json:"referrers"`
+}
+
+// ReferralRecorded MUST emit when a purchase carrying a referrer code is recorded.
+type ReferralRecorded = events.ReferralRecorded
+
+// CommissionWithdrawn MUST emit when a referrer claims accrued commission.
+type CommissionWithdrawn = events.CommissionWithdrawn
+
+// Initialize stores the MSPs authorized to manage referral campaigns and bootstraps the roles of
+// the caller, who must belong to one of them. It succeeds only once.
+func (s *ReferralsContract) Initialize(sdk kalpsdk.TransactionContextInterface, authorizedMSPs []string) error {
+	return accesscontrol.Initialize(sdk, authorizedMSPs)
+}
+
+// CreateCampaign registers a campaign paying rateBps basis points of every recorded purchase.
+func (s *ReferralsContract) CreateCampaign(sdk kalpsdk.TransactionContextInterface, campaignId string, token string, rateBps uint64, sources []string) (*Campaign, error) {
+	err := referralsAdminHelper(sdk)
+	if err != nil {
+		return nil, err
+	}
+	existing, err := readCampaign(sdk, campaignId)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("campaign %s already exists", campaignId)
+	}
+	if rateBps > maxBasisPoints {
+		return nil, fmt.Errorf("commission rate must not exceed %d basis points", maxBasisPoints)
+	}
+	chaincodeName, err := invokingChaincode(sdk)
+	if err != nil {
+		return nil, err
+	}
+	campaign := &Campaign{
+		CampaignId: campaignId,
+		Token:      token,
+		RateBps:    rateBps,
+		Sources:    sources,
+		Account:    ContractAccountID(chaincodeName, campaignId),
+	}
+	err = writeCampaign(sdk, campaign)
+	if err != nil {
+		return nil, err
+	}
+	return campaign, nil
+}
+
+// SetCampaignRate changes the commission rate for referrals recorded from now on.
+func (s *ReferralsContract) SetCampaignRate(sdk kalpsdk.TransactionContextInterface, campaignId string, rateBps uint64) error {
+	err := referralsAdminHelper(sdk)
+	if err != nil {
+		return err
+	}
+	campaign, err := campaignHelper(sdk, campaignId)
+	if err != nil {
+		return err
+	}
+	if rateBps > maxBasisPoints {
+		return fmt.Errorf("commission rate must not exceed %d basis points", maxBasisPoints)
+	}
+	campaign.RateBps = rateBps
+	return writeCampaign(sdk, campaign)
+}
+
+// RegisterReferrer claims code for the calling account.
+func (s *ReferralsContract) RegisterReferrer(sdk kalpsdk.TransactionContextInterface, code string) error {
+	referrer, err := sdk.GetUserID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+	if code == "" {
+		return fmt.Errorf("referrer code must not be empty")
+	}
+	codeKey, err := sdk.CreateCompositeKey(referrerCodePrefix, []string{code})
+	if err != nil {
+		return fmt.Errorf("failed to create the composite key for prefix %s: %v", referrerCodePrefix, err)
+	}
+	existing, err := sdk.GetState(codeKey)
+	if err != nil {
+		return fmt.Errorf("failed to read referrer code %s: %v", code, err)
+	}
+	if existing != nil {
+		return fmt.Errorf("referrer code %s is already registered", code)
+	}
+	return accesscontrol.PutState(sdk, codeKey, []byte(referrer))
+}
+
+// RecordReferral accrues the commission for a purchase of amount made by buyer with code.
+// It must be invoked through one of the campaign's source chaincodes.
+func (s *ReferralsContract) RecordReferral(sdk kalpsdk.TransactionContextInterface, campaignId string, code string, buyer string, amount string) (string, error) {
+	campaign, err := campaignHelper(sdk, campaignId)
+	if err != nil {
+		return "", err
+	}
+	source, err := invokingChaincode(sdk)
+	if err != nil {
+		return "", err
+	}
+	if !containsString(campaign.Sources, source) {
+		return "", fmt.Errorf("chaincode %s is not a referral source of campaign %s", source, campaignId)
+	}
+	referrer, err := referrerOf(sdk, code)
+	if err != nil {
+		return "", err
+	}
+	if referrer == buyer {
+		return "", fmt.Errorf("referrers cannot refer themselves")
+	}
+	purchase, err := parseAmount(amount)
+	if err != nil {
+		return "", err
+	}
+
+	commission := new(big.Int).Mul(purchase, new(big.Int).SetUint64(campaign.RateBps))
+	commission.Quo(commission, big.NewInt(maxBasisPoints))
+
+	stats, err := readReferrerStats(sdk, campaignId, code)
+	if err != nil {
+		return "", err
+	}
+	stats.Referrer = referrer
+	stats.Referrals++
+	stats.Volume = addAmountStrings(stats.Volume, purchase)
+	stats.Accrued = addAmountStrings(stats.Accrued, commission)
+	err = writeReferrerStats(sdk, campaignId, stats)
+	if err != nil {
+		return "", err
+	}
+
+	referralRecordedEvent := ReferralRecorded{CampaignId: campaignId, Code: code, Buyer: buyer, Amount: purchase.String(), Commission: commission.String()}
+	err = events.Emit(sdk, "ReferralRecorded", &referralRecordedEvent)
+	if err != nil {
+		return "", err
+	}
+	return commission.String(), nil
+}
+
+// WithdrawCommission pays the caller's unclaimed commission for code out of the campaign account.
+func (s *ReferralsContract) WithdrawCommission(sdk kalpsdk.TransactionContextInterface, campaignId string, code string) (string, error) {
+	campaign, err := campaignHelper(sdk, campaignId)
+	if err != nil {
+		return "", err
+	}
+	caller, err := sdk.GetUserID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get client id: %v", err)
+	}
+	referrer, err := referrerOf(sdk, code)
+	if err != nil {
+		return "", err
+	}
+	if caller != referrer {
+		return "", fmt.Errorf("caller does not own referrer code %s", code)
+	}
+	stats, err := readReferrerStats(sdk, campaignId, code)
+	if err != nil {
+		return "", err
+	}
+	accrued, _ := parseAmount(stats.Accrued)
+	withdrawn, _ := parseAmount(stats.Withdrawn)
+	payout := new(big.Int).Sub(accrued, withdrawn)
+	if payout.Sign() <= 0 {
+		return "", fmt.Errorf("no commission to withdraw for code %s", code)
+	}
+	stats.Withdrawn = accrued.String()
+	err = writeReferrerStats(sdk, campaignId, stats)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = invokeChaincodeHelper(sdk, campaign.Token, "ContractTransfer", campaign.Account, referrer, payout.String())
+	if err != nil {
+		return "", err
+	}
+
+	commissionWithdrawnEvent := CommissionWithdrawn{CampaignId: campaignId, Code: code, Referrer: referrer, Amount: payout.String()}
+	err = events.Emit(sdk, "CommissionWithdrawn", &commissionWithdrawnEvent)
+	if err != nil {
+		return "", err
+	}
+	return payout.String(), nil
+}
+
+// GetCampaign returns the campaign configuration.
+func (s *ReferralsContract) GetCampaign(sdk kalpsdk.TransactionContextInterface, campaignId string) (*Campaign, error) {
+	return campaignHelper(sdk, campaignId)
+}
+
+// GetReferrerStats returns referral count, volume and commissions of code within a campaign.
+func (s *ReferralsContract) GetReferrerStats(sdk kalpsdk.TransactionContextInterface, campaignId string, code string) (*ReferrerStats, error) {
+	_, err := campaignHelper(sdk, campaignId)
+	if err != nil {
+		return nil, err
+	}
+	return readReferrerStats(sdk, campaignId, code)
+}
+
+// GetCampaignReport returns the statistics of every referrer of a campaign and their totals.
+func (s *ReferralsContract) GetCampaignReport(sdk kalpsdk.TransactionContextInterface, campaignId string) (*CampaignReport, error) {
+	campaign, err := campaignHelper(sdk, campaignId)
+	if err != nil {
+		return nil, err
+	}
+	statsIterator, err := sdk.GetStateByPartialCompositeKey(referralStatsPrefix, []string{campaignId})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get state for prefix %v: %v", referralStatsPrefix, err)
+	}
+	defer statsIterator.Close()
+
+	report := &CampaignReport{Campaign: campaign, Volume: "0", Accrued: "0", Withdrawn: "0", Referrers: []*ReferrerStats{}}
+	for statsIterator.HasNext() {
+		queryResponse, err := statsIterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the next state for prefix %v: %v", referralStatsPrefix, err)
+		}
+		stats := new(ReferrerStats)
+		err = json.Unmarshal(queryResponse.Value, stats)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode referrer stats: %v", err)
+		}
+		volume, _ := parseAmount(stats.Volume)
+		accrued, _ := parseAmount(stats.Accrued)
+		withdrawn, _ := parseAmount(stats.Withdrawn)
+		report.Referrals += stats.Referrals
+		report.Volume = addAmountStrings(report.Volume, volume)
+		report.Accrued = addAmountStrings(report.Accrued, accrued)
+		report.Withdrawn = addAmountStrings(report.Withdrawn, withdrawn)
+		report.Referrers = append(report.Referrers, stats)
+	}
+	return report, nil
+}
+
+// Helper Functions
+
+func referralsAdminHelper(sdk kalpsdk.TransactionContextInterface) error {
+	err := accesscontrol.CheckAuthorizedMSP(sdk)
+	if err != nil {
+		return fmt.Errorf("client is not authorized to manage referral campaigns: %v", err)
+	}
+	return nil
+}
+
+func readCampaign(sdk kalpsdk.TransactionContextInterface, campaignId string) (*Campaign, error) {
+	campaignKey, err := sdk.CreateCompositeKey(referralCampaignPrefix, []string{campaignId})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the composite key for prefix %s: %v", referralCampaignPrefix, err)
+	}
+	campaignBytes, err := sdk.GetState(campaignKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read campaign %s from world state: %v", campaignId, err)
+	}
+	if campaignBytes == nil {
+		return nil, nil
+	}
+	campaign := new(Campaign)
+	err = json.Unmarshal(campaignBytes, campaign)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode campaign %s: %v", campaignId, err)
+	}
+	return campaign, nil
+}
+
+func campaignHelper(sdk kalpsdk.TransactionContextInterface, campaignId string) (*Campaign, error) {
+	campaign, err := readCampaign(sdk, campaignId)
+	if err != nil {
+		return nil, err
+	}
+	if campaign == nil {
+		return nil, fmt.Errorf("campaign %s does not exist", campaignId)
+	}
+	return campaign, nil
+}
+
+func writeCampaign(sdk kalpsdk.TransactionContextInterface, campaign *Campaign) error {
+	campaignKey, err := sdk.CreateCompositeKey(referralCampaignPrefix, []string{campaign.CampaignId})
+	if err != nil {
+		return fmt.Errorf("failed to create the composite key for prefix %s: %v", referralCampaignPrefix, err)
+	}
+	campaignJSON, err := canonical.Marshal(campaign)
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	return accesscontrol.PutState(sdk, campaignKey, campaignJSON)
+}
+
+func referrerOf(sdk kalpsdk.TransactionContextInterface, code string) (string, error) {
+	codeKey, err := sdk.CreateCompositeKey(referrerCodePrefix, []string{code})
+	if err != nil {
+		return "", fmt.Errorf("failed to create the composite key for prefix %s: %v", referrerCodePrefix, err)
+	}
+	referrer, err := sdk.GetState(codeKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to read referrer code %s: %v", code, err)
+	}
+	if referrer == nil {
+		return "", fmt.Errorf("referrer code %s is not registered", code)
+	}
+	return string(referrer), nil
+}
+
+func readReferrerStats(sdk kalpsdk.TransactionContextInterface, campaignId string, code string) (*ReferrerStats, error) {
+	statsKey, err := sdk.CreateCompositeKey(referralStatsPrefix, []string{campaignId, code})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the composite key for prefix %s: %v", referralStatsPrefix, err)
+	}
+	statsBytes, err := sdk.GetState(statsKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read referrer stats for %s: %v", code, err)
+	}
+	stats := &ReferrerStats{Code: code, Volume: "0", Accrued: "0", Withdrawn: "0"}
+	if statsBytes == nil {
+		return stats, nil
+	}
+	err = json.Unmarshal(statsBytes, stats)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode referrer stats for %s: %v", code, err)
+	}
+	return stats, nil
+}
+
+func writeReferrerStats(sdk kalpsdk.TransactionContextInterface, campaignId string, stats *ReferrerStats) error {
+	statsKey, err := sdk.CreateCompositeKey(referralStatsPrefix, []string{campaignId, stats.Code})
+	if err != nil {
+		return fmt.Errorf("failed to create the composite key for prefix %s: %v", referralStatsPrefix, err)
+	}
+	statsJSON, err := canonical.Marshal(stats)
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	return accesscontrol.PutState(sdk, statsKey, statsJSON)
+}
+
+func addAmountStrings(total string, amount *big.Int) string {
+	sum, ok := new(big.Int).SetString(total, 10)
+	if !ok {
+		sum = new(big.Int)
+	}
+	return sum.Add(sum, amount).String()
+}
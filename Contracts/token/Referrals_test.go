@@ -0,0 +1,131 @@
+package token
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/thekalpstudio/kush-go/events"
+	"github.com/thekalpstudio/kush-go/testutil"
+)
+
+// serveReferrals answers the RecordReferral calls of a source chaincode, collecting the events
+// Fabric drops from invoked chaincodes into recorded.
+func serveReferrals(r *ReferralsContract, recorded *[]*events.ReferralRecorded) testutil.Contract {
+	return func(ctx *testutil.Context, args [][]byte) peer.Response {
+		commission, err := r.RecordReferral(ctx, string(args[1]), string(args[2]), string(args[3]), string(args[4]))
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		_, value, err := events.Decode(ctx.EventName, ctx.EventPayload)
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		*recorded = append(*recorded, value.(*events.ReferralRecorded))
+		return shim.Success([]byte(commission))
+	}
+}
+
+func TestReferrals(t *testing.T) {
+	tests := []struct {
+		name           string
+		source         string
+		buyer          string
+		purchases      []string
+		withdrawer     string
+		twice          bool
+		wantRecordErr  bool
+		wantErr        bool
+		wantCommission []string
+		wantAccrued    string
+		wantPaid       string
+		wantCampaign   string
+	}{
+		{name: "one purchase", source: "shop", buyer: "carol", purchases: []string{"1000"}, withdrawer: "bob", wantCommission: []string{"50"}, wantAccrued: "50", wantPaid: "50", wantCampaign: "50"},
+		{name: "several purchases", source: "shop", buyer: "carol", purchases: []string{"1000", "300"}, withdrawer: "bob", wantCommission: []string{"50", "15"}, wantAccrued: "65", wantPaid: "65", wantCampaign: "35"},
+		{name: "commission rounded down", source: "shop", buyer: "carol", purchases: []string{"30"}, withdrawer: "bob", wantCommission: []string{"1"}, wantAccrued: "1", wantPaid: "1", wantCampaign: "99"},
+		{name: "self referral", source: "shop", buyer: "bob", purchases: []string{"1000"}, wantRecordErr: true},
+		{name: "from another chaincode", source: "market", buyer: "carol", purchases: []string{"1000"}, wantRecordErr: true},
+		{name: "by another user", source: "shop", buyer: "carol", purchases: []string{"1000"}, withdrawer: "carol", wantCommission: []string{"50"}, wantAccrued: "50", wantErr: true, wantCampaign: "100"},
+		{name: "more than the campaign holds", source: "shop", buyer: "carol", purchases: []string{"3000"}, withdrawer: "bob", wantCommission: []string{"150"}, wantAccrued: "150", wantErr: true, wantCampaign: "100"},
+		{name: "withdrawn twice", source: "shop", buyer: "carol", purchases: []string{"1000"}, withdrawer: "bob", twice: true, wantCommission: []string{"50"}, wantAccrued: "50", wantErr: true, wantPaid: "50", wantCampaign: "50"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, c := newERC20(t)
+			rl := testutil.NewLedger()
+			rl.SetChaincode("referrals")
+			rl.RegisterContract("token", l, serveERC20(c))
+			r := new(ReferralsContract)
+			testutil.MustRun(t, rl, admin, "Initialize", func(ctx *testutil.Context) error {
+				return r.Initialize(ctx, []string{testutil.MSPID})
+			})
+			var campaign *Campaign
+			testutil.MustRun(t, rl, admin, "CreateCampaign", func(ctx *testutil.Context) error {
+				var err error
+				campaign, err = r.CreateCampaign(ctx, "launch", "token", 500, []string{"shop"})
+				return err
+			})
+			testutil.MustRun(t, l, admin, "Transfer", func(ctx *testutil.Context) error {
+				return c.Transfer(ctx, campaign.Account, "100")
+			})
+			testutil.MustRun(t, rl, "bob", "RegisterReferrer", func(ctx *testutil.Context) error {
+				return r.RegisterReferrer(ctx, "BOB")
+			})
+
+			sl := testutil.NewLedger()
+			sl.SetChaincode(tt.source)
+			var recorded []*events.ReferralRecorded
+			sl.RegisterContract("referrals", rl, serveReferrals(r, &recorded))
+			for _, purchase := range tt.purchases {
+				err := testutil.Run(sl, tt.buyer, "Buy", func(ctx *testutil.Context) error {
+					_, err := invokeChaincodeHelper(ctx, "referrals", "RecordReferral", "launch", "BOB", tt.buyer, purchase)
+					return err
+				})
+				testutil.CheckErr(t, err, tt.wantRecordErr, "")
+			}
+			if tt.wantRecordErr {
+				return
+			}
+
+			if len(recorded) != len(tt.purchases) {
+				t.Fatalf("%d referrals were recorded, want %d", len(recorded), len(tt.purchases))
+			}
+			for i, referral := range recorded {
+				if referral.CampaignId != "launch" || referral.Code != "BOB" || referral.Buyer != tt.buyer || referral.Amount != tt.purchases[i] || referral.Commission != tt.wantCommission[i] {
+					t.Fatalf("event %d is %+v", i, referral)
+				}
+			}
+			stats, err := r.GetReferrerStats(rl.Tx(admin, "GetReferrerStats"), "launch", "BOB")
+			if err != nil || stats.Referrer != "bob" || stats.Referrals != uint64(len(tt.purchases)) || stats.Accrued != tt.wantAccrued {
+				t.Fatalf("stats are %+v: %v, want %s accrued", stats, err, tt.wantAccrued)
+			}
+
+			withdraw := func(ctx *testutil.Context) error {
+				_, err := r.WithdrawCommission(ctx, "launch", "BOB")
+				return err
+			}
+			if tt.twice {
+				testutil.MustRun(t, rl, tt.withdrawer, "WithdrawCommission", withdraw)
+			}
+			err = testutil.Run(rl, tt.withdrawer, "WithdrawCommission", withdraw)
+			testutil.CheckErr(t, err, tt.wantErr, "")
+
+			if balance := erc20Balance(t, l, c, campaign.Account); balance != tt.wantCampaign {
+				t.Fatalf("campaign balance is %s, want %s", balance, tt.wantCampaign)
+			}
+			if tt.wantPaid != "" {
+				if balance := erc20Balance(t, l, c, "bob"); balance != tt.wantPaid {
+					t.Fatalf("referrer balance is %s, want %s", balance, tt.wantPaid)
+				}
+			}
+			if tt.wantErr {
+				return
+			}
+			withdrawn := testutil.LastEvent(t, rl, "CommissionWithdrawn").(*events.CommissionWithdrawn)
+			if withdrawn.CampaignId != "launch" || withdrawn.Code != "BOB" || withdrawn.Referrer != "bob" || withdrawn.Amount != tt.wantPaid {
+				t.Fatalf("event is %+v", withdrawn)
+			}
+		})
+	}
+}
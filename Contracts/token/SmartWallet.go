@@ -0,0 +1,348 @@
+package token
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+	"github.com/thekalpstudio/kush-go/canonical"
+	"github.com/thekalpstudio/kush-go/events"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+	"github.com/thekalpstudio/kush-go/logging"
+)
+
+const walletPrefix = "wallet"
+const walletSpendPrefix = "wallet~day"
+const walletApprovalPrefix = "wallet~digest"
+
+const secondsPerDay = 86400
+
+// SmartWalletContract keeps token balances in contract accounts on behalf of their owners and
+// only moves them through Execute once the wallet's policy is satisfied.
+type SmartWalletContract struct {
+	logging.Contract
+}
+
+// Wallet is a policy-controlled custody account.
+type Wallet struct {
+	WalletId string       `json:"walletId"`
+	Owner    string       `json:"owner"`
+	Account  string       `json:"account"`
+	Policy   WalletPolicy `json:"policy"`
+}
+
+// WalletPolicy restricts what Execute may do. Amounts are decimal strings in base units and an
+// empty limit or threshold disables the corresponding check.
+type WalletPolicy struct {
+	DailyLimit          string   `json:"dailyLimit"`
+	AllowedDestinations []string `json:"allowedDestinations"`
+	CoSigner            string   `json:"coSigner"`
+	CoSignThreshold     string   `json:"coSignThreshold"`
+}
+
+// WalletExecuted MUST emit when a wallet call is forwarded to its target chaincode.
+type WalletExecuted = events.WalletExecuted
+
+// walletCallSpec tells Execute which arguments of a supported function carry the debited
+// account, the destination and the amount.
+type walletCallSpec struct {
+	fromArg   int
+	toArg     int
+	amountArg int
+}
+
+var walletCallSpecs = map[string]walletCallSpec{
+	"ContractTransfer": {fromArg: 0, toArg: 1, amountArg: 2},
+}
+
+// CreateWallet registers a new wallet owned by the caller with an empty policy.
+func (s *SmartWalletContract) CreateWallet(sdk kalpsdk.TransactionContextInterface, walletId string) (*Wallet, error) {
+	owner, err := sdk.GetUserID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client id: %v", err)
+	}
+	if walletId == "" {
+		return nil, fmt.Errorf("wallet id must not be empty")
+	}
+	existing, err := readWallet(sdk, walletId)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("wallet %s already exists", walletId)
+	}
+	chaincodeName, err := invokingChaincode(sdk)
+	if err != nil {
+		return nil, err
+	}
+	wallet := &Wallet{
+		WalletId: walletId,
+		Owner:    owner,
+		Account:  ContractAccountID(chaincodeName, walletId),
+	}
+	err = writeWallet(sdk, wallet)
+	if err != nil {
+		return nil, err
+	}
+	return wallet, nil
+}
+
+// SetPolicy replaces the wallet policy. Once a co-signer is configured, the change must have
+// been approved by that co-signer through ApproveExecution with target "" and function "SetPolicy".
+func (s *SmartWalletContract) SetPolicy(sdk kalpsdk.TransactionContextInterface, walletId string, policyJSON string) error {
+	wallet, err := walletForOwner(sdk, walletId)
+	if err != nil {
+		return err
+	}
+	policy := WalletPolicy{}
+	err = json.Unmarshal([]byte(policyJSON), &policy)
+	if err != nil {
+		return fmt.Errorf("failed to decode wallet policy: %v", err)
+	}
+	for _, amount := range []string{policy.DailyLimit, policy.CoSignThreshold} {
+		if amount == "" {
+			continue
+		}
+		if _, err := parseAmount(amount); err != nil {
+			return err
+		}
+	}
+	if policy.CoSignThreshold != "" && policy.CoSigner == "" {
+		return fmt.Errorf("a co-signer is required when a co-sign threshold is set")
+	}
+	if wallet.Policy.CoSigner != "" {
+		err = consumeWalletApproval(sdk, walletId, walletDigest(walletId, "", "SetPolicy", []string{policyJSON}))
+		if err != nil {
+			return err
+		}
+	}
+	wallet.Policy = policy
+	return writeWallet(sdk, wallet)
+}
+
+// ApproveExecution records the co-signer's approval for one Execute (or SetPolicy) call with
+// exactly these arguments. The approval is consumed by the approved call.
+func (s *SmartWalletContract) ApproveExecution(sdk kalpsdk.TransactionContextInterface, walletId string, target string, function string, args []string) error {
+	wallet, err := readWallet(sdk, walletId)
+	if err != nil {
+		return err
+	}
+	if wallet == nil {
+		return fmt.Errorf("wallet %s does not exist", walletId)
+	}
+	signer, err := sdk.GetUserID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+	if wallet.Policy.CoSigner == "" || signer != wallet.Policy.CoSigner {
+		return fmt.Errorf("caller is not the co-signer of wallet %s", walletId)
+	}
+	approvalKey, err := sdk.CreateCompositeKey(walletApprovalPrefix, []string{walletId, walletDigest(walletId, target, function, args)})
+	if err != nil {
+		return fmt.Errorf("failed to create the composite key for prefix %s: %v", walletApprovalPrefix, err)
+	}
+	return accesscontrol.PutState(sdk, approvalKey, []byte(signer))
+}
+
+// Execute forwards function with args to the target chaincode after enforcing the wallet policy.
+// It returns the payload answered by the target.
+func (s *SmartWalletContract) Execute(sdk kalpsdk.TransactionContextInterface, walletId string, target string, function string, args []string) (string, error) {
+	wallet, err := walletForOwner(sdk, walletId)
+	if err != nil {
+		return "", err
+	}
+	spec, ok := walletCallSpecs[function]
+	if !ok {
+		return "", fmt.Errorf("function %s is not supported by smart wallets", function)
+	}
+	if len(args) <= spec.fromArg || len(args) <= spec.toArg || len(args) <= spec.amountArg {
+		return "", fmt.Errorf("function %s expects at least %d arguments", function, spec.amountArg+1)
+	}
+	if args[spec.fromArg] != wallet.Account {
+		return "", fmt.Errorf("wallet %s can only spend from its own account %s", walletId, wallet.Account)
+	}
+	destination := args[spec.toArg]
+	if len(wallet.Policy.AllowedDestinations) > 0 && !containsString(wallet.Policy.AllowedDestinations, destination) {
+		return "", fmt.Errorf("destination %s is not allowed by the policy of wallet %s", destination, walletId)
+	}
+	amount, err := parseAmount(args[spec.amountArg])
+	if err != nil {
+		return "", err
+	}
+
+	err = spendDailyAllowance(sdk, wallet, amount)
+	if err != nil {
+		return "", err
+	}
+
+	coSigned := false
+	if wallet.Policy.CoSignThreshold != "" {
+		threshold, _ := parseAmount(wallet.Policy.CoSignThreshold)
+		if amount.Cmp(threshold) > 0 {
+			err = consumeWalletApproval(sdk, walletId, walletDigest(walletId, target, function, args))
+			if err != nil {
+				return "", err
+			}
+			coSigned = true
+		}
+	}
+
+	payload, err := invokeChaincodeHelper(sdk, target, function, args...)
+	if err != nil {
+		return "", err
+	}
+
+	walletExecutedEvent := WalletExecuted{WalletId: walletId, Target: target, Function: function, Args: args, CoSigned: coSigned}
+	err = events.Emit(sdk, "WalletExecuted", &walletExecutedEvent)
+	if err != nil {
+		return "", err
+	}
+	return string(payload), nil
+}
+
+// GetWallet returns the wallet record.
+func (s *SmartWalletContract) GetWallet(sdk kalpsdk.TransactionContextInterface, walletId string) (*Wallet, error) {
+	wallet, err := readWallet(sdk, walletId)
+	if err != nil {
+		return nil, err
+	}
+	if wallet == nil {
+		return nil, fmt.Errorf("wallet %s does not exist", walletId)
+	}
+	return wallet, nil
+}
+
+// GetDailySpent returns the amount the wallet has spent during the current (UTC) day.
+func (s *SmartWalletContract) GetDailySpent(sdk kalpsdk.TransactionContextInterface, walletId string) (string, error) {
+	spendKey, err := walletSpendKey(sdk, walletId)
+	if err != nil {
+		return "", err
+	}
+	spent, err := readAmount(sdk, spendKey)
+	if err != nil {
+		return "", err
+	}
+	return spent.String(), nil
+}
+
+// Helper Functions
+
+func readWallet(sdk kalpsdk.TransactionContextInterface, walletId string) (*Wallet, error) {
+	walletKey, err := sdk.CreateCompositeKey(walletPrefix, []string{walletId})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the composite key for prefix %s: %v", walletPrefix, err)
+	}
+	walletBytes, err := sdk.GetState(walletKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read wallet %s from world state: %v", walletId, err)
+	}
+	if walletBytes == nil {
+		return nil, nil
+	}
+	wallet := new(Wallet)
+	err = json.Unmarshal(walletBytes, wallet)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode wallet %s: %v", walletId, err)
+	}
+	return wallet, nil
+}
+
+func writeWallet(sdk kalpsdk.TransactionContextInterface, wallet *Wallet) error {
+	walletKey, err := sdk.CreateCompositeKey(walletPrefix, []string{wallet.WalletId})
+	if err != nil {
+		return fmt.Errorf("failed to create the composite key for prefix %s: %v", walletPrefix, err)
+	}
+	walletJSON, err := canonical.Marshal(wallet)
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	return accesscontrol.PutState(sdk, walletKey, walletJSON)
+}
+
+func walletForOwner(sdk kalpsdk.TransactionContextInterface, walletId string) (*Wallet, error) {
+	wallet, err := readWallet(sdk, walletId)
+	if err != nil {
+		return nil, err
+	}
+	if wallet == nil {
+		return nil, fmt.Errorf("wallet %s does not exist", walletId)
+	}
+	caller, err := sdk.GetUserID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client id: %v", err)
+	}
+	if caller != wallet.Owner {
+		return nil, kusherrors.Errorf(kusherrors.ErrUnauthorized, "caller is not the owner of wallet %s", walletId)
+	}
+	return wallet, nil
+}
+
+// walletDigest identifies a single call so that a co-signer approval cannot be replayed for
+// different arguments.
+func walletDigest(walletId string, target string, function string, args []string) string {
+	parts, _ := canonical.Marshal(append([]string{walletId, target, function}, args...))
+	digest := sha256.Sum256(parts)
+	return hex.EncodeToString(digest[:])
+}
+
+func consumeWalletApproval(sdk kalpsdk.TransactionContextInterface, walletId string, digest string) error {
+	approvalKey, err := sdk.CreateCompositeKey(walletApprovalPrefix, []string{walletId, digest})
+	if err != nil {
+		return fmt.Errorf("failed to create the composite key for prefix %s: %v", walletApprovalPrefix, err)
+	}
+	approvalBytes, err := sdk.GetState(approvalKey)
+	if err != nil {
+		return fmt.Errorf("failed to read co-signer approval: %v", err)
+	}
+	if approvalBytes == nil {
+		return fmt.Errorf("call requires approval from the co-signer of wallet %s", walletId)
+	}
+	return accesscontrol.DelState(sdk, approvalKey)
+}
+
+func walletSpendKey(sdk kalpsdk.TransactionContextInterface, walletId string) (string, error) {
+	timestamp, err := sdk.GetTxTimestamp()
+	if err != nil {
+		return "", fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	day := strconv.FormatInt(timestamp.GetSeconds()/secondsPerDay, 10)
+	spendKey, err := sdk.CreateCompositeKey(walletSpendPrefix, []string{walletId, day})
+	if err != nil {
+		return "", fmt.Errorf("failed to create the composite key for prefix %s: %v", walletSpendPrefix, err)
+	}
+	return spendKey, nil
+}
+
+func spendDailyAllowance(sdk kalpsdk.TransactionContextInterface, wallet *Wallet, amount *big.Int) error {
+	if wallet.Policy.DailyLimit == "" {
+		return nil
+	}
+	limit, _ := parseAmount(wallet.Policy.DailyLimit)
+	spendKey, err := walletSpendKey(sdk, wallet.WalletId)
+	if err != nil {
+		return err
+	}
+	spent, err := readAmount(sdk, spendKey)
+	if err != nil {
+		return err
+	}
+	spent.Add(spent, amount)
+	if spent.Cmp(limit) > 0 {
+		return fmt.Errorf("wallet %s would exceed its daily limit of %s", wallet.WalletId, wallet.Policy.DailyLimit)
+	}
+	return accesscontrol.PutState(sdk, spendKey, []byte(spent.String()))
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,100 @@
+package token
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/thekalpstudio/kush-go/events"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+	"github.com/thekalpstudio/kush-go/testutil"
+)
+
+func TestSmartWalletExecute(t *testing.T) {
+	tests := []struct {
+		name         string
+		policy       string
+		approve      bool
+		user         string
+		from         string
+		to           string
+		amount       string
+		wantErr      bool
+		wantCode     kusherrors.Code
+		wantCoSigned bool
+		wantSpent    string
+	}{
+		{name: "without a policy", user: admin, to: "bob", amount: "200", wantSpent: "0"},
+		{name: "by another user", user: "bob", to: "bob", amount: "200", wantErr: true, wantCode: kusherrors.ErrUnauthorized},
+		{name: "from another account", user: admin, from: admin, to: "bob", amount: "200", wantErr: true},
+		{name: "above the balance", user: admin, to: "bob", amount: "501", wantErr: true},
+		{name: "within the daily limit", policy: `{"dailyLimit":"300"}`, user: admin, to: "bob", amount: "200", wantSpent: "200"},
+		{name: "above the daily limit", policy: `{"dailyLimit":"300"}`, user: admin, to: "bob", amount: "301", wantErr: true},
+		{name: "to an allowed destination", policy: `{"allowedDestinations":["bob"]}`, user: admin, to: "bob", amount: "200", wantSpent: "0"},
+		{name: "to another destination", policy: `{"allowedDestinations":["carol"]}`, user: admin, to: "bob", amount: "200", wantErr: true},
+		{name: "above the co-sign threshold", policy: `{"coSigner":"carol","coSignThreshold":"100"}`, user: admin, to: "bob", amount: "200", wantErr: true},
+		{name: "co-signed", policy: `{"coSigner":"carol","coSignThreshold":"100"}`, approve: true, user: admin, to: "bob", amount: "200", wantCoSigned: true, wantSpent: "0"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, c := newERC20(t)
+			wl := testutil.NewLedger()
+			wl.SetChaincode("wallet")
+			wl.RegisterContract("token", l, serveERC20(c))
+			s := new(SmartWalletContract)
+			var wallet *Wallet
+			testutil.MustRun(t, wl, admin, "CreateWallet", func(ctx *testutil.Context) error {
+				var err error
+				wallet, err = s.CreateWallet(ctx, "savings")
+				return err
+			})
+			if wallet.Account != ContractAccountID("wallet", "savings") {
+				t.Fatalf("wallet account is %s", wallet.Account)
+			}
+			testutil.MustRun(t, l, admin, "Transfer", func(ctx *testutil.Context) error {
+				return c.Transfer(ctx, wallet.Account, "500")
+			})
+			if tt.policy != "" {
+				testutil.MustRun(t, wl, admin, "SetPolicy", func(ctx *testutil.Context) error {
+					return s.SetPolicy(ctx, "savings", tt.policy)
+				})
+			}
+
+			from := tt.from
+			if from == "" {
+				from = wallet.Account
+			}
+			args := []string{from, tt.to, tt.amount}
+			if tt.approve {
+				testutil.MustRun(t, wl, "carol", "ApproveExecution", func(ctx *testutil.Context) error {
+					return s.ApproveExecution(ctx, "savings", "token", "ContractTransfer", args)
+				})
+			}
+			err := testutil.Run(wl, tt.user, "Execute", func(ctx *testutil.Context) error {
+				_, err := s.Execute(ctx, "savings", "token", "ContractTransfer", args)
+				return err
+			})
+			testutil.CheckErr(t, err, tt.wantErr, tt.wantCode)
+			if tt.wantErr {
+				if balance := erc20Balance(t, l, c, wallet.Account); balance != "500" {
+					t.Fatalf("failed call changed the wallet balance to %s", balance)
+				}
+				return
+			}
+
+			if balance := erc20Balance(t, l, c, wallet.Account); balance != "300" {
+				t.Fatalf("wallet balance is %s, want 300", balance)
+			}
+			if balance := erc20Balance(t, l, c, tt.to); balance != tt.amount {
+				t.Fatalf("destination balance is %s, want %s", balance, tt.amount)
+			}
+			spent, err := s.GetDailySpent(wl.Tx(admin, "GetDailySpent"), "savings")
+			if err != nil || spent != tt.wantSpent {
+				t.Fatalf("daily spent is %s: %v, want %s", spent, err, tt.wantSpent)
+			}
+			executed := testutil.LastEvent(t, wl, "WalletExecuted").(*events.WalletExecuted)
+			if executed.WalletId != "savings" || executed.Target != "token" || executed.Function != "ContractTransfer" || !reflect.DeepEqual(executed.Args, args) || executed.CoSigned != tt.wantCoSigned {
+				t.Fatalf("event is %+v", executed)
+			}
+		})
+	}
+}
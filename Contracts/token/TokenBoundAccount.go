@@ -0,0 +1,131 @@
+package token
+
+import (
+	"fmt"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/denylist"
+	"github.com/thekalpstudio/kush-go/events"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+// tokenBoundAccountPrefix marks accounts owned by an NFT (ERC-6551 style). Whoever currently owns
+// the NFT controls the account, so its balances move with the NFT. The NFT chaincode is part of
+// the ID, so a chaincode can only ever vouch for the accounts of its own tokens.
+const tokenBoundAccountPrefix = "tba::"
+
+// TokenBoundAccountID returns the account ID bound to tokenId of the ERC721 chaincode
+// nftChaincode. It matches TokenBoundAccount on that chaincode.
+func TokenBoundAccountID(nftChaincode string, tokenId string) string {
+	return tokenBoundAccountPrefix + nftChaincode + "::" + tokenId
+}
+
+// checkTokenBoundOwner asks nftChaincode for the owner of tokenId and fails unless it is the
+// calling user. It returns the token bound account.
+func checkTokenBoundOwner(sdk kalpsdk.TransactionContextInterface, nftChaincode string, tokenId string) (string, error) {
+	if nftChaincode == "" || tokenId == "" {
+		return "", fmt.Errorf("nft chaincode and token id must be set")
+	}
+	clientID, err := sdk.GetUserID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get client id: %v", err)
+	}
+	owner, err := invokeChaincodeHelper(sdk, nftChaincode, "OwnerOf", tokenId)
+	if err != nil {
+		return "", err
+	}
+	if string(owner) != clientID {
+		return "", fmt.Errorf("client %s does not own token %s of %s", clientID, tokenId, nftChaincode)
+	}
+	return TokenBoundAccountID(nftChaincode, tokenId), nil
+}
+
+// TokenBoundTransfer transfers value tokens out of the account bound to tokenId of nftChaincode.
+// Only the current owner of the NFT may call it.
+func (c *TokenERC20Contract) TokenBoundTransfer(ctx kalpsdk.TransactionContextInterface, nftChaincode string, tokenId string, to string, value string) error {
+	initialized, err := checkInitialized(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	err = checkNotPaused(ctx)
+	if err != nil {
+		return err
+	}
+
+	from, err := checkTokenBoundOwner(ctx, nftChaincode, tokenId)
+	if err != nil {
+		return err
+	}
+
+	transferAmount, err := parseAmount(value)
+	if err != nil {
+		return err
+	}
+
+	err = checkTravelRule(ctx, transferAmount)
+	if err != nil {
+		return err
+	}
+
+	fee, err := transferHelper(ctx, from, to, transferAmount)
+	if err != nil {
+		return fmt.Errorf("failed to transfer: %v", err)
+	}
+
+	transferEvent := events.Transfer{From: from, To: to, Value: transferAmount, FeeCharged: fee}
+	err = events.Emit(ctx, "Transfer", &transferEvent)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// TokenBoundTransferFrom transfers amount tokens of type id out of the account bound to tokenId of
+// nftChaincode. Only the current owner of the NFT may call it.
+func (s *SmartContract) TokenBoundTransferFrom(sdk kalpsdk.TransactionContextInterface, nftChaincode string, tokenId string, recipient string, id uint64, amount uint64) error {
+	initialized, err := checkInitialized2(sdk)
+	if err != nil || !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "failed to check if contract is already initialized: %v", err)
+	}
+	err = checkNotPaused(sdk)
+	if err != nil {
+		return err
+	}
+	err = checkTokenIDsNotFrozen(sdk, id)
+	if err != nil {
+		return err
+	}
+	sender, err := checkTokenBoundOwner(sdk, nftChaincode, tokenId)
+	if err != nil {
+		return err
+	}
+	if sender == recipient {
+		return fmt.Errorf("transfer to self")
+	}
+	err = denylist.CheckNotDenied(sdk, sender, recipient)
+	if err != nil {
+		return err
+	}
+	if recipient == "0x0" {
+		return fmt.Errorf("transfer to the zero address")
+	}
+	operator, err := sdk.GetClientIdentity().GetID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+	err = removeBalance(sdk, sender, []uint64{id}, []uint64{amount})
+	if err != nil {
+		return err
+	}
+	err = add1Balance(sdk, recipient, id, amount)
+	if err != nil {
+		return err
+	}
+	transferSingleEvent := TransferSingle{Operator: operator, From: sender, To: recipient, ID: id, Value: amount}
+	return emitTransferSingle(sdk, transferSingleEvent)
+}
@@ -0,0 +1,379 @@
+package token
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+	"github.com/thekalpstudio/kush-go/canonical"
+	"github.com/thekalpstudio/kush-go/events"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+	"github.com/thekalpstudio/kush-go/logging"
+)
+
+const vaultConfigKey = "vault~config"
+const vaultSharesPrefix = "vault~shares"
+const vaultTotalSharesKey = "vault~totalShares"
+
+// VaultContract is an ERC-4626 style vault over an ERC20 chaincode. Deposited assets are held in
+// the vault's contract account on the underlying token (see ContractAccountID), and depositors
+// receive shares that track their proportional claim on the vault's balance.
+type VaultContract struct {
+	logging.Contract
+}
+
+// VaultConfig names the underlying ERC20 chaincode and the vault's account on it.
+type VaultConfig struct {
+	Underlying string `json:"underlying"`
+	Account    string `json:"account"`
+}
+
+// VaultDeposit MUST emit when assets are deposited.
+type VaultDeposit = events.VaultDeposit
+
+// VaultWithdraw MUST emit when shares are burned for assets.
+type VaultWithdraw = events.VaultWithdraw
+
+// Initialize binds the vault to the underlying ERC20 chaincode and stores the MSPs authorized to
+// administer it, bootstrapping the roles of the caller, who must belong to one of them. It can only
+// be called once.
+func (s *VaultContract) Initialize(sdk kalpsdk.TransactionContextInterface, underlying string, authorizedMSPs []string) (*VaultConfig, error) {
+	config, err := readVaultConfig(sdk)
+	if err == nil && config != nil {
+		return nil, fmt.Errorf("vault is already initialized")
+	}
+	if underlying == "" {
+		return nil, fmt.Errorf("underlying token chaincode must be set")
+	}
+	err = accesscontrol.Initialize(sdk, authorizedMSPs)
+	if err != nil {
+		return nil, fmt.Errorf("client is not authorized to initialize the vault: %v", err)
+	}
+	chaincodeName, err := invokingChaincode(sdk)
+	if err != nil {
+		return nil, err
+	}
+	config = &VaultConfig{underlying, ContractAccountID(chaincodeName, "")}
+	configJSON, err := canonical.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	err = accesscontrol.PutState(sdk, vaultConfigKey, configJSON)
+	if err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// Deposit pulls assets of the underlying token from the caller into the vault and mints the
+// shares of the amount the vault received to receiver. The caller first approves the vault's
+// account (see GetVaultConfig) for assets on the underlying chaincode, which the vault spends with
+// ContractTransferFrom. The underlying may charge a fee on the transfer, so shares are priced on
+// the amount it reports as received rather than on assets.
+func (s *VaultContract) Deposit(sdk kalpsdk.TransactionContextInterface, assets string, receiver string) (string, error) {
+	config, err := vaultConfigHelper(sdk)
+	if err != nil {
+		return "", err
+	}
+	sender, err := sdk.GetUserID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get client id: %v", err)
+	}
+	amount, err := parseAmount(assets)
+	if err != nil {
+		return "", err
+	}
+	if amount.Sign() <= 0 {
+		return "", fmt.Errorf("deposit amount must be a positive integer")
+	}
+	if receiver == "" {
+		receiver = sender
+	}
+
+	payload, err := invokeChaincodeHelper(sdk, config.Underlying, "ContractTransferFrom", config.Account, sender, config.Account, amount.String())
+	if err != nil {
+		return "", err
+	}
+	received, err := parseAmount(string(payload))
+	if err != nil {
+		return "", fmt.Errorf("failed to decode the amount the vault received: %v", err)
+	}
+	// the balance the underlying reports for the vault does not include received yet, as Fabric
+	// reads do not see the writes of the same transaction
+	shares, err := convertToShares(sdk, config, received, false)
+	if err != nil {
+		return "", err
+	}
+	if shares.Sign() == 0 {
+		return "", fmt.Errorf("deposit is too small to mint any shares")
+	}
+	err = updateShares(sdk, receiver, shares)
+	if err != nil {
+		return "", err
+	}
+
+	err = events.Emit(sdk, "Deposit", &VaultDeposit{Sender: sender, Owner: receiver, Assets: received, Shares: shares})
+	if err != nil {
+		return "", err
+	}
+	return shares.String(), nil
+}
+
+// Withdraw burns as many of the caller's shares as needed to pay exactly assets to receiver.
+func (s *VaultContract) Withdraw(sdk kalpsdk.TransactionContextInterface, assets string, receiver string) (string, error) {
+	config, err := vaultConfigHelper(sdk)
+	if err != nil {
+		return "", err
+	}
+	amount, err := parseAmount(assets)
+	if err != nil {
+		return "", err
+	}
+	if amount.Sign() <= 0 {
+		return "", fmt.Errorf("withdraw amount must be a positive integer")
+	}
+	shares, err := convertToShares(sdk, config, amount, true)
+	if err != nil {
+		return "", err
+	}
+	err = withdrawHelper(sdk, config, receiver, amount, shares)
+	if err != nil {
+		return "", err
+	}
+	return shares.String(), nil
+}
+
+// Redeem burns shares of the caller and pays the corresponding assets to receiver.
+func (s *VaultContract) Redeem(sdk kalpsdk.TransactionContextInterface, shares string, receiver string) (string, error) {
+	config, err := vaultConfigHelper(sdk)
+	if err != nil {
+		return "", err
+	}
+	amount, err := parseAmount(shares)
+	if err != nil {
+		return "", err
+	}
+	if amount.Sign() <= 0 {
+		return "", fmt.Errorf("redeem amount must be a positive integer")
+	}
+	assets, err := convertToAssets(sdk, config, amount, false)
+	if err != nil {
+		return "", err
+	}
+	if assets.Sign() == 0 {
+		return "", fmt.Errorf("redeem is too small to pay out any assets")
+	}
+	err = withdrawHelper(sdk, config, receiver, assets, amount)
+	if err != nil {
+		return "", err
+	}
+	return assets.String(), nil
+}
+
+// PreviewDeposit returns the shares Deposit would mint for assets received by the vault, that is
+// after any fee the underlying charges on the transfer.
+func (s *VaultContract) PreviewDeposit(sdk kalpsdk.TransactionContextInterface, assets string) (string, error) {
+	return previewHelper(sdk, assets, func(config *VaultConfig, amount *big.Int) (*big.Int, error) {
+		return convertToShares(sdk, config, amount, false)
+	})
+}
+
+// PreviewWithdraw returns the shares Withdraw would burn for assets.
+func (s *VaultContract) PreviewWithdraw(sdk kalpsdk.TransactionContextInterface, assets string) (string, error) {
+	return previewHelper(sdk, assets, func(config *VaultConfig, amount *big.Int) (*big.Int, error) {
+		return convertToShares(sdk, config, amount, true)
+	})
+}
+
+// PreviewRedeem returns the assets Redeem would pay for shares.
+func (s *VaultContract) PreviewRedeem(sdk kalpsdk.TransactionContextInterface, shares string) (string, error) {
+	return previewHelper(sdk, shares, func(config *VaultConfig, amount *big.Int) (*big.Int, error) {
+		return convertToAssets(sdk, config, amount, false)
+	})
+}
+
+// TotalAssets returns the vault's balance of the underlying token.
+func (s *VaultContract) TotalAssets(sdk kalpsdk.TransactionContextInterface) (string, error) {
+	config, err := vaultConfigHelper(sdk)
+	if err != nil {
+		return "", err
+	}
+	totalAssets, err := vaultTotalAssets(sdk, config)
+	if err != nil {
+		return "", err
+	}
+	return totalAssets.String(), nil
+}
+
+func (s *VaultContract) TotalShares(sdk kalpsdk.TransactionContextInterface) (string, error) {
+	totalShares, err := readAmount(sdk, vaultTotalSharesKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to read total shares: %v", err)
+	}
+	return totalShares.String(), nil
+}
+
+func (s *VaultContract) SharesOf(sdk kalpsdk.TransactionContextInterface, account string) (string, error) {
+	shares, err := readShares(sdk, account)
+	if err != nil {
+		return "", err
+	}
+	return shares.String(), nil
+}
+
+func (s *VaultContract) GetVaultConfig(sdk kalpsdk.TransactionContextInterface) (*VaultConfig, error) {
+	return vaultConfigHelper(sdk)
+}
+
+func withdrawHelper(sdk kalpsdk.TransactionContextInterface, config *VaultConfig, receiver string, assets *big.Int, shares *big.Int) error {
+	owner, err := sdk.GetUserID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+	if receiver == "" {
+		receiver = owner
+	}
+	err = updateShares(sdk, owner, new(big.Int).Neg(shares))
+	if err != nil {
+		return err
+	}
+	_, err = invokeChaincodeHelper(sdk, config.Underlying, "ContractTransfer", config.Account, receiver, assets.String())
+	if err != nil {
+		return err
+	}
+
+	return events.Emit(sdk, "Withdraw", &VaultWithdraw{Sender: owner, Receiver: receiver, Owner: owner, Assets: assets, Shares: shares})
+}
+
+func previewHelper(sdk kalpsdk.TransactionContextInterface, value string, convert func(*VaultConfig, *big.Int) (*big.Int, error)) (string, error) {
+	config, err := vaultConfigHelper(sdk)
+	if err != nil {
+		return "", err
+	}
+	amount, err := parseAmount(value)
+	if err != nil {
+		return "", err
+	}
+	result, err := convert(config, amount)
+	if err != nil {
+		return "", err
+	}
+	return result.String(), nil
+}
+
+// convertToShares and convertToAssets price shares against totalAssets+1 and totalShares+1. The
+// virtual share and asset make the first depositor unable to inflate the share price against
+// later depositors.
+func convertToShares(sdk kalpsdk.TransactionContextInterface, config *VaultConfig, assets *big.Int, roundUp bool) (*big.Int, error) {
+	totalAssets, err := vaultTotalAssets(sdk, config)
+	if err != nil {
+		return nil, err
+	}
+	totalShares, err := readAmount(sdk, vaultTotalSharesKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read total shares: %v", err)
+	}
+	return mulDiv(assets, totalShares.Add(totalShares, big.NewInt(1)), totalAssets.Add(totalAssets, big.NewInt(1)), roundUp), nil
+}
+
+func convertToAssets(sdk kalpsdk.TransactionContextInterface, config *VaultConfig, shares *big.Int, roundUp bool) (*big.Int, error) {
+	totalAssets, err := vaultTotalAssets(sdk, config)
+	if err != nil {
+		return nil, err
+	}
+	totalShares, err := readAmount(sdk, vaultTotalSharesKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read total shares: %v", err)
+	}
+	return mulDiv(shares, totalAssets.Add(totalAssets, big.NewInt(1)), totalShares.Add(totalShares, big.NewInt(1)), roundUp), nil
+}
+
+func mulDiv(x *big.Int, numerator *big.Int, denominator *big.Int, roundUp bool) *big.Int {
+	product := new(big.Int).Mul(x, numerator)
+	quotient, remainder := new(big.Int).QuoRem(product, denominator, new(big.Int))
+	if roundUp && remainder.Sign() > 0 {
+		quotient.Add(quotient, big.NewInt(1))
+	}
+	return quotient
+}
+
+func vaultTotalAssets(sdk kalpsdk.TransactionContextInterface, config *VaultConfig) (*big.Int, error) {
+	payload, err := invokeChaincodeHelper(sdk, config.Underlying, "BalanceOf", config.Account)
+	if err != nil {
+		// The underlying token reports accounts that never held a balance as missing.
+		if strings.Contains(err.Error(), fmt.Sprintf("the account %s does not exist", config.Account)) {
+			return new(big.Int), nil
+		}
+		return nil, err
+	}
+	return parseAmount(string(payload))
+}
+
+// updateShares adds delta (which may be negative) to the shares of account and the total.
+func updateShares(sdk kalpsdk.TransactionContextInterface, account string, delta *big.Int) error {
+	shares, err := readShares(sdk, account)
+	if err != nil {
+		return err
+	}
+	shares.Add(shares, delta)
+	if shares.Sign() < 0 {
+		return kusherrors.Errorf(kusherrors.ErrInsufficientBalance, "account %s has insufficient shares", account)
+	}
+	totalShares, err := readAmount(sdk, vaultTotalSharesKey)
+	if err != nil {
+		return fmt.Errorf("failed to read total shares: %v", err)
+	}
+	totalShares.Add(totalShares, delta)
+
+	sharesKey, err := sdk.CreateCompositeKey(vaultSharesPrefix, []string{account})
+	if err != nil {
+		return fmt.Errorf("failed to create the composite key for prefix %s: %v", vaultSharesPrefix, err)
+	}
+	err = writeAmount(sdk, sharesKey, shares)
+	if err != nil {
+		return err
+	}
+	return writeAmount(sdk, vaultTotalSharesKey, totalShares)
+}
+
+func readShares(sdk kalpsdk.TransactionContextInterface, account string) (*big.Int, error) {
+	sharesKey, err := sdk.CreateCompositeKey(vaultSharesPrefix, []string{account})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the composite key for prefix %s: %v", vaultSharesPrefix, err)
+	}
+	shares, err := readAmount(sdk, sharesKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read shares of %s: %v", account, err)
+	}
+	return shares, nil
+}
+
+func readVaultConfig(sdk kalpsdk.TransactionContextInterface) (*VaultConfig, error) {
+	configBytes, err := sdk.GetState(vaultConfigKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read vault config: %v", err)
+	}
+	if configBytes == nil {
+		return nil, nil
+	}
+	config := new(VaultConfig)
+	err = json.Unmarshal(configBytes, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode vault config: %v", err)
+	}
+	return config, nil
+}
+
+func vaultConfigHelper(sdk kalpsdk.TransactionContextInterface) (*VaultConfig, error) {
+	config, err := readVaultConfig(sdk)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "vault is not initialized, call Initialize() first")
+	}
+	return config, nil
+}
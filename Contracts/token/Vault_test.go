@@ -0,0 +1,183 @@
+package token
+
+import (
+	"testing"
+
+	"github.com/thekalpstudio/kush-go/events"
+	"github.com/thekalpstudio/kush-go/testutil"
+)
+
+// newVault returns a vault over the token of newERC20, served as chaincode "token" to the vault
+// chaincode "vault", with the vault approved for the whole balance of admin.
+func newVault(t *testing.T) (*testutil.Ledger, *TokenERC20Contract, *testutil.Ledger, *VaultContract, *VaultConfig) {
+	t.Helper()
+	l, c := newERC20(t)
+	vl := testutil.NewLedger()
+	vl.SetChaincode("vault")
+	vl.RegisterContract("token", l, serveERC20(c))
+	v := new(VaultContract)
+	var config *VaultConfig
+	testutil.MustRun(t, vl, admin, "Initialize", func(ctx *testutil.Context) error {
+		var err error
+		config, err = v.Initialize(ctx, "token", []string{testutil.MSPID})
+		return err
+	})
+	testutil.MustRun(t, l, admin, "Approve", func(ctx *testutil.Context) error {
+		return c.Approve(ctx, config.Account, "1000")
+	})
+	return l, c, vl, v, config
+}
+
+func vaultShares(t *testing.T, vl *testutil.Ledger, v *VaultContract, account string) string {
+	t.Helper()
+	shares, err := v.SharesOf(vl.Tx(admin, "SharesOf"), account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return shares
+}
+
+func TestVaultDeposit(t *testing.T) {
+	tests := []struct {
+		name       string
+		donate     string
+		fee        bool
+		assets     string
+		receiver   string
+		wantErr    bool
+		wantShares string
+		wantAssets string
+		wantSender string
+	}{
+		{name: "into an empty vault", assets: "400", wantShares: "400", wantAssets: "400", wantSender: "600"},
+		{name: "to another receiver", assets: "400", receiver: "bob", wantShares: "400", wantAssets: "400", wantSender: "600"},
+		{name: "net of the transfer fee", fee: true, assets: "400", wantShares: "396", wantAssets: "396", wantSender: "600"},
+		{name: "after a donation", donate: "100", assets: "400", wantShares: "3", wantAssets: "500", wantSender: "500"},
+		{name: "above the allowance", assets: "1001", wantErr: true},
+		{name: "zero", assets: "0", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, c, vl, v, config := newVault(t)
+			if tt.fee {
+				testutil.MustRun(t, l, admin, "SetTransferFee", func(ctx *testutil.Context) error {
+					return c.SetTransferFee(ctx, 100, "fees")
+				})
+			}
+			if tt.donate != "" {
+				testutil.MustRun(t, l, admin, "Transfer", func(ctx *testutil.Context) error {
+					return c.Transfer(ctx, config.Account, tt.donate)
+				})
+			}
+
+			var shares string
+			err := testutil.Run(vl, admin, "Deposit", func(ctx *testutil.Context) error {
+				var err error
+				shares, err = v.Deposit(ctx, tt.assets, tt.receiver)
+				return err
+			})
+			testutil.CheckErr(t, err, tt.wantErr, "")
+			if tt.wantErr {
+				if balance := erc20Balance(t, l, c, admin); balance != "1000" {
+					t.Fatalf("failed deposit changed the sender balance to %s", balance)
+				}
+				return
+			}
+
+			receiver := tt.receiver
+			if receiver == "" {
+				receiver = admin
+			}
+			if shares != tt.wantShares {
+				t.Fatalf("deposit minted %s shares, want %s", shares, tt.wantShares)
+			}
+			if held := vaultShares(t, vl, v, receiver); held != tt.wantShares {
+				t.Fatalf("receiver holds %s shares, want %s", held, tt.wantShares)
+			}
+			if balance := erc20Balance(t, l, c, admin); balance != tt.wantSender {
+				t.Fatalf("sender balance is %s, want %s", balance, tt.wantSender)
+			}
+			if balance := erc20Balance(t, l, c, config.Account); balance != tt.wantAssets {
+				t.Fatalf("vault balance is %s, want %s", balance, tt.wantAssets)
+			}
+			deposit := testutil.LastEvent(t, vl, "Deposit").(*events.VaultDeposit)
+			if deposit.Sender != admin || deposit.Owner != receiver || deposit.Shares.String() != tt.wantShares {
+				t.Fatalf("event is %+v", deposit)
+			}
+		})
+	}
+}
+
+func TestVaultWithdraw(t *testing.T) {
+	tests := []struct {
+		name        string
+		user        string
+		redeem      bool
+		amount      string
+		receiver    string
+		wantErr     bool
+		wantAssets  string
+		wantShares  string
+		wantReceive string
+	}{
+		{name: "withdraw", user: admin, amount: "100", wantAssets: "100", wantShares: "300", wantReceive: "700"},
+		{name: "withdraw to another receiver", user: admin, amount: "100", receiver: "bob", wantAssets: "100", wantShares: "300", wantReceive: "100"},
+		{name: "redeem", user: admin, redeem: true, amount: "100", wantAssets: "100", wantShares: "300", wantReceive: "700"},
+		{name: "redeem every share", user: admin, redeem: true, amount: "400", wantAssets: "400", wantShares: "0", wantReceive: "1000"},
+		{name: "withdraw more than deposited", user: admin, amount: "401", wantErr: true},
+		{name: "redeem without shares", user: "bob", redeem: true, amount: "1", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, c, vl, v, config := newVault(t)
+			testutil.MustRun(t, vl, admin, "Deposit", func(ctx *testutil.Context) error {
+				_, err := v.Deposit(ctx, "400", "")
+				return err
+			})
+
+			function := "Withdraw"
+			if tt.redeem {
+				function = "Redeem"
+			}
+			var returned string
+			err := testutil.Run(vl, tt.user, function, func(ctx *testutil.Context) error {
+				var err error
+				if tt.redeem {
+					returned, err = v.Redeem(ctx, tt.amount, tt.receiver)
+				} else {
+					returned, err = v.Withdraw(ctx, tt.amount, tt.receiver)
+				}
+				return err
+			})
+			testutil.CheckErr(t, err, tt.wantErr, "")
+			if tt.wantErr {
+				if balance := erc20Balance(t, l, c, config.Account); balance != "400" {
+					t.Fatalf("failed %s changed the vault balance to %s", function, balance)
+				}
+				return
+			}
+
+			receiver := tt.receiver
+			if receiver == "" {
+				receiver = tt.user
+			}
+			burned := tt.amount
+			if !tt.redeem {
+				burned = returned
+			}
+			if tt.redeem && returned != tt.wantAssets {
+				t.Fatalf("%s paid %s, want %s", function, returned, tt.wantAssets)
+			}
+			if held := vaultShares(t, vl, v, tt.user); held != tt.wantShares {
+				t.Fatalf("owner holds %s shares, want %s", held, tt.wantShares)
+			}
+			if balance := erc20Balance(t, l, c, receiver); balance != tt.wantReceive {
+				t.Fatalf("receiver balance is %s, want %s", balance, tt.wantReceive)
+			}
+			withdraw := testutil.LastEvent(t, vl, "Withdraw").(*events.VaultWithdraw)
+			if withdraw.Owner != tt.user || withdraw.Receiver != receiver || withdraw.Assets.String() != tt.wantAssets || withdraw.Shares.String() != burned {
+				t.Fatalf("event is %+v", withdraw)
+			}
+		})
+	}
+}
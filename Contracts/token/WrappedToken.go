@@ -0,0 +1,159 @@
+package token
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+	"github.com/thekalpstudio/kush-go/canonical"
+	"github.com/thekalpstudio/kush-go/config"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+const wrappedConfigKey = "wrapped~config"
+
+// WrappedTokenContract is a full TokenERC20Contract whose supply is backed 1:1 by an underlying
+// token locked in the wrapper's contract account. Wrapping gives tokens deployed without the newer
+// extensions (pausing, snapshots, fees, ...) access to them. The underlying chaincode only has to
+// implement ContractTransferFrom and ContractTransfer.
+type WrappedTokenContract struct {
+	TokenERC20Contract
+}
+
+// WrappedConfig names the underlying token chaincode and the wrapper's account on it.
+type WrappedConfig struct {
+	Underlying string `json:"underlying"`
+	Account    string `json:"account"`
+}
+
+// InitializeWrapper initializes the wrapper token from cfg and binds it to the underlying chaincode.
+func (c *WrappedTokenContract) InitializeWrapper(ctx kalpsdk.TransactionContextInterface, underlying string, cfg config.InitConfig) (*WrappedConfig, error) {
+	if underlying == "" {
+		return nil, fmt.Errorf("underlying token chaincode must be set")
+	}
+	_, err := c.Initialize(ctx, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	chaincodeName, err := invokingChaincode(ctx)
+	if err != nil {
+		return nil, err
+	}
+	config := &WrappedConfig{underlying, ContractAccountID(chaincodeName, "")}
+	configJSON, err := canonical.Marshal(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	err = accesscontrol.PutState(ctx, wrappedConfigKey, configJSON)
+	if err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// DepositFor locks amount of the caller's underlying tokens and mints as many wrapped tokens to
+// account as the wrapper received. The caller first approves the wrapper's account (see
+// GetWrappedConfig) for amount on the underlying chaincode. An underlying charging a fee on
+// transfers delivers less than amount, and minting more than it delivered would leave the wrapped
+// supply unbacked.
+func (c *WrappedTokenContract) DepositFor(ctx kalpsdk.TransactionContextInterface, account string, amount string) error {
+	config, err := wrappedConfigHelper(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = checkNotPaused(ctx)
+	if err != nil {
+		return err
+	}
+
+	if account == "" || account == "0x0" {
+		return fmt.Errorf("deposit for the zero address")
+	}
+	value, err := parseAmount(amount)
+	if err != nil {
+		return err
+	}
+	if value.Sign() <= 0 {
+		return fmt.Errorf("deposit amount must be a positive integer")
+	}
+
+	sender, err := ctx.GetUserID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+	payload, err := invokeChaincodeHelper(ctx, config.Underlying, "ContractTransferFrom", config.Account, sender, config.Account, value.String())
+	if err != nil {
+		return err
+	}
+	received, err := parseAmount(string(payload))
+	if err != nil {
+		return fmt.Errorf("failed to decode the amount the wrapper received: %v", err)
+	}
+	if received.Sign() == 0 {
+		return fmt.Errorf("deposit is too small to mint any wrapped tokens")
+	}
+	return _mint(ctx, account, received)
+}
+
+// WithdrawTo burns amount of the caller's wrapped tokens and releases the same amount of the
+// underlying token to account.
+func (c *WrappedTokenContract) WithdrawTo(ctx kalpsdk.TransactionContextInterface, account string, amount string) error {
+	config, err := wrappedConfigHelper(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = checkNotPaused(ctx)
+	if err != nil {
+		return err
+	}
+
+	owner, err := ctx.GetUserID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+	err = checkNotFrozen(ctx, owner)
+	if err != nil {
+		return err
+	}
+	if account == "" || account == "0x0" {
+		return fmt.Errorf("withdraw to the zero address")
+	}
+	value, err := parseAmount(amount)
+	if err != nil {
+		return err
+	}
+	if value.Sign() <= 0 {
+		return fmt.Errorf("withdraw amount must be a positive integer")
+	}
+
+	err = _burn(ctx, owner, value)
+	if err != nil {
+		return err
+	}
+	_, err = invokeChaincodeHelper(ctx, config.Underlying, "ContractTransfer", config.Account, account, value.String())
+	return err
+}
+
+func (c *WrappedTokenContract) GetWrappedConfig(ctx kalpsdk.TransactionContextInterface) (*WrappedConfig, error) {
+	return wrappedConfigHelper(ctx)
+}
+
+func wrappedConfigHelper(ctx kalpsdk.TransactionContextInterface) (*WrappedConfig, error) {
+	configBytes, err := ctx.GetState(wrappedConfigKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read wrapper config: %v", err)
+	}
+	if configBytes == nil {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "wrapper is not initialized, call InitializeWrapper() first")
+	}
+	config := new(WrappedConfig)
+	err = json.Unmarshal(configBytes, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode wrapper config: %v", err)
+	}
+	return config, nil
+}
@@ -0,0 +1,97 @@
+package token
+
+import (
+	"testing"
+
+	"github.com/thekalpstudio/kush-go/config"
+	"github.com/thekalpstudio/kush-go/events"
+	"github.com/thekalpstudio/kush-go/testutil"
+)
+
+// newWrappedToken returns a wrapper over the token of newERC20, served as chaincode "token" to the
+// wrapper chaincode "wrapped", with the wrapper approved for the whole balance of admin.
+func newWrappedToken(t *testing.T) (*testutil.Ledger, *TokenERC20Contract, *testutil.Ledger, *WrappedTokenContract, *WrappedConfig) {
+	t.Helper()
+	l, c := newERC20(t)
+	wl := testutil.NewLedger()
+	wl.SetChaincode("wrapped")
+	wl.RegisterContract("token", l, serveERC20(c))
+	w := new(WrappedTokenContract)
+	var wrapped *WrappedConfig
+	testutil.MustRun(t, wl, admin, "InitializeWrapper", func(ctx *testutil.Context) error {
+		var err error
+		wrapped, err = w.InitializeWrapper(ctx, "token", config.InitConfig{Name: "Wrapped Test", Symbol: "wTST", AuthorizedOrgs: []string{testutil.MSPID}})
+		return err
+	})
+	testutil.MustRun(t, l, admin, "Approve", func(ctx *testutil.Context) error {
+		return c.Approve(ctx, wrapped.Account, "1000")
+	})
+	return l, c, wl, w, wrapped
+}
+
+func TestWrappedToken(t *testing.T) {
+	tests := []struct {
+		name           string
+		fee            bool
+		deposit        string
+		withdraw       string
+		wantErr        bool
+		wantWrapped    string
+		wantUnderlying string
+		wantLocked     string
+	}{
+		{name: "deposit", deposit: "400", wantWrapped: "400", wantUnderlying: "600", wantLocked: "400"},
+		{name: "deposit net of the underlying fee", fee: true, deposit: "400", wantWrapped: "396", wantUnderlying: "600", wantLocked: "396"},
+		{name: "deposit above the allowance", deposit: "1001", wantErr: true},
+		{name: "withdraw", deposit: "400", withdraw: "100", wantWrapped: "300", wantUnderlying: "700", wantLocked: "300"},
+		{name: "withdraw every wrapped token", deposit: "400", withdraw: "400", wantWrapped: "0", wantUnderlying: "1000", wantLocked: "0"},
+		{name: "withdraw more than wrapped", deposit: "400", withdraw: "401", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, c, wl, w, wrapped := newWrappedToken(t)
+			if tt.fee {
+				testutil.MustRun(t, l, admin, "SetTransferFee", func(ctx *testutil.Context) error {
+					return c.SetTransferFee(ctx, 100, "fees")
+				})
+			}
+
+			err := testutil.Run(wl, admin, "DepositFor", func(ctx *testutil.Context) error {
+				return w.DepositFor(ctx, admin, tt.deposit)
+			})
+			if tt.withdraw != "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				err = testutil.Run(wl, admin, "WithdrawTo", func(ctx *testutil.Context) error {
+					return w.WithdrawTo(ctx, admin, tt.withdraw)
+				})
+			}
+			testutil.CheckErr(t, err, tt.wantErr, "")
+			if tt.wantErr {
+				return
+			}
+
+			if balance := erc20Balance(t, wl, &w.TokenERC20Contract, admin); balance != tt.wantWrapped {
+				t.Fatalf("wrapped balance is %s, want %s", balance, tt.wantWrapped)
+			}
+			supply, err := w.TotalSupply(wl.Tx(admin, "TotalSupply"))
+			if err != nil || supply != tt.wantLocked {
+				t.Fatalf("wrapped supply is %s: %v, want the %s locked", supply, err, tt.wantLocked)
+			}
+			if balance := erc20Balance(t, l, c, admin); balance != tt.wantUnderlying {
+				t.Fatalf("underlying balance is %s, want %s", balance, tt.wantUnderlying)
+			}
+			if balance := erc20Balance(t, l, c, wrapped.Account); balance != tt.wantLocked {
+				t.Fatalf("locked balance is %s, want %s", balance, tt.wantLocked)
+			}
+			transfer := testutil.LastEvent(t, wl, "Transfer").(*events.Transfer)
+			if tt.withdraw == "" && (transfer.From != "0x0" || transfer.To != admin || transfer.Value.String() != tt.wantWrapped) {
+				t.Fatalf("event is %+v", transfer)
+			}
+			if tt.withdraw != "" && (transfer.From != admin || transfer.To != "0x0" || transfer.Value.String() != tt.withdraw) {
+				t.Fatalf("event is %+v", transfer)
+			}
+		})
+	}
+}
@@ -0,0 +1,242 @@
+package token
+
+import (
+	"encoding/binary"
+	"math"
+	"math/big"
+	"math/bits"
+	"strconv"
+	"testing"
+
+	"github.com/thekalpstudio/kush-go/testutil"
+)
+
+func FuzzAddSub(f *testing.F) {
+	f.Add("0", "0")
+	f.Add("1", "0")
+	f.Add("5", "7")
+	f.Add("115792089237316195423570985008687907853269984665640564039457584007913129639935", "1")
+	f.Fuzz(func(t *testing.T, a string, b string) {
+		x, err := parseAmount(a)
+		if err != nil {
+			return
+		}
+		y, err := parseAmount(b)
+		if err != nil {
+			return
+		}
+
+		sum, err := add(x, y)
+		if err != nil || sum.Cmp(new(big.Int).Add(x, y)) != 0 {
+			t.Fatalf("add(%s, %s) = %v, %v", x, y, sum, err)
+		}
+		diff, err := sub(x, y)
+		if y.Sign() <= 0 || x.Cmp(y) < 0 {
+			if err == nil {
+				t.Fatalf("sub(%s, %s) = %s, want an error", x, y, diff)
+			}
+			return
+		}
+		if err != nil || new(big.Int).Add(diff, y).Cmp(x) != 0 {
+			t.Fatalf("sub(%s, %s) = %v, %v", x, y, diff, err)
+		}
+	})
+}
+
+func FuzzAdd1Sub1(f *testing.F) {
+	f.Add(uint64(0), uint64(0))
+	f.Add(uint64(math.MaxUint64), uint64(1))
+	f.Add(uint64(1), uint64(2))
+	f.Add(uint64(1<<63), uint64(1<<63))
+	f.Fuzz(func(t *testing.T, a uint64, b uint64) {
+		sum, carry := bits.Add64(a, b, 0)
+		got, err := add1(a, b)
+		if (err != nil) != (carry != 0) || (err == nil && got != sum) {
+			t.Fatalf("add1(%d, %d) = %d, %v", a, b, got, err)
+		}
+		got, err = sub1(a, b)
+		if (err != nil) != (b > a) || (err == nil && got != a-b) {
+			t.Fatalf("sub1(%d, %d) = %d, %v", a, b, got, err)
+		}
+	})
+}
+
+func FuzzParseAmount(f *testing.F) {
+	for _, seed := range []string{"0", "100", "-1", "1.5", "1e18", "0x10", "+7", " 1", "007", ""} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, amount string) {
+		value, err := parseAmount(amount)
+		if err != nil {
+			return
+		}
+		if value.Sign() < 0 {
+			t.Fatalf("parseAmount(%q) = %s, a negative amount", amount, value)
+		}
+		// amounts are stored in their canonical form and must read back as they were parsed
+		stored, err := decodeAmount([]byte(value.String()))
+		if err != nil || stored.Cmp(value) != 0 {
+			t.Fatalf("amount %s read back as %v, %v", value, stored, err)
+		}
+	})
+}
+
+func FuzzDecodeBalance(f *testing.F) {
+	f.Add([]byte{})
+	f.Add([]byte("42"))
+	f.Add(encodeBalance1(math.MaxUint64))
+	f.Add([]byte{balanceEncodingV1, 1, 2})
+	f.Add([]byte("18446744073709551616"))
+	f.Fuzz(func(t *testing.T, value []byte) {
+		balance, err := decodeBalance1(value)
+		if err != nil {
+			return
+		}
+		if len(value) > 0 && value[0] == balanceEncodingV1 {
+			if len(value) != 9 || binary.BigEndian.Uint64(value[1:]) != balance {
+				t.Fatalf("%x decoded to %d", value, balance)
+			}
+		} else if strconv.FormatUint(balance, 10) != string(value) && value[0] != '0' {
+			t.Fatalf("legacy balance %q decoded to %d", value, balance)
+		}
+		again, err := decodeBalance1(encodeBalance1(balance))
+		if err != nil || again != balance {
+			t.Fatalf("balance %d encoded and decoded to %d, %v", balance, again, err)
+		}
+	})
+}
+
+// FuzzERC1155BatchTransferFrom transfers the amounts packed in amountBytes, 8 bytes each, of the
+// ids in idBytes, one byte each, from admin to bob. Admin holds 100 of type 1, 50 of type 2 and 2^63
+// of type 3, of which bob holds 2^63-1, so the supply of type 3 is the largest balance there is; a
+// transfer must either fail or move exactly the summed amounts.
+func FuzzERC1155BatchTransferFrom(f *testing.F) {
+	f.Add([]byte{1, 2}, binary.BigEndian.AppendUint64(binary.BigEndian.AppendUint64(nil, 10), 20))
+	f.Add([]byte{1, 1}, binary.BigEndian.AppendUint64(binary.BigEndian.AppendUint64(nil, 60), 60))
+	f.Add([]byte{3}, binary.BigEndian.AppendUint64(nil, 1<<63))
+	f.Add([]byte{1, 1}, binary.BigEndian.AppendUint64(binary.BigEndian.AppendUint64(nil, math.MaxUint64), 2))
+	f.Add([]byte{1, 2}, binary.BigEndian.AppendUint64(nil, 1))
+	f.Fuzz(func(t *testing.T, idBytes []byte, amountBytes []byte) {
+		if len(idBytes) > 16 {
+			return
+		}
+		ids := make([]uint64, len(idBytes))
+		for i, id := range idBytes {
+			ids[i] = uint64(id % 5)
+		}
+		amounts := make([]uint64, len(amountBytes)/8)
+		for i := range amounts {
+			amounts[i] = binary.BigEndian.Uint64(amountBytes[8*i:])
+		}
+
+		l, s := newERC1155(t)
+		testutil.MustRun(t, l, admin, "Mint", func(ctx *testutil.Context) error {
+			return s.Mint(ctx, testutil.ClientID(admin), 3, 1<<63)
+		})
+		testutil.MustRun(t, l, admin, "Mint", func(ctx *testutil.Context) error {
+			return s.Mint(ctx, testutil.ClientID("bob"), 3, 1<<63-1)
+		})
+		typeIDs := []uint64{0, 1, 2, 3, 4}
+		before := map[string][]uint64{}
+		for _, user := range []string{admin, "bob"} {
+			for _, id := range typeIDs {
+				before[user] = append(before[user], erc1155Balance(t, l, s, user, id))
+			}
+		}
+
+		err := testutil.Run(l, admin, "BatchTransferFrom", func(ctx *testutil.Context) error {
+			return s.BatchTransferFrom(ctx, testutil.ClientID(admin), testutil.ClientID("bob"), ids, amounts)
+		})
+
+		moved := make([]*big.Int, len(typeIDs))
+		for i := range moved {
+			moved[i] = new(big.Int)
+		}
+		valid := len(ids) == len(amounts)
+		for i := 0; valid && i < len(ids); i++ {
+			moved[ids[i]].Add(moved[ids[i]], new(big.Int).SetUint64(amounts[i]))
+		}
+		for _, id := range typeIDs {
+			held := new(big.Int).SetUint64(before[admin][id])
+			received := new(big.Int).Add(new(big.Int).SetUint64(before["bob"][id]), moved[id])
+			if moved[id].Cmp(held) > 0 || !received.IsUint64() {
+				valid = false
+			}
+		}
+		if err != nil {
+			for _, user := range []string{admin, "bob"} {
+				for _, id := range typeIDs {
+					if balance := erc1155Balance(t, l, s, user, id); balance != before[user][id] {
+						t.Fatalf("failed transfer changed the balance of %s in token %d from %d to %d", user, id, before[user][id], balance)
+					}
+				}
+			}
+			return
+		}
+		if !valid {
+			t.Fatalf("transfer of %v %v succeeded", ids, amounts)
+		}
+		for _, id := range typeIDs {
+			want := before[admin][id] - moved[id].Uint64()
+			if balance := erc1155Balance(t, l, s, admin, id); balance != want {
+				t.Fatalf("sender balance of token %d is %d, want %d", id, balance, want)
+			}
+			want = before["bob"][id] + moved[id].Uint64()
+			if balance := erc1155Balance(t, l, s, "bob", id); balance != want {
+				t.Fatalf("recipient balance of token %d is %d, want %d", id, balance, want)
+			}
+		}
+	})
+}
+
+// FuzzERC20TransferRecipient transfers to arbitrary account IDs, which ERC20 uses as the keys of
+// their balances: a transfer must never write other state than the balances, whatever the ID.
+func FuzzERC20TransferRecipient(f *testing.F) {
+	for _, seed := range []string{"bob", totalSupplyKey, decimalsKey, pausedKey, "role~bootstrapped", "balance~delta", "\x00role\x00ADMIN_ROLE\x00bob\x00", "bob\x00", "\xff", admin} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, recipient string) {
+		l, c := newERC20(t)
+		protected := map[string][]byte{}
+		for _, key := range l.Keys() {
+			if key != admin {
+				protected[key] = l.State(key)
+			}
+		}
+
+		err := testutil.Run(l, admin, "Transfer", func(ctx *testutil.Context) error {
+			return c.Transfer(ctx, recipient, "10")
+		})
+		if err != nil {
+			if l.State(admin) == nil || string(l.State(admin)) != "1000" {
+				t.Fatalf("failed transfer changed the sender balance to %q", l.State(admin))
+			}
+			return
+		}
+		if l.State(admin) == nil || string(l.State(admin)) != "990" {
+			t.Fatalf("sender balance is %q, want 990", l.State(admin))
+		}
+		if string(l.State(recipient)) != "10" {
+			t.Fatalf("recipient %q balance is %q, want 10", recipient, l.State(recipient))
+		}
+		for key, value := range protected {
+			// metrics and checkpoints of the accounts change with every transfer
+			if key == recipient || isAccountBookkeeping(key) {
+				continue
+			}
+			if string(l.State(key)) != string(value) {
+				t.Fatalf("transfer to %q changed %q from %q to %q", recipient, key, value, l.State(key))
+			}
+		}
+	})
+}
+
+// isAccountBookkeeping reports whether key is one of the composite keys a transfer updates next to
+// the balances.
+func isAccountBookkeeping(key string) bool {
+	return len(key) > 0 && key[0] == 0 && !hasObjectType(key, "role")
+}
+
+func hasObjectType(key string, objectType string) bool {
+	return len(key) > len(objectType)+1 && key[1:len(objectType)+2] == objectType+"\x00"
+}
@@ -0,0 +1,38 @@
+package token
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/thekalpstudio/kush-go/config"
+	"github.com/thekalpstudio/kush-go/testutil"
+)
+
+const admin = "admin"
+
+func testConfig() config.InitConfig {
+	return config.InitConfig{Name: "Test", Symbol: "TST", AuthorizedOrgs: []string{testutil.MSPID}}
+}
+
+// serveERC20 answers the calls the contracts holding tokens in a contract account make to c.
+func serveERC20(c *TokenERC20Contract) testutil.Contract {
+	return func(ctx *testutil.Context, args [][]byte) peer.Response {
+		var payload string
+		var err error
+		switch function := string(args[0]); function {
+		case "ContractTransfer":
+			err = c.ContractTransfer(ctx, string(args[1]), string(args[2]), string(args[3]))
+		case "ContractTransferFrom":
+			payload, err = c.ContractTransferFrom(ctx, string(args[1]), string(args[2]), string(args[3]), string(args[4]))
+		case "BalanceOf":
+			payload, err = c.BalanceOf(ctx, string(args[1]))
+		default:
+			err = fmt.Errorf("function %s is not served", function)
+		}
+		if err != nil {
+			return shim.Error(err.Error())
+		}
+		return shim.Success([]byte(payload))
+	}
+}
@@ -0,0 +1,257 @@
+package token
+
+import (
+	"fmt"
+	"math/big"
+	"math/rand"
+	"strconv"
+	"testing"
+
+	"github.com/thekalpstudio/kush-go/events"
+	"github.com/thekalpstudio/kush-go/testutil"
+)
+
+// invariantSeeds seed the random operation sequences; a failure names its seed and step so it can
+// be replayed.
+var invariantSeeds = []int64{1, 2, 3, 4, 5, 6, 7, 8}
+
+const invariantSteps = 150
+
+var invariantUsers = []string{admin, "alice", "bob", "carol"}
+
+// replayERC20 rebuilds the balances from the Transfer events of l.
+func replayERC20(t *testing.T, l *testutil.Ledger) map[string]*big.Int {
+	t.Helper()
+	balances := map[string]*big.Int{}
+	credit := func(account string, amount *big.Int) {
+		if balances[account] == nil {
+			balances[account] = new(big.Int)
+		}
+		balances[account].Add(balances[account], amount)
+	}
+	for _, event := range l.Events() {
+		if event.Name != "Transfer" {
+			continue
+		}
+		_, value, err := events.Decode(event.Name, event.Payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		transfer := value.(*events.Transfer)
+		credit(transfer.From, new(big.Int).Neg(transfer.Value))
+		credit(transfer.To, transfer.Value)
+	}
+	return balances
+}
+
+func TestERC20Invariants(t *testing.T) {
+	for _, seed := range invariantSeeds {
+		t.Run("seed="+strconv.FormatInt(seed, 10), func(t *testing.T) {
+			r := rand.New(rand.NewSource(seed))
+			l, c := newERC20(t)
+			user := func() string { return invariantUsers[r.Intn(len(invariantUsers))] }
+			amount := func() string { return strconv.Itoa(r.Intn(300)) }
+
+			for step := 0; step < invariantSteps; step++ {
+				// most operations are ones the caller may perform, so that the sequences move tokens
+				// rather than fail on the first check
+				caller := user()
+				var op string
+				var fn func(ctx *testutil.Context) error
+				switch r.Intn(6) {
+				case 0:
+					op = "Mint"
+					caller = admin
+					fn = func(ctx *testutil.Context) error { return c.Mint(ctx, amount()) }
+				case 1:
+					op = "Burn"
+					fn = func(ctx *testutil.Context) error { return c.Burn(ctx, amount()) }
+				case 2, 3:
+					op = "Transfer"
+					fn = func(ctx *testutil.Context) error { return c.Transfer(ctx, user(), amount()) }
+				case 4:
+					op = "Approve"
+					fn = func(ctx *testutil.Context) error { return c.Approve(ctx, user(), amount()) }
+				case 5:
+					op = "TransferFrom"
+					fn = func(ctx *testutil.Context) error { return c.TransferFrom(ctx, user(), user(), amount()) }
+				}
+				_ = testutil.Run(l, caller, op, fn)
+
+				ctx := l.Tx(admin, "BalanceOf")
+				supply, err := c.TotalSupply(ctx)
+				if err != nil {
+					t.Fatal(err)
+				}
+				sum := new(big.Int)
+				replayed := replayERC20(t, l)
+				for _, account := range invariantUsers {
+					// lookupBalance, unlike BalanceOf, reads an account that never held tokens as zero
+					balance, _, err := lookupBalance(ctx, account)
+					if err != nil {
+						t.Fatal(err)
+					}
+					sum.Add(sum, balance)
+					if replayed[account] == nil {
+						replayed[account] = new(big.Int)
+					}
+					if replayed[account].Cmp(balance) != 0 {
+						t.Fatalf("step %d (%s): balance of %s is %s, its Transfer events add up to %s", step, op, account, balance, replayed[account])
+					}
+				}
+				if sum.String() != supply {
+					t.Fatalf("step %d (%s): balances add up to %s, total supply is %s", step, op, sum, supply)
+				}
+			}
+		})
+	}
+}
+
+// replayERC1155 rebuilds the balances, by account and token type, from the TransferSingle and
+// TransferBatch events of l.
+func replayERC1155(t *testing.T, l *testutil.Ledger) map[string]map[uint64]int64 {
+	t.Helper()
+	balances := map[string]map[uint64]int64{}
+	move := func(from string, to string, id uint64, value uint64) {
+		for account, delta := range map[string]int64{from: -int64(value), to: int64(value)} {
+			if balances[account] == nil {
+				balances[account] = map[uint64]int64{}
+			}
+			balances[account][id] += delta
+		}
+	}
+	for _, event := range l.Events() {
+		if event.Name != "TransferSingle" && event.Name != "TransferBatch" {
+			continue
+		}
+		_, value, err := events.Decode(event.Name, event.Payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch transfer := value.(type) {
+		case *events.TransferSingle:
+			move(transfer.From, transfer.To, transfer.ID, transfer.Value)
+		case *events.TransferBatch:
+			for i, id := range transfer.IDs {
+				move(transfer.From, transfer.To, id, transfer.Values[i])
+			}
+		}
+	}
+	return balances
+}
+
+func TestERC1155Invariants(t *testing.T) {
+	ids := []uint64{1, 2, 3}
+	for _, seed := range invariantSeeds {
+		t.Run("seed="+strconv.FormatInt(seed, 10), func(t *testing.T) {
+			r := rand.New(rand.NewSource(seed))
+			l, s := newERC1155(t)
+			user := func() string { return invariantUsers[r.Intn(len(invariantUsers))] }
+			account := func() string { return testutil.ClientID(user()) }
+			id := func() uint64 { return ids[r.Intn(len(ids))] }
+			amount := func() uint64 { return uint64(r.Intn(60)) }
+			batch := func() ([]uint64, []uint64) {
+				n := 1 + r.Intn(4)
+				batchIDs, amounts := make([]uint64, n), make([]uint64, n)
+				for i := range batchIDs {
+					batchIDs[i], amounts[i] = id(), amount()
+				}
+				return batchIDs, amounts
+			}
+
+			for step := 0; step < invariantSteps; step++ {
+				// the caller mostly moves its own tokens, or those of an account that may have made it
+				// an operator
+				caller := user()
+				from := func() string {
+					if r.Intn(4) == 0 {
+						return account()
+					}
+					return testutil.ClientID(caller)
+				}
+				var op string
+				var fn func(ctx *testutil.Context) error
+				switch r.Intn(7) {
+				case 0:
+					op = "Mint"
+					caller = admin
+					fn = func(ctx *testutil.Context) error { return s.Mint(ctx, account(), id(), amount()) }
+				case 1:
+					op = "MintBatch"
+					caller = admin
+					fn = func(ctx *testutil.Context) error {
+						batchIDs, amounts := batch()
+						return s.MintBatch(ctx, account(), batchIDs, amounts)
+					}
+				case 2:
+					op = "Burn"
+					fn = func(ctx *testutil.Context) error { return s.Burn(ctx, from(), id(), amount()) }
+				case 3:
+					op = "TransferFrom"
+					fn = func(ctx *testutil.Context) error { return s.TransferFrom(ctx, from(), account(), id(), amount()) }
+				case 4, 5:
+					op = "BatchTransferFrom"
+					fn = func(ctx *testutil.Context) error {
+						batchIDs, amounts := batch()
+						return s.BatchTransferFrom(ctx, from(), account(), batchIDs, amounts)
+					}
+				case 6:
+					op = "SetApprovalForAll"
+					fn = func(ctx *testutil.Context) error { return s.SetApprovalForAll(ctx, account(), r.Intn(2) == 0) }
+				}
+				_ = testutil.Run(l, caller, op, fn)
+
+				checkERC1155Invariants(t, l, s, ids, fmt.Sprintf("step %d (%s)", step, op))
+			}
+		})
+	}
+}
+
+func checkERC1155Invariants(t *testing.T, l *testutil.Ledger, s *SmartContract, ids []uint64, at string) {
+	t.Helper()
+	ctx := l.Tx(admin, "BalanceOf")
+	replayed := replayERC1155(t, l)
+	for _, id := range ids {
+		supply, err := s.TotalSupply(ctx, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var sum uint64
+		for _, user := range invariantUsers {
+			balance, err := s.BalanceOf(ctx, testutil.ClientID(user), id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sum += balance
+			if replayed[testutil.ClientID(user)][id] != int64(balance) {
+				t.Fatalf("%s: balance of %s in token %d is %d, its events add up to %d", at, user, id, balance, replayed[testutil.ClientID(user)][id])
+			}
+		}
+		if sum != supply {
+			t.Fatalf("%s: balances of token %d add up to %d, its supply is %d", at, id, sum, supply)
+		}
+	}
+
+	// every account and token type has a single balance record, and none is left empty
+	seen := map[string]bool{}
+	for _, key := range l.Keys() {
+		objectType, attributes, err := ctx.SplitCompositeKey(key)
+		if err != nil {
+			continue
+		}
+		switch objectType {
+		case balancePrefix1:
+			t.Fatalf("%s: legacy balance record %q", at, key)
+		case balancePrefix2:
+			pair := attributes[0] + "/" + attributes[1]
+			if seen[pair] {
+				t.Fatalf("%s: account and token %s have several balance records", at, pair)
+			}
+			seen[pair] = true
+			balance, err := decodeBalance1(l.State(key))
+			if err != nil || balance == 0 {
+				t.Fatalf("%s: balance record %q holds %d: %v", at, key, balance, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,310 @@
+// Package accesscontrol implements role based access control shared by the token contracts.
+//
+// Roles are granted to user IDs (ctx.GetUserID) and stored under role~<role>~<account> composite
+// keys. Every role has an admin role whose holders may grant and revoke it; unless changed with
+// SetRoleAdmin that is AdminRole, which administers itself. The first admin is created by
+// Initialize or Bootstrap, the only places where membership of an MSP is trusted, and becomes the
+// contract's owner. Ownership, and the ADMIN_ROLE with it, moves in two steps with
+// TransferOwnership and AcceptOwnership.
+package accesscontrol
+
+import (
+	"fmt"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/audit"
+	"github.com/thekalpstudio/kush-go/events"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+// Built-in roles.
+const (
+	AdminRole  = "ADMIN_ROLE"
+	MinterRole = "MINTER_ROLE"
+	PauserRole = "PAUSER_ROLE"
+	BurnerRole = "BURNER_ROLE"
+)
+
+// RolePrefix is the composite key object type of the role~<role>~<account> keys.
+const RolePrefix = "role"
+const roleAdminPrefix = "role~admin"
+const bootstrappedKey = "role~bootstrapped"
+
+// BuiltinRoles are granted to the bootstrapping account.
+var BuiltinRoles = []string{AdminRole, MinterRole, PauserRole, BurnerRole}
+
+// RoleEvent is emitted as RoleGranted or RoleRevoked.
+type RoleEvent = events.RoleEvent
+
+// RoleAdminChanged is emitted when the admin role of Role changes.
+type RoleAdminChanged = events.RoleAdminChanged
+
+// RolesBootstrapped is emitted once, when the first admin is created.
+type RolesBootstrapped = events.RolesBootstrapped
+
+// Bootstrap grants every built-in role to the caller, who must belong to an authorized MSP, and
+// makes it the owner. It lets contracts initialized before roles existed create their first
+// admin; new contracts bootstrap through Initialize. Contracts that never stored their authorized
+// MSPs pass them in authorizedMSPs, which are stored as Initialize does; the others pass none.
+func Bootstrap(ctx kalpsdk.TransactionContextInterface, authorizedMSPs []string) error {
+	stored, err := GetAuthorizedMSPs(ctx)
+	if err != nil {
+		return err
+	}
+	if len(stored) == 0 {
+		return Initialize(ctx, authorizedMSPs)
+	}
+	if len(authorizedMSPs) > 0 {
+		return fmt.Errorf("authorized MSPs are already set")
+	}
+	err = checkMSP(ctx, stored)
+	if err != nil {
+		return err
+	}
+	return bootstrap(ctx)
+}
+
+func bootstrap(ctx kalpsdk.TransactionContextInterface) error {
+	bootstrapped, err := IsBootstrapped(ctx)
+	if err != nil {
+		return err
+	}
+	if bootstrapped {
+		return fmt.Errorf("roles are already bootstrapped")
+	}
+
+	account, err := ctx.GetUserID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+	for _, role := range BuiltinRoles {
+		err = writeRole(ctx, role, account, true)
+		if err != nil {
+			return err
+		}
+	}
+	err = ctx.PutStateWithoutKYC(bootstrappedKey, []byte(account))
+	if err != nil {
+		return fmt.Errorf("failed to mark roles as bootstrapped: %v", err)
+	}
+	err = ctx.PutStateWithoutKYC(ownerKey, []byte(account))
+	if err != nil {
+		return fmt.Errorf("failed to set owner: %v", err)
+	}
+
+	return events.Emit(ctx, "RolesBootstrapped", &RolesBootstrapped{Account: account, Roles: BuiltinRoles})
+}
+
+// IsBootstrapped reports whether Bootstrap already ran.
+func IsBootstrapped(ctx kalpsdk.TransactionContextInterface) (bool, error) {
+	bootstrappedBytes, err := ctx.GetState(bootstrappedKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to read bootstrap state: %v", err)
+	}
+	return bootstrappedBytes != nil, nil
+}
+
+// HasRole reports whether account holds role.
+func HasRole(ctx kalpsdk.TransactionContextInterface, role string, account string) (bool, error) {
+	roleKey, err := ctx.CreateCompositeKey(RolePrefix, []string{role, account})
+	if err != nil {
+		return false, fmt.Errorf("failed to create the composite key for prefix %s: %v", RolePrefix, err)
+	}
+	roleBytes, err := ctx.GetState(roleKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to read role %s for %s: %v", role, account, err)
+	}
+	return roleBytes != nil, nil
+}
+
+// CheckRole fails unless the calling user holds role.
+func CheckRole(ctx kalpsdk.TransactionContextInterface, role string) error {
+	clientID, err := ctx.GetUserID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+	ok, err := HasRole(ctx, role, clientID)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return kusherrors.Errorf(kusherrors.ErrUnauthorized, "client %s is missing role %s", clientID, role)
+	}
+	return nil
+}
+
+// GetRoleAdmin returns the role whose holders administer role.
+func GetRoleAdmin(ctx kalpsdk.TransactionContextInterface, role string) (string, error) {
+	adminKey, err := ctx.CreateCompositeKey(roleAdminPrefix, []string{role})
+	if err != nil {
+		return "", fmt.Errorf("failed to create the composite key for prefix %s: %v", roleAdminPrefix, err)
+	}
+	adminBytes, err := ctx.GetState(adminKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to read admin of role %s: %v", role, err)
+	}
+	if adminBytes == nil {
+		return AdminRole, nil
+	}
+	return string(adminBytes), nil
+}
+
+// SetRoleAdmin makes adminRole the admin of role. The caller must hold the current admin role of
+// role. Once multisig approval is enabled the change has to be proposed instead.
+func SetRoleAdmin(ctx kalpsdk.TransactionContextInterface, role string, adminRole string) error {
+	err := CheckNotMultisig(ctx, ActionSetRoleAdmin)
+	if err != nil {
+		return err
+	}
+	err = checkRoleAdmin(ctx, role)
+	if err != nil {
+		return err
+	}
+	return setRoleAdmin(ctx, role, adminRole)
+}
+
+func setRoleAdmin(ctx kalpsdk.TransactionContextInterface, role string, adminRole string) error {
+	if role == "" || adminRole == "" {
+		return fmt.Errorf("role must not be empty")
+	}
+	previous, err := GetRoleAdmin(ctx, role)
+	if err != nil {
+		return err
+	}
+
+	adminKey, err := ctx.CreateCompositeKey(roleAdminPrefix, []string{role})
+	if err != nil {
+		return fmt.Errorf("failed to create the composite key for prefix %s: %v", roleAdminPrefix, err)
+	}
+	err = ctx.PutStateWithoutKYC(adminKey, []byte(adminRole))
+	if err != nil {
+		return fmt.Errorf("failed to update admin of role %s: %v", role, err)
+	}
+	err = audit.Record(ctx, "RoleAdminChanged", role, previous, adminRole)
+	if err != nil {
+		return err
+	}
+
+	return events.Emit(ctx, "RoleAdminChanged", &RoleAdminChanged{Role: role, PreviousAdminRole: previous, NewAdminRole: adminRole})
+}
+
+// GrantRole gives account role. The caller must hold the admin role of role. Once multisig
+// approval or the timelock is enabled role changes have to be proposed or scheduled instead.
+func GrantRole(ctx kalpsdk.TransactionContextInterface, role string, account string) error {
+	err := CheckNotMultisig(ctx, ActionGrantRole)
+	if err != nil {
+		return err
+	}
+	err = CheckNotTimelocked(ctx, ActionGrantRole)
+	if err != nil {
+		return err
+	}
+	err = checkRoleAdmin(ctx, role)
+	if err != nil {
+		return err
+	}
+	return setRole(ctx, role, account, true)
+}
+
+// RevokeRole removes role from account. The caller must hold the admin role of role.
+func RevokeRole(ctx kalpsdk.TransactionContextInterface, role string, account string) error {
+	err := CheckNotMultisig(ctx, ActionRevokeRole)
+	if err != nil {
+		return err
+	}
+	err = CheckNotTimelocked(ctx, ActionRevokeRole)
+	if err != nil {
+		return err
+	}
+	err = checkRoleAdmin(ctx, role)
+	if err != nil {
+		return err
+	}
+	return setRole(ctx, role, account, false)
+}
+
+// RenounceRole removes role from the caller. account must be the caller's own ID, which guards
+// against renouncing by mistake.
+func RenounceRole(ctx kalpsdk.TransactionContextInterface, role string, account string) error {
+	clientID, err := ctx.GetUserID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+	if account != clientID {
+		return kusherrors.Errorf(kusherrors.ErrUnauthorized, "roles can only be renounced for the calling account")
+	}
+	return setRole(ctx, role, account, false)
+}
+
+// MigrateRole gives account role without checking the caller, for schema upgrades that carry
+// roles over from an earlier version of a contract. Callers must check the caller themselves, as
+// the contracts' Migrate functions do with AdminRole. It does nothing if account already holds
+// role.
+func MigrateRole(ctx kalpsdk.TransactionContextInterface, role string, account string) error {
+	current, err := HasRole(ctx, role, account)
+	if err != nil {
+		return err
+	}
+	if current {
+		return nil
+	}
+	return setRole(ctx, role, account, true)
+}
+
+func checkRoleAdmin(ctx kalpsdk.TransactionContextInterface, role string) error {
+	adminRole, err := GetRoleAdmin(ctx, role)
+	if err != nil {
+		return err
+	}
+	return CheckRole(ctx, adminRole)
+}
+
+func setRole(ctx kalpsdk.TransactionContextInterface, role string, account string, granted bool) error {
+	if role == "" || account == "" {
+		return fmt.Errorf("role and account must not be empty")
+	}
+	current, err := HasRole(ctx, role, account)
+	if err != nil {
+		return err
+	}
+	if current == granted {
+		if granted {
+			return fmt.Errorf("account %s already has role %s", account, role)
+		}
+		return fmt.Errorf("account %s does not have role %s", account, role)
+	}
+	err = writeRole(ctx, role, account, granted)
+	if err != nil {
+		return err
+	}
+
+	sender, err := ctx.GetUserID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+	eventName := "RoleRevoked"
+	if granted {
+		eventName = "RoleGranted"
+	}
+	err = audit.Record(ctx, eventName, role, account)
+	if err != nil {
+		return err
+	}
+	return events.Emit(ctx, eventName, &RoleEvent{Role: role, Account: account, Sender: sender})
+}
+
+func writeRole(ctx kalpsdk.TransactionContextInterface, role string, account string, granted bool) error {
+	roleKey, err := ctx.CreateCompositeKey(RolePrefix, []string{role, account})
+	if err != nil {
+		return fmt.Errorf("failed to create the composite key for prefix %s: %v", RolePrefix, err)
+	}
+	if granted {
+		err = ctx.PutStateWithoutKYC(roleKey, []byte{'\u0000'})
+	} else {
+		err = ctx.DelStateWithoutKYC(roleKey)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update role %s for %s: %v", role, account, err)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+package accesscontrol
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/canonical"
+)
+
+const kycConfigKey = "kyc~config"
+
+// KYCConfig selects which transactions write state through the KYC-enforcing kalpsdk path, which
+// rejects callers that have not completed KYC. Overrides maps contract function names to a mode
+// that replaces Required for that function.
+type KYCConfig struct {
+	Required  bool            `json:"required"`
+	Overrides map[string]bool `json:"overrides,omitempty" metadata:",optional"`
+}
+
+// InitializeKYC stores the initial KYC mode. config.Initialize calls it, after Initialize of this
+// package succeeded. Reads of the initializing transaction do not see the mode, so it writes its
+// keys with WriteState and the mode it stored.
+func InitializeKYC(ctx kalpsdk.TransactionContextInterface, required bool) error {
+	return writeKYCConfig(ctx, &KYCConfig{Required: required})
+}
+
+// SetKYCRequired switches the KYC mode of every function without an override. The caller must
+// hold AdminRole.
+func SetKYCRequired(ctx kalpsdk.TransactionContextInterface, required bool) error {
+	config, err := checkKYCAdmin(ctx)
+	if err != nil {
+		return err
+	}
+	config.Required = required
+	return writeKYCConfig(ctx, config)
+}
+
+// WriteKYCRequired switches the KYC mode as SetKYCRequired does, leaving authorization to the
+// caller.
+func WriteKYCRequired(ctx kalpsdk.TransactionContextInterface, required bool) error {
+	config, err := GetKYCConfig(ctx)
+	if err != nil {
+		return err
+	}
+	config.Required = required
+	return writeKYCConfig(ctx, config)
+}
+
+// SetFunctionKYCRequired overrides the KYC mode of the contract function named function, e.g. to
+// require KYC for transfers only. The caller must hold AdminRole.
+func SetFunctionKYCRequired(ctx kalpsdk.TransactionContextInterface, function string, required bool) error {
+	if function == "" {
+		return fmt.Errorf("function name must not be empty")
+	}
+	config, err := checkKYCAdmin(ctx)
+	if err != nil {
+		return err
+	}
+	if config.Overrides == nil {
+		config.Overrides = make(map[string]bool)
+	}
+	config.Overrides[function] = required
+	return writeKYCConfig(ctx, config)
+}
+
+// RemoveFunctionKYCOverride makes function follow the contract-wide KYC mode again. The caller
+// must hold AdminRole.
+func RemoveFunctionKYCOverride(ctx kalpsdk.TransactionContextInterface, function string) error {
+	config, err := checkKYCAdmin(ctx)
+	if err != nil {
+		return err
+	}
+	if _, ok := config.Overrides[function]; !ok {
+		return fmt.Errorf("function %s has no KYC override", function)
+	}
+	delete(config.Overrides, function)
+	return writeKYCConfig(ctx, config)
+}
+
+// GetKYCConfig returns the KYC mode. Contracts initialized before KYC modes existed never require
+// KYC.
+func GetKYCConfig(ctx kalpsdk.TransactionContextInterface) (*KYCConfig, error) {
+	configBytes, err := ctx.GetState(kycConfigKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read KYC config: %v", err)
+	}
+	config := new(KYCConfig)
+	if configBytes == nil {
+		return config, nil
+	}
+	err = json.Unmarshal(configBytes, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode KYC config: %v", err)
+	}
+	return config, nil
+}
+
+// KYCRequired reports whether the function invoked by the current transaction writes state with
+// KYC enforcement.
+func KYCRequired(ctx kalpsdk.TransactionContextInterface) (bool, error) {
+	config, err := GetKYCConfig(ctx)
+	if err != nil {
+		return false, err
+	}
+	function, _ := ctx.GetFunctionAndParameters()
+	// Functions of named contracts are invoked as "<contract>:<function>".
+	function = function[strings.LastIndex(function, ":")+1:]
+	if required, ok := config.Overrides[function]; ok {
+		return required, nil
+	}
+	return config.Required, nil
+}
+
+// PutState writes key through the kalpsdk path selected by the KYC mode of the current function.
+func PutState(ctx kalpsdk.TransactionContextInterface, key string, value []byte) error {
+	required, err := KYCRequired(ctx)
+	if err != nil {
+		return err
+	}
+	return WriteState(ctx, required, key, value)
+}
+
+// WriteState writes key with KYC enforcement if required is set.
+func WriteState(ctx kalpsdk.TransactionContextInterface, required bool, key string, value []byte) error {
+	if required {
+		return ctx.PutStateWithKYC(key, value)
+	}
+	return ctx.PutStateWithoutKYC(key, value)
+}
+
+// DelState deletes key through the kalpsdk path selected by the KYC mode of the current function.
+func DelState(ctx kalpsdk.TransactionContextInterface, key string) error {
+	required, err := KYCRequired(ctx)
+	if err != nil {
+		return err
+	}
+	if required {
+		return ctx.DelStateWithKYC(key)
+	}
+	return ctx.DelStateWithoutKYC(key)
+}
+
+func checkKYCAdmin(ctx kalpsdk.TransactionContextInterface) (*KYCConfig, error) {
+	err := CheckRole(ctx, AdminRole)
+	if err != nil {
+		return nil, err
+	}
+	return GetKYCConfig(ctx)
+}
+
+func writeKYCConfig(ctx kalpsdk.TransactionContextInterface, config *KYCConfig) error {
+	configJSON, err := canonical.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	err = ctx.PutStateWithoutKYC(kycConfigKey, configJSON)
+	if err != nil {
+		return fmt.Errorf("failed to set KYC config: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package accesscontrol
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/audit"
+	"github.com/thekalpstudio/kush-go/canonical"
+	"github.com/thekalpstudio/kush-go/events"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+const authorizedMSPsKey = "role~authorizedMSPs"
+
+// AuthorizedOrgEvent is emitted as AuthorizedOrgAdded or AuthorizedOrgRemoved.
+type AuthorizedOrgEvent = events.AuthorizedOrgEvent
+
+// Initialize stores the MSPs authorized to administer the contract and bootstraps the roles of the
+// caller, who must belong to one of them. It succeeds only once per contract, so deployers run it
+// as the init transaction of the chaincode definition; authorization is read from the stored MSPs
+// alone from then on.
+func Initialize(ctx kalpsdk.TransactionContextInterface, authorizedMSPs []string) error {
+	if len(authorizedMSPs) == 0 {
+		return fmt.Errorf("at least one authorized MSP must be set")
+	}
+	for _, mspID := range authorizedMSPs {
+		if mspID == "" {
+			return fmt.Errorf("authorized MSP must not be empty")
+		}
+	}
+	err := checkMSP(ctx, authorizedMSPs)
+	if err != nil {
+		return err
+	}
+	mspsBytes, err := ctx.GetState(authorizedMSPsKey)
+	if err != nil {
+		return fmt.Errorf("failed to read authorized MSPs: %v", err)
+	}
+	if mspsBytes != nil {
+		return fmt.Errorf("authorized MSPs are already set")
+	}
+
+	err = writeAuthorizedMSPs(ctx, authorizedMSPs)
+	if err != nil {
+		return err
+	}
+	err = audit.Record(ctx, "Initialize", authorizedMSPs...)
+	if err != nil {
+		return err
+	}
+	return bootstrap(ctx)
+}
+
+// AddAuthorizedOrg authorizes mspID alongside the current MSPs. The caller must hold AdminRole.
+// Once multisig approval is enabled the change has to be proposed instead.
+func AddAuthorizedOrg(ctx kalpsdk.TransactionContextInterface, mspID string) error {
+	err := CheckRole(ctx, AdminRole)
+	if err != nil {
+		return err
+	}
+	err = CheckNotMultisig(ctx, ActionAddAuthorizedOrg)
+	if err != nil {
+		return err
+	}
+	return addAuthorizedOrg(ctx, mspID)
+}
+
+func addAuthorizedOrg(ctx kalpsdk.TransactionContextInterface, mspID string) error {
+	if mspID == "" {
+		return fmt.Errorf("authorized MSP must not be empty")
+	}
+	authorizedMSPs, err := GetAuthorizedMSPs(ctx)
+	if err != nil {
+		return err
+	}
+	for _, current := range authorizedMSPs {
+		if current == mspID {
+			return fmt.Errorf("MSP %s is already authorized", mspID)
+		}
+	}
+	err = writeAuthorizedMSPs(ctx, append(authorizedMSPs, mspID))
+	if err != nil {
+		return err
+	}
+	err = audit.Record(ctx, "AuthorizedOrgAdded", mspID)
+	if err != nil {
+		return err
+	}
+	return emitAuthorizedOrgEvent(ctx, "AuthorizedOrgAdded", mspID)
+}
+
+// RemoveAuthorizedOrg withdraws the authorization of mspID. The caller must hold AdminRole, and
+// the last authorized MSP cannot be removed. Once multisig approval is enabled the change has to
+// be proposed instead.
+func RemoveAuthorizedOrg(ctx kalpsdk.TransactionContextInterface, mspID string) error {
+	err := CheckRole(ctx, AdminRole)
+	if err != nil {
+		return err
+	}
+	err = CheckNotMultisig(ctx, ActionRemoveAuthorizedOrg)
+	if err != nil {
+		return err
+	}
+	return removeAuthorizedOrg(ctx, mspID)
+}
+
+func removeAuthorizedOrg(ctx kalpsdk.TransactionContextInterface, mspID string) error {
+	authorizedMSPs, err := GetAuthorizedMSPs(ctx)
+	if err != nil {
+		return err
+	}
+	remaining := []string{}
+	for _, current := range authorizedMSPs {
+		if current != mspID {
+			remaining = append(remaining, current)
+		}
+	}
+	if len(remaining) == len(authorizedMSPs) {
+		return fmt.Errorf("MSP %s is not authorized", mspID)
+	}
+	if len(remaining) == 0 {
+		return fmt.Errorf("cannot remove the last authorized MSP")
+	}
+	err = writeAuthorizedMSPs(ctx, remaining)
+	if err != nil {
+		return err
+	}
+	err = audit.Record(ctx, "AuthorizedOrgRemoved", mspID)
+	if err != nil {
+		return err
+	}
+	return emitAuthorizedOrgEvent(ctx, "AuthorizedOrgRemoved", mspID)
+}
+
+// GetAuthorizedMSPs returns the MSPs stored by Initialize, or none if they were never stored.
+func GetAuthorizedMSPs(ctx kalpsdk.TransactionContextInterface) ([]string, error) {
+	mspsBytes, err := ctx.GetState(authorizedMSPsKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read authorized MSPs: %v", err)
+	}
+	if mspsBytes == nil {
+		return []string{}, nil
+	}
+	var authorizedMSPs []string
+	err = json.Unmarshal(mspsBytes, &authorizedMSPs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode authorized MSPs: %v", err)
+	}
+	return authorizedMSPs, nil
+}
+
+// CheckAuthorizedMSP fails unless the caller belongs to one of the authorized MSPs.
+func CheckAuthorizedMSP(ctx kalpsdk.TransactionContextInterface) error {
+	authorizedMSPs, err := GetAuthorizedMSPs(ctx)
+	if err != nil {
+		return err
+	}
+	return checkMSP(ctx, authorizedMSPs)
+}
+
+// CheckOrgRole fails unless the caller belongs to an authorized MSP and holds role. It guards
+// minting and burning, so revoking an organization suspends its members' roles.
+func CheckOrgRole(ctx kalpsdk.TransactionContextInterface, role string) error {
+	err := CheckAuthorizedMSP(ctx)
+	if err != nil {
+		return err
+	}
+	return CheckRole(ctx, role)
+}
+
+func checkMSP(ctx kalpsdk.TransactionContextInterface, authorizedMSPs []string) error {
+	clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+	if err != nil {
+		return fmt.Errorf("failed to get MSPID: %v", err)
+	}
+	for _, mspID := range authorizedMSPs {
+		if clientMSPID == mspID {
+			return nil
+		}
+	}
+	return kusherrors.Errorf(kusherrors.ErrUnauthorized, "client MSP %s is not authorized", clientMSPID)
+}
+
+func writeAuthorizedMSPs(ctx kalpsdk.TransactionContextInterface, authorizedMSPs []string) error {
+	mspsJSON, err := canonical.Marshal(authorizedMSPs)
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	err = ctx.PutStateWithoutKYC(authorizedMSPsKey, mspsJSON)
+	if err != nil {
+		return fmt.Errorf("failed to set authorized MSPs: %v", err)
+	}
+	return nil
+}
+
+func emitAuthorizedOrgEvent(ctx kalpsdk.TransactionContextInterface, eventName string, mspID string) error {
+	sender, err := ctx.GetUserID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+	return events.Emit(ctx, eventName, &AuthorizedOrgEvent{MSPID: mspID, Sender: sender})
+}
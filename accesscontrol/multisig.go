@@ -0,0 +1,532 @@
+package accesscontrol
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/canonical"
+	"github.com/thekalpstudio/kush-go/events"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+// Privileged actions that can be proposed or scheduled. The role, ownership and configuration
+// actions are executed by this package; contracts execute the others through an Executor.
+const (
+	ActionMint                = "Mint"
+	ActionPause               = "Pause"
+	ActionUnpause             = "Unpause"
+	ActionSetURI              = "SetURI"
+	ActionSetTokenURI         = "SetTokenURI"
+	ActionSetTransferFee      = "SetTransferFee"
+	ActionGrantRole           = "GrantRole"
+	ActionRevokeRole          = "RevokeRole"
+	ActionSetMultisig         = "SetMultisig"
+	ActionSetTimelockDelay    = "SetTimelockDelay"
+	ActionTransferOwnership   = "TransferOwnership"
+	ActionSetRoleAdmin        = "SetRoleAdmin"
+	ActionAddAuthorizedOrg    = "AddAuthorizedOrg"
+	ActionRemoveAuthorizedOrg = "RemoveAuthorizedOrg"
+	ActionUpdateConfig        = "UpdateConfig"
+	ActionSetGuardian         = "SetGuardian"
+	ActionSetDenyList         = "SetDenyList"
+)
+
+// MultisigActions are the actions that have to be proposed while multisig approval is enabled,
+// rather than called or scheduled by a single admin.
+var MultisigActions = []string{ActionPause, ActionUnpause, ActionSetURI, ActionSetTransferFee, ActionGrantRole, ActionRevokeRole, ActionSetMultisig, ActionSetTimelockDelay, ActionTransferOwnership, ActionSetRoleAdmin, ActionAddAuthorizedOrg, ActionRemoveAuthorizedOrg, ActionUpdateConfig, ActionSetGuardian, ActionSetDenyList}
+
+// Executor carries out a contract specific action whose authorization was already established by
+// approvals or a timelock, so it must not repeat the role checks of the direct call.
+type Executor func(action string, args []string) error
+
+const multisigConfigKey = "multisig~config"
+const proposalPrefix = "multisig~proposal"
+const mintedKey = "multisig~minted"
+
+// MaxProposalTTL bounds how long a proposal can wait for approvals, in seconds.
+const MaxProposalTTL = 30 * 24 * 60 * 60
+
+// DefaultMintWindow is the mint window of a MultisigConfig that sets none, in seconds.
+const DefaultMintWindow = 24 * 60 * 60
+
+// MultisigConfig lists the admins who co-sign privileged operations and how many of them must
+// approve. Once more than MintThreshold tokens were minted without a proposal within a mint window
+// of MintWindow seconds, further mints of the window need a proposal; an empty MintThreshold
+// leaves mints to the MINTER_ROLE alone.
+type MultisigConfig struct {
+	Signers       []string `json:"signers"`
+	Threshold     int      `json:"threshold"`
+	MintThreshold string   `json:"mintThreshold,omitempty" metadata:",optional"`
+	MintWindow    int64    `json:"mintWindow,omitempty" metadata:",optional"`
+}
+
+// mintedInWindow is the amount minted without a proposal in the mint window that starts at Start.
+type mintedInWindow struct {
+	Start  int64  `json:"start"`
+	Amount string `json:"amount"`
+}
+
+// Proposal is a privileged operation waiting for approvals. Args are action specific:
+// account and amount for ActionMint (plus the token id first on ERC1155), role and account for
+// role actions, and a JSON MultisigConfig for ActionSetMultisig. For the other actions they are
+// the parameters of the contract method of the same name. Operation is the timelock operation an
+// executed proposal was scheduled as, "" if it ran right away.
+type Proposal struct {
+	ID        string   `json:"id"`
+	Action    string   `json:"action"`
+	Args      []string `json:"args"`
+	Proposer  string   `json:"proposer"`
+	Approvals []string `json:"approvals"`
+	ExpiresAt int64    `json:"expiresAt"`
+	Executed  bool     `json:"executed"`
+	Operation string   `json:"operation,omitempty" metadata:",optional"`
+}
+
+// ProposalEvent is emitted as ProposalCreated or ProposalApproved.
+type ProposalEvent = events.ProposalEvent
+
+// SetMultisig enables multisig approval with config. Only the owner may enable it; once enabled
+// the configuration can only change through an ActionSetMultisig proposal.
+func SetMultisig(ctx kalpsdk.TransactionContextInterface, config *MultisigConfig) error {
+	_, err := checkOwner(ctx)
+	if err != nil {
+		return err
+	}
+	err = CheckNotMultisig(ctx, ActionSetMultisig)
+	if err != nil {
+		return err
+	}
+	err = writeMultisigConfig(ctx, config)
+	if err != nil {
+		return err
+	}
+	return cancelPendingOwner(ctx)
+}
+
+// GetMultisig returns the multisig configuration, or nil if multisig approval is disabled.
+func GetMultisig(ctx kalpsdk.TransactionContextInterface) (*MultisigConfig, error) {
+	configBytes, err := ctx.GetState(multisigConfigKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read multisig config: %v", err)
+	}
+	if configBytes == nil {
+		return nil, nil
+	}
+	config := new(MultisigConfig)
+	err = json.Unmarshal(configBytes, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode multisig config: %v", err)
+	}
+	return config, nil
+}
+
+// CheckNotMultisig fails if multisig approval is enabled, in which case action has to be
+// proposed instead of called directly.
+func CheckNotMultisig(ctx kalpsdk.TransactionContextInterface, action string) error {
+	config, err := GetMultisig(ctx)
+	if err != nil {
+		return err
+	}
+	if config != nil {
+		return fmt.Errorf("%s requires a multisig proposal", action)
+	}
+	return nil
+}
+
+// CheckMintThreshold fails if multisig approval is enabled and amount, added to what was minted
+// without a proposal in the current mint window, exceeds its mint threshold. Otherwise it counts
+// amount toward the window, so a large mint cannot be split into calls under the threshold. Mint
+// windows are aligned on multiples of MintWindow since the epoch.
+func CheckMintThreshold(ctx kalpsdk.TransactionContextInterface, amount *big.Int) error {
+	config, err := GetMultisig(ctx)
+	if err != nil {
+		return err
+	}
+	if config == nil || config.MintThreshold == "" {
+		return nil
+	}
+	threshold, _ := new(big.Int).SetString(config.MintThreshold, 10)
+	window := config.MintWindow
+	if window == 0 {
+		window = DefaultMintWindow
+	}
+	now, err := txTimestampSeconds(ctx)
+	if err != nil {
+		return err
+	}
+
+	minted := &mintedInWindow{Start: now - now%window, Amount: "0"}
+	mintedBytes, err := ctx.GetState(mintedKey)
+	if err != nil {
+		return fmt.Errorf("failed to read minted amount: %v", err)
+	}
+	if mintedBytes != nil {
+		current := new(mintedInWindow)
+		err = json.Unmarshal(mintedBytes, current)
+		if err != nil {
+			return fmt.Errorf("failed to decode minted amount: %v", err)
+		}
+		if current.Start == minted.Start {
+			minted = current
+		}
+	}
+	total, ok := new(big.Int).SetString(minted.Amount, 10)
+	if !ok {
+		return fmt.Errorf("failed to decode minted amount %q", minted.Amount)
+	}
+	total.Add(total, amount)
+	if total.Cmp(threshold) > 0 {
+		return fmt.Errorf("minting more than %s within %d seconds requires a multisig proposal", config.MintThreshold, window)
+	}
+
+	minted.Amount = total.String()
+	mintedJSON, err := canonical.Marshal(minted)
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	err = ctx.PutStateWithoutKYC(mintedKey, mintedJSON)
+	if err != nil {
+		return fmt.Errorf("failed to store minted amount: %v", err)
+	}
+	return nil
+}
+
+// Propose records a proposal for action, approved by the proposing signer, that expires after
+// ttl seconds. The proposal ID is the transaction ID.
+func Propose(ctx kalpsdk.TransactionContextInterface, action string, args []string, ttl int64) (*Proposal, error) {
+	config, signer, err := checkSigner(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if action == "" {
+		return nil, fmt.Errorf("action must not be empty")
+	}
+	if ttl <= 0 || ttl > MaxProposalTTL {
+		return nil, fmt.Errorf("ttl must be between 1 and %d seconds", MaxProposalTTL)
+	}
+	now, err := txTimestampSeconds(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	proposal := &Proposal{
+		ID:        ctx.GetTxID(),
+		Action:    action,
+		Args:      args,
+		Proposer:  signer,
+		Approvals: []string{signer},
+		ExpiresAt: now + ttl,
+	}
+	err = writeProposal(ctx, proposal)
+	if err != nil {
+		return nil, err
+	}
+	err = emitProposalEvent(ctx, "ProposalCreated", proposal, signer, config)
+	if err != nil {
+		return nil, err
+	}
+	return proposal, nil
+}
+
+// ApproveProposal adds the calling signer's approval to proposal id.
+func ApproveProposal(ctx kalpsdk.TransactionContextInterface, id string) (*Proposal, error) {
+	config, signer, err := checkSigner(ctx)
+	if err != nil {
+		return nil, err
+	}
+	proposal, err := readOpenProposal(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if containsString(proposal.Approvals, signer) {
+		return nil, fmt.Errorf("signer %s already approved proposal %s", signer, id)
+	}
+
+	proposal.Approvals = append(proposal.Approvals, signer)
+	err = writeProposal(ctx, proposal)
+	if err != nil {
+		return nil, err
+	}
+	err = emitProposalEvent(ctx, "ProposalApproved", proposal, signer, config)
+	if err != nil {
+		return nil, err
+	}
+	return proposal, nil
+}
+
+// GetProposal returns proposal id.
+func GetProposal(ctx kalpsdk.TransactionContextInterface, id string) (*Proposal, error) {
+	proposalKey, err := ctx.CreateCompositeKey(proposalPrefix, []string{id})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the composite key for prefix %s: %v", proposalPrefix, err)
+	}
+	proposalBytes, err := ctx.GetState(proposalKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read proposal %s: %v", id, err)
+	}
+	if proposalBytes == nil {
+		return nil, fmt.Errorf("proposal %s does not exist", id)
+	}
+	proposal := new(Proposal)
+	err = json.Unmarshal(proposalBytes, proposal)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode proposal %s: %v", id, err)
+	}
+	return proposal, nil
+}
+
+// GetPendingProposals returns the proposals that are neither executed nor expired.
+func GetPendingProposals(ctx kalpsdk.TransactionContextInterface) ([]*Proposal, error) {
+	now, err := txTimestampSeconds(ctx)
+	if err != nil {
+		return nil, err
+	}
+	iterator, err := ctx.GetStateByPartialCompositeKey(proposalPrefix, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read proposals: %v", err)
+	}
+	defer iterator.Close()
+
+	pending := []*Proposal{}
+	for iterator.HasNext() {
+		queryResponse, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read proposals: %v", err)
+		}
+		proposal := new(Proposal)
+		err = json.Unmarshal(queryResponse.Value, proposal)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode proposal %s: %v", queryResponse.Key, err)
+		}
+		if !proposal.Executed && proposal.ExpiresAt > now {
+			pending = append(pending, proposal)
+		}
+	}
+	return pending, nil
+}
+
+// ExecuteProposal executes proposal id once enough current signers approved it, passing actions
+// this package does not know to execute. Only signers may execute. While the timelock is enabled
+// a timelocked action is scheduled instead, as the operation Operation, and runs through
+// ExecuteOperation once the delay passed. The executed action emits its own event, so none is
+// emitted for the execution itself.
+func ExecuteProposal(ctx kalpsdk.TransactionContextInterface, id string, execute Executor) (*Proposal, error) {
+	config, _, err := checkSigner(ctx)
+	if err != nil {
+		return nil, err
+	}
+	proposal, err := readOpenProposal(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	approvals := countApprovals(proposal, config)
+	if approvals < config.Threshold {
+		return nil, fmt.Errorf("proposal %s has %d of %d approvals", id, approvals, config.Threshold)
+	}
+
+	delay, err := GetTimelockDelay(ctx)
+	if err != nil {
+		return nil, err
+	}
+	proposal.Executed = true
+	if delay > 0 && containsString(TimelockedActions, proposal.Action) {
+		operation, err := scheduleOperation(ctx, proposal.Action, proposal.Args, proposal.Proposer, proposal.ID)
+		if err != nil {
+			return nil, err
+		}
+		proposal.Operation = operation.ID
+	}
+	err = writeProposal(ctx, proposal)
+	if err != nil {
+		return nil, err
+	}
+	if proposal.Operation != "" {
+		return proposal, nil
+	}
+
+	err = executeAction(ctx, proposal.Action, proposal.Args, execute)
+	if err != nil {
+		return nil, err
+	}
+	return proposal, nil
+}
+
+// executeAction executes the role and configuration actions and passes any other to execute.
+func executeAction(ctx kalpsdk.TransactionContextInterface, action string, args []string, execute Executor) error {
+	switch action {
+	case ActionGrantRole, ActionRevokeRole:
+		if len(args) != 2 {
+			return fmt.Errorf("%s takes a role and an account", action)
+		}
+		return setRole(ctx, args[0], args[1], action == ActionGrantRole)
+	case ActionSetMultisig:
+		if len(args) != 1 {
+			return fmt.Errorf("%s takes a JSON multisig config", action)
+		}
+		config := new(MultisigConfig)
+		err := json.Unmarshal([]byte(args[0]), config)
+		if err != nil {
+			return fmt.Errorf("failed to decode multisig config: %v", err)
+		}
+		return writeMultisigConfig(ctx, config)
+	case ActionSetTimelockDelay:
+		if len(args) != 1 {
+			return fmt.Errorf("%s takes a delay in seconds", action)
+		}
+		delay, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid delay %q: %v", args[0], err)
+		}
+		return writeTimelockDelay(ctx, delay)
+	case ActionTransferOwnership:
+		if len(args) != 1 {
+			return fmt.Errorf("%s takes the new owner", action)
+		}
+		owner, err := Owner(ctx)
+		if err != nil {
+			return err
+		}
+		return nominateOwner(ctx, owner, args[0])
+	case ActionSetRoleAdmin:
+		if len(args) != 2 {
+			return fmt.Errorf("%s takes a role and its admin role", action)
+		}
+		return setRoleAdmin(ctx, args[0], args[1])
+	case ActionAddAuthorizedOrg, ActionRemoveAuthorizedOrg:
+		if len(args) != 1 {
+			return fmt.Errorf("%s takes an MSP ID", action)
+		}
+		if action == ActionAddAuthorizedOrg {
+			return addAuthorizedOrg(ctx, args[0])
+		}
+		return removeAuthorizedOrg(ctx, args[0])
+	default:
+		if execute == nil {
+			return fmt.Errorf("action %s is not supported by this contract", action)
+		}
+		return execute(action, args)
+	}
+}
+
+// checkSigner fails unless multisig approval is enabled and the caller is one of its signers.
+func checkSigner(ctx kalpsdk.TransactionContextInterface) (*MultisigConfig, string, error) {
+	config, err := GetMultisig(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	if config == nil {
+		return nil, "", fmt.Errorf("multisig approval is not enabled")
+	}
+	clientID, err := ctx.GetUserID()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get client id: %v", err)
+	}
+	if !containsString(config.Signers, clientID) {
+		return nil, "", kusherrors.Errorf(kusherrors.ErrUnauthorized, "client %s is not a multisig signer", clientID)
+	}
+	return config, clientID, nil
+}
+
+// countApprovals counts the approvals of signers that are still configured.
+func countApprovals(proposal *Proposal, config *MultisigConfig) int {
+	approvals := 0
+	for _, signer := range proposal.Approvals {
+		if containsString(config.Signers, signer) {
+			approvals++
+		}
+	}
+	return approvals
+}
+
+func readOpenProposal(ctx kalpsdk.TransactionContextInterface, id string) (*Proposal, error) {
+	proposal, err := GetProposal(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if proposal.Executed {
+		return nil, fmt.Errorf("proposal %s is already executed", id)
+	}
+	now, err := txTimestampSeconds(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if now >= proposal.ExpiresAt {
+		return nil, fmt.Errorf("proposal %s expired", id)
+	}
+	return proposal, nil
+}
+
+func writeProposal(ctx kalpsdk.TransactionContextInterface, proposal *Proposal) error {
+	proposalKey, err := ctx.CreateCompositeKey(proposalPrefix, []string{proposal.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create the composite key for prefix %s: %v", proposalPrefix, err)
+	}
+	proposalJSON, err := canonical.Marshal(proposal)
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	err = ctx.PutStateWithoutKYC(proposalKey, proposalJSON)
+	if err != nil {
+		return fmt.Errorf("failed to store proposal %s: %v", proposal.ID, err)
+	}
+	return nil
+}
+
+func writeMultisigConfig(ctx kalpsdk.TransactionContextInterface, config *MultisigConfig) error {
+	if config == nil || len(config.Signers) == 0 {
+		return fmt.Errorf("at least one signer must be set")
+	}
+	seen := map[string]bool{}
+	for _, signer := range config.Signers {
+		if signer == "" || seen[signer] {
+			return fmt.Errorf("signers must be unique and not empty")
+		}
+		seen[signer] = true
+	}
+	if config.Threshold < 1 || config.Threshold > len(config.Signers) {
+		return fmt.Errorf("threshold must be between 1 and %d", len(config.Signers))
+	}
+	if config.MintThreshold != "" {
+		threshold, ok := new(big.Int).SetString(config.MintThreshold, 10)
+		if !ok || threshold.Sign() < 0 {
+			return fmt.Errorf("mint threshold must be a non-negative integer")
+		}
+	}
+	if config.MintWindow < 0 {
+		return fmt.Errorf("mint window must not be negative")
+	}
+	configJSON, err := canonical.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	err = ctx.PutStateWithoutKYC(multisigConfigKey, configJSON)
+	if err != nil {
+		return fmt.Errorf("failed to set multisig config: %v", err)
+	}
+	return nil
+}
+
+func emitProposalEvent(ctx kalpsdk.TransactionContextInterface, eventName string, proposal *Proposal, signer string, config *MultisigConfig) error {
+	proposalEvent := ProposalEvent{ID: proposal.ID, Action: proposal.Action, Signer: signer, Approvals: countApprovals(proposal, config)}
+	return events.Emit(ctx, eventName, &proposalEvent)
+}
+
+func txTimestampSeconds(ctx kalpsdk.TransactionContextInterface) (int64, error) {
+	timestamp, err := ctx.GetTxTimestamp()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	return timestamp.GetSeconds(), nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
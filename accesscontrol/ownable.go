@@ -0,0 +1,152 @@
+package accesscontrol
+
+import (
+	"fmt"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/audit"
+	"github.com/thekalpstudio/kush-go/events"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+const ownerKey = "role~owner"
+const pendingOwnerKey = "role~pendingOwner"
+
+// OwnershipTransferStarted is emitted when the owner nominates a successor.
+type OwnershipTransferStarted = events.OwnershipTransferStarted
+
+// OwnershipTransferred is emitted when the nominated successor accepts ownership.
+type OwnershipTransferred = events.OwnershipTransferred
+
+// Owner returns the account that initialized or last accepted ownership of the contract, or ""
+// for contracts whose roles were never bootstrapped.
+func Owner(ctx kalpsdk.TransactionContextInterface) (string, error) {
+	ownerBytes, err := ctx.GetState(ownerKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to read owner: %v", err)
+	}
+	return string(ownerBytes), nil
+}
+
+// PendingOwner returns the account nominated by TransferOwnership, or "" if there is none.
+func PendingOwner(ctx kalpsdk.TransactionContextInterface) (string, error) {
+	pendingBytes, err := ctx.GetState(pendingOwnerKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to read pending owner: %v", err)
+	}
+	return string(pendingBytes), nil
+}
+
+// TransferOwnership nominates newOwner, who takes over once it calls AcceptOwnership. Only the
+// owner may call it; nominating again replaces the pending owner, and nominating "" cancels. While
+// multisig approval or the timelock is enabled the nomination has to be an ActionTransferOwnership
+// proposal or operation instead.
+func TransferOwnership(ctx kalpsdk.TransactionContextInterface, newOwner string) error {
+	owner, err := checkOwner(ctx)
+	if err != nil {
+		return err
+	}
+	err = CheckNotMultisig(ctx, ActionTransferOwnership)
+	if err != nil {
+		return err
+	}
+	err = CheckNotTimelocked(ctx, ActionTransferOwnership)
+	if err != nil {
+		return err
+	}
+	return nominateOwner(ctx, owner, newOwner)
+}
+
+// AcceptOwnership makes the pending owner, who must be the caller, the owner. The ADMIN_ROLE
+// moves with ownership; other roles of the previous owner are left for the new owner to revoke.
+// Enabling multisig approval or the timelock cancels the pending nomination, so a nomination that
+// can still be accepted went through them.
+func AcceptOwnership(ctx kalpsdk.TransactionContextInterface) error {
+	clientID, err := ctx.GetUserID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+	pendingOwner, err := PendingOwner(ctx)
+	if err != nil {
+		return err
+	}
+	if pendingOwner == "" || pendingOwner != clientID {
+		return kusherrors.Errorf(kusherrors.ErrUnauthorized, "client %s is not the pending owner", clientID)
+	}
+	previousOwner, err := Owner(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = ctx.PutStateWithoutKYC(ownerKey, []byte(clientID))
+	if err != nil {
+		return fmt.Errorf("failed to set owner: %v", err)
+	}
+	err = ctx.DelStateWithoutKYC(pendingOwnerKey)
+	if err != nil {
+		return fmt.Errorf("failed to clear pending owner: %v", err)
+	}
+	// Write the roles directly, setRole would replace the OwnershipTransferred event.
+	if previousOwner != "" {
+		err = writeRole(ctx, AdminRole, previousOwner, false)
+		if err != nil {
+			return err
+		}
+	}
+	err = writeRole(ctx, AdminRole, clientID, true)
+	if err != nil {
+		return err
+	}
+	err = audit.Record(ctx, "OwnershipTransferred", previousOwner, clientID)
+	if err != nil {
+		return err
+	}
+
+	return events.Emit(ctx, "OwnershipTransferred", &OwnershipTransferred{PreviousOwner: previousOwner, NewOwner: clientID})
+}
+
+// nominateOwner makes newOwner the pending owner of owner, or cancels the nomination if newOwner
+// is "".
+func nominateOwner(ctx kalpsdk.TransactionContextInterface, owner string, newOwner string) error {
+	var err error
+	if newOwner == "" {
+		err = ctx.DelStateWithoutKYC(pendingOwnerKey)
+	} else {
+		err = ctx.PutStateWithoutKYC(pendingOwnerKey, []byte(newOwner))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update pending owner: %v", err)
+	}
+	err = audit.Record(ctx, "OwnershipTransferStarted", owner, newOwner)
+	if err != nil {
+		return err
+	}
+
+	return events.Emit(ctx, "OwnershipTransferStarted", &OwnershipTransferStarted{PreviousOwner: owner, NewOwner: newOwner})
+}
+
+// cancelPendingOwner drops the pending nomination, made before multisig approval or the timelock
+// was enabled.
+func cancelPendingOwner(ctx kalpsdk.TransactionContextInterface) error {
+	err := ctx.DelStateWithoutKYC(pendingOwnerKey)
+	if err != nil {
+		return fmt.Errorf("failed to clear pending owner: %v", err)
+	}
+	return nil
+}
+
+// checkOwner fails unless the caller is the owner, and returns it.
+func checkOwner(ctx kalpsdk.TransactionContextInterface) (string, error) {
+	clientID, err := ctx.GetUserID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get client id: %v", err)
+	}
+	owner, err := Owner(ctx)
+	if err != nil {
+		return "", err
+	}
+	if owner == "" || owner != clientID {
+		return "", kusherrors.Errorf(kusherrors.ErrUnauthorized, "client %s is not the owner", clientID)
+	}
+	return owner, nil
+}
@@ -0,0 +1,317 @@
+package accesscontrol
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/canonical"
+	"github.com/thekalpstudio/kush-go/events"
+)
+
+const timelockDelayKey = "timelock~delay"
+const timelockPrefix = "timelock~operation"
+
+// MaxTimelockDelay bounds the timelock delay, in seconds.
+const MaxTimelockDelay = 30 * 24 * 60 * 60
+
+// TimelockGracePeriod is how long a ready operation stays executable, in seconds.
+const TimelockGracePeriod = 14 * 24 * 60 * 60
+
+// TimelockedActions are the actions that have to be scheduled while the timelock is enabled.
+var TimelockedActions = []string{ActionSetURI, ActionSetTokenURI, ActionSetTransferFee, ActionGrantRole, ActionRevokeRole, ActionSetTimelockDelay, ActionTransferOwnership, ActionUpdateConfig, ActionSetGuardian, ActionSetDenyList}
+
+// TimelockOperation is an action scheduled to become executable at ReadyAt. Args are the same as
+// for a Proposal of Action. Proposal is the ID of the multisig proposal that scheduled the
+// operation, "" if an admin scheduled it directly.
+type TimelockOperation struct {
+	ID        string   `json:"id"`
+	Action    string   `json:"action"`
+	Args      []string `json:"args"`
+	Proposer  string   `json:"proposer"`
+	Proposal  string   `json:"proposal,omitempty" metadata:",optional"`
+	ReadyAt   int64    `json:"readyAt"`
+	ExpiresAt int64    `json:"expiresAt"`
+	Executed  bool     `json:"executed"`
+	Cancelled bool     `json:"cancelled"`
+}
+
+// TimelockEvent is emitted as CallScheduled, CallExecuted or CallCancelled. CallExecuted replaces
+// the event of the executed action, see package events, and carries its arguments instead.
+type TimelockEvent = events.TimelockEvent
+
+// SetTimelockDelay enables the timelock with delay seconds. Only admins may enable it, through a
+// proposal while multisig approval is enabled; once enabled the delay can only change through a
+// scheduled ActionSetTimelockDelay, and a delay of 0 disables the timelock again.
+func SetTimelockDelay(ctx kalpsdk.TransactionContextInterface, delay int64) error {
+	err := CheckRole(ctx, AdminRole)
+	if err != nil {
+		return err
+	}
+	err = CheckNotMultisig(ctx, ActionSetTimelockDelay)
+	if err != nil {
+		return err
+	}
+	err = CheckNotTimelocked(ctx, ActionSetTimelockDelay)
+	if err != nil {
+		return err
+	}
+	return writeTimelockDelay(ctx, delay)
+}
+
+// GetTimelockDelay returns the timelock delay in seconds, 0 if the timelock is disabled.
+func GetTimelockDelay(ctx kalpsdk.TransactionContextInterface) (int64, error) {
+	delayBytes, err := ctx.GetState(timelockDelayKey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read timelock delay: %v", err)
+	}
+	if delayBytes == nil {
+		return 0, nil
+	}
+	delay, err := strconv.ParseInt(string(delayBytes), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to decode timelock delay: %v", err)
+	}
+	return delay, nil
+}
+
+// CheckNotTimelocked fails if the timelock is enabled and covers action, which then has to be
+// scheduled instead of called directly.
+func CheckNotTimelocked(ctx kalpsdk.TransactionContextInterface, action string) error {
+	delay, err := GetTimelockDelay(ctx)
+	if err != nil {
+		return err
+	}
+	if delay > 0 && containsString(TimelockedActions, action) {
+		return fmt.Errorf("%s has to be scheduled through the timelock", action)
+	}
+	return nil
+}
+
+// ScheduleOperation schedules action to become executable after the timelock delay. Only admins
+// may schedule. While multisig approval is enabled MultisigActions have to be proposed instead, so
+// a single admin cannot get around the signers by waiting.
+func ScheduleOperation(ctx kalpsdk.TransactionContextInterface, action string, args []string) (*TimelockOperation, error) {
+	sender, err := checkTimelockAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !containsString(TimelockedActions, action) {
+		return nil, fmt.Errorf("action %s is not timelocked", action)
+	}
+	err = checkNotProposed(ctx, action)
+	if err != nil {
+		return nil, err
+	}
+	return scheduleOperation(ctx, action, args, sender, "")
+}
+
+// scheduleOperation schedules action on behalf of proposer, approved by proposal if it is not "".
+func scheduleOperation(ctx kalpsdk.TransactionContextInterface, action string, args []string, proposer string, proposal string) (*TimelockOperation, error) {
+	delay, err := GetTimelockDelay(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if delay == 0 {
+		return nil, fmt.Errorf("the timelock is not enabled")
+	}
+	now, err := txTimestampSeconds(ctx)
+	if err != nil {
+		return nil, err
+	}
+	sender, err := ctx.GetUserID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client id: %v", err)
+	}
+
+	operation := &TimelockOperation{
+		ID:        ctx.GetTxID(),
+		Action:    action,
+		Args:      args,
+		Proposer:  proposer,
+		Proposal:  proposal,
+		ReadyAt:   now + delay,
+		ExpiresAt: now + delay + TimelockGracePeriod,
+	}
+	err = writeTimelockOperation(ctx, operation)
+	if err != nil {
+		return nil, err
+	}
+	err = emitTimelockEvent(ctx, "CallScheduled", operation, sender)
+	if err != nil {
+		return nil, err
+	}
+	return operation, nil
+}
+
+// ExecuteOperation executes operation id once its delay passed, passing actions this package does
+// not know to execute. Only admins may execute. An operation an admin scheduled on its own has to
+// pass the multisig check again, so one queued before multisig approval was enabled cannot run
+// on a single signature afterwards.
+func ExecuteOperation(ctx kalpsdk.TransactionContextInterface, id string, execute Executor) (*TimelockOperation, error) {
+	sender, err := checkTimelockAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	operation, err := readOpenOperation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	now, err := txTimestampSeconds(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if now < operation.ReadyAt {
+		return nil, fmt.Errorf("operation %s is not ready until %d", id, operation.ReadyAt)
+	}
+	if now >= operation.ExpiresAt {
+		return nil, fmt.Errorf("operation %s expired", id)
+	}
+	if operation.Proposal == "" {
+		err = checkNotProposed(ctx, operation.Action)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	operation.Executed = true
+	err = writeTimelockOperation(ctx, operation)
+	if err != nil {
+		return nil, err
+	}
+	err = executeAction(ctx, operation.Action, operation.Args, execute)
+	if err != nil {
+		return nil, err
+	}
+	err = emitTimelockEvent(ctx, "CallExecuted", operation, sender)
+	if err != nil {
+		return nil, err
+	}
+	return operation, nil
+}
+
+// CancelOperation cancels operation id before it is executed. Only admins may cancel.
+func CancelOperation(ctx kalpsdk.TransactionContextInterface, id string) (*TimelockOperation, error) {
+	sender, err := checkTimelockAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	operation, err := readOpenOperation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	operation.Cancelled = true
+	err = writeTimelockOperation(ctx, operation)
+	if err != nil {
+		return nil, err
+	}
+	err = emitTimelockEvent(ctx, "CallCancelled", operation, sender)
+	if err != nil {
+		return nil, err
+	}
+	return operation, nil
+}
+
+// GetOperation returns timelock operation id.
+func GetOperation(ctx kalpsdk.TransactionContextInterface, id string) (*TimelockOperation, error) {
+	operationKey, err := ctx.CreateCompositeKey(timelockPrefix, []string{id})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the composite key for prefix %s: %v", timelockPrefix, err)
+	}
+	operationBytes, err := ctx.GetState(operationKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read operation %s: %v", id, err)
+	}
+	if operationBytes == nil {
+		return nil, fmt.Errorf("operation %s does not exist", id)
+	}
+	operation := new(TimelockOperation)
+	err = json.Unmarshal(operationBytes, operation)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode operation %s: %v", id, err)
+	}
+	return operation, nil
+}
+
+// checkNotProposed fails if multisig approval is enabled and action has to be proposed rather
+// than scheduled by a single admin.
+func checkNotProposed(ctx kalpsdk.TransactionContextInterface, action string) error {
+	if containsString(MultisigActions, action) {
+		return CheckNotMultisig(ctx, action)
+	}
+	return nil
+}
+
+func checkTimelockAdmin(ctx kalpsdk.TransactionContextInterface) (string, error) {
+	err := CheckRole(ctx, AdminRole)
+	if err != nil {
+		return "", err
+	}
+	sender, err := ctx.GetUserID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get client id: %v", err)
+	}
+	return sender, nil
+}
+
+func readOpenOperation(ctx kalpsdk.TransactionContextInterface, id string) (*TimelockOperation, error) {
+	operation, err := GetOperation(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if operation.Executed {
+		return nil, fmt.Errorf("operation %s is already executed", id)
+	}
+	if operation.Cancelled {
+		return nil, fmt.Errorf("operation %s is cancelled", id)
+	}
+	return operation, nil
+}
+
+func writeTimelockOperation(ctx kalpsdk.TransactionContextInterface, operation *TimelockOperation) error {
+	operationKey, err := ctx.CreateCompositeKey(timelockPrefix, []string{operation.ID})
+	if err != nil {
+		return fmt.Errorf("failed to create the composite key for prefix %s: %v", timelockPrefix, err)
+	}
+	operationJSON, err := canonical.Marshal(operation)
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	err = ctx.PutStateWithoutKYC(operationKey, operationJSON)
+	if err != nil {
+		return fmt.Errorf("failed to store operation %s: %v", operation.ID, err)
+	}
+	return nil
+}
+
+func writeTimelockDelay(ctx kalpsdk.TransactionContextInterface, delay int64) error {
+	if delay < 0 || delay > MaxTimelockDelay {
+		return fmt.Errorf("timelock delay must be between 0 and %d seconds", MaxTimelockDelay)
+	}
+	previous, err := GetTimelockDelay(ctx)
+	if err != nil {
+		return err
+	}
+	if previous == 0 && delay > 0 {
+		err = cancelPendingOwner(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	if delay == 0 {
+		err = ctx.DelStateWithoutKYC(timelockDelayKey)
+	} else {
+		err = ctx.PutStateWithoutKYC(timelockDelayKey, []byte(strconv.FormatInt(delay, 10)))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set timelock delay: %v", err)
+	}
+	return nil
+}
+
+func emitTimelockEvent(ctx kalpsdk.TransactionContextInterface, eventName string, operation *TimelockOperation, sender string) error {
+	timelockEvent := TimelockEvent{ID: operation.ID, Action: operation.Action, Args: operation.Args, ReadyAt: operation.ReadyAt, Sender: sender}
+	return events.Emit(ctx, eventName, &timelockEvent)
+}
@@ -0,0 +1,98 @@
+// Package audit keeps an append-only log of the privileged actions taken on a contract.
+//
+// Entries are keyed by transaction timestamp and ID, so the log reads in chronological order and
+// an entry can be traced back to its transaction; a digest of the entry separates the entries of
+// one transaction. Nothing in the package updates or deletes an entry once written.
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/canonical"
+	"github.com/thekalpstudio/kush-go/pagination"
+)
+
+const auditPrefix = "audit~entry"
+
+// Entry records one privileged action. Params are the arguments of the action as strings.
+type Entry struct {
+	TxID      string   `json:"txId"`
+	Actor     string   `json:"actor"`
+	Action    string   `json:"action"`
+	Params    []string `json:"params"`
+	Timestamp int64    `json:"timestamp"`
+}
+
+// Page is a page of the audit log. Bookmark is passed to GetAuditLog to read the next page and is
+// empty on the last one.
+type Page struct {
+	Entries  []*Entry `json:"entries"`
+	Bookmark string   `json:"bookmark"`
+}
+
+// Record appends an entry for action, taken by the caller with params.
+func Record(ctx kalpsdk.TransactionContextInterface, action string, params ...string) error {
+	actor, err := ctx.GetUserID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+	timestamp, err := ctx.GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	if params == nil {
+		params = []string{}
+	}
+	entry := &Entry{ctx.GetTxID(), actor, action, params, timestamp.GetSeconds()}
+
+	auditKey, err := ctx.CreateCompositeKey(auditPrefix, []string{fmt.Sprintf("%020d", entry.Timestamp), entry.TxID, entryDigest(entry)})
+	if err != nil {
+		return fmt.Errorf("failed to create the composite key for prefix %s: %v", auditPrefix, err)
+	}
+	entryJSON, err := canonical.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	err = ctx.PutStateWithoutKYC(auditKey, entryJSON)
+	if err != nil {
+		return fmt.Errorf("failed to record audit entry: %v", err)
+	}
+	return nil
+}
+
+// GetAuditLog returns up to pageSize entries starting at bookmark, oldest first. An empty bookmark
+// starts at the beginning of the log. It uses paginated queries, so it has to be evaluated, not
+// submitted.
+func GetAuditLog(ctx kalpsdk.TransactionContextInterface, bookmark string, pageSize int) (*Page, error) {
+	states, next, err := pagination.ByPartialCompositeKey(ctx, auditPrefix, []string{}, bookmark, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	page := &Page{Entries: []*Entry{}, Bookmark: next}
+	for _, state := range states {
+		entry := new(Entry)
+		err = json.Unmarshal(state.Value, entry)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode audit entry %s: %v", state.Key, err)
+		}
+		page.Entries = append(page.Entries, entry)
+	}
+	return page, nil
+}
+
+// entryDigest tells apart the entries of one transaction. It has to be derived from the entry
+// itself: reads do not see earlier writes of the transaction, and every endorser must compute the
+// same key. Identical entries of one transaction collapse into one.
+func entryDigest(entry *Entry) string {
+	digest := sha256.New()
+	digest.Write([]byte(entry.Action))
+	for _, param := range entry.Params {
+		digest.Write([]byte{0})
+		digest.Write([]byte(param))
+	}
+	return hex.EncodeToString(digest.Sum(nil))[:16]
+}
@@ -0,0 +1,176 @@
+package benchmarks
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+
+	"github.com/thekalpstudio/kush-go/Contracts/token"
+	"github.com/thekalpstudio/kush-go/config"
+	"github.com/thekalpstudio/kush-go/migration"
+	"github.com/thekalpstudio/kush-go/testutil"
+)
+
+const admin = "admin"
+
+// stateSizes are the numbers of holders seeded before a benchmark.
+var stateSizes = []int{10, 100, 1000}
+
+// commit runs fn as one transaction of user and commits it.
+func commit(tb testing.TB, l *testutil.Ledger, user string, function string, fn func(ctx *testutil.Context) error) {
+	tb.Helper()
+	ctx := l.Tx(user, function)
+	err := fn(ctx)
+	if err != nil {
+		tb.Fatalf("%s: %v", function, err)
+	}
+	ctx.Commit()
+}
+
+func initConfig() config.InitConfig {
+	return config.InitConfig{Name: "Bench", Symbol: "BNC", AuthorizedOrgs: []string{testutil.MSPID}}
+}
+
+// newERC20 initializes an ERC20 token whose admin sent 1000 base units to each of holders
+// accounts.
+func newERC20(tb testing.TB, holders int) (*testutil.Ledger, *token.TokenERC20Contract) {
+	l := testutil.NewLedger()
+	contract := new(token.TokenERC20Contract)
+	commit(tb, l, admin, "Initialize", func(ctx *testutil.Context) error {
+		_, err := contract.Initialize(ctx, initConfig())
+		return err
+	})
+	commit(tb, l, admin, "Mint", func(ctx *testutil.Context) error {
+		return contract.Mint(ctx, "1000000000000000000000")
+	})
+	for i := 0; i < holders; i++ {
+		commit(tb, l, admin, "Transfer", func(ctx *testutil.Context) error {
+			return contract.Transfer(ctx, fmt.Sprintf("holder%d", i), "1000")
+		})
+	}
+	return l, contract
+}
+
+func BenchmarkERC20Transfer(b *testing.B) {
+	for _, holders := range stateSizes {
+		b.Run("holders="+strconv.Itoa(holders), func(b *testing.B) {
+			l, contract := newERC20(b, holders)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				commit(b, l, admin, "Transfer", func(ctx *testutil.Context) error {
+					return contract.Transfer(ctx, fmt.Sprintf("holder%d", i%holders), "1")
+				})
+			}
+		})
+	}
+}
+
+func BenchmarkERC20BalanceOf(b *testing.B) {
+	for _, holders := range stateSizes {
+		b.Run("holders="+strconv.Itoa(holders), func(b *testing.B) {
+			l, contract := newERC20(b, holders)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, err := contract.BalanceOf(l.Tx(admin, "BalanceOf"), fmt.Sprintf("holder%d", i%holders))
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// erc1155IDs are the token types newERC1155 mints.
+var erc1155IDs = []uint64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10}
+
+// newERC1155 initializes an ERC1155 collection whose admin sent 10 tokens of every type to each of
+// holders accounts.
+func newERC1155(tb testing.TB, holders int) (*testutil.Ledger, *token.SmartContract) {
+	l := testutil.NewLedger()
+	contract := new(token.SmartContract)
+	commit(tb, l, admin, "Initialize", func(ctx *testutil.Context) error {
+		_, err := contract.Initialize(ctx, initConfig())
+		return err
+	})
+	amounts := make([]uint64, len(erc1155IDs))
+	for i := range amounts {
+		amounts[i] = 1 << 40
+	}
+	commit(tb, l, admin, "MintBatch", func(ctx *testutil.Context) error {
+		return contract.MintBatch(ctx, testutil.ClientID(admin), erc1155IDs, amounts)
+	})
+	for i := range amounts {
+		amounts[i] = 10
+	}
+	for i := 0; i < holders; i++ {
+		commit(tb, l, admin, "BatchTransferFrom", func(ctx *testutil.Context) error {
+			return contract.BatchTransferFrom(ctx, testutil.ClientID(admin), testutil.ClientID(fmt.Sprintf("holder%d", i)), erc1155IDs, amounts)
+		})
+	}
+	return l, contract
+}
+
+func BenchmarkERC1155BatchTransferFrom(b *testing.B) {
+	amounts := []uint64{1, 1, 1, 1, 1, 1, 1, 1, 1, 1}
+	for _, holders := range stateSizes {
+		b.Run("holders="+strconv.Itoa(holders), func(b *testing.B) {
+			l, contract := newERC1155(b, holders)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				commit(b, l, admin, "BatchTransferFrom", func(ctx *testutil.Context) error {
+					return contract.BatchTransferFrom(ctx, testutil.ClientID(admin), testutil.ClientID(fmt.Sprintf("holder%d", i%holders)), erc1155IDs, amounts)
+				})
+			}
+		})
+	}
+}
+
+func BenchmarkERC1155BalanceOf(b *testing.B) {
+	for _, holders := range stateSizes {
+		b.Run("holders="+strconv.Itoa(holders), func(b *testing.B) {
+			l, contract := newERC1155(b, holders)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				_, err := contract.BalanceOf(l.Tx(admin, "BalanceOf"), testutil.ClientID(fmt.Sprintf("holder%d", i%holders)), 1)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkERC1155RemoveBalance debits a balance fragmented into legacy records, as left by
+// deployments older than the ERC1155BalancesV2 upgrade, through a transfer.
+func BenchmarkERC1155RemoveBalance(b *testing.B) {
+	for _, records := range []int{1, 10, 100} {
+		b.Run("records="+strconv.Itoa(records), func(b *testing.B) {
+			l, contract := newERC1155(b, 10)
+			sender := testutil.ClientID("fragmented")
+			commit(b, l, admin, "SetVersion", func(ctx *testutil.Context) error {
+				return migration.SetVersion(ctx, 1)
+			})
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				commit(b, l, admin, "seed", func(ctx *testutil.Context) error {
+					for r := 0; r < records; r++ {
+						key, err := ctx.CreateCompositeKey("account~tokenId~sender", []string{sender, "1", fmt.Sprintf("sender%d", r)})
+						if err != nil {
+							return err
+						}
+						err = ctx.PutStateWithoutKYC(key, []byte("10"))
+						if err != nil {
+							return err
+						}
+					}
+					return nil
+				})
+				b.StartTimer()
+				commit(b, l, "fragmented", "BatchTransferFrom", func(ctx *testutil.Context) error {
+					return contract.BatchTransferFrom(ctx, sender, testutil.ClientID(admin), []uint64{1}, []uint64{5})
+				})
+			}
+		})
+	}
+}
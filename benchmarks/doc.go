@@ -0,0 +1,3 @@
+// Package benchmarks measures the contracts against the in-memory ledger of testutil, so
+// performance regressions show up in go test -bench before a deployment does.
+package benchmarks
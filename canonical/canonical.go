@@ -0,0 +1,30 @@
+// Package canonical encodes values as canonical JSON: object keys sorted, no insignificant
+// whitespace and numbers kept exactly as encoding/json writes them.
+//
+// encoding/json writes struct fields in declaration order, so reordering or embedding fields
+// changes the bytes stored for the same value. Contracts persist state and emit events through
+// Marshal, so the stored bytes and their hashes only change when a value does, whichever version of
+// a struct or peer wrote them. Values still decode with encoding/json.
+package canonical
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// Marshal returns the canonical JSON encoding of v. It encodes v with encoding/json, honouring
+// struct tags and Marshaler implementations, and re-encodes the result with sorted keys.
+func Marshal(v interface{}) ([]byte, error) {
+	encoded, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(encoded))
+	decoder.UseNumber()
+	var value interface{}
+	err = decoder.Decode(&value)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(value)
+}
@@ -0,0 +1,19 @@
+// Command kush-sim-erc721 invokes the ERC721 contract on an in-memory ledger kept in a local JSON
+// file, as kush-sim does for the ERC20 and ERC1155 contracts:
+//
+//	kush-sim-erc721 erc721 Initialize '{"name":"Kush","symbol":"KSH","authorizedOrgs":["Org1MSP"]}'
+//	kush-sim-erc721 erc721 MintWithTokenURI 1 https://example.com/1.json
+//	kush-sim-erc721 erc721 TransferFrom admin bob 1
+//	kush-sim-erc721 erc721 OwnerOf 1
+package main
+
+import (
+	"github.com/thekalpstudio/kush-go/contracts/token"
+	"github.com/thekalpstudio/kush-go/internal/sim"
+)
+
+func main() {
+	sim.Main("kush-sim-erc721", map[string]interface{}{
+		"erc721": new(token.TokenERC721Contract),
+	})
+}
@@ -0,0 +1,24 @@
+// Command kush-sim invokes the ERC20 and ERC1155 contracts on an in-memory ledger kept in a local
+// JSON file, to try them out without deploying chaincode:
+//
+//	kush-sim erc20 Initialize '{"name":"Kush","symbol":"KSH","authorizedOrgs":["Org1MSP"]}'
+//	kush-sim erc20 Mint 1000
+//	kush-sim erc20 Transfer bob 10
+//	kush-sim -user bob erc20 ClientAccountBalance
+//
+// Run without a command, it reads commands from the standard input; kush-sim -h lists them. The
+// ERC721 contract has a simulator of its own, kush-sim-erc721, as Go cannot build its package into
+// the same binary as the ERC20 one; both can share a state file.
+package main
+
+import (
+	"github.com/thekalpstudio/kush-go/Contracts/token"
+	"github.com/thekalpstudio/kush-go/internal/sim"
+)
+
+func main() {
+	sim.Main("kush-sim", map[string]interface{}{
+		"erc20":   new(token.TokenERC20Contract),
+		"erc1155": new(token.SmartContract),
+	})
+}
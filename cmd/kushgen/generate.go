@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+//go:embed templates
+var templates embed.FS
+
+// standards are the token standards kushgen generates contracts for.
+var standards = []string{"erc20", "erc721", "erc1155"}
+
+// options describe the contract to generate.
+type options struct {
+	// Standard is one of standards.
+	Standard string
+	// Type names the contract, which is generated as <Type>Contract.
+	Type string
+	// Package is the name of the generated package.
+	Package string
+	// Prefix starts every key of the contract and names its event schemas.
+	Prefix string
+}
+
+// validate checks o before it is used in generated code.
+func (o options) validate() error {
+	known := false
+	for _, standard := range standards {
+		known = known || o.Standard == standard
+	}
+	if !known {
+		return fmt.Errorf("unknown standard %q, want one of %s", o.Standard, strings.Join(standards, ", "))
+	}
+	if !token.IsIdentifier(o.Type) || !token.IsExported(o.Type) {
+		return fmt.Errorf("name %q must be an exported Go identifier", o.Type)
+	}
+	if !token.IsIdentifier(o.Package) || strings.ToLower(o.Package) != o.Package {
+		return fmt.Errorf("package %q must be a lower case Go identifier", o.Package)
+	}
+	if o.Prefix == "" {
+		return fmt.Errorf("prefix must not be empty")
+	}
+	for _, r := range o.Prefix {
+		// the prefix is quoted in keys and schema names; composite keys reserve \x00
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+			return fmt.Errorf("prefix %q may only hold letters, digits, _ and -", o.Prefix)
+		}
+	}
+	return nil
+}
+
+// render returns the files of the contract o describes, by name: the contract, its events, the
+// role functions every contract exposes, and tests running it on the in-memory ledger of testutil.
+func render(o options) (map[string][]byte, error) {
+	err := o.validate()
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string][]byte)
+	for _, dir := range []string{"templates/common", "templates/" + o.Standard} {
+		entries, err := fs.ReadDir(templates, dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			name := strings.TrimSuffix(entry.Name(), ".tmpl")
+			t, err := template.ParseFS(templates, path.Join(dir, entry.Name()))
+			if err != nil {
+				return nil, err
+			}
+			var source bytes.Buffer
+			err = t.Execute(&source, o)
+			if err != nil {
+				return nil, fmt.Errorf("failed to generate %s: %v", name, err)
+			}
+			formatted, err := format.Source(source.Bytes())
+			if err != nil {
+				return nil, fmt.Errorf("generated %s does not parse: %v", name, err)
+			}
+			files[name] = formatted
+		}
+	}
+	return files, nil
+}
+
+// fileNames returns the names of files in order.
+func fileNames(files map[string][]byte) []string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
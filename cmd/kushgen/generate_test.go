@@ -0,0 +1,68 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	valid := options{Standard: "erc20", Type: "Points", Package: "points", Prefix: "points"}
+	tests := []struct {
+		name    string
+		edit    func(o *options)
+		wantErr bool
+	}{
+		{name: "valid", edit: func(o *options) {}},
+		{name: "unknown standard", edit: func(o *options) { o.Standard = "erc777" }, wantErr: true},
+		{name: "unexported name", edit: func(o *options) { o.Type = "points" }, wantErr: true},
+		{name: "name with a dash", edit: func(o *options) { o.Type = "Loyalty-Points" }, wantErr: true},
+		{name: "upper case package", edit: func(o *options) { o.Package = "Points" }, wantErr: true},
+		{name: "empty prefix", edit: func(o *options) { o.Prefix = "" }, wantErr: true},
+		{name: "prefix with a quote", edit: func(o *options) { o.Prefix = `a"b` }, wantErr: true},
+		{name: "prefix with a composite key separator", edit: func(o *options) { o.Prefix = "a\x00b" }, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := valid
+			tt.edit(&o)
+			err := o.validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("validate() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// TestGeneratedContracts generates a contract of every standard into the module and runs go vet
+// and the generated tests on it. The directory starts with _, so ./... patterns skip it.
+func TestGeneratedContracts(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds the generated packages")
+	}
+	for _, standard := range standards {
+		t.Run(standard, func(t *testing.T) {
+			dir, err := os.MkdirTemp(".", "_"+standard)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(dir)
+			o := options{Standard: standard, Type: "Sample", Package: "sample", Prefix: "sample"}
+			err = generate(o, dir, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := generate(o, dir, false); err == nil {
+				t.Fatal("existing files were overwritten without -force")
+			}
+			for _, args := range [][]string{{"vet"}, {"test"}} {
+				cmd := exec.Command("go", append(args, "./"+filepath.ToSlash(dir))...)
+				out, err := cmd.CombinedOutput()
+				if err != nil {
+					t.Fatalf("go %s: %v\n%s", args[0], err, out)
+				}
+			}
+		})
+	}
+}
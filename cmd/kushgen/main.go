@@ -0,0 +1,76 @@
+// Command kushgen generates the skeleton of a new token contract on the packages of this module:
+// configuration through config.InitConfig, minting and burning behind the roles of accesscontrol,
+// events in the envelope of the events package, keys under a prefix of its own, and tests on the
+// in-memory ledger of testutil.
+//
+//	kushgen -standard erc1155 -name Loyalty -prefix loyalty -out ./loyalty
+//
+// The generated package builds and passes its tests as it is; it is meant to be edited from there.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	var o options
+	flag.StringVar(&o.Standard, "standard", "", "token standard, one of "+strings.Join(standards, ", "))
+	flag.StringVar(&o.Type, "name", "", "`name` of the contract, generated as <name>Contract")
+	flag.StringVar(&o.Package, "package", "", "`name` of the generated package (default the lower case name)")
+	flag.StringVar(&o.Prefix, "prefix", "", "`prefix` of the keys of the contract (default the package)")
+	out := flag.String("out", "", "`directory` to write the package to (default ./<package>)")
+	force := flag.Bool("force", false, "overwrite existing files")
+	flag.Parse()
+
+	if o.Package == "" {
+		o.Package = strings.ToLower(o.Type)
+	}
+	if o.Prefix == "" {
+		o.Prefix = o.Package
+	}
+	if *out == "" {
+		*out = o.Package
+	}
+	err := generate(o, *out, *force)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "kushgen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// generate writes the files of o to dir. Existing files are only overwritten with force, and none
+// is written if one exists.
+func generate(o options, dir string, force bool) error {
+	files, err := render(o)
+	if err != nil {
+		return err
+	}
+	if !force {
+		for name := range files {
+			_, err = os.Stat(filepath.Join(dir, name))
+			if err == nil {
+				return fmt.Errorf("%s already exists, use -force to overwrite it", filepath.Join(dir, name))
+			}
+			if !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+		}
+	}
+	err = os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return err
+	}
+	for _, name := range fileNames(files) {
+		err = os.WriteFile(filepath.Join(dir, name), files[name], 0o644)
+		if err != nil {
+			return err
+		}
+		fmt.Println(filepath.Join(dir, name))
+	}
+	return nil
+}
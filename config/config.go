@@ -0,0 +1,498 @@
+// Package config defines the configuration the token contracts are initialized with.
+//
+// ERC20, ERC721 and ERC1155 all take an InitConfig in Initialize and validate it the same way, so
+// their setup semantics cannot drift apart. Name, symbol, decimals and the authorized orgs are
+// fixed at Initialize (orgs change through AddAuthorizedOrg and RemoveAuthorizedOrg only); the max
+// supply, base URI, max balance keys and feature flags may later change through Update.
+package config
+
+import (
+	"fmt"
+	"math/big"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+	"github.com/thekalpstudio/kush-go/audit"
+	"github.com/thekalpstudio/kush-go/canonical"
+	"github.com/thekalpstudio/kush-go/internal/readcache"
+	"github.com/thekalpstudio/kush-go/migration"
+)
+
+const maxSupplyKey = "config~maxSupply"
+const baseURIKey = "config~baseURI"
+const strictURIKey = "config~strictURI"
+const deltaBalancesKey = "config~deltaBalances"
+const maxBalanceKeysKey = "config~maxBalanceKeys"
+const initializedKey = "config~initialized"
+
+// DefaultMaxBalanceKeys is used when MaxBalanceKeys is not set.
+const DefaultMaxBalanceKeys = 100
+
+// uriSchemes are the schemes ValidateURI accepts.
+var uriSchemes = []string{"https", "ipfs", "ar"}
+
+// InitConfig configures a token contract. MaxSupply is a decimal number of base units, "" for an
+// uncapped supply; on ERC1155 it caps the supply of each token type. Decimals must be 0 for
+// non-fungible contracts. MaxBalanceKeys bounds the balance records of an account an ERC1155
+// transaction reads, see GetMaxBalanceKeys. ImportState leaves the state import of package migration
+// open after Initialize, for a deployment taking over the exported state of a previous one until it
+// calls SealImport; Initialize seals the import otherwise. The peer validates the argument of Initialize and the
+// result of GetConfig against the contract metadata, where the omitempty fields are optional.
+type InitConfig struct {
+	Name           string   `json:"name"`
+	Symbol         string   `json:"symbol"`
+	Decimals       int      `json:"decimals,omitempty" metadata:",optional"`
+	MaxSupply      string   `json:"maxSupply,omitempty" metadata:",optional"`
+	AuthorizedOrgs []string `json:"authorizedOrgs"`
+	BaseURI        string   `json:"baseURI,omitempty" metadata:",optional"`
+	MaxBalanceKeys int      `json:"maxBalanceKeys,omitempty" metadata:",optional"`
+	Features       Features `json:"features,omitempty" metadata:",optional"`
+	ImportState    bool     `json:"importState,omitempty" metadata:",optional"`
+}
+
+// Features are optional behaviours switched on per deployment. Its flags are always encoded and
+// required when Features is given: contractapi rejects the schema of a struct without a required
+// field.
+type Features struct {
+	// RequireKYC makes state writes require a KYC'd caller, see accesscontrol.KYCConfig.
+	RequireKYC bool `json:"requireKYC"`
+	// StrictURI makes the base URI, and the URIs an ERC1155 collection sets through SetURI and
+	// SetTokenURI, pass ValidateURI.
+	StrictURI bool `json:"strictURI"`
+	// DeltaBalances makes ERC20 credits append delta records instead of rewriting the balance of
+	// the recipient, so concurrent transfers to the same account do not conflict.
+	DeltaBalances bool `json:"deltaBalances"`
+}
+
+// Validate checks cfg for a contract supporting up to maxDecimals decimals.
+func (cfg InitConfig) Validate(maxDecimals int) error {
+	if cfg.Name == "" || cfg.Symbol == "" {
+		return fmt.Errorf("name and symbol must be set")
+	}
+	if cfg.Decimals < 0 || cfg.Decimals > maxDecimals {
+		return fmt.Errorf("decimals must be between 0 and %d", maxDecimals)
+	}
+	if len(cfg.AuthorizedOrgs) == 0 {
+		return fmt.Errorf("at least one authorized org must be set")
+	}
+	if cfg.MaxBalanceKeys < 0 {
+		return fmt.Errorf("max balance keys must not be negative")
+	}
+	if cfg.Features.StrictURI && cfg.BaseURI != "" {
+		err := ValidateURI(cfg.BaseURI)
+		if err != nil {
+			return err
+		}
+	}
+	_, err := ParseMaxSupply(cfg.MaxSupply)
+	return err
+}
+
+// ValidateURI checks uri for strict mode: it must be an absolute https, ipfs or ar URL without
+// whitespace, and may contain the {id} placeholder at most once and no other braces.
+func ValidateURI(uri string) error {
+	if strings.Count(uri, "{id}") > 1 {
+		return fmt.Errorf("uri %q must contain {id} at most once", uri)
+	}
+	concrete := strings.Replace(uri, "{id}", "0", 1)
+	if strings.ContainsAny(concrete, "{} \t\r\n") {
+		return fmt.Errorf("uri %q may not contain whitespace or braces other than {id}", uri)
+	}
+	parsed, err := url.Parse(concrete)
+	if err != nil {
+		return fmt.Errorf("uri %q is not a valid URL: %v", uri, err)
+	}
+	if !containsString(uriSchemes, parsed.Scheme) || parsed.Host == "" {
+		return fmt.Errorf("uri %q must be an absolute URL with one of the schemes %v", uri, uriSchemes)
+	}
+	return nil
+}
+
+// ParseMaxSupply converts a MaxSupply field into a big.Int, nil for an uncapped supply.
+func ParseMaxSupply(maxSupply string) (*big.Int, error) {
+	if maxSupply == "" {
+		return nil, nil
+	}
+	value, ok := new(big.Int).SetString(maxSupply, 10)
+	if !ok || value.Sign() <= 0 {
+		return nil, fmt.Errorf("max supply %q must be a positive integer", maxSupply)
+	}
+	return value, nil
+}
+
+// Initialize validates cfg and stores its authorized orgs, max supply, base URI and features, and
+// seals the state import unless cfg.ImportState is set.
+// Contracts store name, symbol and decimals under their own keys. It runs accesscontrol.Initialize,
+// so it succeeds only once per contract.
+func Initialize(ctx kalpsdk.TransactionContextInterface, cfg InitConfig, maxDecimals int) error {
+	err := cfg.Validate(maxDecimals)
+	if err != nil {
+		return err
+	}
+	err = accesscontrol.Initialize(ctx, cfg.AuthorizedOrgs)
+	if err != nil {
+		return fmt.Errorf("client is not authorized to initialize contract: %v", err)
+	}
+	err = accesscontrol.InitializeKYC(ctx, cfg.Features.RequireKYC)
+	if err != nil {
+		return err
+	}
+	maxSupply, err := ParseMaxSupply(cfg.MaxSupply)
+	if err != nil {
+		return err
+	}
+	err = writeMaxSupply(ctx, maxSupply)
+	if err != nil {
+		return err
+	}
+	err = writeFlag(ctx, strictURIKey, cfg.Features.StrictURI, "strict URI mode")
+	if err != nil {
+		return err
+	}
+	err = writeFlag(ctx, deltaBalancesKey, cfg.Features.DeltaBalances, "delta balances")
+	if err != nil {
+		return err
+	}
+	err = writeMaxBalanceKeys(ctx, cfg.MaxBalanceKeys)
+	if err != nil {
+		return err
+	}
+	err = writeBaseURI(ctx, cfg.BaseURI)
+	if err != nil {
+		return err
+	}
+	if !cfg.ImportState {
+		err = migration.SealImport(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	err = ctx.PutStateWithoutKYC(initializedKey, []byte("1"))
+	if err != nil {
+		return fmt.Errorf("failed to mark contract as initialized: %v", err)
+	}
+	return nil
+}
+
+// IsInitialized reports whether Initialize has run. It reads a one-byte marker once per
+// transaction, so the checks at the top of every function cost a single read, and falls back to
+// legacyKey, the name key of the contract, for contracts initialized before the marker existed.
+func IsInitialized(ctx kalpsdk.TransactionContextInterface, legacyKey string) (bool, error) {
+	marker, err := readcache.GetState(ctx, initializedKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to read initialization marker: %v", err)
+	}
+	if marker != nil {
+		return true, nil
+	}
+	name, err := readcache.GetState(ctx, legacyKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to get token name: %v", err)
+	}
+	return name != nil, nil
+}
+
+// Read returns the stored configuration of a contract with the given name, symbol and decimals.
+func Read(ctx kalpsdk.TransactionContextInterface, name string, symbol string, decimals int) (*InitConfig, error) {
+	authorizedOrgs, err := accesscontrol.GetAuthorizedMSPs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	maxSupplyBytes, err := ctx.GetState(maxSupplyKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read max supply: %v", err)
+	}
+	baseURI, err := GetBaseURI(ctx)
+	if err != nil {
+		return nil, err
+	}
+	kycConfig, err := accesscontrol.GetKYCConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	strictURI, err := GetStrictURI(ctx)
+	if err != nil {
+		return nil, err
+	}
+	deltaBalances, err := GetDeltaBalances(ctx)
+	if err != nil {
+		return nil, err
+	}
+	maxBalanceKeys, err := readMaxBalanceKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &InitConfig{
+		Name:           name,
+		Symbol:         symbol,
+		Decimals:       decimals,
+		MaxSupply:      string(maxSupplyBytes),
+		AuthorizedOrgs: authorizedOrgs,
+		BaseURI:        baseURI,
+		MaxBalanceKeys: maxBalanceKeys,
+		Features:       Features{RequireKYC: kycConfig.Required, StrictURI: strictURI, DeltaBalances: deltaBalances},
+	}, nil
+}
+
+// Hooks let a contract vet or store the fields of an Update it keeps itself.
+type Hooks struct {
+	// CheckMaxSupply vets a new max supply against the tokens already in existence.
+	CheckMaxSupply func(maxSupply *big.Int) error
+	// SetBaseURI replaces the default storage of the base URI.
+	SetBaseURI func(baseURI string) error
+}
+
+// Update applies the max supply, base URI, max balance keys and features of update. update must repeat the name,
+// symbol and decimals of current and leave AuthorizedOrgs empty or unchanged. The caller must hold
+// AdminRole. While multisig approval or the timelock is enabled the update has to be an
+// accesscontrol.ActionUpdateConfig proposal or operation of the JSON update instead, which
+// contracts execute with Apply.
+func Update(ctx kalpsdk.TransactionContextInterface, current *InitConfig, update InitConfig, hooks Hooks) error {
+	err := accesscontrol.CheckRole(ctx, accesscontrol.AdminRole)
+	if err != nil {
+		return err
+	}
+	err = accesscontrol.CheckNotMultisig(ctx, accesscontrol.ActionUpdateConfig)
+	if err != nil {
+		return err
+	}
+	err = accesscontrol.CheckNotTimelocked(ctx, accesscontrol.ActionUpdateConfig)
+	if err != nil {
+		return err
+	}
+	return Apply(ctx, current, update, hooks)
+}
+
+// Apply applies update as Update does, leaving authorization to the caller.
+func Apply(ctx kalpsdk.TransactionContextInterface, current *InitConfig, update InitConfig, hooks Hooks) error {
+	if update.Name != current.Name || update.Symbol != current.Symbol || update.Decimals != current.Decimals {
+		return fmt.Errorf("name, symbol and decimals cannot be changed")
+	}
+	if len(update.AuthorizedOrgs) > 0 && !equalStrings(update.AuthorizedOrgs, current.AuthorizedOrgs) {
+		return fmt.Errorf("authorized orgs change through AddAuthorizedOrg and RemoveAuthorizedOrg")
+	}
+	maxSupply, err := ParseMaxSupply(update.MaxSupply)
+	if err != nil {
+		return err
+	}
+
+	if formatMaxSupply(maxSupply) != current.MaxSupply {
+		if maxSupply != nil && hooks.CheckMaxSupply != nil {
+			err = hooks.CheckMaxSupply(maxSupply)
+			if err != nil {
+				return err
+			}
+		}
+		err = writeMaxSupply(ctx, maxSupply)
+		if err != nil {
+			return err
+		}
+	}
+	if update.BaseURI != current.BaseURI {
+		if update.Features.StrictURI && update.BaseURI != "" {
+			err = ValidateURI(update.BaseURI)
+			if err != nil {
+				return err
+			}
+		}
+		if hooks.SetBaseURI != nil {
+			err = hooks.SetBaseURI(update.BaseURI)
+		} else {
+			err = writeBaseURI(ctx, update.BaseURI)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if update.MaxBalanceKeys != current.MaxBalanceKeys {
+		if update.MaxBalanceKeys < 0 {
+			return fmt.Errorf("max balance keys must not be negative")
+		}
+		err = writeMaxBalanceKeys(ctx, update.MaxBalanceKeys)
+		if err != nil {
+			return err
+		}
+	}
+	if update.Features.RequireKYC != current.Features.RequireKYC {
+		err = accesscontrol.WriteKYCRequired(ctx, update.Features.RequireKYC)
+		if err != nil {
+			return err
+		}
+	}
+	if update.Features.StrictURI != current.Features.StrictURI {
+		err = writeFlag(ctx, strictURIKey, update.Features.StrictURI, "strict URI mode")
+		if err != nil {
+			return err
+		}
+	}
+	if update.Features.DeltaBalances != current.Features.DeltaBalances {
+		err = writeFlag(ctx, deltaBalancesKey, update.Features.DeltaBalances, "delta balances")
+		if err != nil {
+			return err
+		}
+	}
+	updateJSON, err := canonical.Marshal(update)
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	return audit.Record(ctx, "UpdateConfig", string(updateJSON))
+}
+
+// GetMaxSupply returns the configured max supply, nil if the supply is uncapped.
+func GetMaxSupply(ctx kalpsdk.TransactionContextInterface) (*big.Int, error) {
+	maxSupplyBytes, err := ctx.GetState(maxSupplyKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read max supply: %v", err)
+	}
+	return ParseMaxSupply(string(maxSupplyBytes))
+}
+
+// CheckMaxSupply fails if supply exceeds the configured max supply.
+func CheckMaxSupply(ctx kalpsdk.TransactionContextInterface, supply *big.Int) error {
+	maxSupply, err := GetMaxSupply(ctx)
+	if err != nil {
+		return err
+	}
+	if maxSupply != nil && supply.Cmp(maxSupply) > 0 {
+		return fmt.Errorf("supply of %s would exceed the max supply of %s", supply, maxSupply)
+	}
+	return nil
+}
+
+// GetBaseURI returns the configured base URI, or "".
+func GetBaseURI(ctx kalpsdk.TransactionContextInterface) (string, error) {
+	baseURIBytes, err := ctx.GetState(baseURIKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to read base URI: %v", err)
+	}
+	return string(baseURIBytes), nil
+}
+
+// GetStrictURI reports whether URIs have to pass ValidateURI.
+func GetStrictURI(ctx kalpsdk.TransactionContextInterface) (bool, error) {
+	return readFlag(ctx, strictURIKey, "strict URI mode")
+}
+
+// GetDeltaBalances reports whether ERC20 credits are stored as delta records.
+func GetDeltaBalances(ctx kalpsdk.TransactionContextInterface) (bool, error) {
+	return readFlag(ctx, deltaBalancesKey, "delta balances")
+}
+
+// GetMaxBalanceKeys returns the number of balance records of an account an ERC1155 transaction may
+// read, DefaultMaxBalanceKeys if none is configured.
+func GetMaxBalanceKeys(ctx kalpsdk.TransactionContextInterface) (int, error) {
+	maxBalanceKeys, err := readMaxBalanceKeys(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if maxBalanceKeys == 0 {
+		return DefaultMaxBalanceKeys, nil
+	}
+	return maxBalanceKeys, nil
+}
+
+func formatMaxSupply(maxSupply *big.Int) string {
+	if maxSupply == nil {
+		return ""
+	}
+	return maxSupply.String()
+}
+
+func writeMaxSupply(ctx kalpsdk.TransactionContextInterface, maxSupply *big.Int) error {
+	var err error
+	if maxSupply == nil {
+		err = ctx.DelStateWithoutKYC(maxSupplyKey)
+	} else {
+		err = ctx.PutStateWithoutKYC(maxSupplyKey, []byte(maxSupply.String()))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set max supply: %v", err)
+	}
+	return nil
+}
+
+func readMaxBalanceKeys(ctx kalpsdk.TransactionContextInterface) (int, error) {
+	maxBalanceKeysBytes, err := ctx.GetState(maxBalanceKeysKey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read max balance keys: %v", err)
+	}
+	if maxBalanceKeysBytes == nil {
+		return 0, nil
+	}
+	maxBalanceKeys, err := strconv.Atoi(string(maxBalanceKeysBytes))
+	if err != nil {
+		return 0, fmt.Errorf("failed to decode max balance keys: %v", err)
+	}
+	return maxBalanceKeys, nil
+}
+
+func writeMaxBalanceKeys(ctx kalpsdk.TransactionContextInterface, maxBalanceKeys int) error {
+	var err error
+	if maxBalanceKeys == 0 {
+		err = ctx.DelStateWithoutKYC(maxBalanceKeysKey)
+	} else {
+		err = ctx.PutStateWithoutKYC(maxBalanceKeysKey, []byte(strconv.Itoa(maxBalanceKeys)))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set max balance keys: %v", err)
+	}
+	return nil
+}
+
+func writeBaseURI(ctx kalpsdk.TransactionContextInterface, baseURI string) error {
+	var err error
+	if baseURI == "" {
+		err = ctx.DelStateWithoutKYC(baseURIKey)
+	} else {
+		err = ctx.PutStateWithoutKYC(baseURIKey, []byte(baseURI))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set base URI: %v", err)
+	}
+	return nil
+}
+
+func readFlag(ctx kalpsdk.TransactionContextInterface, key string, name string) (bool, error) {
+	flagBytes, err := ctx.GetState(key)
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s: %v", name, err)
+	}
+	return flagBytes != nil, nil
+}
+
+func writeFlag(ctx kalpsdk.TransactionContextInterface, key string, set bool, name string) error {
+	var err error
+	if set {
+		err = ctx.PutStateWithoutKYC(key, []byte("true"))
+	} else {
+		err = ctx.DelStateWithoutKYC(key)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set %s: %v", name, err)
+	}
+	return nil
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func equalStrings(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
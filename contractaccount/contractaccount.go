@@ -0,0 +1,34 @@
+// Package contractaccount names the accounts owned by a chaincode rather than a user, shared by
+// the fungible and non-fungible token contracts.
+//
+// A contract account ID is contract::<chaincode>[::<sub account>]. Fabric forwards the end user's
+// identity on InvokeChaincode, so a chaincode can never sign for its own tokens; the contracts
+// instead check which chaincode a transaction was proposed to before debiting its accounts, and
+// ask the owning chaincode to acknowledge tokens sent with a safe transfer.
+package contractaccount
+
+import "strings"
+
+// Prefix marks account IDs that are owned by a chaincode.
+const Prefix = "contract::"
+
+// ID returns the account ID owned by chaincodeName. subAccount lets a single chaincode keep
+// several segregated accounts (one per wallet, vault, etc.) and may be empty.
+func ID(chaincodeName string, subAccount string) string {
+	if subAccount == "" {
+		return Prefix + chaincodeName
+	}
+	return Prefix + chaincodeName + "::" + subAccount
+}
+
+// Owner returns the chaincode owning account, or false if account is a user account.
+func Owner(account string) (string, bool) {
+	if !strings.HasPrefix(account, Prefix) {
+		return "", false
+	}
+	owner := strings.TrimPrefix(account, Prefix)
+	if i := strings.Index(owner, "::"); i >= 0 {
+		owner = owner[:i]
+	}
+	return owner, owner != ""
+}
@@ -3,7 +3,18 @@ package token
 import (
     "encoding/json"
     "fmt"
+    "math/big"
     "github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+    "github.com/thekalpstudio/kush-go/accesscontrol"
+    "github.com/thekalpstudio/kush-go/audit"
+    "github.com/thekalpstudio/kush-go/canonical"
+    "github.com/thekalpstudio/kush-go/config"
+    "github.com/thekalpstudio/kush-go/denylist"
+    "github.com/thekalpstudio/kush-go/events"
+    "github.com/thekalpstudio/kush-go/kusherrors"
+    "github.com/thekalpstudio/kush-go/logging"
+    "github.com/thekalpstudio/kush-go/metrics"
+    "github.com/thekalpstudio/kush-go/migration"
 )
 
 const balancePrefix = "balance"
@@ -13,26 +24,21 @@ const nameKey1 = "name"
 const symbolKey1 = "symbol"
 
 type Nft struct {
-    TokenId  string `json:"tokenId"`
-    Owner    string `json:"owner"`
-    TokenURI string `json:"tokenURI"`
-    Approved string `json:"approved"`
+    TokenId  string         `json:"tokenId"`
+    Owner    string         `json:"owner"`
+    TokenURI string         `json:"tokenURI"`
+    Approved string         `json:"approved"`
+    Metadata *TokenMetadata `json:"metadata,omitempty" metadata:",optional"`
 }
 
-type Approval struct {
-    Owner    string `json:"owner"`
-    Operator string `json:"operator"`
-    Approved bool   `json:"approved"`
-}
+type Approval = events.ApprovalForAll
 
-type Transfer struct {
-    From    string `json:"from"`
-    To      string `json:"to"`
-    TokenId string `json:"tokenId"`
-}
+type TokenApproval = events.NFTApproval
+
+type Transfer = events.NFTTransfer
 
 type TokenERC721Contract struct {
-    kalpsdk.Contract
+    logging.Contract
 }
 
 func _readNFT(ctx kalpsdk.TransactionContextInterface, tokenId string) (*Nft, error) {
@@ -55,43 +61,48 @@ func _readNFT(ctx kalpsdk.TransactionContextInterface, tokenId string) (*Nft, er
     return nft, nil
 }
 
-func _nftExists(ctx kalpsdk.TransactionContextInterface, tokenId string) bool {
+// _nftExists reports whether tokenId is minted and not burned.
+func _nftExists(ctx kalpsdk.TransactionContextInterface, tokenId string) (bool, error) {
+    if tokenId == "" {
+        return false, fmt.Errorf("token id must not be empty")
+    }
     nftKey, err := ctx.CreateCompositeKey(nftPrefix, []string{tokenId})
     if err != nil {
-        panic("error creating CreateCompositeKey:" + err.Error())
+        return false, fmt.Errorf("failed to CreateCompositeKey %s: %v", tokenId, err)
     }
 
     nftBytes, err := ctx.GetState(nftKey)
     if err != nil {
-        panic("error GetState nftBytes:" + err.Error())
+        return false, fmt.Errorf("failed to GetState %s: %v", nftKey, err)
     }
 
-    return len(nftBytes) > 0
+    return len(nftBytes) > 0, nil
 }
 
-func (c *TokenERC721Contract) BalanceOf(ctx kalpsdk.TransactionContextInterface, owner string) int {
+// BalanceOf returns the number of tokens of owner, from the counter the enumeration index keeps.
+// Until the ERC721EnumerableV1 upgrade has run the counters are incomplete, and the balance keys
+// of owner are counted instead.
+func (c *TokenERC721Contract) BalanceOf(ctx kalpsdk.TransactionContextInterface, owner string) (int, error) {
     initialized, err := checkInitialized1(ctx)
     if err != nil {
-        panic("failed to check if contract is already initialized:" + err.Error())
+        return 0, fmt.Errorf("failed to check if contract is already initialized: %v", err)
     }
     if !initialized {
-        panic("Contract options need to be set before calling any function, call Initialize() to initialize contract")
+        return 0, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
     }
 
-    iterator, err := ctx.GetStateByPartialCompositeKey(balancePrefix, []string{owner})
+    enumerated, err := _isEnumerated(ctx)
     if err != nil {
-        panic("Error creating asset chaincode:" + err.Error())
+        return 0, err
     }
-
-    balance := 0
-    for iterator.HasNext() {
-        _, err := iterator.Next()
-        if err != nil {
-            return 0
-        }
-        balance++
+    if !enumerated {
+        return _countBalanceKeys(ctx, owner)
     }
-    return balance
+    countKey, err := ctx.CreateCompositeKey(ownerCountPrefix, []string{owner})
+    if err != nil {
+        return 0, fmt.Errorf("failed to CreateCompositeKey ownerCountKey: %v", err)
+    }
+    return _readCounter(ctx, countKey)
 }
 func (c *TokenERC721Contract) OwnerOf(ctx kalpsdk.TransactionContextInterface, tokenId string) (string, error) {
     initialized, err := checkInitialized1(ctx)
@@ -99,7 +110,7 @@ func (c *TokenERC721Contract) OwnerOf(ctx kalpsdk.TransactionContextInterface, t
         return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
     }
     if !initialized {
-        return "", fmt.Errorf("Contract options need to be set before calling any function, call Initialize() to initialize contract")
+        return "", kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
     }
 
     nft, err := _readNFT(ctx, tokenId)
@@ -116,7 +127,7 @@ func (c *TokenERC721Contract) Approve(ctx kalpsdk.TransactionContextInterface, o
         return false, fmt.Errorf("failed to check if contract is already initialized: %v", err)
     }
     if !initialized {
-        return false, fmt.Errorf("Contract options need to be set before calling any function, call Initialize() to initialize contract")
+        return false, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
     }
 
     sender, err := ctx.GetUserID()
@@ -129,13 +140,23 @@ func (c *TokenERC721Contract) Approve(ctx kalpsdk.TransactionContextInterface, o
         return false, fmt.Errorf("failed to _readNFT: %v", err)
     }
 
+    err = _checkNotPaused(ctx)
+    if err != nil {
+        return false, err
+    }
+
+    err = _checkNotLocked(ctx, tokenId)
+    if err != nil {
+        return false, err
+    }
+
     owner := nft.Owner
     operatorApproval, err := c.IsApprovedForAll(ctx, owner, sender)
     if err != nil {
         return false, fmt.Errorf("failed to get IsApprovedForAll: %v", err)
     }
     if owner != sender && !operatorApproval {
-        return false, fmt.Errorf("the sender is not the current owner nor an authorized operator")
+        return false, kusherrors.Errorf(kusherrors.ErrUnauthorized, "the sender is not the current owner nor an authorized operator")
     }
 
     nft.Approved = operator
@@ -144,16 +165,26 @@ func (c *TokenERC721Contract) Approve(ctx kalpsdk.TransactionContextInterface, o
         return false, fmt.Errorf("failed to CreateCompositeKey %s: %v", nftKey, err)
     }
 
-    nftBytes, err := json.Marshal(nft)
+    nftBytes, err := canonical.Marshal(nft)
     if err != nil {
         return false, fmt.Errorf("failed to marshal nftBytes: %v", err)
     }
 
-    err = ctx.PutStateWithoutKYC(nftKey, nftBytes)
+    err = accesscontrol.PutState(ctx, nftKey, nftBytes)
     if err != nil {
         return false, fmt.Errorf("failed to PutState for nftKey: %v", err)
     }
 
+    approvalEvent := new(TokenApproval)
+    approvalEvent.Owner = owner
+    approvalEvent.Approved = operator
+    approvalEvent.TokenId = tokenId
+
+    err = events.Emit(ctx, "Approval", approvalEvent)
+    if err != nil {
+        return false, err
+    }
+
     return true, nil
 }
 
@@ -163,7 +194,12 @@ func (c *TokenERC721Contract) SetApprovalForAll(ctx kalpsdk.TransactionContextIn
         return false, fmt.Errorf("failed to check if contract is already initialized: %v", err)
     }
     if !initialized {
-        return false, fmt.Errorf("Contract options need to be set before calling any function, call Initialize() to initialize contract")
+        return false, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+    }
+
+    err = _checkNotPaused(ctx)
+    if err != nil {
+        return false, err
     }
 
     sender, err := ctx.GetUserID()
@@ -181,19 +217,19 @@ func (c *TokenERC721Contract) SetApprovalForAll(ctx kalpsdk.TransactionContextIn
         return false, fmt.Errorf("failed to CreateCompositeKey: %v", err)
     }
 
-    approvalBytes, err := json.Marshal(nftApproval)
+    approvalBytes, err := canonical.Marshal(nftApproval)
     if err != nil {
         return false, fmt.Errorf("failed to marshal approvalBytes: %v", err)
     }
 
-    err = ctx.PutStateWithoutKYC(approvalKey, approvalBytes)
+    err = accesscontrol.PutState(ctx, approvalKey, approvalBytes)
     if err != nil {
         return false, fmt.Errorf("failed to PutState approvalBytes: %v", err)
     }
 
-    err = ctx.SetEvent("ApprovalForAll", approvalBytes)
+    err = events.Emit(ctx, "ApprovalForAll", nftApproval)
     if err != nil {
-        return false, fmt.Errorf("failed to SetEvent ApprovalForAll: %v", err)
+        return false, err
     }
 
     return true, nil
@@ -206,7 +242,7 @@ func (c *TokenERC721Contract) IsApprovedForAll(ctx kalpsdk.TransactionContextInt
         return false, fmt.Errorf("failed to check if contract is already initialized: %v", err)
     }
     if !initialized {
-        return false, fmt.Errorf("Contract options need to be set before calling any function, call Initialize() to initialize contract")
+        return false, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
     }
 
     approvalKey, err := ctx.CreateCompositeKey(approvalPrefix, []string{owner, operator})
@@ -237,7 +273,7 @@ func (c *TokenERC721Contract) GetApproved(ctx kalpsdk.TransactionContextInterfac
         return "false", fmt.Errorf("failed to check if contract is already initialized: %v", err)
     }
     if !initialized {
-        return "false", fmt.Errorf("Contract options need to be set before calling any function, call Initialize() to initialize contract")
+        return "false", kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
     }
 
     nft, err := _readNFT(ctx, tokenId)
@@ -253,7 +289,7 @@ func (c *TokenERC721Contract) TransferFrom(ctx kalpsdk.TransactionContextInterfa
         return false, fmt.Errorf("failed to check if contract is already initialized: %v", err)
     }
     if !initialized {
-        return false, fmt.Errorf("Contract options need to be set before calling any function, call Initialize() to initialize contract")
+        return false, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
     }
 
     sender, err := ctx.GetUserID()
@@ -266,6 +302,16 @@ func (c *TokenERC721Contract) TransferFrom(ctx kalpsdk.TransactionContextInterfa
         return false, fmt.Errorf("failed to _readNFT : %v", err)
     }
 
+    err = _checkNotPaused(ctx)
+    if err != nil {
+        return false, err
+    }
+
+    err = _checkNotLocked(ctx, tokenId)
+    if err != nil {
+        return false, err
+    }
+
     owner := nft.Owner
     operator := nft.Approved
     operatorApproval, err := c.IsApprovedForAll(ctx, owner, sender)
@@ -273,13 +319,18 @@ func (c *TokenERC721Contract) TransferFrom(ctx kalpsdk.TransactionContextInterfa
         return false, fmt.Errorf("failed to get IsApprovedForAll : %v", err)
     }
     if owner != sender && operator != sender && !operatorApproval {
-        return false, fmt.Errorf("the sender is not the current owner nor an authorized operator")
+        return false, kusherrors.Errorf(kusherrors.ErrUnauthorized, "the sender is not the current owner nor an authorized operator")
     }
 
     if owner != from {
         return false, fmt.Errorf("the from is not the current owner")
     }
 
+    err = denylist.CheckNotDenied(ctx, from, to)
+    if err != nil {
+        return false, err
+    }
+
     nft.Approved = ""
     nft.Owner = to
     nftKey, err := ctx.CreateCompositeKey(nftPrefix, []string{tokenId})
@@ -287,12 +338,12 @@ func (c *TokenERC721Contract) TransferFrom(ctx kalpsdk.TransactionContextInterfa
         return false, fmt.Errorf("failed to CreateCompositeKey: %v", err)
     }
 
-    nftBytes, err := json.Marshal(nft)
+    nftBytes, err := canonical.Marshal(nft)
     if err != nil {
         return false, fmt.Errorf("failed to marshal approval: %v", err)
     }
 
-    err = ctx.PutStateWithoutKYC(nftKey, nftBytes)
+    err = accesscontrol.PutState(ctx, nftKey, nftBytes)
     if err != nil {
         return false, fmt.Errorf("failed to PutState nftBytes %s: %v", nftBytes, err)
     }
@@ -302,7 +353,7 @@ func (c *TokenERC721Contract) TransferFrom(ctx kalpsdk.TransactionContextInterfa
         return false, fmt.Errorf("failed to CreateCompositeKey from: %v", err)
     }
 
-    err = ctx.DelStateWithoutKYC(balanceKeyFrom)
+    err = accesscontrol.DelState(ctx, balanceKeyFrom)
     if err != nil {
         return false, fmt.Errorf("failed to DelState balanceKeyFrom %s: %v", nftBytes, err)
     }
@@ -311,24 +362,40 @@ func (c *TokenERC721Contract) TransferFrom(ctx kalpsdk.TransactionContextInterfa
     if err != nil {
         return false, fmt.Errorf("failed to CreateCompositeKey to: %v", err)
     }
-    err = ctx.PutStateWithoutKYC(balanceKeyTo, []byte{0})
+    err = accesscontrol.PutState(ctx, balanceKeyTo, []byte{0})
     if err != nil {
         return false, fmt.Errorf("failed to PutState balanceKeyTo %s: %v", balanceKeyTo, err)
     }
 
+    if from != to {
+        err = _removeTokenFromOwnerEnumeration(ctx, from, tokenId)
+        if err != nil {
+            return false, fmt.Errorf("failed to remove token %s from enumeration of %s: %v", tokenId, from, err)
+        }
+        err = _addTokenToOwnerEnumeration(ctx, to, tokenId)
+        if err != nil {
+            return false, fmt.Errorf("failed to add token %s to enumeration of %s: %v", tokenId, to, err)
+        }
+        // rentals do not survive a change of owner
+        err = _clearTokenUser(ctx, tokenId)
+        if err != nil {
+            return false, fmt.Errorf("failed to clear user of token %s: %v", tokenId, err)
+        }
+    }
+
+    err = metrics.Count(ctx, from, to)
+    if err != nil {
+        return false, err
+    }
+
     transferEvent := new(Transfer)
     transferEvent.From = from
     transferEvent.To = to
     transferEvent.TokenId = tokenId
 
-    transferEventBytes, err := json.Marshal(transferEvent)
+    err = events.Emit(ctx, "Transfer", transferEvent)
     if err != nil {
-        return false, fmt.Errorf("failed to marshal transferEventBytes: %v", err)
-    }
-
-    err = ctx.SetEvent("Transfer", transferEventBytes)
-    if err != nil {
-        return false, fmt.Errorf("failed to SetEvent transferEventBytes %s: %v", transferEventBytes, err)
+        return false, err
     }
     return true, nil
 }
@@ -339,7 +406,7 @@ func (c *TokenERC721Contract) Name(ctx kalpsdk.TransactionContextInterface) (str
         return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
     }
     if !initialized {
-        return "", fmt.Errorf("Contract options need to be set before calling any function, call Initialize() to initialize contract")
+        return "", kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
     }
 
     bytes, err := ctx.GetState(nameKey1)
@@ -356,7 +423,7 @@ func (c *TokenERC721Contract) Symbol(ctx kalpsdk.TransactionContextInterface) (s
         return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
     }
     if !initialized {
-        return "", fmt.Errorf("Contract options need to be set before calling any function, call Initialize() to initialize contract")
+        return "", kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
     }
 
     bytes, err := ctx.GetState(symbolKey1)
@@ -373,50 +440,40 @@ func (c *TokenERC721Contract) TokenURI(ctx kalpsdk.TransactionContextInterface,
         return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
     }
     if !initialized {
-        return "", fmt.Errorf("Contract options need to be set before calling any function, call Initialize() to initialize contract")
+        return "", kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
     }
 
     nft, err := _readNFT(ctx, tokenId)
     if err != nil {
         return "", fmt.Errorf("failed to get TokenURI: %v", err)
     }
-    return nft.TokenURI, nil
+    return _visibleTokenURI(ctx, nft)
 }
 
-func (c *TokenERC721Contract) TotalSupply(ctx kalpsdk.TransactionContextInterface) int {
+// TotalSupply returns the number of tokens in existence from the token counter of the enumeration
+// index. The nft keys of collections not yet upgraded past ERC721EnumerableV1 are counted instead.
+func (c *TokenERC721Contract) TotalSupply(ctx kalpsdk.TransactionContextInterface) (int, error) {
     initialized, err := checkInitialized1(ctx)
     if err != nil {
-        panic("failed to check if contract is already initialized:" + err.Error())
+        return 0, fmt.Errorf("failed to check if contract is already initialized: %v", err)
     }
     if !initialized {
-        panic("Contract options need to be set before calling any function, call Initialize() to initialize contract")
+        return 0, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
     }
 
-    iterator, err := ctx.GetStateByPartialCompositeKey(nftPrefix, []string{})
+    enumerated, err := _isEnumerated(ctx)
     if err != nil {
-        panic("Error creating GetStateByPartialCompositeKey:" + err.Error())
+        return 0, err
     }
-
-    totalSupply := 0
-    for iterator.HasNext() {
-        _, err := iterator.Next()
-        if err != nil {
-            return 0
-        }
-        totalSupply++
+    if !enumerated {
+        return _countNFTKeys(ctx)
     }
-    return totalSupply
+    return _readCounter(ctx, tokenCountKey)
 }
 
-func (c *TokenERC721Contract) Initialize(ctx kalpsdk.TransactionContextInterface, name string, symbol string) (bool, error) {
-    clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
-    if err != nil {
-        return false, fmt.Errorf("failed to get clientMSPID: %v", err)
-    }
-    if clientMSPID != "mailabs" {
-        return false, fmt.Errorf("client is not authorized to set the name and symbol of the token")
-    }
-
+// Initialize sets up the collection from cfg, see config.InitConfig. BaseURI is prepended to the URI
+// of every token and MaxSupply caps the number of tokens in existence.
+func (c *TokenERC721Contract) Initialize(ctx kalpsdk.TransactionContextInterface, cfg config.InitConfig) (bool, error) {
     bytes, err := ctx.GetState(nameKey1)
     if err != nil {
         return false, fmt.Errorf("failed to get Name: %v", err)
@@ -425,12 +482,22 @@ func (c *TokenERC721Contract) Initialize(ctx kalpsdk.TransactionContextInterface
         return false, fmt.Errorf("contract options are already set, client is not authorized to change them")
     }
 
-    err = ctx.PutStateWithoutKYC(nameKey1, []byte(name))
+    err = config.Initialize(ctx, cfg, 0)
+    if err != nil {
+        return false, err
+    }
+
+    err = migration.SetVersion(ctx, migration.CurrentVersion(erc721Upgrades))
+    if err != nil {
+        return false, err
+    }
+
+    err = accesscontrol.WriteState(ctx, cfg.Features.RequireKYC, nameKey1, []byte(cfg.Name))
     if err != nil {
         return false, fmt.Errorf("failed to PutState nameKey1 %s: %v", nameKey1, err)
     }
 
-    err = ctx.PutStateWithoutKYC(symbolKey1, []byte(symbol))
+    err = accesscontrol.WriteState(ctx, cfg.Features.RequireKYC, symbolKey1, []byte(cfg.Symbol))
     if err != nil {
         return false, fmt.Errorf("failed to PutState symbolKey1 %s: %v", symbolKey1, err)
     }
@@ -443,16 +510,23 @@ func (c *TokenERC721Contract) MintWithTokenURI(ctx kalpsdk.TransactionContextInt
         return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
     }
     if !initialized {
-        return nil, fmt.Errorf("Contract options need to be set before calling any function, call Initialize() to initialize contract")
+        return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
     }
 
-    clientMSPID, err := ctx.GetClientIdentity().GetMSPID()
+    err = _checkMintPhase(ctx, nil)
     if err != nil {
-        return nil, fmt.Errorf("failed to get clientMSPID: %v", err)
+        return nil, err
     }
 
-    if clientMSPID != "mailabs" {
-        return nil, fmt.Errorf("client is not authorized to set the name and symbol of the token")
+    return _mintWithTokenURI(ctx, tokenId, tokenURI, nil)
+}
+
+// _mintWithTokenURI mints tokenId to the caller once the mint has been authorized. metadata may
+// be nil.
+func _mintWithTokenURI(ctx kalpsdk.TransactionContextInterface, tokenId string, tokenURI string, metadata *TokenMetadata) (*Nft, error) {
+    err := _checkNotPaused(ctx)
+    if err != nil {
+        return nil, err
     }
 
     minter, err := ctx.GetUserID()
@@ -460,27 +534,45 @@ func (c *TokenERC721Contract) MintWithTokenURI(ctx kalpsdk.TransactionContextInt
         return nil, fmt.Errorf("failed to get minter id: %v", err)
     }
 
-    exists := _nftExists(ctx, tokenId)
+    exists, err := _nftExists(ctx, tokenId)
+    if err != nil {
+        return nil, err
+    }
     if exists {
         return nil, fmt.Errorf("the token %s is already minted.: %v", tokenId, err)
     }
 
+    err = denylist.CheckNotDenied(ctx, minter)
+    if err != nil {
+        return nil, err
+    }
+
+    tokenCount, err := _readCounter(ctx, tokenCountKey)
+    if err != nil {
+        return nil, err
+    }
+    err = config.CheckMaxSupply(ctx, big.NewInt(int64(tokenCount)+1))
+    if err != nil {
+        return nil, err
+    }
+
     nft := new(Nft)
     nft.TokenId = tokenId
     nft.Owner = minter
     nft.TokenURI = tokenURI
+    nft.Metadata = metadata
 
     nftKey, err := ctx.CreateCompositeKey(nftPrefix, []string{tokenId})
     if err != nil {
         return nil, fmt.Errorf("failed to CreateCompositeKey to nftKey: %v", err)
     }
 
-    nftBytes, err := json.Marshal(nft)
+    nftBytes, err := canonical.Marshal(nft)
     if err != nil {
         return nil, fmt.Errorf("failed to marshal nft: %v", err)
     }
 
-    err = ctx.PutStateWithoutKYC(nftKey, nftBytes)
+    err = accesscontrol.PutState(ctx, nftKey, nftBytes)
     if err != nil {
         return nil, fmt.Errorf("failed to PutState nftBytes %s: %v", nftBytes, err)
     }
@@ -490,24 +582,34 @@ func (c *TokenERC721Contract) MintWithTokenURI(ctx kalpsdk.TransactionContextInt
         return nil, fmt.Errorf("failed to CreateCompositeKey to balanceKey: %v", err)
     }
 
-    err = ctx.PutStateWithoutKYC(balanceKey, []byte{'\u0000'})
+    err = accesscontrol.PutState(ctx, balanceKey, []byte{'\u0000'})
     if err != nil {
         return nil, fmt.Errorf("failed to PutState balanceKey %s: %v", nftBytes, err)
     }
 
+    err = _addTokenToEnumeration(ctx, minter, tokenId)
+    if err != nil {
+        return nil, fmt.Errorf("failed to enumerate token %s: %v", tokenId, err)
+    }
+
+    err = audit.Record(ctx, "Mint", minter, tokenId)
+    if err != nil {
+        return nil, err
+    }
+
+    err = metrics.Count(ctx, "0x0", minter)
+    if err != nil {
+        return nil, err
+    }
+
     transferEvent := new(Transfer)
     transferEvent.From = "0x0"
     transferEvent.To = minter
     transferEvent.TokenId = tokenId
 
-    transferEventBytes, err := json.Marshal(transferEvent)
+    err = events.Emit(ctx, "Transfer", transferEvent)
     if err != nil {
-        return nil, fmt.Errorf("failed to marshal transferEventBytes: %v", err)
-    }
-
-    err = ctx.SetEvent("Transfer", transferEventBytes)
-    if err != nil {
-        return nil, fmt.Errorf("failed to SetEvent transferEventBytes %s: %v", transferEventBytes, err)
+        return nil, err
     }
 
     return nft, nil
@@ -518,7 +620,7 @@ func (c *TokenERC721Contract) Burn(ctx kalpsdk.TransactionContextInterface, toke
         return false, fmt.Errorf("failed to check if contract is already initialized: %v", err)
     }
     if !initialized {
-        return false, fmt.Errorf("Contract options need to be set before calling any function, call Initialize() to initialize contract")
+        return false, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
     }
 
     owner, err := ctx.GetUserID()
@@ -530,8 +632,18 @@ func (c *TokenERC721Contract) Burn(ctx kalpsdk.TransactionContextInterface, toke
     if err != nil {
         return false, fmt.Errorf("failed to _readNFT nft : %v", err)
     }
+
+    err = _checkNotPaused(ctx)
+    if err != nil {
+        return false, err
+    }
+
+    err = _checkNotLocked(ctx, tokenId)
+    if err != nil {
+        return false, err
+    }
     if nft.Owner != owner {
-        return false, fmt.Errorf("non-fungible token %s is not owned by %s", tokenId, owner)
+        return false, kusherrors.Errorf(kusherrors.ErrUnauthorized, "non-fungible token %s is not owned by %s", tokenId, owner)
     }
 
     nftKey, err := ctx.CreateCompositeKey(nftPrefix, []string{tokenId})
@@ -539,7 +651,7 @@ func (c *TokenERC721Contract) Burn(ctx kalpsdk.TransactionContextInterface, toke
         return false, fmt.Errorf("failed to CreateCompositeKey tokenId: %v", err)
     }
 
-    err = ctx.DelStateWithoutKYC(nftKey)
+    err = accesscontrol.DelState(ctx, nftKey)
     if err != nil {
         return false, fmt.Errorf("failed to DelState nftKey: %v", err)
     }
@@ -549,24 +661,45 @@ func (c *TokenERC721Contract) Burn(ctx kalpsdk.TransactionContextInterface, toke
         return false, fmt.Errorf("failed to CreateCompositeKey balanceKey %s: %v", balanceKey, err)
     }
 
-    err = ctx.DelStateWithoutKYC(balanceKey)
+    err = accesscontrol.DelState(ctx, balanceKey)
     if err != nil {
         return false, fmt.Errorf("failed to DelState balanceKey %s: %v", balanceKey, err)
     }
 
+    err = _removeTokenFromEnumeration(ctx, owner, tokenId)
+    if err != nil {
+        return false, fmt.Errorf("failed to remove token %s from enumeration: %v", tokenId, err)
+    }
+
+    // a re-minted token id must not inherit the royalty of the burned token
+    err = _writeRoyalty(ctx, []string{tokenId}, "", 0)
+    if err != nil {
+        return false, fmt.Errorf("failed to reset royalty of token %s: %v", tokenId, err)
+    }
+
+    err = _clearTokenUser(ctx, tokenId)
+    if err != nil {
+        return false, fmt.Errorf("failed to clear user of token %s: %v", tokenId, err)
+    }
+
+    err = audit.Record(ctx, "Burn", owner, tokenId)
+    if err != nil {
+        return false, err
+    }
+
+    err = metrics.Count(ctx, owner, "0x0")
+    if err != nil {
+        return false, err
+    }
+
     transferEvent := new(Transfer)
     transferEvent.From = owner
     transferEvent.To = "0x0"
     transferEvent.TokenId = tokenId
 
-    transferEventBytes, err := json.Marshal(transferEvent)
+    err = events.Emit(ctx, "Transfer", transferEvent)
     if err != nil {
-        return false, fmt.Errorf("failed to marshal transferEventBytes: %v", err)
-    }
-
-    err = ctx.SetEvent("Transfer", transferEventBytes)
-    if err != nil {
-        return false, fmt.Errorf("failed to SetEvent transferEventBytes: %v", err)
+        return false, err
     }
 
     return true, nil
@@ -577,7 +710,7 @@ func (c *TokenERC721Contract) ClientAccountBalance(ctx kalpsdk.TransactionContex
         return 0, fmt.Errorf("failed to check if contract is already initialized: %v", err)
     }
     if !initialized {
-        return 0, fmt.Errorf("Contract options need to be set before calling any function, call Initialize() to initialize contract")
+        return 0, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
     }
 
     clientAccountID, err := ctx.GetUserID()
@@ -585,7 +718,7 @@ func (c *TokenERC721Contract) ClientAccountBalance(ctx kalpsdk.TransactionContex
         return 0, fmt.Errorf("failed to GetClientIdentity minter: %v", err)
     }
 
-    return c.BalanceOf(ctx, clientAccountID), nil
+    return c.BalanceOf(ctx, clientAccountID)
 }
 
 func (c *TokenERC721Contract) ClientAccountID(ctx kalpsdk.TransactionContextInterface) (string, error) {
@@ -594,7 +727,7 @@ func (c *TokenERC721Contract) ClientAccountID(ctx kalpsdk.TransactionContextInte
         return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
     }
     if !initialized {
-        return "", fmt.Errorf("Contract options need to be set before calling any function, call Initialize() to initialize contract")
+        return "", kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
     }
 
     clientAccount, err := ctx.GetUserID()
@@ -607,12 +740,22 @@ func (c *TokenERC721Contract) ClientAccountID(ctx kalpsdk.TransactionContextInte
 
 
 func checkInitialized1(ctx kalpsdk.TransactionContextInterface) (bool, error) {
-    tokenName, err := ctx.GetState(nameKey1)
+    return config.IsInitialized(ctx, nameKey1)
+}
+
+// _checkAdmin1 fails unless the contract is initialized and the caller holds the ADMIN_ROLE.
+func _checkAdmin1(ctx kalpsdk.TransactionContextInterface, action string) error {
+    initialized, err := checkInitialized1(ctx)
     if err != nil {
-        return false, fmt.Errorf("failed to get token name: %v", err)
+        return fmt.Errorf("failed to check if contract is already initialized: %v", err)
     }
-    if tokenName == nil {
-        return false, nil
+    if !initialized {
+        return kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
     }
-    return true, nil
+
+    err = accesscontrol.CheckRole(ctx, accesscontrol.AdminRole)
+    if err != nil {
+        return fmt.Errorf("client is not authorized to %s: %v", action, err)
+    }
+    return nil
 }
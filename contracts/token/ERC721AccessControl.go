@@ -0,0 +1,171 @@
+package token
+
+import (
+	"fmt"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+// MetadataRole allows an account to update the URI of any token.
+const MetadataRole = "METADATA_ROLE"
+
+// BootstrapRoles grants every built-in role to the caller, who must belong to an authorized MSP.
+// Initialize does this for new contracts; contracts initialized before roles existed call it once,
+// passing their authorized MSPs if they never stored any, see accesscontrol.Bootstrap.
+func (c *TokenERC721Contract) BootstrapRoles(ctx kalpsdk.TransactionContextInterface, authorizedMSPs []string) (bool, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return false, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	err = accesscontrol.Bootstrap(ctx, authorizedMSPs)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// GrantMetadataRole gives account the METADATA_ROLE. Only admins of that role may call it.
+func (c *TokenERC721Contract) GrantMetadataRole(ctx kalpsdk.TransactionContextInterface, account string) (bool, error) {
+	return c.GrantRole(ctx, MetadataRole, account)
+}
+
+// RevokeMetadataRole removes the METADATA_ROLE from account. Only admins of that role may call it.
+func (c *TokenERC721Contract) RevokeMetadataRole(ctx kalpsdk.TransactionContextInterface, account string) (bool, error) {
+	return c.RevokeRole(ctx, MetadataRole, account)
+}
+
+// GrantRole gives account role. The caller must hold the admin role of role.
+func (c *TokenERC721Contract) GrantRole(ctx kalpsdk.TransactionContextInterface, role string, account string) (bool, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return false, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	err = accesscontrol.GrantRole(ctx, role, account)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RevokeRole removes role from account. The caller must hold the admin role of role.
+func (c *TokenERC721Contract) RevokeRole(ctx kalpsdk.TransactionContextInterface, role string, account string) (bool, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return false, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	err = accesscontrol.RevokeRole(ctx, role, account)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RenounceRole removes role from the caller, whose ID must be passed as account.
+func (c *TokenERC721Contract) RenounceRole(ctx kalpsdk.TransactionContextInterface, role string, account string) (bool, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return false, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	err = accesscontrol.RenounceRole(ctx, role, account)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// SetRoleAdmin makes adminRole the admin of role. The caller must hold the current admin role.
+func (c *TokenERC721Contract) SetRoleAdmin(ctx kalpsdk.TransactionContextInterface, role string, adminRole string) (bool, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return false, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	err = accesscontrol.SetRoleAdmin(ctx, role, adminRole)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (c *TokenERC721Contract) GetRoleAdmin(ctx kalpsdk.TransactionContextInterface, role string) (string, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return "", kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return accesscontrol.GetRoleAdmin(ctx, role)
+}
+
+func (c *TokenERC721Contract) HasRole(ctx kalpsdk.TransactionContextInterface, role string, account string) (bool, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return false, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	return accesscontrol.HasRole(ctx, role, account)
+}
+
+// AddAuthorizedOrg lets members of mspID exercise minting and burning roles. Only admins may call it.
+func (c *TokenERC721Contract) AddAuthorizedOrg(ctx kalpsdk.TransactionContextInterface, mspID string) (bool, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return false, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	err = accesscontrol.AddAuthorizedOrg(ctx, mspID)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RemoveAuthorizedOrg suspends the roles held by members of mspID. Only admins may call it.
+func (c *TokenERC721Contract) RemoveAuthorizedOrg(ctx kalpsdk.TransactionContextInterface, mspID string) (bool, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return false, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	err = accesscontrol.RemoveAuthorizedOrg(ctx, mspID)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// ListAuthorizedOrgs returns the MSPs whose members may exercise minting and burning roles.
+func (c *TokenERC721Contract) ListAuthorizedOrgs(ctx kalpsdk.TransactionContextInterface) ([]string, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return accesscontrol.GetAuthorizedMSPs(ctx)
+}
@@ -0,0 +1,299 @@
+package token
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+	"github.com/thekalpstudio/kush-go/events"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+const mintPhaseKey = "mint~phase"
+const mintPhaseReachedKey = "mint~phase~reached"
+const phaseMintsPrefix = "mint~phase~mints"
+const allowlistPrefix = "allowlist"
+const allowlistRootKey = "allowlist~root"
+
+// Minting phases of a collection drop. Holders of the MINTER_ROLE in an authorized MSP can always
+// mint; everyone else needs the allowlist phase and an allowlist entry, or the public phase.
+const (
+	MintPhaseClosed    = "closed"
+	MintPhaseAllowlist = "allowlist"
+	MintPhasePublic    = "public"
+)
+
+// mintPhaseOrder ranks the open phases. A drop only moves forward: it may be closed at any time,
+// but reopens in the last open phase or a later one.
+var mintPhaseOrder = map[string]int{
+	MintPhaseAllowlist: 1,
+	MintPhasePublic:    2,
+}
+
+// allowlistMintLimit is the number of tokens an allowlist entry may mint in the allowlist phase.
+const allowlistMintLimit = 1
+
+// MintPhaseChanged is emitted when the minting phase changes.
+type MintPhaseChanged = events.MintPhaseChanged
+
+// SetMintPhase moves the collection to phase. Phases only move forward, see mintPhaseOrder.
+func (c *TokenERC721Contract) SetMintPhase(ctx kalpsdk.TransactionContextInterface, phase string) (bool, error) {
+	err := _checkAdmin1(ctx, "change the mint phase")
+	if err != nil {
+		return false, err
+	}
+
+	current, err := _readMintPhase(ctx)
+	if err != nil {
+		return false, err
+	}
+	reachedBytes, err := ctx.GetState(mintPhaseReachedKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to GetState mintPhaseReachedKey %s: %v", mintPhaseReachedKey, err)
+	}
+	order, open := mintPhaseOrder[phase]
+	if phase == current || (phase != MintPhaseClosed && (!open || order < mintPhaseOrder[string(reachedBytes)])) {
+		return false, fmt.Errorf("cannot move from mint phase %s to %q", current, phase)
+	}
+
+	err = accesscontrol.PutState(ctx, mintPhaseKey, []byte(phase))
+	if err != nil {
+		return false, fmt.Errorf("failed to PutState mintPhaseKey %s: %v", mintPhaseKey, err)
+	}
+	if open {
+		err = accesscontrol.PutState(ctx, mintPhaseReachedKey, []byte(phase))
+		if err != nil {
+			return false, fmt.Errorf("failed to PutState mintPhaseReachedKey %s: %v", mintPhaseReachedKey, err)
+		}
+	}
+
+	sender, err := ctx.GetUserID()
+	if err != nil {
+		return false, fmt.Errorf("failed to GetClientIdentity: %v", err)
+	}
+	err = events.Emit(ctx, "MintPhaseChanged", &MintPhaseChanged{From: current, To: phase, Sender: sender})
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// GetMintPhase returns the current minting phase.
+func (c *TokenERC721Contract) GetMintPhase(ctx kalpsdk.TransactionContextInterface) (string, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return "", kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return _readMintPhase(ctx)
+}
+
+// AddToAllowlist allows accounts to mint allowlistMintLimit tokens during the allowlist phase.
+func (c *TokenERC721Contract) AddToAllowlist(ctx kalpsdk.TransactionContextInterface, accounts []string) (bool, error) {
+	return _setAllowlisted(ctx, accounts, true)
+}
+
+// RemoveFromAllowlist removes accounts added by AddToAllowlist.
+func (c *TokenERC721Contract) RemoveFromAllowlist(ctx kalpsdk.TransactionContextInterface, accounts []string) (bool, error) {
+	return _setAllowlisted(ctx, accounts, false)
+}
+
+// IsAllowlisted reports whether account was added by AddToAllowlist. Accounts covered only by the
+// Merkle root are not listed and have to present a proof.
+func (c *TokenERC721Contract) IsAllowlisted(ctx kalpsdk.TransactionContextInterface, account string) (bool, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return false, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return _isAllowlisted(ctx, account)
+}
+
+// SetAllowlistRoot sets the hex encoded root of a Merkle tree of allowlisted accounts, for drops
+// too large to list one by one. Leaves are sha256(account) and pairs are hashed in sorted order.
+// An empty root removes the tree.
+func (c *TokenERC721Contract) SetAllowlistRoot(ctx kalpsdk.TransactionContextInterface, root string) (bool, error) {
+	err := _checkAdmin1(ctx, "manage the allowlist")
+	if err != nil {
+		return false, err
+	}
+	if root == "" {
+		err = accesscontrol.DelState(ctx, allowlistRootKey)
+		if err != nil {
+			return false, fmt.Errorf("failed to DelState allowlistRootKey: %v", err)
+		}
+		return true, nil
+	}
+	rootBytes, err := hex.DecodeString(root)
+	if err != nil || len(rootBytes) != sha256.Size {
+		return false, fmt.Errorf("allowlist root must be a hex encoded sha256 hash")
+	}
+	err = accesscontrol.PutState(ctx, allowlistRootKey, rootBytes)
+	if err != nil {
+		return false, fmt.Errorf("failed to PutState allowlistRootKey: %v", err)
+	}
+	return true, nil
+}
+
+// MintWithProof mints like MintWithTokenURI, proving allowlist membership during the allowlist
+// phase with the hex encoded Merkle proof of the caller's account.
+func (c *TokenERC721Contract) MintWithProof(ctx kalpsdk.TransactionContextInterface, tokenId string, tokenURI string, proof []string) (*Nft, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	err = _checkMintPhase(ctx, proof)
+	if err != nil {
+		return nil, err
+	}
+	return _mintWithTokenURI(ctx, tokenId, tokenURI, nil)
+}
+
+// _checkMintPhase fails unless the caller may mint in the current phase, and counts the mint of
+// an allowlisted caller against its entry. proof is only consulted for callers that are not on
+// the explicit allowlist.
+func _checkMintPhase(ctx kalpsdk.TransactionContextInterface, proof []string) error {
+	minter, err := ctx.GetUserID()
+	if err != nil {
+		return fmt.Errorf("failed to get minter id: %v", err)
+	}
+	isMinter, err := accesscontrol.HasRole(ctx, accesscontrol.MinterRole, minter)
+	if err != nil {
+		return err
+	}
+	if isMinter && accesscontrol.CheckAuthorizedMSP(ctx) == nil {
+		return nil
+	}
+
+	phase, err := _readMintPhase(ctx)
+	if err != nil {
+		return err
+	}
+	switch phase {
+	case MintPhasePublic:
+		return nil
+	case MintPhaseAllowlist:
+		listed, err := _isAllowlisted(ctx, minter)
+		if err != nil {
+			return err
+		}
+		if !listed && len(proof) > 0 {
+			listed, err = _verifyAllowlistProof(ctx, minter, proof)
+			if err != nil {
+				return err
+			}
+		}
+		if !listed {
+			return fmt.Errorf("client %s is not on the allowlist", minter)
+		}
+		return _countPhaseMint(ctx, phase, minter, allowlistMintLimit)
+	default:
+		return kusherrors.Errorf(kusherrors.ErrUnauthorized, "client is not authorized to mint while minting is %s", phase)
+	}
+}
+
+func _readMintPhase(ctx kalpsdk.TransactionContextInterface) (string, error) {
+	phaseBytes, err := ctx.GetState(mintPhaseKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to GetState mintPhaseKey %s: %v", mintPhaseKey, err)
+	}
+	if phaseBytes == nil {
+		return MintPhaseClosed, nil
+	}
+	return string(phaseBytes), nil
+}
+
+// _countPhaseMint records a mint of account in phase, failing once account minted limit tokens in
+// it.
+func _countPhaseMint(ctx kalpsdk.TransactionContextInterface, phase string, account string, limit int) error {
+	mintsKey, err := ctx.CreateCompositeKey(phaseMintsPrefix, []string{phase, account})
+	if err != nil {
+		return fmt.Errorf("failed to CreateCompositeKey mintsKey: %v", err)
+	}
+	mints, err := _readCounter(ctx, mintsKey)
+	if err != nil {
+		return err
+	}
+	if mints >= limit {
+		return kusherrors.Errorf(kusherrors.ErrUnauthorized, "client %s already minted %d tokens in the %s phase", account, mints, phase)
+	}
+	return accesscontrol.PutState(ctx, mintsKey, []byte(strconv.Itoa(mints+1)))
+}
+
+func _setAllowlisted(ctx kalpsdk.TransactionContextInterface, accounts []string, allowed bool) (bool, error) {
+	err := _checkAdmin1(ctx, "manage the allowlist")
+	if err != nil {
+		return false, err
+	}
+	for _, account := range accounts {
+		if account == "" {
+			return false, fmt.Errorf("account must not be empty")
+		}
+		allowlistKey, err := ctx.CreateCompositeKey(allowlistPrefix, []string{account})
+		if err != nil {
+			return false, fmt.Errorf("failed to CreateCompositeKey allowlistKey: %v", err)
+		}
+		if allowed {
+			err = accesscontrol.PutState(ctx, allowlistKey, []byte{'\u0000'})
+		} else {
+			err = accesscontrol.DelState(ctx, allowlistKey)
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to update allowlist entry of %s: %v", account, err)
+		}
+	}
+	return true, nil
+}
+
+func _isAllowlisted(ctx kalpsdk.TransactionContextInterface, account string) (bool, error) {
+	allowlistKey, err := ctx.CreateCompositeKey(allowlistPrefix, []string{account})
+	if err != nil {
+		return false, fmt.Errorf("failed to CreateCompositeKey allowlistKey: %v", err)
+	}
+	allowlistBytes, err := ctx.GetState(allowlistKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to GetState allowlistKey %s: %v", allowlistKey, err)
+	}
+	return allowlistBytes != nil, nil
+}
+
+// _verifyAllowlistProof checks proof against the stored Merkle root.
+func _verifyAllowlistProof(ctx kalpsdk.TransactionContextInterface, account string, proof []string) (bool, error) {
+	root, err := ctx.GetState(allowlistRootKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to GetState allowlistRootKey: %v", err)
+	}
+	if root == nil {
+		return false, nil
+	}
+
+	leaf := sha256.Sum256([]byte(account))
+	node := leaf[:]
+	for _, siblingHex := range proof {
+		sibling, err := hex.DecodeString(siblingHex)
+		if err != nil || len(sibling) != sha256.Size {
+			return false, fmt.Errorf("invalid proof element %q", siblingHex)
+		}
+		var pair []byte
+		if bytes.Compare(node, sibling) <= 0 {
+			pair = append(append(pair, node...), sibling...)
+		} else {
+			pair = append(append(pair, sibling...), node...)
+		}
+		hash := sha256.Sum256(pair)
+		node = hash[:]
+	}
+	return bytes.Equal(node, root), nil
+}
@@ -0,0 +1,22 @@
+package token
+
+import (
+	"fmt"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/audit"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+// GetAuditLog returns up to pageSize privileged actions taken on the collection starting at
+// bookmark, oldest first. Pass the returned bookmark to read the next page.
+func (c *TokenERC721Contract) GetAuditLog(ctx kalpsdk.TransactionContextInterface, bookmark string, pageSize int) (*audit.Page, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return audit.GetAuditLog(ctx, bookmark, pageSize)
+}
@@ -0,0 +1,87 @@
+package token
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/config"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+// GetConfig returns the configuration the collection was initialized with, as changed since.
+func (c *TokenERC721Contract) GetConfig(ctx kalpsdk.TransactionContextInterface) (*config.InitConfig, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return _readERC721Config(ctx)
+}
+
+// UpdateConfig changes the max supply, base URI or features, see config.Update. The max supply
+// cannot drop below the number of tokens in existence.
+func (c *TokenERC721Contract) UpdateConfig(ctx kalpsdk.TransactionContextInterface, cfg config.InitConfig) (bool, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return false, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	current, err := _readERC721Config(ctx)
+	if err != nil {
+		return false, err
+	}
+	err = config.Update(ctx, current, cfg, _erc721ConfigHooks(ctx))
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// _applyERC721Config applies the JSON config.InitConfig of an approved
+// accesscontrol.ActionUpdateConfig.
+func _applyERC721Config(ctx kalpsdk.TransactionContextInterface, updateJSON string) error {
+	var update config.InitConfig
+	err := json.Unmarshal([]byte(updateJSON), &update)
+	if err != nil {
+		return fmt.Errorf("failed to decode config: %v", err)
+	}
+	current, err := _readERC721Config(ctx)
+	if err != nil {
+		return err
+	}
+	return config.Apply(ctx, current, update, _erc721ConfigHooks(ctx))
+}
+
+// _erc721ConfigHooks keeps the max supply above the number of tokens in existence.
+func _erc721ConfigHooks(ctx kalpsdk.TransactionContextInterface) config.Hooks {
+	return config.Hooks{
+		CheckMaxSupply: func(maxSupply *big.Int) error {
+			tokenCount, err := _readCounter(ctx, tokenCountKey)
+			if err != nil {
+				return err
+			}
+			if maxSupply.Cmp(big.NewInt(int64(tokenCount))) < 0 {
+				return fmt.Errorf("max supply %s is below the %d tokens in existence", maxSupply, tokenCount)
+			}
+			return nil
+		},
+	}
+}
+
+func _readERC721Config(ctx kalpsdk.TransactionContextInterface) (*config.InitConfig, error) {
+	nameBytes, err := ctx.GetState(nameKey1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to GetState nameKey1 %s: %v", nameKey1, err)
+	}
+	symbolBytes, err := ctx.GetState(symbolKey1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to GetState symbolKey1 %s: %v", symbolKey1, err)
+	}
+	return config.Read(ctx, string(nameBytes), string(symbolBytes), 0)
+}
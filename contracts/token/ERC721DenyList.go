@@ -0,0 +1,37 @@
+package token
+
+import (
+	"fmt"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/denylist"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+// SetDenyList makes transfers from or to, and mints by, accounts on the deny list chaincode
+// chaincodeName fail. An empty name detaches the deny list.
+func (c *TokenERC721Contract) SetDenyList(ctx kalpsdk.TransactionContextInterface, chaincodeName string) (bool, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return false, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	err = denylist.SetDenyList(ctx, chaincodeName)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (c *TokenERC721Contract) GetDenyList(ctx kalpsdk.TransactionContextInterface) (string, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return "", kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return denylist.GetDenyList(ctx)
+}
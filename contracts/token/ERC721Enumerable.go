@@ -0,0 +1,432 @@
+package token
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+	"github.com/thekalpstudio/kush-go/migration"
+	"github.com/thekalpstudio/kush-go/pagination"
+)
+
+// Enumeration index keys. Every token has a position in the global list and in its owner's list;
+// removals move the last entry into the freed slot, so both lists stay dense and can be paged by
+// index without scanning the nft namespace.
+const tokenIndexPrefix = "token~index"
+const tokenPositionPrefix = "token~position"
+const tokenCountKey = "token~count"
+const ownerTokenPrefix = "owner~token"
+const ownerPositionPrefix = "owner~position"
+const ownerCountPrefix = "owner~count"
+
+// DefaultPageSize is used when callers pass a non-positive page size.
+const DefaultPageSize = 50
+const maxPageSize = 500
+
+// TokenPage is one page of a paginated token listing. Bookmark is empty on the last page.
+type TokenPage struct {
+	Tokens   []*Nft `json:"tokens"`
+	Bookmark string `json:"bookmark"`
+	Total    int    `json:"total"`
+}
+
+// TokenByIndex returns the token id at index in the list of all tokens.
+func (c *TokenERC721Contract) TokenByIndex(ctx kalpsdk.TransactionContextInterface, index int) (string, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return "", kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	return _tokenAt(ctx, tokenIndexPrefix, nil, tokenCountKey, index)
+}
+
+// TokenOfOwnerByIndex returns the token id at index in the list of tokens owned by owner.
+func (c *TokenERC721Contract) TokenOfOwnerByIndex(ctx kalpsdk.TransactionContextInterface, owner string, index int) (string, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return "", kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	countKey, err := ctx.CreateCompositeKey(ownerCountPrefix, []string{owner})
+	if err != nil {
+		return "", fmt.Errorf("failed to CreateCompositeKey ownerCountKey: %v", err)
+	}
+	return _tokenAt(ctx, ownerTokenPrefix, []string{owner}, countKey, index)
+}
+
+// GetTokensOfOwner returns every token held by owner, read from the balance keys written on mint
+// and transfer. Owners of large collections should page with TokensOfOwnerWithPagination instead.
+func (c *TokenERC721Contract) GetTokensOfOwner(ctx kalpsdk.TransactionContextInterface, owner string) ([]*Nft, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	iterator, err := ctx.GetStateByPartialCompositeKey(balancePrefix, []string{owner})
+	if err != nil {
+		return nil, fmt.Errorf("failed to GetStateByPartialCompositeKey %s: %v", balancePrefix, err)
+	}
+	defer iterator.Close()
+
+	tokens := []*Nft{}
+	for iterator.HasNext() {
+		queryResponse, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read balance of %s: %v", owner, err)
+		}
+		_, attrs, err := ctx.SplitCompositeKey(queryResponse.Key)
+		if err != nil || len(attrs) != 2 {
+			return nil, fmt.Errorf("failed to SplitCompositeKey %s: %v", queryResponse.Key, err)
+		}
+		nft, err := _readNFT(ctx, attrs[1])
+		if err != nil {
+			return nil, err
+		}
+		nft.TokenURI, err = _visibleTokenURI(ctx, nft)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, nft)
+	}
+	return tokens, nil
+}
+
+// TokensOfOwner returns up to pageSize tokens of owner starting at bookmark.
+//
+// Deprecated: use TokensOfOwnerWithPagination, or GetTokensOfOwner for every token at once.
+func (c *TokenERC721Contract) TokensOfOwner(ctx kalpsdk.TransactionContextInterface, owner string, bookmark string, pageSize int) (*TokenPage, error) {
+	return c.TokensOfOwnerWithPagination(ctx, owner, bookmark, pageSize)
+}
+
+// TokensOfOwnerWithPagination returns up to pageSize tokens of owner starting at bookmark, an
+// opaque cursor returned by the previous page (empty for the first page).
+func (c *TokenERC721Contract) TokensOfOwnerWithPagination(ctx kalpsdk.TransactionContextInterface, owner string, bookmark string, pageSize int) (*TokenPage, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+	start := 0
+	if bookmark != "" {
+		start, err = strconv.Atoi(bookmark)
+		if err != nil || start < 0 {
+			return nil, fmt.Errorf("invalid bookmark %q", bookmark)
+		}
+	}
+
+	countKey, err := ctx.CreateCompositeKey(ownerCountPrefix, []string{owner})
+	if err != nil {
+		return nil, fmt.Errorf("failed to CreateCompositeKey ownerCountKey: %v", err)
+	}
+	total, err := _readCounter(ctx, countKey)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &TokenPage{Tokens: []*Nft{}, Total: total}
+	end := start + pageSize
+	if end > total {
+		end = total
+	}
+	for i := start; i < end; i++ {
+		tokenId, err := _tokenAt(ctx, ownerTokenPrefix, []string{owner}, countKey, i)
+		if err != nil {
+			return nil, err
+		}
+		nft, err := _readNFT(ctx, tokenId)
+		if err != nil {
+			return nil, err
+		}
+		nft.TokenURI, err = _visibleTokenURI(ctx, nft)
+		if err != nil {
+			return nil, err
+		}
+		page.Tokens = append(page.Tokens, nft)
+	}
+	if end < total {
+		page.Bookmark = strconv.Itoa(end)
+	}
+	return page, nil
+}
+
+// CountPage is one page of a paginated count. Callers add up the counts of the pages; Bookmark is
+// empty on the last page.
+type CountPage struct {
+	Count    int    `json:"count"`
+	Bookmark string `json:"bookmark"`
+}
+
+// BalanceOfWithPagination counts up to pageSize tokens of owner starting at bookmark, like
+// BalanceOf, without reading every balance key of the owner in one query.
+func (c *TokenERC721Contract) BalanceOfWithPagination(ctx kalpsdk.TransactionContextInterface, owner string, bookmark string, pageSize int) (*CountPage, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	states, next, err := pagination.ByPartialCompositeKey(ctx, balancePrefix, []string{owner}, bookmark, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	return &CountPage{len(states), next}, nil
+}
+
+// TotalSupplyWithPagination counts up to pageSize tokens starting at bookmark, like TotalSupply,
+// without reading the whole nft namespace in one query.
+func (c *TokenERC721Contract) TotalSupplyWithPagination(ctx kalpsdk.TransactionContextInterface, bookmark string, pageSize int) (*CountPage, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	states, next, err := pagination.ByPartialCompositeKey(ctx, nftPrefix, []string{}, bookmark, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	return &CountPage{len(states), next}, nil
+}
+
+// _addTokenToEnumeration appends tokenId to the global list and to owner's list.
+func _addTokenToEnumeration(ctx kalpsdk.TransactionContextInterface, owner string, tokenId string) error {
+	err := _appendToList(ctx, tokenIndexPrefix, nil, tokenPositionPrefix, tokenCountKey, tokenId)
+	if err != nil {
+		return err
+	}
+	return _addTokenToOwnerEnumeration(ctx, owner, tokenId)
+}
+
+// _removeTokenFromEnumeration removes tokenId from the global list and from owner's list.
+func _removeTokenFromEnumeration(ctx kalpsdk.TransactionContextInterface, owner string, tokenId string) error {
+	err := _removeFromList(ctx, tokenIndexPrefix, nil, tokenPositionPrefix, tokenCountKey, tokenId)
+	if err != nil {
+		return err
+	}
+	return _removeTokenFromOwnerEnumeration(ctx, owner, tokenId)
+}
+
+func _addTokenToOwnerEnumeration(ctx kalpsdk.TransactionContextInterface, owner string, tokenId string) error {
+	countKey, err := ctx.CreateCompositeKey(ownerCountPrefix, []string{owner})
+	if err != nil {
+		return fmt.Errorf("failed to CreateCompositeKey ownerCountKey: %v", err)
+	}
+	return _appendToList(ctx, ownerTokenPrefix, []string{owner}, ownerPositionPrefix, countKey, tokenId)
+}
+
+func _removeTokenFromOwnerEnumeration(ctx kalpsdk.TransactionContextInterface, owner string, tokenId string) error {
+	countKey, err := ctx.CreateCompositeKey(ownerCountPrefix, []string{owner})
+	if err != nil {
+		return fmt.Errorf("failed to CreateCompositeKey ownerCountKey: %v", err)
+	}
+	return _removeFromList(ctx, ownerTokenPrefix, []string{owner}, ownerPositionPrefix, countKey, tokenId)
+}
+
+func _appendToList(ctx kalpsdk.TransactionContextInterface, listPrefix string, listAttrs []string, positionPrefix string, countKey string, tokenId string) error {
+	count, err := _readCounter(ctx, countKey)
+	if err != nil {
+		return err
+	}
+	err = _writeListEntry(ctx, listPrefix, listAttrs, positionPrefix, count, tokenId)
+	if err != nil {
+		return err
+	}
+	return accesscontrol.PutState(ctx, countKey, []byte(strconv.Itoa(count+1)))
+}
+
+// _removeFromList moves the last entry of the list into the slot of tokenId and shrinks the list.
+func _removeFromList(ctx kalpsdk.TransactionContextInterface, listPrefix string, listAttrs []string, positionPrefix string, countKey string, tokenId string) error {
+	count, err := _readCounter(ctx, countKey)
+	if err != nil {
+		return err
+	}
+	positionKey, err := ctx.CreateCompositeKey(positionPrefix, []string{tokenId})
+	if err != nil {
+		return fmt.Errorf("failed to CreateCompositeKey positionKey: %v", err)
+	}
+	positionBytes, err := ctx.GetState(positionKey)
+	if err != nil {
+		return fmt.Errorf("failed to GetState positionKey %s: %v", positionKey, err)
+	}
+	// Tokens minted before enumeration was introduced have no entry until
+	// MigrateERC721Enumerable indexes them.
+	if positionBytes == nil {
+		return nil
+	}
+	position, err := strconv.Atoi(string(positionBytes))
+	if err != nil {
+		return fmt.Errorf("failed to decode position of token %s: %v", tokenId, err)
+	}
+	last := count - 1
+	if last < 0 || position > last {
+		return fmt.Errorf("enumeration of token %s is inconsistent", tokenId)
+	}
+
+	if position != last {
+		lastTokenId, err := _tokenAt(ctx, listPrefix, listAttrs, countKey, last)
+		if err != nil {
+			return err
+		}
+		err = _writeListEntry(ctx, listPrefix, listAttrs, positionPrefix, position, lastTokenId)
+		if err != nil {
+			return err
+		}
+	}
+
+	lastKey, err := ctx.CreateCompositeKey(listPrefix, append(append([]string{}, listAttrs...), _indexString(last)))
+	if err != nil {
+		return fmt.Errorf("failed to CreateCompositeKey listKey: %v", err)
+	}
+	err = accesscontrol.DelState(ctx, lastKey)
+	if err != nil {
+		return fmt.Errorf("failed to DelState listKey %s: %v", lastKey, err)
+	}
+	err = accesscontrol.DelState(ctx, positionKey)
+	if err != nil {
+		return fmt.Errorf("failed to DelState positionKey %s: %v", positionKey, err)
+	}
+	return accesscontrol.PutState(ctx, countKey, []byte(strconv.Itoa(last)))
+}
+
+func _writeListEntry(ctx kalpsdk.TransactionContextInterface, listPrefix string, listAttrs []string, positionPrefix string, index int, tokenId string) error {
+	listKey, err := ctx.CreateCompositeKey(listPrefix, append(append([]string{}, listAttrs...), _indexString(index)))
+	if err != nil {
+		return fmt.Errorf("failed to CreateCompositeKey listKey: %v", err)
+	}
+	err = accesscontrol.PutState(ctx, listKey, []byte(tokenId))
+	if err != nil {
+		return fmt.Errorf("failed to PutState listKey %s: %v", listKey, err)
+	}
+	positionKey, err := ctx.CreateCompositeKey(positionPrefix, []string{tokenId})
+	if err != nil {
+		return fmt.Errorf("failed to CreateCompositeKey positionKey: %v", err)
+	}
+	return accesscontrol.PutState(ctx, positionKey, []byte(strconv.Itoa(index)))
+}
+
+func _tokenAt(ctx kalpsdk.TransactionContextInterface, listPrefix string, listAttrs []string, countKey string, index int) (string, error) {
+	count, err := _readCounter(ctx, countKey)
+	if err != nil {
+		return "", err
+	}
+	if index < 0 || index >= count {
+		return "", fmt.Errorf("index %d is out of bounds", index)
+	}
+	listKey, err := ctx.CreateCompositeKey(listPrefix, append(append([]string{}, listAttrs...), _indexString(index)))
+	if err != nil {
+		return "", fmt.Errorf("failed to CreateCompositeKey listKey: %v", err)
+	}
+	tokenIdBytes, err := ctx.GetState(listKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to GetState listKey %s: %v", listKey, err)
+	}
+	if tokenIdBytes == nil {
+		return "", fmt.Errorf("no token is enumerated at index %d", index)
+	}
+	return string(tokenIdBytes), nil
+}
+
+func _readCounter(ctx kalpsdk.TransactionContextInterface, key string) (int, error) {
+	counterBytes, err := ctx.GetState(key)
+	if err != nil {
+		return 0, fmt.Errorf("failed to GetState %s: %v", key, err)
+	}
+	if counterBytes == nil {
+		return 0, nil
+	}
+	counter, err := strconv.Atoi(string(counterBytes))
+	if err != nil {
+		return 0, fmt.Errorf("failed to decode counter %s: %v", key, err)
+	}
+	return counter, nil
+}
+
+func _indexString(index int) string {
+	return fmt.Sprintf("%020d", index)
+}
+
+// BalanceCheck compares the owner counter BalanceOf reads with the balance keys of the owner.
+type BalanceCheck struct {
+	Owner      string `json:"owner"`
+	Counter    int    `json:"counter"`
+	Keys       int    `json:"keys"`
+	Consistent bool   `json:"consistent"`
+}
+
+// VerifyBalance counts the balance keys of owner and compares them with the owner counter, to
+// find counters a failed upgrade or a bug left behind. MigrateERC721Enumerable rebuilds missing
+// entries; MigrateERC721BalancesV2 repairs the balance keys.
+func (c *TokenERC721Contract) VerifyBalance(ctx kalpsdk.TransactionContextInterface, owner string) (*BalanceCheck, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	countKey, err := ctx.CreateCompositeKey(ownerCountPrefix, []string{owner})
+	if err != nil {
+		return nil, fmt.Errorf("failed to CreateCompositeKey ownerCountKey: %v", err)
+	}
+	counter, err := _readCounter(ctx, countKey)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := _countBalanceKeys(ctx, owner)
+	if err != nil {
+		return nil, err
+	}
+	return &BalanceCheck{owner, counter, keys, counter == keys}, nil
+}
+
+// _isEnumerated reports whether every token is in the enumeration index, so its counters are
+// complete: the contract was initialized with enumeration or upgraded past ERC721EnumerableV1.
+func _isEnumerated(ctx kalpsdk.TransactionContextInterface) (bool, error) {
+	version, err := migration.GetVersion(ctx)
+	if err != nil {
+		return false, err
+	}
+	return version >= migration.CurrentVersion(erc721Upgrades), nil
+}
+
+// _countBalanceKeys counts the balance keys of owner.
+func _countBalanceKeys(ctx kalpsdk.TransactionContextInterface, owner string) (int, error) {
+	iterator, err := ctx.GetStateByPartialCompositeKey(balancePrefix, []string{owner})
+	if err != nil {
+		return 0, fmt.Errorf("failed to GetStateByPartialCompositeKey %s: %v", balancePrefix, err)
+	}
+	defer iterator.Close()
+
+	balance := 0
+	for iterator.HasNext() {
+		_, err := iterator.Next()
+		if err != nil {
+			return 0, fmt.Errorf("failed to read balance keys of %s: %v", owner, err)
+		}
+		balance++
+	}
+	return balance, nil
+}
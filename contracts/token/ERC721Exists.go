@@ -0,0 +1,51 @@
+package token
+
+import (
+	"fmt"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+// TokenExistence is an entry of ExistBatch.
+type TokenExistence struct {
+	TokenId string `json:"tokenId"`
+	Exists  bool   `json:"exists"`
+}
+
+// Exists reports whether tokenId is minted and not burned.
+func (c *TokenERC721Contract) Exists(ctx kalpsdk.TransactionContextInterface, tokenId string) (bool, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return false, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return _nftExists(ctx, tokenId)
+}
+
+// ExistBatch reports for each of up to 500 tokenIds whether it exists, in the order given, so
+// minting frontends can check candidate ids in one query.
+func (c *TokenERC721Contract) ExistBatch(ctx kalpsdk.TransactionContextInterface, tokenIds []string) ([]*TokenExistence, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	if len(tokenIds) > maxPageSize {
+		return nil, fmt.Errorf("at most %d token ids can be checked at once", maxPageSize)
+	}
+
+	results := make([]*TokenExistence, 0, len(tokenIds))
+	for _, tokenId := range tokenIds {
+		exists, err := _nftExists(ctx, tokenId)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, &TokenExistence{tokenId, exists})
+	}
+	return results, nil
+}
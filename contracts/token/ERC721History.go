@@ -0,0 +1,118 @@
+package token
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+// TokenHistoryEntry is one committed write of a token record. Nft is nil when the write deleted
+// the token (a burn).
+type TokenHistoryEntry struct {
+	TxId      string `json:"txId"`
+	Timestamp int64  `json:"timestamp"`
+	IsDelete  bool   `json:"isDelete"`
+	Nft       *Nft   `json:"nft,omitempty" metadata:",optional"`
+}
+
+// GetTokenHistory returns every committed version of tokenId, oldest first, with the URI TokenURI
+// answers for each. It needs the peer's history database
+// (core.ledger.history.enableHistoryDatabase) and is meant for queries only: Fabric does not
+// re-check history reads at commit time.
+func (c *TokenERC721Contract) GetTokenHistory(ctx kalpsdk.TransactionContextInterface, tokenId string) ([]*TokenHistoryEntry, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	nftKey, err := ctx.CreateCompositeKey(nftPrefix, []string{tokenId})
+	if err != nil {
+		return nil, fmt.Errorf("failed to CreateCompositeKey %s: %v", tokenId, err)
+	}
+	history, err := _readTokenHistory(ctx, nftKey)
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range history {
+		if entry.Nft == nil {
+			continue
+		}
+		entry.Nft.TokenURI, err = _visibleTokenURI(ctx, entry.Nft)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return history, nil
+}
+
+// _readTokenHistory reads the history of nftKey and returns it oldest first.
+func _readTokenHistory(ctx kalpsdk.TransactionContextInterface, nftKey string) ([]*TokenHistoryEntry, error) {
+	iterator, err := ctx.GetHistoryForKey(nftKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to GetHistoryForKey %s: %v", nftKey, err)
+	}
+	defer iterator.Close()
+
+	history := []*TokenHistoryEntry{}
+	for iterator.HasNext() {
+		modification, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read history of %s: %v", nftKey, err)
+		}
+		entry := &TokenHistoryEntry{
+			TxId:      modification.TxId,
+			Timestamp: modification.Timestamp.GetSeconds(),
+			IsDelete:  modification.IsDelete,
+		}
+		if !modification.IsDelete {
+			entry.Nft = new(Nft)
+			err = json.Unmarshal(modification.Value, entry.Nft)
+			if err != nil {
+				return nil, fmt.Errorf("failed to Unmarshal history of %s at %s: %v", nftKey, modification.TxId, err)
+			}
+		}
+		history = append(history, entry)
+	}
+
+	// Fabric returns the newest write first
+	for i, j := 0, len(history)-1; i < j; i, j = i+1, j-1 {
+		history[i], history[j] = history[j], history[i]
+	}
+	return history, nil
+}
+
+// OwnershipRecord is one owner in the provenance of a token: Owner held the token from the
+// transaction TxId on. Owner is "0x0" once the token is burned.
+type OwnershipRecord struct {
+	Owner     string `json:"owner"`
+	TxId      string `json:"txId"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// GetOwnershipHistory returns the owners of tokenId in order, starting with the one it was minted
+// to. Writes that did not change the owner, such as approvals, are left out. Like GetTokenHistory
+// it needs the peer's history database and is meant for queries only.
+func (c *TokenERC721Contract) GetOwnershipHistory(ctx kalpsdk.TransactionContextInterface, tokenId string) ([]*OwnershipRecord, error) {
+	history, err := c.GetTokenHistory(ctx, tokenId)
+	if err != nil {
+		return nil, err
+	}
+
+	owners := []*OwnershipRecord{}
+	for _, entry := range history {
+		owner := "0x0"
+		if entry.Nft != nil {
+			owner = entry.Nft.Owner
+		}
+		if len(owners) > 0 && owners[len(owners)-1].Owner == owner {
+			continue
+		}
+		owners = append(owners, &OwnershipRecord{owner, entry.TxId, entry.Timestamp})
+	}
+	return owners, nil
+}
@@ -0,0 +1,50 @@
+package token
+
+import (
+	"fmt"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/journal"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+// SetJournalConfig switches the journal of rejected operations on or off, see journal.Config. The
+// caller must hold AdminRole.
+func (c *TokenERC721Contract) SetJournalConfig(ctx kalpsdk.TransactionContextInterface, cfg journal.Config) (bool, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return false, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	err = journal.SetConfig(ctx, cfg)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (c *TokenERC721Contract) GetJournalConfig(ctx kalpsdk.TransactionContextInterface) (*journal.Config, error) {
+	return journal.GetConfig(ctx)
+}
+
+// ReportRejection records that the transaction txID of account calling function was rejected with
+// reason, see journal.Report. amount is "" for operations that move no amount. The caller must
+// hold journal.ReporterRole.
+func (c *TokenERC721Contract) ReportRejection(ctx kalpsdk.TransactionContextInterface, txID string, account string, function string, amount string, reason string) (bool, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return false, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return journal.Report(ctx, txID, account, function, amount, reason)
+}
+
+// GetRejections returns up to pageSize rejected operations of account starting at bookmark,
+// oldest first. An empty account reads those of every account.
+func (c *TokenERC721Contract) GetRejections(ctx kalpsdk.TransactionContextInterface, account string, bookmark string, pageSize int) (*journal.Page, error) {
+	return journal.GetRejections(ctx, account, bookmark, pageSize)
+}
@@ -0,0 +1,69 @@
+package token
+
+import (
+	"fmt"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+// SetKYCRequired makes state writes of every function without an override require a KYC'd
+// caller, or stop requiring one.
+func (c *TokenERC721Contract) SetKYCRequired(ctx kalpsdk.TransactionContextInterface, required bool) (bool, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return false, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	err = accesscontrol.SetKYCRequired(ctx, required)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// SetFunctionKYCRequired overrides the KYC mode of function, e.g. "Transfer".
+func (c *TokenERC721Contract) SetFunctionKYCRequired(ctx kalpsdk.TransactionContextInterface, function string, required bool) (bool, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return false, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	err = accesscontrol.SetFunctionKYCRequired(ctx, function, required)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RemoveFunctionKYCOverride makes function follow the contract-wide KYC mode again.
+func (c *TokenERC721Contract) RemoveFunctionKYCOverride(ctx kalpsdk.TransactionContextInterface, function string) (bool, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return false, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	err = accesscontrol.RemoveFunctionKYCOverride(ctx, function)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (c *TokenERC721Contract) GetKYCConfig(ctx kalpsdk.TransactionContextInterface) (*accesscontrol.KYCConfig, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return accesscontrol.GetKYCConfig(ctx)
+}
@@ -0,0 +1,157 @@
+package token
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+	"github.com/thekalpstudio/kush-go/canonical"
+	"github.com/thekalpstudio/kush-go/events"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+const tokenLockPrefix = "lock"
+
+// TokenLock records why and when an admin locked a token. A locked token cannot be transferred,
+// approved or burned until it is unlocked.
+type TokenLock struct {
+	TokenId  string `json:"tokenId"`
+	Reason   string `json:"reason"`
+	LockedBy string `json:"lockedBy"`
+	LockedAt int64  `json:"lockedAt"`
+}
+
+// LockToken marks tokenId as non-transferable, e.g. while it is under legal dispute.
+func (c *TokenERC721Contract) LockToken(ctx kalpsdk.TransactionContextInterface, tokenId string, reason string) (*TokenLock, error) {
+	err := _checkAdmin1(ctx, "lock tokens")
+	if err != nil {
+		return nil, err
+	}
+	exists, err := _nftExists(ctx, tokenId)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("token %s does not exist", tokenId)
+	}
+	if reason == "" {
+		return nil, fmt.Errorf("a lock reason must be given")
+	}
+	existing, err := _readTokenLock(ctx, tokenId)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil {
+		return nil, fmt.Errorf("token %s is already locked", tokenId)
+	}
+
+	sender, err := ctx.GetUserID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to GetClientIdentity: %v", err)
+	}
+	lockedAt, err := _txTimestampSeconds(ctx)
+	if err != nil {
+		return nil, err
+	}
+	lock := &TokenLock{tokenId, reason, sender, lockedAt}
+
+	lockKey, err := ctx.CreateCompositeKey(tokenLockPrefix, []string{tokenId})
+	if err != nil {
+		return nil, fmt.Errorf("failed to CreateCompositeKey lockKey: %v", err)
+	}
+	lockBytes, err := canonical.Marshal(lock)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal lock: %v", err)
+	}
+	err = accesscontrol.PutState(ctx, lockKey, lockBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to PutState lockKey %s: %v", lockKey, err)
+	}
+
+	err = _emitTokenLockEvent(ctx, "TokenLocked", lock)
+	if err != nil {
+		return nil, err
+	}
+	return lock, nil
+}
+
+// UnlockToken makes a locked token transferable again.
+func (c *TokenERC721Contract) UnlockToken(ctx kalpsdk.TransactionContextInterface, tokenId string) (bool, error) {
+	err := _checkAdmin1(ctx, "unlock tokens")
+	if err != nil {
+		return false, err
+	}
+	lock, err := _readTokenLock(ctx, tokenId)
+	if err != nil {
+		return false, err
+	}
+	if lock == nil {
+		return false, fmt.Errorf("token %s is not locked", tokenId)
+	}
+
+	lockKey, err := ctx.CreateCompositeKey(tokenLockPrefix, []string{tokenId})
+	if err != nil {
+		return false, fmt.Errorf("failed to CreateCompositeKey lockKey: %v", err)
+	}
+	err = accesscontrol.DelState(ctx, lockKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to DelState lockKey %s: %v", lockKey, err)
+	}
+
+	// the event carries the lifted lock so the audit trail keeps its reason and timestamp
+	err = _emitTokenLockEvent(ctx, "TokenUnlocked", lock)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// GetTokenLock returns the lock of tokenId, or nil if the token is not locked.
+func (c *TokenERC721Contract) GetTokenLock(ctx kalpsdk.TransactionContextInterface, tokenId string) (*TokenLock, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return _readTokenLock(ctx, tokenId)
+}
+
+// _checkNotLocked fails if tokenId is locked.
+func _checkNotLocked(ctx kalpsdk.TransactionContextInterface, tokenId string) error {
+	lock, err := _readTokenLock(ctx, tokenId)
+	if err != nil {
+		return err
+	}
+	if lock != nil {
+		return fmt.Errorf("token %s is locked: %s", tokenId, lock.Reason)
+	}
+	return nil
+}
+
+func _readTokenLock(ctx kalpsdk.TransactionContextInterface, tokenId string) (*TokenLock, error) {
+	lockKey, err := ctx.CreateCompositeKey(tokenLockPrefix, []string{tokenId})
+	if err != nil {
+		return nil, fmt.Errorf("failed to CreateCompositeKey lockKey: %v", err)
+	}
+	lockBytes, err := ctx.GetState(lockKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to GetState lockKey %s: %v", lockKey, err)
+	}
+	if lockBytes == nil {
+		return nil, nil
+	}
+	lock := new(TokenLock)
+	err = json.Unmarshal(lockBytes, lock)
+	if err != nil {
+		return nil, fmt.Errorf("failed to Unmarshal lockBytes: %v", err)
+	}
+	return lock, nil
+}
+
+func _emitTokenLockEvent(ctx kalpsdk.TransactionContextInterface, eventName string, lock *TokenLock) error {
+	lockEvent := events.TokenLockEvent{TokenId: lock.TokenId, Reason: lock.Reason, LockedBy: lock.LockedBy, LockedAt: lock.LockedAt}
+	return events.Emit(ctx, eventName, &lockEvent)
+}
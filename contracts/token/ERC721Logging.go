@@ -0,0 +1,30 @@
+package token
+
+import (
+	"fmt"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+	"github.com/thekalpstudio/kush-go/logging"
+)
+
+// SetLogLevel changes the level at which the peer evaluating it logs the transactions of the
+// collection, one of logging.Levels, until the chaincode restarts.
+func (c *TokenERC721Contract) SetLogLevel(ctx kalpsdk.TransactionContextInterface, level string) (bool, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return false, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	err = logging.SetLogLevel(ctx, level)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (c *TokenERC721Contract) GetLogLevel(ctx kalpsdk.TransactionContextInterface) (string, error) {
+	return logging.GetLogLevel(ctx)
+}
@@ -0,0 +1,209 @@
+package token
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+	"github.com/thekalpstudio/kush-go/audit"
+	"github.com/thekalpstudio/kush-go/canonical"
+	"github.com/thekalpstudio/kush-go/events"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// tokenMetadataSchema follows the widely used ERC721 metadata JSON format. Attribute values may be
+// strings or numbers; display_type is optional.
+const tokenMetadataSchema = `{
+  "type": "object",
+  "additionalProperties": false,
+  "required": ["name"],
+  "properties": {
+    "name": {"type": "string", "minLength": 1, "maxLength": 256},
+    "description": {"type": "string", "maxLength": 4096},
+    "image": {"type": "string", "maxLength": 2048},
+    "attributes": {
+      "type": "array",
+      "maxItems": 100,
+      "items": {
+        "type": "object",
+        "additionalProperties": false,
+        "required": ["trait_type", "value"],
+        "properties": {
+          "trait_type": {"type": "string", "minLength": 1, "maxLength": 256},
+          "value": {"type": ["string", "number"]},
+          "display_type": {"type": "string", "maxLength": 64}
+        }
+      }
+    }
+  }
+}`
+
+var tokenMetadataSchemaLoader = gojsonschema.NewStringLoader(tokenMetadataSchema)
+
+// TokenMetadata is the structured metadata of a token, stored on-chain alongside its URI.
+type TokenMetadata struct {
+	Name        string              `json:"name"`
+	Description string              `json:"description,omitempty" metadata:",optional"`
+	Image       string              `json:"image,omitempty" metadata:",optional"`
+	Attributes  []MetadataAttribute `json:"attributes,omitempty" metadata:",optional"`
+}
+
+// MetadataAttribute is one trait of a token. Value is a string or a number.
+type MetadataAttribute struct {
+	TraitType   string      `json:"trait_type"`
+	Value       interface{} `json:"value"`
+	DisplayType string      `json:"display_type,omitempty" metadata:",optional"`
+}
+
+// MetadataUpdate is emitted when the metadata of a token changes (ERC-4906).
+type MetadataUpdate = events.MetadataUpdate
+
+// SetTokenMetadata validates metadataJSON against the token metadata schema and stores it on the
+// token.
+func (c *TokenERC721Contract) SetTokenMetadata(ctx kalpsdk.TransactionContextInterface, tokenId string, metadataJSON string) (*Nft, error) {
+	err := _checkAdmin1(ctx, "set token metadata")
+	if err != nil {
+		return nil, err
+	}
+	exists, err := _nftExists(ctx, tokenId)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("token %s does not exist", tokenId)
+	}
+
+	metadata, err := _parseTokenMetadata(metadataJSON)
+	if err != nil {
+		return nil, err
+	}
+
+	nft, err := _readNFT(ctx, tokenId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to _readNFT : %v", err)
+	}
+	nft.Metadata = metadata
+
+	err = _writeNFTWithUpdate(ctx, nft)
+	if err != nil {
+		return nil, err
+	}
+	return nft, nil
+}
+
+// GetTokenMetadata returns the structured metadata of tokenId, or nil if none was set.
+func (c *TokenERC721Contract) GetTokenMetadata(ctx kalpsdk.TransactionContextInterface, tokenId string) (*TokenMetadata, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	exists, err := _nftExists(ctx, tokenId)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("token %s does not exist", tokenId)
+	}
+
+	nft, err := _readNFT(ctx, tokenId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to _readNFT : %v", err)
+	}
+	return nft.Metadata, nil
+}
+
+// UpdateTokenURI points tokenId at newURI, e.g. after moving to another storage provider. Only the
+// token owner or a holder of the METADATA_ROLE may update it.
+func (c *TokenERC721Contract) UpdateTokenURI(ctx kalpsdk.TransactionContextInterface, tokenId string, newURI string) (*Nft, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	if newURI == "" {
+		return nil, fmt.Errorf("token URI must not be empty")
+	}
+
+	sender, err := ctx.GetUserID()
+	if err != nil {
+		return nil, fmt.Errorf("failed to GetClientIdentity: %v", err)
+	}
+	exists, err := _nftExists(ctx, tokenId)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("token %s does not exist", tokenId)
+	}
+	nft, err := _readNFT(ctx, tokenId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to _readNFT : %v", err)
+	}
+	if nft.Owner != sender {
+		authorized, err := accesscontrol.HasRole(ctx, MetadataRole, sender)
+		if err != nil {
+			return nil, err
+		}
+		if !authorized {
+			return nil, kusherrors.Errorf(kusherrors.ErrUnauthorized, "the sender is not the current owner nor holds %s", MetadataRole)
+		}
+	}
+
+	nft.TokenURI = newURI
+	err = audit.Record(ctx, "SetTokenURI", tokenId, newURI)
+	if err != nil {
+		return nil, err
+	}
+	err = _writeNFTWithUpdate(ctx, nft)
+	if err != nil {
+		return nil, err
+	}
+	return nft, nil
+}
+
+// _writeNFTWithUpdate stores nft and emits MetadataUpdate for it.
+func _writeNFTWithUpdate(ctx kalpsdk.TransactionContextInterface, nft *Nft) error {
+	nftKey, err := ctx.CreateCompositeKey(nftPrefix, []string{nft.TokenId})
+	if err != nil {
+		return fmt.Errorf("failed to CreateCompositeKey: %v", err)
+	}
+	nftBytes, err := canonical.Marshal(nft)
+	if err != nil {
+		return fmt.Errorf("failed to marshal nft: %v", err)
+	}
+	err = accesscontrol.PutState(ctx, nftKey, nftBytes)
+	if err != nil {
+		return fmt.Errorf("failed to PutState nftBytes %s: %v", nftBytes, err)
+	}
+
+	return events.Emit(ctx, "MetadataUpdate", &MetadataUpdate{TokenId: nft.TokenId})
+}
+
+func _parseTokenMetadata(metadataJSON string) (*TokenMetadata, error) {
+	result, err := gojsonschema.Validate(tokenMetadataSchemaLoader, gojsonschema.NewStringLoader(metadataJSON))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse token metadata: %v", err)
+	}
+	if !result.Valid() {
+		problems := []string{}
+		for _, desc := range result.Errors() {
+			problems = append(problems, desc.String())
+		}
+		return nil, fmt.Errorf("invalid token metadata: %s", strings.Join(problems, "; "))
+	}
+
+	metadata := new(TokenMetadata)
+	err = json.Unmarshal([]byte(metadataJSON), metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to Unmarshal metadata: %v", err)
+	}
+	return metadata, nil
+}
@@ -0,0 +1,35 @@
+package token
+
+import (
+	"fmt"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+	"github.com/thekalpstudio/kush-go/metrics"
+)
+
+// GetMetrics returns the number of transfers, mints and burns of the collection and of the
+// accounts that ever held a token of it.
+func (c *TokenERC721Contract) GetMetrics(ctx kalpsdk.TransactionContextInterface) (*metrics.Metrics, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return metrics.GetMetrics(ctx)
+}
+
+// CompactMetrics folds the per-transaction metrics deltas into the totals, keeping GetMetrics
+// cheap. The caller must hold AdminRole.
+func (c *TokenERC721Contract) CompactMetrics(ctx kalpsdk.TransactionContextInterface) (int, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return 0, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return metrics.CompactMetrics(ctx)
+}
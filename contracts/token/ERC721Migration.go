@@ -0,0 +1,320 @@
+package token
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+	"github.com/thekalpstudio/kush-go/metrics"
+	"github.com/thekalpstudio/kush-go/migration"
+)
+
+const erc721BalancesV2 = "ERC721BalancesV2"
+const erc721EnumerableV1 = "ERC721EnumerableV1"
+
+// erc721Migrations lists every migration known to this contract by name. Migrations are built per
+// batch so that steps can keep state that has to survive between keys of the same transaction.
+var erc721Migrations = map[string]func() migration.Migration{
+	erc721BalancesV2: func() migration.Migration {
+		return migration.Migration{
+			Name: erc721BalancesV2,
+			Phases: []migration.Phase{
+				{Prefix: balancePrefix, Step: dropOrphanBalanceStep},
+				{Prefix: nftPrefix, Step: restoreBalanceStep},
+			},
+		}
+	},
+	erc721EnumerableV1: func() migration.Migration {
+		return migration.Migration{
+			Name: erc721EnumerableV1,
+			Phases: []migration.Phase{
+				{Prefix: nftPrefix, Step: newEnumerateTokenStep()},
+			},
+		}
+	},
+}
+
+// erc721Upgrades moves state written by earlier versions of the contract to the current schema.
+var erc721Upgrades = []migration.Upgrade{
+	{From: 0, Migration: erc721Migrations[erc721BalancesV2]},
+	{From: 1, Migration: erc721Migrations[erc721EnumerableV1]},
+}
+
+// GetVersion returns the schema version of the contract state.
+func (c *TokenERC721Contract) GetVersion(ctx kalpsdk.TransactionContextInterface) (int, error) {
+	return migration.GetVersion(ctx)
+}
+
+// Migrate runs the next batch of the upgrade from fromVersion, moving the state to fromVersion+1
+// once it is Done. Invoke it repeatedly, and again for each version, until GetVersion reports the
+// current version.
+func (c *TokenERC721Contract) Migrate(ctx kalpsdk.TransactionContextInterface, fromVersion int, batchSize int, dryRun bool) (*migration.Report, error) {
+	err := checkMigrationAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return migration.Migrate(ctx, erc721Upgrades, fromVersion, batchSize, dryRun)
+}
+
+// PreviewMigration dry-runs up to batchSize keys of the upgrade from fromVersion after phase and
+// bookmark, those of MigrationStatus or of the previous preview. Evaluate it, do not submit it.
+func (c *TokenERC721Contract) PreviewMigration(ctx kalpsdk.TransactionContextInterface, fromVersion int, phase int, bookmark string, batchSize int) (*migration.Report, error) {
+	err := checkMigrationAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return migration.PreviewUpgrade(ctx, erc721Upgrades, fromVersion, phase, bookmark, batchSize)
+}
+
+// MigrateERC721BalancesV2 rebuilds the balance index from the nft records: every token ends up
+// with exactly one balance key for its current owner. Invoke it repeatedly until Done.
+func (c *TokenERC721Contract) MigrateERC721BalancesV2(ctx kalpsdk.TransactionContextInterface, batchSize int, dryRun bool) (*migration.Report, error) {
+	return runERC721Migration(ctx, erc721BalancesV2, batchSize, dryRun)
+}
+
+// MigrateERC721Enumerable adds tokens minted before the Enumerable extension to the token and
+// owner index lists. Invoke it repeatedly until Done.
+func (c *TokenERC721Contract) MigrateERC721Enumerable(ctx kalpsdk.TransactionContextInterface, batchSize int, dryRun bool) (*migration.Report, error) {
+	return runERC721Migration(ctx, erc721EnumerableV1, batchSize, dryRun)
+}
+
+// MigrationStatus returns the progress of the named migration.
+func (c *TokenERC721Contract) MigrationStatus(ctx kalpsdk.TransactionContextInterface, name string) (*migration.Status, error) {
+	if _, ok := erc721Migrations[name]; !ok {
+		return nil, fmt.Errorf("unknown migration %s", name)
+	}
+	return migration.GetStatus(ctx, name)
+}
+
+func runERC721Migration(ctx kalpsdk.TransactionContextInterface, name string, batchSize int, dryRun bool) (*migration.Report, error) {
+	err := checkMigrationAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return migration.Run(ctx, erc721Migrations[name](), batchSize, dryRun)
+}
+
+func checkMigrationAdmin(ctx kalpsdk.TransactionContextInterface) error {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	err = accesscontrol.CheckRole(ctx, accesscontrol.AdminRole)
+	if err != nil {
+		return fmt.Errorf("client is not authorized to run migrations: %v", err)
+	}
+	return nil
+}
+
+// dropOrphanBalanceStep deletes balance keys whose token is gone or owned by someone else.
+func dropOrphanBalanceStep(ctx kalpsdk.TransactionContextInterface, key string, value []byte, dryRun bool) ([]string, error) {
+	_, parts, err := ctx.SplitCompositeKey(key)
+	if err != nil || len(parts) != 2 {
+		return nil, fmt.Errorf("failed to split balance key %s: %v", key, err)
+	}
+	owner, tokenId := parts[0], parts[1]
+	exists, err := _nftExists(ctx, tokenId)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		nft, err := _readNFT(ctx, tokenId)
+		if err != nil {
+			return nil, err
+		}
+		if nft.Owner == owner {
+			return nil, nil
+		}
+	}
+	if !dryRun {
+		err = ctx.DelStateWithoutKYC(key)
+		if err != nil {
+			return nil, fmt.Errorf("failed to DelState balance key %s: %v", key, err)
+		}
+	}
+	return []string{key}, nil
+}
+
+// restoreBalanceStep writes the owner's balance key for tokens that are missing one.
+func restoreBalanceStep(ctx kalpsdk.TransactionContextInterface, key string, value []byte, dryRun bool) ([]string, error) {
+	nft := new(Nft)
+	err := json.Unmarshal(value, nft)
+	if err != nil {
+		return nil, fmt.Errorf("failed to Unmarshal nft %s: %v", key, err)
+	}
+	balanceKey, err := ctx.CreateCompositeKey(balancePrefix, []string{nft.Owner, nft.TokenId})
+	if err != nil {
+		return nil, fmt.Errorf("failed to CreateCompositeKey to balanceKey: %v", err)
+	}
+	balanceBytes, err := ctx.GetState(balanceKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to GetState balanceKey %s: %v", balanceKey, err)
+	}
+	if balanceBytes != nil {
+		return nil, nil
+	}
+	if !dryRun {
+		err = ctx.PutStateWithoutKYC(balanceKey, []byte{'\u0000'})
+		if err != nil {
+			return nil, fmt.Errorf("failed to PutState balanceKey %s: %v", balanceKey, err)
+		}
+	}
+	return []string{balanceKey}, nil
+}
+
+// newEnumerateTokenStep indexes tokens that are missing from the global or owner list. World state
+// reads do not see writes of the same transaction, so list lengths are tracked in memory across
+// the keys of one batch.
+func newEnumerateTokenStep() migration.Step {
+	counts := make(map[string]int)
+	appendCached := func(ctx kalpsdk.TransactionContextInterface, listPrefix string, listAttrs []string, positionPrefix string, countKey string, tokenId string) error {
+		count, ok := counts[countKey]
+		if !ok {
+			var err error
+			count, err = _readCounter(ctx, countKey)
+			if err != nil {
+				return err
+			}
+		}
+		err := _writeListEntry(ctx, listPrefix, listAttrs, positionPrefix, count, tokenId)
+		if err != nil {
+			return err
+		}
+		counts[countKey] = count + 1
+		return ctx.PutStateWithoutKYC(countKey, []byte(strconv.Itoa(count+1)))
+	}
+
+	return func(ctx kalpsdk.TransactionContextInterface, key string, value []byte, dryRun bool) ([]string, error) {
+		nft := new(Nft)
+		err := json.Unmarshal(value, nft)
+		if err != nil {
+			return nil, fmt.Errorf("failed to Unmarshal nft %s: %v", key, err)
+		}
+
+		affected := []string{}
+		globalKey, err := ctx.CreateCompositeKey(tokenPositionPrefix, []string{nft.TokenId})
+		if err != nil {
+			return nil, fmt.Errorf("failed to CreateCompositeKey positionKey: %v", err)
+		}
+		globalBytes, err := ctx.GetState(globalKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to GetState positionKey %s: %v", globalKey, err)
+		}
+		if globalBytes == nil {
+			affected = append(affected, globalKey)
+			if !dryRun {
+				err = appendCached(ctx, tokenIndexPrefix, nil, tokenPositionPrefix, tokenCountKey, nft.TokenId)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		ownerKey, err := ctx.CreateCompositeKey(ownerPositionPrefix, []string{nft.TokenId})
+		if err != nil {
+			return nil, fmt.Errorf("failed to CreateCompositeKey positionKey: %v", err)
+		}
+		ownerBytes, err := ctx.GetState(ownerKey)
+		if err != nil {
+			return nil, fmt.Errorf("failed to GetState positionKey %s: %v", ownerKey, err)
+		}
+		if ownerBytes == nil {
+			affected = append(affected, ownerKey)
+			if !dryRun {
+				countKey, err := ctx.CreateCompositeKey(ownerCountPrefix, []string{nft.Owner})
+				if err != nil {
+					return nil, fmt.Errorf("failed to CreateCompositeKey ownerCountKey: %v", err)
+				}
+				err = appendCached(ctx, ownerTokenPrefix, []string{nft.Owner}, ownerPositionPrefix, countKey, nft.TokenId)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+		return affected, nil
+	}
+}
+
+// erc721ExportPrefixes are the keys ExportState reads: contract options, tokens, balances,
+// approvals and the enumeration index.
+var erc721ExportPrefixes = []string{
+	migration.SimpleKeys, nftPrefix, balancePrefix, approvalPrefix,
+	tokenIndexPrefix, tokenPositionPrefix, ownerTokenPrefix, ownerPositionPrefix, ownerCountPrefix,
+}
+
+// ExportState returns the next chunk of the token state, for backups, audits or moving the
+// collection to a new channel. Start with phase 0 and an empty bookmark and continue with those of
+// the returned chunk until it is Done. The caller must hold AdminRole.
+func (c *TokenERC721Contract) ExportState(ctx kalpsdk.TransactionContextInterface, phase int, bookmark string, pageSize int) (*migration.Chunk, error) {
+	err := checkMigrationAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return migration.Export(ctx, erc721ExportPrefixes, phase, bookmark, pageSize)
+}
+
+// ImportState writes a chunk exported by ExportState of a previous deployment, so a collection
+// moves to a new channel without replaying its transactions. Initialize the collection with
+// ImportState set, import every chunk before the collection is opened to users, then call
+// SealImport. The token count travels with the enumeration index, and the activity metrics start
+// over. The caller must hold AdminRole.
+func (c *TokenERC721Contract) ImportState(ctx kalpsdk.TransactionContextInterface, chunk migration.Chunk) (int, error) {
+	err := checkMigrationAdmin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	entries := make([]*migration.Entry, 0, len(chunk.Entries))
+	for _, entry := range chunk.Entries {
+		if entry.Prefix == migration.SimpleKeys && metrics.IsDerivedKey(entry.Key) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	chunk.Entries = entries
+	return migration.Import(ctx, erc721ExportPrefixes, &chunk)
+}
+
+// SealImport refuses every later ImportState call. The caller must hold AdminRole.
+func (c *TokenERC721Contract) SealImport(ctx kalpsdk.TransactionContextInterface) error {
+	err := checkMigrationAdmin(ctx)
+	if err != nil {
+		return err
+	}
+	return migration.SealImport(ctx)
+}
+
+// erc721Decoders decode the values GetRawStateRange reads. Balance keys are markers without a
+// value.
+var erc721Decoders = map[string]migration.Decoder{
+	migration.SimpleKeys: migration.DecodeText,
+	nftPrefix: func(value []byte) (interface{}, error) {
+		nft := new(Nft)
+		err := json.Unmarshal(value, nft)
+		return nft, err
+	},
+	approvalPrefix:      migration.DecodeJSON,
+	tokenIndexPrefix:    migration.DecodeText,
+	tokenPositionPrefix: migration.DecodeText,
+	ownerTokenPrefix:    migration.DecodeText,
+	ownerPositionPrefix: migration.DecodeText,
+	ownerCountPrefix:    migration.DecodeText,
+}
+
+// GetRawStateRange returns up to pageSize raw keys under prefix, a composite key object type or ""
+// for the simple keys, starting at bookmark, decoding the values of the known prefixes. It is
+// meant for debugging orphaned keys without access to the state database. The caller must hold
+// AdminRole.
+func (c *TokenERC721Contract) GetRawStateRange(ctx kalpsdk.TransactionContextInterface, prefix string, bookmark string, pageSize int) (*migration.RawPage, error) {
+	err := checkMigrationAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return migration.RawRange(ctx, prefix, erc721Decoders, bookmark, pageSize)
+}
@@ -0,0 +1,67 @@
+package token
+
+import (
+	"testing"
+
+	"github.com/thekalpstudio/kush-go/migration"
+	"github.com/thekalpstudio/kush-go/testutil"
+)
+
+func TestERC721ImportState(t *testing.T) {
+	source, c := newERC721(t)
+	testutil.MustRun(t, source, admin, "MintWithTokenURI", func(ctx *testutil.Context) error {
+		_, err := c.MintWithTokenURI(ctx, "2", "https://example.com/2.json")
+		return err
+	})
+	testutil.MustRun(t, source, admin, "TransferFrom", func(ctx *testutil.Context) error {
+		_, err := c.TransferFrom(ctx, admin, "bob", "2")
+		return err
+	})
+	chunks := []*migration.Chunk{}
+	for phase, bookmark, done := 0, "", false; !done; {
+		chunk, err := c.ExportState(source.Tx(admin, "ExportState"), phase, bookmark, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+		chunks = append(chunks, chunk)
+		phase, bookmark, done = chunk.Phase, chunk.Bookmark, chunk.Done
+	}
+
+	l := testutil.NewLedger()
+	testutil.MustRun(t, l, admin, "Initialize", func(ctx *testutil.Context) error {
+		cfg := testConfig()
+		cfg.ImportState = true
+		_, err := c.Initialize(ctx, cfg)
+		return err
+	})
+	for _, chunk := range chunks {
+		chunk := chunk
+		testutil.MustRun(t, l, admin, "ImportState", func(ctx *testutil.Context) error {
+			_, err := c.ImportState(ctx, *chunk)
+			return err
+		})
+	}
+	testutil.MustRun(t, l, admin, "SealImport", func(ctx *testutil.Context) error {
+		return c.SealImport(ctx)
+	})
+
+	for tokenId, want := range map[string]string{"1": admin, "2": "bob"} {
+		if owner := erc721Owner(t, l, c, tokenId); owner != want {
+			t.Fatalf("owner of %s is %s, want %s", tokenId, owner, want)
+		}
+	}
+	if balance := erc721Balance(t, l, c, "bob"); balance != 1 {
+		t.Fatalf("balance of bob is %d, want 1", balance)
+	}
+	ctx := l.Tx(admin, "TotalSupply")
+	if supply, err := c.TotalSupply(ctx); err != nil || supply != 2 {
+		t.Fatalf("total supply is %d, want 2: %v", supply, err)
+	}
+	if tokenId, err := c.TokenByIndex(ctx, 1); err != nil || tokenId != "2" {
+		t.Fatalf("token at index 1 is %s, want 2: %v", tokenId, err)
+	}
+	testutil.CheckErr(t, testutil.Run(l, admin, "ImportState", func(ctx *testutil.Context) error {
+		_, err := c.ImportState(ctx, *chunks[0])
+		return err
+	}), true, "")
+}
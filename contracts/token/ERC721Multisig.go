@@ -0,0 +1,129 @@
+package token
+
+import (
+	"fmt"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+	"github.com/thekalpstudio/kush-go/denylist"
+	"github.com/thekalpstudio/kush-go/guardian"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+// SetMultisig makes role changes require threshold approvals of signers. Only the owner may
+// enable it; afterwards the configuration changes through an accesscontrol.ActionSetMultisig
+// proposal. ERC721 mints are single tokens, so no mint threshold applies.
+func (c *TokenERC721Contract) SetMultisig(ctx kalpsdk.TransactionContextInterface, signers []string, threshold int) (bool, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return false, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	err = accesscontrol.SetMultisig(ctx, &accesscontrol.MultisigConfig{Signers: signers, Threshold: threshold})
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// GetMultisig returns the multisig configuration, or nil if multisig approval is disabled.
+func (c *TokenERC721Contract) GetMultisig(ctx kalpsdk.TransactionContextInterface) (*accesscontrol.MultisigConfig, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return accesscontrol.GetMultisig(ctx)
+}
+
+// Propose records a proposal for action with args, valid for ttl seconds. See
+// accesscontrol.Proposal for the arguments of each action.
+func (c *TokenERC721Contract) Propose(ctx kalpsdk.TransactionContextInterface, action string, args []string, ttl int64) (*accesscontrol.Proposal, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return accesscontrol.Propose(ctx, action, args, ttl)
+}
+
+// ApproveProposal adds the caller's approval to proposal id.
+func (c *TokenERC721Contract) ApproveProposal(ctx kalpsdk.TransactionContextInterface, id string) (*accesscontrol.Proposal, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return accesscontrol.ApproveProposal(ctx, id)
+}
+
+func (c *TokenERC721Contract) GetProposal(ctx kalpsdk.TransactionContextInterface, id string) (*accesscontrol.Proposal, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return accesscontrol.GetProposal(ctx, id)
+}
+
+// GetPendingProposals returns the proposals that are neither executed nor expired.
+func (c *TokenERC721Contract) GetPendingProposals(ctx kalpsdk.TransactionContextInterface) ([]*accesscontrol.Proposal, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return accesscontrol.GetPendingProposals(ctx)
+}
+
+// ExecuteProposal executes proposal id once enough signers approved it, or schedules it while
+// the timelock covers its action. Only role, ownership and configuration proposals apply to
+// ERC721.
+func (c *TokenERC721Contract) ExecuteProposal(ctx kalpsdk.TransactionContextInterface, id string) (*accesscontrol.Proposal, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return accesscontrol.ExecuteProposal(ctx, id, _erc721Executor(ctx))
+}
+
+// _erc721Executor executes the ERC721 actions of approved proposals and ready timelock
+// operations.
+func _erc721Executor(ctx kalpsdk.TransactionContextInterface) accesscontrol.Executor {
+	return func(action string, args []string) error {
+		switch action {
+		case accesscontrol.ActionUpdateConfig:
+			if len(args) != 1 {
+				return fmt.Errorf("%s takes a JSON config", action)
+			}
+			return _applyERC721Config(ctx, args[0])
+		case accesscontrol.ActionSetGuardian:
+			if len(args) != 1 {
+				return fmt.Errorf("%s takes a chaincode name", action)
+			}
+			return guardian.WriteGuardian(ctx, args[0])
+		case accesscontrol.ActionSetDenyList:
+			if len(args) != 1 {
+				return fmt.Errorf("%s takes a chaincode name", action)
+			}
+			return denylist.WriteDenyList(ctx, args[0])
+		default:
+			return fmt.Errorf("action %s is not supported by this contract", action)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package token
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+// GetOperators returns the operators account ever approved with SetApprovalForAll, revoked ones
+// included with Approved false, so owners can review and revoke blanket approvals.
+func (c *TokenERC721Contract) GetOperators(ctx kalpsdk.TransactionContextInterface, account string) ([]*Approval, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	if account == "" {
+		return nil, fmt.Errorf("account must not be empty")
+	}
+
+	iterator, err := ctx.GetStateByPartialCompositeKey(approvalPrefix, []string{account})
+	if err != nil {
+		return nil, fmt.Errorf("failed to GetStateByPartialCompositeKey %s: %v", approvalPrefix, err)
+	}
+	defer iterator.Close()
+
+	operators := []*Approval{}
+	for iterator.HasNext() {
+		queryResponse, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read approvals of %s: %v", account, err)
+		}
+		approval := new(Approval)
+		err = json.Unmarshal(queryResponse.Value, approval)
+		if err != nil {
+			return nil, fmt.Errorf("failed to Unmarshal approval %s: %v", queryResponse.Key, err)
+		}
+		operators = append(operators, approval)
+	}
+	return operators, nil
+}
@@ -0,0 +1,42 @@
+package token
+
+import (
+	"fmt"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/guardian"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+// SetGuardian makes approvals, transfers, mints, burns and rentals stop while the guardian
+// chaincode chaincodeName is paused. An empty name detaches the guardian.
+func (c *TokenERC721Contract) SetGuardian(ctx kalpsdk.TransactionContextInterface, chaincodeName string) (bool, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return false, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	err = guardian.SetGuardian(ctx, chaincodeName)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func (c *TokenERC721Contract) GetGuardian(ctx kalpsdk.TransactionContextInterface) (string, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return "", kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	return guardian.GetGuardian(ctx)
+}
+
+// _checkNotPaused fails while the guardian of the contract is paused.
+func _checkNotPaused(ctx kalpsdk.TransactionContextInterface) error {
+	return guardian.CheckNotGloballyPaused(ctx)
+}
@@ -0,0 +1,81 @@
+package token
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+const promoterKey = "promote~chaincode"
+
+// SetPromoter names the ERC1155 chaincode allowed to mint promoted tokens. An empty name
+// disables promotion.
+func (c *TokenERC721Contract) SetPromoter(ctx kalpsdk.TransactionContextInterface, chaincodeName string) (bool, error) {
+	err := _checkAdmin1(ctx, "set the promoter")
+	if err != nil {
+		return false, err
+	}
+	if chaincodeName == "" {
+		err = accesscontrol.DelState(ctx, promoterKey)
+	} else {
+		err = accesscontrol.PutState(ctx, promoterKey, []byte(chaincodeName))
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to update promoterKey %s: %v", promoterKey, err)
+	}
+	return true, nil
+}
+
+// MintPromoted mints tokenId to the caller from its promotion on the promoter chaincode, which
+// burned the unique ERC1155 token it replaces. The promotion is claimed through ClaimPromotion of
+// the promoter, which checks that the caller promoted tokenId and lets it be claimed only once;
+// the token gets the URI and metadata recorded with it.
+func (c *TokenERC721Contract) MintPromoted(ctx kalpsdk.TransactionContextInterface, tokenId string) (*Nft, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	promoterBytes, err := ctx.GetState(promoterKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to GetState promoterKey %s: %v", promoterKey, err)
+	}
+	if promoterBytes == nil {
+		return nil, fmt.Errorf("promotion is disabled, call SetPromoter() first")
+	}
+	response := ctx.InvokeChaincode(string(promoterBytes), [][]byte{[]byte("ClaimPromotion"), []byte(tokenId)}, "")
+	if response.Status != shim.OK {
+		return nil, fmt.Errorf("promoter chaincode %s refused the promotion of token %s: %s", promoterBytes, tokenId, response.Message)
+	}
+	claimed := new(promotion)
+	err = json.Unmarshal(response.Payload, claimed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode the promotion of token %s: %v", tokenId, err)
+	}
+	if claimed.TokenId != tokenId {
+		return nil, fmt.Errorf("promoter chaincode %s answered with the promotion of token %s", promoterBytes, claimed.TokenId)
+	}
+
+	var metadata *TokenMetadata
+	if claimed.Metadata != "" {
+		metadata, err = _parseTokenMetadata(claimed.Metadata)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return _mintWithTokenURI(ctx, tokenId, claimed.URI, metadata)
+}
+
+// promotion is the part of the promotion record of the promoter chaincode MintPromoted uses.
+type promotion struct {
+	TokenId  string `json:"tokenId"`
+	URI      string `json:"uri"`
+	Metadata string `json:"metadata"`
+}
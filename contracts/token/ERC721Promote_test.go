@@ -0,0 +1,54 @@
+package token
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/thekalpstudio/kush-go/testutil"
+)
+
+// promoter registers a promoter chaincode on l that answers the first ClaimPromotion of tokenId
+// made by holder.
+func promoter(l *testutil.Ledger, holder string, tokenId string) {
+	claimed := false
+	l.RegisterChaincode("erc1155", func(args [][]byte) peer.Response {
+		if string(args[0]) != "ClaimPromotion" || string(args[1]) != tokenId || claimed {
+			return shim.Error("no promotion to claim")
+		}
+		claimed = true
+		promotionJSON, _ := json.Marshal(map[string]interface{}{
+			"account": testutil.ClientID(holder), "tokenId": tokenId,
+			"uri": "https://example.com/promoted.json", "metadata": `{"name":"Promoted"}`,
+		})
+		return shim.Success(promotionJSON)
+	})
+}
+
+func TestERC721MintPromoted(t *testing.T) {
+	l, c := newERC721(t)
+	promoter(l, "bob", "7")
+	mint := func(ctx *testutil.Context) error {
+		_, err := c.MintPromoted(ctx, "7")
+		return err
+	}
+	testutil.CheckErr(t, testutil.Run(l, "bob", "MintPromoted", mint), true, "")
+
+	testutil.MustRun(t, l, admin, "SetPromoter", func(ctx *testutil.Context) error {
+		_, err := c.SetPromoter(ctx, "erc1155")
+		return err
+	})
+	testutil.MustRun(t, l, "bob", "MintPromoted", mint)
+	if owner := erc721Owner(t, l, c, "7"); owner != "bob" {
+		t.Fatalf("owner is %s, want bob", owner)
+	}
+	if uri, err := c.TokenURI(l.Tx("bob", "TokenURI"), "7"); err != nil || uri != "https://example.com/promoted.json" {
+		t.Fatalf("token URI is %q: %v", uri, err)
+	}
+	// the promoter answers a promotion once
+	testutil.CheckErr(t, testutil.Run(l, "bob", "MintPromoted", func(ctx *testutil.Context) error {
+		_, err := c.MintPromoted(ctx, "8")
+		return err
+	}), true, "")
+}
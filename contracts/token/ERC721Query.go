@@ -0,0 +1,133 @@
+package token
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+	"github.com/thekalpstudio/kush-go/pagination"
+)
+
+// QueryNFTs returns up to pageSize tokens matching the CouchDB selector, a JSON object such as
+// {"owner":"x","metadata.attributes":{"$elemMatch":{"trait_type":"rarity","value":"legendary"}}},
+// starting at bookmark. It needs a CouchDB state database; the indexes in
+// integration/chaincode/erc721/META-INF must be packaged with the chaincode for such queries to
+// stay fast. Other documents matching the selector are skipped, so a page may hold fewer than
+// pageSize tokens before the last one. Token URIs are those TokenURI answers, and selectors may not
+// match on tokenURI until the collection is revealed. Total is the number of tokens in the page.
+func (c *TokenERC721Contract) QueryNFTs(ctx kalpsdk.TransactionContextInterface, selector string, bookmark string, pageSize int) (*TokenPage, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	query, err := pagination.SelectorQuery(selector)
+	if err != nil {
+		return nil, err
+	}
+	hidden, err := _isURIHidden(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if hidden {
+		var fields interface{}
+		err = json.Unmarshal([]byte(selector), &fields)
+		if err != nil {
+			return nil, fmt.Errorf("selector must be a non-empty JSON object")
+		}
+		if _selectsField(fields, "tokenURI") {
+			return nil, fmt.Errorf("token URIs are hidden until the collection is revealed")
+		}
+	}
+	states, next, err := pagination.ByQuery(ctx, query, bookmark, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	page := &TokenPage{Tokens: []*Nft{}, Bookmark: next}
+	for _, state := range states {
+		objectType, _, err := ctx.SplitCompositeKey(state.Key)
+		if err != nil || objectType != nftPrefix {
+			continue
+		}
+		nft := new(Nft)
+		err = json.Unmarshal(state.Value, nft)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode nft %s: %v", state.Key, err)
+		}
+		nft.TokenURI, err = _visibleTokenURI(ctx, nft)
+		if err != nil {
+			return nil, err
+		}
+		page.Tokens = append(page.Tokens, nft)
+	}
+	page.Total = len(page.Tokens)
+	return page, nil
+}
+
+// _selectsField reports whether the selector value, or an operator nested in it, matches on
+// field or one of its subfields.
+func _selectsField(value interface{}, field string) bool {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, nested := range value {
+			if key == field || strings.HasPrefix(key, field+".") || _selectsField(nested, field) {
+				return true
+			}
+		}
+	case []interface{}:
+		for _, nested := range value {
+			if _selectsField(nested, field) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// FindTokensByURIPrefix returns the tokens whose stored URI starts with prefix, such as the
+// address of a retired storage host, so they can be moved with UpdateTokenURI. It reads up to
+// pageSize tokens starting at bookmark, so a page may hold fewer matches than pageSize before the
+// last one. Unlike QueryNFTs it needs no CouchDB, and the URIs are the stored ones, without the
+// base URI, so it fails while the collection hides its URIs.
+func (c *TokenERC721Contract) FindTokensByURIPrefix(ctx kalpsdk.TransactionContextInterface, prefix string, bookmark string, pageSize int) (*TokenPage, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	if prefix == "" {
+		return nil, fmt.Errorf("prefix must not be empty")
+	}
+	hidden, err := _isURIHidden(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if hidden {
+		return nil, fmt.Errorf("token URIs are hidden until the collection is revealed")
+	}
+
+	states, next, err := pagination.ByPartialCompositeKey(ctx, nftPrefix, []string{}, bookmark, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	page := &TokenPage{Tokens: []*Nft{}, Bookmark: next}
+	for _, state := range states {
+		nft := new(Nft)
+		err = json.Unmarshal(state.Value, nft)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode nft %s: %v", state.Key, err)
+		}
+		if strings.HasPrefix(nft.TokenURI, prefix) {
+			page.Tokens = append(page.Tokens, nft)
+		}
+	}
+	page.Total = len(page.Tokens)
+	return page, nil
+}
@@ -0,0 +1,50 @@
+package token
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/contractaccount"
+)
+
+// onERC721Received is the function invoked on receiving chaincodes. It must return its own name as
+// the acknowledgment that the chaincode can handle the token.
+const onERC721Received = "OnERC721Received"
+
+// SafeTransferFrom transfers like TransferFrom. If to is a contract account, the owning chaincode's
+// OnERC721Received(operator, from, tokenId, data) is invoked and the transfer reverts unless it
+// acknowledges the token.
+func (c *TokenERC721Contract) SafeTransferFrom(ctx kalpsdk.TransactionContextInterface, from string, to string, tokenId string, data string) (bool, error) {
+	_, err := c.TransferFrom(ctx, from, to, tokenId)
+	if err != nil {
+		return false, err
+	}
+
+	err = _checkOnERC721Received(ctx, from, to, tokenId, data)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func _checkOnERC721Received(ctx kalpsdk.TransactionContextInterface, from string, to string, tokenId string, data string) error {
+	receiver, ok := contractaccount.Owner(to)
+	if !ok {
+		return nil
+	}
+	operator, err := ctx.GetUserID()
+	if err != nil {
+		return fmt.Errorf("failed to GetClientIdentity: %v", err)
+	}
+
+	args := [][]byte{[]byte(onERC721Received), []byte(operator), []byte(from), []byte(tokenId), []byte(data)}
+	response := ctx.InvokeChaincode(receiver, args, "")
+	if response.Status != shim.OK {
+		return fmt.Errorf("receiver chaincode %s rejected token %s: %s", receiver, tokenId, response.Message)
+	}
+	if string(response.Payload) != onERC721Received {
+		return fmt.Errorf("receiver chaincode %s did not acknowledge token %s", receiver, tokenId)
+	}
+	return nil
+}
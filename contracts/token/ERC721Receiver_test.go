@@ -0,0 +1,45 @@
+package token
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/thekalpstudio/kush-go/contractaccount"
+	"github.com/thekalpstudio/kush-go/testutil"
+)
+
+func TestERC721SafeTransferFrom(t *testing.T) {
+	l, c := newERC721(t)
+	accepted := map[string]bool{"1": true}
+	l.RegisterChaincode("vault", func(args [][]byte) peer.Response {
+		// OnERC721Received(operator, from, tokenId, data)
+		if len(args) != 5 || string(args[0]) != onERC721Received || string(args[2]) != admin {
+			return shim.Error("unexpected call")
+		}
+		if !accepted[string(args[3])] {
+			return shim.Success([]byte("rejected"))
+		}
+		return shim.Success([]byte(onERC721Received))
+	})
+	testutil.MustRun(t, l, admin, "MintWithTokenURI", func(ctx *testutil.Context) error {
+		_, err := c.MintWithTokenURI(ctx, "2", "https://example.com/2.json")
+		return err
+	})
+	vault := contractaccount.ID("vault", "")
+	transfer := func(to string, tokenId string) error {
+		return testutil.Run(l, admin, "SafeTransferFrom", func(ctx *testutil.Context) error {
+			_, err := c.SafeTransferFrom(ctx, admin, to, tokenId, "memo")
+			return err
+		})
+	}
+	testutil.CheckErr(t, transfer(vault, "1"), false, "")
+	testutil.CheckErr(t, transfer(vault, "2"), true, "")
+	// user accounts are not asked
+	testutil.CheckErr(t, transfer("bob", "2"), false, "")
+	for tokenId, want := range map[string]string{"1": vault, "2": "bob"} {
+		if owner := erc721Owner(t, l, c, tokenId); owner != want {
+			t.Fatalf("owner of %s is %s, want %s", tokenId, owner, want)
+		}
+	}
+}
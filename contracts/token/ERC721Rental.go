@@ -0,0 +1,190 @@
+package token
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+	"github.com/thekalpstudio/kush-go/canonical"
+	"github.com/thekalpstudio/kush-go/events"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+const tokenUserPrefix = "user"
+
+// TokenUser is the ERC-4907 user of a token: an account allowed to use the token, without owning
+// it, until Expires (unix seconds).
+type TokenUser struct {
+	TokenId string `json:"tokenId"`
+	User    string `json:"user"`
+	Expires int64  `json:"expires"`
+}
+
+// SetUser assigns user to tokenId until expires. Only the owner or an approved operator can set
+// the user; an empty user removes it. The user is also cleared whenever the token changes hands.
+func (c *TokenERC721Contract) SetUser(ctx kalpsdk.TransactionContextInterface, tokenId string, user string, expires int64) (bool, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return false, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	sender, err := ctx.GetUserID()
+	if err != nil {
+		return false, fmt.Errorf("failed to GetClientIdentity: %v", err)
+	}
+	exists, err := _nftExists(ctx, tokenId)
+	if err != nil {
+		return false, err
+	}
+	if !exists {
+		return false, fmt.Errorf("token %s does not exist", tokenId)
+	}
+	nft, err := _readNFT(ctx, tokenId)
+	if err != nil {
+		return false, fmt.Errorf("failed to _readNFT: %v", err)
+	}
+
+	err = _checkNotPaused(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	err = _checkNotLocked(ctx, tokenId)
+	if err != nil {
+		return false, err
+	}
+
+	operatorApproval, err := c.IsApprovedForAll(ctx, nft.Owner, sender)
+	if err != nil {
+		return false, fmt.Errorf("failed to get IsApprovedForAll: %v", err)
+	}
+	if nft.Owner != sender && nft.Approved != sender && !operatorApproval {
+		return false, kusherrors.Errorf(kusherrors.ErrUnauthorized, "the sender is not the current owner nor an authorized operator")
+	}
+
+	if user == "" {
+		expires = 0
+	} else {
+		now, err := _txTimestampSeconds(ctx)
+		if err != nil {
+			return false, err
+		}
+		if expires <= now {
+			return false, fmt.Errorf("expiry %d must be in the future", expires)
+		}
+	}
+
+	err = _writeTokenUser(ctx, tokenId, user, expires)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// UserOf returns the current user of tokenId, or an empty string if there is none or it expired.
+func (c *TokenERC721Contract) UserOf(ctx kalpsdk.TransactionContextInterface, tokenId string) (string, error) {
+	tokenUser, err := _activeTokenUser(ctx, tokenId)
+	if err != nil {
+		return "", err
+	}
+	if tokenUser == nil {
+		return "", nil
+	}
+	return tokenUser.User, nil
+}
+
+// UserExpires returns when the current user of tokenId expires, or 0 if there is no active user.
+func (c *TokenERC721Contract) UserExpires(ctx kalpsdk.TransactionContextInterface, tokenId string) (int64, error) {
+	tokenUser, err := _activeTokenUser(ctx, tokenId)
+	if err != nil {
+		return 0, err
+	}
+	if tokenUser == nil {
+		return 0, nil
+	}
+	return tokenUser.Expires, nil
+}
+
+// _activeTokenUser returns the user record of tokenId unless it is missing or expired.
+func _activeTokenUser(ctx kalpsdk.TransactionContextInterface, tokenId string) (*TokenUser, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	userKey, err := ctx.CreateCompositeKey(tokenUserPrefix, []string{tokenId})
+	if err != nil {
+		return nil, fmt.Errorf("failed to CreateCompositeKey userKey: %v", err)
+	}
+	userBytes, err := ctx.GetState(userKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to GetState userKey %s: %v", userKey, err)
+	}
+	if userBytes == nil {
+		return nil, nil
+	}
+	tokenUser := new(TokenUser)
+	err = json.Unmarshal(userBytes, tokenUser)
+	if err != nil {
+		return nil, fmt.Errorf("failed to Unmarshal userBytes: %v", err)
+	}
+
+	now, err := _txTimestampSeconds(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if tokenUser.Expires <= now {
+		return nil, nil
+	}
+	return tokenUser, nil
+}
+
+// _writeTokenUser stores the user of tokenId, deleting the record when user is empty, and emits
+// UpdateUser.
+func _writeTokenUser(ctx kalpsdk.TransactionContextInterface, tokenId string, user string, expires int64) error {
+	userKey, err := ctx.CreateCompositeKey(tokenUserPrefix, []string{tokenId})
+	if err != nil {
+		return fmt.Errorf("failed to CreateCompositeKey userKey: %v", err)
+	}
+	tokenUser := &TokenUser{tokenId, user, expires}
+	userBytes, err := canonical.Marshal(tokenUser)
+	if err != nil {
+		return fmt.Errorf("failed to marshal tokenUser: %v", err)
+	}
+
+	if user == "" {
+		err = accesscontrol.DelState(ctx, userKey)
+	} else {
+		err = accesscontrol.PutState(ctx, userKey, userBytes)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write userKey %s: %v", userKey, err)
+	}
+
+	return events.Emit(ctx, "UpdateUser", &events.UpdateUser{TokenId: tokenId, User: user, Expires: expires})
+}
+
+// _clearTokenUser removes the user of tokenId without emitting an event, for use inside
+// operations that emit their own.
+func _clearTokenUser(ctx kalpsdk.TransactionContextInterface, tokenId string) error {
+	userKey, err := ctx.CreateCompositeKey(tokenUserPrefix, []string{tokenId})
+	if err != nil {
+		return fmt.Errorf("failed to CreateCompositeKey userKey: %v", err)
+	}
+	return accesscontrol.DelState(ctx, userKey)
+}
+
+func _txTimestampSeconds(ctx kalpsdk.TransactionContextInterface) (int64, error) {
+	timestamp, err := ctx.GetTxTimestamp()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	return timestamp.GetSeconds(), nil
+}
@@ -0,0 +1,166 @@
+package token
+
+import (
+	"fmt"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+	"github.com/thekalpstudio/kush-go/audit"
+	"github.com/thekalpstudio/kush-go/config"
+	"github.com/thekalpstudio/kush-go/events"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+const hiddenURIKey = "reveal~hiddenURI"
+const revealedKey = "reveal~revealed"
+
+// Revealed is emitted once when the real token URIs become visible.
+type Revealed = events.Revealed
+
+// SetHiddenURI makes TokenURI return uri for every token until Reveal is called. Token records
+// still hold their real URIs, which peers can read from the ledger; collections that need them to
+// stay secret should mint with placeholder URIs and publish the real ones with UpdateTokenURI.
+func (c *TokenERC721Contract) SetHiddenURI(ctx kalpsdk.TransactionContextInterface, uri string) (bool, error) {
+	err := _checkAdmin1(ctx, "set the hidden URI")
+	if err != nil {
+		return false, err
+	}
+	revealed, err := _isRevealed(ctx)
+	if err != nil {
+		return false, err
+	}
+	if revealed {
+		return false, fmt.Errorf("the collection is already revealed")
+	}
+	if uri == "" {
+		return false, fmt.Errorf("hidden URI must not be empty")
+	}
+
+	err = accesscontrol.PutState(ctx, hiddenURIKey, []byte(uri))
+	if err != nil {
+		return false, fmt.Errorf("failed to PutState hiddenURIKey %s: %v", hiddenURIKey, err)
+	}
+	err = audit.Record(ctx, "SetHiddenURI", uri)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Reveal permanently switches TokenURI from the hidden URI to the tokens' own URIs.
+func (c *TokenERC721Contract) Reveal(ctx kalpsdk.TransactionContextInterface) (bool, error) {
+	err := _checkAdmin1(ctx, "reveal the collection")
+	if err != nil {
+		return false, err
+	}
+	revealed, err := _isRevealed(ctx)
+	if err != nil {
+		return false, err
+	}
+	if revealed {
+		return false, fmt.Errorf("the collection is already revealed")
+	}
+	hiddenURI, err := _readHiddenURI(ctx)
+	if err != nil {
+		return false, err
+	}
+	if hiddenURI == "" {
+		return false, fmt.Errorf("no hidden URI is set, call SetHiddenURI() first")
+	}
+
+	err = accesscontrol.PutState(ctx, revealedKey, []byte("true"))
+	if err != nil {
+		return false, fmt.Errorf("failed to PutState revealedKey %s: %v", revealedKey, err)
+	}
+	err = audit.Record(ctx, "Reveal", hiddenURI)
+	if err != nil {
+		return false, err
+	}
+
+	sender, err := ctx.GetUserID()
+	if err != nil {
+		return false, fmt.Errorf("failed to GetClientIdentity: %v", err)
+	}
+	err = events.Emit(ctx, "Revealed", &Revealed{HiddenURI: hiddenURI, Sender: sender})
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// IsRevealed reports whether TokenURI returns the tokens' own URIs.
+func (c *TokenERC721Contract) IsRevealed(ctx kalpsdk.TransactionContextInterface) (bool, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return false, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	hidden, err := _isURIHidden(ctx)
+	if err != nil {
+		return false, err
+	}
+	return !hidden, nil
+}
+
+// _isURIHidden reports whether TokenURI answers the hidden URI, so the stored URIs must not be
+// disclosed.
+func _isURIHidden(ctx kalpsdk.TransactionContextInterface) (bool, error) {
+	hiddenURI, err := _readHiddenURI(ctx)
+	if err != nil {
+		return false, err
+	}
+	if hiddenURI == "" {
+		return false, nil
+	}
+	revealed, err := _isRevealed(ctx)
+	if err != nil {
+		return false, err
+	}
+	return !revealed, nil
+}
+
+// _visibleTokenURI returns the URI TokenURI should answer for nft.
+func _visibleTokenURI(ctx kalpsdk.TransactionContextInterface, nft *Nft) (string, error) {
+	hiddenURI, err := _readHiddenURI(ctx)
+	if err != nil {
+		return "", err
+	}
+	if hiddenURI == "" {
+		return _fullTokenURI(ctx, nft)
+	}
+	revealed, err := _isRevealed(ctx)
+	if err != nil {
+		return "", err
+	}
+	if revealed {
+		return _fullTokenURI(ctx, nft)
+	}
+	return hiddenURI, nil
+}
+
+// _fullTokenURI prepends the configured base URI to the URI of nft.
+func _fullTokenURI(ctx kalpsdk.TransactionContextInterface, nft *Nft) (string, error) {
+	baseURI, err := config.GetBaseURI(ctx)
+	if err != nil {
+		return "", err
+	}
+	return baseURI + nft.TokenURI, nil
+}
+
+func _readHiddenURI(ctx kalpsdk.TransactionContextInterface) (string, error) {
+	hiddenURIBytes, err := ctx.GetState(hiddenURIKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to GetState hiddenURIKey %s: %v", hiddenURIKey, err)
+	}
+	return string(hiddenURIBytes), nil
+}
+
+func _isRevealed(ctx kalpsdk.TransactionContextInterface) (bool, error) {
+	revealedBytes, err := ctx.GetState(revealedKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to GetState revealedKey %s: %v", revealedKey, err)
+	}
+	return revealedBytes != nil, nil
+}
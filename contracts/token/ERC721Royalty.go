@@ -0,0 +1,151 @@
+package token
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+	"github.com/thekalpstudio/kush-go/canonical"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+// Royalty records follow ERC-2981: the default record, stored under the bare prefix, applies to
+// every token without its own record.
+const royaltyPrefix = "royalty"
+
+// maxRoyaltyBps is the fee denominator; a royalty can never exceed the sale price.
+const maxRoyaltyBps = 10000
+
+// Royalty is the receiver and rate, in basis points of the sale price, of a royalty record.
+type Royalty struct {
+	Receiver string `json:"receiver"`
+	FeeBps   int    `json:"feeBps"`
+}
+
+// RoyaltyInfo is the royalty owed on a sale. RoyaltyAmount is a decimal string in the sale
+// currency's base units.
+type RoyaltyInfo struct {
+	Receiver      string `json:"receiver"`
+	RoyaltyAmount string `json:"royaltyAmount"`
+}
+
+// SetDefaultRoyalty sets the royalty applied to tokens without a token specific royalty.
+// A feeBps of 0 removes the default royalty.
+func (c *TokenERC721Contract) SetDefaultRoyalty(ctx kalpsdk.TransactionContextInterface, receiver string, feeBps int) (bool, error) {
+	err := _checkAdmin1(ctx, "set royalties")
+	if err != nil {
+		return false, err
+	}
+
+	err = _writeRoyalty(ctx, nil, receiver, feeBps)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// SetTokenRoyalty sets the royalty of tokenId, overriding the default royalty.
+// A feeBps of 0 removes the token royalty so the default applies again.
+func (c *TokenERC721Contract) SetTokenRoyalty(ctx kalpsdk.TransactionContextInterface, tokenId string, receiver string, feeBps int) (bool, error) {
+	err := _checkAdmin1(ctx, "set royalties")
+	if err != nil {
+		return false, err
+	}
+	exists, err := _nftExists(ctx, tokenId)
+	if err != nil {
+		return false, err
+	}
+	if !exists {
+		return false, fmt.Errorf("token %s does not exist", tokenId)
+	}
+
+	err = _writeRoyalty(ctx, []string{tokenId}, receiver, feeBps)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// RoyaltyInfo returns the receiver and amount of the royalty owed when tokenId is sold for
+// salePrice, a decimal string in base units. The receiver is empty if no royalty is set.
+func (c *TokenERC721Contract) RoyaltyInfo(ctx kalpsdk.TransactionContextInterface, tokenId string, salePrice string) (*RoyaltyInfo, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return nil, kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+
+	price, ok := new(big.Int).SetString(salePrice, 10)
+	if !ok || price.Sign() < 0 {
+		return nil, fmt.Errorf("sale price must be a non-negative integer")
+	}
+
+	royalty, err := _readRoyalty(ctx, []string{tokenId})
+	if err != nil {
+		return nil, err
+	}
+	if royalty == nil {
+		royalty, err = _readRoyalty(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if royalty == nil {
+		return &RoyaltyInfo{Receiver: "", RoyaltyAmount: "0"}, nil
+	}
+
+	amount := new(big.Int).Mul(price, big.NewInt(int64(royalty.FeeBps)))
+	amount.Quo(amount, big.NewInt(maxRoyaltyBps))
+	return &RoyaltyInfo{Receiver: royalty.Receiver, RoyaltyAmount: amount.String()}, nil
+}
+
+func _writeRoyalty(ctx kalpsdk.TransactionContextInterface, attrs []string, receiver string, feeBps int) error {
+	royaltyKey, err := ctx.CreateCompositeKey(royaltyPrefix, attrs)
+	if err != nil {
+		return fmt.Errorf("failed to CreateCompositeKey royaltyKey: %v", err)
+	}
+	if feeBps < 0 || feeBps > maxRoyaltyBps {
+		return fmt.Errorf("royalty must be between 0 and %d basis points", maxRoyaltyBps)
+	}
+	if feeBps == 0 {
+		return accesscontrol.DelState(ctx, royaltyKey)
+	}
+	if receiver == "" || receiver == "0x0" {
+		return fmt.Errorf("royalty receiver must be set")
+	}
+
+	royaltyJSON, err := canonical.Marshal(Royalty{receiver, feeBps})
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	err = accesscontrol.PutState(ctx, royaltyKey, royaltyJSON)
+	if err != nil {
+		return fmt.Errorf("failed to PutState royaltyKey %s: %v", royaltyKey, err)
+	}
+	return nil
+}
+
+// _readRoyalty returns the royalty stored under attrs, or nil if there is none.
+func _readRoyalty(ctx kalpsdk.TransactionContextInterface, attrs []string) (*Royalty, error) {
+	royaltyKey, err := ctx.CreateCompositeKey(royaltyPrefix, attrs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to CreateCompositeKey royaltyKey: %v", err)
+	}
+	royaltyBytes, err := ctx.GetState(royaltyKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to GetState royaltyKey %s: %v", royaltyKey, err)
+	}
+	if royaltyBytes == nil {
+		return nil, nil
+	}
+	royalty := new(Royalty)
+	err = json.Unmarshal(royaltyBytes, royalty)
+	if err != nil {
+		return nil, fmt.Errorf("failed to Unmarshal royaltyBytes: %v", err)
+	}
+	return royalty, nil
+}
@@ -0,0 +1,26 @@
+package token
+
+import (
+	"fmt"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+)
+
+// _countNFTKeys counts the tokens in existence by reading every nft key.
+func _countNFTKeys(ctx kalpsdk.TransactionContextInterface) (int, error) {
+	iterator, err := ctx.GetStateByPartialCompositeKey(nftPrefix, []string{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to GetStateByPartialCompositeKey %s: %v", nftPrefix, err)
+	}
+	defer iterator.Close()
+
+	supply := 0
+	for iterator.HasNext() {
+		_, err := iterator.Next()
+		if err != nil {
+			return 0, fmt.Errorf("failed to read nft keys: %v", err)
+		}
+		supply++
+	}
+	return supply, nil
+}
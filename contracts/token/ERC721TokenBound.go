@@ -0,0 +1,74 @@
+package token
+
+import (
+	"fmt"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+// tokenBoundAccountPrefix marks accounts owned by an NFT (ERC-6551 style), using the same
+// tba::<nft chaincode>::<token id> layout as the fungible token contracts. Token contracts let the
+// current owner of the NFT spend from the account, so its balances move with the NFT.
+const tokenBoundAccountPrefix = "tba::"
+
+// TokenBoundAccount returns the deterministic account ID bound to tokenId. ERC20 and ERC1155
+// balances sent to it are controlled by whoever owns the token.
+func (c *TokenERC721Contract) TokenBoundAccount(ctx kalpsdk.TransactionContextInterface, tokenId string) (string, error) {
+	initialized, err := checkInitialized1(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to check if contract is already initialized: %v", err)
+	}
+	if !initialized {
+		return "", kusherrors.Errorf(kusherrors.ErrNotInitialized, "Contract options need to be set before calling any function, call Initialize() to initialize contract")
+	}
+	exists, err := _nftExists(ctx, tokenId)
+	if err != nil {
+		return "", err
+	}
+	if !exists {
+		return "", fmt.Errorf("token %s does not exist", tokenId)
+	}
+
+	chaincodeName, err := _invokingChaincode(ctx)
+	if err != nil {
+		return "", err
+	}
+	return tokenBoundAccountPrefix + chaincodeName + "::" + tokenId, nil
+}
+
+// _invokingChaincode returns the name of the chaincode the transaction proposal was addressed to.
+func _invokingChaincode(ctx kalpsdk.TransactionContextInterface) (string, error) {
+	stubContext, ok := ctx.(interface {
+		GetStub() shim.ChaincodeStubInterface
+	})
+	if !ok || stubContext.GetStub() == nil {
+		return "", fmt.Errorf("transaction context does not expose the chaincode stub")
+	}
+	signedProposal, err := stubContext.GetStub().GetSignedProposal()
+	if err != nil || signedProposal == nil {
+		return "", fmt.Errorf("failed to get signed proposal: %v", err)
+	}
+	proposal := new(peer.Proposal)
+	err = proto.Unmarshal(signedProposal.ProposalBytes, proposal)
+	if err != nil {
+		return "", fmt.Errorf("failed to unmarshal proposal: %v", err)
+	}
+	payload := new(peer.ChaincodeProposalPayload)
+	err = proto.Unmarshal(proposal.Payload, payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to unmarshal proposal payload: %v", err)
+	}
+	invocationSpec := new(peer.ChaincodeInvocationSpec)
+	err = proto.Unmarshal(payload.Input, invocationSpec)
+	if err != nil {
+		return "", fmt.Errorf("failed to unmarshal chaincode invocation spec: %v", err)
+	}
+	if invocationSpec.ChaincodeSpec == nil || invocationSpec.ChaincodeSpec.ChaincodeId == nil {
+		return "", fmt.Errorf("proposal does not name a chaincode")
+	}
+	return invocationSpec.ChaincodeSpec.ChaincodeId.Name, nil
+}
@@ -0,0 +1,375 @@
+package token
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/thekalpstudio/kush-go/config"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+	"github.com/thekalpstudio/kush-go/testutil"
+)
+
+// newERC721 returns an initialized collection in which admin minted token "1".
+func newERC721(t *testing.T) (*testutil.Ledger, *TokenERC721Contract) {
+	t.Helper()
+	l := testutil.NewLedger()
+	c := new(TokenERC721Contract)
+	testutil.MustRun(t, l, admin, "Initialize", func(ctx *testutil.Context) error {
+		_, err := c.Initialize(ctx, testConfig())
+		return err
+	})
+	testutil.MustRun(t, l, admin, "MintWithTokenURI", func(ctx *testutil.Context) error {
+		_, err := c.MintWithTokenURI(ctx, "1", "https://example.com/1.json")
+		return err
+	})
+	return l, c
+}
+
+func erc721Owner(t *testing.T, l *testutil.Ledger, c *TokenERC721Contract, tokenId string) string {
+	t.Helper()
+	owner, err := c.OwnerOf(l.Tx(admin, "OwnerOf"), tokenId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return owner
+}
+
+func erc721Balance(t *testing.T, l *testutil.Ledger, c *TokenERC721Contract, owner string) int {
+	t.Helper()
+	balance, err := c.BalanceOf(l.Tx(admin, "BalanceOf"), owner)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return balance
+}
+
+func TestERC721Initialize(t *testing.T) {
+	tests := []struct {
+		name    string
+		init    func(cfg *config.InitConfig)
+		twice   bool
+		wantErr bool
+	}{
+		{name: "valid", init: func(cfg *config.InitConfig) {}},
+		{name: "with base URI", init: func(cfg *config.InitConfig) { cfg.BaseURI = "https://example.com/" }},
+		{name: "already initialized", init: func(cfg *config.InitConfig) {}, twice: true, wantErr: true},
+		{name: "decimals", init: func(cfg *config.InitConfig) { cfg.Decimals = 1 }, wantErr: true},
+		{name: "no name", init: func(cfg *config.InitConfig) { cfg.Name = "" }, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := testutil.NewLedger()
+			c := new(TokenERC721Contract)
+			cfg := testConfig()
+			tt.init(&cfg)
+			initialize := func(ctx *testutil.Context) error {
+				_, err := c.Initialize(ctx, cfg)
+				return err
+			}
+			if tt.twice {
+				testutil.MustRun(t, l, admin, "Initialize", initialize)
+			}
+			err := testutil.Run(l, admin, "Initialize", initialize)
+			testutil.CheckErr(t, err, tt.wantErr, "")
+			if tt.wantErr {
+				return
+			}
+
+			ctx := l.Tx(admin, "Name")
+			name, err := c.Name(ctx)
+			if err != nil || name != cfg.Name {
+				t.Fatalf("name is %q: %v", name, err)
+			}
+			supply, err := c.TotalSupply(ctx)
+			if err != nil || supply != 0 {
+				t.Fatalf("total supply is %d: %v", supply, err)
+			}
+		})
+	}
+}
+
+func TestERC721NotInitialized(t *testing.T) {
+	l := testutil.NewLedger()
+	c := new(TokenERC721Contract)
+	err := testutil.Run(l, admin, "MintWithTokenURI", func(ctx *testutil.Context) error {
+		_, err := c.MintWithTokenURI(ctx, "1", "https://example.com/1.json")
+		return err
+	})
+	testutil.CheckErr(t, err, true, kusherrors.ErrNotInitialized)
+}
+
+func TestERC721Mint(t *testing.T) {
+	tests := []struct {
+		name     string
+		user     string
+		tokenId  string
+		wantErr  bool
+		wantCode kusherrors.Code
+	}{
+		{name: "new token", user: admin, tokenId: "2"},
+		{name: "minted token", user: admin, tokenId: "1", wantErr: true},
+		{name: "without minter role", user: "bob", tokenId: "2", wantErr: true, wantCode: kusherrors.ErrUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, c := newERC721(t)
+			err := testutil.Run(l, tt.user, "MintWithTokenURI", func(ctx *testutil.Context) error {
+				nft, err := c.MintWithTokenURI(ctx, tt.tokenId, "https://example.com/"+tt.tokenId+".json")
+				if err == nil && (nft.Owner != tt.user || nft.TokenId != tt.tokenId) {
+					t.Fatalf("minted %+v", nft)
+				}
+				return err
+			})
+			testutil.CheckErr(t, err, tt.wantErr, tt.wantCode)
+			if tt.wantErr {
+				if supply, _ := c.TotalSupply(l.Tx(admin, "TotalSupply")); supply != 1 {
+					t.Fatalf("failed mint changed the supply to %d", supply)
+				}
+				return
+			}
+
+			if owner := erc721Owner(t, l, c, tt.tokenId); owner != tt.user {
+				t.Fatalf("owner is %s, want %s", owner, tt.user)
+			}
+			if balance := erc721Balance(t, l, c, tt.user); balance != 2 {
+				t.Fatalf("balance is %d, want 2", balance)
+			}
+			if supply, err := c.TotalSupply(l.Tx(admin, "TotalSupply")); err != nil || supply != 2 {
+				t.Fatalf("total supply is %d: %v", supply, err)
+			}
+			transfer := testutil.LastEvent(t, l, "Transfer").(*Transfer)
+			if transfer.From != "0x0" || transfer.To != tt.user || transfer.TokenId != tt.tokenId {
+				t.Fatalf("event is %+v", transfer)
+			}
+		})
+	}
+}
+
+func TestERC721TransferFrom(t *testing.T) {
+	tests := []struct {
+		name     string
+		user     string
+		setup    func(t *testing.T, l *testutil.Ledger, c *TokenERC721Contract)
+		from     string
+		tokenId  string
+		wantErr  bool
+		wantCode kusherrors.Code
+	}{
+		{name: "by the owner", user: admin, from: admin, tokenId: "1"},
+		{name: "by the approved account", user: "carol", from: admin, tokenId: "1", setup: func(t *testing.T, l *testutil.Ledger, c *TokenERC721Contract) {
+			testutil.MustRun(t, l, admin, "Approve", func(ctx *testutil.Context) error {
+				_, err := c.Approve(ctx, "carol", "1")
+				return err
+			})
+		}},
+		{name: "by an approved operator", user: "carol", from: admin, tokenId: "1", setup: func(t *testing.T, l *testutil.Ledger, c *TokenERC721Contract) {
+			testutil.MustRun(t, l, admin, "SetApprovalForAll", func(ctx *testutil.Context) error {
+				_, err := c.SetApprovalForAll(ctx, "carol", true)
+				return err
+			})
+		}},
+		{name: "by a revoked operator", user: "carol", from: admin, tokenId: "1", wantErr: true, wantCode: kusherrors.ErrUnauthorized, setup: func(t *testing.T, l *testutil.Ledger, c *TokenERC721Contract) {
+			for _, approved := range []bool{true, false} {
+				testutil.MustRun(t, l, admin, "SetApprovalForAll", func(ctx *testutil.Context) error {
+					_, err := c.SetApprovalForAll(ctx, "carol", approved)
+					return err
+				})
+			}
+		}},
+		{name: "by a stranger", user: "carol", from: admin, tokenId: "1", wantErr: true, wantCode: kusherrors.ErrUnauthorized},
+		{name: "from another account than the owner", user: admin, from: "carol", tokenId: "1", wantErr: true},
+		{name: "unminted token", user: admin, from: admin, tokenId: "2", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, c := newERC721(t)
+			if tt.setup != nil {
+				tt.setup(t, l, c)
+			}
+			err := testutil.Run(l, tt.user, "TransferFrom", func(ctx *testutil.Context) error {
+				_, err := c.TransferFrom(ctx, tt.from, "bob", tt.tokenId)
+				return err
+			})
+			testutil.CheckErr(t, err, tt.wantErr, tt.wantCode)
+			if tt.wantErr {
+				return
+			}
+
+			if owner := erc721Owner(t, l, c, "1"); owner != "bob" {
+				t.Fatalf("owner is %s, want bob", owner)
+			}
+			if balance := erc721Balance(t, l, c, admin); balance != 0 {
+				t.Fatalf("sender balance is %d, want 0", balance)
+			}
+			if balance := erc721Balance(t, l, c, "bob"); balance != 1 {
+				t.Fatalf("recipient balance is %d, want 1", balance)
+			}
+			approved, err := c.GetApproved(l.Tx(admin, "GetApproved"), "1")
+			if err != nil || approved != "" {
+				t.Fatalf("approval survived the transfer: %q, %v", approved, err)
+			}
+			transfer := testutil.LastEvent(t, l, "Transfer").(*Transfer)
+			if transfer.From != admin || transfer.To != "bob" || transfer.TokenId != "1" {
+				t.Fatalf("event is %+v", transfer)
+			}
+		})
+	}
+}
+
+func TestERC721Approve(t *testing.T) {
+	tests := []struct {
+		name     string
+		user     string
+		tokenId  string
+		wantErr  bool
+		wantCode kusherrors.Code
+	}{
+		{name: "by the owner", user: admin, tokenId: "1"},
+		{name: "by a stranger", user: "carol", tokenId: "1", wantErr: true, wantCode: kusherrors.ErrUnauthorized},
+		{name: "unminted token", user: admin, tokenId: "2", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, c := newERC721(t)
+			err := testutil.Run(l, tt.user, "Approve", func(ctx *testutil.Context) error {
+				_, err := c.Approve(ctx, "bob", tt.tokenId)
+				return err
+			})
+			testutil.CheckErr(t, err, tt.wantErr, tt.wantCode)
+			if tt.wantErr {
+				return
+			}
+
+			approved, err := c.GetApproved(l.Tx(admin, "GetApproved"), tt.tokenId)
+			if err != nil || approved != "bob" {
+				t.Fatalf("approved account is %q: %v", approved, err)
+			}
+			approval := testutil.LastEvent(t, l, "Approval").(*TokenApproval)
+			if approval.Owner != admin || approval.Approved != "bob" || approval.TokenId != tt.tokenId {
+				t.Fatalf("event is %+v", approval)
+			}
+		})
+	}
+}
+
+func TestERC721Burn(t *testing.T) {
+	tests := []struct {
+		name     string
+		user     string
+		tokenId  string
+		wantErr  bool
+		wantCode kusherrors.Code
+	}{
+		{name: "by the owner", user: admin, tokenId: "1"},
+		{name: "by a stranger", user: "bob", tokenId: "1", wantErr: true, wantCode: kusherrors.ErrUnauthorized},
+		{name: "unminted token", user: admin, tokenId: "2", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, c := newERC721(t)
+			err := testutil.Run(l, tt.user, "Burn", func(ctx *testutil.Context) error {
+				_, err := c.Burn(ctx, tt.tokenId)
+				return err
+			})
+			testutil.CheckErr(t, err, tt.wantErr, tt.wantCode)
+			if tt.wantErr {
+				if owner := erc721Owner(t, l, c, "1"); owner != admin {
+					t.Fatalf("failed burn changed the owner to %s", owner)
+				}
+				return
+			}
+
+			if _, err := c.OwnerOf(l.Tx(admin, "OwnerOf"), tt.tokenId); err == nil {
+				t.Fatal("burned token still has an owner")
+			}
+			if balance := erc721Balance(t, l, c, admin); balance != 0 {
+				t.Fatalf("balance is %d, want 0", balance)
+			}
+			if supply, err := c.TotalSupply(l.Tx(admin, "TotalSupply")); err != nil || supply != 0 {
+				t.Fatalf("total supply is %d: %v", supply, err)
+			}
+			transfer := testutil.LastEvent(t, l, "Transfer").(*Transfer)
+			if transfer.From != admin || transfer.To != "0x0" || transfer.TokenId != tt.tokenId {
+				t.Fatalf("event is %+v", transfer)
+			}
+		})
+	}
+}
+
+func TestERC721HiddenURI(t *testing.T) {
+	l, c := newERC721(t)
+	testutil.MustRun(t, l, admin, "SetHiddenURI", func(ctx *testutil.Context) error {
+		_, err := c.SetHiddenURI(ctx, "https://example.com/hidden.json")
+		return err
+	})
+
+	// the ledger of the tests has no CouchDB, so selectors that are let through fail to run
+	for selector, hidden := range map[string]bool{
+		`{"tokenURI":{"$regex":"1.json"}}`:                                                true,
+		`{"owner":"x","$or":[{"tokenURI":"https://example.com/1.json"},{"tokenId":"2"}]}`: true,
+		`{"owner":"x"}`: false,
+	} {
+		_, err := c.QueryNFTs(l.Tx(admin, "QueryNFTs"), selector, "", 10)
+		if err == nil || strings.Contains(err.Error(), "hidden") != hidden {
+			t.Fatalf("query %s failed with %v", selector, err)
+		}
+	}
+
+	ctx := l.Tx(admin, "GetTokensOfOwner")
+	tokens, err := c.GetTokensOfOwner(ctx, admin)
+	if err != nil || len(tokens) != 1 || tokens[0].TokenURI != "https://example.com/hidden.json" {
+		t.Fatalf("GetTokensOfOwner returned %v: %v", tokens, err)
+	}
+	page, err := c.TokensOfOwnerWithPagination(ctx, admin, "", 10)
+	if err != nil || len(page.Tokens) != 1 || page.Tokens[0].TokenURI != "https://example.com/hidden.json" {
+		t.Fatalf("TokensOfOwnerWithPagination returned %v: %v", page, err)
+	}
+	// the paginated TokensOfOwner of earlier releases is kept for the clients calling it
+	page, err = c.TokensOfOwner(ctx, admin, "", 10)
+	if err != nil || len(page.Tokens) != 1 || page.Tokens[0].TokenURI != "https://example.com/hidden.json" {
+		t.Fatalf("TokensOfOwner returned %v: %v", page, err)
+	}
+	_, err = c.FindTokensByURIPrefix(ctx, "https://example.com/", "", 10)
+	if err == nil || !strings.Contains(err.Error(), "hidden") {
+		t.Fatalf("FindTokensByURIPrefix failed with %v", err)
+	}
+}
+
+func TestERC721MintPhases(t *testing.T) {
+	l, c := newERC721(t)
+	setPhase := func(phase string) error {
+		return testutil.Run(l, admin, "SetMintPhase", func(ctx *testutil.Context) error {
+			_, err := c.SetMintPhase(ctx, phase)
+			return err
+		})
+	}
+	mint := func(user string, tokenId string) error {
+		return testutil.Run(l, user, "MintWithTokenURI", func(ctx *testutil.Context) error {
+			_, err := c.MintWithTokenURI(ctx, tokenId, "https://example.com/"+tokenId+".json")
+			return err
+		})
+	}
+	testutil.MustRun(t, l, admin, "AddToAllowlist", func(ctx *testutil.Context) error {
+		_, err := c.AddToAllowlist(ctx, []string{"bob"})
+		return err
+	})
+	testutil.CheckErr(t, mint("bob", "2"), true, kusherrors.ErrUnauthorized)
+
+	testutil.CheckErr(t, setPhase(MintPhaseAllowlist), false, "")
+	testutil.CheckErr(t, mint("bob", "2"), false, "")
+	// an allowlist entry is good for allowlistMintLimit mints
+	testutil.CheckErr(t, mint("bob", "3"), true, kusherrors.ErrUnauthorized)
+	testutil.CheckErr(t, mint("carol", "3"), true, "")
+
+	testutil.CheckErr(t, setPhase(MintPhaseClosed), false, "")
+	testutil.CheckErr(t, setPhase(MintPhaseClosed), true, "")
+	testutil.CheckErr(t, setPhase(MintPhasePublic), false, "")
+	testutil.CheckErr(t, mint("carol", "3"), false, "")
+
+	// the drop never moves back to the allowlist phase, closed or not
+	testutil.CheckErr(t, setPhase(MintPhaseAllowlist), true, "")
+	testutil.CheckErr(t, setPhase(MintPhaseClosed), false, "")
+	testutil.CheckErr(t, setPhase(MintPhaseAllowlist), true, "")
+	testutil.CheckErr(t, setPhase("presale"), true, "")
+	testutil.CheckErr(t, setPhase(MintPhasePublic), false, "")
+}
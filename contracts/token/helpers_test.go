@@ -0,0 +1,12 @@
+package token
+
+import (
+	"github.com/thekalpstudio/kush-go/config"
+	"github.com/thekalpstudio/kush-go/testutil"
+)
+
+const admin = "admin"
+
+func testConfig() config.InitConfig {
+	return config.InitConfig{Name: "Test", Symbol: "TST", AuthorizedOrgs: []string{testutil.MSPID}}
+}
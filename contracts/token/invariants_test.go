@@ -0,0 +1,160 @@
+package token
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"testing"
+
+	"github.com/thekalpstudio/kush-go/events"
+	"github.com/thekalpstudio/kush-go/testutil"
+)
+
+// invariantSeeds seed the random operation sequences; a failure names its seed and step so it can
+// be replayed.
+var invariantSeeds = []int64{1, 2, 3, 4, 5, 6, 7, 8}
+
+const invariantSteps = 150
+
+var invariantUsers = []string{admin, "alice", "bob", "carol"}
+
+func TestERC721Invariants(t *testing.T) {
+	for _, seed := range invariantSeeds {
+		t.Run("seed="+strconv.FormatInt(seed, 10), func(t *testing.T) {
+			r := rand.New(rand.NewSource(seed))
+			l, c := newERC721(t)
+			user := func() string { return invariantUsers[r.Intn(len(invariantUsers))] }
+			// a small pool of ids, so that tokens are minted again after they are burned
+			tokenId := func() string { return strconv.Itoa(1 + r.Intn(8)) }
+
+			for step := 0; step < invariantSteps; step++ {
+				// the caller is mostly the owner of the token, so that the sequences move tokens rather
+				// than fail on the first check
+				caller := user()
+				id := tokenId()
+				if owner, err := c.OwnerOf(l.Tx(admin, "OwnerOf"), id); err == nil && r.Intn(4) != 0 {
+					caller = owner
+				}
+				var op string
+				var fn func(ctx *testutil.Context) error
+				switch r.Intn(6) {
+				case 0:
+					op = "MintWithTokenURI"
+					caller = admin
+					fn = func(ctx *testutil.Context) error {
+						_, err := c.MintWithTokenURI(ctx, id, "https://example.com/"+id+".json")
+						return err
+					}
+				case 1, 2:
+					op = "TransferFrom"
+					fn = func(ctx *testutil.Context) error {
+						from := caller
+						if r.Intn(3) == 0 {
+							from = user()
+						}
+						_, err := c.TransferFrom(ctx, from, user(), id)
+						return err
+					}
+				case 3:
+					op = "Approve"
+					fn = func(ctx *testutil.Context) error {
+						_, err := c.Approve(ctx, user(), id)
+						return err
+					}
+				case 4:
+					op = "SetApprovalForAll"
+					fn = func(ctx *testutil.Context) error {
+						_, err := c.SetApprovalForAll(ctx, user(), r.Intn(2) == 0)
+						return err
+					}
+				case 5:
+					op = "Burn"
+					fn = func(ctx *testutil.Context) error {
+						_, err := c.Burn(ctx, id)
+						return err
+					}
+				}
+				_ = testutil.Run(l, caller, op, fn)
+
+				checkERC721Invariants(t, l, c, fmt.Sprintf("step %d (%s)", step, op))
+			}
+		})
+	}
+}
+
+func checkERC721Invariants(t *testing.T, l *testutil.Ledger, c *TokenERC721Contract, at string) {
+	t.Helper()
+	ctx := l.Tx(admin, "OwnerOf")
+
+	owners := map[string]string{}
+	balanceKeys := map[string][]string{}
+	for _, key := range l.Keys() {
+		objectType, attributes, err := ctx.SplitCompositeKey(key)
+		if err != nil {
+			continue
+		}
+		switch objectType {
+		case nftPrefix:
+			var nft Nft
+			if err := json.Unmarshal(l.State(key), &nft); err != nil {
+				t.Fatalf("%s: %v", at, err)
+			}
+			owners[attributes[0]] = nft.Owner
+		case balancePrefix:
+			balanceKeys[attributes[1]] = append(balanceKeys[attributes[1]], attributes[0])
+		}
+	}
+
+	// every token has exactly one balance record, which names its owner, and no record outlives
+	// its token
+	counts := map[string]int{}
+	for id, owner := range owners {
+		if len(balanceKeys[id]) != 1 || balanceKeys[id][0] != owner {
+			t.Fatalf("%s: token %s of %s has the balance records of %v", at, id, owner, balanceKeys[id])
+		}
+		counts[owner]++
+	}
+	for id, holders := range balanceKeys {
+		if _, ok := owners[id]; !ok {
+			t.Fatalf("%s: burned token %s has the balance records of %v", at, id, holders)
+		}
+	}
+
+	supply, err := c.TotalSupply(ctx)
+	if err != nil || supply != len(owners) {
+		t.Fatalf("%s: total supply is %d, %d tokens exist: %v", at, supply, len(owners), err)
+	}
+	for _, user := range invariantUsers {
+		balance, err := c.BalanceOf(ctx, user)
+		if err != nil || balance != counts[user] {
+			t.Fatalf("%s: balance of %s is %d, it owns %d tokens: %v", at, user, balance, counts[user], err)
+		}
+	}
+
+	// replaying the Transfer events yields the owners in state
+	replayed := map[string]string{}
+	for _, event := range l.Events() {
+		if event.Name != "Transfer" {
+			continue
+		}
+		_, value, err := events.Decode(event.Name, event.Payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		transfer := value.(*Transfer)
+		if transfer.To == "0x0" {
+			delete(replayed, transfer.TokenId)
+		} else {
+			replayed[transfer.TokenId] = transfer.To
+		}
+	}
+	if len(replayed) != len(owners) {
+		t.Fatalf("%s: Transfer events leave %d tokens, %d exist", at, len(replayed), len(owners))
+	}
+	for id, owner := range owners {
+		if replayed[id] != owner {
+			t.Fatalf("%s: token %s is owned by %s, its Transfer events give it to %s", at, id, owner, replayed[id])
+		}
+	}
+}
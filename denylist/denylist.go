@@ -0,0 +1,277 @@
+// Package denylist implements a sanctions list shared by the token contracts.
+//
+// DenyListContract is deployed as its own chaincode. Token contracts that are pointed at it with
+// SetDenyList ask it, through a cross-chaincode query, whether the accounts of a transfer are
+// denied before moving assets, so adding an account once blocks it on every token of the channel.
+package denylist
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+	"github.com/thekalpstudio/kush-go/audit"
+	"github.com/thekalpstudio/kush-go/canonical"
+	"github.com/thekalpstudio/kush-go/events"
+	"github.com/thekalpstudio/kush-go/internal/txstate"
+	"github.com/thekalpstudio/kush-go/logging"
+)
+
+const deniedPrefix = "denylist~account"
+const denyListKey = "denylist~chaincode"
+
+// ListerRole may add accounts to and remove accounts from the deny list.
+const ListerRole = "LISTER_ROLE"
+
+// DenyListContract holds the denied accounts.
+type DenyListContract struct {
+	logging.Contract
+}
+
+// DeniedAccount is a deny list entry.
+type DeniedAccount struct {
+	Account string `json:"account"`
+	Reason  string `json:"reason"`
+	Lister  string `json:"lister"`
+}
+
+// Initialize bootstraps the roles of the deny list for the caller, who must belong to one of
+// authorizedMSPs. The caller may grant ListerRole to compliance operators.
+func (d *DenyListContract) Initialize(ctx kalpsdk.TransactionContextInterface, authorizedMSPs []string) error {
+	return accesscontrol.Initialize(ctx, authorizedMSPs)
+}
+
+// Deny adds account to the deny list and emits AccountDenied. reason is recorded with the entry.
+func (d *DenyListContract) Deny(ctx kalpsdk.TransactionContextInterface, account string, reason string) error {
+	lister, err := checkLister(ctx)
+	if err != nil {
+		return err
+	}
+	if account == "" {
+		return fmt.Errorf("account must not be empty")
+	}
+	entry, err := readDeniedAccount(ctx, account)
+	if err != nil {
+		return err
+	}
+	if entry != nil {
+		return fmt.Errorf("account %s is already denied", account)
+	}
+
+	entry = &DeniedAccount{account, reason, lister}
+	deniedKey, err := ctx.CreateCompositeKey(deniedPrefix, []string{account})
+	if err != nil {
+		return fmt.Errorf("failed to create the composite key for prefix %s: %v", deniedPrefix, err)
+	}
+	entryJSON, err := canonical.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	err = ctx.PutStateWithoutKYC(deniedKey, entryJSON)
+	if err != nil {
+		return fmt.Errorf("failed to deny account %s: %v", account, err)
+	}
+	err = audit.Record(ctx, "AccountDenied", account, reason)
+	if err != nil {
+		return err
+	}
+	return emitDenyListEvent(ctx, "AccountDenied", entry)
+}
+
+// Allow removes account from the deny list and emits AccountAllowed.
+func (d *DenyListContract) Allow(ctx kalpsdk.TransactionContextInterface, account string) error {
+	lister, err := checkLister(ctx)
+	if err != nil {
+		return err
+	}
+	entry, err := readDeniedAccount(ctx, account)
+	if err != nil {
+		return err
+	}
+	if entry == nil {
+		return fmt.Errorf("account %s is not denied", account)
+	}
+
+	deniedKey, err := ctx.CreateCompositeKey(deniedPrefix, []string{account})
+	if err != nil {
+		return fmt.Errorf("failed to create the composite key for prefix %s: %v", deniedPrefix, err)
+	}
+	err = ctx.DelStateWithoutKYC(deniedKey)
+	if err != nil {
+		return fmt.Errorf("failed to allow account %s: %v", account, err)
+	}
+	err = audit.Record(ctx, "AccountAllowed", account)
+	if err != nil {
+		return err
+	}
+	return emitDenyListEvent(ctx, "AccountAllowed", &DeniedAccount{Account: account, Lister: lister})
+}
+
+func (d *DenyListContract) IsDenied(ctx kalpsdk.TransactionContextInterface, account string) (bool, error) {
+	entry, err := readDeniedAccount(ctx, account)
+	if err != nil {
+		return false, err
+	}
+	return entry != nil, nil
+}
+
+// GetDeniedAccount returns the deny list entry of account, or nil if it is not denied.
+func (d *DenyListContract) GetDeniedAccount(ctx kalpsdk.TransactionContextInterface, account string) (*DeniedAccount, error) {
+	return readDeniedAccount(ctx, account)
+}
+
+// GrantRole gives account role, typically the LISTER_ROLE.
+func (d *DenyListContract) GrantRole(ctx kalpsdk.TransactionContextInterface, role string, account string) error {
+	return accesscontrol.GrantRole(ctx, role, account)
+}
+
+// RevokeRole removes role from account.
+func (d *DenyListContract) RevokeRole(ctx kalpsdk.TransactionContextInterface, role string, account string) error {
+	return accesscontrol.RevokeRole(ctx, role, account)
+}
+
+func (d *DenyListContract) HasRole(ctx kalpsdk.TransactionContextInterface, role string, account string) (bool, error) {
+	return accesscontrol.HasRole(ctx, role, account)
+}
+
+// GetAuditLog returns a page of the changes made to the deny list and its roles.
+func (d *DenyListContract) GetAuditLog(ctx kalpsdk.TransactionContextInterface, bookmark string, pageSize int) (*audit.Page, error) {
+	return audit.GetAuditLog(ctx, bookmark, pageSize)
+}
+
+// SetLogLevel changes the level at which the peer evaluating it logs the transactions of the deny
+// list, until the chaincode restarts.
+func (d *DenyListContract) SetLogLevel(ctx kalpsdk.TransactionContextInterface, level string) error {
+	return logging.SetLogLevel(ctx, level)
+}
+
+func (d *DenyListContract) GetLogLevel(ctx kalpsdk.TransactionContextInterface) (string, error) {
+	return logging.GetLogLevel(ctx)
+}
+
+// SetDenyList points the calling token contract at the deny list chaincode chaincodeName. An
+// empty name detaches it. The caller must hold the ADMIN_ROLE of the token contract; while
+// multisig approval or the timelock is enabled the change has to be proposed or scheduled as
+// accesscontrol.ActionSetDenyList instead.
+func SetDenyList(ctx kalpsdk.TransactionContextInterface, chaincodeName string) error {
+	err := accesscontrol.CheckRole(ctx, accesscontrol.AdminRole)
+	if err != nil {
+		return err
+	}
+	err = accesscontrol.CheckNotMultisig(ctx, accesscontrol.ActionSetDenyList)
+	if err != nil {
+		return err
+	}
+	err = accesscontrol.CheckNotTimelocked(ctx, accesscontrol.ActionSetDenyList)
+	if err != nil {
+		return err
+	}
+	return WriteDenyList(ctx, chaincodeName)
+}
+
+// WriteDenyList stores the deny list chaincode of the calling token contract, leaving
+// authorization to the caller. Contracts call it to execute an approved ActionSetDenyList.
+func WriteDenyList(ctx kalpsdk.TransactionContextInterface, chaincodeName string) error {
+	var err error
+	if chaincodeName == "" {
+		err = ctx.DelStateWithoutKYC(denyListKey)
+	} else {
+		err = ctx.PutStateWithoutKYC(denyListKey, []byte(chaincodeName))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set deny list: %v", err)
+	}
+	return audit.Record(ctx, "SetDenyList", chaincodeName)
+}
+
+// GetDenyList returns the deny list chaincode of the calling token contract, or "" if none is set.
+func GetDenyList(ctx kalpsdk.TransactionContextInterface) (string, error) {
+	denyListBytes, err := ctx.GetState(denyListKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to read deny list: %v", err)
+	}
+	return string(denyListBytes), nil
+}
+
+// answersKey keys the deny list answers of a transaction in its transaction state, since a batch
+// transfer or airdrop may check the same account many times.
+type answersKey struct{}
+
+// CheckNotDenied fails if any of accounts is on the deny list of the calling token contract.
+// Contracts without a deny list deny nobody.
+func CheckNotDenied(ctx kalpsdk.TransactionContextInterface, accounts ...string) error {
+	denyList, err := GetDenyList(ctx)
+	if err != nil {
+		return err
+	}
+	if denyList == "" {
+		return nil
+	}
+	for _, account := range accounts {
+		denied, err := isDenied(ctx, denyList, account)
+		if err != nil {
+			return err
+		}
+		if denied {
+			return fmt.Errorf("account %s is on deny list %s", account, denyList)
+		}
+	}
+	return nil
+}
+
+func isDenied(ctx kalpsdk.TransactionContextInterface, denyList string, account string) (bool, error) {
+	cached, err := txstate.Get(ctx, answersKey{}, func() interface{} { return make(map[string]bool) })
+	if err != nil {
+		return false, err
+	}
+	answers := cached.(map[string]bool)
+	if denied, ok := answers[account]; ok {
+		return denied, nil
+	}
+
+	response := ctx.InvokeChaincode(denyList, [][]byte{[]byte("IsDenied"), []byte(account)}, "")
+	if response.Status != shim.OK {
+		return false, fmt.Errorf("failed to invoke IsDenied on chaincode %s: %s", denyList, response.Message)
+	}
+	denied := string(response.Payload) == "true"
+	answers[account] = denied
+	return denied, nil
+}
+
+func checkLister(ctx kalpsdk.TransactionContextInterface) (string, error) {
+	err := accesscontrol.CheckRole(ctx, ListerRole)
+	if err != nil {
+		return "", fmt.Errorf("client is not authorized to change the deny list: %v", err)
+	}
+	lister, err := ctx.GetUserID()
+	if err != nil {
+		return "", fmt.Errorf("failed to get client id: %v", err)
+	}
+	return lister, nil
+}
+
+func readDeniedAccount(ctx kalpsdk.TransactionContextInterface, account string) (*DeniedAccount, error) {
+	deniedKey, err := ctx.CreateCompositeKey(deniedPrefix, []string{account})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the composite key for prefix %s: %v", deniedPrefix, err)
+	}
+	entryBytes, err := ctx.GetState(deniedKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read deny list entry of %s: %v", account, err)
+	}
+	if entryBytes == nil {
+		return nil, nil
+	}
+	entry := new(DeniedAccount)
+	err = json.Unmarshal(entryBytes, entry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode deny list entry of %s: %v", account, err)
+	}
+	return entry, nil
+}
+
+func emitDenyListEvent(ctx kalpsdk.TransactionContextInterface, eventName string, entry *DeniedAccount) error {
+	return events.Emit(ctx, eventName, &events.DenyListEvent{Account: entry.Account, Reason: entry.Reason, Lister: entry.Lister})
+}
@@ -0,0 +1,71 @@
+// Package events defines the token events, as emitted by the contracts and decoded by listeners.
+//
+// Every event is emitted as an Envelope naming the schema of its data, so listeners keep decoding
+// old payloads with Decode while the structs evolve. Events are stamped with the transaction that
+// emitted them: chaincode runs before its transaction is ordered into a block, so the block number
+// is not known when an event is built, but the channel, tx ID and tx timestamp are, and they let
+// listeners order events and drop the duplicates a reconnecting listener receives.
+//
+// Fabric keeps a single event per transaction, the last one the chaincode set. A transaction made
+// of several steps therefore emits one event describing all of them: BatchTransfer and Airdrop list
+// their legs, Transfer carries the fee charged, and an executed timelock operation replaces the
+// event of its action.
+//
+// Off-chain, Listen subscribes to the events of a contract through the gateway service of a peer
+// and delivers them decoded, checkpointing the events the consumer acknowledges so it can resume
+// or replay from a block.
+package events
+
+import (
+	"fmt"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/canonical"
+)
+
+// TxContext identifies the transaction of an event. Events embed it, so its fields appear next to
+// the fields of the event; payloads nested in an event leave it empty, which omits the fields.
+type TxContext struct {
+	ChannelID string `json:"channelId,omitempty"`
+	TxID      string `json:"txId,omitempty"`
+	Timestamp int64  `json:"timestamp,omitempty"`
+}
+
+// Stamp fills t from the current transaction.
+func (t *TxContext) Stamp(ctx kalpsdk.TransactionContextInterface) error {
+	timestamp, err := ctx.GetTxTimestamp()
+	if err != nil {
+		return fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	t.ChannelID = ctx.GetChannelID()
+	t.TxID = ctx.GetTxID()
+	t.Timestamp = timestamp.GetSeconds()
+	return nil
+}
+
+// Payload is implemented by the event structs of this package.
+type Payload interface {
+	Stamp(ctx kalpsdk.TransactionContextInterface) error
+	Schema() string
+}
+
+// Emit stamps payload and sets it, wrapped in an Envelope, as the event name of the transaction.
+func Emit(ctx kalpsdk.TransactionContextInterface, name string, payload Payload) error {
+	err := payload.Stamp(ctx)
+	if err != nil {
+		return err
+	}
+	data, err := canonical.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	envelopeJSON, err := canonical.Marshal(Envelope{payload.Schema(), data})
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	err = ctx.SetEvent(name, envelopeJSON)
+	if err != nil {
+		return fmt.Errorf("failed to set event: %v", err)
+	}
+	return nil
+}
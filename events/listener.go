@@ -0,0 +1,251 @@
+package events
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/gateway"
+	"github.com/hyperledger/fabric-protos-go/msp"
+	"github.com/hyperledger/fabric-protos-go/orderer"
+	"github.com/thekalpstudio/kush-go/canonical"
+	"google.golang.org/grpc"
+)
+
+// Event is a chaincode event received by a Listener. Value is the decoded payload, a pointer to
+// one of the event structs of this package; when the payload cannot be decoded, Value is nil and
+// Err tells why, so events of other contracts on the chaincode do not stop the listener.
+type Event struct {
+	BlockNumber uint64
+	TxID        string
+	Name        string
+	Schema      string
+	Value       interface{}
+	Payload     []byte
+	Err         error
+
+	// checkpoint is the position of the listener once the event is acknowledged.
+	checkpoint Checkpoint
+}
+
+// Checkpoint is the position of a listener in the ledger: the events of block BlockNumber up to
+// and including those of TxID were delivered. An empty TxID means no event of the block was.
+type Checkpoint struct {
+	BlockNumber uint64 `json:"blockNumber"`
+	TxID        string `json:"txId,omitempty"`
+}
+
+// Checkpointer persists the checkpoint of a listener, so a restarted listener resumes after the
+// last acknowledged event instead of replaying or missing events.
+type Checkpointer interface {
+	// Load returns the saved checkpoint, or false if there is none.
+	Load() (Checkpoint, bool, error)
+	Save(checkpoint Checkpoint) error
+}
+
+// MemoryCheckpointer keeps the checkpoint in memory. It resumes a listener reconnecting within
+// the process.
+type MemoryCheckpointer struct {
+	mu         sync.Mutex
+	checkpoint *Checkpoint
+}
+
+func (m *MemoryCheckpointer) Load() (Checkpoint, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.checkpoint == nil {
+		return Checkpoint{}, false, nil
+	}
+	return *m.checkpoint, true, nil
+}
+
+func (m *MemoryCheckpointer) Save(checkpoint Checkpoint) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.checkpoint = &checkpoint
+	return nil
+}
+
+// FileCheckpointer keeps the checkpoint as JSON in the file Path.
+type FileCheckpointer struct {
+	Path string
+}
+
+func (f *FileCheckpointer) Load() (Checkpoint, bool, error) {
+	checkpointBytes, err := os.ReadFile(f.Path)
+	if os.IsNotExist(err) {
+		return Checkpoint{}, false, nil
+	}
+	if err != nil {
+		return Checkpoint{}, false, fmt.Errorf("failed to read checkpoint: %v", err)
+	}
+	var checkpoint Checkpoint
+	err = json.Unmarshal(checkpointBytes, &checkpoint)
+	if err != nil {
+		return Checkpoint{}, false, fmt.Errorf("failed to decode checkpoint %s: %v", f.Path, err)
+	}
+	return checkpoint, true, nil
+}
+
+// Save writes the checkpoint to a temporary file first, so a crash never leaves a truncated one.
+func (f *FileCheckpointer) Save(checkpoint Checkpoint) error {
+	checkpointJSON, err := canonical.Marshal(checkpoint)
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	err = os.WriteFile(f.Path+".tmp", checkpointJSON, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to write checkpoint: %v", err)
+	}
+	err = os.Rename(f.Path+".tmp", f.Path)
+	if err != nil {
+		return fmt.Errorf("failed to write checkpoint: %v", err)
+	}
+	return nil
+}
+
+// ListenerConfig configures a Listener.
+type ListenerConfig struct {
+	// Conn is a connection to the gateway service of a Kalp peer.
+	Conn *grpc.ClientConn
+	// ChannelID and ChaincodeID select the contract whose events are delivered.
+	ChannelID   string
+	ChaincodeID string
+	// Identity is the serialized identity of the client, see Identity.
+	Identity []byte
+	// Sign signs the SHA-256 digest of a request with the private key of Identity.
+	Sign func(digest []byte) ([]byte, error)
+	// Checkpointer, if set, resumes the listener from its saved checkpoint, and is updated as the
+	// events are acknowledged with Ack.
+	Checkpointer Checkpointer
+	// StartBlock is the block from which events are replayed when there is no saved checkpoint,
+	// zero for the genesis block.
+	StartBlock uint64
+	// FromNewest starts with the next committed block instead of StartBlock when there is no
+	// saved checkpoint.
+	FromNewest bool
+	// BufferSize is the capacity of the events channel.
+	BufferSize int
+}
+
+// Identity serializes the identity of a client of org mspID holding the PEM encoded certificate
+// certPEM.
+func Identity(mspID string, certPEM []byte) ([]byte, error) {
+	identity, err := proto.Marshal(&msp.SerializedIdentity{Mspid: mspID, IdBytes: certPEM})
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize identity: %v", err)
+	}
+	return identity, nil
+}
+
+// Listener delivers the decoded chaincode events of a contract, in ledger order.
+type Listener struct {
+	events       chan Event
+	checkpointer Checkpointer
+	err          error
+}
+
+// Listen subscribes to the chaincode events of cfg.ChaincodeID. The listener stops when ctx is
+// done or the stream fails; Events is then closed and Err returns the failure. Listening again
+// with the same Checkpointer resumes after the last acknowledged event, so events delivered but
+// not acknowledged before a crash are delivered again.
+func Listen(ctx context.Context, cfg ListenerConfig) (*Listener, error) {
+	request := &gateway.ChaincodeEventsRequest{
+		ChannelId:     cfg.ChannelID,
+		ChaincodeId:   cfg.ChaincodeID,
+		Identity:      cfg.Identity,
+		StartPosition: startPosition(cfg.StartBlock),
+	}
+	if cfg.FromNewest {
+		request.StartPosition = &orderer.SeekPosition{Type: &orderer.SeekPosition_NextCommit{NextCommit: &orderer.SeekNextCommit{}}}
+	}
+	if cfg.Checkpointer != nil {
+		checkpoint, ok, err := cfg.Checkpointer.Load()
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			request.StartPosition = startPosition(checkpoint.BlockNumber)
+			request.AfterTransactionId = checkpoint.TxID
+		}
+	}
+
+	requestBytes, err := proto.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize events request: %v", err)
+	}
+	digest := sha256.Sum256(requestBytes)
+	signature, err := cfg.Sign(digest[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign events request: %v", err)
+	}
+	stream, err := gateway.NewGatewayClient(cfg.Conn).ChaincodeEvents(ctx, &gateway.SignedChaincodeEventsRequest{
+		Request:   requestBytes,
+		Signature: signature,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe to events of %s: %v", cfg.ChaincodeID, err)
+	}
+
+	listener := &Listener{events: make(chan Event, cfg.BufferSize), checkpointer: cfg.Checkpointer}
+	go listener.run(ctx, stream)
+	return listener, nil
+}
+
+// Events returns the channel the events are delivered on.
+func (l *Listener) Events() <-chan Event {
+	return l.events
+}
+
+// Err returns why the listener stopped, once Events is closed. It is nil if ctx was done.
+func (l *Listener) Err() error {
+	return l.err
+}
+
+// Ack saves the checkpoint after event once the consumer has processed it. Events have to be
+// acknowledged in the order they are delivered.
+func (l *Listener) Ack(event Event) error {
+	if l.checkpointer == nil {
+		return nil
+	}
+	return l.checkpointer.Save(event.checkpoint)
+}
+
+func (l *Listener) run(ctx context.Context, stream gateway.Gateway_ChaincodeEventsClient) {
+	defer close(l.events)
+	for {
+		response, err := stream.Recv()
+		if err != nil {
+			if ctx.Err() == nil {
+				l.err = fmt.Errorf("failed to receive events: %v", err)
+			}
+			return
+		}
+		for i, chaincodeEvent := range response.Events {
+			event := Event{
+				BlockNumber: response.BlockNumber,
+				TxID:        chaincodeEvent.TxId,
+				Name:        chaincodeEvent.EventName,
+				Payload:     chaincodeEvent.Payload,
+				checkpoint:  Checkpoint{response.BlockNumber, chaincodeEvent.TxId},
+			}
+			if i == len(response.Events)-1 {
+				event.checkpoint = Checkpoint{BlockNumber: response.BlockNumber + 1}
+			}
+			event.Schema, event.Value, event.Err = Decode(chaincodeEvent.EventName, chaincodeEvent.Payload)
+			select {
+			case l.events <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
+}
+
+func startPosition(blockNumber uint64) *orderer.SeekPosition {
+	return &orderer.SeekPosition{Type: &orderer.SeekPosition_Specified{Specified: &orderer.SeekSpecified{Number: blockNumber}}}
+}
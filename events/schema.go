@@ -0,0 +1,712 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+)
+
+// Event schemas. A schema names the shape of Envelope.Data and changes version whenever the shape
+// does. The V1 constants name the bare payloads emitted before events had an envelope; events added
+// since start at their own v1.
+const (
+	SchemaTransfer       = "kush.transfer.v2"
+	SchemaApproval       = "kush.approval.v2"
+	SchemaBatchTransfer  = "kush.batchtransfer.v2"
+	SchemaAirdrop        = "kush.airdrop.v2"
+	SchemaTransferSingle = "kush.transfersingle.v2"
+	SchemaTransferBatch  = "kush.transferbatch.v2"
+	SchemaApprovalForAll = "kush.approvalforall.v2"
+	SchemaNFTTransfer    = "kush.nft.transfer.v2"
+	SchemaNFTApproval    = "kush.nft.approval.v1"
+	SchemaURI            = "kush.uri.v2"
+
+	SchemaVestingReleased     = "kush.vestingreleased.v2"
+	SchemaStream              = "kush.stream.v2"
+	SchemaPause               = "kush.pause.v2"
+	SchemaFreeze              = "kush.freeze.v2"
+	SchemaTokenFreeze         = "kush.tokenfreeze.v2"
+	SchemaSnapshot            = "kush.snapshot.v2"
+	SchemaEmissionExecuted    = "kush.emissionexecuted.v2"
+	SchemaVaultDeposit        = "kush.vault.deposit.v2"
+	SchemaVaultWithdraw       = "kush.vault.withdraw.v2"
+	SchemaWalletExecuted      = "kush.walletexecuted.v2"
+	SchemaPromoted            = "kush.promoted.v2"
+	SchemaReferralRecorded    = "kush.referralrecorded.v2"
+	SchemaCommissionWithdrawn = "kush.commissionwithdrawn.v2"
+
+	SchemaAuthorizedOrg            = "kush.authorizedorg.v2"
+	SchemaRole                     = "kush.role.v2"
+	SchemaRoleAdminChanged         = "kush.roleadminchanged.v2"
+	SchemaRolesBootstrapped        = "kush.rolesbootstrapped.v2"
+	SchemaProposal                 = "kush.proposal.v2"
+	SchemaTimelock                 = "kush.timelock.v2"
+	SchemaOwnershipTransferStarted = "kush.ownershiptransferstarted.v2"
+	SchemaOwnershipTransferred     = "kush.ownershiptransferred.v2"
+	SchemaGlobalPause              = "kush.globalpause.v2"
+	SchemaDenyList                 = "kush.denylist.v2"
+
+	SchemaUpdateUser       = "kush.nft.updateuser.v2"
+	SchemaRevealed         = "kush.nft.revealed.v2"
+	SchemaTokenLock        = "kush.nft.tokenlock.v2"
+	SchemaMintPhaseChanged = "kush.nft.mintphasechanged.v2"
+	SchemaMetadataUpdate   = "kush.nft.metadataupdate.v2"
+
+	SchemaTransferV1       = "kush.transfer.v1"
+	SchemaApprovalV1       = "kush.approval.v1"
+	SchemaBatchTransferV1  = "kush.batchtransfer.v1"
+	SchemaAirdropV1        = "kush.airdrop.v1"
+	SchemaTransferSingleV1 = "kush.transfersingle.v1"
+	SchemaTransferBatchV1  = "kush.transferbatch.v1"
+	SchemaApprovalForAllV1 = "kush.approvalforall.v1"
+	SchemaNFTTransferV1    = "kush.nft.transfer.v1"
+	SchemaURIV1            = "kush.uri.v1"
+
+	SchemaVestingReleasedV1     = "kush.vestingreleased.v1"
+	SchemaStreamV1              = "kush.stream.v1"
+	SchemaPauseV1               = "kush.pause.v1"
+	SchemaFreezeV1              = "kush.freeze.v1"
+	SchemaTokenFreezeV1         = "kush.tokenfreeze.v1"
+	SchemaSnapshotV1            = "kush.snapshot.v1"
+	SchemaEmissionExecutedV1    = "kush.emissionexecuted.v1"
+	SchemaVaultDepositV1        = "kush.vault.deposit.v1"
+	SchemaVaultWithdrawV1       = "kush.vault.withdraw.v1"
+	SchemaWalletExecutedV1      = "kush.walletexecuted.v1"
+	SchemaPromotedV1            = "kush.promoted.v1"
+	SchemaReferralRecordedV1    = "kush.referralrecorded.v1"
+	SchemaCommissionWithdrawnV1 = "kush.commissionwithdrawn.v1"
+
+	SchemaAuthorizedOrgV1            = "kush.authorizedorg.v1"
+	SchemaRoleV1                     = "kush.role.v1"
+	SchemaRoleAdminChangedV1         = "kush.roleadminchanged.v1"
+	SchemaRolesBootstrappedV1        = "kush.rolesbootstrapped.v1"
+	SchemaProposalV1                 = "kush.proposal.v1"
+	SchemaTimelockV1                 = "kush.timelock.v1"
+	SchemaOwnershipTransferStartedV1 = "kush.ownershiptransferstarted.v1"
+	SchemaOwnershipTransferredV1     = "kush.ownershiptransferred.v1"
+	SchemaGlobalPauseV1              = "kush.globalpause.v1"
+	SchemaDenyListV1                 = "kush.denylist.v1"
+
+	SchemaUpdateUserV1       = "kush.nft.updateuser.v1"
+	SchemaRevealedV1         = "kush.nft.revealed.v1"
+	SchemaTokenLockV1        = "kush.nft.tokenlock.v1"
+	SchemaMintPhaseChangedV1 = "kush.nft.mintphasechanged.v1"
+	SchemaMetadataUpdateV1   = "kush.nft.metadataupdate.v1"
+)
+
+// Envelope is the payload of every event emitted through Emit.
+type Envelope struct {
+	Schema string          `json:"schema"`
+	Data   json.RawMessage `json:"data"`
+}
+
+// FeeCharged describes the fee deducted from an ERC20 transfer.
+type FeeCharged struct {
+	From      string   `json:"from"`
+	Collector string   `json:"collector"`
+	Amount    *big.Int `json:"amount"`
+}
+
+// Transfer is the ERC20 Transfer event; mints come from and burns go to "0x0". FeeCharged is set
+// when a transfer fee was withheld from Value.
+type Transfer struct {
+	From       string      `json:"from"`
+	To         string      `json:"to"`
+	Value      *big.Int    `json:"value"`
+	FeeCharged *FeeCharged `json:"feeCharged,omitempty"`
+	TxContext
+}
+
+func (Transfer) Schema() string { return SchemaTransfer }
+
+// Approval is the ERC20 Approval event. From is the owner and To the spender of the allowance.
+type Approval struct {
+	From  string   `json:"from"`
+	To    string   `json:"to"`
+	Value *big.Int `json:"value"`
+	TxContext
+}
+
+func (Approval) Schema() string { return SchemaApproval }
+
+// BatchTransfer is the ERC20 TransferBatch event, listing every leg of a BatchTransfer. FeeCharged
+// sums the fees of the legs; the legs of older events do not carry their own.
+type BatchTransfer struct {
+	From       string      `json:"from"`
+	Transfers  []Transfer  `json:"transfers"`
+	FeeCharged *FeeCharged `json:"feeCharged,omitempty"`
+	TxContext
+}
+
+func (BatchTransfer) Schema() string { return SchemaBatchTransfer }
+
+// Airdrop is the ERC20 Airdrop event, listing the credited accounts of a batch.
+type Airdrop struct {
+	From      string     `json:"from"`
+	Transfers []Transfer `json:"transfers"`
+	TxContext
+}
+
+func (Airdrop) Schema() string { return SchemaAirdrop }
+
+// TransferSingle is the ERC1155 TransferSingle event.
+type TransferSingle struct {
+	Operator string `json:"operator"`
+	From     string `json:"from"`
+	To       string `json:"to"`
+	ID       uint64 `json:"id"`
+	Value    uint64 `json:"value"`
+	TxContext
+}
+
+func (TransferSingle) Schema() string { return SchemaTransferSingle }
+
+// TransferBatch is the ERC1155 TransferBatch event.
+type TransferBatch struct {
+	Operator string   `json:"operator"`
+	From     string   `json:"from"`
+	To       string   `json:"to"`
+	IDs      []uint64 `json:"ids"`
+	Values   []uint64 `json:"values"`
+	TxContext
+}
+
+func (TransferBatch) Schema() string { return SchemaTransferBatch }
+
+// ApprovalForAll is the ApprovalForAll event of ERC721 and ERC1155.
+type ApprovalForAll struct {
+	Owner    string `json:"owner"`
+	Operator string `json:"operator"`
+	Approved bool   `json:"approved"`
+	TxContext
+}
+
+func (ApprovalForAll) Schema() string { return SchemaApprovalForAll }
+
+// NFTTransfer is the ERC721 Transfer event.
+type NFTTransfer struct {
+	From    string `json:"from"`
+	To      string `json:"to"`
+	TokenId string `json:"tokenId"`
+	TxContext
+}
+
+func (NFTTransfer) Schema() string { return SchemaNFTTransfer }
+
+// NFTApproval is the ERC721 Approval event. Approved is the account approved for the token, or ""
+// when the approval is cleared.
+type NFTApproval struct {
+	Owner    string `json:"owner"`
+	Approved string `json:"approved"`
+	TokenId  string `json:"tokenId"`
+	TxContext
+}
+
+func (NFTApproval) Schema() string { return SchemaNFTApproval }
+
+// URI is the ERC1155 URI event. Global marks a change of the global template URI, which applies to
+// every token type without a URI of its own; ID is then 0.
+type URI struct {
+	Value  string `json:"value"`
+	ID     uint64 `json:"id"`
+	Global bool   `json:"global,omitempty"`
+	TxContext
+}
+
+func (URI) Schema() string { return SchemaURI }
+
+// VestingReleased is the ERC20 VestingReleased event. Transfers lists the move of Amount out of the
+// vesting escrow account to Beneficiary.
+type VestingReleased struct {
+	Beneficiary string     `json:"beneficiary"`
+	Amount      *big.Int   `json:"amount"`
+	Transfers   []Transfer `json:"transfers"`
+	TxContext
+}
+
+func (VestingReleased) Schema() string { return SchemaVestingReleased }
+
+// StreamEvent is the ERC20 StreamCreated, StreamWithdrawn and StreamCancelled event. Amount is the
+// deposit net of the transfer fee, the withdrawal and the amount paid to the recipient
+// respectively; Refund is only set on cancellation. Transfers lists the moves in and out of the
+// stream escrow account.
+type StreamEvent struct {
+	StreamId  string     `json:"streamId"`
+	Sender    string     `json:"sender"`
+	Recipient string     `json:"recipient"`
+	Amount    *big.Int   `json:"amount"`
+	Refund    *big.Int   `json:"refund,omitempty"`
+	Transfers []Transfer `json:"transfers"`
+	TxContext
+}
+
+func (StreamEvent) Schema() string { return SchemaStream }
+
+// PauseEvent is the Paused and Unpaused event of the token contracts, naming the account that
+// changed the state.
+type PauseEvent struct {
+	Account string `json:"account"`
+	TxContext
+}
+
+func (PauseEvent) Schema() string { return SchemaPause }
+
+// FreezeEvent is the ERC20 Frozen and Unfrozen event.
+type FreezeEvent struct {
+	Account string `json:"account"`
+	Sender  string `json:"sender"`
+	TxContext
+}
+
+func (FreezeEvent) Schema() string { return SchemaFreeze }
+
+// TokenFreezeEvent is the ERC1155 TokenIDFrozen and TokenIDUnfrozen event.
+type TokenFreezeEvent struct {
+	ID     uint64 `json:"id"`
+	Sender string `json:"sender"`
+	TxContext
+}
+
+func (TokenFreezeEvent) Schema() string { return SchemaTokenFreeze }
+
+// Snapshot is the ERC20 Snapshot event.
+type Snapshot struct {
+	Id uint64 `json:"id"`
+	TxContext
+}
+
+func (Snapshot) Schema() string { return SchemaSnapshot }
+
+// EmissionExecuted is the ERC20 EmissionExecuted event. It replaces the Transfer event of the mint
+// of Amount to Treasury.
+type EmissionExecuted struct {
+	Treasury string   `json:"treasury"`
+	Periods  int64    `json:"periods"`
+	Amount   *big.Int `json:"amount"`
+	TxContext
+}
+
+func (EmissionExecuted) Schema() string { return SchemaEmissionExecuted }
+
+// VaultDeposit is the Deposit event of a vault.
+type VaultDeposit struct {
+	Sender string   `json:"sender"`
+	Owner  string   `json:"owner"`
+	Assets *big.Int `json:"assets"`
+	Shares *big.Int `json:"shares"`
+	TxContext
+}
+
+func (VaultDeposit) Schema() string { return SchemaVaultDeposit }
+
+// VaultWithdraw is the Withdraw event of a vault.
+type VaultWithdraw struct {
+	Sender   string   `json:"sender"`
+	Receiver string   `json:"receiver"`
+	Owner    string   `json:"owner"`
+	Assets   *big.Int `json:"assets"`
+	Shares   *big.Int `json:"shares"`
+	TxContext
+}
+
+func (VaultWithdraw) Schema() string { return SchemaVaultWithdraw }
+
+// WalletExecuted is the WalletExecuted event of a smart wallet.
+type WalletExecuted struct {
+	WalletId string   `json:"walletId"`
+	Target   string   `json:"target"`
+	Function string   `json:"function"`
+	Args     []string `json:"args"`
+	CoSigned bool     `json:"coSigned"`
+	TxContext
+}
+
+func (WalletExecuted) Schema() string { return SchemaWalletExecuted }
+
+// Promoted is the ERC1155 Promoted event. It replaces the TransferSingle event of the burn of one
+// unit of ID from Account.
+type Promoted struct {
+	Account      string `json:"account"`
+	ID           uint64 `json:"id"`
+	NftChaincode string `json:"nftChaincode"`
+	TokenId      string `json:"tokenId"`
+	TxContext
+}
+
+func (Promoted) Schema() string { return SchemaPromoted }
+
+// ReferralRecorded is the ReferralRecorded event of the referrals contract.
+type ReferralRecorded struct {
+	CampaignId string `json:"campaignId"`
+	Code       string `json:"code"`
+	Buyer      string `json:"buyer"`
+	Amount     string `json:"amount"`
+	Commission string `json:"commission"`
+	TxContext
+}
+
+func (ReferralRecorded) Schema() string { return SchemaReferralRecorded }
+
+// CommissionWithdrawn is the CommissionWithdrawn event of the referrals contract.
+type CommissionWithdrawn struct {
+	CampaignId string `json:"campaignId"`
+	Code       string `json:"code"`
+	Referrer   string `json:"referrer"`
+	Amount     string `json:"amount"`
+	TxContext
+}
+
+func (CommissionWithdrawn) Schema() string { return SchemaCommissionWithdrawn }
+
+// AuthorizedOrgEvent is the AuthorizedOrgAdded and AuthorizedOrgRemoved event.
+type AuthorizedOrgEvent struct {
+	MSPID  string `json:"mspId"`
+	Sender string `json:"sender"`
+	TxContext
+}
+
+func (AuthorizedOrgEvent) Schema() string { return SchemaAuthorizedOrg }
+
+// RoleEvent is the RoleGranted and RoleRevoked event.
+type RoleEvent struct {
+	Role    string `json:"role"`
+	Account string `json:"account"`
+	Sender  string `json:"sender"`
+	TxContext
+}
+
+func (RoleEvent) Schema() string { return SchemaRole }
+
+// RoleAdminChanged is the RoleAdminChanged event.
+type RoleAdminChanged struct {
+	Role              string `json:"role"`
+	PreviousAdminRole string `json:"previousAdminRole"`
+	NewAdminRole      string `json:"newAdminRole"`
+	TxContext
+}
+
+func (RoleAdminChanged) Schema() string { return SchemaRoleAdminChanged }
+
+// RolesBootstrapped is the RolesBootstrapped event.
+type RolesBootstrapped struct {
+	Account string   `json:"account"`
+	Roles   []string `json:"roles"`
+	TxContext
+}
+
+func (RolesBootstrapped) Schema() string { return SchemaRolesBootstrapped }
+
+// ProposalEvent is the ProposalCreated and ProposalApproved event of multisig approval.
+type ProposalEvent struct {
+	ID        string `json:"id"`
+	Action    string `json:"action"`
+	Signer    string `json:"signer"`
+	Approvals int    `json:"approvals"`
+	TxContext
+}
+
+func (ProposalEvent) Schema() string { return SchemaProposal }
+
+// TimelockEvent is the CallScheduled, CallExecuted and CallCancelled event of the timelock.
+type TimelockEvent struct {
+	ID      string   `json:"id"`
+	Action  string   `json:"action"`
+	Args    []string `json:"args"`
+	ReadyAt int64    `json:"readyAt"`
+	Sender  string   `json:"sender"`
+	TxContext
+}
+
+func (TimelockEvent) Schema() string { return SchemaTimelock }
+
+// OwnershipTransferStarted is the OwnershipTransferStarted event.
+type OwnershipTransferStarted struct {
+	PreviousOwner string `json:"previousOwner"`
+	NewOwner      string `json:"newOwner"`
+	TxContext
+}
+
+func (OwnershipTransferStarted) Schema() string { return SchemaOwnershipTransferStarted }
+
+// OwnershipTransferred is the OwnershipTransferred event.
+type OwnershipTransferred struct {
+	PreviousOwner string `json:"previousOwner"`
+	NewOwner      string `json:"newOwner"`
+	TxContext
+}
+
+func (OwnershipTransferred) Schema() string { return SchemaOwnershipTransferred }
+
+// GlobalPauseEvent is the GlobalPaused and GlobalUnpaused event of the guardian.
+type GlobalPauseEvent struct {
+	Account string `json:"account"`
+	Reason  string `json:"reason,omitempty" metadata:",optional"`
+	TxContext
+}
+
+func (GlobalPauseEvent) Schema() string { return SchemaGlobalPause }
+
+// DenyListEvent is the AccountDenied and AccountAllowed event of the deny list. Reason is empty
+// when an account is allowed again.
+type DenyListEvent struct {
+	Account string `json:"account"`
+	Reason  string `json:"reason"`
+	Lister  string `json:"lister"`
+	TxContext
+}
+
+func (DenyListEvent) Schema() string { return SchemaDenyList }
+
+// UpdateUser is the ERC721 UpdateUser event (ERC-4907). User is "" when the user is removed.
+type UpdateUser struct {
+	TokenId string `json:"tokenId"`
+	User    string `json:"user"`
+	Expires int64  `json:"expires"`
+	TxContext
+}
+
+func (UpdateUser) Schema() string { return SchemaUpdateUser }
+
+// Revealed is the ERC721 Revealed event.
+type Revealed struct {
+	HiddenURI string `json:"hiddenURI"`
+	Sender    string `json:"sender"`
+	TxContext
+}
+
+func (Revealed) Schema() string { return SchemaRevealed }
+
+// TokenLockEvent is the ERC721 TokenLocked and TokenUnlocked event, carrying the lock placed or
+// lifted.
+type TokenLockEvent struct {
+	TokenId  string `json:"tokenId"`
+	Reason   string `json:"reason"`
+	LockedBy string `json:"lockedBy"`
+	LockedAt int64  `json:"lockedAt"`
+	TxContext
+}
+
+func (TokenLockEvent) Schema() string { return SchemaTokenLock }
+
+// MintPhaseChanged is the ERC721 MintPhaseChanged event.
+type MintPhaseChanged struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Sender string `json:"sender"`
+	TxContext
+}
+
+func (MintPhaseChanged) Schema() string { return SchemaMintPhaseChanged }
+
+// MetadataUpdate is the ERC721 MetadataUpdate event (ERC-4906).
+type MetadataUpdate struct {
+	TokenId string `json:"tokenId"`
+	TxContext
+}
+
+func (MetadataUpdate) Schema() string { return SchemaMetadataUpdate }
+
+// decoders decode the data of each schema. A v1 payload decodes into the struct of its v2 schema,
+// with an empty TxContext.
+var decoders = map[string]func(data []byte) (interface{}, error){
+	SchemaTransfer:                   decodeAs[Transfer],
+	SchemaTransferV1:                 decodeAs[Transfer],
+	SchemaApproval:                   decodeAs[Approval],
+	SchemaApprovalV1:                 decodeAs[Approval],
+	SchemaBatchTransfer:              decodeAs[BatchTransfer],
+	SchemaBatchTransferV1:            decodeAs[BatchTransfer],
+	SchemaAirdrop:                    decodeAs[Airdrop],
+	SchemaAirdropV1:                  decodeAs[Airdrop],
+	SchemaTransferSingle:             decodeAs[TransferSingle],
+	SchemaTransferSingleV1:           decodeAs[TransferSingle],
+	SchemaTransferBatch:              decodeAs[TransferBatch],
+	SchemaTransferBatchV1:            decodeAs[TransferBatch],
+	SchemaApprovalForAll:             decodeAs[ApprovalForAll],
+	SchemaApprovalForAllV1:           decodeAs[ApprovalForAll],
+	SchemaNFTTransfer:                decodeAs[NFTTransfer],
+	SchemaNFTTransferV1:              decodeAs[NFTTransfer],
+	SchemaNFTApproval:                decodeAs[NFTApproval],
+	SchemaURI:                        decodeAs[URI],
+	SchemaURIV1:                      decodeAs[URI],
+	SchemaVestingReleased:            decodeAs[VestingReleased],
+	SchemaVestingReleasedV1:          decodeAs[VestingReleased],
+	SchemaStream:                     decodeAs[StreamEvent],
+	SchemaStreamV1:                   decodeAs[StreamEvent],
+	SchemaPause:                      decodeAs[PauseEvent],
+	SchemaPauseV1:                    decodeAs[PauseEvent],
+	SchemaFreeze:                     decodeAs[FreezeEvent],
+	SchemaFreezeV1:                   decodeAs[FreezeEvent],
+	SchemaTokenFreeze:                decodeAs[TokenFreezeEvent],
+	SchemaTokenFreezeV1:              decodeAs[TokenFreezeEvent],
+	SchemaSnapshot:                   decodeAs[Snapshot],
+	SchemaSnapshotV1:                 decodeAs[Snapshot],
+	SchemaEmissionExecuted:           decodeAs[EmissionExecuted],
+	SchemaEmissionExecutedV1:         decodeAs[EmissionExecuted],
+	SchemaVaultDeposit:               decodeAs[VaultDeposit],
+	SchemaVaultDepositV1:             decodeAs[VaultDeposit],
+	SchemaVaultWithdraw:              decodeAs[VaultWithdraw],
+	SchemaVaultWithdrawV1:            decodeAs[VaultWithdraw],
+	SchemaWalletExecuted:             decodeAs[WalletExecuted],
+	SchemaWalletExecutedV1:           decodeAs[WalletExecuted],
+	SchemaPromoted:                   decodeAs[Promoted],
+	SchemaPromotedV1:                 decodeAs[Promoted],
+	SchemaReferralRecorded:           decodeAs[ReferralRecorded],
+	SchemaReferralRecordedV1:         decodeAs[ReferralRecorded],
+	SchemaCommissionWithdrawn:        decodeAs[CommissionWithdrawn],
+	SchemaCommissionWithdrawnV1:      decodeAs[CommissionWithdrawn],
+	SchemaAuthorizedOrg:              decodeAs[AuthorizedOrgEvent],
+	SchemaAuthorizedOrgV1:            decodeAs[AuthorizedOrgEvent],
+	SchemaRole:                       decodeAs[RoleEvent],
+	SchemaRoleV1:                     decodeAs[RoleEvent],
+	SchemaRoleAdminChanged:           decodeAs[RoleAdminChanged],
+	SchemaRoleAdminChangedV1:         decodeAs[RoleAdminChanged],
+	SchemaRolesBootstrapped:          decodeAs[RolesBootstrapped],
+	SchemaRolesBootstrappedV1:        decodeAs[RolesBootstrapped],
+	SchemaProposal:                   decodeAs[ProposalEvent],
+	SchemaProposalV1:                 decodeAs[ProposalEvent],
+	SchemaTimelock:                   decodeAs[TimelockEvent],
+	SchemaTimelockV1:                 decodeAs[TimelockEvent],
+	SchemaOwnershipTransferStarted:   decodeAs[OwnershipTransferStarted],
+	SchemaOwnershipTransferStartedV1: decodeAs[OwnershipTransferStarted],
+	SchemaOwnershipTransferred:       decodeAs[OwnershipTransferred],
+	SchemaOwnershipTransferredV1:     decodeAs[OwnershipTransferred],
+	SchemaGlobalPause:                decodeAs[GlobalPauseEvent],
+	SchemaGlobalPauseV1:              decodeAs[GlobalPauseEvent],
+	SchemaDenyList:                   decodeAs[DenyListEvent],
+	SchemaDenyListV1:                 decodeAs[DenyListEvent],
+	SchemaUpdateUser:                 decodeAs[UpdateUser],
+	SchemaUpdateUserV1:               decodeAs[UpdateUser],
+	SchemaRevealed:                   decodeAs[Revealed],
+	SchemaRevealedV1:                 decodeAs[Revealed],
+	SchemaTokenLock:                  decodeAs[TokenLockEvent],
+	SchemaTokenLockV1:                decodeAs[TokenLockEvent],
+	SchemaMintPhaseChanged:           decodeAs[MintPhaseChanged],
+	SchemaMintPhaseChangedV1:         decodeAs[MintPhaseChanged],
+	SchemaMetadataUpdate:             decodeAs[MetadataUpdate],
+	SchemaMetadataUpdateV1:           decodeAs[MetadataUpdate],
+}
+
+func decodeAs[T any](data []byte) (interface{}, error) {
+	value := new(T)
+	err := json.Unmarshal(data, value)
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// Decode decodes the payload of the event name into a pointer to one of the event structs of this
+// package, and returns its schema. Bare payloads from before the envelope are decoded by their v1
+// schema.
+func Decode(name string, payload []byte) (string, interface{}, error) {
+	var fields map[string]json.RawMessage
+	err := json.Unmarshal(payload, &fields)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to decode event %s: %v", name, err)
+	}
+
+	schema, data := "", payload
+	if rawSchema, ok := fields["schema"]; ok {
+		err = json.Unmarshal(rawSchema, &schema)
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to decode schema of event %s: %v", name, err)
+		}
+		data = fields["data"]
+	} else {
+		schema = legacySchema(name, fields)
+	}
+
+	decode, ok := decoders[schema]
+	if !ok {
+		return "", nil, fmt.Errorf("event %s has unknown schema %q", name, schema)
+	}
+	value, err := decode(data)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to decode event %s as %s: %v", name, schema, err)
+	}
+	return schema, value, nil
+}
+
+// legacySchema tells the v1 schema of a bare payload from its event name, and from its fields
+// where ERC20 and ERC721 or ERC1155 share the name.
+func legacySchema(name string, fields map[string]json.RawMessage) string {
+	switch name {
+	case "Transfer":
+		if _, ok := fields["tokenId"]; ok {
+			return SchemaNFTTransferV1
+		}
+		return SchemaTransferV1
+	case "Approval":
+		return SchemaApprovalV1
+	case "TransferBatch":
+		if _, ok := fields["transfers"]; ok {
+			return SchemaBatchTransferV1
+		}
+		return SchemaTransferBatchV1
+	case "Airdrop":
+		return SchemaAirdropV1
+	case "TransferSingle":
+		return SchemaTransferSingleV1
+	case "ApprovalForAll":
+		return SchemaApprovalForAllV1
+	case "URI":
+		return SchemaURIV1
+	case "VestingReleased":
+		return SchemaVestingReleasedV1
+	case "StreamCreated", "StreamWithdrawn", "StreamCancelled":
+		return SchemaStreamV1
+	case "Paused", "Unpaused":
+		return SchemaPauseV1
+	case "Frozen", "Unfrozen":
+		return SchemaFreezeV1
+	case "TokenIDFrozen", "TokenIDUnfrozen":
+		return SchemaTokenFreezeV1
+	case "Snapshot":
+		return SchemaSnapshotV1
+	case "EmissionExecuted":
+		return SchemaEmissionExecutedV1
+	case "Deposit":
+		return SchemaVaultDepositV1
+	case "Withdraw":
+		return SchemaVaultWithdrawV1
+	case "WalletExecuted":
+		return SchemaWalletExecutedV1
+	case "Promoted":
+		return SchemaPromotedV1
+	case "ReferralRecorded":
+		return SchemaReferralRecordedV1
+	case "CommissionWithdrawn":
+		return SchemaCommissionWithdrawnV1
+	case "AuthorizedOrgAdded", "AuthorizedOrgRemoved":
+		return SchemaAuthorizedOrgV1
+	case "RoleGranted", "RoleRevoked":
+		return SchemaRoleV1
+	case "RoleAdminChanged":
+		return SchemaRoleAdminChangedV1
+	case "RolesBootstrapped":
+		return SchemaRolesBootstrappedV1
+	case "ProposalCreated", "ProposalApproved":
+		return SchemaProposalV1
+	case "CallScheduled", "CallExecuted", "CallCancelled":
+		return SchemaTimelockV1
+	case "OwnershipTransferStarted":
+		return SchemaOwnershipTransferStartedV1
+	case "OwnershipTransferred":
+		return SchemaOwnershipTransferredV1
+	case "GlobalPaused", "GlobalUnpaused":
+		return SchemaGlobalPauseV1
+	case "AccountDenied", "AccountAllowed":
+		return SchemaDenyListV1
+	case "UpdateUser":
+		return SchemaUpdateUserV1
+	case "Revealed":
+		return SchemaRevealedV1
+	case "TokenLocked", "TokenUnlocked":
+		return SchemaTokenLockV1
+	case "MintPhaseChanged":
+		return SchemaMintPhaseChangedV1
+	case "MetadataUpdate":
+		return SchemaMetadataUpdateV1
+	}
+	return ""
+}
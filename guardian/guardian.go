@@ -0,0 +1,187 @@
+// Package guardian implements a global circuit breaker for the token contracts.
+//
+// GuardianContract is deployed as its own chaincode. Token contracts that are pointed at it with
+// SetGuardian ask it whether they are paused, through a cross-chaincode query, before every
+// mutation, so a PAUSER_ROLE holder on the guardian halts all of them with a single Pause.
+package guardian
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+	"github.com/thekalpstudio/kush-go/audit"
+	"github.com/thekalpstudio/kush-go/events"
+	"github.com/thekalpstudio/kush-go/logging"
+)
+
+const pausedKey = "guardian~paused"
+const guardianKey = "guardian~chaincode"
+
+// GuardianContract holds the global pause flag.
+type GuardianContract struct {
+	logging.Contract
+}
+
+// GlobalPauseEvent is emitted as GlobalPaused or GlobalUnpaused.
+type GlobalPauseEvent = events.GlobalPauseEvent
+
+// Initialize bootstraps the roles of the guardian, making the caller, who must belong to one of
+// authorizedMSPs, its first pauser.
+func (g *GuardianContract) Initialize(ctx kalpsdk.TransactionContextInterface, authorizedMSPs []string) error {
+	return accesscontrol.Initialize(ctx, authorizedMSPs)
+}
+
+// Pause halts every contract that consults this guardian. reason is recorded in the event.
+func (g *GuardianContract) Pause(ctx kalpsdk.TransactionContextInterface, reason string) error {
+	return setGlobalPaused(ctx, true, reason)
+}
+
+// Unpause lifts a Pause.
+func (g *GuardianContract) Unpause(ctx kalpsdk.TransactionContextInterface) error {
+	return setGlobalPaused(ctx, false, "")
+}
+
+func (g *GuardianContract) IsPaused(ctx kalpsdk.TransactionContextInterface) (bool, error) {
+	pausedBytes, err := ctx.GetState(pausedKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to read paused state: %v", err)
+	}
+	return pausedBytes != nil, nil
+}
+
+// GrantRole gives account role, typically the PAUSER_ROLE for incident responders.
+func (g *GuardianContract) GrantRole(ctx kalpsdk.TransactionContextInterface, role string, account string) error {
+	return accesscontrol.GrantRole(ctx, role, account)
+}
+
+// RevokeRole removes role from account.
+func (g *GuardianContract) RevokeRole(ctx kalpsdk.TransactionContextInterface, role string, account string) error {
+	return accesscontrol.RevokeRole(ctx, role, account)
+}
+
+func (g *GuardianContract) HasRole(ctx kalpsdk.TransactionContextInterface, role string, account string) (bool, error) {
+	return accesscontrol.HasRole(ctx, role, account)
+}
+
+// GetAuditLog returns a page of the pauses, unpauses and role changes of the guardian.
+func (g *GuardianContract) GetAuditLog(ctx kalpsdk.TransactionContextInterface, bookmark string, pageSize int) (*audit.Page, error) {
+	return audit.GetAuditLog(ctx, bookmark, pageSize)
+}
+
+// SetLogLevel changes the level at which the peer evaluating it logs the transactions of the
+// guardian, until the chaincode restarts.
+func (g *GuardianContract) SetLogLevel(ctx kalpsdk.TransactionContextInterface, level string) error {
+	return logging.SetLogLevel(ctx, level)
+}
+
+func (g *GuardianContract) GetLogLevel(ctx kalpsdk.TransactionContextInterface) (string, error) {
+	return logging.GetLogLevel(ctx)
+}
+
+// SetGuardian points the calling token contract at the guardian chaincode chaincodeName. An empty
+// name detaches it. The caller must hold the ADMIN_ROLE of the token contract; while multisig
+// approval or the timelock is enabled the change has to be proposed or scheduled as
+// accesscontrol.ActionSetGuardian instead.
+func SetGuardian(ctx kalpsdk.TransactionContextInterface, chaincodeName string) error {
+	err := accesscontrol.CheckRole(ctx, accesscontrol.AdminRole)
+	if err != nil {
+		return err
+	}
+	err = accesscontrol.CheckNotMultisig(ctx, accesscontrol.ActionSetGuardian)
+	if err != nil {
+		return err
+	}
+	err = accesscontrol.CheckNotTimelocked(ctx, accesscontrol.ActionSetGuardian)
+	if err != nil {
+		return err
+	}
+	return WriteGuardian(ctx, chaincodeName)
+}
+
+// WriteGuardian stores the guardian chaincode of the calling token contract, leaving
+// authorization to the caller. Contracts call it to execute an approved ActionSetGuardian.
+func WriteGuardian(ctx kalpsdk.TransactionContextInterface, chaincodeName string) error {
+	var err error
+	if chaincodeName == "" {
+		err = ctx.DelStateWithoutKYC(guardianKey)
+	} else {
+		err = ctx.PutStateWithoutKYC(guardianKey, []byte(chaincodeName))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to set guardian: %v", err)
+	}
+	return audit.Record(ctx, "SetGuardian", chaincodeName)
+}
+
+// GetGuardian returns the guardian chaincode of the calling token contract, or "" if none is set.
+func GetGuardian(ctx kalpsdk.TransactionContextInterface) (string, error) {
+	guardianBytes, err := ctx.GetState(guardianKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to read guardian: %v", err)
+	}
+	return string(guardianBytes), nil
+}
+
+// CheckNotGloballyPaused fails if the guardian of the calling token contract is paused. Contracts
+// without a guardian are never globally paused.
+func CheckNotGloballyPaused(ctx kalpsdk.TransactionContextInterface) error {
+	guardian, err := GetGuardian(ctx)
+	if err != nil {
+		return err
+	}
+	if guardian == "" {
+		return nil
+	}
+	response := ctx.InvokeChaincode(guardian, [][]byte{[]byte("IsPaused")}, "")
+	if response.Status != shim.OK {
+		return fmt.Errorf("failed to invoke IsPaused on chaincode %s: %s", guardian, response.Message)
+	}
+	if string(response.Payload) == "true" {
+		return fmt.Errorf("token operations are paused by guardian %s", guardian)
+	}
+	return nil
+}
+
+func setGlobalPaused(ctx kalpsdk.TransactionContextInterface, paused bool, reason string) error {
+	err := accesscontrol.CheckRole(ctx, accesscontrol.PauserRole)
+	if err != nil {
+		return fmt.Errorf("client is not authorized to pause: %v", err)
+	}
+	pausedBytes, err := ctx.GetState(pausedKey)
+	if err != nil {
+		return fmt.Errorf("failed to read paused state: %v", err)
+	}
+	if (pausedBytes != nil) == paused {
+		if paused {
+			return fmt.Errorf("contracts are already paused")
+		}
+		return fmt.Errorf("contracts are not paused")
+	}
+
+	if paused {
+		err = ctx.PutStateWithoutKYC(pausedKey, []byte("true"))
+	} else {
+		err = ctx.DelStateWithoutKYC(pausedKey)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update paused state: %v", err)
+	}
+
+	account, err := ctx.GetUserID()
+	if err != nil {
+		return fmt.Errorf("failed to get client id: %v", err)
+	}
+	eventName := "GlobalUnpaused"
+	params := []string{}
+	if paused {
+		eventName = "GlobalPaused"
+		params = append(params, reason)
+	}
+	err = audit.Record(ctx, eventName, params...)
+	if err != nil {
+		return err
+	}
+	return events.Emit(ctx, eventName, &GlobalPauseEvent{Account: account, Reason: reason})
+}
@@ -0,0 +1,36 @@
+// Package chaincode starts the contracts as chaincode, for the devnet of the integration tests or
+// any Kalp network.
+//
+// A chaincode is launched by its peer unless CHAINCODE_SERVER_ADDRESS is set, in which case it runs
+// as a service the peer connects to, under the package ID in CHAINCODE_ID. The devnet runs every
+// contract as a service, so deploying one builds no image on the peer.
+//
+// Fabric installs the CouchDB indexes of a chaincode only from the META-INF directory at the root
+// of its package, so they live next to the main packages, in erc721/META-INF and erc1155/META-INF.
+// Deployers building their own main package copy that directory next to it.
+//
+// KUSH_LOG_LEVEL sets the level the contracts log at, see package logging.
+package chaincode
+
+import (
+	"os"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+)
+
+// Serve runs cc until it fails.
+func Serve(cc *kalpsdk.ContractChaincode) error {
+	address := os.Getenv("CHAINCODE_SERVER_ADDRESS")
+	if address == "" {
+		return cc.Start()
+	}
+	server := &shim.ChaincodeServer{
+		CCID:    os.Getenv("CHAINCODE_ID"),
+		Address: address,
+		CC:      &cc.ContractChaincode,
+		// the devnet keeps chaincode traffic inside its docker network
+		TLSProps: shim.TLSProperties{Disabled: true},
+	}
+	return server.Start()
+}
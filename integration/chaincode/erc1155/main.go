@@ -0,0 +1,22 @@
+// Command erc1155 is the chaincode of the ERC1155 contract, see package chaincode.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/Contracts/token"
+	"github.com/thekalpstudio/kush-go/integration/chaincode"
+)
+
+func main() {
+	cc, err := kalpsdk.NewChaincode(new(token.SmartContract))
+	if err == nil {
+		err = chaincode.Serve(cc)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "erc1155: %v\n", err)
+		os.Exit(1)
+	}
+}
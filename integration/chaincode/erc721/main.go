@@ -0,0 +1,22 @@
+// Command erc721 is the chaincode of the ERC721 contract, see package chaincode.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/contracts/token"
+	"github.com/thekalpstudio/kush-go/integration/chaincode"
+)
+
+func main() {
+	cc, err := kalpsdk.NewChaincode(new(token.TokenERC721Contract))
+	if err == nil {
+		err = chaincode.Serve(cc)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "erc721: %v\n", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/pem"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/shimtest"
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/contracts/token"
+	"github.com/thekalpstudio/kush-go/events"
+	"github.com/thekalpstudio/kush-go/testutil"
+)
+
+// creator returns the serialized identity of user, with a certificate issued by l.
+func creator(t *testing.T, l *testutil.Ledger, user string) []byte {
+	t.Helper()
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: l.Certificate(user).Raw})
+	identity, err := events.Identity(testutil.MSPID, certPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return identity
+}
+
+// TestChaincode runs the lifecycle of the integration tests on a mock stub, through the contract
+// metadata and argument parsing a peer goes through.
+func TestChaincode(t *testing.T) {
+	cc, err := kalpsdk.NewChaincode(new(token.TokenERC721Contract))
+	if err != nil {
+		t.Fatal(err)
+	}
+	stub := shimtest.NewMockStub("erc721", &cc.ContractChaincode)
+	l := testutil.NewLedger()
+	admin, bob := creator(t, l, "admin"), creator(t, l, "bob")
+	txs := 0
+	invoke := func(identity []byte, args ...string) string {
+		t.Helper()
+		txs++
+		stub.Creator = identity
+		input := make([][]byte, len(args))
+		for i, arg := range args {
+			input[i] = []byte(arg)
+		}
+		response := stub.MockInvoke(string(rune('a'+txs)), input)
+		if response.Status != 200 {
+			t.Fatalf("%s failed: %s", args[0], response.Message)
+		}
+		return string(response.Payload)
+	}
+
+	invoke(admin, "Initialize", `{"name":"Collectibles","symbol":"CLT","authorizedOrgs":["Org1MSP"]}`)
+	invoke(admin, "MintWithTokenURI", "1", "https://example.com/1.json")
+	invoke(admin, "TransferFrom", "admin", "bob", "1")
+	if got := invoke(bob, "OwnerOf", "1"); got != "bob" {
+		t.Fatalf("owner is %s, want bob", got)
+	}
+	if got := invoke(bob, "BalanceOf", "bob"); got != "1" {
+		t.Fatalf("recipient balance is %s, want 1", got)
+	}
+	if got := invoke(bob, "TotalSupply"); got != "1" {
+		t.Fatalf("total supply is %s, want 1", got)
+	}
+}
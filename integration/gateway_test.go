@@ -0,0 +1,256 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/gateway"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/thekalpstudio/kush-go/events"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// env returns the environment variable name, or fallback when it is unset.
+func env(name string, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
+
+var (
+	peerEndpoint = env("KUSH_PEER_ENDPOINT", "localhost:7051")
+	peerHost     = env("KUSH_PEER_HOST_OVERRIDE", "peer0.org1.example.com")
+	channelID    = env("KUSH_CHANNEL", "kalp")
+	mspID        = env("KUSH_MSP_ID", "Org1MSP")
+	orgDir       = env("KUSH_ORG_DIR", "network/organizations/peerOrganizations/org1.example.com")
+)
+
+// dial connects to the gateway of the devnet peer. The connection is closed with the test.
+func dial(t *testing.T) *grpc.ClientConn {
+	t.Helper()
+	caPEM, err := os.ReadFile(filepath.Join(orgDir, "peers", peerHost, "tls", "ca.crt"))
+	if err != nil {
+		t.Fatalf("failed to read the TLS CA of the peer, is the devnet up? %v", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		t.Fatal("no certificate in the TLS CA of the peer")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	conn, err := grpc.DialContext(ctx, peerEndpoint, grpc.WithBlock(),
+		grpc.WithTransportCredentials(credentials.NewClientTLSFromCert(pool, peerHost)))
+	if err != nil {
+		t.Fatalf("failed to connect to %s: %v", peerEndpoint, err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// client submits and evaluates transactions as one identity of the devnet.
+type client struct {
+	t        *testing.T
+	gateway  gateway.GatewayClient
+	identity []byte
+	key      *ecdsa.PrivateKey
+}
+
+// newClient returns a client acting as user, Admin or User1, of the devnet organization.
+func newClient(t *testing.T, conn *grpc.ClientConn, user string) *client {
+	t.Helper()
+	mspDir := filepath.Join(orgDir, "users", user+"@"+filepath.Base(orgDir), "msp")
+	certPEM := readOnly(t, filepath.Join(mspDir, "signcerts"))
+	identity, err := events.Identity(mspID, certPEM)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, _ := pem.Decode(readOnly(t, filepath.Join(mspDir, "keystore")))
+	if block == nil {
+		t.Fatalf("no PEM block in the keystore of %s", user)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		t.Fatalf("failed to parse the key of %s: %v", user, err)
+	}
+	ecKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		t.Fatalf("the key of %s is not an ECDSA key", user)
+	}
+	return &client{t: t, gateway: gateway.NewGatewayClient(conn), identity: identity, key: ecKey}
+}
+
+// readOnly returns the content of the only file in dir, as cryptogen lays out an MSP.
+func readOnly(t *testing.T, dir string) []byte {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("%s holds %d files, want 1", dir, len(entries))
+	}
+	content, err := os.ReadFile(filepath.Join(dir, entries[0].Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return content
+}
+
+// sign signs digest with the key of c, in the low-S form peers require.
+func (c *client) sign(digest []byte) ([]byte, error) {
+	r, s, err := ecdsa.Sign(rand.Reader, c.key, digest)
+	if err != nil {
+		return nil, err
+	}
+	n := elliptic.P256().Params().N
+	if s.Cmp(new(big.Int).Rsh(n, 1)) > 0 {
+		s.Sub(n, s)
+	}
+	return asn1.Marshal(struct{ R, S *big.Int }{r, s})
+}
+
+// proposal returns the signed proposal of function of chaincode with args, and its transaction
+// id.
+func (c *client) proposal(chaincode string, function string, args ...string) (*peer.SignedProposal, string) {
+	c.t.Helper()
+	nonce := make([]byte, 24)
+	_, err := rand.Read(nonce)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	txHash := sha256.Sum256(append(nonce, c.identity...))
+	txID := hex.EncodeToString(txHash[:])
+
+	chaincodeID := &peer.ChaincodeID{Name: chaincode}
+	extension := c.marshal(&peer.ChaincodeHeaderExtension{ChaincodeId: chaincodeID})
+	header := c.marshal(&common.Header{
+		ChannelHeader: c.marshal(&common.ChannelHeader{
+			Type:      int32(common.HeaderType_ENDORSER_TRANSACTION),
+			ChannelId: channelID,
+			TxId:      txID,
+			Timestamp: timestamppb.Now(),
+			Extension: extension,
+		}),
+		SignatureHeader: c.marshal(&common.SignatureHeader{Creator: c.identity, Nonce: nonce}),
+	})
+	input := &peer.ChaincodeInput{Args: [][]byte{[]byte(function)}}
+	for _, arg := range args {
+		input.Args = append(input.Args, []byte(arg))
+	}
+	payload := c.marshal(&peer.ChaincodeProposalPayload{Input: c.marshal(&peer.ChaincodeInvocationSpec{
+		ChaincodeSpec: &peer.ChaincodeSpec{Type: peer.ChaincodeSpec_GOLANG, ChaincodeId: chaincodeID, Input: input},
+	})})
+	proposal := c.marshal(&peer.Proposal{Header: header, Payload: payload})
+	return &peer.SignedProposal{ProposalBytes: proposal, Signature: c.signBytes(proposal)}, txID
+}
+
+func (c *client) marshal(m proto.Message) []byte {
+	c.t.Helper()
+	b, err := proto.Marshal(m)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return b
+}
+
+func (c *client) signBytes(b []byte) []byte {
+	c.t.Helper()
+	digest := sha256.Sum256(b)
+	signature, err := c.sign(digest[:])
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	return signature
+}
+
+// Evaluate runs function of chaincode on the peer without committing it, and returns its result.
+func (c *client) Evaluate(chaincode string, function string, args ...string) (string, error) {
+	c.t.Helper()
+	proposal, txID := c.proposal(chaincode, function, args...)
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	response, err := c.gateway.Evaluate(ctx, &gateway.EvaluateRequest{
+		TransactionId:       txID,
+		ChannelId:           channelID,
+		ProposedTransaction: proposal,
+	})
+	if err != nil {
+		return "", gatewayError(function, err)
+	}
+	return string(response.Result.Payload), nil
+}
+
+// Submit endorses function of chaincode, orders it and waits until it is committed valid.
+func (c *client) Submit(chaincode string, function string, args ...string) error {
+	c.t.Helper()
+	proposal, txID := c.proposal(chaincode, function, args...)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	endorsed, err := c.gateway.Endorse(ctx, &gateway.EndorseRequest{
+		TransactionId:       txID,
+		ChannelId:           channelID,
+		ProposedTransaction: proposal,
+	})
+	if err != nil {
+		return gatewayError(function, err)
+	}
+	envelope := endorsed.PreparedTransaction
+	envelope.Signature = c.signBytes(envelope.Payload)
+	_, err = c.gateway.Submit(ctx, &gateway.SubmitRequest{
+		TransactionId:       txID,
+		ChannelId:           channelID,
+		PreparedTransaction: envelope,
+	})
+	if err != nil {
+		return gatewayError(function, err)
+	}
+
+	request := c.marshal(&gateway.CommitStatusRequest{TransactionId: txID, ChannelId: channelID, Identity: c.identity})
+	committed, err := c.gateway.CommitStatus(ctx, &gateway.SignedCommitStatusRequest{
+		Request:   request,
+		Signature: c.signBytes(request),
+	})
+	if err != nil {
+		return gatewayError(function, err)
+	}
+	if committed.Result != peer.TxValidationCode_VALID {
+		return fmt.Errorf("%s was committed as %s", function, committed.Result)
+	}
+	return nil
+}
+
+// gatewayError adds the messages of the peers, which carry the errors of the contract, to err.
+func gatewayError(function string, err error) error {
+	var messages []string
+	for _, detail := range status.Convert(err).Details() {
+		if detail, ok := detail.(*gateway.ErrorDetail); ok {
+			messages = append(messages, detail.Message)
+		}
+	}
+	if len(messages) == 0 {
+		return fmt.Errorf("%s failed: %v", function, err)
+	}
+	return fmt.Errorf("%s failed: %v: %s", function, err, strings.Join(messages, "; "))
+}
@@ -0,0 +1,129 @@
+//go:build integration
+
+package integration
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/thekalpstudio/kush-go/events"
+)
+
+// initialize initializes chaincode as admin. The devnet keeps its ledger between runs, so a
+// contract initialized by an earlier run is accepted as it is.
+func initialize(t *testing.T, admin *client, chaincode string, name string, symbol string) {
+	t.Helper()
+	cfg := fmt.Sprintf(`{"name":%q,"symbol":%q,"authorizedOrgs":[%q]}`, name, symbol, mspID)
+	err := admin.Submit(chaincode, "Initialize", cfg)
+	if err != nil && !strings.Contains(err.Error(), "already set") {
+		t.Fatal(err)
+	}
+}
+
+func evaluate(t *testing.T, c *client, chaincode string, function string, args ...string) string {
+	t.Helper()
+	result, err := c.Evaluate(chaincode, function, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func submit(t *testing.T, c *client, chaincode string, function string, args ...string) {
+	t.Helper()
+	err := c.Submit(chaincode, function, args...)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestERC1155Lifecycle(t *testing.T) {
+	conn := dial(t)
+	admin, user := newClient(t, conn, "Admin"), newClient(t, conn, "User1")
+	initialize(t, admin, "erc1155", "Items", "ITM")
+	adminAccount := evaluate(t, admin, "erc1155", "ClientAccountID")
+	userAccount := evaluate(t, user, "erc1155", "ClientAccountID")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+	listener, err := events.Listen(ctx, events.ListenerConfig{
+		Conn:        conn,
+		ChannelID:   channelID,
+		ChaincodeID: "erc1155",
+		Identity:    admin.identity,
+		Sign:        admin.sign,
+		FromNewest:  true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a token type of its own keeps the balances of this run apart from those of earlier runs
+	id := strconv.FormatInt(time.Now().UnixNano(), 10)
+	submit(t, admin, "erc1155", "Mint", adminAccount, id, "100")
+	submit(t, admin, "erc1155", "TransferFrom", adminAccount, userAccount, id, "40")
+
+	if got := evaluate(t, user, "erc1155", "BalanceOf", userAccount, id); got != "40" {
+		t.Fatalf("recipient balance is %s, want 40", got)
+	}
+	if got := evaluate(t, user, "erc1155", "BalanceOf", adminAccount, id); got != "60" {
+		t.Fatalf("sender balance is %s, want 60", got)
+	}
+	if got := evaluate(t, user, "erc1155", "TotalSupply", id); got != "100" {
+		t.Fatalf("total supply is %s, want 100", got)
+	}
+
+	var transfers []*events.TransferSingle
+	for len(transfers) < 2 {
+		select {
+		case event, ok := <-listener.Events():
+			if !ok {
+				t.Fatalf("listener stopped: %v", listener.Err())
+			}
+			if transfer, ok := event.Value.(*events.TransferSingle); ok && strconv.FormatUint(transfer.ID, 10) == id {
+				transfers = append(transfers, transfer)
+			}
+		case <-ctx.Done():
+			t.Fatalf("received %d TransferSingle events, want 2", len(transfers))
+		}
+	}
+	if transfers[0].From != "0x0" || transfers[0].To != adminAccount || transfers[0].Value != 100 {
+		t.Fatalf("mint event is %+v", transfers[0])
+	}
+	if transfers[1].From != adminAccount || transfers[1].To != userAccount || transfers[1].Value != 40 {
+		t.Fatalf("transfer event is %+v", transfers[1])
+	}
+}
+
+func TestERC721Lifecycle(t *testing.T) {
+	conn := dial(t)
+	admin, user := newClient(t, conn, "Admin"), newClient(t, conn, "User1")
+	initialize(t, admin, "erc721", "Collectibles", "CLT")
+	adminAccount := evaluate(t, admin, "erc721", "ClientAccountID")
+	userAccount := evaluate(t, user, "erc721", "ClientAccountID")
+	balance, err := strconv.Atoi(evaluate(t, user, "erc721", "BalanceOf", userAccount))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokenID := fmt.Sprintf("lifecycle-%d", time.Now().UnixNano())
+	submit(t, admin, "erc721", "MintWithTokenURI", tokenID, "https://example.com/"+tokenID+".json")
+	if got := evaluate(t, user, "erc721", "OwnerOf", tokenID); got != adminAccount {
+		t.Fatalf("owner after mint is %s, want %s", got, adminAccount)
+	}
+	submit(t, admin, "erc721", "TransferFrom", adminAccount, userAccount, tokenID)
+
+	if got := evaluate(t, user, "erc721", "OwnerOf", tokenID); got != userAccount {
+		t.Fatalf("owner after transfer is %s, want %s", got, userAccount)
+	}
+	if got := evaluate(t, user, "erc721", "BalanceOf", userAccount); got != strconv.Itoa(balance+1) {
+		t.Fatalf("recipient balance is %s, want %d", got, balance+1)
+	}
+	if got := evaluate(t, user, "erc721", "TokenURI", tokenID); got != "https://example.com/"+tokenID+".json" {
+		t.Fatalf("token URI is %s", got)
+	}
+}
@@ -0,0 +1,38 @@
+# Settings and helpers shared by network.sh and deploy.sh.
+
+NETWORK_DIR=$(cd "$(dirname "${BASH_SOURCE[0]}")/../network" && pwd)
+export FABRIC_VERSION=${FABRIC_VERSION:-2.5}
+CHANNEL=${KUSH_CHANNEL:-kalp}
+PROJECT=kush-devnet
+
+# paths inside the fabric-tools container, where NETWORK_DIR is /network
+ORG1=/network/organizations/peerOrganizations/org1.example.com
+PEER_CA=$ORG1/peers/peer0.org1.example.com/tls/ca.crt
+ORDERER_TLS=/network/organizations/ordererOrganizations/example.com/orderers/orderer.example.com/tls
+
+compose() {
+  docker compose -p "$PROJECT" -f "$NETWORK_DIR/docker-compose.yaml" "$@"
+}
+
+# tools runs a command of the fabric-tools image on the devnet network, as the admin of Org1MSP and
+# as the current user, so the files it writes to /network stay readable by the tests.
+tools() {
+  docker run --rm --user "$(id -u):$(id -g)" --network "$PROJECT" \
+    -v "$NETWORK_DIR:/network" -w /network -e HOME=/tmp \
+    -e CORE_PEER_ADDRESS=peer0.org1.example.com:7051 \
+    -e CORE_PEER_LOCALMSPID=Org1MSP \
+    -e CORE_PEER_MSPCONFIGPATH=$ORG1/users/Admin@org1.example.com/msp \
+    -e CORE_PEER_TLS_ENABLED=true \
+    -e CORE_PEER_TLS_ROOTCERT_FILE=$PEER_CA \
+    "hyperledger/fabric-tools:$FABRIC_VERSION" "$@"
+}
+
+# retry runs its arguments until they succeed, for nodes that are still starting.
+retry() {
+  local attempt
+  for attempt in 1 2 3 4 5 6 7 8 9 10; do
+    "$@" && return 0
+    sleep 2
+  done
+  return 1
+}
@@ -0,0 +1,40 @@
+#!/usr/bin/env bash
+#
+# deploy.sh <chaincode> builds integration/chaincode/<chaincode> into a chaincode service and
+# deploys it on channel kalp of the devnet. Once the chaincode is committed, running it again only
+# rebuilds and restarts the service, which picks up changes to the contract.
+set -euo pipefail
+
+SCRIPTS_DIR=$(cd "$(dirname "$0")" && pwd)
+source "$SCRIPTS_DIR/common.sh"
+
+name=${1:?usage: $0 <chaincode>}
+label=${name}_1.0
+package=packages/$name.tar.gz
+
+# A chaincode-as-a-service package holds no code, only where the peer reaches the service.
+mkdir -p "$NETWORK_DIR/packages/$name"
+(
+  cd "$NETWORK_DIR/packages/$name"
+  printf '{"address":"%s:9999","dial_timeout":"10s","tls_required":false}\n' "$name" > connection.json
+  printf '{"type":"ccaas","label":"%s"}\n' "$label" > metadata.json
+  tar czf code.tar.gz connection.json
+  tar czf "../$name.tar.gz" metadata.json code.tar.gz
+)
+package_id=$(tools peer lifecycle chaincode calculatepackageid "$package")
+
+# the service must know its package ID, which compose reads from <NAME>_PACKAGE_ID
+export "$(echo "$name" | tr '[:lower:]' '[:upper:]')_PACKAGE_ID=$package_id"
+compose --profile chaincode up -d --build "$name"
+
+if tools peer lifecycle chaincode querycommitted --channelID "$CHANNEL" --name "$name" >/dev/null 2>&1; then
+  echo "$name is already committed, restarted its service"
+  exit 0
+fi
+tools peer lifecycle chaincode install "$package"
+tools peer lifecycle chaincode approveformyorg -o orderer.example.com:7050 --tls --cafile "$ORDERER_TLS/ca.crt" \
+  --channelID "$CHANNEL" --name "$name" --version 1.0 --package-id "$package_id" --sequence 1
+tools peer lifecycle chaincode commit -o orderer.example.com:7050 --tls --cafile "$ORDERER_TLS/ca.crt" \
+  --channelID "$CHANNEL" --name "$name" --version 1.0 --sequence 1 \
+  --peerAddresses peer0.org1.example.com:7051 --tlsRootCertFiles "$PEER_CA"
+echo "$name deployed as $package_id"
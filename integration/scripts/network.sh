@@ -0,0 +1,42 @@
+#!/usr/bin/env bash
+#
+# network.sh up|down starts or removes the devnet of the integration tests: an orderer and a peer
+# of Org1MSP on channel kalp, with every chaincode under integration/chaincode deployed as a
+# service. up starts from a clean devnet; down removes its containers, ledgers and identities.
+set -euo pipefail
+
+SCRIPTS_DIR=$(cd "$(dirname "$0")" && pwd)
+source "$SCRIPTS_DIR/common.sh"
+
+up() {
+  docker network create "$PROJECT" >/dev/null
+  tools cryptogen generate --config=crypto-config.yaml --output=organizations
+  tools env FABRIC_CFG_PATH=/network configtxgen -profile KalpDevnet -channelID "$CHANNEL" \
+    -outputBlock "channel-artifacts/$CHANNEL.block"
+  compose up -d orderer.example.com peer0.org1.example.com
+
+  retry tools osnadmin channel join --channelID "$CHANNEL" --config-block "channel-artifacts/$CHANNEL.block" \
+    -o orderer.example.com:7053 --ca-file "$ORDERER_TLS/ca.crt" \
+    --client-cert "$ORDERER_TLS/server.crt" --client-key "$ORDERER_TLS/server.key"
+  retry tools peer channel join -b "channel-artifacts/$CHANNEL.block"
+
+  local dir
+  for dir in "$SCRIPTS_DIR"/../chaincode/*/; do
+    "$SCRIPTS_DIR/deploy.sh" "$(basename "$dir")"
+  done
+}
+
+down() {
+  compose --profile chaincode down --volumes --remove-orphans
+  docker network rm "$PROJECT" >/dev/null 2>&1 || true
+  rm -rf "$NETWORK_DIR/organizations" "$NETWORK_DIR/channel-artifacts" "$NETWORK_DIR/packages"
+}
+
+case "${1:-}" in
+  up) up ;;
+  down) down ;;
+  *)
+    echo "usage: $0 up|down" >&2
+    exit 2
+    ;;
+esac
@@ -0,0 +1,90 @@
+// Package readcache serves repeated world state reads of a transaction from memory.
+//
+// Fabric does not let a transaction read its own writes: every read of a key returns the value
+// committed before the transaction, however often it is repeated. Caching the reads of a transaction
+// therefore changes no result, and a transaction checking the same balance or approval for every leg
+// of a batch reads it once. The cache of a transaction is kept in the state of its context, see
+// txstate, so another invocation reusing the tx ID starts over.
+package readcache
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/internal/txstate"
+)
+
+type txCache struct {
+	states map[string][]byte
+	ranges map[string][]*queryresult.KV
+}
+
+// cacheKey keys the txCache of a transaction in its transaction state.
+type cacheKey struct{}
+
+// cacheFor returns the cache of the transaction of ctx, or nil if ctx carries no transaction
+// state.
+func cacheFor(ctx kalpsdk.TransactionContextInterface) *txCache {
+	tx, err := txstate.Get(ctx, cacheKey{}, func() interface{} {
+		return &txCache{
+			states: make(map[string][]byte),
+			ranges: make(map[string][]*queryresult.KV),
+		}
+	})
+	if err != nil {
+		return nil
+	}
+	return tx.(*txCache)
+}
+
+// GetState returns the world state value of key, reading it once per transaction.
+func GetState(ctx kalpsdk.TransactionContextInterface, key string) ([]byte, error) {
+	tx := cacheFor(ctx)
+	if tx != nil {
+		value, ok := tx.states[key]
+		if ok {
+			return value, nil
+		}
+	}
+	value, err := ctx.GetState(key)
+	if err != nil {
+		return nil, err
+	}
+	if tx != nil {
+		tx.states[key] = value
+	}
+	return value, nil
+}
+
+// GetStateByPartialCompositeKey returns the states under objectType and attributes, reading the
+// range once per transaction.
+func GetStateByPartialCompositeKey(ctx kalpsdk.TransactionContextInterface, objectType string, attributes []string) ([]*queryresult.KV, error) {
+	rangeKey := objectType + "\x00" + strings.Join(attributes, "\x00")
+	tx := cacheFor(ctx)
+	if tx != nil {
+		states, ok := tx.ranges[rangeKey]
+		if ok {
+			return states, nil
+		}
+	}
+
+	iterator, err := ctx.GetStateByPartialCompositeKey(objectType, attributes)
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+	states := []*queryresult.KV{}
+	for iterator.HasNext() {
+		queryResponse, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the next state for prefix %v: %v", objectType, err)
+		}
+		states = append(states, queryResponse)
+	}
+	if tx != nil {
+		tx.ranges[rangeKey] = states
+	}
+	return states, nil
+}
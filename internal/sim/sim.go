@@ -0,0 +1,390 @@
+// Package sim invokes contracts on the in-memory ledger of testutil from the command line, so a
+// contract can be tried out without deploying it to a Kalp network.
+//
+// Every contract has a ledger of its own, as every chaincode has its own namespace on a channel. The
+// ledgers are kept in a JSON state file, read when the simulator starts and written after every
+// command that changes them, so consecutive runs build on each other. A state file may hold ledgers
+// of contracts another simulator binary serves; they are kept untouched.
+//
+// A command invokes a transaction, as a given user, with its arguments in their text form: strings
+// as they are, numbers and booleans as Go parses them, and any other parameter, a config.InitConfig
+// or a []string, as JSON. A transaction that succeeds is committed and prints its result and event;
+// one that fails leaves the ledger unchanged.
+package sim
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/testutil"
+)
+
+// DefaultStateFile is the state file used unless -state names another one.
+const DefaultStateFile = "kush-sim.json"
+
+var (
+	contextType = reflect.TypeOf((*kalpsdk.TransactionContextInterface)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+const usage = `usage: %[1]s [-state file] [-user name] [command]
+
+commands:
+  <contract> <function> [args...]  invoke a transaction as the user
+  functions [contract]             list the transactions of the contracts
+  events <contract>                list the events of the committed transactions
+  kyc <user> [true|false]          record whether user has completed KYC
+  user <name>                      invoke the next transactions as name, in the shell
+
+Without a command, %[1]s reads commands from the standard input, one per line. Arguments may be
+quoted with ' or ".
+
+contracts: %[2]s
+`
+
+// Main runs the simulator named name for contracts, by the name commands use, with the arguments of
+// the process, and exits.
+func Main(name string, contracts map[string]interface{}) {
+	os.Exit(Run(name, contracts, os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// Run runs the simulator with args and returns its exit code.
+func Run(name string, contracts map[string]interface{}, args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	stateFile := flags.String("state", DefaultStateFile, "`file` holding the ledgers")
+	user := flags.String("user", "admin", "`name` of the user invoking transactions")
+	flags.Usage = func() {
+		fmt.Fprintf(stderr, usage, name, strings.Join(contractNames(contracts), ", "))
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	s := &simulator{contracts: contracts, stateFile: *stateFile, user: *user, out: stdout}
+	if err := s.load(); err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", name, err)
+		return 1
+	}
+	if flags.NArg() > 0 {
+		if err := s.execute(flags.Args()); err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", name, err)
+			return 1
+		}
+		return 0
+	}
+
+	scanner := bufio.NewScanner(stdin)
+	for fmt.Fprintf(stdout, "%s> ", s.user); scanner.Scan(); fmt.Fprintf(stdout, "%s> ", s.user) {
+		line, err := splitLine(scanner.Text())
+		if err == nil && len(line) > 0 {
+			if line[0] == "exit" || line[0] == "quit" {
+				return 0
+			}
+			err = s.execute(line)
+		}
+		if err != nil {
+			fmt.Fprintf(stdout, "error: %v\n", err)
+		}
+	}
+	fmt.Fprintln(stdout)
+	if err := scanner.Err(); err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", name, err)
+		return 1
+	}
+	return 0
+}
+
+type simulator struct {
+	contracts map[string]interface{}
+	stateFile string
+	user      string
+	out       io.Writer
+	// ledgers holds the ledger of every contract of the simulator.
+	ledgers map[string]*testutil.Ledger
+	// others holds the ledgers of the state file the simulator does not serve.
+	others map[string]json.RawMessage
+}
+
+func (s *simulator) load() error {
+	s.ledgers = make(map[string]*testutil.Ledger, len(s.contracts))
+	s.others = make(map[string]json.RawMessage)
+	data, err := os.ReadFile(s.stateFile)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &s.others); err != nil {
+			return fmt.Errorf("failed to read state file %s: %v", s.stateFile, err)
+		}
+	}
+	for name := range s.contracts {
+		l := testutil.NewLedger()
+		if data, ok := s.others[name]; ok {
+			if err := json.Unmarshal(data, l); err != nil {
+				return fmt.Errorf("failed to read the ledger of %s from %s: %v", name, s.stateFile, err)
+			}
+			delete(s.others, name)
+		}
+		s.ledgers[name] = l
+	}
+	return nil
+}
+
+// save writes the state file through a temporary file, so an interrupted write does not lose it.
+func (s *simulator) save() error {
+	file := make(map[string]interface{}, len(s.ledgers)+len(s.others))
+	for name, data := range s.others {
+		file[name] = data
+	}
+	for name, l := range s.ledgers {
+		file[name] = l
+	}
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.stateFile), filepath.Base(s.stateFile)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.stateFile)
+}
+
+func (s *simulator) execute(args []string) error {
+	switch args[0] {
+	case "functions":
+		names := contractNames(s.contracts)
+		if len(args) > 1 {
+			if _, ok := s.contracts[args[1]]; !ok {
+				return fmt.Errorf("unknown contract %s", args[1])
+			}
+			names = args[1:2]
+		}
+		for _, name := range names {
+			for _, signature := range transactions(s.contracts[name]) {
+				fmt.Fprintf(s.out, "%s %s\n", name, signature)
+			}
+		}
+		return nil
+	case "events":
+		if len(args) != 2 {
+			return errors.New("usage: events <contract>")
+		}
+		l, ok := s.ledgers[args[1]]
+		if !ok {
+			return fmt.Errorf("unknown contract %s", args[1])
+		}
+		for _, event := range l.Events() {
+			fmt.Fprintf(s.out, "%s %s %s\n", event.TxID, event.Name, event.Payload)
+		}
+		return nil
+	case "kyc":
+		if len(args) < 2 || len(args) > 3 {
+			return errors.New("usage: kyc <user> [true|false]")
+		}
+		done := true
+		if len(args) == 3 {
+			var err error
+			if done, err = strconv.ParseBool(args[2]); err != nil {
+				return fmt.Errorf("invalid KYC status %q", args[2])
+			}
+		}
+		for _, l := range s.ledgers {
+			l.SetKYC(args[1], done)
+		}
+		return s.save()
+	case "user":
+		if len(args) != 2 {
+			return errors.New("usage: user <name>")
+		}
+		s.user = args[1]
+		return nil
+	}
+
+	if len(args) < 2 {
+		return fmt.Errorf("unknown command %s", args[0])
+	}
+	if err := s.invoke(args[0], args[1], args[2:]); err != nil {
+		return err
+	}
+	return s.save()
+}
+
+// invoke runs function of contract with args as one transaction of the user, and commits it if it
+// succeeds.
+func (s *simulator) invoke(contract string, function string, args []string) error {
+	c, ok := s.contracts[contract]
+	if !ok {
+		return fmt.Errorf("unknown contract %s", contract)
+	}
+	method := reflect.ValueOf(c).MethodByName(function)
+	if !method.IsValid() || !isTransaction(method.Type()) {
+		return fmt.Errorf("contract %s has no transaction %s", contract, function)
+	}
+	t := method.Type()
+	if len(args) != t.NumIn()-1 {
+		return fmt.Errorf("%s takes %d arguments: %s", function, t.NumIn()-1, signature(function, t))
+	}
+
+	ctx := s.ledgers[contract].Tx(s.user, function, args...)
+	in := []reflect.Value{reflect.ValueOf(ctx)}
+	for i, arg := range args {
+		value, err := parseArg(arg, t.In(i+1))
+		if err != nil {
+			return fmt.Errorf("argument %d of %s: %v", i+1, function, err)
+		}
+		in = append(in, value)
+	}
+	out := method.Call(in)
+	if err, _ := out[len(out)-1].Interface().(error); err != nil {
+		return err
+	}
+	eventName, eventPayload := ctx.EventName, ctx.EventPayload
+	ctx.Commit()
+
+	if len(out) == 2 {
+		result := out[0].Interface()
+		if text, ok := result.(string); ok {
+			fmt.Fprintln(s.out, text)
+		} else {
+			data, err := json.Marshal(result)
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(s.out, "%s\n", data)
+		}
+	}
+	if eventName != "" {
+		fmt.Fprintf(s.out, "event %s %s\n", eventName, eventPayload)
+	}
+	return nil
+}
+
+// isTransaction reports whether t is the type of a contract method chaincode clients can invoke:
+// one taking the transaction context first and returning an error last, after at most one result.
+func isTransaction(t reflect.Type) bool {
+	return t.NumIn() >= 1 && t.In(0) == contextType &&
+		(t.NumOut() == 1 || t.NumOut() == 2) && t.Out(t.NumOut()-1) == errorType
+}
+
+// transactions returns the signatures of the transactions of contract, by name.
+func transactions(contract interface{}) []string {
+	var signatures []string
+	v := reflect.ValueOf(contract)
+	for i := 0; i < v.NumMethod(); i++ {
+		if t := v.Method(i).Type(); isTransaction(t) {
+			signatures = append(signatures, signature(v.Type().Method(i).Name, t))
+		}
+	}
+	return signatures
+}
+
+func signature(function string, t reflect.Type) string {
+	params := make([]string, 0, t.NumIn()-1)
+	for i := 1; i < t.NumIn(); i++ {
+		params = append(params, t.In(i).String())
+	}
+	return function + "(" + strings.Join(params, ", ") + ")"
+}
+
+func parseArg(arg string, t reflect.Type) (reflect.Value, error) {
+	v := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.String:
+		v.SetString(arg)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(arg)
+		if err != nil {
+			return v, err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(arg, 10, t.Bits())
+		if err != nil {
+			return v, err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(arg, 10, t.Bits())
+		if err != nil {
+			return v, err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(arg, t.Bits())
+		if err != nil {
+			return v, err
+		}
+		v.SetFloat(f)
+	default:
+		if err := json.Unmarshal([]byte(arg), v.Addr().Interface()); err != nil {
+			return v, fmt.Errorf("invalid %s: %v", t, err)
+		}
+	}
+	return v, nil
+}
+
+// splitLine splits a line of the shell into its arguments, separated by spaces unless they are
+// quoted.
+func splitLine(line string) ([]string, error) {
+	var args []string
+	var arg strings.Builder
+	var quote rune
+	inArg := false
+	for _, r := range line {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			arg.WriteRune(r)
+		case r == '\'' || r == '"':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
+
+func contractNames(contracts map[string]interface{}) []string {
+	names := make([]string, 0, len(contracts))
+	for name := range contracts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
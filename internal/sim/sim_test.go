@@ -0,0 +1,89 @@
+package sim
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/thekalpstudio/kush-go/Contracts/token"
+)
+
+var contracts = map[string]interface{}{"erc20": new(token.TokenERC20Contract)}
+
+func run(t *testing.T, stateFile string, args ...string) (string, int) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	code := Run("kush-sim", contracts, append([]string{"-state", stateFile}, args...), strings.NewReader(""), &stdout, &stderr)
+	return stdout.String() + stderr.String(), code
+}
+
+func TestStatePersistsAcrossRuns(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	commands := [][]string{
+		{"erc20", "Initialize", `{"name":"Kush","symbol":"KSH","authorizedOrgs":["Org1MSP"]}`},
+		{"erc20", "Mint", "1000"},
+		{"erc20", "Transfer", "bob", "10"},
+	}
+	for _, command := range commands {
+		if out, code := run(t, stateFile, command...); code != 0 {
+			t.Fatalf("%v exited with %d: %s", command, code, out)
+		}
+	}
+
+	out, code := run(t, stateFile, "-user", "bob", "erc20", "ClientAccountBalance")
+	if code != 0 || out != "10\n" {
+		t.Fatalf("balance of bob is %q, exit code %d", out, code)
+	}
+	out, code = run(t, stateFile, "erc20", "Transfer", "bob", "5000")
+	if code != 1 {
+		t.Fatalf("overdrawn transfer exited with %d: %s", code, out)
+	}
+	out, _ = run(t, stateFile, "erc20", "BalanceOf", "admin")
+	if out != "990\n" {
+		t.Fatalf("failed transfer left the balance of admin at %q", out)
+	}
+	out, _ = run(t, stateFile, "events", "erc20")
+	if strings.Count(out, " Transfer ") != 2 || !strings.Contains(out, "tx00000003 ") {
+		t.Fatalf("events are %q", out)
+	}
+}
+
+func TestShell(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	input := `erc20 Initialize '{"name":"Kush","symbol":"KSH","authorizedOrgs":["Org1MSP"]}'
+erc20 Mint 1 2
+user bob
+erc20 ClientAccountID
+`
+	var stdout, stderr bytes.Buffer
+	code := Run("kush-sim", contracts, []string{"-state", stateFile}, strings.NewReader(input), &stdout, &stderr)
+	if code != 0 {
+		t.Fatalf("shell exited with %d: %s", code, stderr.String())
+	}
+	out := stdout.String()
+	if !strings.Contains(out, "error: Mint takes 1 arguments") || !strings.Contains(out, "bob> bob\n") {
+		t.Fatalf("shell printed %q", out)
+	}
+}
+
+func TestSplitLine(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    []string
+		wantErr bool
+	}{
+		{line: "erc20  Transfer\tbob 10", want: []string{"erc20", "Transfer", "bob", "10"}},
+		{line: `erc20 Initialize '{"name":"K K"}'`, want: []string{"erc20", "Initialize", `{"name":"K K"}`}},
+		{line: `kyc "" false`, want: []string{"kyc", "", "false"}},
+		{line: "", want: nil},
+		{line: `erc20 Approve "bob`, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := splitLine(tt.line)
+		if (err != nil) != tt.wantErr || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitLine(%q) = %q, %v", tt.line, got, err)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+// Package statebatch collects the writes of a transaction and flushes them at once.
+//
+// Fabric does not let a transaction read its own writes, so code that reads a key it wrote earlier
+// in the same transaction sees the old value. A Batch answers Get from its pending writes first and
+// keeps only the last write to each key, so a key deleted and then rewritten is written once. Flush
+// resolves the KYC mode of the current function once for all writes, instead of once per write as
+// accesscontrol.PutState and accesscontrol.DelState do.
+package statebatch
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+)
+
+type write struct {
+	value   []byte
+	deleted bool
+}
+
+// Batch holds the pending writes of one transaction. It is not safe for concurrent use.
+type Batch struct {
+	ctx    kalpsdk.TransactionContextInterface
+	writes map[string]write
+}
+
+// New returns an empty batch writing to the world state of ctx.
+func New(ctx kalpsdk.TransactionContextInterface) *Batch {
+	return &Batch{ctx: ctx, writes: make(map[string]write)}
+}
+
+// Get returns the pending value of key, nil if it is pending deletion, or else its world state value.
+func (b *Batch) Get(key string) ([]byte, error) {
+	if pending, ok := b.writes[key]; ok {
+		return pending.value, nil
+	}
+	value, err := b.ctx.GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s from world state: %v", key, err)
+	}
+	return value, nil
+}
+
+// Put records a write of value to key, replacing any pending write of key.
+func (b *Batch) Put(key string, value []byte) {
+	b.writes[key] = write{value: value}
+}
+
+// Del records the deletion of key, replacing any pending write of key.
+func (b *Batch) Del(key string) {
+	b.writes[key] = write{deleted: true}
+}
+
+// Deleted reports whether key is pending deletion.
+func (b *Batch) Deleted(key string) bool {
+	return b.writes[key].deleted
+}
+
+// Len returns the number of keys with a pending write.
+func (b *Batch) Len() int {
+	return len(b.writes)
+}
+
+// Flush writes the pending writes in key order and empties the batch.
+func (b *Batch) Flush() error {
+	if len(b.writes) == 0 {
+		return nil
+	}
+	required, err := accesscontrol.KYCRequired(b.ctx)
+	if err != nil {
+		return err
+	}
+	keys := make([]string, 0, len(b.writes))
+	for key := range b.writes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		pending := b.writes[key]
+		switch {
+		case pending.deleted && required:
+			err = b.ctx.DelStateWithKYC(key)
+		case pending.deleted:
+			err = b.ctx.DelStateWithoutKYC(key)
+		case required:
+			err = b.ctx.PutStateWithKYC(key, pending.value)
+		default:
+			err = b.ctx.PutStateWithoutKYC(key, pending.value)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write %s: %v", key, err)
+		}
+	}
+	b.writes = make(map[string]write)
+	return nil
+}
@@ -0,0 +1,55 @@
+// Package txstate keeps the in-memory state of a transaction on its transaction context.
+//
+// Fabric does not let a transaction read its own writes, so code called several times by one
+// transaction, such as the metrics of a batch transfer, remembers what it already wrote. That state
+// belongs to the context of the transaction: contractapi creates a new context for every
+// invocation, so a re-simulated proposal or a transaction reusing a tx ID starts over, and the state
+// of a failed transaction goes away with its context.
+package txstate
+
+import (
+	"fmt"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+)
+
+// Carrier is a transaction context holding the state of its transaction.
+type Carrier interface {
+	kalpsdk.TransactionContextInterface
+	// TxState returns the state of the transaction, by key. It is never nil.
+	TxState() map[interface{}]interface{}
+}
+
+// Context is the kalpsdk transaction context with the state of its transaction. logging.Contract
+// has contractapi create one for every invocation.
+type Context struct {
+	kalpsdk.TransactionContext
+	state map[interface{}]interface{}
+}
+
+var _ Carrier = (*Context)(nil)
+
+// TxState returns the state of the transaction, by key.
+func (c *Context) TxState() map[interface{}]interface{} {
+	if c.state == nil {
+		c.state = make(map[interface{}]interface{})
+	}
+	return c.state
+}
+
+// Get returns the value stored under key for the transaction of ctx, storing the one create
+// returns on first use. Keys should be of an unexported type of the calling package, so packages
+// cannot collide. Get fails if ctx does not carry transaction state.
+func Get(ctx kalpsdk.TransactionContextInterface, key interface{}, create func() interface{}) (interface{}, error) {
+	carrier, ok := ctx.(Carrier)
+	if !ok {
+		return nil, fmt.Errorf("transaction context %T does not carry transaction state", ctx)
+	}
+	state := carrier.TxState()
+	value, ok := state[key]
+	if !ok {
+		value = create()
+		state[key] = value
+	}
+	return value, nil
+}
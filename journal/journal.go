@@ -0,0 +1,208 @@
+// Package journal keeps an opt-in record of rejected operations, so compliance can watch for
+// abuse patterns without scraping peer logs.
+//
+// Fabric discards every write of a failed transaction, so a contract cannot record its own
+// rejections. Instead the gateways that submit transactions report the rejected ones: a
+// REPORTER_ROLE holder calls Report with the tx ID, the caller and the error, whose kusherrors code
+// classifies the rejection. Entries are keyed by account and report time, so GetRejections reads
+// the rejections of an account in chronological order.
+package journal
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+	"github.com/thekalpstudio/kush-go/audit"
+	"github.com/thekalpstudio/kush-go/canonical"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+	"github.com/thekalpstudio/kush-go/pagination"
+)
+
+const configKey = "journal~config"
+const rejectionPrefix = "journal~rejection"
+const reportedPrefix = "journal~tx"
+
+// ReporterRole may report rejected operations. It is granted to the gateways of the channel.
+const ReporterRole = "REPORTER_ROLE"
+
+// Config switches the journal on. Rejections moving less than MinAmount base units are not
+// recorded; rejections without an amount, such as those of ERC721 operations, always are.
+type Config struct {
+	Enabled   bool   `json:"enabled"`
+	MinAmount string `json:"minAmount,omitempty" metadata:",optional"`
+}
+
+// Rejection is a rejected operation. Code is the kusherrors code of Reason, the error returned to
+// the caller; Amount is "" for operations that do not move an amount.
+type Rejection struct {
+	TxID      string          `json:"txId"`
+	Account   string          `json:"account"`
+	Function  string          `json:"function"`
+	Amount    string          `json:"amount,omitempty" metadata:",optional"`
+	Code      kusherrors.Code `json:"code"`
+	Reason    string          `json:"reason"`
+	Reporter  string          `json:"reporter"`
+	Timestamp int64           `json:"timestamp"`
+}
+
+// Page is a page of rejections. Bookmark is passed to GetRejections to read the next page and is
+// empty on the last one.
+type Page struct {
+	Rejections []*Rejection `json:"rejections"`
+	Bookmark   string       `json:"bookmark"`
+}
+
+// SetConfig stores the journal configuration. The caller must hold AdminRole.
+func SetConfig(ctx kalpsdk.TransactionContextInterface, cfg Config) error {
+	err := accesscontrol.CheckRole(ctx, accesscontrol.AdminRole)
+	if err != nil {
+		return err
+	}
+	_, err = parseMinAmount(cfg.MinAmount)
+	if err != nil {
+		return err
+	}
+	cfgJSON, err := canonical.Marshal(cfg)
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	err = ctx.PutStateWithoutKYC(configKey, cfgJSON)
+	if err != nil {
+		return fmt.Errorf("failed to set journal config: %v", err)
+	}
+	return audit.Record(ctx, "SetJournalConfig", string(cfgJSON))
+}
+
+// GetConfig returns the journal configuration; the journal is disabled until SetConfig is called.
+func GetConfig(ctx kalpsdk.TransactionContextInterface) (*Config, error) {
+	cfgBytes, err := ctx.GetState(configKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read journal config: %v", err)
+	}
+	cfg := new(Config)
+	if cfgBytes == nil {
+		return cfg, nil
+	}
+	err = json.Unmarshal(cfgBytes, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode journal config: %v", err)
+	}
+	return cfg, nil
+}
+
+// Report records the rejected transaction txID, in which account called function, moving amount,
+// and was refused with reason. It returns false without recording anything when the journal is
+// disabled or amount is below the minimum. The caller must hold ReporterRole, and each rejected
+// transaction is recorded once.
+func Report(ctx kalpsdk.TransactionContextInterface, txID string, account string, function string, amount string, reason string) (bool, error) {
+	err := accesscontrol.CheckRole(ctx, ReporterRole)
+	if err != nil {
+		return false, err
+	}
+	if txID == "" || account == "" || function == "" {
+		return false, fmt.Errorf("tx id, account and function must be set")
+	}
+	code := kusherrors.Parse(reason)
+	if code == "" {
+		return false, fmt.Errorf("reason %q carries no error code", reason)
+	}
+
+	cfg, err := GetConfig(ctx)
+	if err != nil {
+		return false, err
+	}
+	if !cfg.Enabled {
+		return false, nil
+	}
+	if amount != "" {
+		value, ok := new(big.Int).SetString(amount, 10)
+		if !ok || value.Sign() < 0 {
+			return false, fmt.Errorf("amount %q must be a non-negative integer", amount)
+		}
+		minAmount, err := parseMinAmount(cfg.MinAmount)
+		if err != nil {
+			return false, err
+		}
+		if minAmount != nil && value.Cmp(minAmount) < 0 {
+			return false, nil
+		}
+	}
+
+	reportedKey, err := ctx.CreateCompositeKey(reportedPrefix, []string{txID})
+	if err != nil {
+		return false, fmt.Errorf("failed to create the composite key for prefix %s: %v", reportedPrefix, err)
+	}
+	reportedBytes, err := ctx.GetState(reportedKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to read report of tx %s: %v", txID, err)
+	}
+	if reportedBytes != nil {
+		return false, fmt.Errorf("rejection of tx %s is already recorded", txID)
+	}
+
+	reporter, err := ctx.GetUserID()
+	if err != nil {
+		return false, fmt.Errorf("failed to get client id: %v", err)
+	}
+	timestamp, err := ctx.GetTxTimestamp()
+	if err != nil {
+		return false, fmt.Errorf("failed to get transaction timestamp: %v", err)
+	}
+	rejection := &Rejection{txID, account, function, amount, code, reason, reporter, timestamp.GetSeconds()}
+
+	rejectionKey, err := ctx.CreateCompositeKey(rejectionPrefix, []string{account, fmt.Sprintf("%020d", rejection.Timestamp), txID})
+	if err != nil {
+		return false, fmt.Errorf("failed to create the composite key for prefix %s: %v", rejectionPrefix, err)
+	}
+	rejectionJSON, err := canonical.Marshal(rejection)
+	if err != nil {
+		return false, fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	err = ctx.PutStateWithoutKYC(rejectionKey, rejectionJSON)
+	if err != nil {
+		return false, fmt.Errorf("failed to record rejection of tx %s: %v", txID, err)
+	}
+	err = ctx.PutStateWithoutKYC(reportedKey, []byte(rejectionKey))
+	if err != nil {
+		return false, fmt.Errorf("failed to record rejection of tx %s: %v", txID, err)
+	}
+	return true, nil
+}
+
+// GetRejections returns up to pageSize rejections of account starting at bookmark, oldest first.
+// An empty account reads the rejections of every account, ordered by account. It uses paginated
+// queries, so it has to be evaluated, not submitted.
+func GetRejections(ctx kalpsdk.TransactionContextInterface, account string, bookmark string, pageSize int) (*Page, error) {
+	attributes := []string{}
+	if account != "" {
+		attributes = append(attributes, account)
+	}
+	states, next, err := pagination.ByPartialCompositeKey(ctx, rejectionPrefix, attributes, bookmark, pageSize)
+	if err != nil {
+		return nil, err
+	}
+	page := &Page{Rejections: []*Rejection{}, Bookmark: next}
+	for _, state := range states {
+		rejection := new(Rejection)
+		err = json.Unmarshal(state.Value, rejection)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode rejection %s: %v", state.Key, err)
+		}
+		page.Rejections = append(page.Rejections, rejection)
+	}
+	return page, nil
+}
+
+func parseMinAmount(minAmount string) (*big.Int, error) {
+	if minAmount == "" {
+		return nil, nil
+	}
+	value, ok := new(big.Int).SetString(minAmount, 10)
+	if !ok || value.Sign() < 0 {
+		return nil, fmt.Errorf("min amount %q must be a non-negative integer", minAmount)
+	}
+	return value, nil
+}
@@ -0,0 +1,71 @@
+// Package kusherrors defines the machine-readable codes carried by the errors of the contracts.
+//
+// A coded error reads "<CODE>: <description>". Fabric hands clients the error message only, so
+// gateways recover the code with Parse instead of matching the description, which may change. In
+// Go the codes are sentinel errors: errors.Is(err, ErrUnauthorized) holds when the coded error was
+// wrapped with %w, and CodeOf also finds codes wrapped with %v.
+package kusherrors
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Code identifies a class of failures. It is the sentinel error of that class.
+type Code string
+
+func (c Code) Error() string {
+	return string(c)
+}
+
+// Error codes.
+const (
+	// ErrNotInitialized is returned by every function of a contract before Initialize.
+	ErrNotInitialized Code = "ERR_NOT_INITIALIZED"
+	// ErrInsufficientBalance is returned when an account holds less than a transaction moves.
+	ErrInsufficientBalance Code = "ERR_INSUFFICIENT_BALANCE"
+	// ErrUnauthorized is returned when the caller lacks the role, org or ownership an action needs.
+	ErrUnauthorized Code = "ERR_UNAUTHORIZED"
+	// ErrOverflow is returned when an amount would leave the range of its type.
+	ErrOverflow Code = "ERR_OVERFLOW"
+	// ErrBalanceFragmented is returned when a balance spans more records than a transaction may
+	// read; compacting the balance lets the transaction through.
+	ErrBalanceFragmented Code = "ERR_BALANCE_FRAGMENTED"
+	// ErrInvalidAccount is returned for account IDs that would address other state than a balance.
+	ErrInvalidAccount Code = "ERR_INVALID_ACCOUNT"
+)
+
+// Codes lists every code, for gateways mapping them to their own errors.
+var Codes = []Code{ErrNotInitialized, ErrInsufficientBalance, ErrUnauthorized, ErrOverflow, ErrBalanceFragmented, ErrInvalidAccount}
+
+// Errorf formats an error carrying code.
+func Errorf(code Code, format string, args ...interface{}) error {
+	return fmt.Errorf("%w: "+format, append([]interface{}{code}, args...)...)
+}
+
+// CodeOf returns the outermost code of err, or "" if it carries none.
+func CodeOf(err error) Code {
+	if err == nil {
+		return ""
+	}
+	var code Code
+	if errors.As(err, &code) {
+		return code
+	}
+	return Parse(err.Error())
+}
+
+// Parse returns the first code in an error message, or "" if it carries none. The first code is
+// the outermost one, since wrapping prepends context.
+func Parse(message string) Code {
+	var first Code
+	firstIndex := -1
+	for _, code := range Codes {
+		index := strings.Index(message, string(code))
+		if index >= 0 && (firstIndex < 0 || index < firstIndex) {
+			first, firstIndex = code, index
+		}
+	}
+	return first
+}
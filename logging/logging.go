@@ -0,0 +1,162 @@
+// Package logging writes structured peer logs for the transactions of the contracts.
+//
+// Contracts embed Contract instead of kalpsdk.Contract. Its transaction hooks log a line when a
+// transaction starts and one when it completes, with the tx ID, function, actor and duration as
+// key=value pairs, through the kalpsdk chaincode logger. Fabric skips the after hook of a failed
+// transaction, so a start line without a matching end line marks a failure; the peer logs its
+// error.
+//
+// The level is read from KUSH_LOG_LEVEL when the chaincode starts, not from state: a key every
+// transaction read would make each change of the level conflict with the transactions in flight.
+// SetLogLevel changes it on the peer evaluating it, until the chaincode restarts.
+package logging
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric-contract-api-go/contractapi"
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+	"github.com/thekalpstudio/kush-go/internal/txstate"
+)
+
+// LevelEnv names the environment variable holding the log level the chaincode starts with.
+const LevelEnv = "KUSH_LOG_LEVEL"
+
+// DefaultLevel is used when LevelEnv is unset or not one of Levels.
+const DefaultLevel = "info"
+
+// Levels lists the accepted log levels, most verbose first.
+var Levels = []string{"trace", "debug", "info", "warning", "error"}
+
+var logger = kalpsdk.NewLogger()
+
+var (
+	levelMu sync.Mutex
+	level   = DefaultLevel
+)
+
+func init() {
+	if envLevel := os.Getenv(LevelEnv); validLevel(envLevel) {
+		level = envLevel
+	}
+	logger.SetChaincodeLogLevel(level)
+}
+
+// Contract adds transaction logging to kalpsdk.Contract.
+type Contract struct {
+	kalpsdk.Contract
+}
+
+// GetTransactionContextHandler returns the context contractapi creates for every invocation: a
+// txstate.Context, which keeps the in-memory state of the transaction, unless
+// TransactionContextHandler is set.
+func (c *Contract) GetTransactionContextHandler() contractapi.SettableTransactionContextInterface {
+	if c.TransactionContextHandler == nil {
+		return new(txstate.Context)
+	}
+	return c.TransactionContextHandler
+}
+
+// GetBeforeTransaction returns the kalpsdk before hook, extended to log the start of every
+// transaction.
+func (c *Contract) GetBeforeTransaction() interface{} {
+	next, _ := c.Contract.GetBeforeTransaction().(func(kalpsdk.TransactionContextInterface) error)
+	return func(ctx kalpsdk.TransactionContextInterface) error {
+		start(ctx)
+		if next != nil {
+			return next(ctx)
+		}
+		return nil
+	}
+}
+
+// GetAfterTransaction returns the kalpsdk after hook, extended to log the end of every
+// transaction.
+func (c *Contract) GetAfterTransaction() interface{} {
+	next, _ := c.Contract.GetAfterTransaction().(func(kalpsdk.TransactionContextInterface) error)
+	return func(ctx kalpsdk.TransactionContextInterface) error {
+		var err error
+		if next != nil {
+			err = next(ctx)
+		}
+		finish(ctx, err)
+		return err
+	}
+}
+
+// SetLogLevel changes the log level of the peer evaluating it to level, one of Levels, until the
+// chaincode restarts. It writes nothing, so evaluate it on every peer to change. The caller must
+// hold AdminRole.
+func SetLogLevel(ctx kalpsdk.TransactionContextInterface, newLevel string) error {
+	err := accesscontrol.CheckRole(ctx, accesscontrol.AdminRole)
+	if err != nil {
+		return err
+	}
+	if !validLevel(newLevel) {
+		return fmt.Errorf("log level %q must be one of %v", newLevel, Levels)
+	}
+	levelMu.Lock()
+	defer levelMu.Unlock()
+	level = newLevel
+	logger.SetChaincodeLogLevel(level)
+	return nil
+}
+
+// GetLogLevel returns the log level of the peer evaluating it.
+func GetLogLevel(ctx kalpsdk.TransactionContextInterface) (string, error) {
+	levelMu.Lock()
+	defer levelMu.Unlock()
+	return level, nil
+}
+
+func validLevel(level string) bool {
+	for _, valid := range Levels {
+		if level == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// startedKey keys the start time of a transaction in its transaction state.
+type startedKey struct{}
+
+func startTime(ctx kalpsdk.TransactionContextInterface) *time.Time {
+	at, err := txstate.Get(ctx, startedKey{}, func() interface{} { return new(time.Time) })
+	if err != nil {
+		// a context without transaction state is still logged, without its duration
+		return new(time.Time)
+	}
+	return at.(*time.Time)
+}
+
+func start(ctx kalpsdk.TransactionContextInterface) {
+	*startTime(ctx) = time.Now()
+
+	logger.Debug(fields(ctx), " msg=started")
+}
+
+func finish(ctx kalpsdk.TransactionContextInterface, err error) {
+	duration := "unknown"
+	if at := startTime(ctx); !at.IsZero() {
+		duration = time.Since(*at).String()
+	}
+	if err != nil {
+		logger.Error(fields(ctx), " duration=", duration, " msg=failed err=", fmt.Sprintf("%q", err.Error()))
+		return
+	}
+	logger.Info(fields(ctx), " duration=", duration, " msg=completed")
+}
+
+func fields(ctx kalpsdk.TransactionContextInterface) string {
+	function, _ := ctx.GetFunctionAndParameters()
+	actor, err := ctx.GetUserID()
+	if err != nil {
+		actor = "unknown"
+	}
+	return fmt.Sprintf("tx=%s fn=%s actor=%s", ctx.GetTxID(), function, actor)
+}
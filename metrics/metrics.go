@@ -0,0 +1,368 @@
+// Package metrics keeps activity counters of a contract in state, so dashboards read them with a
+// query instead of replaying the blocks.
+//
+// A single counter key would be written by every transfer and make concurrent transfers conflict
+// at validation. Each transaction therefore writes its own delta under its tx ID, GetMetrics adds
+// the deltas to the stored totals, and CompactMetrics folds them into the totals from time to time.
+// An account is counted once, the first time it sends or receives tokens.
+//
+// Hold also keeps an index of the accounts holding a fungible token, paged through by ListHolders
+// and counted by the Holders counter.
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/accesscontrol"
+	"github.com/thekalpstudio/kush-go/audit"
+	"github.com/thekalpstudio/kush-go/canonical"
+	"github.com/thekalpstudio/kush-go/internal/txstate"
+	"github.com/thekalpstudio/kush-go/pagination"
+)
+
+const totalsKey = "metrics~totals"
+const deltaPrefix = "metrics~delta"
+const accountPrefix = "metrics~account"
+const holderPrefix = "metrics~holder"
+
+// zeroAddress is the counterparty of mints and burns.
+const zeroAddress = "0x0"
+
+// maxCompact bounds the deltas folded by one CompactMetrics call, so its write set stays
+// reasonable.
+const maxCompact = 500
+
+// Metrics are the activity counters of a contract.
+type Metrics struct {
+	Transfers uint64 `json:"transfers"`
+	Mints     uint64 `json:"mints"`
+	Burns     uint64 `json:"burns"`
+	Accounts  uint64 `json:"accounts"`
+	// Holders is the number of accounts with a positive balance, for contracts that call Hold.
+	Holders int64 `json:"holders,omitempty" metadata:",optional"`
+}
+
+func (m *Metrics) add(other *Metrics) {
+	m.Transfers += other.Transfers
+	m.Mints += other.Mints
+	m.Burns += other.Burns
+	m.Accounts += other.Accounts
+	m.Holders += other.Holders
+}
+
+type txMetrics struct {
+	delta   Metrics
+	touched map[string]bool
+	// holding is whether the accounts whose balance the transaction wrote hold tokens afterwards.
+	holding map[string]bool
+	// tokenHolding is the same by holder key of a token type, and tokenHolders the change of the
+	// holder count of each token type.
+	tokenHolding map[string]bool
+	tokenHolders map[string]int64
+}
+
+// txMetricsKey keys the txMetrics of a transaction in its transaction state. World state reads
+// do not see earlier writes of the same transaction, so a transaction counting several operations
+// accumulates them there and rewrites its delta.
+type txMetricsKey struct{}
+
+func pendingFor(ctx kalpsdk.TransactionContextInterface) (*txMetrics, error) {
+	tx, err := txstate.Get(ctx, txMetricsKey{}, func() interface{} {
+		return &txMetrics{
+			touched:      make(map[string]bool),
+			holding:      make(map[string]bool),
+			tokenHolding: make(map[string]bool),
+			tokenHolders: make(map[string]int64),
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metrics of the transaction: %v", err)
+	}
+	return tx.(*txMetrics), nil
+}
+
+// IsDerivedKey reports whether key is one of the simple keys metrics derives from the activity of a
+// deployment, which a state import must not carry over from the previous deployment: the holders
+// of the imported balances are recorded again with Hold and HoldToken.
+func IsDerivedKey(key string) bool {
+	return key == totalsKey
+}
+
+// Count counts one operation moving tokens from from to each of tos: a mint when from is "0x0", a
+// burn when the recipient is "0x0" and a transfer otherwise.
+func Count(ctx kalpsdk.TransactionContextInterface, from string, tos ...string) error {
+	txID := ctx.GetTxID()
+	tx, err := pendingFor(ctx)
+	if err != nil {
+		return err
+	}
+	for _, to := range tos {
+		switch {
+		case from == zeroAddress:
+			tx.delta.Mints++
+		case to == zeroAddress:
+			tx.delta.Burns++
+		default:
+			tx.delta.Transfers++
+		}
+	}
+
+	for _, account := range append([]string{from}, tos...) {
+		if account == zeroAddress || account == "" || tx.touched[account] {
+			continue
+		}
+		tx.touched[account] = true
+		accountKey, err := ctx.CreateCompositeKey(accountPrefix, []string{account})
+		if err != nil {
+			return fmt.Errorf("failed to create the composite key for prefix %s: %v", accountPrefix, err)
+		}
+		markerBytes, err := ctx.GetState(accountKey)
+		if err != nil {
+			return fmt.Errorf("failed to read metrics of account %s: %v", account, err)
+		}
+		if markerBytes != nil {
+			continue
+		}
+		err = ctx.PutStateWithoutKYC(accountKey, []byte(txID))
+		if err != nil {
+			return fmt.Errorf("failed to write metrics of account %s: %v", account, err)
+		}
+		tx.delta.Accounts++
+	}
+
+	deltaKey, err := ctx.CreateCompositeKey(deltaPrefix, []string{txID})
+	if err != nil {
+		return fmt.Errorf("failed to create the composite key for prefix %s: %v", deltaPrefix, err)
+	}
+	return writeMetrics(ctx, deltaKey, &tx.delta)
+}
+
+// Hold records whether account holds tokens after its balance was written, adding it to or
+// removing it from the holder index. Accounts whose balance has not been written since the index
+// was introduced are missing from it.
+func Hold(ctx kalpsdk.TransactionContextInterface, account string, holding bool) error {
+	txID := ctx.GetTxID()
+	tx, err := pendingFor(ctx)
+	if err != nil {
+		return err
+	}
+	holderKey, err := ctx.CreateCompositeKey(holderPrefix, []string{account})
+	if err != nil {
+		return fmt.Errorf("failed to create the composite key for prefix %s: %v", holderPrefix, err)
+	}
+	held, ok := tx.holding[account]
+	if !ok {
+		markerBytes, err := ctx.GetState(holderKey)
+		if err != nil {
+			return fmt.Errorf("failed to read holder %s: %v", account, err)
+		}
+		held = markerBytes != nil
+	}
+	tx.holding[account] = holding
+	switch {
+	case holding && !held:
+		err = ctx.PutStateWithoutKYC(holderKey, []byte(txID))
+		tx.delta.Holders++
+	case !holding && held:
+		err = ctx.DelStateWithoutKYC(holderKey)
+		tx.delta.Holders--
+	default:
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write holder %s: %v", account, err)
+	}
+
+	deltaKey, err := ctx.CreateCompositeKey(deltaPrefix, []string{txID})
+	if err != nil {
+		return fmt.Errorf("failed to create the composite key for prefix %s: %v", deltaPrefix, err)
+	}
+	return writeMetrics(ctx, deltaKey, &tx.delta)
+}
+
+// ListHolders returns up to pageSize holders starting at bookmark, and the bookmark of the next
+// page.
+func ListHolders(ctx kalpsdk.TransactionContextInterface, bookmark string, pageSize int) ([]string, string, error) {
+	states, next, err := pagination.ByPartialCompositeKey(ctx, holderPrefix, []string{}, bookmark, pageSize)
+	if err != nil {
+		return nil, "", err
+	}
+	holders := make([]string, 0, len(states))
+	for _, state := range states {
+		_, parts, err := ctx.SplitCompositeKey(state.Key)
+		if err != nil || len(parts) != 1 {
+			return nil, "", fmt.Errorf("invalid holder key %s", state.Key)
+		}
+		holders = append(holders, parts[0])
+	}
+	return holders, next, nil
+}
+
+// AllHolders returns every holder in lexical order. Unlike ListHolders it reads the index in one
+// range query, so it also works in transactions that write.
+func AllHolders(ctx kalpsdk.TransactionContextInterface) ([]string, error) {
+	iterator, err := ctx.GetStateByPartialCompositeKey(holderPrefix, []string{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get state for prefix %v: %v", holderPrefix, err)
+	}
+	defer iterator.Close()
+
+	holders := []string{}
+	for iterator.HasNext() {
+		queryResponse, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the next state for prefix %v: %v", holderPrefix, err)
+		}
+		_, parts, err := ctx.SplitCompositeKey(queryResponse.Key)
+		if err != nil || len(parts) != 1 {
+			return nil, fmt.Errorf("invalid holder key %s", queryResponse.Key)
+		}
+		holders = append(holders, parts[0])
+	}
+	return holders, nil
+}
+
+// HoldersAfter returns up to limit holders following the holder after, "" for the first, in
+// lexical order, and reports whether none is left. Like AllHolders it works in transactions that
+// write, which Fabric only serves composite key ranges from their partial key, so it reads the
+// index from its first holder on.
+func HoldersAfter(ctx kalpsdk.TransactionContextInterface, after string, limit int) ([]string, bool, error) {
+	iterator, err := ctx.GetStateByPartialCompositeKey(holderPrefix, []string{})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get state for prefix %v: %v", holderPrefix, err)
+	}
+	defer iterator.Close()
+
+	holders := []string{}
+	for iterator.HasNext() {
+		queryResponse, err := iterator.Next()
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to get the next state for prefix %v: %v", holderPrefix, err)
+		}
+		_, parts, err := ctx.SplitCompositeKey(queryResponse.Key)
+		if err != nil || len(parts) != 1 {
+			return nil, false, fmt.Errorf("invalid holder key %s", queryResponse.Key)
+		}
+		if after != "" && parts[0] <= after {
+			continue
+		}
+		if len(holders) == limit {
+			return holders, false, nil
+		}
+		holders = append(holders, parts[0])
+	}
+	return holders, true, nil
+}
+
+// GetMetrics returns the counters of the contract.
+func GetMetrics(ctx kalpsdk.TransactionContextInterface) (*Metrics, error) {
+	totals, err := readMetrics(ctx, totalsKey)
+	if err != nil {
+		return nil, err
+	}
+	_, err = forEachDelta(ctx, -1, func(key string, delta *Metrics) error {
+		totals.add(delta)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return totals, nil
+}
+
+// CompactMetrics folds up to 500 deltas, including those of the token holder counts, into the
+// stored totals and returns how many it folded. The caller must hold AdminRole.
+func CompactMetrics(ctx kalpsdk.TransactionContextInterface) (int, error) {
+	err := accesscontrol.CheckRole(ctx, accesscontrol.AdminRole)
+	if err != nil {
+		return 0, err
+	}
+	totals, err := readMetrics(ctx, totalsKey)
+	if err != nil {
+		return 0, err
+	}
+	folded, err := forEachDelta(ctx, maxCompact, func(key string, delta *Metrics) error {
+		totals.add(delta)
+		err := ctx.DelStateWithoutKYC(key)
+		if err != nil {
+			return fmt.Errorf("failed to delete metrics delta %s: %v", key, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	foldedHolders, err := compactTokenHolders(ctx, maxCompact-folded)
+	if err != nil {
+		return 0, err
+	}
+	folded += foldedHolders
+	err = writeMetrics(ctx, totalsKey, totals)
+	if err != nil {
+		return 0, err
+	}
+	err = audit.Record(ctx, "CompactMetrics", fmt.Sprint(folded))
+	if err != nil {
+		return 0, err
+	}
+	return folded, nil
+}
+
+// forEachDelta calls visit with up to limit stored deltas, or all of them if limit is negative,
+// and returns how many it visited.
+func forEachDelta(ctx kalpsdk.TransactionContextInterface, limit int, visit func(key string, delta *Metrics) error) (int, error) {
+	iterator, err := ctx.GetStateByPartialCompositeKey(deltaPrefix, []string{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get state for prefix %v: %v", deltaPrefix, err)
+	}
+	defer iterator.Close()
+
+	visited := 0
+	for iterator.HasNext() && visited != limit {
+		queryResponse, err := iterator.Next()
+		if err != nil {
+			return 0, fmt.Errorf("failed to get the next state for prefix %v: %v", deltaPrefix, err)
+		}
+		delta := new(Metrics)
+		err = json.Unmarshal(queryResponse.Value, delta)
+		if err != nil {
+			return 0, fmt.Errorf("failed to decode metrics delta %s: %v", queryResponse.Key, err)
+		}
+		err = visit(queryResponse.Key, delta)
+		if err != nil {
+			return 0, err
+		}
+		visited++
+	}
+	return visited, nil
+}
+
+func readMetrics(ctx kalpsdk.TransactionContextInterface, key string) (*Metrics, error) {
+	metricsBytes, err := ctx.GetState(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read metrics %s: %v", key, err)
+	}
+	metrics := new(Metrics)
+	if metricsBytes == nil {
+		return metrics, nil
+	}
+	err = json.Unmarshal(metricsBytes, metrics)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode metrics %s: %v", key, err)
+	}
+	return metrics, nil
+}
+
+func writeMetrics(ctx kalpsdk.TransactionContextInterface, key string, metrics *Metrics) error {
+	metricsJSON, err := canonical.Marshal(metrics)
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	err = ctx.PutStateWithoutKYC(key, metricsJSON)
+	if err != nil {
+		return fmt.Errorf("failed to write metrics %s: %v", key, err)
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package metrics
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+)
+
+// Holders of the token types of multi-token contracts: a marker per token type and holder, and a
+// holder count per token type kept like the other counters, as a stored count plus per-transaction
+// deltas.
+const tokenHolderPrefix = "metrics~tokenHolder"
+const tokenHolderCountPrefix = "metrics~tokenHolderCount"
+const tokenHolderDeltaPrefix = "metrics~tokenHolderDelta"
+
+// HoldToken records whether account holds tokens of type id after its balance changed, keeping
+// the holder count of id up to date. Like Hold, it only knows the accounts whose balance changed
+// since it was introduced.
+func HoldToken(ctx kalpsdk.TransactionContextInterface, id string, account string, holding bool) error {
+	txID := ctx.GetTxID()
+	tx, err := pendingFor(ctx)
+	if err != nil {
+		return err
+	}
+	holderKey, err := ctx.CreateCompositeKey(tokenHolderPrefix, []string{id, account})
+	if err != nil {
+		return fmt.Errorf("failed to create the composite key for prefix %s: %v", tokenHolderPrefix, err)
+	}
+	held, ok := tx.tokenHolding[holderKey]
+	if !ok {
+		markerBytes, err := ctx.GetState(holderKey)
+		if err != nil {
+			return fmt.Errorf("failed to read holder %s of token %s: %v", account, id, err)
+		}
+		held = markerBytes != nil
+	}
+	tx.tokenHolding[holderKey] = holding
+	switch {
+	case holding && !held:
+		err = ctx.PutStateWithoutKYC(holderKey, []byte(txID))
+		tx.tokenHolders[id]++
+	case !holding && held:
+		err = ctx.DelStateWithoutKYC(holderKey)
+		tx.tokenHolders[id]--
+	default:
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write holder %s of token %s: %v", account, id, err)
+	}
+
+	deltaKey, err := ctx.CreateCompositeKey(tokenHolderDeltaPrefix, []string{id, txID})
+	if err != nil {
+		return fmt.Errorf("failed to create the composite key for prefix %s: %v", tokenHolderDeltaPrefix, err)
+	}
+	err = ctx.PutStateWithoutKYC(deltaKey, []byte(strconv.FormatInt(tx.tokenHolders[id], 10)))
+	if err != nil {
+		return fmt.Errorf("failed to write holder count of token %s: %v", id, err)
+	}
+	return nil
+}
+
+// TokenHolderCount returns the number of accounts holding tokens of type id.
+func TokenHolderCount(ctx kalpsdk.TransactionContextInterface, id string) (int64, error) {
+	countKey, err := ctx.CreateCompositeKey(tokenHolderCountPrefix, []string{id})
+	if err != nil {
+		return 0, fmt.Errorf("failed to create the composite key for prefix %s: %v", tokenHolderCountPrefix, err)
+	}
+	count, err := readCount(ctx, countKey)
+	if err != nil {
+		return 0, err
+	}
+
+	iterator, err := ctx.GetStateByPartialCompositeKey(tokenHolderDeltaPrefix, []string{id})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get state for prefix %v: %v", tokenHolderDeltaPrefix, err)
+	}
+	defer iterator.Close()
+	for iterator.HasNext() {
+		queryResponse, err := iterator.Next()
+		if err != nil {
+			return 0, fmt.Errorf("failed to get the next state for prefix %v: %v", tokenHolderDeltaPrefix, err)
+		}
+		delta, err := strconv.ParseInt(string(queryResponse.Value), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("failed to decode holder count delta %s: %v", queryResponse.Key, err)
+		}
+		count += delta
+	}
+	return count, nil
+}
+
+// compactTokenHolders folds up to limit holder count deltas into the stored counts and returns how
+// many it folded.
+func compactTokenHolders(ctx kalpsdk.TransactionContextInterface, limit int) (int, error) {
+	iterator, err := ctx.GetStateByPartialCompositeKey(tokenHolderDeltaPrefix, []string{})
+	if err != nil {
+		return 0, fmt.Errorf("failed to get state for prefix %v: %v", tokenHolderDeltaPrefix, err)
+	}
+	defer iterator.Close()
+
+	deltas := make(map[string]int64)
+	ids := []string{}
+	folded := 0
+	for iterator.HasNext() && folded < limit {
+		queryResponse, err := iterator.Next()
+		if err != nil {
+			return 0, fmt.Errorf("failed to get the next state for prefix %v: %v", tokenHolderDeltaPrefix, err)
+		}
+		_, parts, err := ctx.SplitCompositeKey(queryResponse.Key)
+		if err != nil || len(parts) != 2 {
+			return 0, fmt.Errorf("invalid holder count delta key %s", queryResponse.Key)
+		}
+		delta, err := strconv.ParseInt(string(queryResponse.Value), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("failed to decode holder count delta %s: %v", queryResponse.Key, err)
+		}
+		if _, ok := deltas[parts[0]]; !ok {
+			ids = append(ids, parts[0])
+		}
+		deltas[parts[0]] += delta
+		err = ctx.DelStateWithoutKYC(queryResponse.Key)
+		if err != nil {
+			return 0, fmt.Errorf("failed to delete holder count delta %s: %v", queryResponse.Key, err)
+		}
+		folded++
+	}
+
+	// deltas are read in key order, so ids is sorted and the writes are deterministic
+	for _, id := range ids {
+		countKey, err := ctx.CreateCompositeKey(tokenHolderCountPrefix, []string{id})
+		if err != nil {
+			return 0, fmt.Errorf("failed to create the composite key for prefix %s: %v", tokenHolderCountPrefix, err)
+		}
+		count, err := readCount(ctx, countKey)
+		if err != nil {
+			return 0, err
+		}
+		err = ctx.PutStateWithoutKYC(countKey, []byte(strconv.FormatInt(count+deltas[id], 10)))
+		if err != nil {
+			return 0, fmt.Errorf("failed to write holder count of token %s: %v", id, err)
+		}
+	}
+	return folded, nil
+}
+
+func readCount(ctx kalpsdk.TransactionContextInterface, key string) (int64, error) {
+	countBytes, err := ctx.GetState(key)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read holder count %s: %v", key, err)
+	}
+	if countBytes == nil {
+		return 0, nil
+	}
+	count, err := strconv.ParseInt(string(countBytes), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to decode holder count %s: %v", key, err)
+	}
+	return count, nil
+}
@@ -0,0 +1,66 @@
+package migration
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/pagination"
+)
+
+// SimpleKeys stands for the keys that are not composite, such as ERC20 balances and contract
+// options, in the prefixes passed to Export.
+const SimpleKeys = ""
+
+// Entry is one exported key. Key is the raw key, composite keys included, so it can be written
+// back as is.
+type Entry struct {
+	Prefix string `json:"prefix"`
+	Key    string `json:"key"`
+	Value  []byte `json:"value"`
+}
+
+// Chunk is one page of an export. Phase and Bookmark are passed to the next Export call; Done is
+// set on the last chunk.
+type Chunk struct {
+	Entries  []*Entry `json:"entries"`
+	Phase    int      `json:"phase"`
+	Bookmark string   `json:"bookmark"`
+	Done     bool     `json:"done"`
+}
+
+// Export reads up to pageSize keys of the prefix at index phase of prefixes, starting at
+// bookmark. Exporting starts with phase 0 and an empty bookmark and continues with the Phase and
+// Bookmark of the returned Chunk until it is Done. It uses paginated queries, so it has to be
+// evaluated, not submitted.
+func Export(ctx kalpsdk.TransactionContextInterface, prefixes []string, phase int, bookmark string, pageSize int) (*Chunk, error) {
+	if phase < 0 || phase > len(prefixes) {
+		return nil, fmt.Errorf("phase %d out of range", phase)
+	}
+	chunk := &Chunk{Entries: []*Entry{}, Phase: phase, Done: phase == len(prefixes)}
+	if chunk.Done {
+		return chunk, nil
+	}
+
+	prefix := prefixes[phase]
+	var states []*queryresult.KV
+	var next string
+	var err error
+	if prefix == SimpleKeys {
+		states, next, err = pagination.ByRange(ctx, "", "", bookmark, pageSize)
+	} else {
+		states, next, err = pagination.ByPartialCompositeKey(ctx, prefix, []string{}, bookmark, pageSize)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, state := range states {
+		chunk.Entries = append(chunk.Entries, &Entry{prefix, state.Key, state.Value})
+	}
+	chunk.Bookmark = next
+	if next == "" {
+		chunk.Phase++
+		chunk.Done = chunk.Phase == len(prefixes)
+	}
+	return chunk, nil
+}
@@ -0,0 +1,81 @@
+package migration
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+)
+
+// importPrefix keys the seal of Import. It is composite, so exports of SimpleKeys do not carry the
+// seal of the old deployment over.
+const importPrefix = "migration~import"
+
+// Import writes the entries of chunk, exported from a previous deployment by Export, and returns
+// how many it wrote. Every entry has to belong to one of prefixes, the prefixes the contract
+// exports. Imports are refused once SealImport was called, which config.Initialize does unless the
+// contract is initialized to import state.
+func Import(ctx kalpsdk.TransactionContextInterface, prefixes []string, chunk *Chunk) (int, error) {
+	sealed, err := ImportSealed(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if sealed {
+		return 0, fmt.Errorf("state import is sealed")
+	}
+
+	allowed := make(map[string]bool, len(prefixes))
+	for _, prefix := range prefixes {
+		allowed[prefix] = true
+	}
+	for _, entry := range chunk.Entries {
+		if !allowed[entry.Prefix] {
+			return 0, fmt.Errorf("prefix %q is not part of the contract state", entry.Prefix)
+		}
+		composite := strings.HasPrefix(entry.Key, "\x00")
+		if entry.Prefix == SimpleKeys {
+			if composite || entry.Key == "" {
+				return 0, fmt.Errorf("key %q is not a simple key", entry.Key)
+			}
+		} else {
+			objectType := ""
+			if composite {
+				objectType, _, err = ctx.SplitCompositeKey(entry.Key)
+			}
+			if !composite || err != nil || objectType != entry.Prefix {
+				return 0, fmt.Errorf("key %q does not belong to prefix %s", entry.Key, entry.Prefix)
+			}
+		}
+		err = ctx.PutStateWithoutKYC(entry.Key, entry.Value)
+		if err != nil {
+			return 0, fmt.Errorf("failed to import %q: %v", entry.Key, err)
+		}
+	}
+	return len(chunk.Entries), nil
+}
+
+// SealImport ends the import of state for good.
+func SealImport(ctx kalpsdk.TransactionContextInterface) error {
+	sealKey, err := ctx.CreateCompositeKey(importPrefix, []string{"sealed"})
+	if err != nil {
+		return fmt.Errorf("failed to create the composite key for prefix %s: %v", importPrefix, err)
+	}
+	err = ctx.PutStateWithoutKYC(sealKey, []byte(ctx.GetTxID()))
+	if err != nil {
+		return fmt.Errorf("failed to seal state import: %v", err)
+	}
+	return nil
+}
+
+// ImportSealed reports whether SealImport was called.
+func ImportSealed(ctx kalpsdk.TransactionContextInterface) (bool, error) {
+	sealKey, err := ctx.CreateCompositeKey(importPrefix, []string{"sealed"})
+	if err != nil {
+		return false, fmt.Errorf("failed to create the composite key for prefix %s: %v", importPrefix, err)
+	}
+	sealBytes, err := ctx.GetState(sealKey)
+	if err != nil {
+		return false, fmt.Errorf("failed to read state import seal: %v", err)
+	}
+	return sealBytes != nil, nil
+}
@@ -0,0 +1,85 @@
+package migration
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/pagination"
+)
+
+// Decoder decodes the value of a key for RawRange.
+type Decoder func(value []byte) (interface{}, error)
+
+// DecodeJSON decodes a JSON value, keeping numbers exact.
+func DecodeJSON(value []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(value))
+	decoder.UseNumber()
+	var decoded interface{}
+	err := decoder.Decode(&decoded)
+	if err != nil {
+		return nil, err
+	}
+	return decoded, nil
+}
+
+// DecodeText returns the value as a string, for amounts, URIs and other plain values.
+func DecodeText(value []byte) (interface{}, error) {
+	return string(value), nil
+}
+
+// RawEntry is a key read by RawRange. ObjectType and Attributes are the parts of composite keys;
+// Decoded is the value decoded by the decoder of the prefix, and DecodeError why that failed.
+type RawEntry struct {
+	Key         string      `json:"key"`
+	ObjectType  string      `json:"objectType,omitempty" metadata:",optional"`
+	Attributes  []string    `json:"attributes,omitempty" metadata:",optional"`
+	Value       []byte      `json:"value"`
+	Decoded     interface{} `json:"decoded,omitempty" metadata:",optional"`
+	DecodeError string      `json:"decodeError,omitempty" metadata:",optional"`
+}
+
+// RawPage is one page of RawRange. Bookmark is empty on the last page.
+type RawPage struct {
+	Entries  []*RawEntry `json:"entries"`
+	Bookmark string      `json:"bookmark"`
+}
+
+// RawRange reads up to pageSize keys under prefix, a composite key object type or SimpleKeys,
+// starting at bookmark, for operators looking into orphaned or corrupt keys. Values are decoded
+// with the decoder of the object type in decoders, if any. Like Export it has to be evaluated.
+func RawRange(ctx kalpsdk.TransactionContextInterface, prefix string, decoders map[string]Decoder, bookmark string, pageSize int) (*RawPage, error) {
+	var states []*queryresult.KV
+	var next string
+	var err error
+	if prefix == SimpleKeys {
+		states, next, err = pagination.ByRange(ctx, "", "", bookmark, pageSize)
+	} else {
+		states, next, err = pagination.ByPartialCompositeKey(ctx, prefix, []string{}, bookmark, pageSize)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	page := &RawPage{Entries: []*RawEntry{}, Bookmark: next}
+	for _, state := range states {
+		entry := &RawEntry{Key: state.Key, Value: state.Value}
+		if strings.HasPrefix(state.Key, "\x00") {
+			entry.ObjectType, entry.Attributes, err = ctx.SplitCompositeKey(state.Key)
+			if err != nil {
+				entry.DecodeError = fmt.Sprintf("invalid composite key: %v", err)
+			}
+		}
+		if decode, ok := decoders[prefix]; ok && entry.DecodeError == "" {
+			entry.Decoded, err = decode(state.Value)
+			if err != nil {
+				entry.DecodeError = err.Error()
+			}
+		}
+		page.Entries = append(page.Entries, entry)
+	}
+	return page, nil
+}
@@ -0,0 +1,237 @@
+// Package migration runs chaincode state migrations in bounded, resumable batches.
+//
+// A migration walks one or more composite key prefixes ("phases") and hands every key to a
+// Step that rewrites it into the new layout. Each call processes at most batchSize keys and
+// records a bookmark, so large ledgers are migrated by invoking the same entry point until the
+// returned Report says Done. In dry-run mode steps only report the keys they would touch and
+// nothing, not even the status, is written; Preview pages through the rest of a migration that way.
+//
+// Export reads prefixes page by page the same way, to copy the state of a contract out of the
+// ledger, and Import writes the exported chunks into a new deployment until SealImport is called.
+// RawRange pages through a prefix without a Step, decoding the values for operators.
+package migration
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	"github.com/thekalpstudio/kush-go/canonical"
+	"github.com/thekalpstudio/kush-go/pagination"
+)
+
+const statusPrefix = "migration"
+
+// DefaultBatchSize is used when callers pass a non-positive batch size.
+const DefaultBatchSize = 100
+
+// Step migrates a single key. It returns the keys it wrote or deleted (or would have, when dryRun
+// is set).
+type Step func(ctx kalpsdk.TransactionContextInterface, key string, value []byte, dryRun bool) ([]string, error)
+
+// Phase applies Step to every key under the composite key Prefix.
+type Phase struct {
+	Prefix string
+	Step   Step
+}
+
+// Migration is a named, ordered list of phases.
+type Migration struct {
+	Name   string
+	Phases []Phase
+}
+
+// Status is the persisted progress of a migration.
+type Status struct {
+	Name      string `json:"name"`
+	Phase     int    `json:"phase"`
+	Bookmark  string `json:"bookmark"`
+	Processed int    `json:"processed"`
+	Affected  int    `json:"affected"`
+	Done      bool   `json:"done"`
+	LastTxId  string `json:"lastTxId"`
+}
+
+// Report describes the outcome of one batch.
+type Report struct {
+	Name         string   `json:"name"`
+	DryRun       bool     `json:"dryRun"`
+	Processed    int      `json:"processed"`
+	AffectedKeys []string `json:"affectedKeys"`
+	Phase        int      `json:"phase"`
+	Bookmark     string   `json:"bookmark"`
+	Done         bool     `json:"done"`
+}
+
+// Run processes the next batch of m, resuming from the stored status. A dry run previews that
+// batch, see Preview.
+func Run(ctx kalpsdk.TransactionContextInterface, m Migration, batchSize int, dryRun bool) (*Report, error) {
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	status, err := GetStatus(ctx, m.Name)
+	if err != nil {
+		return nil, err
+	}
+	if dryRun && !status.Done {
+		return Preview(ctx, m, status.Phase, status.Bookmark, batchSize)
+	}
+	report := &Report{Name: m.Name, DryRun: dryRun, AffectedKeys: []string{}}
+	if status.Done {
+		report.Phase = status.Phase
+		report.Done = true
+		return report, nil
+	}
+
+	for status.Phase < len(m.Phases) && report.Processed < batchSize {
+		phase := m.Phases[status.Phase]
+		bookmark, exhausted, err := runPhase(ctx, phase, status.Bookmark, batchSize-report.Processed, report)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s failed in phase %d: %v", m.Name, status.Phase, err)
+		}
+		status.Bookmark = bookmark
+		if exhausted {
+			status.Phase++
+			status.Bookmark = ""
+		}
+	}
+	status.Done = status.Phase >= len(m.Phases)
+	status.Processed += report.Processed
+	status.Affected += len(report.AffectedKeys)
+	status.LastTxId = ctx.GetTxID()
+
+	report.Phase = status.Phase
+	report.Bookmark = status.Bookmark
+	report.Done = status.Done
+	err = putStatus(ctx, status)
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// runPhase migrates up to limit keys after bookmark and reports whether the prefix is exhausted.
+// Fabric neither serves paginated queries to transactions that write nor starts a composite key
+// range past its partial key, so every batch reads the prefix from its first key; only previews
+// start at their bookmark.
+func runPhase(ctx kalpsdk.TransactionContextInterface, phase Phase, bookmark string, limit int, report *Report) (string, bool, error) {
+	iterator, err := ctx.GetStateByPartialCompositeKey(phase.Prefix, []string{})
+	if err != nil {
+		return "", false, fmt.Errorf("failed to get state for prefix %v: %v", phase.Prefix, err)
+	}
+	defer iterator.Close()
+
+	processed := 0
+	for iterator.HasNext() {
+		queryResponse, err := iterator.Next()
+		if err != nil {
+			return "", false, fmt.Errorf("failed to get the next state for prefix %v: %v", phase.Prefix, err)
+		}
+		// Keys are returned in lexical order, so everything up to the bookmark is already done.
+		if bookmark != "" && queryResponse.Key <= bookmark {
+			continue
+		}
+		if processed == limit {
+			return bookmark, false, nil
+		}
+		affected, err := phase.Step(ctx, queryResponse.Key, queryResponse.Value, false)
+		if err != nil {
+			return "", false, err
+		}
+		report.AffectedKeys = append(report.AffectedKeys, affected...)
+		report.Processed++
+		processed++
+		bookmark = queryResponse.Key
+	}
+	return bookmark, true, nil
+}
+
+// Preview dry-runs up to batchSize keys of m after the key bookmark of the phase at index phase,
+// and writes nothing. Previewing the rest of a migration starts with the Phase and Bookmark of its
+// status and continues with those of the returned Report until it is Done. It uses paginated
+// queries, so it has to be evaluated, not submitted.
+func Preview(ctx kalpsdk.TransactionContextInterface, m Migration, phase int, bookmark string, batchSize int) (*Report, error) {
+	if phase < 0 || phase > len(m.Phases) {
+		return nil, fmt.Errorf("phase %d out of range", phase)
+	}
+	if batchSize <= 0 {
+		batchSize = DefaultBatchSize
+	}
+	report := &Report{Name: m.Name, DryRun: true, AffectedKeys: []string{}}
+	for phase < len(m.Phases) && report.Processed < batchSize {
+		var exhausted bool
+		var err error
+		bookmark, exhausted, err = previewPhase(ctx, m.Phases[phase], bookmark, batchSize-report.Processed, report)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s failed in phase %d: %v", m.Name, phase, err)
+		}
+		if exhausted {
+			phase++
+			bookmark = ""
+		}
+	}
+	report.Phase = phase
+	report.Bookmark = bookmark
+	report.Done = phase == len(m.Phases)
+	return report, nil
+}
+
+// previewPhase dry-runs up to limit keys after bookmark, reading one page, and reports whether the
+// prefix is exhausted. Pages start at their bookmark, so the page after bookmark starts with it.
+func previewPhase(ctx kalpsdk.TransactionContextInterface, phase Phase, bookmark string, limit int, report *Report) (string, bool, error) {
+	states, next, err := pagination.ByPartialCompositeKey(ctx, phase.Prefix, []string{}, bookmark, limit+1)
+	if err != nil {
+		return "", false, err
+	}
+	processed := 0
+	for _, state := range states {
+		if state.Key == bookmark {
+			continue
+		}
+		if processed == limit {
+			return bookmark, false, nil
+		}
+		affected, err := phase.Step(ctx, state.Key, state.Value, true)
+		if err != nil {
+			return "", false, err
+		}
+		report.AffectedKeys = append(report.AffectedKeys, affected...)
+		report.Processed++
+		processed++
+		bookmark = state.Key
+	}
+	return bookmark, next == "", nil
+}
+
+// GetStatus returns the stored progress of the named migration.
+func GetStatus(ctx kalpsdk.TransactionContextInterface, name string) (*Status, error) {
+	statusKey, err := ctx.CreateCompositeKey(statusPrefix, []string{name})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the composite key for prefix %s: %v", statusPrefix, err)
+	}
+	statusBytes, err := ctx.GetState(statusKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migration status %s: %v", name, err)
+	}
+	status := &Status{Name: name}
+	if statusBytes == nil {
+		return status, nil
+	}
+	err = json.Unmarshal(statusBytes, status)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode migration status %s: %v", name, err)
+	}
+	return status, nil
+}
+
+func putStatus(ctx kalpsdk.TransactionContextInterface, status *Status) error {
+	statusKey, err := ctx.CreateCompositeKey(statusPrefix, []string{status.Name})
+	if err != nil {
+		return fmt.Errorf("failed to create the composite key for prefix %s: %v", statusPrefix, err)
+	}
+	statusJSON, err := canonical.Marshal(status)
+	if err != nil {
+		return fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	return ctx.PutStateWithoutKYC(statusKey, statusJSON)
+}
@@ -0,0 +1,96 @@
+package migration
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+)
+
+const versionKey = "migration~version"
+
+// Upgrade moves contract state from schema version From to From+1. Migration is called once per
+// batch, so steps can keep state between the keys of one transaction.
+type Upgrade struct {
+	From      int
+	Migration func() Migration
+}
+
+// GetVersion returns the stored schema version. Contracts initialized before versioning have no
+// version key and report 0.
+func GetVersion(ctx kalpsdk.TransactionContextInterface) (int, error) {
+	versionBytes, err := ctx.GetState(versionKey)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %v", err)
+	}
+	if versionBytes == nil {
+		return 0, nil
+	}
+	version, err := strconv.Atoi(string(versionBytes))
+	if err != nil {
+		return 0, fmt.Errorf("failed to decode schema version: %v", err)
+	}
+	return version, nil
+}
+
+// SetVersion stores the schema version. Contracts call it from Initialize with their current
+// version, since freshly initialized state needs no upgrades.
+func SetVersion(ctx kalpsdk.TransactionContextInterface, version int) error {
+	err := ctx.PutStateWithoutKYC(versionKey, []byte(strconv.Itoa(version)))
+	if err != nil {
+		return fmt.Errorf("failed to set schema version: %v", err)
+	}
+	return nil
+}
+
+// CurrentVersion returns the version reached after applying every upgrade.
+func CurrentVersion(upgrades []Upgrade) int {
+	return len(upgrades)
+}
+
+// Migrate runs the next batch of the upgrade from fromVersion, which has to be the stored version
+// so upgrades are applied in order and never twice. Once the upgrade reports Done the version is
+// bumped to fromVersion+1, and the next upgrade can be started.
+func Migrate(ctx kalpsdk.TransactionContextInterface, upgrades []Upgrade, fromVersion int, batchSize int, dryRun bool) (*Report, error) {
+	m, err := upgradeFrom(ctx, upgrades, fromVersion)
+	if err != nil {
+		return nil, err
+	}
+	report, err := Run(ctx, m, batchSize, dryRun)
+	if err != nil {
+		return nil, err
+	}
+	if report.Done && !dryRun {
+		err = SetVersion(ctx, fromVersion+1)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
+
+// PreviewUpgrade previews the upgrade from fromVersion, the stored version, after phase and
+// bookmark, see Preview.
+func PreviewUpgrade(ctx kalpsdk.TransactionContextInterface, upgrades []Upgrade, fromVersion int, phase int, bookmark string, batchSize int) (*Report, error) {
+	m, err := upgradeFrom(ctx, upgrades, fromVersion)
+	if err != nil {
+		return nil, err
+	}
+	return Preview(ctx, m, phase, bookmark, batchSize)
+}
+
+// upgradeFrom returns the migration of the upgrade from fromVersion, which has to be the stored
+// version.
+func upgradeFrom(ctx kalpsdk.TransactionContextInterface, upgrades []Upgrade, fromVersion int) (Migration, error) {
+	version, err := GetVersion(ctx)
+	if err != nil {
+		return Migration{}, err
+	}
+	if version != fromVersion {
+		return Migration{}, fmt.Errorf("state is at version %d, not %d", version, fromVersion)
+	}
+	if fromVersion < 0 || fromVersion >= len(upgrades) || upgrades[fromVersion].From != fromVersion {
+		return Migration{}, fmt.Errorf("no upgrade from version %d, current version is %d", fromVersion, CurrentVersion(upgrades))
+	}
+	return upgrades[fromVersion].Migration(), nil
+}
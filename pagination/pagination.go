@@ -0,0 +1,176 @@
+// Package pagination reads composite key ranges one page at a time, so queries over large
+// collections stay within the chaincode execution timeout.
+//
+// Pages are read with the stub's GetStateByPartialCompositeKeyWithPagination, which Fabric only
+// serves in read-only transactions: paginated functions are queries, and transactions that write
+// keep reading their ranges whole. ByQuery pages through CouchDB rich queries the same way.
+// Bookmarks are opaque; an empty bookmark starts at the first key and the bookmark of the last page
+// is empty.
+package pagination
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+)
+
+// DefaultPageSize is used when a non-positive page size is requested.
+const DefaultPageSize = 50
+
+// MaxPageSize bounds the page size.
+const MaxPageSize = 500
+
+// PageSize returns pageSize within (0, MaxPageSize], DefaultPageSize if it is not positive.
+func PageSize(pageSize int) int {
+	if pageSize <= 0 {
+		return DefaultPageSize
+	}
+	if pageSize > MaxPageSize {
+		return MaxPageSize
+	}
+	return pageSize
+}
+
+// stubber is implemented by the kalpsdk transaction context, which does not expose the paginated
+// queries itself.
+type stubber interface {
+	GetStub() shim.ChaincodeStubInterface
+}
+
+// ByPartialCompositeKey returns up to pageSize states under objectType and attributes, starting at
+// bookmark, and the bookmark of the next page.
+func ByPartialCompositeKey(ctx kalpsdk.TransactionContextInterface, objectType string, attributes []string, bookmark string, pageSize int) ([]*queryresult.KV, string, error) {
+	pageSize = PageSize(pageSize)
+	if withStub, ok := ctx.(stubber); ok && withStub.GetStub() != nil {
+		iterator, metadata, err := withStub.GetStub().GetStateByPartialCompositeKeyWithPagination(objectType, attributes, int32(pageSize), bookmark)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to get state for prefix %v: %v", objectType, err)
+		}
+		defer iterator.Close()
+		states, err := collect(iterator, objectType, pageSize)
+		if err != nil {
+			return nil, "", err
+		}
+		if len(states) < pageSize {
+			return states, "", nil
+		}
+		return states, metadata.GetBookmark(), nil
+	}
+	return scan(ctx, objectType, attributes, bookmark, pageSize)
+}
+
+// ByRange returns up to pageSize simple keys, those that are not composite, in [startKey,
+// endKey), starting at bookmark, and the bookmark of the next page. Empty keys leave the range open.
+func ByRange(ctx kalpsdk.TransactionContextInterface, startKey string, endKey string, bookmark string, pageSize int) ([]*queryresult.KV, string, error) {
+	pageSize = PageSize(pageSize)
+	if withStub, ok := ctx.(stubber); ok && withStub.GetStub() != nil {
+		iterator, metadata, err := withStub.GetStub().GetStateByRangeWithPagination(startKey, endKey, int32(pageSize), bookmark)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to get state by range: %v", err)
+		}
+		defer iterator.Close()
+		states, err := collect(iterator, "range", pageSize)
+		if err != nil {
+			return nil, "", err
+		}
+		if len(states) < pageSize {
+			return states, "", nil
+		}
+		return states, metadata.GetBookmark(), nil
+	}
+
+	iterator, err := ctx.GetStateByRange(startKey, endKey)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get state by range: %v", err)
+	}
+	return page(iterator, "range", bookmark, pageSize)
+}
+
+// ByQuery returns up to pageSize states matching the CouchDB query, starting at bookmark, and the
+// bookmark of the next page. The state database of the channel must be CouchDB.
+func ByQuery(ctx kalpsdk.TransactionContextInterface, query string, bookmark string, pageSize int) ([]*queryresult.KV, string, error) {
+	pageSize = PageSize(pageSize)
+	if withStub, ok := ctx.(stubber); ok && withStub.GetStub() != nil {
+		iterator, metadata, err := withStub.GetStub().GetQueryResultWithPagination(query, int32(pageSize), bookmark)
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to run query: %v", err)
+		}
+		defer iterator.Close()
+		states, err := collect(iterator, "query", pageSize)
+		if err != nil {
+			return nil, "", err
+		}
+		if len(states) < pageSize {
+			return states, "", nil
+		}
+		return states, metadata.GetBookmark(), nil
+	}
+
+	iterator, err := ctx.GetQueryResult(query)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to run query: %v", err)
+	}
+	return page(iterator, "query", bookmark, pageSize)
+}
+
+// SelectorQuery wraps the CouchDB selector, a JSON object, in a query for ByQuery. Only the
+// selector is passed on, so callers cannot set fields, limits or other query options.
+func SelectorQuery(selector string) (string, error) {
+	var fields map[string]interface{}
+	err := json.Unmarshal([]byte(selector), &fields)
+	if err != nil || len(fields) == 0 {
+		return "", fmt.Errorf("selector must be a non-empty JSON object")
+	}
+	query, err := json.Marshal(map[string]interface{}{"selector": fields})
+	if err != nil {
+		return "", fmt.Errorf("failed to obtain JSON encoding: %v", err)
+	}
+	return string(query), nil
+}
+
+// scan pages through a full range query for contexts without a stub, such as test doubles. Its
+// bookmark is the first key of the next page, like Fabric's.
+func scan(ctx kalpsdk.TransactionContextInterface, objectType string, attributes []string, bookmark string, pageSize int) ([]*queryresult.KV, string, error) {
+	iterator, err := ctx.GetStateByPartialCompositeKey(objectType, attributes)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get state for prefix %v: %v", objectType, err)
+	}
+	return page(iterator, objectType, bookmark, pageSize)
+}
+
+// page skips the states of iterator before bookmark and returns the next pageSize, closing the
+// iterator.
+func page(iterator shim.StateQueryIteratorInterface, objectType string, bookmark string, pageSize int) ([]*queryresult.KV, string, error) {
+	defer iterator.Close()
+
+	states := []*queryresult.KV{}
+	for iterator.HasNext() {
+		queryResponse, err := iterator.Next()
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to get the next state for prefix %v: %v", objectType, err)
+		}
+		if queryResponse.Key < bookmark {
+			continue
+		}
+		if len(states) == pageSize {
+			return states, queryResponse.Key, nil
+		}
+		states = append(states, queryResponse)
+	}
+	return states, "", nil
+}
+
+func collect(iterator shim.StateQueryIteratorInterface, objectType string, pageSize int) ([]*queryresult.KV, error) {
+	states := make([]*queryresult.KV, 0, pageSize)
+	for iterator.HasNext() {
+		queryResponse, err := iterator.Next()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the next state for prefix %v: %v", objectType, err)
+		}
+		states = append(states, queryResponse)
+	}
+	return states, nil
+}
@@ -0,0 +1,141 @@
+package reconcile
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-protos-go/common"
+	"github.com/hyperledger/fabric-protos-go/gateway"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// GatewayReader reads balances by evaluating the BalanceOf and OwnerOf queries of a contract
+// through the gateway service of a Kalp peer. Identity and Sign are as in events.ListenerConfig.
+type GatewayReader struct {
+	Conn        *grpc.ClientConn
+	ChannelID   string
+	ChaincodeID string
+	Identity    []byte
+	Sign        func(digest []byte) ([]byte, error)
+}
+
+func (g *GatewayReader) ERC20Balance(ctx context.Context, account string) (*big.Int, error) {
+	result, err := g.evaluate(ctx, "BalanceOf", account)
+	if err != nil {
+		return nil, err
+	}
+	balance, ok := new(big.Int).SetString(string(result), 10)
+	if !ok {
+		return nil, fmt.Errorf("balance %q is not an integer", result)
+	}
+	return balance, nil
+}
+
+func (g *GatewayReader) ERC1155Balance(ctx context.Context, account string, id uint64) (uint64, error) {
+	result, err := g.evaluate(ctx, "BalanceOf", account, strconv.FormatUint(id, 10))
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(string(result), 10, 64)
+}
+
+func (g *GatewayReader) OwnerOf(ctx context.Context, tokenId string) (string, error) {
+	result, err := g.evaluate(ctx, "OwnerOf", tokenId)
+	if err != nil {
+		return "", err
+	}
+	return string(result), nil
+}
+
+// evaluate runs a query on a peer without submitting it for ordering.
+func (g *GatewayReader) evaluate(ctx context.Context, function string, args ...string) ([]byte, error) {
+	proposal, txID, err := g.newProposal(function, args)
+	if err != nil {
+		return nil, err
+	}
+	proposalBytes, err := proto.Marshal(proposal)
+	if err != nil {
+		return nil, fmt.Errorf("failed to serialize proposal: %v", err)
+	}
+	digest := sha256.Sum256(proposalBytes)
+	signature, err := g.Sign(digest[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign proposal: %v", err)
+	}
+	response, err := gateway.NewGatewayClient(g.Conn).Evaluate(ctx, &gateway.EvaluateRequest{
+		TransactionId: txID,
+		ChannelId:     g.ChannelID,
+		ProposedTransaction: &peer.SignedProposal{
+			ProposalBytes: proposalBytes,
+			Signature:     signature,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate %s: %v", function, err)
+	}
+	return response.GetResult().GetPayload(), nil
+}
+
+func (g *GatewayReader) newProposal(function string, args []string) (*peer.Proposal, string, error) {
+	nonce := make([]byte, 24)
+	_, err := rand.Read(nonce)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to generate nonce: %v", err)
+	}
+	txIDDigest := sha256.Sum256(append(append([]byte{}, nonce...), g.Identity...))
+	txID := hex.EncodeToString(txIDDigest[:])
+
+	input := [][]byte{[]byte(function)}
+	for _, arg := range args {
+		input = append(input, []byte(arg))
+	}
+	chaincodeID := &peer.ChaincodeID{Name: g.ChaincodeID}
+	invocationBytes, err := proto.Marshal(&peer.ChaincodeInvocationSpec{
+		ChaincodeSpec: &peer.ChaincodeSpec{
+			Type:        peer.ChaincodeSpec_GOLANG,
+			ChaincodeId: chaincodeID,
+			Input:       &peer.ChaincodeInput{Args: input},
+		},
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to serialize invocation: %v", err)
+	}
+	payloadBytes, err := proto.Marshal(&peer.ChaincodeProposalPayload{Input: invocationBytes})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to serialize proposal payload: %v", err)
+	}
+	extensionBytes, err := proto.Marshal(&peer.ChaincodeHeaderExtension{ChaincodeId: chaincodeID})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to serialize header extension: %v", err)
+	}
+	channelHeaderBytes, err := proto.Marshal(&common.ChannelHeader{
+		Type:      int32(common.HeaderType_ENDORSER_TRANSACTION),
+		ChannelId: g.ChannelID,
+		TxId:      txID,
+		Timestamp: timestamppb.Now(),
+		Extension: extensionBytes,
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to serialize channel header: %v", err)
+	}
+	signatureHeaderBytes, err := proto.Marshal(&common.SignatureHeader{Creator: g.Identity, Nonce: nonce})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to serialize signature header: %v", err)
+	}
+	headerBytes, err := proto.Marshal(&common.Header{
+		ChannelHeader:   channelHeaderBytes,
+		SignatureHeader: signatureHeaderBytes,
+	})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to serialize header: %v", err)
+	}
+	return &peer.Proposal{Header: headerBytes, Payload: payloadBytes}, txID, nil
+}
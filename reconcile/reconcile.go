@@ -0,0 +1,229 @@
+// Package reconcile rebuilds the balances of a token from its events and diffs them against world
+// state, to detect drift or missed writes, for instance after a chaincode upgrade.
+//
+// Balances applies the ERC20 Transfer, TransferBatch, Airdrop, EmissionExecuted, VestingReleased
+// and stream events, the ERC1155 TransferSingle, TransferBatch and Promoted events and the ERC721
+// Transfer events delivered by an events.Listener. Replaying from the block the contract was
+// deployed in yields the full balances; replaying from a later block needs the balances at that
+// block seeded first.
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+
+	"github.com/thekalpstudio/kush-go/events"
+)
+
+// zeroAddress is the counterparty of mints and burns.
+const zeroAddress = "0x0"
+
+// TokenKey identifies an ERC1155 balance.
+type TokenKey struct {
+	Account string
+	ID      uint64
+}
+
+// Balances are the balances expected from the events applied so far.
+type Balances struct {
+	// ERC20 holds the fungible balance of every account.
+	ERC20 map[string]*big.Int
+	// ERC1155 holds the balance of every account in every token type. Like ERC20 balances they go
+	// negative when an account spends more than the events credited it.
+	ERC1155 map[TokenKey]*big.Int
+	// Owners holds the owner of every existing ERC721 token.
+	Owners map[string]string
+	// LastBlock is the block of the last applied event.
+	LastBlock uint64
+}
+
+// NewBalances returns empty balances.
+func NewBalances() *Balances {
+	return &Balances{
+		ERC20:   make(map[string]*big.Int),
+		ERC1155: make(map[TokenKey]*big.Int),
+		Owners:  make(map[string]string),
+	}
+}
+
+// Replay applies the events received on ch up to and including block toBlock. It returns when an
+// event past toBlock arrives, ch is closed or ctx is done.
+func (b *Balances) Replay(ctx context.Context, ch <-chan events.Event, toBlock uint64) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case event, ok := <-ch:
+			if !ok || event.BlockNumber > toBlock {
+				return nil
+			}
+			err := b.Apply(event)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// Apply applies one event. Events that do not move tokens are ignored.
+func (b *Balances) Apply(event events.Event) error {
+	if event.Err != nil {
+		return nil
+	}
+	b.LastBlock = event.BlockNumber
+	switch value := event.Value.(type) {
+	case *events.Transfer:
+		b.applyTransfer(value)
+	case *events.BatchTransfer:
+		legFees := false
+		for i := range value.Transfers {
+			b.applyTransfer(&value.Transfers[i])
+			legFees = legFees || value.Transfers[i].FeeCharged != nil
+		}
+		// older events only carry the sum of the fees, which cannot be attributed to the legs
+		if value.FeeCharged != nil && !legFees {
+			b.addERC20(value.FeeCharged.Collector, value.FeeCharged.Amount)
+		}
+	case *events.Airdrop:
+		for i := range value.Transfers {
+			b.applyTransfer(&value.Transfers[i])
+		}
+	case *events.EmissionExecuted:
+		b.addERC20(value.Treasury, value.Amount)
+	case *events.VestingReleased:
+		for i := range value.Transfers {
+			b.applyTransfer(&value.Transfers[i])
+		}
+	case *events.StreamEvent:
+		for i := range value.Transfers {
+			b.applyTransfer(&value.Transfers[i])
+		}
+	case *events.TransferSingle:
+		b.applyTransferBatch(value.From, value.To, []uint64{value.ID}, []uint64{value.Value})
+	case *events.TransferBatch:
+		if len(value.IDs) != len(value.Values) {
+			return fmt.Errorf("TransferBatch of tx %s has %d ids but %d values", event.TxID, len(value.IDs), len(value.Values))
+		}
+		b.applyTransferBatch(value.From, value.To, value.IDs, value.Values)
+	case *events.Promoted:
+		b.applyTransferBatch(value.Account, zeroAddress, []uint64{value.ID}, []uint64{1})
+	case *events.NFTTransfer:
+		if value.To == zeroAddress {
+			delete(b.Owners, value.TokenId)
+		} else {
+			b.Owners[value.TokenId] = value.To
+		}
+	}
+	return nil
+}
+
+// applyTransfer debits From with Value and credits To with Value less the fee, which goes to the
+// fee collector.
+func (b *Balances) applyTransfer(transfer *events.Transfer) {
+	if transfer.Value == nil {
+		return
+	}
+	b.addERC20(transfer.From, new(big.Int).Neg(transfer.Value))
+	received := transfer.Value
+	if transfer.FeeCharged != nil && transfer.FeeCharged.Amount != nil {
+		received = new(big.Int).Sub(transfer.Value, transfer.FeeCharged.Amount)
+		b.addERC20(transfer.FeeCharged.Collector, transfer.FeeCharged.Amount)
+	}
+	b.addERC20(transfer.To, received)
+}
+
+func (b *Balances) addERC20(account string, amount *big.Int) {
+	if account == zeroAddress {
+		return
+	}
+	balance, ok := b.ERC20[account]
+	if !ok {
+		balance = new(big.Int)
+		b.ERC20[account] = balance
+	}
+	balance.Add(balance, amount)
+}
+
+func (b *Balances) applyTransferBatch(from string, to string, ids []uint64, values []uint64) {
+	for i, id := range ids {
+		value := new(big.Int).SetUint64(values[i])
+		b.addERC1155(TokenKey{from, id}, new(big.Int).Neg(value))
+		b.addERC1155(TokenKey{to, id}, value)
+	}
+}
+
+func (b *Balances) addERC1155(key TokenKey, amount *big.Int) {
+	if key.Account == zeroAddress {
+		return
+	}
+	balance, ok := b.ERC1155[key]
+	if !ok {
+		balance = new(big.Int)
+		b.ERC1155[key] = balance
+	}
+	balance.Add(balance, amount)
+}
+
+// Reader reads the balances of a token from world state, see GatewayReader.
+type Reader interface {
+	ERC20Balance(ctx context.Context, account string) (*big.Int, error)
+	ERC1155Balance(ctx context.Context, account string, id uint64) (uint64, error)
+	OwnerOf(ctx context.Context, tokenId string) (string, error)
+}
+
+// Drift is a balance whose world state value differs from the replayed one. TokenID is empty for
+// ERC20 balances; for ERC721 tokens Account is empty and the values are owners.
+type Drift struct {
+	Account  string `json:"account,omitempty"`
+	TokenID  string `json:"tokenId,omitempty"`
+	Expected string `json:"expected"`
+	Actual   string `json:"actual"`
+}
+
+// Diff compares the replayed balances with world state and returns the drifts, sorted by account
+// and token. Only accounts and tokens that appear in the applied events are compared.
+func (b *Balances) Diff(ctx context.Context, reader Reader) ([]Drift, error) {
+	drifts := []Drift{}
+	for account, expected := range b.ERC20 {
+		actual, err := reader.ERC20Balance(ctx, account)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read balance of %s: %v", account, err)
+		}
+		if actual.Cmp(expected) != 0 {
+			drifts = append(drifts, Drift{Account: account, Expected: expected.String(), Actual: actual.String()})
+		}
+	}
+	for key, expected := range b.ERC1155 {
+		actual, err := reader.ERC1155Balance(ctx, key.Account, key.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read balance of %s in token %d: %v", key.Account, key.ID, err)
+		}
+		if new(big.Int).SetUint64(actual).Cmp(expected) != 0 {
+			drifts = append(drifts, Drift{
+				Account:  key.Account,
+				TokenID:  strconv.FormatUint(key.ID, 10),
+				Expected: expected.String(),
+				Actual:   strconv.FormatUint(actual, 10),
+			})
+		}
+	}
+	for tokenId, expected := range b.Owners {
+		actual, err := reader.OwnerOf(ctx, tokenId)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read owner of token %s: %v", tokenId, err)
+		}
+		if actual != expected {
+			drifts = append(drifts, Drift{TokenID: tokenId, Expected: expected, Actual: actual})
+		}
+	}
+	sort.Slice(drifts, func(i, j int) bool {
+		if drifts[i].Account != drifts[j].Account {
+			return drifts[i].Account < drifts[j].Account
+		}
+		return drifts[i].TokenID < drifts[j].TokenID
+	})
+	return drifts, nil
+}
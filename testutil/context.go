@@ -0,0 +1,278 @@
+package testutil
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric-chaincode-go/pkg/cid"
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"github.com/p2eengineering/kalp-sdk-public/kalpsdk"
+	res "github.com/p2eengineering/kalp-sdk-public/response"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Context is the transaction context of one transaction on a Ledger.
+type Context struct {
+	ledger    *Ledger
+	txID      string
+	timestamp time.Time
+	user      string
+	chaincode string
+	function  string
+	args      []string
+	writes    map[string][]byte
+	deletes   map[string]bool
+	// invoked holds the transactions of the chaincodes the transaction invoked, which commit with it.
+	invoked []*Context
+	state   map[interface{}]interface{}
+	// EventName and EventPayload are the event the transaction set, the last one as on Fabric.
+	EventName    string
+	EventPayload []byte
+}
+
+var _ kalpsdk.TransactionContextInterface = (*Context)(nil)
+
+// Commit applies the writes and the event of the transaction, and the writes of the chaincodes it
+// invoked, to their ledgers. A transaction that is not committed leaves no trace, as one whose
+// proposal failed.
+func (c *Context) Commit() {
+	for _, invoked := range c.invoked {
+		invoked.Commit()
+	}
+	c.invoked = nil
+	for key := range c.deletes {
+		c.ledger.del(key)
+		c.ledger.record(key, c.txID, c.timestamp, nil, true)
+	}
+	for key, value := range c.writes {
+		c.ledger.put(key, value)
+		c.ledger.record(key, c.txID, c.timestamp, value, false)
+	}
+	if c.EventName != "" {
+		c.ledger.events = append(c.ledger.events, Event{TxID: c.txID, Name: c.EventName, Payload: c.EventPayload})
+	}
+	c.writes = make(map[string][]byte)
+	c.deletes = make(map[string]bool)
+	c.EventName = ""
+	c.EventPayload = nil
+}
+
+// TxState returns the in-memory state of the transaction, as the context of a chaincode keeps it.
+func (c *Context) TxState() map[interface{}]interface{} {
+	if c.state == nil {
+		c.state = make(map[interface{}]interface{})
+	}
+	return c.state
+}
+
+// Written returns the value the transaction wrote to key and whether it wrote or deleted the key,
+// so tests can check writes the transaction itself cannot read.
+func (c *Context) Written(key string) ([]byte, bool) {
+	if c.deletes[key] {
+		return nil, true
+	}
+	value, ok := c.writes[key]
+	return value, ok
+}
+
+func (c *Context) PutStateWithKYC(key string, value []byte) error {
+	if !c.ledger.kyc[c.user] {
+		return fmt.Errorf("user %s has not completed KYC", c.user)
+	}
+	return c.PutStateWithoutKYC(key, value)
+}
+
+func (c *Context) PutStateWithoutKYC(key string, value []byte) error {
+	if key == "" {
+		return fmt.Errorf("key must not be an empty string")
+	}
+	delete(c.deletes, key)
+	c.writes[key] = value
+	return nil
+}
+
+func (c *Context) GetKYC(userId string) (bool, error) {
+	return c.ledger.kyc[userId], nil
+}
+
+func (c *Context) PutKYC(id string, kycId string, kycHash string) error {
+	c.ledger.kyc[id] = true
+	return nil
+}
+
+func (c *Context) DelStateWithoutKYC(key string) error {
+	if key == "" {
+		return fmt.Errorf("key must not be an empty string")
+	}
+	delete(c.writes, key)
+	c.deletes[key] = true
+	return nil
+}
+
+func (c *Context) DelStateWithKYC(key string) error {
+	if !c.ledger.kyc[c.user] {
+		return fmt.Errorf("user %s has not completed KYC", c.user)
+	}
+	return c.DelStateWithoutKYC(key)
+}
+
+func (c *Context) GetState(key string) ([]byte, error) {
+	return c.ledger.state[key], nil
+}
+
+func (c *Context) SetEvent(name string, payload []byte) error {
+	if name == "" {
+		return fmt.Errorf("event name can not be empty string")
+	}
+	c.EventName = name
+	c.EventPayload = payload
+	return nil
+}
+
+func (c *Context) GetTxID() string {
+	return c.txID
+}
+
+func (c *Context) GetChannelID() string {
+	return "kalp"
+}
+
+func (c *Context) GetUserID() (string, error) {
+	return c.user, nil
+}
+
+func (c *Context) InvokeChaincode(chaincodeName string, args [][]byte, channel string) res.Response {
+	return res.Response{Response: c.ledger.invoke(c, chaincodeName, args)}
+}
+
+// GetStub returns a stub that only answers GetSignedProposal, with a proposal addressed to the
+// chaincode named by SetChaincode, or nil if the ledger names none.
+func (c *Context) GetStub() shim.ChaincodeStubInterface {
+	if c.chaincode == "" {
+		return nil
+	}
+	return &stub{chaincode: c.chaincode}
+}
+
+// stub is the chaincode stub of a transaction as far as the contracts use it. The methods it does
+// not implement panic.
+type stub struct {
+	shim.ChaincodeStubInterface
+	chaincode string
+}
+
+func (s *stub) GetSignedProposal() (*peer.SignedProposal, error) {
+	input, err := proto.Marshal(&peer.ChaincodeInvocationSpec{
+		ChaincodeSpec: &peer.ChaincodeSpec{ChaincodeId: &peer.ChaincodeID{Name: s.chaincode}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	payload, err := proto.Marshal(&peer.ChaincodeProposalPayload{Input: input})
+	if err != nil {
+		return nil, err
+	}
+	proposal, err := proto.Marshal(&peer.Proposal{Payload: payload})
+	if err != nil {
+		return nil, err
+	}
+	return &peer.SignedProposal{ProposalBytes: proposal}, nil
+}
+
+func (c *Context) CreateCompositeKey(objectType string, attributes []string) (string, error) {
+	return shim.CreateCompositeKey(objectType, attributes)
+}
+
+func (c *Context) SplitCompositeKey(compositeKey string) (string, []string, error) {
+	if !strings.HasPrefix(compositeKey, "\x00") || !strings.HasSuffix(compositeKey, "\x00") {
+		return "", nil, fmt.Errorf("%q is not a composite key", compositeKey)
+	}
+	parts := strings.Split(compositeKey[1:len(compositeKey)-1], "\x00")
+	return parts[0], parts[1:], nil
+}
+
+func (c *Context) GetStateByPartialCompositeKey(objectType string, keys []string) (kalpsdk.StateQueryIteratorInterface, error) {
+	prefix, err := shim.CreateCompositeKey(objectType, keys)
+	if err != nil {
+		return nil, err
+	}
+	return &iterator{states: c.ledger.between(prefix, prefix+string(rune(0x10FFFF)))}, nil
+}
+
+func (c *Context) GetStateByRange(startKey string, endKey string) (kalpsdk.StateQueryIteratorInterface, error) {
+	// as on Fabric, an empty start key skips the composite keys
+	if startKey == "" {
+		startKey = "\x01"
+	}
+	return &iterator{states: c.ledger.between(startKey, endKey)}, nil
+}
+
+func (c *Context) GetQueryResult(query string) (kalpsdk.StateQueryIteratorInterface, error) {
+	return nil, fmt.Errorf("rich queries need a CouchDB state database")
+}
+
+func (c *Context) GetHistoryForKey(key string) (kalpsdk.HistoryQueryIteratorInterface, error) {
+	return &historyIterator{modifications: c.ledger.history[key]}, nil
+}
+
+func (c *Context) GetTxTimestamp() (*timestamppb.Timestamp, error) {
+	return timestamppb.New(c.timestamp), nil
+}
+
+func (c *Context) GetFunctionAndParameters() (string, []string) {
+	return c.function, c.args
+}
+
+func (c *Context) ValidateCreateTokenTransaction(id string, docType string, account []string) error {
+	return nil
+}
+
+func (c *Context) GetClientIdentity() cid.ClientIdentity {
+	return identity{ledger: c.ledger, user: c.user}
+}
+
+type iterator struct {
+	states []*queryresult.KV
+	next   int
+}
+
+func (it *iterator) HasNext() bool {
+	return it.next < len(it.states)
+}
+
+func (it *iterator) Next() (*queryresult.KV, error) {
+	if !it.HasNext() {
+		return nil, fmt.Errorf("no more states")
+	}
+	it.next++
+	return it.states[it.next-1], nil
+}
+
+func (it *iterator) Close() error {
+	return nil
+}
+
+type historyIterator struct {
+	modifications []*queryresult.KeyModification
+	next          int
+}
+
+func (it *historyIterator) HasNext() bool {
+	return it.next < len(it.modifications)
+}
+
+func (it *historyIterator) Next() (*queryresult.KeyModification, error) {
+	if !it.HasNext() {
+		return nil, fmt.Errorf("no more modifications")
+	}
+	it.next++
+	return it.modifications[it.next-1], nil
+}
+
+func (it *historyIterator) Close() error {
+	return nil
+}
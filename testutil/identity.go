@@ -0,0 +1,80 @@
+package testutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// enrollment is the key pair and certificate a user signs with.
+type enrollment struct {
+	key         *ecdsa.PrivateKey
+	certificate *x509.Certificate
+}
+
+func (l *Ledger) enroll(user string) *enrollment {
+	if e, ok := l.identities[user]; ok {
+		return e
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(fmt.Sprintf("failed to generate key of %s: %v", user, err))
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(int64(len(l.identities) + 1)),
+		Subject:      pkix.Name{CommonName: user, OrganizationalUnit: []string{"client"}},
+		NotBefore:    l.clock,
+		NotAfter:     l.clock.Add(10 * 365 * 24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		panic(fmt.Sprintf("failed to issue certificate of %s: %v", user, err))
+	}
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		panic(fmt.Sprintf("failed to parse certificate of %s: %v", user, err))
+	}
+	e := &enrollment{key: key, certificate: certificate}
+	l.identities[user] = e
+	return e
+}
+
+// identity is the client identity of a transaction.
+type identity struct {
+	ledger *Ledger
+	user   string
+}
+
+func (i identity) GetID() (string, error) {
+	return ClientID(i.user), nil
+}
+
+func (i identity) GetMSPID() (string, error) {
+	return i.ledger.mspID(i.user), nil
+}
+
+func (i identity) GetAttributeValue(attrName string) (string, bool, error) {
+	value, ok := i.ledger.attributes[i.user][attrName]
+	return value, ok, nil
+}
+
+func (i identity) AssertAttributeValue(attrName, attrValue string) error {
+	value, ok := i.ledger.attributes[i.user][attrName]
+	if !ok {
+		return fmt.Errorf("attribute '%s' was not found", attrName)
+	}
+	if value != attrValue {
+		return fmt.Errorf("attribute '%s' equals '%s', not '%s'", attrName, value, attrValue)
+	}
+	return nil
+}
+
+func (i identity) GetX509Certificate() (*x509.Certificate, error) {
+	return i.ledger.Certificate(i.user), nil
+}
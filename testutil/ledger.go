@@ -0,0 +1,272 @@
+// Package testutil runs the contracts against an in-memory ledger, so they can be tested without a
+// Kalp network.
+//
+// The Ledger follows the rules of Fabric the contracts depend on: a transaction reads the state
+// committed before it, never its own writes, and its writes, history and event only reach the ledger
+// when it commits. A chaincode it invokes runs against its own ledger within the transaction, see
+// RegisterContract. Range reads use a sorted key index, so they cost what they cost on a peer rather
+// than a scan of the whole state. Rich queries need CouchDB and are not supported.
+//
+//	l := testutil.NewLedger()
+//	ctx := l.Tx("admin", "Initialize")
+//	_, err := contract.Initialize(ctx, cfg)
+//	ctx.Commit()
+package testutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	"github.com/hyperledger/fabric-protos-go/peer"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// MSPID is the MSP of the clients of a Ledger unless SetMSPID says otherwise.
+const MSPID = "Org1MSP"
+
+// ClientID returns the client identity ID of user, as GetClientIdentity().GetID() reports it: the
+// base64 encoding of its subject and issuer. GetUserID reports user itself.
+func ClientID(user string) string {
+	return base64.StdEncoding.EncodeToString([]byte("x509::CN=" + user + ",OU=client::CN=ca.org1.example.com,O=org1.example.com"))
+}
+
+// Chaincode answers the InvokeChaincode calls made to it with args, the function name first.
+type Chaincode func(args [][]byte) peer.Response
+
+// Contract answers the InvokeChaincode calls made to it with args, the function name first, in ctx:
+// a transaction of the ledger of the invoked chaincode.
+type Contract func(ctx *Context, args [][]byte) peer.Response
+
+// contract is a Contract registered with the ledger it runs against.
+type contract struct {
+	ledger *Ledger
+	cc     Contract
+}
+
+// Event is an event a committed transaction set.
+type Event struct {
+	TxID    string
+	Name    string
+	Payload []byte
+}
+
+// Ledger is an in-memory world state.
+type Ledger struct {
+	state map[string][]byte
+	// keys holds the keys of state in order, for range reads.
+	keys []string
+	// history holds the writes of every key, newest first as GetHistoryForKey returns them.
+	history    map[string][]*queryresult.KeyModification
+	events     []Event
+	kyc        map[string]bool
+	msps       map[string]string
+	attributes map[string]map[string]string
+	identities map[string]*enrollment
+	chaincodes map[string]Chaincode
+	contracts  map[string]contract
+	// chaincode is the chaincode the transactions of the ledger are proposed to.
+	chaincode string
+	txCount   int
+	clock     time.Time
+}
+
+// NewLedger returns an empty ledger.
+func NewLedger() *Ledger {
+	return &Ledger{
+		state:      make(map[string][]byte),
+		history:    make(map[string][]*queryresult.KeyModification),
+		kyc:        make(map[string]bool),
+		msps:       make(map[string]string),
+		attributes: make(map[string]map[string]string),
+		identities: make(map[string]*enrollment),
+		chaincodes: make(map[string]Chaincode),
+		contracts:  make(map[string]contract),
+		clock:      time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+// Len returns the number of keys in the ledger.
+func (l *Ledger) Len() int {
+	return len(l.keys)
+}
+
+// State returns the committed value of key, nil if it is not set.
+func (l *Ledger) State(key string) []byte {
+	return l.state[key]
+}
+
+// Keys returns the committed keys in order.
+func (l *Ledger) Keys() []string {
+	return append([]string(nil), l.keys...)
+}
+
+// Events returns the events of the committed transactions, oldest first.
+func (l *Ledger) Events() []Event {
+	return append([]Event(nil), l.events...)
+}
+
+// SetKYC records whether user has completed KYC, for the WithKYC writes and GetKYC.
+func (l *Ledger) SetKYC(user string, done bool) {
+	l.kyc[user] = done
+}
+
+// SetMSPID makes user a client of mspID.
+func (l *Ledger) SetMSPID(user string, mspID string) {
+	l.msps[user] = mspID
+}
+
+// SetAttribute gives the certificate of user the attribute name with value.
+func (l *Ledger) SetAttribute(user string, name string, value string) {
+	if l.attributes[user] == nil {
+		l.attributes[user] = make(map[string]string)
+	}
+	l.attributes[user][name] = value
+}
+
+// Certificate returns the enrollment certificate of user, issuing it on first use.
+func (l *Ledger) Certificate(user string) *x509.Certificate {
+	return l.enroll(user).certificate
+}
+
+// PrivateKey returns the private key of the enrollment certificate of user, to sign what the
+// contracts verify against the certificate.
+func (l *Ledger) PrivateKey(user string) *ecdsa.PrivateKey {
+	return l.enroll(user).key
+}
+
+// RegisterChaincode makes InvokeChaincode calls to name reach cc.
+func (l *Ledger) RegisterChaincode(name string, cc Chaincode) {
+	l.chaincodes[name] = cc
+}
+
+// RegisterContract makes InvokeChaincode calls to name run cc against target, the ledger of the
+// invoked chaincode, as part of the calling transaction: with its ID, timestamp, identity and
+// proposal. The writes of cc commit with the calling transaction if cc succeeds, and its event is
+// dropped, as Fabric drops the events of invoked chaincodes.
+func (l *Ledger) RegisterContract(name string, target *Ledger, cc Contract) {
+	l.contracts[name] = contract{ledger: target, cc: cc}
+}
+
+// SetChaincode names the chaincode the transactions of the ledger are proposed to, so GetStub
+// returns a stub whose signed proposal addresses it. Until it is set, GetStub returns nil.
+func (l *Ledger) SetChaincode(name string) {
+	l.chaincode = name
+}
+
+// Advance moves the clock of the ledger forward by d. Every transaction also advances it by a
+// second.
+func (l *Ledger) Advance(d time.Duration) {
+	l.clock = l.clock.Add(d)
+}
+
+// Tx starts a transaction of user invoking function with args. Commit applies its writes.
+func (l *Ledger) Tx(user string, function string, args ...string) *Context {
+	l.txCount++
+	l.clock = l.clock.Add(time.Second)
+	return &Context{
+		ledger:    l,
+		txID:      fmt.Sprintf("tx%08d", l.txCount),
+		timestamp: l.clock,
+		user:      user,
+		chaincode: l.chaincode,
+		function:  function,
+		args:      args,
+		writes:    make(map[string][]byte),
+		deletes:   make(map[string]bool),
+	}
+}
+
+func (l *Ledger) mspID(user string) string {
+	if mspID, ok := l.msps[user]; ok {
+		return mspID
+	}
+	return MSPID
+}
+
+func (l *Ledger) invoke(caller *Context, name string, args [][]byte) peer.Response {
+	if target, ok := l.contracts[name]; ok {
+		return target.invoke(caller, args)
+	}
+	cc, ok := l.chaincodes[name]
+	if !ok {
+		return shim.Error(fmt.Sprintf("chaincode %s is not registered with the in-memory ledger", name))
+	}
+	return cc(args)
+}
+
+func (c contract) invoke(caller *Context, args [][]byte) peer.Response {
+	if len(args) == 0 {
+		return shim.Error("no function given to invoke")
+	}
+	params := make([]string, len(args)-1)
+	for i, arg := range args[1:] {
+		params[i] = string(arg)
+	}
+	ctx := &Context{
+		ledger:    c.ledger,
+		txID:      caller.txID,
+		timestamp: caller.timestamp,
+		user:      caller.user,
+		chaincode: caller.chaincode,
+		function:  string(args[0]),
+		args:      params,
+		writes:    make(map[string][]byte),
+		deletes:   make(map[string]bool),
+	}
+	response := c.cc(ctx, args)
+	if response.Status == shim.OK {
+		ctx.EventName = ""
+		ctx.EventPayload = nil
+		caller.invoked = append(caller.invoked, ctx)
+	}
+	return response
+}
+
+func (l *Ledger) put(key string, value []byte) {
+	if _, ok := l.state[key]; !ok {
+		i := sort.SearchStrings(l.keys, key)
+		l.keys = append(l.keys, "")
+		copy(l.keys[i+1:], l.keys[i:])
+		l.keys[i] = key
+	}
+	l.state[key] = value
+}
+
+func (l *Ledger) del(key string) {
+	if _, ok := l.state[key]; !ok {
+		return
+	}
+	delete(l.state, key)
+	i := sort.SearchStrings(l.keys, key)
+	l.keys = append(l.keys[:i], l.keys[i+1:]...)
+}
+
+func (l *Ledger) record(key string, txID string, timestamp time.Time, value []byte, isDelete bool) {
+	modification := &queryresult.KeyModification{
+		TxId:      txID,
+		Value:     value,
+		Timestamp: timestamppb.New(timestamp),
+		IsDelete:  isDelete,
+	}
+	l.history[key] = append([]*queryresult.KeyModification{modification}, l.history[key]...)
+}
+
+// between returns the states with keys in [startKey, endKey); an empty endKey leaves the range
+// open.
+func (l *Ledger) between(startKey string, endKey string) []*queryresult.KV {
+	states := []*queryresult.KV{}
+	for i := sort.SearchStrings(l.keys, startKey); i < len(l.keys); i++ {
+		key := l.keys[i]
+		if endKey != "" && key >= endKey {
+			break
+		}
+		states = append(states, &queryresult.KV{Key: key, Value: l.state[key]})
+	}
+	return states
+}
@@ -0,0 +1,178 @@
+package testutil
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hyperledger/fabric-chaincode-go/shim"
+	"github.com/hyperledger/fabric-protos-go/peer"
+)
+
+func TestTransactionDoesNotReadItsOwnWrites(t *testing.T) {
+	l := NewLedger()
+	ctx := l.Tx("alice", "Put")
+	err := ctx.PutStateWithoutKYC("k", []byte("v1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, _ := ctx.GetState("k")
+	if value != nil {
+		t.Fatalf("uncommitted write is visible: %q", value)
+	}
+	ctx.Commit()
+	value, _ = l.Tx("alice", "Get").GetState("k")
+	if string(value) != "v1" {
+		t.Fatalf("committed value is %q, want v1", value)
+	}
+}
+
+func TestHistoryIsNewestFirst(t *testing.T) {
+	l := NewLedger()
+	for _, value := range []string{"v1", "v2"} {
+		ctx := l.Tx("alice", "Put")
+		_ = ctx.PutStateWithoutKYC("k", []byte(value))
+		ctx.Commit()
+	}
+	ctx := l.Tx("alice", "Del")
+	_ = ctx.DelStateWithoutKYC("k")
+	ctx.Commit()
+
+	iterator, err := l.Tx("alice", "History").GetHistoryForKey("k")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"", "v2", "v1"}
+	for i := 0; iterator.HasNext(); i++ {
+		modification, _ := iterator.Next()
+		if string(modification.Value) != want[i] || modification.IsDelete != (i == 0) {
+			t.Fatalf("modification %d is %q (delete %v), want %q", i, modification.Value, modification.IsDelete, want[i])
+		}
+	}
+}
+
+func TestRangesSkipCompositeKeys(t *testing.T) {
+	l := NewLedger()
+	ctx := l.Tx("alice", "Put")
+	composite, _ := ctx.CreateCompositeKey("balance", []string{"alice", "1"})
+	_ = ctx.PutStateWithoutKYC(composite, []byte("10"))
+	_ = ctx.PutStateWithoutKYC("alice", []byte("5"))
+	ctx.Commit()
+
+	ctx = l.Tx("alice", "Query")
+	ranged, _ := ctx.GetStateByRange("", "")
+	if !ranged.HasNext() {
+		t.Fatal("range is empty")
+	}
+	state, _ := ranged.Next()
+	if state.Key != "alice" || ranged.HasNext() {
+		t.Fatalf("range returned %q and more: %v", state.Key, ranged.HasNext())
+	}
+	partial, _ := ctx.GetStateByPartialCompositeKey("balance", []string{"alice"})
+	state, _ = partial.Next()
+	if state.Key != composite {
+		t.Fatalf("partial composite key query returned %q", state.Key)
+	}
+	objectType, attributes, err := ctx.SplitCompositeKey(composite)
+	if err != nil || objectType != "balance" || len(attributes) != 2 || attributes[1] != "1" {
+		t.Fatalf("split %q into %q %v: %v", composite, objectType, attributes, err)
+	}
+}
+
+func TestEventsAndIdentity(t *testing.T) {
+	l := NewLedger()
+	l.SetMSPID("bob", "Org2MSP")
+	ctx := l.Tx("bob", "Emit")
+	_ = ctx.SetEvent("Transfer", []byte("{}"))
+	ctx.Commit()
+	events := l.Events()
+	if len(events) != 1 || events[0].Name != "Transfer" || events[0].TxID != ctx.GetTxID() {
+		t.Fatalf("events are %v", events)
+	}
+
+	id, _ := ctx.GetClientIdentity().GetID()
+	mspID, _ := ctx.GetClientIdentity().GetMSPID()
+	if id != ClientID("bob") || mspID != "Org2MSP" {
+		t.Fatalf("identity is %s of %s", id, mspID)
+	}
+	cert, err := ctx.GetClientIdentity().GetX509Certificate()
+	if err != nil || cert.Subject.CommonName != "bob" {
+		t.Fatalf("certificate is %v: %v", cert, err)
+	}
+}
+
+func TestInvokedContractCommitsWithTheCaller(t *testing.T) {
+	l := NewLedger()
+	l.SetChaincode("shop")
+	target := NewLedger()
+	var proposedTo string
+	l.RegisterContract("token", target, func(ctx *Context, args [][]byte) peer.Response {
+		proposedTo = ctx.GetStub().(*stub).chaincode
+		if string(args[1]) == "fail" {
+			return shim.Error("failed")
+		}
+		_ = ctx.PutStateWithoutKYC("k", args[1])
+		_ = ctx.SetEvent("Transfer", []byte("{}"))
+		return shim.Success(nil)
+	})
+
+	ctx := l.Tx("alice", "Buy")
+	response := ctx.InvokeChaincode("token", [][]byte{[]byte("Put"), []byte("v1")}, "")
+	if response.Status != shim.OK || proposedTo != "shop" {
+		t.Fatalf("invoke answered %v with the proposal addressed to %q", response, proposedTo)
+	}
+	if target.State("k") != nil {
+		t.Fatal("invoked write is visible before the caller commits")
+	}
+	_ = ctx.SetEvent("Bought", []byte("{}"))
+	ctx.Commit()
+	if string(target.State("k")) != "v1" {
+		t.Fatalf("invoked write is %q, want v1", target.State("k"))
+	}
+	if len(target.Events()) != 0 || len(l.Events()) != 1 {
+		t.Fatalf("events are %v on the target and %v on the caller", target.Events(), l.Events())
+	}
+	history, _ := target.Tx("alice", "History").GetHistoryForKey("k")
+	modification, _ := history.Next()
+	if modification.TxId != ctx.GetTxID() {
+		t.Fatalf("invoked write was recorded in %s, want %s", modification.TxId, ctx.GetTxID())
+	}
+
+	ctx = l.Tx("alice", "Buy")
+	response = ctx.InvokeChaincode("token", [][]byte{[]byte("Put"), []byte("fail")}, "")
+	ctx.Commit()
+	if response.Status == shim.OK || string(target.State("k")) != "v1" {
+		t.Fatalf("failed invoke answered %v and left %q", response, target.State("k"))
+	}
+}
+
+func TestLedgerJSONRoundTrip(t *testing.T) {
+	l := NewLedger()
+	l.SetKYC("alice", true)
+	ctx := l.Tx("alice", "Put")
+	_ = ctx.PutStateWithKYC("k", []byte("v1"))
+	_ = ctx.SetEvent("Put", []byte("{}"))
+	ctx.Commit()
+	data, err := json.Marshal(l)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored := NewLedger()
+	if err := json.Unmarshal(data, restored); err != nil {
+		t.Fatal(err)
+	}
+	if string(restored.State("k")) != "v1" || len(restored.Keys()) != 1 || len(restored.Events()) != 1 {
+		t.Fatalf("restored state %v, events %v", restored.Keys(), restored.Events())
+	}
+	next := restored.Tx("alice", "Put")
+	if next.GetTxID() == ctx.GetTxID() {
+		t.Fatalf("restored ledger reuses the tx ID %s", next.GetTxID())
+	}
+	if err := next.PutStateWithKYC("k", []byte("v2")); err != nil {
+		t.Fatalf("KYC was not restored: %v", err)
+	}
+	iterator, _ := next.GetHistoryForKey("k")
+	if modification, err := iterator.Next(); err != nil || string(modification.Value) != "v1" {
+		t.Fatalf("history is %v: %v", modification, err)
+	}
+}
@@ -0,0 +1,101 @@
+package testutil
+
+import (
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric-protos-go/ledger/queryresult"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// persistedLedger is the JSON form of a Ledger. Enrollments are not kept: a restored ledger issues
+// new certificates, with the same subjects and so the same client IDs. Registered chaincodes are not
+// kept either.
+type persistedLedger struct {
+	State      map[string][]byte            `json:"state"`
+	History    map[string][]modification    `json:"history,omitempty"`
+	Events     []Event                      `json:"events,omitempty"`
+	KYC        map[string]bool              `json:"kyc,omitempty"`
+	MSPs       map[string]string            `json:"msps,omitempty"`
+	Attributes map[string]map[string]string `json:"attributes,omitempty"`
+	Chaincode  string                       `json:"chaincode,omitempty"`
+	TxCount    int                          `json:"txCount"`
+	Clock      time.Time                    `json:"clock"`
+}
+
+type modification struct {
+	TxID      string    `json:"txId"`
+	Value     []byte    `json:"value,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+	IsDelete  bool      `json:"isDelete,omitempty"`
+}
+
+// MarshalJSON encodes the committed state of the ledger, with its history, events and the settings
+// of its users, so that a ledger outlives the process using it.
+func (l *Ledger) MarshalJSON() ([]byte, error) {
+	p := persistedLedger{
+		State:      l.state,
+		History:    make(map[string][]modification, len(l.history)),
+		Events:     l.events,
+		KYC:        l.kyc,
+		MSPs:       l.msps,
+		Attributes: l.attributes,
+		Chaincode:  l.chaincode,
+		TxCount:    l.txCount,
+		Clock:      l.clock,
+	}
+	for key, modifications := range l.history {
+		for _, m := range modifications {
+			p.History[key] = append(p.History[key], modification{
+				TxID:      m.TxId,
+				Value:     m.Value,
+				Timestamp: m.Timestamp.AsTime(),
+				IsDelete:  m.IsDelete,
+			})
+		}
+	}
+	return json.Marshal(p)
+}
+
+// UnmarshalJSON restores a ledger encoded by MarshalJSON. Transactions started afterwards continue
+// its transaction IDs and clock.
+func (l *Ledger) UnmarshalJSON(data []byte) error {
+	var p persistedLedger
+	if err := json.Unmarshal(data, &p); err != nil {
+		return err
+	}
+	restored := NewLedger()
+	for key, value := range p.State {
+		restored.state[key] = value
+		restored.keys = append(restored.keys, key)
+	}
+	sort.Strings(restored.keys)
+	for key, modifications := range p.History {
+		for _, m := range modifications {
+			restored.history[key] = append(restored.history[key], &queryresult.KeyModification{
+				TxId:      m.TxID,
+				Value:     m.Value,
+				Timestamp: timestamppb.New(m.Timestamp),
+				IsDelete:  m.IsDelete,
+			})
+		}
+	}
+	restored.events = p.Events
+	for user, done := range p.KYC {
+		restored.kyc[user] = done
+	}
+	for user, mspID := range p.MSPs {
+		restored.msps[user] = mspID
+	}
+	for user, attributes := range p.Attributes {
+		restored.attributes[user] = attributes
+	}
+	restored.chaincode = p.Chaincode
+	restored.txCount = p.TxCount
+	if !p.Clock.IsZero() {
+		restored.clock = p.Clock
+	}
+	*l = *restored
+	return nil
+}
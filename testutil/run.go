@@ -0,0 +1,64 @@
+package testutil
+
+import (
+	"testing"
+
+	"github.com/thekalpstudio/kush-go/events"
+	"github.com/thekalpstudio/kush-go/kusherrors"
+)
+
+// Run executes fn as one transaction of user and commits it if fn succeeds.
+func Run(l *Ledger, user string, function string, fn func(ctx *Context) error) error {
+	ctx := l.Tx(user, function)
+	err := fn(ctx)
+	if err == nil {
+		ctx.Commit()
+	}
+	return err
+}
+
+// MustRun is Run for the set up of a test, which must succeed.
+func MustRun(t *testing.T, l *Ledger, user string, function string, fn func(ctx *Context) error) {
+	t.Helper()
+	err := Run(l, user, function, fn)
+	if err != nil {
+		t.Fatalf("%s: %v", function, err)
+	}
+}
+
+// CheckErr compares err with the outcome a test case expects: no error if wantErr is false, else
+// an error carrying wantCode when it is set. Contracts wrap coded errors with %v, so the code is
+// read from the message, as clients do.
+func CheckErr(t *testing.T, err error, wantErr bool, wantCode kusherrors.Code) {
+	t.Helper()
+	if !wantErr {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return
+	}
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if wantCode != "" && kusherrors.Parse(err.Error()) != wantCode {
+		t.Fatalf("error %q does not carry %s", err, wantCode)
+	}
+}
+
+// LastEvent decodes the event of the last committed transaction, which must be name.
+func LastEvent(t *testing.T, l *Ledger, name string) interface{} {
+	t.Helper()
+	emitted := l.Events()
+	if len(emitted) == 0 {
+		t.Fatalf("no event was emitted, want %s", name)
+	}
+	event := emitted[len(emitted)-1]
+	if event.Name != name {
+		t.Fatalf("last event is %s, want %s", event.Name, name)
+	}
+	_, value, err := events.Decode(event.Name, event.Payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return value
+}